@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/git"
+	"github.com/adityaraj/agentflow/internal/state"
+)
+
+// gitBuiltins returns the {{git.*}} variables of the repository at dir, if
+// the workflow uses any. It fails if it does and dir isn't in a repository.
+func gitBuiltins(cfg *config.AgentflowConfig, dir string) (map[string]string, error) {
+	if !config.UsesGitBuiltins(cfg) {
+		return nil, nil
+	}
+
+	ctx := context.Background()
+	commit, err := git.Output(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("the workflow uses {{git.*}} variables, but %s is not a git repository with commits: %w", dir, err)
+	}
+	// Empty on a detached HEAD
+	branch, _ := git.Output(ctx, dir, "symbolic-ref", "--short", "-q", "HEAD")
+	short, err := git.Output(ctx, dir, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		config.BuiltinGitCommit:      commit,
+		config.BuiltinGitShortCommit: short,
+		config.BuiltinGitBranch:      branch,
+	}, nil
+}
+
+// builtinVars returns the values of the built-in template variables of a
+// run, adding the run and project ones to vars.
+func builtinVars(vars map[string]string, store *state.Store, projectDir string, start time.Time) map[string]string {
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+	vars[config.BuiltinRunID] = store.RunID()
+	vars[config.BuiltinRunDate] = start.Format("2006-01-02")
+	vars[config.BuiltinRunTime] = start.Format("15:04:05")
+	vars[config.BuiltinRunTimestamp] = start.Format(time.RFC3339)
+	vars[config.BuiltinRunDir] = store.RunDir()
+	vars[config.BuiltinProjectName] = filepath.Base(projectDir)
+	vars[config.BuiltinProjectDir] = projectDir
+	return vars
+}
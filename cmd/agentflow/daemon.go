@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/paths"
+	"github.com/adityaraj/agentflow/internal/server"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// daemonTokenEnv holds the API token `cortex daemon enqueue` sends.
+const daemonTokenEnv = "CORTEX_TOKEN"
+
+// newDaemonCmd creates the `cortex daemon` command and its enqueue
+// subcommand.
+func newDaemonCmd() *cobra.Command {
+	opts := serveOptions{maxRuns: 1}
+
+	daemonCmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Serve the REST API with a queue of runs, for shared machines",
+		Long: `Starts the server of ` + "`cortex serve`" + `, but runs at most --max-runs
+workflows at once. Further runs, from POST /runs, webhooks, or
+` + "`cortex daemon enqueue`" + `, wait in a queue and start in the order they
+arrived. The queue is kept in --queue-file, so runs still waiting when the
+daemon stops start when it is started again.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.maxRuns < 1 {
+				return fmt.Errorf("--max-runs must be at least 1")
+			}
+			if opts.queueFile == "" {
+				dir, err := paths.StateDir()
+				if err != nil {
+					return err
+				}
+				opts.queueFile = filepath.Join(dir, "daemon", "queue.json")
+			}
+			return serve(opts)
+		},
+	}
+
+	daemonCmd.Flags().StringVar(&opts.host, "host", "127.0.0.1", "Address to listen on")
+	daemonCmd.Flags().IntVar(&opts.port, "port", 8080, "Port to listen on")
+	daemonCmd.Flags().StringArrayVarP(&opts.files, "file", "f", nil, "Cortexfile whose triggers to serve (repeatable)")
+	daemonCmd.Flags().IntVar(&opts.maxRuns, "max-runs", opts.maxRuns, "Runs at once; more wait in the queue")
+	daemonCmd.Flags().StringVar(&opts.queueFile, "queue-file", "", "File the queue is kept in (default: daemon/queue.json in the state directory)")
+
+	daemonCmd.AddCommand(newDaemonEnqueueCmd())
+	return daemonCmd
+}
+
+// newDaemonEnqueueCmd creates the `cortex daemon enqueue` command.
+func newDaemonEnqueueCmd() *cobra.Command {
+	var (
+		file string
+		addr string
+		vars []string
+	)
+
+	enqueueCmd := &cobra.Command{
+		Use:   "enqueue",
+		Short: "Add a run of a Cortexfile to a daemon's queue",
+		Long: `Asks a running ` + "`cortex daemon`" + ` to run a Cortexfile, which starts
+when a slot is free. The Cortexfile is read by the daemon, from the same path,
+so it must be on the daemon's machine. When the daemon requires tokens, set
+` + daemonTokenEnv + `.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return enqueueRun(addr, file, vars)
+		},
+	}
+
+	enqueueCmd.Flags().StringVarP(&file, "file", "f", "Cortexfile.yml", "Cortexfile to run")
+	enqueueCmd.Flags().StringVar(&addr, "addr", "http://127.0.0.1:8080", "URL of the daemon")
+	enqueueCmd.Flags().StringArrayVar(&vars, "var", nil, "Set a workflow variable (name=value, repeatable)")
+	addOutputFlags(enqueueCmd)
+
+	return enqueueCmd
+}
+
+func enqueueRun(addr, file string, varFlags []string) error {
+	if err := checkOutputFormat(); err != nil {
+		return err
+	}
+	vars, err := config.ParseVarFlags(varFlags)
+	if err != nil {
+		return err
+	}
+	// The daemon resolves relative paths from its own directory
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(server.RunRequest{File: path, Vars: vars})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(addr, "/")+"/runs", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := os.Getenv(daemonTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot reach the daemon at %s: %w", addr, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		if apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return fmt.Errorf("daemon refused the run: %s", apiErr.Error)
+	}
+
+	var info server.RunInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return fmt.Errorf("invalid response from the daemon: %w", err)
+	}
+
+	if outputFormat == outputJSON {
+		return writeJSON(os.Stdout, info)
+	}
+	if info.Status == server.StatusQueued {
+		ui.Info("Queued run %s of %s", info.ID, info.Project)
+	} else {
+		ui.Info("Started run %s of %s", info.ID, info.Project)
+	}
+	ui.Info("Follow it with: curl -N %s/runs/%s/events", strings.TrimRight(addr, "/"), info.ID)
+	return nil
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/diff"
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diffOptions holds the flags of the diff command.
+type diffOptions struct {
+	project string
+	stat    bool
+}
+
+// newDiffCmd creates the `cortex diff <run-a> <run-b> [task]` command.
+func newDiffCmd() *cobra.Command {
+	var opts diffOptions
+
+	diffCmd := &cobra.Command{
+		Use:   "diff <run-a> <run-b> [task]",
+		Short: "Compare the task outputs and artifacts of two runs",
+		Long: `Compares two runs task by task: status changes, changes to each task's
+output, and the artifacts each task collected (files added, removed, or
+changed, with their content diffs). Each task's duration in both runs is
+shown alongside. Use it to see the concrete effect of a
+prompt change on what a workflow produces.
+
+Runs are given by ID, as <project>/<run-id>, or as "latest".`,
+		Args: cobra.RangeArgs(2, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			task := ""
+			if len(args) > 2 {
+				task = args[2]
+			}
+			return diffRuns(args[0], args[1], task, opts)
+		},
+	}
+
+	diffCmd.Flags().StringVar(&opts.project, "project", "", "Project name (default: current directory name)")
+	diffCmd.Flags().BoolVar(&opts.stat, "stat", false, "Only list what changed, without content diffs")
+	diffCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+
+	return diffCmd
+}
+
+// diffRun is one side of a comparison.
+type diffRun struct {
+	project, id, dir string
+	tasks            map[string]*state.TaskResult
+	order            []string // Task names in completion order
+}
+
+// loadDiffRun resolves a run given as <run-id>, <project>/<run-id>, or
+// "latest", and loads its task results.
+func loadDiffRun(ref, project string) (*diffRun, error) {
+	if p, id, ok := strings.Cut(ref, "/"); ok {
+		project, ref = p, id
+	}
+	project, err := resolveProject(project)
+	if err != nil {
+		return nil, err
+	}
+	runDir, project, runID, err := state.FindRunDir(project, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := state.ListTaskResults(runDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := state.LoadOutputs(runDir, results); err != nil {
+		return nil, err
+	}
+	run := &diffRun{project: project, id: runID, dir: runDir, tasks: make(map[string]*state.TaskResult)}
+	for i := range results {
+		run.tasks[results[i].TaskName] = &results[i]
+		run.order = append(run.order, results[i].TaskName)
+	}
+	return run, nil
+}
+
+func (r *diffRun) label() string {
+	return r.project + "/" + r.id
+}
+
+// artifacts returns the task's artifacts keyed by their path within the
+// task's artifacts directory.
+func (r *diffRun) artifacts(task string) map[string]string {
+	files := make(map[string]string)
+	result := r.tasks[task]
+	if result == nil {
+		return files
+	}
+	prefix := filepath.Join(state.ArtifactsDir, state.TaskFileName(task)) + string(filepath.Separator)
+	for _, rel := range result.Artifacts {
+		files[strings.TrimPrefix(filepath.FromSlash(rel), prefix)] = filepath.Join(r.dir, filepath.FromSlash(rel))
+	}
+	return files
+}
+
+// diffCounts totals the differences between two runs.
+type diffCounts struct {
+	tasks, added, removed, changed int
+}
+
+func diffRuns(refA, refB, task string, opts diffOptions) error {
+	if noColor {
+		ui.SetColorsEnabled(false)
+	}
+
+	a, err := loadDiffRun(refA, opts.project)
+	if err != nil {
+		ui.Error("%s", err)
+		return err
+	}
+	b, err := loadDiffRun(refB, opts.project)
+	if err != nil {
+		ui.Error("%s", err)
+		return err
+	}
+
+	// Tasks of b in completion order, then those only a has
+	names := append([]string(nil), b.order...)
+	for _, name := range a.order {
+		if b.tasks[name] == nil {
+			names = append(names, name)
+		}
+	}
+	if task != "" {
+		if a.tasks[task] == nil && b.tasks[task] == nil {
+			err := fmt.Errorf("task %q not found in either run", task)
+			ui.Error("%s", err)
+			return err
+		}
+		names = []string{task}
+	}
+
+	fmt.Printf("%sComparing%s %s %s→%s %s\n", ui.Bold, ui.Reset, a.label(), ui.Dim, ui.Reset, b.label())
+
+	var counts diffCounts
+	for _, name := range names {
+		if diffTask(a, b, name, opts.stat, &counts) {
+			counts.tasks++
+		}
+	}
+
+	fmt.Println()
+	if counts.tasks == 0 {
+		fmt.Println("No differences.")
+		return nil
+	}
+	label := "tasks"
+	if counts.tasks == 1 {
+		label = "task"
+	}
+	fmt.Printf("%d %s changed", counts.tasks, label)
+	if n := counts.added + counts.removed + counts.changed; n > 0 {
+		fmt.Printf("; artifacts: %d added, %d removed, %d changed", counts.added, counts.removed, counts.changed)
+	}
+	fmt.Println()
+	return nil
+}
+
+// diffTask prints the differences of a task between two runs and reports
+// whether there were any.
+func diffTask(a, b *diffRun, name string, stat bool, counts *diffCounts) bool {
+	ra, rb := a.tasks[name], b.tasks[name]
+	fmt.Println()
+	switch {
+	case ra == nil:
+		fmt.Printf("%s %s %s\n", ui.GreenText("+"), ui.BoldText(name), ui.DimText("only in "+b.label()))
+		return true
+	case rb == nil:
+		fmt.Printf("%s %s %s\n", ui.RedText("-"), ui.BoldText(name), ui.DimText("only in "+a.label()))
+		return true
+	}
+
+	var lines []string // Indented detail lines, printed under the task
+	if ra.Status != rb.Status {
+		lines = append(lines, fmt.Sprintf("status: %s → %s", statusText(ra.Status), statusText(rb.Status)))
+	}
+	if ra.Stdout != rb.Stdout {
+		lines = append(lines, "output changed")
+		if !stat {
+			lines = append(lines, unifiedLines(ra.Stdout, rb.Stdout)...)
+		}
+	}
+
+	filesA, filesB := a.artifacts(name), b.artifacts(name)
+	paths := make([]string, 0, len(filesA)+len(filesB))
+	for p := range filesA {
+		paths = append(paths, p)
+	}
+	for p := range filesB {
+		if _, ok := filesA[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		pa, inA := filesA[p]
+		pb, inB := filesB[p]
+		switch {
+		case !inA:
+			counts.added++
+			lines = append(lines, ui.GreenText("+ "+filepath.ToSlash(p)))
+		case !inB:
+			counts.removed++
+			lines = append(lines, ui.RedText("- "+filepath.ToSlash(p)))
+		default:
+			fileLines, changed := diffFiles(pa, pb, stat)
+			if !changed {
+				continue
+			}
+			counts.changed++
+			lines = append(lines, ui.YellowText("~ "+filepath.ToSlash(p)))
+			lines = append(lines, fileLines...)
+		}
+	}
+
+	durations := ui.DimText(fmt.Sprintf("(%s → %s)", ui.FormatDuration(ra.Elapsed()), ui.FormatDuration(rb.Elapsed())))
+	if len(lines) == 0 {
+		fmt.Printf("  %s %s %s\n", ui.BoldText(name), ui.DimText("unchanged"), durations)
+		return false
+	}
+	fmt.Printf("%s %s %s\n", ui.YellowText("~"), ui.BoldText(name), durations)
+	for _, line := range lines {
+		fmt.Printf("    %s\n", line)
+	}
+	return true
+}
+
+// diffFiles compares two artifact files, returning the lines of their diff
+// (none with stat) and whether they differ.
+func diffFiles(pathA, pathB string, stat bool) ([]string, bool) {
+	dataA, errA := os.ReadFile(pathA)
+	dataB, errB := os.ReadFile(pathB)
+	if errA != nil {
+		return []string{ui.DimText("  " + errA.Error())}, true
+	}
+	if errB != nil {
+		return []string{ui.DimText("  " + errB.Error())}, true
+	}
+	if bytes.Equal(dataA, dataB) {
+		return nil, false
+	}
+	if stat {
+		return nil, true
+	}
+	if !isText(dataA) || !isText(dataB) {
+		return []string{ui.DimText(fmt.Sprintf("  binary files differ (%d → %d bytes)", len(dataA), len(dataB)))}, true
+	}
+	return unifiedLines(string(dataA), string(dataB)), true
+}
+
+// statusText returns a task status in its color.
+func statusText(status string) string {
+	return ui.Colorize(ui.StatusColor(ui.Status(status)), status)
+}
+
+// isText reports whether data looks like text rather than binary content.
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+// unifiedLines returns the colored unified diff of two texts, indented by
+// two spaces.
+func unifiedLines(textA, textB string) []string {
+	script, ok := diff.Lines(diff.SplitLines(textA), diff.SplitLines(textB))
+	if !ok {
+		return []string{ui.DimText("  too large to diff")}
+	}
+
+	var lines []string
+	for _, h := range diff.Hunks(script, diffContext) {
+		lines = append(lines, "  "+ui.CyanText(h.Header()))
+		for _, l := range h.Lines {
+			switch l.Op {
+			case diff.Delete:
+				lines = append(lines, "  "+ui.RedText("-"+l.Text))
+			case diff.Insert:
+				lines = append(lines, "  "+ui.GreenText("+"+l.Text))
+			default:
+				lines = append(lines, "   "+l.Text)
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/paths"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// builtinToolExecutables lists the CLIs the built-in agent tools run.
+var builtinToolExecutables = []struct{ tool, executable string }{
+	{"claude-code", "claude"},
+	{"opencode", "opencode"},
+	{"aider", "aider"},
+}
+
+// newDoctorCmd creates the `cortex doctor` command.
+func newDoctorCmd() *cobra.Command {
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check where cortex keeps its files and which agent tools are installed",
+		Long: `Shows the config, data, and state directories in use and reports files
+split between ~/.cortex and the XDG base directories. Also shows which agent
+tools, built-in and declared under tools: in the global config, have their
+CLI installed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor()
+		},
+	}
+	doctorCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return doctorCmd
+}
+
+func runDoctor() error {
+	if noColor {
+		ui.SetColorsEnabled(false)
+	}
+
+	layout, err := paths.Current()
+	if err != nil {
+		ui.Error("%s", err)
+		return err
+	}
+
+	name := "legacy (~/.cortex)"
+	if layout.XDG {
+		name = "XDG base directories"
+	}
+	fmt.Printf("%sLayout:%s %s\n", ui.Bold, ui.Reset, name)
+	fmt.Printf("  %sconfig%s  %s\n", ui.Dim, ui.Reset, layout.Config)
+	fmt.Printf("  %sdata%s    %s\n", ui.Dim, ui.Reset, layout.Data)
+	fmt.Printf("  %sstate%s   %s\n", ui.Dim, ui.Reset, layout.State)
+	fmt.Printf("  %scache%s   %s\n\n", ui.Dim, ui.Reset, layout.Cache)
+	printTools()
+
+	problems, err := paths.Check()
+	if err != nil {
+		ui.Error("%s", err)
+		return err
+	}
+	if len(problems) == 0 {
+		ui.Success("No problems found")
+		return nil
+	}
+	for _, p := range problems {
+		ui.Warning("%s", p.Message)
+		fmt.Printf("  %sHint: %s%s\n", ui.Dim, p.Hint, ui.Reset)
+	}
+	return fmt.Errorf("%d problem(s) found", len(problems))
+}
+
+// printTools shows where the CLI of each agent tool was found on PATH.
+// A missing CLI is only a problem for workflows that use the tool, so it is
+// not reported as one.
+func printTools() {
+	type toolCLI struct{ tool, executable, kind string }
+	var tools []toolCLI
+	for _, t := range builtinToolExecutables {
+		tools = append(tools, toolCLI{t.tool, t.executable, "built-in"})
+	}
+	if globalCfg, err := config.LoadGlobalConfig(); err == nil {
+		names := make([]string, 0, len(globalCfg.Tools))
+		for name := range globalCfg.Tools {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			tools = append(tools, toolCLI{name, globalCfg.Tools[name].Executable, "custom"})
+		}
+	}
+
+	width := 0
+	for _, t := range tools {
+		width = max(width, len(t.tool))
+	}
+	fmt.Printf("%sTools:%s\n", ui.Bold, ui.Reset)
+	for _, t := range tools {
+		found := ui.Colorize(ui.Dim, "not found ("+t.executable+")")
+		if path, err := exec.LookPath(t.executable); err == nil {
+			found = path
+		}
+		fmt.Printf("  %-*s  %s %s\n", width, t.tool, found, ui.Colorize(ui.Dim, "("+t.kind+")"))
+	}
+	fmt.Println()
+}
+
+// migrateLegacyDir moves ~/.cortex into the XDG directories when they are in
+// use. Notices go to stderr, so they never mix with JSON output.
+func migrateLegacyDir() {
+	moved, err := paths.Migrate()
+	for _, m := range moved {
+		fmt.Fprintf(os.Stderr, "Moved %s to %s\n", m.From, m.To)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s (run 'cortex doctor' for details)\n", err)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// estimateRuns is how many recent runs `cortex validate` estimates from.
+const estimateRuns = 10
+
+// runEstimate is the expected duration and cost of a workflow, based on the
+// project's past runs.
+type runEstimate struct {
+	Runs        int           // Past runs the estimate is based on
+	Sequential  time.Duration // With one task at a time
+	Parallel    time.Duration // With max_parallel tasks at a time
+	MaxParallel int
+	Unlimited   time.Duration // With every ready task running at once
+	CostUSD     float64
+	Approximate []string // Tasks estimated from other tasks using their model
+	Missing     []string // Tasks with no history, counted as taking no time
+}
+
+// estimateRun estimates a plan's duration and cost from history.
+func estimateRun(plan *planner.ExecutionPlan, history *state.History, maxParallel int) runEstimate {
+	est := runEstimate{Runs: history.Runs, MaxParallel: maxParallel}
+
+	durations := make(map[string]time.Duration, len(plan.Tasks))
+	for _, t := range plan.Tasks {
+		e, exact, ok := history.Estimate(t.Name, t.Model)
+		switch {
+		case !ok:
+			est.Missing = append(est.Missing, t.Name)
+		case !exact:
+			est.Approximate = append(est.Approximate, t.Name)
+		}
+		durations[t.Name] = e.Duration
+		est.Sequential += e.Duration
+		est.CostUSD += e.CostUSD
+	}
+
+	est.Parallel = simulateRun(plan, durations, maxParallel)
+	est.Unlimited = simulateRun(plan, durations, len(plan.Tasks))
+	return est
+}
+
+// simulateRun returns how long the plan takes when each task runs for its
+// duration and up to workers tasks run at once. Like the executor, a task
+// starts as soon as its dependencies finish and a worker is free, by
+// priority and then in plan order.
+func simulateRun(plan *planner.ExecutionPlan, durations map[string]time.Duration, workers int) time.Duration {
+	if workers < 1 {
+		workers = 1
+	}
+
+	pending := make(map[string]int, len(plan.Tasks))
+	ready := planner.NewReadyQueue(plan)
+	for _, t := range plan.Tasks {
+		pending[t.Name] = len(plan.DAG.Edges[t.Name])
+		if pending[t.Name] == 0 {
+			ready.Push(t.Name)
+		}
+	}
+
+	type running struct {
+		name   string
+		finish time.Duration
+	}
+	var active []running
+	var now time.Duration
+
+	for ready.Len() > 0 || len(active) > 0 {
+		for ready.Len() > 0 && len(active) < workers {
+			name := ready.Pop()
+			active = append(active, running{name, now + durations[name]})
+		}
+
+		// Advance to the first task to finish
+		first := 0
+		for i, r := range active {
+			if r.finish < active[first].finish {
+				first = i
+			}
+		}
+		done := active[first]
+		active = append(active[:first], active[first+1:]...)
+		now = done.finish
+
+		for _, dependent := range plan.DAG.ReverseEdges[done.name] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready.Push(dependent)
+			}
+		}
+	}
+
+	return now
+}
+
+// validateMaxParallel returns the max_parallel a run of cfg would use, from
+// the Cortexfile or the global config.
+func validateMaxParallel(cfg *config.AgentflowConfig) int {
+	globalCfg, err := config.LoadGlobalConfig()
+	if err != nil {
+		globalCfg = &config.GlobalConfig{Settings: config.DefaultSettings()}
+	}
+	return config.MergeConfigs(globalCfg, cfg, nil).Settings.MaxParallel
+}
+
+// estimateOutput converts an estimate for `cortex validate --output json`.
+func estimateOutput(est runEstimate) *EstimateOutput {
+	if est.Runs == 0 {
+		return nil
+	}
+	return &EstimateOutput{
+		Runs:              est.Runs,
+		SequentialSeconds: est.Sequential.Seconds(),
+		ParallelSeconds:   est.Parallel.Seconds(),
+		MaxParallel:       est.MaxParallel,
+		UnlimitedSeconds:  est.Unlimited.Seconds(),
+		CostUSD:           est.CostUSD,
+		Approximate:       est.Approximate,
+		NoHistory:         est.Missing,
+	}
+}
+
+// printEstimate prints the estimate shown by `cortex validate`.
+func printEstimate(est runEstimate) {
+	if est.Runs == 0 {
+		fmt.Printf("  %sEstimate:%s no past runs of this project yet\n\n", ui.Dim, ui.Reset)
+		return
+	}
+
+	runs := "runs"
+	if est.Runs == 1 {
+		runs = "run"
+	}
+	line := func(label, value string) {
+		fmt.Printf("  %s%-16s%s%s\n", ui.Dim, label+":", ui.Reset, value)
+	}
+
+	fmt.Printf("  %sEstimate%s %s(from last %d %s)%s\n", ui.Bold, ui.Reset, ui.Dim, est.Runs, runs, ui.Reset)
+	line("Sequential", ui.FormatDuration(est.Sequential))
+	line(fmt.Sprintf("Parallel (×%d)", est.MaxParallel), ui.FormatDuration(est.Parallel))
+	// Only suggest more workers when they would save a tenth of the time
+	if est.Unlimited < est.Parallel*9/10 {
+		line("No limit", ui.FormatDuration(est.Unlimited)+" "+ui.Dim+"(raise max_parallel to get closer)"+ui.Reset)
+	}
+	if est.CostUSD > 0 {
+		line("Cost", fmt.Sprintf("$%.2f", est.CostUSD))
+	}
+	if len(est.Approximate) > 0 {
+		line("From model avg", strings.Join(est.Approximate, ", "))
+	}
+	if len(est.Missing) > 0 {
+		line("No history", strings.Join(est.Missing, ", "))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/runtime"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// Values of the hidden --inject-failure and --inject-latency flags of
+// `cortex run`, which make adapters misbehave on purpose.
+var (
+	injectFailures  []string
+	injectLatencies []string
+)
+
+// injectedFaults parses --inject-failure and --inject-latency and warns
+// about the faults a run will simulate, and about tasks of the plan they
+// name that don't exist.
+func injectedFaults(plan *planner.ExecutionPlan) ([]runtime.Fault, error) {
+	var faults []runtime.Fault
+	for _, value := range injectFailures {
+		fault, err := parseFault(value, true)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --inject-failure %q: %w", value, err)
+		}
+		faults = append(faults, fault)
+	}
+	for _, value := range injectLatencies {
+		fault, err := parseFault(value, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --inject-latency %q: %w", value, err)
+		}
+		faults = append(faults, fault)
+	}
+
+	// A fault of a fan-out task applies to each of its instances
+	var expanded []runtime.Fault
+	for _, fault := range faults {
+		instances, ok := plan.Groups[fault.Task]
+		if !ok {
+			if fault.Task != "" && !planHasTask(plan, fault.Task) {
+				ui.Warning("Fault injection: no task %q in this workflow", fault.Task)
+				continue
+			}
+			instances = []string{fault.Task}
+		}
+		ui.Warning("Fault injection: %s", fault)
+		slog.Warn("fault injected", "fault", fault.String())
+		for _, name := range instances {
+			fault.Task = name
+			expanded = append(expanded, fault)
+		}
+	}
+	return expanded, nil
+}
+
+// parseFault parses a comma-separated list of key=value pairs: task=NAME
+// and, for a failure, exit=CODE, or for latency, delay=DURATION. A bare
+// duration is a delay of every task; a bare name, a failure of that task.
+func parseFault(value string, fail bool) (runtime.Fault, error) {
+	fault := runtime.Fault{Fail: fail}
+	for _, part := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			key, val = "", key
+		}
+		switch {
+		case key == "task" || (key == "" && fail):
+			if val == "" {
+				return fault, fmt.Errorf("task name is empty")
+			}
+			fault.Task = val
+		case key == "exit" && fail:
+			code, err := strconv.Atoi(val)
+			if err != nil || code <= 0 || code > 255 {
+				return fault, fmt.Errorf("exit code must be between 1 and 255")
+			}
+			fault.ExitCode = code
+		case (key == "delay" || key == "") && !fail:
+			d, err := time.ParseDuration(val)
+			if err != nil || d <= 0 {
+				return fault, fmt.Errorf("delay must be a positive duration like 30s")
+			}
+			fault.Latency = d
+		default:
+			return fault, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	if fail && fault.Task == "" {
+		return fault, fmt.Errorf("use task=NAME to choose the task that fails")
+	}
+	if !fail && fault.Latency == 0 {
+		return fault, fmt.Errorf("use delay=DURATION or a bare duration like 30s")
+	}
+	return fault, nil
+}
+
+// planHasTask reports whether a plan has a task of the given name.
+func planHasTask(plan *planner.ExecutionPlan, name string) bool {
+	return slices.ContainsFunc(plan.Tasks, func(t planner.ExecutionTask) bool { return t.Name == name })
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// showMasterGraph renders the workflows of a MasterCortex, and with expand
+// the tasks of each workflow too. The MasterCortex is the first -f file, or
+// the one in the current directory.
+func showMasterGraph(format string, expand bool) error {
+	var masterPath string
+	if len(configFiles) > 0 {
+		masterPath = configFiles[0]
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			ui.Error("Failed to get working directory: %s", err)
+			return err
+		}
+		masterPath, err = config.FindMasterCortex(cwd)
+		if err != nil {
+			ui.Error("No MasterCortex.yml found. Create one with: cortex init --master")
+			return err
+		}
+	}
+
+	masterCfg, err := config.LoadMasterConfig(masterPath)
+	if err != nil {
+		ui.Error("Failed to load master config: %s", err)
+		return err
+	}
+	if err := config.ValidateMasterConfig(masterCfg); err != nil {
+		ui.Error("Invalid master config: %s", err)
+		return err
+	}
+
+	baseDir := filepath.Dir(masterPath)
+	entries, err := config.ResolveWorkflowPaths(masterCfg, baseDir)
+	if err != nil {
+		ui.Error("Failed to resolve workflow paths: %s", err)
+		return err
+	}
+
+	workflows := make([]planner.MasterWorkflow, 0, len(entries))
+	for _, w := range entries {
+		workflow := planner.MasterWorkflow{Name: w.Name, Path: w.Path, Needs: w.Needs}
+		if rel, err := filepath.Rel(baseDir, w.Path); err == nil {
+			workflow.Path = rel
+		}
+		if expand {
+			if workflow.Plan, err = workflowPlan(w.Path); err != nil {
+				ui.Error("Workflow %s: %s", w.Name, err)
+				return err
+			}
+		}
+		workflows = append(workflows, workflow)
+	}
+
+	graphFormat := planner.FormatASCII
+	if format == "dot" {
+		graphFormat = planner.FormatDOT
+	}
+	fmt.Print(planner.RenderMasterGraph(masterCfg.Name, workflows, graphFormat))
+	return nil
+}
+
+// workflowPlan loads, validates, and plans the Cortexfile of a workflow.
+func workflowPlan(path string) (*planner.ExecutionPlan, error) {
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := config.ValidateWithFile(cfg, path); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	plan, err := planner.BuildPlan(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build plan: %w", err)
+	}
+	return plan, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// newLintCmd creates the `cortex lint` command.
+func newLintCmd() *cobra.Command {
+	var (
+		strict          bool
+		maxPromptLength int
+	)
+
+	lintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Warn about likely mistakes in the Cortexfile",
+		Long: `Validates the Cortexfile, then warns about what validation allows but is
+likely a mistake: ` + strings.Join(config.LintRules, ", ") + `.
+
+Warnings don't fail the command unless --strict is set. Use --output json
+in CI.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLint(strict, maxPromptLength)
+		},
+	}
+
+	lintCmd.Flags().StringArrayVarP(&configFiles, "file", "f", nil, "Path to Cortexfile (default: auto-detect)")
+	addOutputFlags(lintCmd)
+	lintCmd.Flags().BoolVar(&strict, "strict", false, "Fail if there are warnings")
+	lintCmd.Flags().IntVar(&maxPromptLength, "max-prompt-length", config.DefaultMaxPromptLength, "Warn about prompts longer than this many characters")
+	lintCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+
+	return lintCmd
+}
+
+func runLint(strict bool, maxPromptLength int) error {
+	if err := checkOutputFormat(); err != nil {
+		return err
+	}
+	if noColor {
+		ui.SetColorsEnabled(false)
+	}
+
+	output := LintOutput{Warnings: []LintIssue{}}
+	if outputFormat == outputJSON {
+		stdout, restore := suppressUI()
+		defer func() {
+			restore()
+			_ = writeJSON(stdout, output)
+		}()
+	}
+
+	ui.PrintCompactBanner(version)
+
+	cfg, configPath, err := loadConfig()
+	output.ConfigFile = configPath
+	if err != nil {
+		ui.Error("Validation failed:\n%s", err)
+		output.Errors = validationIssues(err)
+		return err
+	}
+	output.Valid = true
+
+	// Agents without a model get the global default one, if it is set
+	opts := config.LintOptions{MaxPromptLength: maxPromptLength}
+	if globalCfg, err := config.LoadGlobalConfig(); err == nil {
+		opts.DefaultModel = globalCfg.Defaults.Model
+	}
+
+	warnings := config.Lint(cfg, configPath, opts)
+	for _, w := range warnings {
+		output.Warnings = append(output.Warnings, LintIssue(w))
+		location := w.File
+		if w.Line > 0 {
+			location += fmt.Sprintf(":%d", w.Line)
+		}
+		ui.Warning("%s: %s %s", location, w.Message, ui.DimText("["+w.Rule+"]"))
+		fmt.Printf("  %sHint: %s%s\n", ui.Dim, w.Hint, ui.Reset)
+	}
+
+	if len(warnings) == 0 {
+		ui.Success("No warnings")
+		return nil
+	}
+	fmt.Println()
+	ui.Info("%d warning(s)", len(warnings))
+	if strict {
+		return fmt.Errorf("%d lint warning(s)", len(warnings))
+	}
+	return nil
+}
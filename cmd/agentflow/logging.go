@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/observability"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// closeLog closes the log file once the command has finished.
+var closeLog = func() error { return nil }
+
+// setupLogging sets up the operational log from the log section of
+// config.yml, overridden by --log-level, --log-file, and --log-format. A log
+// that can't be set up is disabled with a warning rather than failing the
+// command; invalid flags are errors.
+func setupLogging(cmd *cobra.Command) error {
+	var cfg config.LogConfig
+	if globalCfg, err := config.LoadGlobalConfig(); err == nil {
+		cfg = globalCfg.Log
+	}
+	if cmd.Flags().Changed("log-level") || cfg.Level == "" {
+		cfg.Level = logLevel
+	}
+	if cmd.Flags().Changed("log-file") {
+		cfg.File = logFile
+	}
+	if cmd.Flags().Changed("log-format") || cfg.Format == "" {
+		cfg.Format = logFormat
+	}
+
+	level, err := observability.ParseLevel(cfg.Level)
+	if err != nil {
+		observability.Disable()
+		return err
+	}
+	closeFn, err := observability.Setup(observability.Config{Level: level, Format: cfg.Format, File: cfg.File})
+	if err != nil {
+		observability.Disable()
+		if cmd.Flags().Changed("log-format") {
+			return err
+		}
+		ui.Warning("Logging disabled: %s", err)
+		return nil
+	}
+	closeLog = closeFn
+
+	slog.Debug("command started", "command", cmd.CommandPath(), "version", version)
+	return nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// followInterval is how often `cortex logs --follow` polls the run directory.
+const followInterval = 500 * time.Millisecond
+
+// logsOptions holds the flags of the logs command.
+type logsOptions struct {
+	project    string
+	raw        bool
+	stderr     bool
+	showPrompt bool
+	follow     bool
+}
+
+// newLogsCmd creates the `cortex logs <run-id> [task]` command.
+func newLogsCmd() *cobra.Command {
+	var opts logsOptions
+
+	logsCmd := &cobra.Command{
+		Use:   "logs <run-id> [task]",
+		Short: "Show saved output of a run's tasks",
+		Long: `Prints the saved output of a run from ~/.cortex/sessions/<project>/run-<id>/.
+Use "latest" as the run ID for the most recent run. Without a task, all tasks
+of the run are shown in completion order.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			task := ""
+			if len(args) > 1 {
+				task = args[1]
+			}
+			return showLogs(args[0], task, opts)
+		},
+	}
+
+	logsCmd.Flags().StringVar(&opts.project, "project", "", "Project name (default: current directory name)")
+	logsCmd.Flags().BoolVar(&opts.raw, "raw", false, "Print output as saved, without headers or formatting")
+	logsCmd.Flags().BoolVar(&opts.stderr, "stderr", false, "Show stderr instead of stdout")
+	logsCmd.Flags().BoolVar(&opts.showPrompt, "prompt", false, "Also show the expanded prompt")
+	logsCmd.Flags().BoolVarP(&opts.follow, "follow", "F", false, "Keep printing task results until the run completes")
+	logsCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+
+	return logsCmd
+}
+
+func showLogs(runID, task string, opts logsOptions) error {
+	if noColor || opts.raw {
+		ui.SetColorsEnabled(false)
+	}
+
+	project, err := resolveProject(opts.project)
+	if err != nil {
+		return err
+	}
+
+	runDir, runID, err := state.ResolveRunDir(project, runID)
+	if err != nil {
+		ui.Error("%s", err)
+		return err
+	}
+
+	if !opts.raw {
+		fmt.Printf("%s%s%s %s%s%s\n", ui.Bold, project, ui.Reset, ui.Dim, runID, ui.Reset)
+		fmt.Printf("%s─────────────────────────────────────────────────%s\n", ui.Dim, ui.Reset)
+	}
+
+	if task != "" {
+		return showTaskLog(runDir, task, opts)
+	}
+
+	printed := make(map[string]bool)
+	for {
+		// Check completion before listing so results saved just before
+		// run.json are not missed on the final pass
+		complete := state.IsRunComplete(runDir)
+
+		results, err := state.ListTaskResults(runDir)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			if printed[r.TaskName] {
+				continue
+			}
+			printed[r.TaskName] = true
+			if err := r.LoadOutput(runDir); err != nil {
+				return err
+			}
+			printTaskLog(&r, opts)
+		}
+
+		if complete || !opts.follow {
+			break
+		}
+		time.Sleep(followInterval)
+	}
+
+	if len(printed) == 0 && !opts.raw {
+		fmt.Printf("%sNo task results saved for this run.%s\n", ui.Dim, ui.Reset)
+	}
+	return nil
+}
+
+// resolveProject returns the --project value, defaulting to the name of the
+// current directory like `cortex run` does.
+func resolveProject(project string) (string, error) {
+	if project != "" {
+		return project, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return filepath.Base(cwd), nil
+}
+
+// showTaskLog prints a single task, waiting for it to finish with --follow.
+func showTaskLog(runDir, task string, opts logsOptions) error {
+	waiting := false
+	for {
+		complete := state.IsRunComplete(runDir)
+
+		result, err := state.LoadTaskResultFromDir(runDir, task)
+		if err == nil {
+			printTaskLog(result, opts)
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+
+		if complete || !opts.follow {
+			err := fmt.Errorf("no result for task %q in this run", task)
+			ui.Error("%s", err)
+			return err
+		}
+
+		if !waiting && !opts.raw {
+			fmt.Printf("%sWaiting for %s to finish...%s\n", ui.Dim, task, ui.Reset)
+			waiting = true
+		}
+		time.Sleep(followInterval)
+	}
+}
+
+// printTaskLog prints one task result. In raw mode only the selected stream
+// is written, exactly as saved.
+func printTaskLog(r *state.TaskResult, opts logsOptions) {
+	output := r.Stdout
+	if opts.stderr {
+		output = r.Stderr
+	}
+
+	if opts.raw {
+		if opts.showPrompt {
+			fmt.Println(r.Prompt)
+		}
+		fmt.Print(output)
+		if output != "" && !strings.HasSuffix(output, "\n") {
+			fmt.Println()
+		}
+		return
+	}
+
+	statusIcon := ui.StatusIcon(ui.StatusSuccess)
+	switch r.Status {
+	case state.StatusFailed:
+		statusIcon = ui.StatusIcon(ui.StatusFailed)
+	case state.StatusSkipped:
+		statusIcon = ui.StatusIcon(ui.StatusSkipped)
+	case state.StatusCancelled:
+		statusIcon = ui.StatusIcon(ui.StatusCancelled)
+	}
+
+	fmt.Printf("\n%s %s%s%s %s(%s, %s)%s\n", statusIcon, ui.Bold, r.TaskName, ui.Reset, ui.Dim, r.Tool, ui.FormatDuration(r.Elapsed()), ui.Reset)
+	if r.SkipReason != "" {
+		fmt.Printf("  %sSkipped:%s %s\n", ui.Dim, ui.Reset, r.SkipReason)
+	}
+	if r.ExitCode != 0 {
+		fmt.Printf("  %sExit code:%s %d\n", ui.Dim, ui.Reset, r.ExitCode)
+	}
+	if r.TokenUsage.TotalTokens > 0 {
+		fmt.Printf("  %sTokens:%s %s\n", ui.Dim, ui.Reset, ui.FormatTokenCount(r.TokenUsage.TotalTokens))
+	}
+
+	if opts.showPrompt && r.Prompt != "" {
+		fmt.Printf("\n  %sPrompt:%s\n", ui.Dim, ui.Reset)
+		printIndented(r.Prompt, ui.Dim)
+	}
+
+	label := "Output"
+	if opts.stderr {
+		label = "Stderr"
+	}
+	if strings.TrimSpace(output) == "" {
+		fmt.Printf("\n  %s%s: (empty)%s\n", ui.Dim, label, ui.Reset)
+		return
+	}
+	fmt.Printf("\n  %s%s:%s\n", ui.Dim, label, ui.Reset)
+	if !opts.stderr {
+		output = ui.StripMarkdown(output)
+	}
+	printIndented(output, "")
+}
+
+// printIndented prints text indented under a section label.
+func printIndented(text, color string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if color != "" {
+			fmt.Printf("    %s%s%s\n", color, line, ui.Reset)
+		} else {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+}
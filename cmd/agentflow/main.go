@@ -3,26 +3,43 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
 	"os"
 	"os/signal"
+	"os/user"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/events"
 	"github.com/adityaraj/agentflow/internal/observability"
+	"github.com/adityaraj/agentflow/internal/paths"
 	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/proc"
 	"github.com/adityaraj/agentflow/internal/runtime"
+	"github.com/adityaraj/agentflow/internal/runtime/adapters/aider"
+	"github.com/adityaraj/agentflow/internal/runtime/adapters/api"
 	"github.com/adityaraj/agentflow/internal/runtime/adapters/claude"
+	"github.com/adityaraj/agentflow/internal/runtime/adapters/custom"
+	"github.com/adityaraj/agentflow/internal/runtime/adapters/git"
+	"github.com/adityaraj/agentflow/internal/runtime/adapters/github"
+	"github.com/adityaraj/agentflow/internal/runtime/adapters/httpreq"
+	"github.com/adityaraj/agentflow/internal/runtime/adapters/notify"
 	"github.com/adityaraj/agentflow/internal/runtime/adapters/opencode"
+	"github.com/adityaraj/agentflow/internal/runtime/adapters/script"
 	"github.com/adityaraj/agentflow/internal/runtime/adapters/shell"
+	"github.com/adityaraj/agentflow/internal/runtime/adapters/wait"
 	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/telemetry"
 	"github.com/adityaraj/agentflow/internal/ui"
 	"github.com/adityaraj/agentflow/internal/webhook"
 )
@@ -48,9 +65,36 @@ var (
 	logFormat   string
 	logLevel    string
 	logFile     string
+	incremental bool
+	varFlags    []string
+	varFiles    []string
+
+	// noDefaultSystemPrompt drops the formatting rules claude-code tasks
+	// without a system prompt get
+	noDefaultSystemPrompt bool
+
+	// afterRun is the --after run whose task outputs fill {{previous.task}}
+	afterRun string
+
+	outputFormat string
+
+	// workDir is the directory set with -C, which every command runs in as
+	// if cortex was started there
+	workDir string
+
+	// noProgress hides the executor's spinner and progress bar, e.g. when
+	// several workflows run at once and would share the status line
+	noProgress bool
+
+	// plainOutput turns off colors, spinners, and box drawing, as when
+	// stdout is not a terminal
+	plainOutput bool
 )
 
 func main() {
+	// Nothing is logged until the command's PersistentPreRunE sets up the log
+	observability.Disable()
+
 	versionStr := version
 	if buildTime != "unknown" {
 		versionStr = fmt.Sprintf("%s (built %s)", version, buildTime)
@@ -61,7 +105,24 @@ func main() {
 		Short:   "AI agent orchestrator",
 		Long:    "Cortex orchestrates AI agent workflows defined in YAML.",
 		Version: versionStr,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Change directory first, so -f paths, Cortexfile discovery, project
+			// names, and agent workdirs all resolve from it
+			if workDir != "" {
+				if err := os.Chdir(workDir); err != nil {
+					return err
+				}
+			}
+			migrateLegacyDir()
+			applyGlobalTheme()
+			return setupLogging(cmd)
+		},
 	}
+	rootCmd.PersistentFlags().StringVarP(&workDir, "directory", "C", "", "Run as if cortex was started in this directory")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log level: debug, info, warn, error")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Log file path, or - for stderr (default: daily file in the logs directory)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "json", "Log format: json or text")
+	rootCmd.PersistentFlags().BoolVar(&plainOutput, "plain", false, "Plain output: no colors, spinners, or box drawing")
 
 	// Run command
 	runCmd := &cobra.Command{
@@ -82,9 +143,16 @@ func main() {
 	runCmd.Flags().IntVar(&maxParallel, "max-parallel", 0, "Max concurrent tasks (0 = use config default)")
 	runCmd.Flags().BoolVar(&fullOutput, "full", false, "Show full output (default: summary only)")
 	runCmd.Flags().BoolVarP(&interactive, "interactive", "i", true, "Enable interactive mode with Ctrl+O toggle")
-	runCmd.Flags().StringVar(&logFormat, "log-format", "text", "Log format: text or json")
-	runCmd.Flags().StringVar(&logLevel, "log-level", "info", "Log level: debug, info, warn, error")
-	runCmd.Flags().StringVar(&logFile, "log-file", "", "Log file path (default: stderr)")
+	runCmd.Flags().BoolVar(&incremental, "incremental", false, "Skip tasks whose declared outputs are newer than their inputs")
+	runCmd.Flags().BoolVar(&noDefaultSystemPrompt, "no-default-system-prompt", false, "Don't give claude-code tasks without a system prompt the built-in formatting rules")
+	runCmd.Flags().StringArrayVar(&varFlags, "var", nil, "Set a workflow variable (name=value, repeatable)")
+	runCmd.Flags().StringArrayVar(&varFiles, "var-file", nil, "Load workflow variables from a YAML/JSON file (repeatable)")
+	runCmd.Flags().StringVar(&afterRun, "after", "", "Use the task outputs of an earlier run as {{previous.task}} (run ID, project/run-id, or latest)")
+	addOutputFlags(runCmd)
+	runCmd.Flags().StringArrayVar(&injectFailures, "inject-failure", nil, "Make a task fail without running it: task=NAME[,exit=CODE] (repeatable)")
+	runCmd.Flags().StringArrayVar(&injectLatencies, "inject-latency", nil, "Delay tasks before they run: DURATION or task=NAME,delay=DURATION (repeatable)")
+	_ = runCmd.Flags().MarkHidden("inject-failure")
+	_ = runCmd.Flags().MarkHidden("inject-latency")
 
 	// Validate command
 	validateCmd := &cobra.Command{
@@ -96,6 +164,11 @@ func main() {
 
 	var validateFile string
 	validateCmd.Flags().StringVarP(&validateFile, "file", "f", "", "Path to Cortexfile (default: auto-detect)")
+	addOutputFlags(validateCmd)
+	validateCmd.Flags().StringArrayVar(&varFlags, "var", nil, "Set a workflow variable (name=value, repeatable)")
+	validateCmd.Flags().StringArrayVar(&varFiles, "var-file", nil, "Load workflow variables from a YAML/JSON file (repeatable)")
+	validateCmd.Flags().StringVar(&afterRun, "after", "", "Use the task outputs of an earlier run as {{previous.task}} (run ID, project/run-id, or latest)")
+	validateCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "List the files matched by prompt_file and inputs")
 
 	// Sessions command
 	sessionsCmd := &cobra.Command{
@@ -112,6 +185,14 @@ func main() {
 	sessionsCmd.Flags().StringVar(&sessionProject, "project", "", "Filter by project name")
 	sessionsCmd.Flags().IntVar(&sessionLimit, "limit", 10, "Maximum number of sessions to show")
 	sessionsCmd.Flags().BoolVar(&sessionFailed, "failed", false, "Show only failed sessions")
+	addOutputFlags(sessionsCmd)
+	sessionsCmd.AddCommand(newSessionsShowCmd())
+	sessionsCmd.AddCommand(newDiffCmd())
+	sessionsCmd.AddCommand(newSessionsCleanCmd())
+	sessionsCmd.AddCommand(newSessionsPruneCmd())
+	sessionsCmd.AddCommand(newSessionsExportCmd())
+	sessionsCmd.AddCommand(newSessionsImportCmd())
+	sessionsCmd.AddCommand(newSessionsSearchCmd())
 
 	// Init command - create template files
 	initCmd := &cobra.Command{
@@ -143,6 +224,9 @@ func main() {
 	dryRunCmd.Flags().StringArrayVarP(&configFiles, "file", "f", nil, "Path to Cortexfile(s)")
 	dryRunCmd.Flags().BoolVar(&dryRunJSON, "json", false, "Output in JSON format")
 	dryRunCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	dryRunCmd.Flags().StringArrayVar(&varFlags, "var", nil, "Set a workflow variable (name=value, repeatable)")
+	dryRunCmd.Flags().StringArrayVar(&varFiles, "var-file", nil, "Load workflow variables from a YAML/JSON file (repeatable)")
+	dryRunCmd.Flags().StringVar(&afterRun, "after", "", "Use the task outputs of an earlier run as {{previous.task}} (run ID, project/run-id, or latest)")
 
 	// Master command - run MasterCortex.yml
 	masterCmd := &cobra.Command{
@@ -166,8 +250,10 @@ func main() {
 	graphCmd := &cobra.Command{
 		Use:   "graph",
 		Short: "Visualize the task execution graph",
-		Long:  "Displays the task dependency graph as ASCII art or Graphviz DOT format",
-		RunE:  showGraph,
+		Long: `Displays the task dependency graph as ASCII art or Graphviz DOT format.
+With --master, displays the workflows of a MasterCortex.yml instead, and with
+--expand each workflow's tasks too, for one picture of the whole orchestration.`,
+		RunE: showGraph,
 	}
 
 	var graphFormat string
@@ -175,6 +261,8 @@ func main() {
 	graphCmd.Flags().StringArrayVarP(&configFiles, "file", "f", nil, "Path to Cortexfile(s)")
 	graphCmd.Flags().StringVar(&graphFormat, "format", "ascii", "Output format: ascii or dot")
 	graphCmd.Flags().BoolVar(&graphCompact, "compact", false, "Show compact single-line representation")
+	graphCmd.Flags().Bool("master", false, "Show the workflows of a MasterCortex.yml (-f or auto-detect)")
+	graphCmd.Flags().Bool("expand", false, "With --master, show each workflow's tasks too")
 	graphCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
 
 	rootCmd.AddCommand(runCmd)
@@ -184,23 +272,49 @@ func main() {
 	rootCmd.AddCommand(dryRunCmd)
 	rootCmd.AddCommand(masterCmd)
 	rootCmd.AddCommand(graphCmd)
-
-	if err := rootCmd.Execute(); err != nil {
+	rootCmd.AddCommand(newLogsCmd())
+	rootCmd.AddCommand(newDiffCmd())
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newDaemonCmd())
+	rootCmd.AddCommand(newWebhooksCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newStatsCmd())
+	rootCmd.AddCommand(newVerifyCmd())
+	rootCmd.AddCommand(newSchemaCmd())
+	rootCmd.AddCommand(newLintCmd())
+	rootCmd.AddCommand(newStatusCmd())
+
+	cmd, err := rootCmd.ExecuteC()
+	recordUsage(cmd, err)
+	if err != nil && cmd != nil {
+		slog.Error("command failed", "command", cmd.CommandPath(), "error", err)
+	}
+	_ = closeLog()
+	if err != nil {
 		os.Exit(1)
 	}
 }
 
 func runWorkflow(cmd *cobra.Command, args []string) error {
+	if err := checkOutputFormat(); err != nil {
+		return err
+	}
+
+	// In JSON mode the UI is discarded and a single document is written at the end
+	output := RunOutput{Success: true, Runs: []RunReport{}}
+	if outputFormat == outputJSON {
+		stdout, restore := suppressUI()
+		defer func() {
+			restore()
+			_ = writeJSON(stdout, output)
+		}()
+	}
+
 	// Handle color settings
 	if noColor {
 		ui.SetColorsEnabled(false)
 	}
 
-	// Set up structured logging if enabled
-	if cmd.Flags().Changed("log-format") || cmd.Flags().Changed("log-level") || cmd.Flags().Changed("log-file") {
-		setupLogger(cmd)
-	}
-
 	// Print banner
 	if compact {
 		ui.PrintCompactBanner(version)
@@ -212,11 +326,13 @@ func runWorkflow(cmd *cobra.Command, args []string) error {
 	configPaths, err := resolveConfigFiles()
 	if err != nil {
 		ui.Error("Failed to resolve config files: %s", err)
+		output.Success, output.Error = false, err.Error()
 		return err
 	}
 
 	if len(configPaths) == 0 {
 		ui.Error("No Cortexfile found")
+		output.Success, output.Error = false, "no Cortexfile found"
 		return fmt.Errorf("no Cortexfile found")
 	}
 
@@ -233,27 +349,31 @@ func runWorkflow(cmd *cobra.Command, args []string) error {
 				ui.Bold, configPath, ui.Reset)
 		}
 
-		success, tasks, err := runSingleConfig(cmd, configPath)
+		run, err := executeConfig(cmd, configPath, nil)
+		output.Runs = append(output.Runs, newRunReport(configPath, run, err))
 		if err != nil {
 			ui.Error("Config %s failed: %s", configPath, err)
 			allSuccess = false
-		} else if success {
+		} else if run.Result.Success {
 			successfulRuns++
 		} else {
 			allSuccess = false
 		}
-		totalTasks += tasks
+		if run != nil {
+			totalTasks += len(run.Result.Tasks)
+		}
 	}
+	output.Success = allSuccess
 
 	// Print aggregate summary for multiple configs
 	if len(configPaths) > 1 {
 		ui.PrintDivider()
 		if allSuccess {
-			fmt.Printf("\n  %s%s All %d configs completed successfully (%d tasks)%s\n\n",
-				ui.Bold, ui.Green, len(configPaths), totalTasks, ui.Reset)
+			fmt.Printf("\n  %s%s%s All %d configs completed successfully (%d tasks)%s\n\n",
+				ui.Bold, ui.StatusColor(ui.StatusSuccess), ui.StatusGlyph(ui.StatusSuccess), len(configPaths), totalTasks, ui.Reset)
 		} else {
-			fmt.Printf("\n  %s%s %d/%d configs completed (%d tasks)%s\n\n",
-				ui.Bold, ui.Red, successfulRuns, len(configPaths), totalTasks, ui.Reset)
+			fmt.Printf("\n  %s%s%s %d/%d configs completed (%d tasks)%s\n\n",
+				ui.Bold, ui.StatusColor(ui.StatusFailed), ui.StatusGlyph(ui.StatusFailed), successfulRuns, len(configPaths), totalTasks, ui.Reset)
 		}
 	}
 
@@ -263,7 +383,18 @@ func runWorkflow(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func runSingleConfig(cmd *cobra.Command, configPath string) (bool, int, error) {
+// configRun holds the outcome of executing a single Cortexfile.
+type configRun struct {
+	Project  string
+	RunDir   string
+	Result   *state.RunResult
+	Duration time.Duration
+}
+
+// executeConfig loads, validates, and runs a single Cortexfile, with the
+// variables of its MasterCortex workflow entry (if any).
+// Returns a nil configRun if the workflow failed before execution started.
+func executeConfig(cmd *cobra.Command, configPath string, workflowVars map[string]string) (*configRun, error) {
 	// Load global config
 	globalCfg, err := config.LoadGlobalConfig()
 	if err != nil {
@@ -281,15 +412,31 @@ func runSingleConfig(cmd *cobra.Command, configPath string) (bool, int, error) {
 	}
 
 	ui.PrintSetupStart()
-	ui.PrintSetupStep("Loading " + displayPath)
+	ui.PrintSetupStep("Loading " + ui.TruncatePath(displayPath, ui.Width()-14))
 	localCfg, err := config.LoadConfig(configPath)
 	if err != nil {
-		return false, 0, fmt.Errorf("failed to load config: %w", err)
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if err := applyCLIVars(localCfg, configPath, workflowVars); err != nil {
+		return nil, err
 	}
 
 	ui.PrintSetupStep("Validating configuration")
 	if err := config.ValidateWithFile(localCfg, configPath); err != nil {
-		return false, 0, err
+		return nil, err
+	}
+
+	secrets, err := config.ResolveSecrets(localCfg, filepath.Dir(configPath))
+	if err != nil {
+		return nil, err
+	}
+	if len(secrets) > 0 {
+		ui.PrintSetupStep(fmt.Sprintf("Loaded %d secret(s)", len(secrets)))
+	}
+	signingKey, err := config.ResolveSigningKey(localCfg, filepath.Dir(configPath))
+	if err != nil {
+		return nil, err
 	}
 
 	// Build CLI settings override
@@ -300,11 +447,19 @@ func runSingleConfig(cmd *cobra.Command, configPath string) (bool, int, error) {
 	if cmd.Flags().Changed("verbose") {
 		cliSettings.Verbose = verbose
 	}
+	if cmd.Flags().Changed("incremental") {
+		cliSettings.Incremental = incremental
+	}
+	if noDefaultSystemPrompt {
+		cliSettings.DefaultSystemPrompt = new(bool)
+	}
 	// Stream is on by default, --no-stream disables it
 	cliSettings.Stream = streamLogs && !noStream
 
 	// Merge configs: CLI > local > global
 	merged := config.MergeConfigs(globalCfg, localCfg, cliSettings)
+	applyTheme(merged.Settings.Theme)
+	applyColor(merged.Settings.Color)
 
 	// Handle parallel execution flags
 	// Default is parallel ON (from global config)
@@ -321,7 +476,7 @@ func runSingleConfig(cmd *cobra.Command, configPath string) (bool, int, error) {
 	plan, err := planner.BuildPlan(localCfg)
 	if err != nil {
 		ui.Error("Failed to build plan: %s", err)
-		return false, 0, err
+		return nil, err
 	}
 
 	// Show execution mode
@@ -337,31 +492,38 @@ func runSingleConfig(cmd *cobra.Command, configPath string) (bool, int, error) {
 	}
 	ui.PrintConfigInfo(levelCount, effectiveMax, useParallel)
 
-	// Convert plan to TaskInfo for display
-	taskInfos := make([]ui.TaskInfo, len(plan.Tasks))
-	for i, t := range plan.Tasks {
-		taskInfos[i] = ui.TaskInfo{
-			Name:         t.Name,
-			Agent:        t.AgentName,
-			Tool:         t.Tool,
-			Model:        t.Model,
-			Dependencies: t.Dependencies,
-		}
+	ui.PrintExecutionPlan(planTaskInfos(plan), useParallel)
+
+	faults, err := injectedFaults(plan)
+	if err != nil {
+		ui.Error("%s", err)
+		return nil, err
 	}
-	ui.PrintExecutionPlan(taskInfos)
 
 	// Set up state store
 	cwd, err := os.Getwd()
 	if err != nil {
 		ui.Error("Failed to get working directory: %s", err)
-		return false, 0, err
+		return nil, err
+	}
+
+	gitVars, err := gitBuiltins(localCfg, cwd)
+	if err != nil {
+		ui.Error("%s", err)
+		return nil, err
 	}
 
 	store, err := state.NewStore(cwd)
 	if err != nil {
 		ui.Error("Failed to create state store: %s", err)
-		return false, 0, err
+		return nil, err
 	}
+	store.SetSecrets(config.SecretValues(secrets))
+	store.SetSigningKey(signingKey)
+	if err := saveConfigSnapshot(store, localCfg, configPath); err != nil {
+		ui.Warning("Failed to save config snapshot: %s", err)
+	}
+	builtins := builtinVars(gitVars, store, cwd, time.Now())
 
 	// Print session info
 	ui.PrintSessionInfo(store.RunID(), store.RunDir())
@@ -369,49 +531,138 @@ func runSingleConfig(cmd *cobra.Command, configPath string) (bool, int, error) {
 	// Get project name
 	projectName := filepath.Base(cwd)
 
-	// Log run start
-	observability.Info("Starting workflow execution",
-		observability.WithEvent(observability.EventRunStart),
-		observability.WithData(observability.RunData{
-			RunID:      store.RunID(),
-			Project:    projectName,
-			TaskCount:  len(plan.Tasks),
-			ConfigFile: configPath,
-		}),
-	)
+	slog.Debug("workflow loaded", "config_file", configPath, "run_id", store.RunID(), "project", projectName, "tasks", len(plan.Tasks))
 
 	// Set up webhook manager
 	webhookMgr := webhook.NewManager(merged.Webhooks)
+	webhookMgr.SetSecrets(config.SecretValues(secrets))
 	if webhookMgr.HasWebhooks() {
 		ui.Info("Webhooks configured: %d", webhookMgr.Count())
 	}
 
-	// Send run_start event
-	webhookMgr.Send(webhook.NewRunStartEvent(store.RunID(), projectName))
+	// Export the run to an OpenTelemetry collector, if configured
+	var subscribers []events.Subscriber
+	if exporter := telemetry.New(merged.Settings.Telemetry, projectName); exporter != nil {
+		subscribers = append(subscribers, exporter)
+		defer func() {
+			if err := exporter.Wait(); err != nil {
+				ui.Warning("%s", err)
+			}
+		}()
+	}
+
+	// Report task events to `cortex serve`, if it started this run
+	if path := os.Getenv(events.FileEnv); path != "" {
+		eventsFile, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			ui.Warning("Failed to open events file: %s", err)
+		} else {
+			defer eventsFile.Close()
+			subscribers = append(subscribers, events.NewJSONWriter(eventsFile))
+		}
+	}
+	// Both were meant for this run alone, not for a `cortex run` one of its
+	// tasks starts, which would then reuse the run's ID and events file
+	os.Unsetenv(state.RunIDEnv)
+	os.Unsetenv(events.FileEnv)
 
 	// Set up agent registry
 	registry := runtime.NewAgentRegistry()
 
 	claudeAdapter := claude.New()
 	claudeAdapter.SetStreamLogs(merged.Settings.Stream)
+	claudeAdapter.SetDefaultSystemPrompt(merged.Settings.DefaultSystemPromptEnabled())
 	registry.Register("claude-code", claudeAdapter)
 
 	opencodeAdapter := opencode.New()
 	opencodeAdapter.SetStreamLogs(merged.Settings.Stream)
 	registry.Register("opencode", opencodeAdapter)
 
+	aiderAdapter := aider.New()
+	aiderAdapter.SetStreamLogs(merged.Settings.Stream)
+	registry.Register("aider", aiderAdapter)
+
+	apiAdapter := api.New()
+	apiAdapter.SetStreamLogs(merged.Settings.Stream)
+	registry.Register("api", apiAdapter)
+
 	shellAdapter := shell.New()
 	shellAdapter.SetStreamLogs(merged.Settings.Stream)
 	registry.Register("shell", shellAdapter)
 
+	scriptAdapter := script.New()
+	scriptAdapter.SetStreamLogs(merged.Settings.Stream)
+	registry.Register("script", scriptAdapter)
+
+	httpAdapter := httpreq.New()
+	httpAdapter.SetStreamLogs(merged.Settings.Stream)
+	registry.Register("http", httpAdapter)
+
+	gitAdapter := git.New()
+	gitAdapter.SetStreamLogs(merged.Settings.Stream)
+	registry.Register("git", gitAdapter)
+
+	githubAdapter := github.New()
+	githubAdapter.SetStreamLogs(merged.Settings.Stream)
+	registry.Register("pull_request", githubAdapter)
+
+	waitAdapter := wait.New()
+	waitAdapter.SetStreamLogs(merged.Settings.Stream)
+	registry.Register("wait", waitAdapter)
+	registry.Register("poll", waitAdapter)
+
+	notifyAdapter := notify.New(webhookMgr, store.RunID(), projectName)
+	notifyAdapter.SetStreamLogs(merged.Settings.Stream)
+	registry.Register("notify", notifyAdapter)
+
+	// Tools declared under tools: in the global config
+	for name, tool := range globalCfg.Tools {
+		customAdapter := custom.New(name, tool)
+		customAdapter.SetStreamLogs(merged.Settings.Stream)
+		registry.Register(name, customAdapter)
+	}
+
+	var promptHooks []runtime.PromptHook
+	for _, command := range merged.Settings.PromptHooks {
+		promptHooks = append(promptHooks, runtime.ExecPromptHook{Command: command})
+	}
+
+	// The git settings apply to the repository of the workflow's workdir
+	workdir := localCfg.Workdir
+	if workdir == "" {
+		workdir = cwd
+	}
+
 	// Create executor with config
 	executor := runtime.NewExecutorWithConfig(runtime.ExecutorConfig{
 		Registry:    registry,
 		Store:       store,
-		Writer:      os.Stdout,
+		Writer:      ui.Stdout,
 		Verbose:     merged.Settings.Verbose,
 		Parallel:    useParallel,
 		MaxParallel: merged.Settings.MaxParallel,
+		Incremental: merged.Settings.Incremental,
+		Secrets:     secrets,
+		Builtins:    builtins,
+		Vars:        localCfg.Vars,
+		PromptHooks: promptHooks,
+		Budget: runtime.Budget{
+			MaxTokens:  merged.Settings.MaxTokens,
+			MaxCostUSD: merged.Settings.MaxCostUSD,
+		},
+		Webhooks:    webhookMgr,
+		Subscribers: subscribers,
+		Project:     projectName,
+		User:        currentUser(),
+		Stream:      merged.Settings.Stream,
+		Progress:    !noProgress,
+		Output:      merged.Settings.Output,
+		FailFast:    merged.Settings.FailFastEnabled(),
+		Faults:      faults,
+		KillTimeout: merged.Settings.KillTimeoutDuration(),
+		MaxOutput:   merged.Settings.MaxOutput(),
+		Git:         localCfg.Git,
+		Workdir:     workdir,
 	})
 
 	// Set up context with cancellation on interrupt
@@ -419,78 +670,118 @@ func runSingleConfig(cmd *cobra.Command, configPath string) (bool, int, error) {
 	defer cancel()
 
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(sigCh, proc.ShutdownSignals...)
 	go func() {
 		<-sigCh
-		fmt.Printf("\n%s⚠ Received interrupt, cancelling...%s\n", ui.BrightYellow, ui.Reset)
+		fmt.Fprintf(ui.Stdout, "\n%s⚠ Received interrupt, cancelling...%s\n", ui.BrightYellow, ui.Reset)
 		cancel()
+
+		// Teardown tasks ignore the cancellation; a second interrupt
+		// gives up on them, saving the run as it stands
+		<-sigCh
+		fmt.Fprintf(ui.Stdout, "\n%s⚠ Received second interrupt, exiting%s\n", ui.BrightYellow, ui.Reset)
+		executor.Abort(abortGrace)
+		proc.KillStopping()
+		os.Exit(130)
 	}()
 
+	// Connect to api endpoints while the first tasks start
+	warmupAPIAgents(ctx, apiAdapter, plan)
+
 	// Execute the plan
 	ui.PrintDivider()
-	fmt.Printf("%sRunning tasks...%s\n", ui.Bold, ui.Reset)
+	fmt.Fprintf(ui.Stdout, "%sRunning tasks...%s\n", ui.Bold, ui.Reset)
 
 	startTime := time.Now()
 	result, err := executor.Execute(ctx, plan)
 	duration := time.Since(startTime)
+	run := &configRun{
+		Project:  projectName,
+		RunDir:   store.RunDir(),
+		Result:   result,
+		Duration: duration,
+	}
+	recordRunUsage(localCfg, merged, plan, useParallel, result, duration)
+	defer enforceRetention(projectName, store.RunID(), merged.Settings.Retention)
 
-	// Wait for pending webhooks
-	defer webhookMgr.Wait()
-
-	// Send run_complete event
-	webhookMgr.Send(webhook.NewRunCompleteEvent(
-		store.RunID(),
-		projectName,
-		len(result.Tasks),
-		duration,
-		result.Success,
-	))
+	// Wait for pending webhooks, run_complete or run_cancelled included
+	defer waitForWebhooks(webhookMgr)
 
 	if err != nil {
-		observability.Error("Workflow execution failed",
-			observability.WithEvent(observability.EventRunComplete),
-			observability.WithData(observability.RunData{
-				RunID:     store.RunID(),
-				Project:   projectName,
-				TaskCount: len(result.Tasks),
-				Duration:  duration.String(),
-				Success:   false,
-			}),
-		)
-		ui.PrintSummary(false, store.RunDir())
-		return false, len(result.Tasks), err
-	}
-
-	// Log run complete
-	observability.Info("Workflow execution completed",
-		observability.WithEvent(observability.EventRunComplete),
-		observability.WithData(observability.RunData{
-			RunID:     store.RunID(),
-			Project:   projectName,
-			TaskCount: len(result.Tasks),
-			Duration:  duration.String(),
-			Success:   result.Success,
-		}),
-	)
+		ui.PrintSummary(false, result.SkippedCount(), duration, store.RunDir())
+		return run, err
+	}
 
 	// Print summary
-	ui.PrintSummary(result.Success, store.RunDir())
+	ui.PrintSummary(result.Success, result.SkippedCount(), duration, store.RunDir())
+
+	return run, nil
+}
+
+// abortGrace is how long a second interrupt waits for the tasks it cancels
+// to return their output before the run is saved without it.
+const abortGrace = 3 * time.Second
+
+// warmupTimeout caps how long a run waits for api endpoints to connect.
+const warmupTimeout = 5 * time.Second
+
+// warmupAPIAgents opens a connection to the endpoint of each api agent with
+// warmup enabled, all at once, and waits for them so the first tasks reuse
+// them. Failures are left for the tasks to report.
+func warmupAPIAgents(ctx context.Context, adapter *api.Adapter, plan *planner.ExecutionPlan) {
+	ctx, cancel := context.WithTimeout(ctx, warmupTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	seen := make(map[string]bool)
+	for _, t := range plan.Tasks {
+		if t.Tool != "api" || !t.Warmup {
+			continue
+		}
+		key := fmt.Sprintf("%s|%s|%v", t.BaseURL, t.Proxy, t.TLS)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
 
-	return result.Success, len(result.Tasks), nil
+		task := runtime.Task{BaseURL: t.BaseURL, Proxy: t.Proxy, TLS: t.TLS}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = adapter.Warmup(ctx, task)
+		}()
+	}
+	wg.Wait()
 }
 
 func validateConfig(cmd *cobra.Command, args []string) error {
+	if err := checkOutputFormat(); err != nil {
+		return err
+	}
+
+	var output ValidateOutput
+	if outputFormat == outputJSON {
+		stdout, restore := suppressUI()
+		defer func() {
+			restore()
+			_ = writeJSON(stdout, output)
+		}()
+	}
+
 	ui.PrintCompactBanner(version)
 
 	cfg, configPath, err := loadConfig()
+	output.ConfigFile = configPath
 	if err != nil {
 		ui.Error("Validation failed: %s", err)
+		output.Errors = validationIssues(err)
 		return err
 	}
 
 	// Validate with file path for better error messages
 	if err := config.ValidateWithFile(cfg, configPath); err != nil {
 		ui.Error("Validation failed:\n%s", err)
+		output.Errors = validationIssues(err)
 		return err
 	}
 
@@ -498,6 +789,7 @@ func validateConfig(cmd *cobra.Command, args []string) error {
 	plan, err := planner.BuildPlan(cfg)
 	if err != nil {
 		ui.Error("Plan validation failed: %s", err)
+		output.Errors = validationIssues(err)
 		return err
 	}
 
@@ -508,11 +800,89 @@ func validateConfig(cmd *cobra.Command, args []string) error {
 
 	// Show execution levels for parallel info
 	levels := planner.BuildExecutionLevels(plan.DAG)
+	output = ValidateOutput{
+		ConfigFile: configPath,
+		Valid:      true,
+		Agents:     len(cfg.Agents),
+		Tasks:      len(cfg.Tasks),
+		Levels:     len(levels),
+	}
 	fmt.Printf("  %sExecution Levels:%s %d\n", ui.Dim, ui.Reset, len(levels))
 	fmt.Printf("  %sMax Parallelism:%s  %d\n", ui.Dim, ui.Reset, planner.MaxParallelism(levels))
 	fmt.Println()
 
-	// Convert plan to TaskInfo for display
+	if verbose {
+		printMatchedFiles(cfg, configPath)
+	}
+
+	// Estimate duration and cost from the project's past runs
+	if project, err := resolveProject(""); err == nil {
+		if history, err := state.LoadHistory(project, estimateRuns); err == nil {
+			est := estimateRun(plan, history, validateMaxParallel(cfg))
+			printEstimate(est)
+			output.Estimate = estimateOutput(est)
+		}
+	}
+
+	ui.PrintExecutionPlan(planTaskInfos(plan), true)
+
+	return nil
+}
+
+// printMatchedFiles lists the files matched by each task's prompt_file and
+// inputs, for `cortex validate --verbose`.
+func printMatchedFiles(cfg *config.AgentflowConfig, configPath string) {
+	baseDir := filepath.Dir(configPath)
+	rel := func(path string) string {
+		if r, err := filepath.Rel(baseDir, path); err == nil && !strings.HasPrefix(r, "..") {
+			return r
+		}
+		return path
+	}
+
+	names := make([]string, 0, len(cfg.Tasks))
+	for name, task := range cfg.Tasks {
+		if task.PromptFile != "" || len(task.Inputs) > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	fmt.Printf("  %sMatched Files:%s\n", ui.Dim, ui.Reset)
+	printMatches := func(name, field, pattern string, matches []string) {
+		fmt.Printf("    %s%s%s %s%s%s %s\n", ui.Bold, name, ui.Reset, ui.Dim, field, ui.Reset, rel(pattern))
+		if len(matches) == 0 {
+			fmt.Printf("      %s(none yet; created during the run)%s\n", ui.Dim, ui.Reset)
+		}
+		for _, match := range matches {
+			fmt.Printf("      %s\n", rel(match))
+		}
+	}
+	for _, name := range names {
+		task := cfg.Tasks[name]
+		if task.PromptFile != "" {
+			printMatches(name, "prompt_file", task.PromptFile, task.PromptFiles)
+		}
+		for _, pattern := range task.Inputs {
+			matches, _ := config.MatchFiles(pattern, baseDir)
+			printMatches(name, "inputs", pattern, matches)
+		}
+	}
+	fmt.Println()
+}
+
+// planTaskInfos converts the plan's tasks for display in the execution plan,
+// with their levels and critical path membership.
+func planTaskInfos(plan *planner.ExecutionPlan) []ui.TaskInfo {
+	levels := planner.BuildExecutionLevels(plan.DAG)
+	critical := make(map[string]bool)
+	for _, name := range planner.CriticalPath(plan.DAG) {
+		critical[name] = true
+	}
+
 	taskInfos := make([]ui.TaskInfo, len(plan.Tasks))
 	for i, t := range plan.Tasks {
 		taskInfos[i] = ui.TaskInfo{
@@ -521,11 +891,11 @@ func validateConfig(cmd *cobra.Command, args []string) error {
 			Tool:         t.Tool,
 			Model:        t.Model,
 			Dependencies: t.Dependencies,
+			Level:        planner.LevelForTask(levels, t.Name),
+			Critical:     critical[t.Name],
 		}
 	}
-	ui.PrintExecutionPlan(taskInfos)
-
-	return nil
+	return taskInfos
 }
 
 // DryRunTask represents a task in dry-run output
@@ -538,14 +908,17 @@ type DryRunTask struct {
 	Prompt       string   `json:"prompt"`
 	Workdir      string   `json:"workdir,omitempty"`
 	Level        int      `json:"level"`
+	Priority     int      `json:"priority,omitempty"` // Including priority inherited from dependents
+	Phase        string   `json:"phase,omitempty"`    // setup or teardown
 }
 
 // DryRunOutput represents the full dry-run output
 type DryRunOutput struct {
-	ConfigFile  string       `json:"config_file"`
-	TotalTasks  int          `json:"total_tasks"`
-	TotalLevels int          `json:"total_levels"`
-	Tasks       []DryRunTask `json:"tasks"`
+	ConfigFile   string       `json:"config_file"`
+	TotalTasks   int          `json:"total_tasks"`
+	TotalLevels  int          `json:"total_levels"`
+	CriticalPath []string     `json:"critical_path,omitempty"`
+	Tasks        []DryRunTask `json:"tasks"`
 }
 
 func dryRunWorkflow(cmd *cobra.Command, args []string) error {
@@ -583,6 +956,13 @@ func dryRunWorkflow(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if err := applyCLIVars(localCfg, configPath, nil); err != nil {
+		if !jsonOutput {
+			ui.Error("%s", err)
+		}
+		return err
+	}
+
 	// Validate
 	if err := config.ValidateWithFile(localCfg, configPath); err != nil {
 		if !jsonOutput {
@@ -613,10 +993,11 @@ func dryRunWorkflow(cmd *cobra.Command, args []string) error {
 
 	// Build output
 	output := DryRunOutput{
-		ConfigFile:  configPath,
-		TotalTasks:  len(plan.Tasks),
-		TotalLevels: len(levels),
-		Tasks:       make([]DryRunTask, 0, len(plan.Tasks)),
+		ConfigFile:   configPath,
+		TotalTasks:   len(plan.Tasks),
+		TotalLevels:  len(levels),
+		CriticalPath: planner.CriticalPath(plan.DAG),
+		Tasks:        make([]DryRunTask, 0, len(plan.Tasks)),
 	}
 
 	for _, t := range plan.Tasks {
@@ -629,6 +1010,8 @@ func dryRunWorkflow(cmd *cobra.Command, args []string) error {
 			Prompt:       t.Prompt,
 			Workdir:      t.Workdir,
 			Level:        taskLevel[t.Name],
+			Priority:     t.Priority,
+			Phase:        t.Phase,
 		})
 	}
 
@@ -644,7 +1027,11 @@ func dryRunWorkflow(cmd *cobra.Command, args []string) error {
 	fmt.Printf("%s═══════════════════════════════════════════════════%s\n\n", ui.Dim, ui.Reset)
 
 	fmt.Printf("  %sTasks:%s  %d\n", ui.Dim, ui.Reset, output.TotalTasks)
-	fmt.Printf("  %sLevels:%s %d\n\n", ui.Dim, ui.Reset, output.TotalLevels)
+	fmt.Printf("  %sLevels:%s %d\n", ui.Dim, ui.Reset, output.TotalLevels)
+	if len(output.CriticalPath) > 1 {
+		fmt.Printf("  %sCritical path:%s %s\n", ui.Dim, ui.Reset, strings.Join(output.CriticalPath, " → "))
+	}
+	fmt.Println()
 
 	// Group tasks by level
 	for levelIdx, level := range levels {
@@ -675,6 +1062,17 @@ func dryRunWorkflow(cmd *cobra.Command, args []string) error {
 						fmt.Printf("    %sWorkdir:%s %s\n", ui.Dim, ui.Reset, t.Workdir)
 					}
 
+					if t.Priority != 0 {
+						fmt.Printf("    %sPriority:%s %d\n", ui.Dim, ui.Reset, t.Priority)
+					}
+
+					switch t.Phase {
+					case config.PhaseSetup:
+						fmt.Printf("    %sPhase:%s setup (before all other tasks)\n", ui.Dim, ui.Reset)
+					case config.PhaseTeardown:
+						fmt.Printf("    %sPhase:%s teardown (after all other tasks, even on failure)\n", ui.Dim, ui.Reset)
+					}
+
 					// Show prompt (truncated)
 					fmt.Printf("    %sPrompt:%s\n", ui.Dim, ui.Reset)
 					promptLines := strings.Split(strings.TrimSpace(t.Prompt), "\n")
@@ -684,10 +1082,8 @@ func dryRunWorkflow(cmd *cobra.Command, args []string) error {
 							fmt.Printf("      %s... (%d more lines)%s\n", ui.Dim, len(promptLines)-maxLines, ui.Reset)
 							break
 						}
-						// Truncate long lines
-						if len(line) > 70 {
-							line = line[:67] + "..."
-						}
+						// Truncate long lines to the terminal width
+						line = ui.Truncate(line, min(70, ui.Width()-6))
 						fmt.Printf("      %s%s%s\n", ui.Dim, line, ui.Reset)
 					}
 					break
@@ -697,7 +1093,7 @@ func dryRunWorkflow(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
-	fmt.Printf("%s✓ Dry run complete. No tasks were executed.%s\n\n", ui.Green, ui.Reset)
+	fmt.Printf("%s%s Dry run complete. No tasks were executed.%s\n\n", ui.StatusColor(ui.StatusSuccess), ui.StatusGlyph(ui.StatusSuccess), ui.Reset)
 
 	return nil
 }
@@ -711,6 +1107,18 @@ func showGraph(cmd *cobra.Command, args []string) error {
 		ui.SetColorsEnabled(false)
 	}
 
+	master, _ := cmd.Flags().GetBool("master")
+	expand, _ := cmd.Flags().GetBool("expand")
+	if expand && !master {
+		return fmt.Errorf("--expand requires --master")
+	}
+	if master {
+		if compactGraph {
+			return fmt.Errorf("--compact can't be combined with --master")
+		}
+		return showMasterGraph(format, expand)
+	}
+
 	// Resolve config files
 	configPaths, err := resolveConfigFiles()
 	if err != nil {
@@ -764,6 +1172,24 @@ func listSessions(cmd *cobra.Command, args []string) error {
 	limit, _ := cmd.Flags().GetInt("limit")
 	failedOnly, _ := cmd.Flags().GetBool("failed")
 
+	if err := checkOutputFormat(); err != nil {
+		return err
+	}
+	if outputFormat == outputJSON {
+		sessions, err := state.ListSessions(state.SessionFilter{
+			Project:    project,
+			Limit:      limit,
+			FailedOnly: failedOnly,
+		})
+		if err != nil {
+			return err
+		}
+		if sessions == nil {
+			sessions = []state.SessionInfo{}
+		}
+		return writeJSON(os.Stdout, SessionsOutput{Sessions: sessions})
+	}
+
 	// If no project specified, show interactive project selector
 	if project == "" {
 		return listSessionsInteractive(limit, failedOnly)
@@ -789,10 +1215,7 @@ func listSessionsInteractive(limit int, failedOnly bool) error {
 	// Build selectable items
 	items := make([]ui.SelectableItem, len(summaries))
 	for i, s := range summaries {
-		timeStr := s.LatestTime.Format("2006-01-02 15:04:05")
-		if s.LatestTime.IsZero() {
-			timeStr = "unknown"
-		}
+		timeStr := ui.FormatAge(s.LatestTime, time.Now())
 
 		items[i] = ui.SelectableItem{
 			Label:       fmt.Sprintf("%-25s %s%s%s  %s%d sessions%s", s.Name, ui.Dim, timeStr, ui.Reset, ui.Cyan, s.SessionCount, ui.Reset),
@@ -839,21 +1262,27 @@ func showProjectSessions(project string, limit int, failedOnly bool) error {
 
 	for _, s := range sessions {
 		// Status indicator
-		statusIcon := fmt.Sprintf("%s✓%s", ui.BrightGreen, ui.Reset)
-		if !s.Success {
-			statusIcon = fmt.Sprintf("%s✗%s", ui.BrightRed, ui.Reset)
+		statusIcon := ui.StatusIcon(ui.StatusSuccess)
+		switch {
+		case s.Cancelled:
+			statusIcon = ui.StatusIcon(ui.StatusCancelled)
+		case !s.Success:
+			statusIcon = ui.StatusIcon(ui.StatusFailed)
 		}
 
-		// Format time
-		timeStr := s.StartTime.Format("2006-01-02 15:04:05")
-		if s.StartTime.IsZero() {
-			timeStr = "unknown"
+		// Local time, with how long ago it was
+		timeStr := ui.FormatTime(s.StartTime)
+		if !s.StartTime.IsZero() {
+			timeStr += " (" + ui.FormatAge(s.StartTime, time.Now()) + ")"
+		}
+		if s.User != "" {
+			timeStr += " by " + s.User
 		}
 
 		// Duration
 		durationStr := ""
 		if s.Duration > 0 {
-			durationStr = fmt.Sprintf(" (%s)", state.FormatDuration(s.Duration))
+			durationStr = fmt.Sprintf(" (%s)", ui.FormatDuration(s.Duration))
 		}
 
 		fmt.Printf("  %s %s%s%s %s%s%s\n",
@@ -878,6 +1307,18 @@ func showProjectSessions(project string, limit int, failedOnly bool) error {
 	return nil
 }
 
+// currentUser returns the name runs are attributed to: $CORTEX_USER if set,
+// otherwise the OS user.
+func currentUser() string {
+	if name := os.Getenv("CORTEX_USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
 func loadConfig() (*config.AgentflowConfig, string, error) {
 	paths, err := resolveConfigFiles()
 	if err != nil {
@@ -898,6 +1339,10 @@ func loadConfig() (*config.AgentflowConfig, string, error) {
 		return nil, path, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if err := applyCLIVars(cfg, path, nil); err != nil {
+		return nil, path, err
+	}
+
 	ui.Info("Validating configuration...")
 	if err := config.ValidateWithFile(cfg, path); err != nil {
 		return nil, path, err
@@ -906,6 +1351,78 @@ func loadConfig() (*config.AgentflowConfig, string, error) {
 	return cfg, path, nil
 }
 
+// applyCLIVars applies workflow variables from a MasterCortex (if any), then
+// --var-file and --var values to the config's variables, fills
+// {{previous.task}} from the --after run, and checks the variables against
+// the declared inputs.
+// Precedence: --var > --var-file (later files win) > MasterCortex variables >
+// Cortexfile vars > input defaults.
+func applyCLIVars(cfg *config.AgentflowConfig, configPath string, workflowVars map[string]string) error {
+	overrides := make([]map[string]string, 0, len(varFiles)+2)
+	overrides = append(overrides, workflowVars)
+	for _, path := range varFiles {
+		vars, err := config.LoadVarFile(path)
+		if err != nil {
+			return err
+		}
+		overrides = append(overrides, vars)
+	}
+
+	cliVars, err := config.ParseVarFlags(varFlags)
+	if err != nil {
+		return err
+	}
+	overrides = append(overrides, cliVars)
+
+	config.ApplyVars(cfg, overrides...)
+
+	if afterRun != "" {
+		outputs, err := loadAfterOutputs(afterRun)
+		if err != nil {
+			return err
+		}
+		config.ApplyPrevious(cfg, outputs)
+	}
+	return config.CheckInputs(cfg, configPath)
+}
+
+// saveConfigSnapshot saves the files cfg was read from, its prompt files,
+// and its final vars into the run directory.
+func saveConfigSnapshot(store *state.Store, cfg *config.AgentflowConfig, configPath string) error {
+	baseDir, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return err
+	}
+	files := slices.Clone(cfg.Sources)
+	for _, name := range slices.Sorted(maps.Keys(cfg.Tasks)) {
+		files = append(files, cfg.Tasks[name].PromptFiles...)
+	}
+	return store.SaveConfigSnapshot(baseDir, files, cfg.Vars)
+}
+
+// loadAfterOutputs loads the task outputs of the --after run. A bare run ID
+// is looked up in the current project first, then in the others; use
+// project/run-id to pick one explicitly.
+func loadAfterOutputs(ref string) (map[string]string, error) {
+	project, runID, ok := strings.Cut(ref, "/")
+	if !ok {
+		runID = ref
+		var err error
+		if project, err = resolveProject(""); err != nil {
+			return nil, err
+		}
+	}
+
+	runDir, project, runID, err := state.FindRunDir(project, runID)
+	if err != nil {
+		return nil, fmt.Errorf("--after: %w", err)
+	}
+	if !state.IsRunComplete(runDir) {
+		return nil, fmt.Errorf("--after: run %s of %s has not finished", runID, project)
+	}
+	return state.RunOutputs(runDir)
+}
+
 // resolveConfigFiles expands glob patterns and returns all matching config files
 func resolveConfigFiles() ([]string, error) {
 	if len(configFiles) == 0 {
@@ -975,16 +1492,14 @@ func initCortexfile(cmd *cobra.Command, args []string) error {
 	var content string
 
 	if global {
-		// Create global config in ~/.cortex/config.yml
-		homeDir, err := os.UserHomeDir()
+		// Create global config in the config directory
+		cortexDir, err := paths.ConfigDir()
 		if err != nil {
-			ui.Error("Failed to get home directory: %s", err)
+			ui.Error("%s", err)
 			return err
 		}
-
-		cortexDir := filepath.Join(homeDir, ".cortex")
 		if err := os.MkdirAll(cortexDir, 0755); err != nil {
-			ui.Error("Failed to create ~/.cortex directory: %s", err)
+			ui.Error("Failed to create %s: %s", cortexDir, err)
 			return err
 		}
 
@@ -1130,13 +1645,16 @@ func runMasterWorkflow(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	// Execute workflows
+	notifier := newMasterNotifier(masterCfg, masterPath, len(workflows))
+	notifier.start()
+
 	startTime := time.Now()
 	var results []workflowResult
 
 	if mode == "parallel" {
-		results = executeWorkflowsParallel(cmd, workflows, masterCfg)
+		results = executeWorkflowsParallel(cmd, workflows, masterCfg, notifier)
 	} else {
-		results = executeWorkflowsSequential(cmd, workflows, masterCfg)
+		results = executeWorkflowsSequential(cmd, workflows, masterCfg, notifier)
 	}
 
 	duration := time.Since(startTime)
@@ -1154,11 +1672,15 @@ func runMasterWorkflow(cmd *cobra.Command, args []string) error {
 	}
 
 	if successCount == len(results) {
-		fmt.Printf("\n  %s%s All %d workflows completed successfully%s\n", ui.Bold, ui.Green, len(results), ui.Reset)
+		fmt.Printf("\n  %s%s%s All %d workflows completed successfully%s\n",
+			ui.Bold, ui.StatusColor(ui.StatusSuccess), ui.StatusGlyph(ui.StatusSuccess), len(results), ui.Reset)
 	} else {
-		fmt.Printf("\n  %s%s %d/%d workflows completed%s\n", ui.Bold, ui.Red, successCount, len(results), ui.Reset)
+		fmt.Printf("\n  %s%s%s %d/%d workflows completed%s\n",
+			ui.Bold, ui.StatusColor(ui.StatusFailed), ui.StatusGlyph(ui.StatusFailed), successCount, len(results), ui.Reset)
 	}
-	fmt.Printf("  %sTotal tasks: %d, Duration: %s%s\n\n", ui.Dim, totalTasks, duration.Round(time.Second), ui.Reset)
+	fmt.Printf("  %sTotal tasks: %d, Duration: %s%s\n\n", ui.Dim, totalTasks, ui.FormatDuration(duration), ui.Reset)
+
+	notifier.complete(totalTasks, duration, successCount == len(results))
 
 	if successCount < len(results) {
 		return fmt.Errorf("master workflow completed with failures")
@@ -1171,13 +1693,122 @@ type workflowResult struct {
 	Success bool
 	Tasks   int
 	Error   error
+	Outputs map[string]string // Task outputs, for {{workflows.<name>.outputs.<task>}}
+}
+
+// masterNotifier sends master_run_* and workflow_* webhook events carrying
+// aggregate progress across the workflows of a master run. Events are sent
+// synchronously, under the lock, so receivers see them in order.
+type masterNotifier struct {
+	mgr      *webhook.Manager
+	runID    string
+	master   string
+	mu       sync.Mutex
+	progress webhook.ProgressEvent
+}
+
+func newMasterNotifier(masterCfg *config.MasterConfig, masterPath string, total int) *masterNotifier {
+	var hooks []config.WebhookConfig
+	if globalCfg, err := config.LoadGlobalConfig(); err == nil {
+		hooks = globalCfg.Webhooks
+	}
+
+	name := masterCfg.Name
+	if name == "" {
+		name = filepath.Base(filepath.Dir(masterPath))
+	}
+
+	return &masterNotifier{
+		mgr:      webhook.NewManager(hooks),
+		runID:    state.NewRunID(),
+		master:   name,
+		progress: webhook.ProgressEvent{Total: total},
+	}
+}
+
+// send delivers an event to all matching webhooks; failures are ignored
+// like asynchronous run events. Callers must hold n.mu.
+func (n *masterNotifier) send(event webhook.Event) {
+	_ = n.mgr.SendSync(event)
+}
+
+func (n *masterNotifier) start() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.send(webhook.NewMasterRunStartEvent(n.runID, n.master, n.progress))
+}
+
+func (n *masterNotifier) workflowStart(w config.WorkflowEntry) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.send(webhook.NewWorkflowStartEvent(n.runID, n.master, w.Name, w.Path, n.progress))
 }
 
-func executeWorkflowsSequential(cmd *cobra.Command, workflows []config.WorkflowEntry, masterCfg *config.MasterConfig) []workflowResult {
+func (n *masterNotifier) workflowDone(w config.WorkflowEntry, r workflowResult, duration time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if r.Success {
+		n.progress.Completed++
+		n.send(webhook.NewWorkflowCompleteEvent(n.runID, n.master, w.Name, w.Path, r.Tasks, duration, n.progress))
+		return
+	}
+
+	n.progress.Failed++
+	errMsg := ""
+	if r.Error != nil {
+		errMsg = r.Error.Error()
+	}
+	n.send(webhook.NewWorkflowFailedEvent(n.runID, n.master, w.Name, w.Path, r.Tasks, duration, errMsg, n.progress))
+}
+
+func (n *masterNotifier) workflowSkipped(w config.WorkflowEntry, reason string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.progress.Skipped++
+	n.send(webhook.NewWorkflowSkippedEvent(n.runID, n.master, w.Name, w.Path, reason, n.progress))
+}
+
+func (n *masterNotifier) complete(taskCount int, duration time.Duration, success bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.send(webhook.NewMasterRunCompleteEvent(n.runID, n.master, taskCount, duration, success, n.progress))
+}
+
+// runWorkflowEntry runs one workflow of a master run, with its variables
+// filled from the outputs of finished workflows, and reports it to the
+// notifier.
+func runWorkflowEntry(cmd *cobra.Command, masterCfg *config.MasterConfig, w config.WorkflowEntry, outputs map[string]map[string]string, notifier *masterNotifier) workflowResult {
+	notifier.workflowStart(w)
+	start := time.Now()
+
+	result := workflowResult{Name: w.Name}
+	vars, err := config.WorkflowVars(masterCfg, w, outputs)
+	if err != nil {
+		ui.Error("Workflow %s: %s", w.Name, err)
+	} else {
+		var run *configRun
+		run, err = executeConfig(cmd, w.Path, vars)
+		if run != nil {
+			result.Success = run.Result.Success
+			result.Tasks = len(run.Result.Tasks)
+			var outErr error
+			if result.Outputs, outErr = state.RunOutputs(run.RunDir); outErr != nil {
+				ui.Warning("Failed to read outputs of workflow %s: %s", w.Name, outErr)
+			}
+		}
+	}
+	result.Error = err
+
+	notifier.workflowDone(w, result, time.Since(start))
+	return result
+}
+
+func executeWorkflowsSequential(cmd *cobra.Command, workflows []config.WorkflowEntry, masterCfg *config.MasterConfig, notifier *masterNotifier) []workflowResult {
 	results := make([]workflowResult, 0, len(workflows))
 	completed := make(map[string]bool)
+	outputs := make(map[string]map[string]string)
 
-	for _, w := range workflows {
+	for i, w := range workflows {
 		// Check dependencies
 		canRun := true
 		for _, dep := range w.Needs {
@@ -1190,6 +1821,7 @@ func executeWorkflowsSequential(cmd *cobra.Command, workflows []config.WorkflowE
 		if !canRun {
 			ui.Warning("Skipping %s: dependencies not met", w.Name)
 			results = append(results, workflowResult{Name: w.Name, Success: false, Error: fmt.Errorf("dependencies not met")})
+			notifier.workflowSkipped(w, "dependencies not met")
 			continue
 		}
 
@@ -1201,18 +1833,17 @@ func executeWorkflowsSequential(cmd *cobra.Command, workflows []config.WorkflowE
 		// Set configFiles for this workflow
 		configFiles = []string{w.Path}
 
-		success, tasks, err := runSingleConfig(cmd, w.Path)
-		results = append(results, workflowResult{
-			Name:    w.Name,
-			Success: success,
-			Tasks:   tasks,
-			Error:   err,
-		})
+		result := runWorkflowEntry(cmd, masterCfg, w, outputs, notifier)
+		results = append(results, result)
 
-		if success {
+		if result.Success {
 			completed[w.Name] = true
+			outputs[w.Name] = result.Outputs
 		} else if masterCfg.StopOnError != nil && *masterCfg.StopOnError {
 			ui.Error("Stopping due to error in %s", w.Name)
+			for _, rest := range workflows[i+1:] {
+				notifier.workflowSkipped(rest, "stopped after failure in "+w.Name)
+			}
 			break
 		}
 	}
@@ -1220,94 +1851,114 @@ func executeWorkflowsSequential(cmd *cobra.Command, workflows []config.WorkflowE
 	return results
 }
 
-func executeWorkflowsParallel(cmd *cobra.Command, workflows []config.WorkflowEntry, masterCfg *config.MasterConfig) []workflowResult {
-	// For parallel execution with dependencies, we need to build execution levels
-	// similar to task execution. For simplicity, we'll run all without deps first,
-	// then those with deps.
-
+// executeWorkflowsParallel runs each workflow as soon as the workflows it
+// needs have succeeded, up to max_parallel at a time. A workflow that fails
+// or is skipped skips the workflows that need it; with stop_on_error, no
+// workflow starts after a failure.
+func executeWorkflowsParallel(cmd *cobra.Command, workflows []config.WorkflowEntry, masterCfg *config.MasterConfig, notifier *masterNotifier) []workflowResult {
 	results := make([]workflowResult, len(workflows))
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	completed := make(map[string]bool)
-
-	// First pass: run workflows without dependencies
-	sem := make(chan struct{}, maxOrDefault(masterCfg.MaxParallel, len(workflows)))
+	noProgress = true
 
+	nodes := make([]planner.MasterWorkflow, len(workflows))
+	index := make(map[string]int, len(workflows))
 	for i, w := range workflows {
-		if len(w.Needs) > 0 {
-			continue // Skip workflows with dependencies for now
-		}
+		nodes[i] = planner.MasterWorkflow{Name: w.Name, Path: w.Path, Needs: w.Needs}
+		index[w.Name] = i
+	}
+	dag := planner.MasterDAG(nodes)
 
-		wg.Add(1)
-		go func(idx int, workflow config.WorkflowEntry) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
+	remaining := make(map[string]int, len(workflows))
+	for name, degree := range dag.InDegree {
+		remaining[name] = degree
+	}
+	started := make([]bool, len(workflows))
+	outputs := make(map[string]map[string]string)
 
-			fmt.Printf("\n%s[%s]%s Starting...\n", ui.Orange, workflow.Name, ui.Reset)
+	// skip marks a workflow that will never run, and the workflows needing it
+	var skip func(i int, reason string)
+	skip = func(i int, reason string) {
+		if started[i] {
+			return
+		}
+		started[i] = true
+		w := workflows[i]
+		ui.Warning("Skipping %s: %s", w.Name, reason)
+		results[i] = workflowResult{Name: w.Name, Success: false, Error: errors.New(reason)}
+		notifier.workflowSkipped(w, reason)
+		for _, dependent := range dag.GetDependents(w.Name) {
+			skip(index[dependent], "dependencies not met")
+		}
+	}
+	for i, w := range workflows {
+		for _, dep := range w.Needs {
+			if _, ok := index[dep]; !ok {
+				skip(i, "dependencies not met")
+				break
+			}
+		}
+	}
 
-			success, tasks, err := runSingleConfig(cmd, workflow.Path)
+	limit := maxOrDefault(masterCfg.MaxParallel, len(workflows))
+	finished := make(chan int)
+	running := 0
+	stopped := "" // Workflow whose failure stopped the run
 
-			mu.Lock()
-			results[idx] = workflowResult{
-				Name:    workflow.Name,
-				Success: success,
-				Tasks:   tasks,
-				Error:   err,
+	launch := func() {
+		for i, w := range workflows {
+			if running >= limit {
+				return
 			}
-			if success {
-				completed[workflow.Name] = true
+			if started[i] || remaining[w.Name] > 0 {
+				continue
 			}
-			mu.Unlock()
+			started[i] = true
+			running++
 
-			if success {
-				fmt.Printf("%s[%s]%s %sCompleted%s\n", ui.Orange, workflow.Name, ui.Reset, ui.Green, ui.Reset)
+			if needs := dag.GetDependencies(w.Name); len(needs) > 0 {
+				fmt.Fprintf(ui.Stdout, "\n%s[%s]%s Starting (deps: %v)...\n", ui.Orange, w.Name, ui.Reset, needs)
 			} else {
-				fmt.Printf("%s[%s]%s %sFailed%s\n", ui.Orange, workflow.Name, ui.Reset, ui.Red, ui.Reset)
+				fmt.Fprintf(ui.Stdout, "\n%s[%s]%s Starting...\n", ui.Orange, w.Name, ui.Reset)
 			}
-		}(i, w)
+			go func(idx int, workflow config.WorkflowEntry, outputs map[string]map[string]string) {
+				results[idx] = runWorkflowEntry(cmd, masterCfg, workflow, outputs, notifier)
+				finished <- idx
+			}(i, w, maps.Clone(outputs))
+		}
 	}
 
-	wg.Wait()
-
-	// Second pass: run workflows with dependencies (sequentially for simplicity)
-	for i, w := range workflows {
-		if len(w.Needs) == 0 {
-			continue // Already ran
-		}
+	launch()
+	for running > 0 {
+		i := <-finished
+		running--
+		w := workflows[i]
 
-		// Check dependencies
-		canRun := true
-		for _, dep := range w.Needs {
-			if !completed[dep] {
-				canRun = false
-				break
+		if results[i].Success {
+			fmt.Fprintf(ui.Stdout, "%s[%s]%s %s Completed\n", ui.Orange, w.Name, ui.Reset, ui.StatusIcon(ui.StatusSuccess))
+			outputs[w.Name] = results[i].Outputs
+			for _, dependent := range dag.GetDependents(w.Name) {
+				remaining[dependent]--
+			}
+		} else {
+			fmt.Fprintf(ui.Stdout, "%s[%s]%s %s Failed\n", ui.Orange, w.Name, ui.Reset, ui.StatusIcon(ui.StatusFailed))
+			for _, dependent := range dag.GetDependents(w.Name) {
+				skip(index[dependent], "dependencies not met")
+			}
+			if stopped == "" && masterCfg.StopOnError != nil && *masterCfg.StopOnError {
+				stopped = w.Name
+				ui.Error("Stopping due to error in %s", w.Name)
 			}
 		}
 
-		if !canRun {
-			results[i] = workflowResult{Name: w.Name, Success: false, Error: fmt.Errorf("dependencies not met")}
-			continue
-		}
-
-		fmt.Printf("\n%s[%s]%s Starting (deps: %v)...\n", ui.Orange, w.Name, ui.Reset, w.Needs)
-
-		success, tasks, err := runSingleConfig(cmd, w.Path)
-		results[i] = workflowResult{
-			Name:    w.Name,
-			Success: success,
-			Tasks:   tasks,
-			Error:   err,
+		if stopped == "" {
+			launch()
 		}
+	}
 
-		if success {
-			completed[w.Name] = true
-			fmt.Printf("%s[%s]%s %sCompleted%s\n", ui.Orange, w.Name, ui.Reset, ui.Green, ui.Reset)
-		} else {
-			fmt.Printf("%s[%s]%s %sFailed%s\n", ui.Orange, w.Name, ui.Reset, ui.Red, ui.Reset)
+	if stopped != "" {
+		for i := range workflows {
+			skip(i, "stopped after failure in "+stopped)
 		}
 	}
-
 	return results
 }
 
@@ -1317,32 +1968,3 @@ func maxOrDefault(val, def int) int {
 	}
 	return def
 }
-
-// setupLogger configures the global logger based on CLI flags
-func setupLogger(cmd *cobra.Command) {
-	format := observability.FormatText
-	if logFormat == "json" {
-		format = observability.FormatJSON
-	}
-
-	level := observability.ParseLogLevel(logLevel)
-
-	var output = os.Stderr
-	if logFile != "" {
-		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			ui.Warning("Failed to open log file %s: %s", logFile, err)
-		} else {
-			output = f
-		}
-	}
-
-	logger := observability.NewLogger(observability.LoggerConfig{
-		Level:   level,
-		Format:  format,
-		Output:  output,
-		Enabled: true,
-	})
-
-	observability.SetGlobalLogger(logger)
-}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// Output formats for --output.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// RunOutput is the JSON document written by `cortex run --output json`.
+type RunOutput struct {
+	Success bool        `json:"success"`
+	Error   string      `json:"error,omitempty"`
+	Runs    []RunReport `json:"runs"`
+}
+
+// RunReport describes the run of a single Cortexfile.
+type RunReport struct {
+	ConfigFile string       `json:"config_file"`
+	Project    string       `json:"project,omitempty"`
+	RunID      string       `json:"run_id,omitempty"`
+	RunDir     string       `json:"run_dir,omitempty"`
+	Success    bool         `json:"success"`
+	Duration   string       `json:"duration,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	TokensUsed int          `json:"tokens_used,omitempty"`
+	CostUSD    float64      `json:"cost_usd,omitempty"`
+	Tasks      []TaskReport `json:"tasks"`
+}
+
+// TaskReport describes a single task result. Output is left in the run dir.
+type TaskReport struct {
+	Name       string  `json:"name"`
+	Agent      string  `json:"agent"`
+	Tool       string  `json:"tool"`
+	Model      string  `json:"model,omitempty"`
+	Status     string  `json:"status"`
+	Success    bool    `json:"success"`
+	ExitCode   int     `json:"exit_code"`
+	Duration   string  `json:"duration"`
+	SkipReason string  `json:"skip_reason,omitempty"`
+	TokensUsed int     `json:"tokens_used,omitempty"`
+	CostUSD    float64 `json:"cost_usd,omitempty"`
+}
+
+// ValidateOutput is the JSON document written by `cortex validate --output json`.
+type ValidateOutput struct {
+	ConfigFile string            `json:"config_file,omitempty"`
+	Valid      bool              `json:"valid"`
+	Errors     []ValidationIssue `json:"errors,omitempty"`
+	Agents     int               `json:"agents,omitempty"`
+	Tasks      int               `json:"tasks,omitempty"`
+	Levels     int               `json:"levels,omitempty"`
+	Estimate   *EstimateOutput   `json:"estimate,omitempty"`
+}
+
+// EstimateOutput is the duration and cost of a run estimated from the
+// project's past runs.
+type EstimateOutput struct {
+	Runs              int      `json:"runs"`
+	SequentialSeconds float64  `json:"sequential_seconds"`
+	ParallelSeconds   float64  `json:"parallel_seconds"`
+	MaxParallel       int      `json:"max_parallel"`
+	UnlimitedSeconds  float64  `json:"unlimited_seconds"`
+	CostUSD           float64  `json:"cost_usd"`
+	Approximate       []string `json:"approximate,omitempty"` // Estimated from other tasks using the same model
+	NoHistory         []string `json:"no_history,omitempty"`
+}
+
+// LintOutput is the JSON document written by `cortex lint --output json`.
+type LintOutput struct {
+	ConfigFile string            `json:"config_file,omitempty"`
+	Valid      bool              `json:"valid"`
+	Errors     []ValidationIssue `json:"errors,omitempty"`
+	Warnings   []LintIssue       `json:"warnings"`
+}
+
+// LintIssue is a single lint warning.
+type LintIssue struct {
+	Rule    string `json:"rule"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// ValidationIssue is a single configuration error.
+type ValidationIssue struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// SessionsOutput is the JSON document written by `cortex sessions --output json`.
+type SessionsOutput struct {
+	Sessions []state.SessionInfo `json:"sessions"`
+}
+
+// StatusOutput is the JSON document written by `cortex status --output json`.
+type StatusOutput struct {
+	Runs []state.RunStatus `json:"runs"`
+}
+
+// SessionOutput is the JSON document written by `cortex sessions show --output json`.
+type SessionOutput struct {
+	Project  string             `json:"project"`
+	RunID    string             `json:"run_id"`
+	RunDir   string             `json:"run_dir"`
+	Complete bool               `json:"complete"`
+	Success  bool               `json:"success"`
+	Tasks    []state.TaskResult `json:"tasks"`
+}
+
+// SearchOutput is the JSON document written by `cortex sessions search
+// --output json`.
+type SearchOutput struct {
+	Query   string              `json:"query"`
+	Matches []state.SearchMatch `json:"matches"`
+}
+
+// SessionExport is the JSON document written by `cortex sessions export
+// --format json`: the run result, with the task results saved so far for
+// runs that did not finish.
+type SessionExport struct {
+	Project  string `json:"project"`
+	Complete bool   `json:"complete"`
+	*state.RunResult
+}
+
+// VerifyOutput is the JSON document written by `cortex verify --output json`.
+type VerifyOutput struct {
+	RunDir   string `json:"run_dir"`
+	Verified bool   `json:"verified"`
+	*state.Verification
+}
+
+// addOutputFlags registers -o/--output, the format of a command's output,
+// and --format, another name for it.
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", outputText, "Output format: text or json")
+	cmd.Flags().StringVar(&outputFormat, "format", outputText, "Same as --output")
+}
+
+// checkOutputFormat validates the --output flag value.
+func checkOutputFormat() error {
+	if outputFormat != outputText && outputFormat != outputJSON {
+		return fmt.Errorf("invalid output format %q: must be %q or %q", outputFormat, outputText, outputJSON)
+	}
+	return nil
+}
+
+// suppressUI silences the terminal UI so stdout carries only the JSON
+// document. Returns the real stdout to write to and a function that
+// restores it.
+func suppressUI() (*os.File, func()) {
+	stdout := os.Stdout
+	ui.SetColorsEnabled(false)
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return stdout, func() {}
+	}
+	os.Stdout = devNull
+
+	return stdout, func() {
+		os.Stdout = stdout
+		devNull.Close()
+	}
+}
+
+// validationIssues flattens a validation error into one issue per problem.
+func validationIssues(err error) []ValidationIssue {
+	if errs, ok := err.(*config.ConfigErrors); ok {
+		issues := make([]ValidationIssue, 0, len(errs.Errors))
+		for _, e := range errs.Errors {
+			issues = append(issues, ValidationIssue{File: e.File, Line: e.Line, Message: e.Message, Hint: e.Hint})
+		}
+		return issues
+	}
+	return []ValidationIssue{{Message: err.Error()}}
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(out io.Writer, v interface{}) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// newRunReport builds a report from a config run, which may be nil if the
+// workflow failed before execution.
+func newRunReport(configPath string, run *configRun, err error) RunReport {
+	report := RunReport{
+		ConfigFile: configPath,
+		Tasks:      []TaskReport{},
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	if run == nil {
+		return report
+	}
+
+	report.Project = run.Project
+	report.RunID = run.Result.RunID
+	report.RunDir = run.RunDir
+	report.Success = run.Result.Success && err == nil
+	report.Duration = run.Duration.Round(time.Millisecond * 100).String()
+	report.TokensUsed = run.Result.TokenUsage.TotalTokens
+	report.CostUSD = run.Result.CostUSD
+
+	for _, t := range run.Result.Tasks {
+		report.Tasks = append(report.Tasks, TaskReport{
+			Name:       t.TaskName,
+			Agent:      t.Agent,
+			Tool:       t.Tool,
+			Model:      t.Model,
+			Status:     t.Status,
+			Success:    t.Success,
+			ExitCode:   t.ExitCode,
+			Duration:   t.Duration,
+			SkipReason: t.SkipReason,
+			TokensUsed: t.TokenUsage.TotalTokens,
+			CostUSD:    t.CostUSD,
+		})
+	}
+	return report
+}
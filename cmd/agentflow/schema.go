@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/config"
+)
+
+// newSchemaCmd creates the `cortex schema` command.
+func newSchemaCmd() *cobra.Command {
+	var schemaType string
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of a config file",
+		Long: `Prints the JSON Schema of a Cortexfile, MasterCortex file, or the global
+config, for editors to complete and check them. For example, with the VS Code
+YAML extension:
+
+  cortex schema > .vscode/cortexfile.schema.json
+
+and in .vscode/settings.json:
+
+  "yaml.schemas": { ".vscode/cortexfile.schema.json": "Cortexfile.yml" }`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, err := config.GenerateSchema(schemaType)
+			if err != nil {
+				return err
+			}
+			data, err := json.MarshalIndent(schema, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		},
+	}
+	schemaCmd.Flags().StringVar(&schemaType, "type", config.SchemaCortexfile,
+		"Config file: "+strings.Join(config.SchemaTypes, ", "))
+	return schemaCmd
+}
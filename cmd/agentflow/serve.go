@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/proc"
+	"github.com/adityaraj/agentflow/internal/server"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// serveShutdownTimeout is how long open requests get to finish on shutdown.
+const serveShutdownTimeout = 10 * time.Second
+
+// serveOptions holds the flags of the serve command.
+type serveOptions struct {
+	host  string
+	port  int
+	files []string // Cortexfiles whose triggers are served
+
+	maxRuns   int    // Runs at once (cortex daemon), 0 for no limit
+	queueFile string // Where queued runs are kept across restarts (cortex daemon)
+}
+
+// newServeCmd creates the `cortex serve` command.
+func newServeCmd() *cobra.Command {
+	var opts serveOptions
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve a REST API and web dashboard for triggering and watching runs",
+		Long: `Starts an HTTP server that runs workflows on request:
+
+  GET  /                  Web dashboard
+  POST /runs              Start a run ({"file": ...} or {"yaml": ...})
+  GET  /runs              List runs started by this server
+  GET  /runs/<id>         Run status and task results
+  GET  /runs/<id>/logs    Console output of the run
+  GET  /runs/<id>/events  Console output and status as server-sent events
+  GET  /sessions          Past runs of every project
+  GET  /metrics           Prometheus metrics of runs and tasks
+  POST /triggers/<name>   Webhook of a trigger declared in a --file Cortexfile
+
+Requests need a bearer token: one of server.users in ~/.cortex/config.yml, or
+without users, the token in ` + daemonTokenEnv + ` or else a random one printed at
+start. Without users, the server only listens on and answers to localhost.
+Webhooks are checked against the trigger's secret_env instead.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serve(opts)
+		},
+	}
+
+	serveCmd.Flags().StringVar(&opts.host, "host", "127.0.0.1", "Address to listen on")
+	serveCmd.Flags().IntVar(&opts.port, "port", 8080, "Port to listen on")
+	serveCmd.Flags().StringArrayVarP(&opts.files, "file", "f", nil, "Cortexfile whose triggers to serve (repeatable)")
+
+	return serveCmd
+}
+
+func serve(opts serveOptions) error {
+	globalCfg, err := config.LoadGlobalConfig()
+	if err != nil {
+		ui.Error("Failed to load global config: %s", err)
+		return err
+	}
+	auth, err := server.NewAuth(globalCfg.Server)
+	if err != nil {
+		ui.Error("Invalid server config: %s", err)
+		return err
+	}
+	// Without users, a token still keeps web pages the browser visits from
+	// starting runs
+	localToken := ""
+	if !auth.Enabled() {
+		if !server.IsLoopback(opts.host) {
+			err := fmt.Errorf("server.users must be configured to listen on %s", opts.host)
+			ui.Error("%s", err)
+			return err
+		}
+		localToken = os.Getenv(daemonTokenEnv)
+		if localToken == "" {
+			if localToken, err = newToken(); err != nil {
+				return err
+			}
+		}
+		auth.AddLocalToken(localToken)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	// Interrupting the server interrupts its runs
+	ctx, stop := signal.NotifyContext(context.Background(), proc.ShutdownSignals...)
+	defer stop()
+
+	srv := server.New(ctx, server.Config{
+		Executable: executable,
+		Auth:       auth,
+		WorkDir:    cwd,
+		MaxRuns:    opts.maxRuns,
+		QueueFile:  opts.queueFile,
+	})
+	restored, err := srv.LoadQueue()
+	if err != nil {
+		ui.Error("Cannot restore the run queue: %s", err)
+		return err
+	}
+	if restored > 0 {
+		ui.Info("Restored %d queued run(s) from %s", restored, opts.queueFile)
+	}
+	for _, file := range opts.files {
+		triggers, err := srv.AddWorkflow(file)
+		if err != nil {
+			ui.Error("Cannot serve triggers of %s: %s", file, err)
+			return err
+		}
+		for _, t := range triggers {
+			ui.Info("Trigger %s (%s): POST %s", t.Name, t.Type, t.Path)
+			if !t.Secret {
+				ui.Warning("Trigger %s has no secret_env: any process on this machine can start it", t.Name)
+			}
+		}
+	}
+
+	addr := net.JoinHostPort(opts.host, strconv.Itoa(opts.port))
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ui.Info("Serving on http://%s", addr)
+	if localToken != "" && os.Getenv(daemonTokenEnv) == "" {
+		ui.Info("API token (set %s to choose it): %s", daemonTokenEnv, localToken)
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		ui.Error("Server failed: %s", err)
+		return err
+	}
+
+	srv.Wait()
+	return nil
+}
+
+// newToken returns a random API token.
+func newToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// newSessionsCleanCmd creates the `cortex sessions clean` command.
+func newSessionsCleanCmd() *cobra.Command {
+	var (
+		project   string
+		olderThan string
+		dryRun    bool
+	)
+
+	cleanCmd := &cobra.Command{
+		Use:   "clean --older-than <age>",
+		Short: "Delete runs older than an age",
+		Long: `Deletes saved runs, with their task results and artifacts, that started
+longer ago than --older-than: a number of days (30d), weeks (2w), or a
+duration such as 12h. Runs of every project are cleaned unless --project is
+given. Incremental caches and task cache directories are kept.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cleanSessions(project, olderThan, dryRun)
+		},
+	}
+
+	cleanCmd.Flags().StringVar(&olderThan, "older-than", "", "Delete runs that started longer ago than this (e.g. 30d, 2w, 12h)")
+	cleanCmd.Flags().StringVar(&project, "project", "", "Only clean runs of this project")
+	cleanCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the runs that would be deleted without deleting them")
+	cleanCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	_ = cleanCmd.MarkFlagRequired("older-than")
+
+	return cleanCmd
+}
+
+func cleanSessions(project, olderThan string, dryRun bool) error {
+	if noColor {
+		ui.SetColorsEnabled(false)
+	}
+
+	age, err := config.ParseAge(olderThan)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := state.ListSessions(state.SessionFilter{Project: project})
+	if err != nil {
+		ui.Error("Failed to list sessions: %s", err)
+		return err
+	}
+	// Runs in progress are never deleted, however long ago they started
+	running, err := state.RunningDirs(project)
+	if err != nil {
+		ui.Error("Failed to list running sessions: %s", err)
+		return err
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-age)
+	var removed int
+	var freed int64
+	for _, s := range sessions {
+		started := s.Started()
+		if started.IsZero() || !started.Before(cutoff) || running[s.RunDir] {
+			continue
+		}
+
+		size := state.DirSize(s.RunDir)
+		if dryRun {
+			fmt.Printf("  %s%s/%s%s  %s, %s\n", ui.Bold, s.Project, s.RunID, ui.Reset, ui.FormatAge(started, now), formatBytes(size))
+		} else if err := os.RemoveAll(s.RunDir); err != nil {
+			ui.Warning("Failed to delete %s: %s", s.RunDir, err)
+			continue
+		}
+		removed++
+		freed += size
+	}
+
+	runs := "runs"
+	if removed == 1 {
+		runs = "run"
+	}
+	switch {
+	case removed == 0:
+		fmt.Printf("No runs older than %s.\n", olderThan)
+	case dryRun:
+		fmt.Printf("\nWould delete %d %s, freeing %s.\n", removed, runs, formatBytes(freed))
+	default:
+		ui.Success("Deleted %d %s, freed %s", removed, runs, formatBytes(freed))
+	}
+	return nil
+}
+
+// formatBytes formats a size in bytes with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
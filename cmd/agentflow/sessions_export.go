@@ -0,0 +1,194 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// Formats of `cortex sessions export`.
+const (
+	exportTar  = "tar"
+	exportJSON = "json"
+)
+
+// newSessionsExportCmd creates the `cortex sessions export <run-id>` command.
+func newSessionsExportCmd() *cobra.Command {
+	var project, format, out string
+
+	exportCmd := &cobra.Command{
+		Use:   "export <run-id>",
+		Short: "Export a run as a tar archive or JSON",
+		Long: `Exports a run to share or keep it elsewhere. With --format tar (the
+default), the whole run directory, artifacts and config snapshot included,
+is written as a gzip-compressed tar archive, or zstd-compressed if --out
+ends in .tar.zst; with --format json, the run
+and its task results (prompts and output included) as one JSON document.
+Tar archives can be loaded on another machine with "cortex sessions import".
+
+The file is named <project>-<run-id>.tar.gz or .json unless --out (-o) is
+given; --out - writes to stdout. Unlike in other commands, -o is the file and
+--format the export format, not the format of the command's output. Use "latest" as the run ID for the most recent run.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return exportSession(project, args[0], format, out)
+		},
+	}
+
+	exportCmd.Flags().StringVar(&project, "project", "", "Project name (default: current directory name)")
+	exportCmd.Flags().StringVar(&format, "format", exportTar, "Export format: tar or json")
+	exportCmd.Flags().StringVarP(&out, "out", "o", "", "File to write (.tar.gz or .tar.zst), or - for stdout")
+
+	return exportCmd
+}
+
+func exportSession(project, runID, format, out string) error {
+	if format != exportTar && format != exportJSON {
+		return fmt.Errorf("invalid format %q (use tar or json)", format)
+	}
+
+	project, err := resolveProject(project)
+	if err != nil {
+		return err
+	}
+	runDir, runID, err := state.ResolveRunDir(project, runID)
+	if err != nil {
+		ui.Error("%s", err)
+		return err
+	}
+
+	if out == "" {
+		out = project + "-" + runID + ".tar.gz"
+		if format == exportJSON {
+			out = project + "-" + runID + ".json"
+		}
+	}
+	var w io.Writer = os.Stdout
+	if out != "-" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if format == exportJSON {
+		err = exportSessionJSON(w, project, runID, runDir)
+	} else {
+		err = exportSessionTar(w, filepath.Join(project, "run-"+runID), runDir, isZstdArchive(out))
+	}
+	if err != nil {
+		if out != "-" {
+			_ = os.Remove(out)
+		}
+		ui.Error("Failed to export %s/%s: %s", project, runID, err)
+		return err
+	}
+	if out != "-" {
+		ui.Success("Exported %s/%s to %s", project, runID, out)
+	}
+	return nil
+}
+
+// exportSessionJSON writes a run and its task results as a SessionExport.
+func exportSessionJSON(w io.Writer, project, runID, runDir string) error {
+	export := SessionExport{Project: project, Complete: state.IsRunComplete(runDir)}
+	if export.Complete {
+		run, err := state.GetSession(project, runID)
+		if err != nil {
+			return err
+		}
+		export.RunResult = run
+	} else {
+		tasks, err := state.ListTaskResults(runDir)
+		if err != nil {
+			return err
+		}
+		if err := state.LoadOutputs(runDir, tasks); err != nil {
+			return err
+		}
+		if tasks == nil {
+			tasks = []state.TaskResult{}
+		}
+		export.RunResult = &state.RunResult{RunID: runID, Tasks: tasks}
+	}
+	return writeJSON(w, export)
+}
+
+// isZstdArchive reports whether a file name is that of a zstd-compressed tar
+// archive rather than a gzip-compressed one.
+func isZstdArchive(name string) bool {
+	return strings.HasSuffix(name, ".zst") || strings.HasSuffix(name, ".tzst")
+}
+
+// exportSessionTar writes the files of a run directory to a tar archive,
+// under prefix. The archive is gzip-compressed, or zstd-compressed if zstd
+// is set.
+func exportSessionTar(w io.Writer, prefix, runDir string, zstd bool) error {
+	var zw io.WriteCloser = gzip.NewWriter(w)
+	if zstd {
+		var err error
+		if zw, err = newZstdWriter(w); err != nil {
+			return err
+		}
+	}
+	tw := tar.NewWriter(zw)
+
+	err := filepath.WalkDir(runDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil // Artifacts are copies, so links and devices are not expected
+		}
+
+		rel, err := filepath.Rel(runDir, path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(filepath.Join(prefix, rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
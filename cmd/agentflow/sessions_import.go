@@ -0,0 +1,196 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// newSessionsImportCmd creates the `cortex sessions import <archive>` command.
+func newSessionsImportCmd() *cobra.Command {
+	var project string
+	var force bool
+
+	importCmd := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Import a run exported with sessions export",
+		Long: `Imports a run from a .tar.gz or .tar.zst archive written by
+"cortex sessions export", e.g. one a teammate attached to a bug report. The
+run is saved under the project it was exported from unless --project is
+given, and can then be inspected with sessions show, logs, and diff like a
+local run. Its config snapshot, the Cortexfile and prompt files the run used,
+is in the config directory of the run. Use - to read the archive from stdin.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return importSession(args[0], project, force)
+		},
+	}
+
+	importCmd.Flags().StringVar(&project, "project", "", "Project to import the run into (default: the exported run's project)")
+	importCmd.Flags().BoolVar(&force, "force", false, "Replace a run with the same ID")
+
+	return importCmd
+}
+
+func importSession(archive, project string, force bool) error {
+	if project != "" && (!filepath.IsLocal(project) || filepath.Base(project) != project) {
+		return fmt.Errorf("invalid project name %q", project)
+	}
+
+	var r io.Reader = os.Stdin
+	if archive != "-" {
+		f, err := os.Open(archive)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	fromProject, runID, runDir, err := importSessionTar(r, project, force)
+	if err != nil {
+		ui.Error("Failed to import %s: %s", archive, err)
+		return err
+	}
+	if project == "" {
+		project = fromProject
+	}
+
+	ui.Success("Imported %s/%s to %s", project, runID, runDir)
+	if info, err := os.Stat(filepath.Join(runDir, state.ConfigDir)); err == nil && info.IsDir() {
+		ui.Info("Config snapshot: %s", filepath.Join(runDir, state.ConfigDir))
+	}
+	return nil
+}
+
+// importSessionTar extracts a tar archive written by exportSessionTar into
+// the sessions directory, under project if set. The run is extracted next to
+// its destination first, so a bad archive leaves nothing behind. Returns the
+// project the run was exported from, the run ID, and the run directory.
+func importSessionTar(r io.Reader, project string, force bool) (string, string, string, error) {
+	zr, err := decompressReader(r)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	var fromProject, runID, runDir, tmpDir string
+	defer func() {
+		if tmpDir != "" {
+			_ = os.RemoveAll(tmpDir)
+		}
+	}()
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", "", fmt.Errorf("invalid archive: %w", err)
+		}
+
+		// Entries are <project>/run-<id>/<path>
+		parts := strings.SplitN(path.Clean(hdr.Name), "/", 3)
+		if len(parts) < 2 || !filepath.IsLocal(hdr.Name) || !strings.HasPrefix(parts[1], "run-") || parts[0] == "." {
+			return "", "", "", fmt.Errorf("invalid archive: unexpected entry %q", hdr.Name)
+		}
+
+		if tmpDir == "" {
+			fromProject, runID = parts[0], strings.TrimPrefix(parts[1], "run-")
+			if project == "" {
+				project = fromProject
+			}
+			if runDir, err = state.RunDirPath(project, runID); err != nil {
+				return "", "", "", err
+			}
+			if _, err := os.Stat(runDir); err == nil && !force {
+				return "", "", "", fmt.Errorf("run %s/%s already exists (use --force to replace it)", project, runID)
+			}
+			if err := os.MkdirAll(filepath.Dir(runDir), 0755); err != nil {
+				return "", "", "", err
+			}
+			if tmpDir, err = os.MkdirTemp(filepath.Dir(runDir), ".import-*"); err != nil {
+				return "", "", "", err
+			}
+		} else if parts[0] != fromProject || parts[1] != "run-"+runID {
+			return "", "", "", fmt.Errorf("invalid archive: entry %q is not part of run %s/%s", hdr.Name, fromProject, runID)
+		}
+		if len(parts) < 3 {
+			continue
+		}
+
+		dest := filepath.Join(tmpDir, filepath.FromSlash(parts[2]))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				return "", "", "", err
+			}
+		case tar.TypeReg:
+			if err := extractFile(tr, dest); err != nil {
+				return "", "", "", err
+			}
+		default:
+			// Exports hold only files and directories
+			return "", "", "", fmt.Errorf("invalid archive: unsupported entry %q", hdr.Name)
+		}
+	}
+	if tmpDir == "" {
+		return "", "", "", fmt.Errorf("archive is empty")
+	}
+
+	if force {
+		if err := os.RemoveAll(runDir); err != nil {
+			return "", "", "", err
+		}
+	}
+	if err := os.Rename(tmpDir, runDir); err != nil {
+		return "", "", "", err
+	}
+	tmpDir = ""
+	return fromProject, runID, runDir, nil
+}
+
+// decompressReader returns a reader decompressing r, by its gzip or zstd
+// magic number.
+func decompressReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case bytes.Equal(magic, zstdMagic):
+		return newZstdReader(br)
+	default:
+		return nil, fmt.Errorf("not a .tar.gz or .tar.zst archive")
+	}
+}
+
+// extractFile writes the current entry of tr to dest.
+func extractFile(tr *tar.Reader, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, tr); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// newSessionsPruneCmd creates the `cortex sessions prune` command.
+func newSessionsPruneCmd() *cobra.Command {
+	var (
+		project string
+		dryRun  bool
+	)
+
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the runs the retention policy doesn't keep",
+		Long: `Applies settings.retention to a project's saved runs, as is done after
+every run: the oldest runs beyond max_sessions, older than max_age, or over
+max_size in total are deleted. The policy comes from config.yml and the
+Cortexfile in the current directory. Use --dry-run to see what would be
+deleted, and why, before enabling a policy.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pruneSessions(project, dryRun)
+		},
+	}
+
+	pruneCmd.Flags().StringVar(&project, "project", "", "Project name (default: current directory name)")
+	pruneCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the runs that would be deleted without deleting them")
+	pruneCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+
+	return pruneCmd
+}
+
+func pruneSessions(project string, dryRun bool) error {
+	if noColor {
+		ui.SetColorsEnabled(false)
+	}
+
+	project, err := resolveProject(project)
+	if err != nil {
+		return err
+	}
+	retention, err := loadRetention()
+	if err != nil {
+		ui.Error("%s", err)
+		return err
+	}
+	policy, err := retentionPolicy(retention)
+	if err != nil {
+		ui.Error("%s", err)
+		return err
+	}
+	if policy.IsZero() {
+		fmt.Println("No retention policy set (settings.retention in config.yml or the Cortexfile).")
+		return nil
+	}
+
+	pruned, err := state.ApplyRetention(project, policy, "", dryRun)
+	now := time.Now()
+	var freed int64
+	for _, p := range pruned {
+		freed += p.Size
+		if dryRun {
+			fmt.Printf("  %s%s%s  %s, %s  %s(%s)%s\n", ui.Bold, p.RunID, ui.Reset, ui.FormatAge(p.Started(), now), formatBytes(p.Size), ui.Dim, p.Reason, ui.Reset)
+		}
+	}
+	if err != nil {
+		ui.Error("Failed to prune sessions: %s", err)
+		return err
+	}
+
+	runs := "runs"
+	if len(pruned) == 1 {
+		runs = "run"
+	}
+	switch {
+	case len(pruned) == 0:
+		fmt.Printf("Nothing to prune in %s.\n", project)
+	case dryRun:
+		fmt.Printf("\nWould delete %d %s of %s, freeing %s.\n", len(pruned), runs, project, formatBytes(freed))
+	default:
+		ui.Success("Deleted %d %s of %s, freed %s", len(pruned), runs, project, formatBytes(freed))
+	}
+	return nil
+}
+
+// loadRetention returns settings.retention of config.yml, overridden by the
+// Cortexfile in the current directory, if there is one.
+func loadRetention() (config.RetentionConfig, error) {
+	globalCfg, err := config.LoadGlobalConfig()
+	if err != nil {
+		return config.RetentionConfig{}, fmt.Errorf("failed to load global config: %w", err)
+	}
+	paths, err := resolveConfigFiles()
+	if err != nil || len(paths) == 0 {
+		return globalCfg.Settings.Retention, nil
+	}
+	localCfg, err := config.LoadConfig(paths[0])
+	if err != nil {
+		return config.RetentionConfig{}, fmt.Errorf("failed to load config: %w", err)
+	}
+	return config.MergeConfigs(globalCfg, localCfg, nil).Settings.Retention, nil
+}
+
+// retentionPolicy converts settings.retention to the limits the state
+// package enforces.
+func retentionPolicy(r config.RetentionConfig) (state.RetentionPolicy, error) {
+	maxAge, maxBytes, err := r.Limits()
+	if err != nil {
+		return state.RetentionPolicy{}, fmt.Errorf("settings.retention: %w", err)
+	}
+	return state.RetentionPolicy{MaxSessions: r.MaxSessions, MaxAge: maxAge, MaxBytes: maxBytes}, nil
+}
+
+// enforceRetention deletes the project's runs that settings.retention
+// doesn't keep, other than the run that just finished. Failures are
+// warnings: they don't fail the run.
+func enforceRetention(project, runID string, r config.RetentionConfig) {
+	if r.IsZero() {
+		return
+	}
+	policy, err := retentionPolicy(r)
+	if err != nil {
+		ui.Warning("Retention not applied: %s", err)
+		return
+	}
+
+	pruned, err := state.ApplyRetention(project, policy, runID, false)
+	if err != nil {
+		ui.Warning("Failed to prune old runs: %s", err)
+		slog.Warn("failed to prune sessions", "project", project, "error", err)
+	}
+	if len(pruned) == 0 {
+		return
+	}
+	var freed int64
+	for _, p := range pruned {
+		freed += p.Size
+		slog.Info("session pruned", "project", project, "run_id", p.RunID, "reason", p.Reason, "bytes", p.Size)
+	}
+	runs := "runs"
+	if len(pruned) == 1 {
+		runs = "run"
+	}
+	ui.Info("Retention: deleted %d old %s, freed %s", len(pruned), runs, formatBytes(freed))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// newSessionsSearchCmd creates the `cortex sessions search <query>` command.
+func newSessionsSearchCmd() *cobra.Command {
+	var (
+		project       string
+		limit         int
+		caseSensitive bool
+	)
+
+	searchCmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search the prompts and output of saved runs",
+		Long: `Searches the prompts, output, and errors saved for every task of every run,
+newest run first, and shows each matching line with the run and task it
+belongs to. The search covers all projects unless --project is given, and
+ignores case unless --case-sensitive is set. 'cortex logs <run-id> <task>'
+shows a match's full output.`,
+		Example: `  cortex sessions search "circular import"
+  cortex sessions search --project api --limit 5 TODO`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return searchSessions(args[0], state.SearchFilter{Project: project, Limit: limit, CaseSensitive: caseSensitive})
+		},
+	}
+
+	searchCmd.Flags().StringVar(&project, "project", "", "Search only this project (default: all projects)")
+	searchCmd.Flags().IntVar(&limit, "limit", 20, "Maximum number of matches to show (0 = no limit)")
+	searchCmd.Flags().BoolVar(&caseSensitive, "case-sensitive", false, "Match case exactly")
+	addOutputFlags(searchCmd)
+	searchCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+
+	return searchCmd
+}
+
+func searchSessions(query string, filter state.SearchFilter) error {
+	if err := checkOutputFormat(); err != nil {
+		return err
+	}
+	if noColor || outputFormat == outputJSON {
+		ui.SetColorsEnabled(false)
+	}
+	if query == "" {
+		return fmt.Errorf("search query is empty")
+	}
+	if filter.Limit < 0 {
+		return fmt.Errorf("invalid --limit %d: must not be negative", filter.Limit)
+	}
+
+	matches, err := state.Search(query, filter)
+	if err != nil {
+		if outputFormat != outputJSON {
+			ui.Error("Failed to search sessions: %s", err)
+		}
+		return err
+	}
+
+	if outputFormat == outputJSON {
+		if matches == nil {
+			matches = []state.SearchMatch{}
+		}
+		return writeJSON(os.Stdout, SearchOutput{Query: query, Matches: matches})
+	}
+
+	if len(matches) == 0 {
+		fmt.Printf("%sNo matches for %q.%s\n", ui.Dim, query, ui.Reset)
+		return nil
+	}
+
+	runs := make(map[string]bool)
+	last := ""
+	for _, m := range matches {
+		// One heading per task of a run
+		if location := m.Project + "/" + m.RunID + "/" + m.Task; location != last {
+			if last != "" {
+				fmt.Println()
+			}
+			last = location
+			runs[m.Project+"/"+m.RunID] = true
+			fmt.Printf("%s%s%s %s%s%s  %s\n", ui.Bold, m.Project, ui.Reset, ui.Dim, m.RunID, ui.Reset, m.Task)
+		}
+		end := min(m.Offset+len(query), len(m.Snippet))
+		fmt.Printf("  %s%s:%d%s  %s%s%s%s%s\n", ui.Dim, m.Field, m.Line, ui.Reset,
+			m.Snippet[:m.Offset], ui.Bold+ui.Yellow, m.Snippet[m.Offset:end], ui.Reset, m.Snippet[end:])
+	}
+
+	matchWord, runWord := "matches", "runs"
+	if len(matches) == 1 {
+		matchWord = "match"
+	}
+	if len(runs) == 1 {
+		runWord = "run"
+	}
+	fmt.Printf("\n%s%d %s in %d %s", ui.Dim, len(matches), matchWord, len(runs), runWord)
+	if filter.Limit > 0 && len(matches) == filter.Limit {
+		fmt.Printf(" (limited to %d; use --limit for more)", filter.Limit)
+	}
+	fmt.Printf("%s\n", ui.Reset)
+	return nil
+}
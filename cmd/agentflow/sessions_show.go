@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// newSessionsShowCmd creates the `cortex sessions show <run-id>` command.
+func newSessionsShowCmd() *cobra.Command {
+	var project string
+	var tools bool
+
+	showCmd := &cobra.Command{
+		Use:   "show <run-id>",
+		Short: "Show a run's tasks and collected artifacts",
+		Long: `Shows a run and each of its tasks: status, duration, agent, exit code,
+token usage and cost, whether its output was truncated, why it was skipped
+or how it failed, the tools an agent used and the files it edited, the
+files a write task changed and their commit, and the artifacts collected
+into the run directory. Use "latest" as the run ID for the most recent run;
+'cortex logs' shows the tasks' output.
+
+With --tools, each tool call of claude-code tasks is listed in order, with
+its target and when it started relative to the task.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showSession(project, args[0], tools)
+		},
+	}
+
+	showCmd.Flags().StringVar(&project, "project", "", "Project name (default: current directory name)")
+	showCmd.Flags().BoolVar(&tools, "tools", false, "List every tool call of each task")
+	addOutputFlags(showCmd)
+	showCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+
+	return showCmd
+}
+
+func showSession(project, runID string, tools bool) error {
+	if err := checkOutputFormat(); err != nil {
+		return err
+	}
+	if noColor || outputFormat == outputJSON {
+		ui.SetColorsEnabled(false)
+	}
+
+	project, err := resolveProject(project)
+	if err != nil {
+		return err
+	}
+
+	runDir, runID, err := state.ResolveRunDir(project, runID)
+	if err != nil {
+		if outputFormat != outputJSON {
+			ui.Error("%s", err)
+		}
+		return err
+	}
+
+	tasks, err := state.ListTaskResults(runDir)
+	if err != nil {
+		return err
+	}
+	if err := state.LoadOutputs(runDir, tasks); err != nil {
+		return err
+	}
+	complete := state.IsRunComplete(runDir)
+	success := complete
+	run, err := state.GetSession(project, runID)
+	if err == nil {
+		success = run.Success
+	}
+
+	if outputFormat == outputJSON {
+		if tasks == nil {
+			tasks = []state.TaskResult{}
+		}
+		return writeJSON(os.Stdout, SessionOutput{
+			Project:  project,
+			RunID:    runID,
+			RunDir:   runDir,
+			Complete: complete,
+			Success:  success,
+			Tasks:    tasks,
+		})
+	}
+
+	status := ui.StatusIcon(ui.StatusSuccess) + " success"
+	switch {
+	case !complete:
+		status = ui.StatusIcon(ui.StatusRunning) + " running"
+	case run != nil && run.Cancelled:
+		status = ui.StatusIcon(ui.StatusCancelled) + " cancelled"
+	case !success:
+		status = ui.StatusIcon(ui.StatusFailed) + " failed"
+	}
+	fmt.Printf("%s%s%s %s%s%s  %s\n", ui.Bold, project, ui.Reset, ui.Dim, runID, ui.Reset, status)
+	if run != nil && !run.StartTime.IsZero() {
+		when := ui.FormatTime(run.StartTime) + " (" + ui.FormatAge(run.StartTime, time.Now()) + ")"
+		if !run.EndTime.IsZero() {
+			when += ", took " + ui.FormatDuration(run.EndTime.Sub(run.StartTime))
+		}
+		if run.User != "" {
+			when += ", by " + run.User
+		}
+		fmt.Printf("%s%s%s\n", ui.Dim, when, ui.Reset)
+		if totals := usageSummary(run.TokenUsage, run.CostUSD); totals != "" {
+			fmt.Printf("%s%s%s\n", ui.Dim, totals, ui.Reset)
+		}
+		if run.Error != "" {
+			fmt.Printf("%s\n", ui.RedText(run.Error))
+		}
+	}
+	fmt.Printf("%s%s%s\n", ui.Dim, runDir, ui.Reset)
+	fmt.Printf("%s─────────────────────────────────────────────────%s\n", ui.Dim, ui.Reset)
+
+	if len(tasks) == 0 {
+		fmt.Printf("%sNo task results saved for this run.%s\n", ui.Dim, ui.Reset)
+		return nil
+	}
+
+	for _, t := range tasks {
+		statusIcon := ui.StatusIcon(ui.StatusSuccess)
+		switch t.Status {
+		case state.StatusFailed:
+			statusIcon = ui.StatusIcon(ui.StatusFailed)
+		case state.StatusSkipped:
+			statusIcon = ui.StatusIcon(ui.StatusSkipped)
+		case state.StatusCancelled:
+			statusIcon = ui.StatusIcon(ui.StatusCancelled)
+		}
+		fmt.Printf("  %s %s%s%s %s(%s, %s)%s\n", statusIcon, ui.Bold, t.TaskName, ui.Reset, ui.Dim, t.Tool, ui.FormatDuration(t.Elapsed()), ui.Reset)
+		for _, line := range taskDetails(t) {
+			fmt.Printf("      %s%s%s\n", ui.Dim, line, ui.Reset)
+		}
+
+		if tools {
+			for i, call := range t.ToolCalls {
+				fmt.Printf("      %s%3d  +%-7s%s %s%s\n", ui.Dim, i+1, ui.FormatDuration(call.StartTime.Sub(t.StartTime)), ui.Reset, call.Tool, toolCallSuffix(call))
+			}
+		}
+
+		for _, artifact := range t.Artifacts {
+			fmt.Printf("      %s↳%s %s\n", ui.Dim, ui.Reset, filepath.Join(runDir, filepath.FromSlash(artifact)))
+		}
+	}
+
+	fmt.Println()
+	return nil
+}
+
+// taskDetails returns the lines describing a saved task result under its
+// name: agent, exit code, usage, and whether its output was truncated, then
+// why it was skipped or the last line of stderr of a failure.
+func taskDetails(t state.TaskResult) []string {
+	details := []string{"agent " + t.Agent}
+	if t.Model != "" {
+		details[0] += " (" + t.Model + ")"
+	}
+	switch t.Status {
+	case state.StatusSkipped:
+		// Never ran
+	case state.StatusCancelled:
+		details = append(details, "cancelled")
+	default:
+		details = append(details, fmt.Sprintf("exit %d", t.ExitCode))
+	}
+	if usage := usageSummary(t.TokenUsage, t.CostUSD); usage != "" {
+		details = append(details, usage)
+	}
+	if t.Truncated {
+		details = append(details, "output truncated")
+	}
+	lines := []string{strings.Join(details, " · ")}
+
+	if len(t.ToolCounts) > 0 {
+		tools := make([]string, 0, len(t.ToolCounts))
+		for _, tool := range slices.Sorted(maps.Keys(t.ToolCounts)) {
+			tools = append(tools, fmt.Sprintf("%s %d", tool, t.ToolCounts[tool]))
+		}
+		lines = append(lines, "tools: "+strings.Join(tools, ", "))
+	}
+	if edited := t.EditedFiles(); len(edited) > 0 {
+		lines = append(lines, "edited: "+strings.Join(edited, ", "))
+	}
+	if summary := t.ChangeSummary(); summary != "" {
+		if t.DiffFile != "" {
+			summary += ", diff in " + t.DiffFile
+		}
+		lines = append(lines, summary)
+	}
+	if t.Commit != "" {
+		lines = append(lines, "commit: "+t.Commit[:min(len(t.Commit), 12)])
+	}
+
+	switch {
+	case t.SkipReason != "":
+		lines = append(lines, "skipped: "+t.SkipReason)
+	case t.Status == state.StatusFailed:
+		if stderr := strings.TrimSpace(t.Stderr); stderr != "" {
+			lines = append(lines, "stderr: "+stderr[strings.LastIndexByte(stderr, '\n')+1:])
+		}
+	}
+	return lines
+}
+
+// toolCallSuffix returns the target of a tool call and whether it failed,
+// for the --tools listing.
+func toolCallSuffix(call state.ToolCall) string {
+	suffix := ""
+	if call.Target != "" {
+		target := strings.ReplaceAll(call.Target, "\n", " ")
+		if len(target) > 80 {
+			target = target[:77] + "..."
+		}
+		suffix += " " + ui.Dim + target + ui.Reset
+	}
+	if call.Error {
+		suffix += " " + ui.RedText("(error)")
+	}
+	return suffix
+}
+
+// usageSummary describes token usage and cost, or returns "" if there was
+// none.
+func usageSummary(usage state.TokenUsage, costUSD float64) string {
+	var parts []string
+	if usage.TotalTokens > 0 {
+		parts = append(parts, fmt.Sprintf("%s tokens (%s in, %s out)",
+			ui.FormatTokenCount(usage.TotalTokens), ui.FormatTokenCount(usage.InputTokens), ui.FormatTokenCount(usage.OutputTokens)))
+	}
+	if costUSD > 0 {
+		parts = append(parts, fmt.Sprintf("$%.4f", costUSD))
+	}
+	return strings.Join(parts, " · ")
+}
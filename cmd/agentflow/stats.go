@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/adityaraj/agentflow/internal/analytics"
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// newStatsCmd creates the `cortex stats` command.
+func newStatsCmd() *cobra.Command {
+	var self bool
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show usage statistics",
+		Long: `With --self, summarizes your own use of cortex: the commands, flags, tools,
+and workflow features you use and how your runs turn out. It is read from
+the local analytics file, which is only written after opting in with
+'analytics.enabled: true' in config.yml.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !self {
+				return fmt.Errorf("nothing to show; use --self for your own usage")
+			}
+			return showSelfStats()
+		},
+	}
+	statsCmd.Flags().BoolVar(&self, "self", false, "Summarize your own cortex usage from the local analytics file")
+	statsCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return statsCmd
+}
+
+func showSelfStats() error {
+	if noColor {
+		ui.SetColorsEnabled(false)
+	}
+
+	path, err := analytics.Path()
+	if err != nil {
+		return err
+	}
+	records, err := analytics.Load(path)
+	if err != nil {
+		ui.Error("%s", err)
+		return err
+	}
+
+	globalCfg, err := config.LoadGlobalConfig()
+	enabled := err == nil && globalCfg.Analytics.Enabled
+	if len(records) == 0 {
+		if enabled {
+			fmt.Printf("No usage recorded yet in %s.\n", path)
+		} else {
+			fmt.Println("Usage analytics are off. Add this to config.yml to record them locally:")
+			fmt.Printf("\n  analytics:\n    enabled: true\n\n")
+			fmt.Printf("%sNothing is sent anywhere unless analytics.remote is set.%s\n", ui.Dim, ui.Reset)
+		}
+		return nil
+	}
+
+	s := analytics.Summarize(records)
+	status := "on"
+	if !enabled {
+		status = "off (showing earlier records)"
+	}
+	fmt.Printf("%sUsage analytics:%s %s\n", ui.Bold, ui.Reset, status)
+	fmt.Printf("  %s%s%s\n", ui.Dim, path, ui.Reset)
+	fmt.Printf("  %d commands from %s to %s\n", s.Commands, s.First.Local().Format("2006-01-02"), s.Last.Local().Format("2006-01-02"))
+
+	printCounts("Commands", s.ByCommand, false)
+	if s.Runs > 0 {
+		fmt.Printf("\n%sRuns%s\n", ui.Bold, ui.Reset)
+		succeeded := s.Runs - s.RunsFailed
+		fmt.Printf("  %d runs, %d succeeded (%.0f%%)\n", s.Runs, succeeded, 100*float64(succeeded)/float64(s.Runs))
+		fmt.Printf("  %d tasks, %d failed, %d skipped\n", s.Tasks, s.TasksFailed, s.TasksSkipped)
+		fmt.Printf("  %s total, %s per run\n", ui.FormatDuration(s.RunTime), ui.FormatDuration(s.RunTime/time.Duration(s.Runs)))
+	}
+	printCounts("Tools", s.Tools, true)
+	printCounts("Features", s.Features, true)
+	printCounts("Flags", s.Flags, false)
+	return nil
+}
+
+// printCounts prints a titled list of counts, if there are any. With runs,
+// counts are labeled as runs.
+func printCounts(title string, counts []analytics.Count, runs bool) {
+	if len(counts) == 0 {
+		return
+	}
+	width := 0
+	for _, c := range counts {
+		width = max(width, len(c.Name))
+	}
+	fmt.Printf("\n%s%s%s\n", ui.Bold, title, ui.Reset)
+	for _, c := range counts {
+		label := ""
+		if runs {
+			label = " runs"
+			if c.Count == 1 {
+				label = " run"
+			}
+		}
+		fmt.Printf("  %-*s  %d%s\n", width, c.Name, c.Count, label)
+	}
+}
+
+// usageRuns collects the outcomes of the workflows a command runs, for its
+// analytics record. Guarded by usageMu, since MasterCortex workflows run in
+// parallel.
+var (
+	usageMu   sync.Mutex
+	usageRuns []analytics.Run
+)
+
+// recordRunUsage adds a finished workflow run to the command's analytics
+// record.
+func recordRunUsage(localCfg *config.AgentflowConfig, merged *config.MergedConfig, plan *planner.ExecutionPlan, useParallel bool, result *state.RunResult, duration time.Duration) {
+	run := analytics.Run{
+		Success:    result.Success,
+		Tasks:      len(result.Tasks),
+		Skipped:    result.SkippedCount(),
+		DurationMS: duration.Milliseconds(),
+		Features:   runFeatures(localCfg, merged, plan, useParallel),
+	}
+	tools := make(map[string]bool)
+	for _, t := range result.Tasks {
+		if t.Status == state.StatusFailed {
+			run.Failed++
+		}
+		if !tools[t.Tool] {
+			tools[t.Tool] = true
+			run.Tools = append(run.Tools, t.Tool)
+		}
+	}
+	sort.Strings(run.Tools)
+
+	usageMu.Lock()
+	usageRuns = append(usageRuns, run)
+	usageMu.Unlock()
+}
+
+// runFeatures lists the workflow features a run used.
+func runFeatures(localCfg *config.AgentflowConfig, merged *config.MergedConfig, plan *planner.ExecutionPlan, useParallel bool) []string {
+	settings := merged.Settings
+	used := map[string]bool{
+		"parallel":    useParallel,
+		"incremental": settings.Incremental,
+		"budget":      settings.MaxTokens > 0 || settings.MaxCostUSD > 0,
+		"keep_going":  !settings.FailFastEnabled(),
+		"webhooks":    len(merged.Webhooks) > 0,
+		"telemetry":   settings.Telemetry.Endpoint != "",
+		"secrets":     len(localCfg.Secrets) > 0,
+		"vars":        len(localCfg.Vars) > 0,
+		"inputs":      len(localCfg.Inputs) > 0,
+		"stages":      len(localCfg.Stages) > 0,
+		"fan_out":     len(plan.Groups) > 0,
+	}
+	if settings.Output != "" {
+		used["output_"+settings.Output] = true
+	}
+	for _, t := range plan.Tasks {
+		used["when"] = used["when"] || t.When != ""
+		used["until"] = used["until"] || t.Until != ""
+		used["continue_on_error"] = used["continue_on_error"] || t.ContinueOnError
+		used["artifacts"] = used["artifacts"] || len(t.Artifacts) > 0
+		used["declared_files"] = used["declared_files"] || len(t.Inputs) > 0 || len(t.Outputs) > 0
+	}
+
+	var features []string
+	for name, ok := range used {
+		if ok {
+			features = append(features, name)
+		}
+	}
+	sort.Strings(features)
+	return features
+}
+
+// recordUsage records the command that ran, with the runs it made, for users
+// who opted in to analytics. Failures are ignored: analytics never get in
+// the way of a command.
+func recordUsage(cmd *cobra.Command, err error) {
+	if cmd == nil || !cmd.HasParent() || cmd.Name() == "help" {
+		return
+	}
+	globalCfg, loadErr := config.LoadGlobalConfig()
+	if loadErr != nil || !globalCfg.Analytics.Active() {
+		return
+	}
+
+	var flags []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		flags = append(flags, f.Name)
+	})
+	usageMu.Lock()
+	runs := usageRuns
+	usageMu.Unlock()
+
+	record := analytics.Record{
+		Time:    time.Now().UTC(),
+		Version: version,
+		Command: strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "),
+		Success: err == nil,
+		Flags:   flags,
+		Runs:    runs,
+	}
+	if globalCfg.Analytics.Enabled {
+		if path, err := analytics.Path(); err == nil {
+			_ = analytics.Append(path, record)
+		}
+	}
+	if globalCfg.Analytics.Remote.URL != "" {
+		_ = analytics.Send(globalCfg.Analytics.Remote, record)
+	}
+}
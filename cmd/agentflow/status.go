@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// newStatusCmd creates the `cortex status` command.
+func newStatusCmd() *cobra.Command {
+	var project string
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the workflows running now",
+		Long: `Shows the runs in progress on this machine, whichever terminal or
+` + "`cortex serve`" + ` started them: how long each has been running, how many of
+its tasks have finished, and the tasks running now.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showStatus(project)
+		},
+	}
+
+	statusCmd.Flags().StringVar(&project, "project", "", "Only show runs of this project")
+	addOutputFlags(statusCmd)
+	statusCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+
+	return statusCmd
+}
+
+func showStatus(project string) error {
+	if err := checkOutputFormat(); err != nil {
+		return err
+	}
+	if noColor {
+		ui.SetColorsEnabled(false)
+	}
+
+	running, err := state.ListRunning(project)
+	if err != nil {
+		return err
+	}
+	if outputFormat == outputJSON {
+		if running == nil {
+			running = []state.RunStatus{}
+		}
+		return writeJSON(os.Stdout, StatusOutput{Runs: running})
+	}
+
+	if len(running) == 0 {
+		fmt.Printf("%sNo workflows running.%s\n", ui.Dim, ui.Reset)
+		return nil
+	}
+
+	now := time.Now()
+	for i, r := range running {
+		if i > 0 {
+			fmt.Println()
+		}
+		details := fmt.Sprintf("running for %s, pid %d", ui.FormatDuration(now.Sub(r.StartTime)), r.PID)
+		if r.User != "" {
+			details += ", by " + r.User
+		}
+		fmt.Printf("  %s %s%s/%s%s %s(%s)%s\n",
+			ui.StatusIcon(ui.StatusRunning), ui.Bold, r.Project, r.RunID, ui.Reset, ui.Dim, details, ui.Reset)
+
+		progress := fmt.Sprintf("%d/%d tasks finished", r.Finished, r.TaskCount)
+		if r.Levels > 0 {
+			progress += fmt.Sprintf(" │ level %d/%d", r.Level, r.Levels)
+		}
+		fmt.Printf("      %s%s%s\n", ui.Dim, progress, ui.Reset)
+
+		for _, t := range r.Running {
+			fmt.Printf("      %s↳%s %s %s(%s, %s)%s\n",
+				ui.Dim, ui.Reset, t.Name, ui.Dim, t.Tool, ui.FormatDuration(now.Sub(t.StartTime)), ui.Reset)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// applyGlobalTheme sets the status theme and color mode from
+// ~/.cortex/config.yml, so commands that don't load a Cortexfile still
+// honor them.
+func applyGlobalTheme() {
+	color := ""
+	if globalCfg, err := config.LoadGlobalConfig(); err == nil {
+		applyTheme(globalCfg.Settings.Theme)
+		color = globalCfg.Settings.Color
+	}
+	applyColor(color)
+}
+
+// applyColor sets whether output is colored from settings.color, unless
+// --no-color or --plain turn colors off, and turns on plain output with
+// --plain. Plain output is already on when stdout is not a terminal.
+func applyColor(color string) {
+	switch {
+	case noColor || plainOutput || color == config.ColorNever:
+		ui.SetColorsEnabled(false)
+	case color == config.ColorAlways:
+		ui.SetColorsEnabled(true)
+	default:
+		ui.SetColorsEnabled(ui.ColorsSupported())
+	}
+	if plainOutput {
+		ui.SetPlain(true)
+	}
+}
+
+// applyTheme sets the status palette and glyphs. Unknown palettes fall
+// back to the default one.
+func applyTheme(cfg config.ThemeConfig) {
+	theme := ui.DefaultTheme
+	if cfg.Palette == config.PaletteAccessible {
+		theme = ui.AccessibleTheme
+	}
+
+	glyphs := make(map[ui.Status]string, len(cfg.Glyphs))
+	for status, glyph := range cfg.Glyphs {
+		if glyph != "" {
+			glyphs[ui.Status(status)] = glyph
+		}
+	}
+	ui.SetTheme(theme.WithGlyphs(glyphs))
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// newVerifyCmd creates the `cortex verify <run-id>` command.
+func newVerifyCmd() *cobra.Command {
+	var project, keyEnv string
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify <run-id|dir>",
+		Short: "Check that a signed run hasn't been changed",
+		Long: `Checks a run signed with the Cortexfile's signing_key_env: the signature of
+its manifest, and that no file of the run directory was modified, removed,
+or added since the run finished. Use "latest" as the run ID for the most
+recent run, or give the path of a run directory, like one unpacked from
+'cortex sessions export'.
+
+The key is read from the variable named by signing_key_env in the Cortexfile
+in the current directory (from the environment, then .env), or from the
+variable named with --key-env.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return verifyRun(project, args[0], keyEnv)
+		},
+	}
+
+	verifyCmd.Flags().StringVar(&project, "project", "", "Project name (default: current directory name)")
+	verifyCmd.Flags().StringVar(&keyEnv, "key-env", "", "Environment variable holding the signing key (default: the Cortexfile's signing_key_env)")
+	addOutputFlags(verifyCmd)
+	verifyCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+
+	return verifyCmd
+}
+
+func verifyRun(project, ref, keyEnv string) error {
+	if err := checkOutputFormat(); err != nil {
+		return err
+	}
+	if noColor || outputFormat == outputJSON {
+		ui.SetColorsEnabled(false)
+	}
+	fail := func(err error) error {
+		if outputFormat != outputJSON {
+			ui.Error("%s", err)
+		}
+		return err
+	}
+
+	runDir, err := verifyRunDir(project, ref)
+	if err != nil {
+		return fail(err)
+	}
+	key, err := signingKey(keyEnv)
+	if err != nil {
+		return fail(err)
+	}
+
+	v, err := state.VerifyRun(runDir, key)
+	if errors.Is(err, state.ErrNotSigned) {
+		return fail(fmt.Errorf("run %s is not signed (no %s)", filepath.Base(runDir), state.ManifestFile))
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	if outputFormat == outputJSON {
+		if err := writeJSON(os.Stdout, VerifyOutput{RunDir: runDir, Verified: v.OK(), Verification: v}); err != nil {
+			return err
+		}
+	} else {
+		printVerification(v)
+	}
+	if !v.OK() {
+		return fmt.Errorf("run %s failed verification", v.Manifest.RunID)
+	}
+	return nil
+}
+
+// verifyRunDir resolves a run ID of project, or takes ref as a run
+// directory if it is one.
+func verifyRunDir(project, ref string) (string, error) {
+	if _, err := os.Stat(filepath.Join(ref, state.ManifestFile)); err == nil {
+		return filepath.Abs(ref)
+	}
+
+	project, err := resolveProject(project)
+	if err != nil {
+		return "", err
+	}
+	runDir, _, _, err := state.FindRunDir(project, ref)
+	return runDir, err
+}
+
+// signingKey reads the signing key from the variable named keyEnv, or the
+// one named by signing_key_env in the Cortexfile.
+func signingKey(keyEnv string) (string, error) {
+	if keyEnv != "" {
+		key := os.Getenv(keyEnv)
+		if key == "" {
+			return "", fmt.Errorf("the variable named by --key-env is not set")
+		}
+		return key, nil
+	}
+
+	paths, err := resolveConfigFiles()
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("no Cortexfile found; use --key-env to name the signing key variable")
+	}
+	cfg, err := config.LoadConfig(paths[0])
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.SigningKeyEnv == "" {
+		return "", fmt.Errorf("%s sets no signing_key_env; use --key-env to name the signing key variable", paths[0])
+	}
+	return config.ResolveSigningKey(cfg, filepath.Dir(paths[0]))
+}
+
+// printVerification prints the result of checking a run.
+func printVerification(v *state.Verification) {
+	m := v.Manifest
+	fmt.Printf("%s%s%s %s%s%s\n", ui.Bold, m.Project, ui.Reset, ui.Dim, m.RunID, ui.Reset)
+	fmt.Printf("%sSigned %s with key %s%s\n\n", ui.Dim, m.SignedAt.Local().Format("2006-01-02 15:04:05"), m.KeyID, ui.Reset)
+
+	switch {
+	case v.SignatureValid:
+		ui.Success("Signature valid")
+	case !v.KeyMatches:
+		ui.Error("Signature not checked: the run was signed with a different key")
+	default:
+		ui.Error("Signature invalid: the manifest was changed")
+	}
+
+	for _, group := range []struct {
+		label string
+		files []string
+	}{
+		{"Modified", v.Modified},
+		{"Missing", v.Missing},
+		{"Not in manifest", v.Added},
+	} {
+		for _, f := range group.files {
+			ui.Error("%s: %s", group.label, f)
+		}
+	}
+	if len(v.Modified) == 0 && len(v.Missing) == 0 && len(v.Added) == 0 {
+		ui.Success("%d files unchanged", len(m.Files))
+	}
+}
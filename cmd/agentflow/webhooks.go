@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adityaraj/agentflow/internal/ui"
+	"github.com/adityaraj/agentflow/internal/webhook"
+)
+
+// newWebhooksCmd creates the `cortex webhooks` command and its subcommands.
+func newWebhooksCmd() *cobra.Command {
+	webhooksCmd := &cobra.Command{
+		Use:   "webhooks",
+		Short: "Inspect and resend undelivered webhook events",
+		Long: `Webhook deliveries that fail are retried with exponential backoff. Those
+still failing, cut short when cortex exited, or dropped because too many
+events were waiting, stay queued in ~/.cortex/webhooks/queue until they are
+drained.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List undelivered webhook events",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listWebhookQueue()
+		},
+	}
+
+	drainCmd := &cobra.Command{
+		Use:   "drain",
+		Short: "Send undelivered webhook events again",
+		Long:  "Sends each queued delivery once and removes those that succeed.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return drainWebhookQueue()
+		},
+	}
+
+	for _, c := range []*cobra.Command{listCmd, drainCmd} {
+		c.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	}
+	webhooksCmd.AddCommand(listCmd, drainCmd)
+	return webhooksCmd
+}
+
+func listWebhookQueue() error {
+	if noColor {
+		ui.SetColorsEnabled(false)
+	}
+
+	queue, err := webhook.DefaultQueue()
+	if err != nil {
+		return err
+	}
+	deliveries, err := queue.List()
+	if err != nil {
+		ui.Error("Failed to read webhook queue: %s", err)
+		return err
+	}
+	if len(deliveries) == 0 {
+		fmt.Println("No undelivered webhook events.")
+		return nil
+	}
+
+	now := time.Now()
+	for _, d := range deliveries {
+		fmt.Printf("  %s%s%s %s → %s\n", ui.Bold, d.Event, ui.Reset, ui.FormatAge(d.CreatedAt, now), d.URL)
+		detail := fmt.Sprintf("%d attempts", d.Attempts)
+		if d.Attempts == 1 {
+			detail = "1 attempt"
+		}
+		if d.LastError != "" {
+			detail += ", last error: " + d.LastError
+		}
+		if d.Retrying() {
+			detail += ", being retried by process " + strconv.Itoa(d.Sender)
+		}
+		fmt.Printf("    %s%s%s\n", ui.Dim, detail, ui.Reset)
+	}
+	fmt.Printf("\n%d undelivered. Run 'cortex webhooks drain' to send them again.\n", len(deliveries))
+	return nil
+}
+
+func drainWebhookQueue() error {
+	if noColor {
+		ui.SetColorsEnabled(false)
+	}
+
+	queue, err := webhook.DefaultQueue()
+	if err != nil {
+		return err
+	}
+	result, err := webhook.Drain(queue)
+	if err != nil {
+		ui.Error("Failed to drain webhook queue: %s", err)
+		return err
+	}
+
+	if result.Delivered == 0 && len(result.Failed) == 0 && result.Retrying == 0 {
+		fmt.Println("No undelivered webhook events.")
+		return nil
+	}
+	if result.Delivered > 0 {
+		ui.Success("Delivered %d webhook events", result.Delivered)
+	}
+	if result.Retrying > 0 {
+		ui.Info("Skipped %d webhook events still being retried by a running cortex", result.Retrying)
+	}
+	for _, d := range result.Failed {
+		ui.Warning("%s to %s: %s", d.Event, d.URL, d.LastError)
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d webhook events still undelivered", len(result.Failed))
+	}
+	return nil
+}
+
+// waitForWebhooks waits for a run's webhook deliveries and reports those
+// dropped because the delivery queue was full.
+func waitForWebhooks(mgr *webhook.Manager) {
+	mgr.Wait()
+	if dropped := mgr.Stats().Dropped; dropped > 0 {
+		ui.Warning("%d webhook events dropped (delivery queue full); run 'cortex webhooks drain' to send them", dropped)
+	}
+}
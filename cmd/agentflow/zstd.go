@@ -0,0 +1,35 @@
+package main
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdMagic starts every zstd frame.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// newZstdWriter returns a writer compressing to w. Close flushes it.
+func newZstdWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+// zstdReader decompresses what it reads from its decoder.
+type zstdReader struct {
+	*zstd.Decoder
+}
+
+// Close releases the decoder. It never fails.
+func (z zstdReader) Close() error {
+	z.Decoder.Close()
+	return nil
+}
+
+// newZstdReader returns a reader decompressing r. Close releases it.
+func newZstdReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return zstdReader{Decoder: d}, nil
+}
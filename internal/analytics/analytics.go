@@ -0,0 +1,180 @@
+// Package analytics records how cortex is used, for users who opt in with
+// analytics.enabled in the global config. Records are appended to a local
+// file and only leave the machine when analytics.remote is set.
+package analytics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/paths"
+)
+
+// FileName is the name of the analytics file in the state directory.
+const FileName = "analytics.jsonl"
+
+// Record is one cortex command.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Version string    `json:"version"`
+	Command string    `json:"command"`         // e.g. "run" or "webhooks drain"
+	Success bool      `json:"success"`         // The command exited with status 0
+	Flags   []string  `json:"flags,omitempty"` // Names of the flags set, never their values
+	Runs    []Run     `json:"runs,omitempty"`  // Workflows the command ran
+}
+
+// Run is the outcome of one workflow run.
+type Run struct {
+	Success    bool     `json:"success"`
+	Tasks      int      `json:"tasks"`
+	Failed     int      `json:"failed,omitempty"`
+	Skipped    int      `json:"skipped,omitempty"`
+	DurationMS int64    `json:"duration_ms"`
+	Tools      []string `json:"tools,omitempty"`    // Distinct tools of the run's tasks
+	Features   []string `json:"features,omitempty"` // Workflow features used, e.g. parallel or webhooks
+}
+
+// Path returns the analytics file in the state directory.
+func Path() (string, error) {
+	dir, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Append adds a record to the analytics file at path.
+func Append(path string, r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads the records of the analytics file at path. A missing file has
+// no records; lines that can't be parsed are skipped.
+func Load(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return records, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// Count is a name and how often it occurred.
+type Count struct {
+	Name  string
+	Count int
+}
+
+// Summary aggregates analytics records.
+type Summary struct {
+	Commands     int
+	First, Last  time.Time
+	ByCommand    []Count // Commands by name, most used first
+	Flags        []Count // Flags by name, most used first
+	Runs         int
+	RunsFailed   int
+	Tasks        int
+	TasksFailed  int
+	TasksSkipped int
+	RunTime      time.Duration // Total duration of all runs
+	Tools        []Count       // Runs using each tool
+	Features     []Count       // Runs using each feature
+}
+
+// Summarize aggregates records.
+func Summarize(records []Record) Summary {
+	var s Summary
+	commands := make(map[string]int)
+	flags := make(map[string]int)
+	tools := make(map[string]int)
+	features := make(map[string]int)
+
+	for _, r := range records {
+		s.Commands++
+		if s.First.IsZero() || r.Time.Before(s.First) {
+			s.First = r.Time
+		}
+		if r.Time.After(s.Last) {
+			s.Last = r.Time
+		}
+		commands[r.Command]++
+		for _, f := range r.Flags {
+			flags[f]++
+		}
+
+		for _, run := range r.Runs {
+			s.Runs++
+			if !run.Success {
+				s.RunsFailed++
+			}
+			s.Tasks += run.Tasks
+			s.TasksFailed += run.Failed
+			s.TasksSkipped += run.Skipped
+			s.RunTime += time.Duration(run.DurationMS) * time.Millisecond
+			for _, t := range run.Tools {
+				tools[t]++
+			}
+			for _, f := range run.Features {
+				features[f]++
+			}
+		}
+	}
+
+	s.ByCommand = sortedCounts(commands)
+	s.Flags = sortedCounts(flags)
+	s.Tools = sortedCounts(tools)
+	s.Features = sortedCounts(features)
+	return s
+}
+
+// sortedCounts returns counts by descending count, then name.
+func sortedCounts(m map[string]int) []Count {
+	counts := make([]Count, 0, len(m))
+	for name, n := range m {
+		counts = append(counts, Count{Name: name, Count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts
+}
@@ -0,0 +1,43 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/config"
+)
+
+// remoteTimeout bounds a remote delivery, which happens as cortex exits.
+const remoteTimeout = 3 * time.Second
+
+// Send posts a record as JSON to the remote endpoint. It is only called when
+// analytics.remote.url is set; records are not queued or retried.
+func Send(remote config.AnalyticsRemote, r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, remote.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range remote.Headers {
+		req.Header.Set(key, value)
+	}
+
+	client := &http.Client{Timeout: remoteTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("analytics endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
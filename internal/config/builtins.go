@@ -0,0 +1,95 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// builtinRegex matches {{run.name}}, {{project.name}}, and {{git.name}}
+// patterns.
+var builtinRegex = regexp.MustCompile(`\{\{((?:run|project|git)\.[a-zA-Z0-9_]+)\}\}`)
+
+// Built-in template variables, set when a run starts.
+const (
+	BuiltinRunID          = "run.id"           // ID of the run
+	BuiltinRunDate        = "run.date"         // Start date, 2006-01-02
+	BuiltinRunTime        = "run.time"         // Start time, 15:04:05
+	BuiltinRunTimestamp   = "run.timestamp"    // Start time in RFC 3339
+	BuiltinRunDir         = "run.dir"          // Directory the run is saved in
+	BuiltinProjectName    = "project.name"     // Project name (directory name)
+	BuiltinProjectDir     = "project.dir"      // Project directory
+	BuiltinGitCommit      = "git.commit"       // Commit checked out in the project
+	BuiltinGitShortCommit = "git.short_commit" // Abbreviated commit
+	BuiltinGitBranch      = "git.branch"       // Branch checked out in the project
+)
+
+// BuiltinVars lists all built-in template variables.
+var BuiltinVars = []string{
+	BuiltinRunID, BuiltinRunDate, BuiltinRunTime, BuiltinRunTimestamp, BuiltinRunDir,
+	BuiltinProjectName, BuiltinProjectDir,
+	BuiltinGitCommit, BuiltinGitShortCommit, BuiltinGitBranch,
+}
+
+// IsBuiltinVar reports whether name is a built-in template variable.
+func IsBuiltinVar(name string) bool {
+	for _, v := range BuiltinVars {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandBuiltins replaces {{run.id}} and the other built-in placeholders
+// with their values. Variables without a value are left as-is.
+func ExpandBuiltins(text string, values map[string]string) string {
+	return builtinRegex.ReplaceAllStringFunc(text, func(match string) string {
+		if value, ok := values[builtinRegex.FindStringSubmatch(match)[1]]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// ExtractBuiltinRefs returns the built-in variables referenced in text,
+// valid or not, like "run.id" for {{run.id}}.
+func ExtractBuiltinRefs(text string) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, match := range builtinRegex.FindAllStringSubmatch(text, -1) {
+		if !seen[match[1]] {
+			refs = append(refs, match[1])
+			seen[match[1]] = true
+		}
+	}
+	return refs
+}
+
+// UsesGitBuiltins reports whether any task or the git settings reference a
+// {{git.*}} variable, directly or in a template expression, which needs the
+// project to be a git repository.
+func UsesGitBuiltins(config *AgentflowConfig) bool {
+	if config.Git != nil {
+		for _, ref := range ExtractBuiltinRefs(config.Git.Branch + "\n" + config.Git.Message) {
+			if strings.HasPrefix(ref, "git.") {
+				return true
+			}
+		}
+	}
+	for _, task := range config.Tasks {
+		text := taskTemplateText(task) + "\n" + task.Command
+		for _, ref := range ExtractBuiltinRefs(text) {
+			if strings.HasPrefix(ref, "git.") {
+				return true
+			}
+		}
+		if tmpl, err := ParseTemplate(text); err == nil {
+			for _, ref := range tmpl.Refs() {
+				if strings.HasPrefix(ref.Name, "git.") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
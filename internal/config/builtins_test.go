@@ -0,0 +1,80 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestExpandBuiltins tests substituting built-in variables.
+func TestExpandBuiltins(t *testing.T) {
+	values := map[string]string{
+		BuiltinRunID:       "20261016-120000",
+		BuiltinProjectName: "app",
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"run id", "Report for {{run.id}}", "Report for 20261016-120000"},
+		{"several", "{{project.name}}/{{run.id}}", "app/20261016-120000"},
+		{"no value", "Commit {{git.commit}}", "Commit {{git.commit}}"},
+		{"other templates", "{{vars.run}} {{outputs.run}}", "{{vars.run}} {{outputs.run}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpandBuiltins(tt.text, values); got != tt.want {
+				t.Errorf("ExpandBuiltins() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExtractBuiltinRefs tests finding built-in variable references.
+func TestExtractBuiltinRefs(t *testing.T) {
+	got := ExtractBuiltinRefs("{{run.id}} {{git.commit}} {{run.id}} {{run.idd}} {{vars.x}}")
+	want := []string{"run.id", "git.commit", "run.idd"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractBuiltinRefs() = %v, want %v", got, want)
+	}
+}
+
+// TestValidate_UnknownBuiltin tests that misspelled built-in variables are
+// reported with a suggestion.
+func TestValidate_UnknownBuiltin(t *testing.T) {
+	cfg := &AgentflowConfig{
+		Agents: map[string]AgentConfig{
+			"agent1": {Tool: "claude-code"},
+			"sh":     {Tool: "shell"},
+		},
+		Tasks: map[string]TaskConfig{
+			"task1": {Agent: "agent1", Prompt: "Summarize run {{run.id}} of {{project.name}}"},
+			"task2": {Agent: "sh", Command: "echo {{run.idd}}"},
+		},
+	}
+
+	err := Validate(cfg)
+	valErr, ok := err.(*ConfigErrors)
+	if !ok {
+		t.Fatalf("expected *ConfigErrors, got %T", err)
+	}
+	if !errorsContain(valErr, "unknown built-in variable {{run.idd}}") {
+		t.Errorf("expected unknown built-in variable error, got: %v", valErr)
+	}
+	if !strings.Contains(valErr.Error(), "{{run.id}}") {
+		t.Errorf("expected a suggestion of {{run.id}}, got: %v", valErr)
+	}
+
+	task := cfg.Tasks["task2"]
+	task.Command = "echo {{run.id}} {{git.short_commit}}"
+	cfg.Tasks["task2"] = task
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+	if !UsesGitBuiltins(cfg) {
+		t.Error("expected UsesGitBuiltins to be true")
+	}
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Condition operators supported in `when` expressions.
+const (
+	OpContains    = "contains"
+	OpNotContains = "not contains"
+	OpEquals      = "=="
+	OpNotEquals   = "!="
+)
+
+// conditionOps lists operators as they appear in expressions. Word operators
+// need surrounding spaces so they don't match inside template variables.
+var conditionOps = []struct {
+	token string
+	op    string
+}{
+	{" not contains ", OpNotContains},
+	{" contains ", OpContains},
+	{"==", OpEquals},
+	{"!=", OpNotEquals},
+}
+
+// statusVarRegex matches {{status.taskname}} patterns.
+var statusVarRegex = regexp.MustCompile(`\{\{status\.([a-zA-Z0-9_-]+)\}\}`)
+
+// Condition is a parsed `when` expression. Without an operator, the
+// condition holds when Left expands to a truthy value. A condition written
+// as a single {{ ... }} block is evaluated as an Expr instead.
+type Condition struct {
+	Left  string
+	Op    string
+	Right string
+	Expr  *Expr
+}
+
+// ParseCondition parses a `when` expression of the form
+// "<left> <op> <right>" or a single operand. Operands may be quoted with
+// single or double quotes and may contain {{outputs.X}} and {{status.X}}.
+//
+// Examples:
+//
+//	{{outputs.test}} contains 'FAIL'
+//	{{status.lint}} == skipped
+//	{{outputs.check}}
+//	{{ status.lint == 'failed' || outputs.count > 3 }}
+func ParseCondition(expr string) (*Condition, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("empty condition")
+	}
+
+	if inner, ok := ExprBlock(expr); ok {
+		parsed, err := ParseExpr(inner)
+		if err != nil {
+			return nil, err
+		}
+		return &Condition{Expr: parsed}, nil
+	}
+
+	// Use the earliest operator outside quotes
+	idx, token, op := -1, "", ""
+	for _, candidate := range conditionOps {
+		i := indexOutsideQuotes(expr, candidate.token)
+		if i >= 0 && (idx < 0 || i < idx) {
+			idx, token, op = i, candidate.token, candidate.op
+		}
+	}
+
+	if idx < 0 {
+		return &Condition{Left: unquote(expr)}, nil
+	}
+
+	left := strings.TrimSpace(expr[:idx])
+	right := strings.TrimSpace(expr[idx+len(token):])
+	if left == "" || right == "" {
+		return nil, fmt.Errorf("condition %q: operator %q needs a value on both sides", expr, op)
+	}
+
+	return &Condition{Left: unquote(left), Op: op, Right: unquote(right)}, nil
+}
+
+// Evaluate expands template variables in both operands and applies the operator.
+// statuses maps task names to their result status (success, failed, skipped).
+// Expression errors evaluate to false; use Check to see them.
+func (c *Condition) Evaluate(outputs, statuses map[string]string) bool {
+	result, _ := c.Check(outputs, statuses)
+	return result
+}
+
+// Check evaluates the condition like Evaluate but reports expression errors,
+// such as comparing non-numeric output with a number.
+func (c *Condition) Check(outputs, statuses map[string]string) (bool, error) {
+	if c.Expr != nil {
+		return c.Expr.EvalBool(ConditionEnv(outputs, statuses))
+	}
+	return c.evaluateOperands(outputs, statuses), nil
+}
+
+// evaluateOperands applies the operator to the expanded operands.
+func (c *Condition) evaluateOperands(outputs, statuses map[string]string) bool {
+	left := expandCondition(c.Left, outputs, statuses)
+	right := expandCondition(c.Right, outputs, statuses)
+
+	switch c.Op {
+	case OpContains:
+		return strings.Contains(left, right)
+	case OpNotContains:
+		return !strings.Contains(left, right)
+	case OpEquals:
+		return strings.TrimSpace(left) == strings.TrimSpace(right)
+	case OpNotEquals:
+		return strings.TrimSpace(left) != strings.TrimSpace(right)
+	default:
+		return isTruthy(left)
+	}
+}
+
+// TemplateText returns both operands, for template variable validation.
+func (c *Condition) TemplateText() string {
+	return c.Left + "\n" + c.Right
+}
+
+// ExtractStatusVars returns all task names referenced in {{status.X}} patterns.
+func ExtractStatusVars(text string) []string {
+	var tasks []string
+	seen := make(map[string]bool)
+	for _, match := range statusVarRegex.FindAllStringSubmatch(text, -1) {
+		if !seen[match[1]] {
+			tasks = append(tasks, match[1])
+			seen[match[1]] = true
+		}
+	}
+	return tasks
+}
+
+// expandCondition substitutes {{outputs.X}} and {{status.X}} in an operand.
+func expandCondition(text string, outputs, statuses map[string]string) string {
+	text = statusVarRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := statusVarRegex.FindStringSubmatch(placeholder)[1]
+		if status, ok := statuses[name]; ok {
+			return status
+		}
+		return placeholder
+	})
+	return ExpandPrompt(text, outputs)
+}
+
+// isTruthy reports whether a single-operand condition holds: the value is
+// non-empty and not "false", "0", or "no" (case-insensitive).
+func isTruthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "false", "0", "no":
+		return false
+	}
+	return true
+}
+
+// indexOutsideQuotes returns the index of the first occurrence of token that
+// is not inside a single- or double-quoted string, or -1.
+func indexOutsideQuotes(s, token string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case strings.HasPrefix(s[i:], token):
+			return i
+		}
+	}
+	return -1
+}
+
+// unquote strips one pair of matching surrounding quotes.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package config
+
+import "testing"
+
+// TestParseCondition tests parsing `when` expressions.
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    Condition
+		wantErr bool
+	}{
+		{
+			name: "contains with quoted value",
+			expr: "{{outputs.test}} contains 'FAIL'",
+			want: Condition{Left: "{{outputs.test}}", Op: OpContains, Right: "FAIL"},
+		},
+		{
+			name: "not contains",
+			expr: `{{outputs.test}} not contains "ok"`,
+			want: Condition{Left: "{{outputs.test}}", Op: OpNotContains, Right: "ok"},
+		},
+		{
+			name: "equals without spaces",
+			expr: "{{status.lint}}==skipped",
+			want: Condition{Left: "{{status.lint}}", Op: OpEquals, Right: "skipped"},
+		},
+		{
+			name: "not equals",
+			expr: "{{status.lint}} != success",
+			want: Condition{Left: "{{status.lint}}", Op: OpNotEquals, Right: "success"},
+		},
+		{
+			name: "operator inside quotes is ignored",
+			expr: "'a == b' contains '=='",
+			want: Condition{Left: "a == b", Op: OpContains, Right: "=="},
+		},
+		{
+			name: "single operand",
+			expr: "{{outputs.check}}",
+			want: Condition{Left: "{{outputs.check}}"},
+		},
+		{
+			name:    "empty",
+			expr:    "  ",
+			wantErr: true,
+		},
+		{
+			name:    "missing left operand",
+			expr:    "== 'x'",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCondition(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCondition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && *got != tt.want {
+				t.Errorf("ParseCondition() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCondition_Evaluate tests evaluating conditions against task results.
+func TestCondition_Evaluate(t *testing.T) {
+	outputs := map[string]string{
+		"test":  "3 passed, 1 FAIL",
+		"check": "false\n",
+		"count": "42",
+	}
+	statuses := map[string]string{
+		"test": "success",
+		"lint": "skipped",
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"{{outputs.test}} contains 'FAIL'", true},
+		{"{{outputs.test}} not contains 'FAIL'", false},
+		{"{{outputs.count}} == 42", true},
+		{"{{outputs.count}} != 42", false},
+		{"{{status.lint}} == skipped", true},
+		{"{{status.test}} == 'success'", true},
+		{"{{outputs.check}}", false},
+		{"{{outputs.count}}", true},
+		{"true", true},
+		{"0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cond, err := ParseCondition(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCondition() error = %v", err)
+			}
+			if got := cond.Evaluate(outputs, statuses); got != tt.want {
+				t.Errorf("Evaluate(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
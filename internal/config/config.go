@@ -2,31 +2,412 @@
 package config
 
 import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
 	"gopkg.in/yaml.v3"
 )
 
 // AgentflowConfig represents the root configuration from Cortexfile.yml.
 type AgentflowConfig struct {
-	Agents   map[string]AgentConfig `yaml:"agents"`
-	Tasks    map[string]TaskConfig  `yaml:"tasks"`
-	Settings *SettingsConfig        `yaml:"settings"` // Optional local settings
-	Workdir  string                 `yaml:"workdir"`  // Working directory for agents (optional)
+	Agents        map[string]AgentConfig   `yaml:"agents"`
+	Tasks         map[string]TaskConfig    `yaml:"tasks"`
+	Settings      *SettingsConfig          `yaml:"settings"`        // Optional local settings
+	Workdir       string                   `yaml:"workdir"`         // Working directory for agents (optional)
+	Secrets       StringList               `yaml:"secrets"`         // Env var names injected into every task and redacted from results
+	EnvFile       string                   `yaml:"env_file"`        // Dotenv file for secrets (default: .env, optional)
+	SigningKeyEnv string                   `yaml:"signing_key_env"` // Env var holding the key run manifests are signed with (optional)
+	Vars          map[string]string        `yaml:"vars"`            // Default values for {{vars.name}} (overridden by --var-file and --var)
+	Inputs        map[string]InputConfig   `yaml:"inputs"`          // Declared variables validated before execution
+	Triggers      map[string]TriggerConfig `yaml:"triggers"`        // Incoming webhooks that start the workflow under `cortex serve`
+	Setup         StringList               `yaml:"setup"`           // Tasks that run before every other task
+	Teardown      StringList               `yaml:"teardown"`        // Tasks that run after every other task, even on failure or cancellation
+	Stages        map[string]StageConfig   `yaml:"stages"`          // Groups of tasks with shared defaults, needed together as stage:<name>
+	Include       []IncludeConfig          `yaml:"include"`         // Files whose agents and tasks are merged in (overridden by this file's)
+	TaskTemplates map[string]TaskConfig    `yaml:"task_templates"`  // Task skeletons instantiated by tasks with template and params
+	Git           *GitRunConfig            `yaml:"git"`             // Branch per run and commit per write task (optional)
+	Sources       []string                 `yaml:"-"`               // Files the config was read from: its own, then the ones it includes
+
+	unknownFields []unknownField      // Keys of the file and its includes that no field decodes
+	files         []string            // Files and directories read or used by the file and its includes, besides Sources
+	positions     map[string]position // Where the agents, tasks, and other named definitions are, by "<section>.<name>"
+}
+
+// Privileged reports whether running the workflow may do more than read
+// files and call models: run commands on the host (shell and custom tools,
+// prompt hooks, MCP servers, sandboxes, script and poll tasks), write files,
+// change state elsewhere (git, pull_request, http, and notify tasks, or the
+// git settings), or send prompts and keys to another endpoint than the
+// default one (base_url, api_key_env, proxy, or env, which can point a tool
+// at another endpoint).
+func (c *AgentflowConfig) Privileged() bool {
+	if c.Git != nil || (c.Settings != nil && len(c.Settings.PromptHooks) > 0) {
+		return true
+	}
+	for _, task := range c.Tasks {
+		if task.Write || (task.Permissions != nil && len(task.Permissions.Allow) > 0) {
+			return true
+		}
+		if builtin := task.BuiltinTool(); builtin != "" {
+			if builtin != "wait" {
+				return true
+			}
+			continue
+		}
+		agent := task.RunsWith(c.Agents[task.Agent])
+		if agent.Tool == "shell" || isCustomTool(agent.Tool) ||
+			len(agent.PromptHooks) > 0 || len(agent.MCPServers) > 0 || agent.Sandbox != nil {
+			return true
+		}
+		if (agent.BaseURL != "" && agent.BaseURL != DefaultAPIBaseURL) ||
+			(agent.APIKeyEnv != "" && agent.APIKeyEnv != DefaultAPIKeyEnv) ||
+			agent.Proxy != "" || len(agent.Env) > 0 || len(task.Env) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// FilesOutside returns the files and directories the workflow reads, such as
+// prompt files, items_from data, certificates, included files, and working
+// directories, that are not inside dir, following symbolic links.
+func (c *AgentflowConfig) FilesOutside(dir string) []string {
+	root := realPath(dir)
+	var outside []string
+	for _, path := range slices.Concat(c.Sources, c.files) {
+		rel, err := filepath.Rel(root, realPath(path))
+		if err != nil || !filepath.IsLocal(rel) {
+			outside = append(outside, path)
+		}
+	}
+	slices.Sort(outside)
+	return slices.Compact(outside)
+}
+
+// realPath returns path absolute, with symbolic links resolved if it exists.
+func realPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	return path
 }
 
 // AgentConfig defines an AI agent's configuration.
 type AgentConfig struct {
-	Tool  string `yaml:"tool"`  // "claude-code" or "opencode"
-	Model string `yaml:"model"` // Optional: model identifier (e.g., "sonnet", "opus")
+	Tool             string            `yaml:"tool"`               // "claude-code", "opencode", "aider", "api", or "shell"
+	Model            string            `yaml:"model"`              // Optional: model identifier (e.g., "sonnet", "opus")
+	BaseURL          string            `yaml:"base_url"`           // API agents: OpenAI-compatible endpoint (default: OpenAI)
+	APIKeyEnv        string            `yaml:"api_key_env"`        // API agents: env var holding the API key (default: OPENAI_API_KEY)
+	Env              map[string]string `yaml:"env"`                // Environment variables for all tasks using this agent
+	Workdir          string            `yaml:"workdir"`            // Working directory for tasks using this agent (overrides top-level workdir)
+	Proxy            string            `yaml:"proxy"`              // API agents: proxy URL (default: HTTPS_PROXY/HTTP_PROXY)
+	TLS              *TLSConfig        `yaml:"tls"`                // API agents: certificate settings
+	Warmup           bool              `yaml:"warmup"`             // API agents: connect to base_url at run start
+	PromptHooks      StringList        `yaml:"prompt_hooks"`       // Commands transforming the prompts of this agent's tasks, after the global ones
+	Shell            string            `yaml:"shell"`              // Shell agents: shell running commands (default: cmd.exe on Windows, /bin/sh elsewhere)
+	SystemPrompt     string            `yaml:"system_prompt"`      // System prompt of the agent's tasks (default: claude-code's formatting rules)
+	SystemPromptFile string            `yaml:"system_prompt_file"` // File loaded into system_prompt
+	MCPServers       StringList        `yaml:"mcp_servers"`        // claude-code agents: MCP config files (JSON with mcpServers) passed with --mcp-config
+	Sandbox          *SandboxConfig    `yaml:"sandbox"`            // Container the agent's tasks run in (default: none, on the host)
+}
+
+// TLSConfig customizes the certificates of HTTPS requests. Paths are
+// relative to the Cortexfile.
+type TLSConfig struct {
+	CAFile             string `yaml:"ca_file"`              // PEM certificates trusted in addition to the system's
+	CertFile           string `yaml:"cert_file"`            // Client certificate (PEM) for mutual TLS
+	KeyFile            string `yaml:"key_file"`             // Key of cert_file (PEM)
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"` // Accept any server certificate (testing only)
 }
 
+// Endpoint and key of api agents that do not set base_url or api_key_env.
+const (
+	DefaultAPIBaseURL = "https://api.openai.com/v1"
+	DefaultAPIKeyEnv  = "OPENAI_API_KEY"
+)
+
+// SupportedProxySchemes lists the URL schemes a proxy may use.
+var SupportedProxySchemes = []string{"http", "https", "socks5"}
+
 // TaskConfig defines a single task's configuration.
 type TaskConfig struct {
-	Agent      string     `yaml:"agent"`       // Reference to agent name in agents section
-	Prompt     string     `yaml:"prompt"`      // Inline prompt text (option A)
-	PromptFile string     `yaml:"prompt_file"` // Path to prompt file (option B)
-	Command    string     `yaml:"command"`     // Shell command to execute (for shell agents)
-	Needs      StringList `yaml:"needs"`       // Dependencies: single string or array
-	Write      bool       `yaml:"write"`       // Allow file writes (default: false)
+	Agent            string                 `yaml:"agent"`               // Reference to agent name in agents section
+	Tool             string                 `yaml:"tool"`                // Tool overriding the agent's for this task (optional)
+	Model            string                 `yaml:"model"`               // Model overriding the agent's for this task (optional)
+	Prompt           string                 `yaml:"prompt"`              // Inline prompt text (option A)
+	PromptFile       string                 `yaml:"prompt_file"`         // Path or glob of prompt file(s) (option B)
+	PromptFiles      []string               `yaml:"-"`                   // Files prompt_file matched, loaded into Prompt
+	SystemPrompt     string                 `yaml:"system_prompt"`       // System prompt overriding the agent's for this task (optional)
+	SystemPromptFile string                 `yaml:"system_prompt_file"`  // File loaded into system_prompt
+	Command          string                 `yaml:"command"`             // Shell command to execute (for shell agents)
+	Needs            StringList             `yaml:"needs"`               // Dependencies: single string or array
+	Write            bool                   `yaml:"write"`               // Allow file writes (default: false)
+	Permissions      *PermissionsConfig     `yaml:"permissions"`         // Tools and paths a claude-code task may use, in place of blanket write access
+	Limits           *LimitsConfig          `yaml:"limits"`              // CPU, memory, time, and output caps of a shell task
+	Script           *ScriptConfig          `yaml:"script"`              // Inline script (built-in task type, no agent)
+	HTTP             *HTTPConfig            `yaml:"http"`                // HTTP request (built-in task type, no agent)
+	Git              *GitConfig             `yaml:"git"`                 // Git operation (built-in task type, no agent)
+	Wait             *WaitConfig            `yaml:"wait"`                // Fixed delay (built-in task type, no agent)
+	Poll             *PollConfig            `yaml:"poll"`                // Repeated check until success (built-in task type, no agent)
+	Notify           *NotifyConfig          `yaml:"notify"`              // Message to webhooks (built-in task type, no agent)
+	PullRequest      *PullRequestConfig     `yaml:"pull_request"`        // GitHub pull request (built-in task type, no agent)
+	ItemsFrom        string                 `yaml:"items_from"`          // JSON/CSV data file to fan out over at plan time
+	Matrix           StringList             `yaml:"matrix"`              // Values or file globs to fan out over at plan time
+	Items            []Item                 `yaml:"-"`                   // Rows loaded from items_from or matrix
+	Inputs           StringList             `yaml:"inputs"`              // Files (or globs) the task reads, for incremental runs
+	Outputs          StringList             `yaml:"outputs"`             // Files the task produces, for incremental runs
+	Artifacts        StringList             `yaml:"artifacts"`           // Files (or globs) copied into the run directory after the task
+	Env              map[string]string      `yaml:"env"`                 // Environment variables (override agent env)
+	When             string                 `yaml:"when"`                // Condition evaluated at runtime; task is skipped when false
+	Until            string                 `yaml:"until"`               // Condition ending a loop: the task re-runs until it holds
+	MaxIterations    int                    `yaml:"max_iterations"`      // Cap on the runs of a task with until (default: 5)
+	ShowOutput       string                 `yaml:"show_output"`         // full, summary, or none (default: follow --stream/--verbose)
+	Workdir          string                 `yaml:"workdir"`             // Working directory for this task (overrides agent and top-level workdir)
+	ContinueOnError  bool                   `yaml:"continue_on_error"`   // A failure neither fails the run nor stops dependents
+	Priority         int                    `yaml:"priority"`            // Start order among ready tasks in parallel runs; higher first (default: 0)
+	ReviewWith       string                 `yaml:"review_changes_with"` // Agent that reviews the git changes the task makes
+	RollbackOnReject bool                   `yaml:"rollback_on_reject"`  // Undo the task's changes if the review rejects them
+	ReviewOf         string                 `yaml:"-"`                   // Task whose changes this task reviews (set by the planner)
+	Template         string                 `yaml:"template"`            // Task template this task instantiates
+	Params           map[string]string      `yaml:"params"`              // Values of {{params.name}} in the template (in a template: defaults)
+	OutputFormat     string                 `yaml:"output_format"`       // text (default) or json; json output is repaired and validated
+	OutputSchema     map[string]interface{} `yaml:"output_schema"`       // JSON Schema the json output must match (optional)
+}
+
+// ScriptConfig defines an inline script run by a language interpreter.
+// The script is written to a temporary file and its output is captured
+// like a shell task.
+type ScriptConfig struct {
+	Lang string `yaml:"lang"` // "python" or "node"
+	Code string `yaml:"code"` // Script source (supports template variables)
+}
+
+// HTTPConfig defines an HTTP request whose response body becomes the task
+// output. The URL, header values, and body support template variables, and
+// ${NAME} in the URL, proxy, and headers expands from the task environment.
+type HTTPConfig struct {
+	Method       string            `yaml:"method"`        // Request method (default: GET)
+	URL          string            `yaml:"url"`           // Request URL
+	Headers      map[string]string `yaml:"headers"`       // Request headers
+	Body         string            `yaml:"body"`          // Request body
+	ExpectStatus int               `yaml:"expect_status"` // Required status code (default: any 2xx)
+	Timeout      string            `yaml:"timeout"`       // Request timeout (default: 30s)
+	Proxy        string            `yaml:"proxy"`         // Proxy URL (default: HTTPS_PROXY/HTTP_PROXY)
+	TLS          *TLSConfig        `yaml:"tls"`           // Certificate settings
+}
+
+// SupportedHTTPMethods lists all valid method values for http tasks.
+var SupportedHTTPMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// Expand returns a copy of the request with fn applied to the URL, header
+// values, and body.
+func (h *HTTPConfig) Expand(fn func(string) string) *HTTPConfig {
+	expanded := *h
+	expanded.URL = fn(h.URL)
+	expanded.Body = fn(h.Body)
+	expanded.Headers = expandEnv(h.Headers, fn)
+	return &expanded
+}
+
+// Text returns all request text that may contain template variables.
+func (h *HTTPConfig) Text() string {
+	text := h.URL + "\n" + h.Body
+	for _, v := range h.Headers {
+		text += "\n" + v
+	}
+	return text
+}
+
+// GitConfig defines a git operation. Each op uses only the fields it
+// needs; nothing is ever force-pushed unless force is set.
+type GitConfig struct {
+	Op      string     `yaml:"op"`      // clone, checkout, create-branch, commit, or push
+	Repo    string     `yaml:"repo"`    // Repository URL to clone
+	Dir     string     `yaml:"dir"`     // Repository directory, or clone target (default: workdir)
+	Branch  string     `yaml:"branch"`  // Branch to clone, check out, create, or push
+	Message string     `yaml:"message"` // Commit message
+	Paths   StringList `yaml:"paths"`   // Paths to stage before committing (default: all changes)
+	Remote  string     `yaml:"remote"`  // Remote to push to (default: origin)
+	Depth   int        `yaml:"depth"`   // Shallow clone depth
+	Force   bool       `yaml:"force"`   // Push with --force-with-lease
+}
+
+// Git operations supported by git tasks.
+const (
+	GitClone        = "clone"
+	GitCheckout     = "checkout"
+	GitCreateBranch = "create-branch"
+	GitCommit       = "commit"
+	GitPush         = "push"
+)
+
+// SupportedGitOps lists all valid op values for git tasks.
+var SupportedGitOps = []string{GitClone, GitCheckout, GitCreateBranch, GitCommit, GitPush}
+
+// Expand returns a copy of the operation with fn applied to all text fields.
+func (g *GitConfig) Expand(fn func(string) string) *GitConfig {
+	expanded := *g
+	expanded.Repo = fn(g.Repo)
+	expanded.Dir = fn(g.Dir)
+	expanded.Branch = fn(g.Branch)
+	expanded.Message = fn(g.Message)
+	expanded.Remote = fn(g.Remote)
+	expanded.Paths = expandList(g.Paths, fn)
+	return &expanded
+}
+
+// Text returns all operation text that may contain template variables.
+func (g *GitConfig) Text() string {
+	return strings.Join(append([]string{g.Repo, g.Dir, g.Branch, g.Message, g.Remote}, g.Paths...), "\n")
+}
+
+// WaitConfig defines a fixed delay, e.g. to let a deploy settle.
+type WaitConfig struct {
+	Duration string `yaml:"duration"` // How long to wait, e.g. "30s" or "5m"
+}
+
+// PollConfig defines a check that is repeated until it succeeds or the
+// timeout expires. Exactly one of Command and HTTP is set.
+type PollConfig struct {
+	Command  string      `yaml:"command"`  // Shell command that exits 0 when ready
+	HTTP     *HTTPConfig `yaml:"http"`     // HTTP request that returns the expected status when ready
+	Interval string      `yaml:"interval"` // Delay between attempts (default: 10s)
+	Timeout  string      `yaml:"timeout"`  // Give up after this long (default: 10m)
+}
+
+// Expand returns a copy of the check with fn applied to the command and
+// HTTP request.
+func (p *PollConfig) Expand(fn func(string) string) *PollConfig {
+	expanded := *p
+	expanded.Command = fn(p.Command)
+	if p.HTTP != nil {
+		expanded.HTTP = p.HTTP.Expand(fn)
+	}
+	return &expanded
+}
+
+// Text returns all check text that may contain template variables.
+func (p *PollConfig) Text() string {
+	text := p.Command
+	if p.HTTP != nil {
+		text += "\n" + p.HTTP.Text()
+	}
+	return text
+}
+
+// NotifyConfig defines a message sent to the configured webhooks as a
+// "notify" event while the workflow runs.
+type NotifyConfig struct {
+	Message string `yaml:"message"` // Message text (supports template variables)
+	Level   string `yaml:"level"`   // info (default), success, warning, or error
+}
+
+// SupportedNotifyLevels lists all valid level values for notify tasks.
+var SupportedNotifyLevels = []string{"info", "success", "warning", "error"}
+
+// PullRequestConfig defines a GitHub pull request opened from a branch that
+// was pushed, such as the run's. The title and body support template
+// variables, so they can include the outputs of earlier tasks.
+type PullRequestConfig struct {
+	Title    string `yaml:"title"`     // Pull request title
+	Body     string `yaml:"body"`      // Pull request description (Markdown)
+	Head     string `yaml:"head"`      // Branch with the changes (default: the branch checked out in the task's workdir)
+	Base     string `yaml:"base"`      // Branch to merge into (default: the repository's default branch)
+	Repo     string `yaml:"repo"`      // Repository as owner/name (default: from the origin remote)
+	Draft    bool   `yaml:"draft"`     // Open the pull request as a draft
+	TokenEnv string `yaml:"token_env"` // Env var holding the token (default: GITHUB_TOKEN)
+	APIURL   string `yaml:"api_url"`   // API endpoint, for GitHub Enterprise (default: https://api.github.com)
+}
+
+// DefaultGitHubTokenEnv holds the token of pull_request tasks that do not
+// set token_env.
+const DefaultGitHubTokenEnv = "GITHUB_TOKEN"
+
+// Expand returns a copy of the pull request with fn applied to all text
+// fields.
+func (p *PullRequestConfig) Expand(fn func(string) string) *PullRequestConfig {
+	expanded := *p
+	expanded.Title = fn(p.Title)
+	expanded.Body = fn(p.Body)
+	expanded.Head = fn(p.Head)
+	expanded.Base = fn(p.Base)
+	expanded.Repo = fn(p.Repo)
+	return &expanded
+}
+
+// Text returns all pull request text that may contain template variables.
+func (p *PullRequestConfig) Text() string {
+	return strings.Join([]string{p.Title, p.Body, p.Head, p.Base, p.Repo}, "\n")
+}
+
+// BuiltinTool returns the tool name for built-in task types that run without
+// an agent, or an empty string for agent tasks.
+func (t TaskConfig) BuiltinTool() string {
+	if types := t.builtinTypes(); len(types) > 0 {
+		return types[0]
+	}
+	return ""
+}
+
+// builtinTypes returns the keys of all built-in task types the task sets.
+func (t TaskConfig) builtinTypes() []string {
+	var types []string
+	if t.Script != nil {
+		types = append(types, "script")
+	}
+	if t.HTTP != nil {
+		types = append(types, "http")
+	}
+	if t.Git != nil {
+		types = append(types, "git")
+	}
+	if t.Wait != nil {
+		types = append(types, "wait")
+	}
+	if t.Poll != nil {
+		types = append(types, "poll")
+	}
+	if t.Notify != nil {
+		types = append(types, "notify")
+	}
+	if t.PullRequest != nil {
+		types = append(types, "pull_request")
+	}
+	return types
+}
+
+// RunsWith returns the agent as the task runs it: with the task's tool,
+// model, and system prompt, when set, in place of the agent's.
+func (t TaskConfig) RunsWith(agent AgentConfig) AgentConfig {
+	if t.Tool != "" {
+		agent.Tool = t.Tool
+	}
+	if t.Model != "" {
+		agent.Model = t.Model
+	}
+	if t.SystemPrompt != "" {
+		agent.SystemPrompt = t.SystemPrompt
+	}
+	return agent
+}
+
+// systemPromptTools lists the built-in tools that take a system prompt.
+// Custom tools pass it with system_prompt_args.
+var systemPromptTools = []string{"claude-code", "api"}
+
+// SupportsSystemPrompt reports whether agents of tool can set a system prompt.
+func SupportsSystemPrompt(tool string) bool {
+	return containsString(systemPromptTools, tool) || isCustomTool(tool)
+}
+
+// IsFanOut reports whether the task expands into one instance per item.
+func (t TaskConfig) IsFanOut() bool {
+	return t.ItemsFrom != "" || len(t.Matrix) > 0
 }
 
 // StringList is a custom type that can unmarshal from either a single string or an array of strings.
@@ -39,6 +420,10 @@ type StringList []string
 // UnmarshalYAML implements custom unmarshaling for StringList to handle both string and []string.
 func (s *StringList) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
+	case yaml.AliasNode:
+		// Anchored value, e.g. needs: *deps
+		return s.UnmarshalYAML(node.Alias)
+
 	case yaml.ScalarNode:
 		// Single string value
 		var single string
@@ -61,6 +446,11 @@ func (s *StringList) UnmarshalYAML(node *yaml.Node) error {
 		*s = list
 		return nil
 
+	case yaml.MappingNode:
+		return &yaml.TypeError{Errors: []string{
+			fmt.Sprintf("line %d: cannot unmarshal !!map into config.StringList", node.Line),
+		}}
+
 	default:
 		// Null or empty
 		*s = []string{}
@@ -69,10 +459,37 @@ func (s *StringList) UnmarshalYAML(node *yaml.Node) error {
 }
 
 // SupportedTools lists all valid tool values for agents.
-var SupportedTools = []string{"claude-code", "opencode", "shell"}
+var SupportedTools = []string{"claude-code", "opencode", "aider", "api", "shell"}
+
+// Values for TaskConfig.ShowOutput.
+const (
+	ShowOutputFull    = "full"    // Stream output live
+	ShowOutputSummary = "summary" // Print the first lines of output when the task finishes
+	ShowOutputNone    = "none"    // Print nothing, even with --verbose
+)
+
+// SupportedShowOutputModes lists all valid show_output values.
+var SupportedShowOutputModes = []string{ShowOutputFull, ShowOutputSummary, ShowOutputNone}
+
+// SupportedScriptLangs lists all valid lang values for script tasks.
+var SupportedScriptLangs = []string{"python", "node"}
+
+// IsSupportedScriptLang checks if a script language is valid.
+func IsSupportedScriptLang(lang string) bool {
+	for _, l := range SupportedScriptLangs {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
 
-// IsSupportedTool checks if a tool name is valid.
+// IsSupportedTool checks if a tool name is valid: a built-in tool or one
+// declared under tools: in the global config.
 func IsSupportedTool(tool string) bool {
+	if isCustomTool(tool) {
+		return true
+	}
 	for _, t := range SupportedTools {
 		if t == tool {
 			return true
@@ -28,6 +28,8 @@ func (e *ConfigError) Error() string {
 			}
 		}
 		sb.WriteString(": ")
+	} else if e.Line > 0 {
+		sb.WriteString(fmt.Sprintf("line %d: ", e.Line))
 	}
 
 	// Message
@@ -115,11 +117,12 @@ func ErrUndefinedAgent(file string, line int, taskName, agentName string, availa
 
 // ErrUnsupportedTool creates an error for an unsupported tool.
 func ErrUnsupportedTool(file string, line int, agentName, tool string) *ConfigError {
+	tools := SupportedToolNames()
 	hint := ""
-	if suggestion := SuggestClosestMatch(tool, SupportedTools); suggestion != "" {
-		hint = fmt.Sprintf("Did you mean %q? Supported tools: %s", suggestion, strings.Join(SupportedTools, ", "))
+	if suggestion := SuggestClosestMatch(tool, tools); suggestion != "" {
+		hint = fmt.Sprintf("Did you mean %q? Supported tools: %s", suggestion, strings.Join(tools, ", "))
 	} else {
-		hint = fmt.Sprintf("Supported tools: %s", strings.Join(SupportedTools, ", "))
+		hint = fmt.Sprintf("Supported tools: %s", strings.Join(tools, ", "))
 	}
 	return &ConfigError{
 		File:    file,
@@ -177,6 +180,16 @@ func ErrPromptFileNotFound(file string, line int, taskName, promptFile string) *
 	}
 }
 
+// ErrNoFilesMatched creates an error for a file pattern that matches nothing.
+func ErrNoFilesMatched(file string, line int, taskName, field, pattern string) *ConfigError {
+	return &ConfigError{
+		File:    file,
+		Line:    line,
+		Message: fmt.Sprintf("task %q: %s %q matched no files", taskName, field, pattern),
+		Hint:    "Check the path or glob; patterns are relative to the Cortexfile's directory",
+	}
+}
+
 // ErrNoAgents creates an error for config with no agents defined.
 func ErrNoAgents(file string) *ConfigError {
 	return &ConfigError{
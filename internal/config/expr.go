@@ -0,0 +1,923 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Expr is a parsed expression from a `{{ ... }}` block, used in `when`
+// conditions and dynamic settings.
+//
+// Values are strings, numbers (float64), booleans, lists, or objects.
+// Supported syntax:
+//
+//	literals     'text', "text", 42, 1.5, true, false
+//	variables    outputs.<task>, status.<task>, env.<NAME>, cpu_count
+//	fields       outputs.<task>.<field>, item.<field>, list.0
+//	logic        ||  &&  !  (or: or, and, not)
+//	comparison   ==  !=  <  <=  >  >=  contains  not contains  matches
+//	arithmetic   +  -  *  /  %
+//	functions    len, lower, upper, trim, int, min, max, replace, split,
+//	             join, lines, default, json, quote
+//
+// Strings that look like numbers compare and calculate as numbers, so
+// `outputs.count > 3` works on task output. Fields of a string are read
+// from the JSON it holds.
+type Expr struct {
+	src  string
+	root exprNode
+}
+
+// ExprEnv maps variable names (e.g. "outputs.build", "cpu_count") to values.
+type ExprEnv map[string]interface{}
+
+// exprBlockRegex matches a value that is a single {{ ... }} block.
+var exprBlockRegex = regexp.MustCompile(`^\{\{(.*)\}\}$`)
+
+// plainRefRegex matches the inside of a plain template variable such as
+// {{outputs.task}}, {{outputs.task.field}}, {{outputs.task | head 10}}, or
+// {{vars.name}}, which is substituted rather than evaluated.
+var plainRefRegex = regexp.MustCompile(`^(outputs\.[a-zA-Z0-9_-]+(\.\*|(\.[a-zA-Z0-9_-]+)*)(\s*\|.*)?|(status|vars)\.[a-zA-Z0-9_-]+(\.\*)?)$`)
+
+// ExprBlock returns the expression inside a value of the form "{{ expr }}".
+// Plain template variables like {{outputs.task}} are not expression blocks.
+func ExprBlock(s string) (string, bool) {
+	m := exprBlockRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || strings.Contains(m[1], "{{") || strings.Contains(m[1], "}}") {
+		return "", false
+	}
+	inner := strings.TrimSpace(m[1])
+	if inner == "" || plainRefRegex.MatchString(inner) {
+		return "", false
+	}
+	return inner, true
+}
+
+// ParseExpr parses an expression (without the surrounding braces).
+func ParseExpr(src string) (*Expr, error) {
+	tokens, err := tokenizeExpr(src)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", src, err)
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", src, err)
+	}
+
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the expression source.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression against env.
+func (e *Expr) Eval(env ExprEnv) (interface{}, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", e.src, err)
+	}
+	return v, nil
+}
+
+// EvalBool evaluates the expression and converts the result to a boolean.
+func (e *Expr) EvalBool(env ExprEnv) (bool, error) {
+	v, err := e.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	return exprTruthy(v), nil
+}
+
+// EvalInt evaluates the expression and rounds the result down to an integer.
+func (e *Expr) EvalInt(env ExprEnv) (int, error) {
+	v, err := e.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := exprNumber(v)
+	if !ok {
+		return 0, fmt.Errorf("expression %q: result %q is not a number", e.src, exprString(v))
+	}
+	return int(math.Floor(n)), nil
+}
+
+// EvalFloat evaluates the expression to a number.
+func (e *Expr) EvalFloat(env ExprEnv) (float64, error) {
+	v, err := e.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := exprNumber(v)
+	if !ok {
+		return 0, fmt.Errorf("expression %q: result %q is not a number", e.src, exprString(v))
+	}
+	return n, nil
+}
+
+// Vars returns the variable names the expression references, sorted.
+func (e *Expr) Vars() []string {
+	seen := make(map[string]bool)
+	e.root.vars(seen)
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SettingsEnv returns the variables available to expressions in settings:
+// cpu_count and env.<NAME> for the process environment.
+func SettingsEnv() ExprEnv {
+	env := ExprEnv{"cpu_count": float64(runtime.NumCPU())}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env["env."+k] = v
+		}
+	}
+	return env
+}
+
+// ConditionEnv returns the variables available to `when` expressions: task
+// outputs and statuses plus the settings variables.
+func ConditionEnv(outputs, statuses map[string]string) ExprEnv {
+	env := SettingsEnv()
+	for name, output := range outputs {
+		env["outputs."+name] = output
+	}
+	for name, status := range statuses {
+		env["status."+name] = status
+	}
+	return env
+}
+
+// --- Tokenizer ---
+
+type exprTokenKind int
+
+const (
+	tokEOF exprTokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+	num  float64
+}
+
+// exprOps lists symbolic operators, longest first.
+var exprOps = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", ","}
+
+func tokenizeExpr(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '\'' || c == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != c; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				sb.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, exprToken{kind: tokString, text: sb.String()})
+			i = j + 1
+
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			n, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", src[i:j])
+			}
+			tokens = append(tokens, exprToken{kind: tokNumber, text: src[i:j], num: n})
+			i = j
+
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			// Segments after a dot may contain dashes, like task names
+			j := i
+			for j < len(src) && isIdentChar(src[j]) {
+				j++
+			}
+			for j+1 < len(src) && src[j] == '.' && (isIdentChar(src[j+1]) || src[j+1] == '-' || src[j+1] == '*') {
+				j++
+				for j < len(src) && (isIdentChar(src[j]) || src[j] == '-' || src[j] == '*') {
+					j++
+				}
+			}
+			tokens = append(tokens, exprToken{kind: tokIdent, text: src[i:j]})
+			i = j
+
+		default:
+			op := ""
+			for _, candidate := range exprOps {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, fmt.Errorf("unexpected character %q", r)
+			}
+			tokens = append(tokens, exprToken{kind: tokOp, text: op})
+			i += len(op)
+		}
+	}
+	return append(tokens, exprToken{kind: tokEOF}), nil
+}
+
+// isIdentChar reports whether c may continue an identifier. Identifiers are
+// ASCII only.
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// --- Parser ---
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators or
+// keywords and returns it.
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "||", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("==", "!=", "<=", ">=", "<", ">", "contains", "matches")
+		if !ok {
+			// "not contains" is the only binary use of "not"
+			if t := p.peek(); t.kind == tokIdent && t.text == "not" &&
+				p.tokens[p.pos+1].kind == tokIdent && p.tokens[p.pos+1].text == "contains" {
+				p.pos += 2
+				op, ok = "not contains", true
+			}
+		}
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if op, ok := p.accept("!", "not", "-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "not" {
+			op = "!"
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return &literalNode{value: t.num}, nil
+	case tokString:
+		return &literalNode{value: t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(t.text)
+		}
+		return &varNode{name: t.text}, nil
+	case tokOp:
+		if t.text == "(" {
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, fmt.Errorf("missing closing parenthesis")
+			}
+			return inner, nil
+		}
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	default:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+}
+
+func (p *exprParser) parseCall(name string) (exprNode, error) {
+	fn, ok := exprFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+
+	var args []exprNode
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); ok {
+				continue
+			}
+			if _, ok := p.accept(")"); ok {
+				break
+			}
+			return nil, fmt.Errorf("expected ',' or ')' in call to %s", name)
+		}
+	}
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments to %s", name)
+	}
+	return &callNode{name: name, fn: fn.call, args: args}, nil
+}
+
+// --- Evaluation ---
+
+type exprNode interface {
+	eval(env ExprEnv) (interface{}, error)
+	vars(seen map[string]bool)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(ExprEnv) (interface{}, error) { return n.value, nil }
+func (n *literalNode) vars(map[string]bool)              {}
+
+type varNode struct {
+	name string
+}
+
+func (n *varNode) eval(env ExprEnv) (interface{}, error) {
+	if v, ok := env[n.name]; ok {
+		return v, nil
+	}
+	// Unset environment variables read as empty, like in a shell
+	if strings.HasPrefix(n.name, "env.") {
+		return "", nil
+	}
+
+	// Select a field of the longest defined prefix, like outputs.review.verdict
+	keys := strings.Split(n.name, ".")
+	for i := len(keys) - 1; i > 0; i-- {
+		if v, ok := env[strings.Join(keys[:i], ".")]; ok {
+			return exprField(v, keys[i:]), nil
+		}
+	}
+	return nil, fmt.Errorf("undefined variable %q", n.name)
+}
+
+// exprField returns the field at path of a value, or nil if it has none.
+// Strings are decoded as JSON first, and list elements are selected by
+// index.
+func exprField(v interface{}, path []string) interface{} {
+	for _, key := range path {
+		if s, ok := v.(string); ok {
+			decoded, err := decodeJSON(s)
+			if err != nil {
+				return nil
+			}
+			v = decoded
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			v = val[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(val) {
+				return nil
+			}
+			v = val[i]
+		default:
+			return nil
+		}
+	}
+	return exprValue(v)
+}
+
+// exprValue converts a decoded JSON number to float64, the number type of
+// expressions.
+func exprValue(v interface{}) interface{} {
+	if n, ok := v.(json.Number); ok {
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+		return n.String()
+	}
+	return v
+}
+
+// exprList returns the elements of a list, or of the JSON array a string
+// holds. nil and empty strings are empty lists.
+func exprList(v interface{}) ([]interface{}, bool) {
+	if v == nil {
+		return nil, true
+	}
+	if s, ok := v.(string); ok {
+		if strings.TrimSpace(s) == "" {
+			return nil, true
+		}
+		decoded, err := decodeJSON(s)
+		if err != nil {
+			return nil, false
+		}
+		v = decoded
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	items := make([]interface{}, len(list))
+	for i, item := range list {
+		items[i] = exprValue(item)
+	}
+	return items, true
+}
+
+func (n *varNode) vars(seen map[string]bool) { seen[n.name] = true }
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+func (n *unaryNode) eval(env ExprEnv) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !exprTruthy(v), nil
+	}
+	num, ok := exprNumber(v)
+	if !ok {
+		return nil, fmt.Errorf("cannot negate %q", exprString(v))
+	}
+	return -num, nil
+}
+
+func (n *unaryNode) vars(seen map[string]bool) { n.operand.vars(seen) }
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *binaryNode) eval(env ExprEnv) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit logic operators
+	switch n.op {
+	case "||":
+		if exprTruthy(left) {
+			return true, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return exprTruthy(right), nil
+	case "&&":
+		if !exprTruthy(left) {
+			return false, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return exprTruthy(right), nil
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return exprEqual(left, right), nil
+	case "!=":
+		return !exprEqual(left, right), nil
+	case "contains":
+		return strings.Contains(exprString(left), exprString(right)), nil
+	case "not contains":
+		return !strings.Contains(exprString(left), exprString(right)), nil
+	case "matches":
+		re, err := regexp.Compile(exprString(right))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", exprString(right), err)
+		}
+		return re.MatchString(exprString(left)), nil
+	case "+":
+		// Two strings concatenate; anything else adds as numbers
+		_, ls := left.(string)
+		_, rs := right.(string)
+		if ls && rs {
+			return exprString(left) + exprString(right), nil
+		}
+	}
+
+	l, lok := exprNumber(left)
+	r, rok := exprNumber(right)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operator %q needs numbers, got %q and %q", n.op, exprString(left), exprString(right))
+	}
+
+	switch n.op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(l, r), nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+func (n *binaryNode) vars(seen map[string]bool) {
+	n.left.vars(seen)
+	n.right.vars(seen)
+}
+
+type callNode struct {
+	name string
+	fn   func(args []interface{}) (interface{}, error)
+	args []exprNode
+}
+
+func (n *callNode) eval(env ExprEnv) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := n.fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+func (n *callNode) vars(seen map[string]bool) {
+	for _, arg := range n.args {
+		arg.vars(seen)
+	}
+}
+
+// exprVars adds the variables node reads to seen, like its vars method, but
+// with skipQuoted, leaves out the arguments of quote() calls.
+func exprVars(node exprNode, skipQuoted bool, seen map[string]bool) {
+	switch n := node.(type) {
+	case *callNode:
+		if skipQuoted && n.name == "quote" {
+			return
+		}
+		for _, arg := range n.args {
+			exprVars(arg, skipQuoted, seen)
+		}
+	case *unaryNode:
+		exprVars(n.operand, skipQuoted, seen)
+	case *binaryNode:
+		exprVars(n.left, skipQuoted, seen)
+		exprVars(n.right, skipQuoted, seen)
+	default:
+		node.vars(seen)
+	}
+}
+
+// exprFunc describes a built-in function. maxArgs -1 means variadic.
+type exprFunc struct {
+	minArgs, maxArgs int
+	call             func(args []interface{}) (interface{}, error)
+}
+
+var exprFuncs = map[string]exprFunc{
+	"len": {1, 1, func(args []interface{}) (interface{}, error) {
+		switch val := args[0].(type) {
+		case []interface{}:
+			return float64(len(val)), nil
+		case map[string]interface{}:
+			return float64(len(val)), nil
+		}
+		return float64(len([]rune(exprString(args[0])))), nil
+	}},
+	"lower": {1, 1, func(args []interface{}) (interface{}, error) {
+		return strings.ToLower(exprString(args[0])), nil
+	}},
+	"upper": {1, 1, func(args []interface{}) (interface{}, error) {
+		return strings.ToUpper(exprString(args[0])), nil
+	}},
+	"trim": {1, 1, func(args []interface{}) (interface{}, error) {
+		return strings.TrimSpace(exprString(args[0])), nil
+	}},
+	"int": {1, 1, func(args []interface{}) (interface{}, error) {
+		n, ok := exprNumber(args[0])
+		if !ok {
+			return nil, fmt.Errorf("%q is not a number", exprString(args[0]))
+		}
+		return math.Trunc(n), nil
+	}},
+	"min": {1, -1, func(args []interface{}) (interface{}, error) {
+		return exprFold(args, math.Min)
+	}},
+	"max": {1, -1, func(args []interface{}) (interface{}, error) {
+		return exprFold(args, math.Max)
+	}},
+	"replace": {3, 3, func(args []interface{}) (interface{}, error) {
+		return strings.ReplaceAll(exprString(args[0]), exprString(args[1]), exprString(args[2])), nil
+	}},
+	"split": {2, 2, func(args []interface{}) (interface{}, error) {
+		return exprStrings(strings.Split(exprString(args[0]), exprString(args[1]))), nil
+	}},
+	"join": {2, 2, func(args []interface{}) (interface{}, error) {
+		items, ok := exprList(args[0])
+		if !ok {
+			return nil, fmt.Errorf("%q is not a list", exprString(args[0]))
+		}
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = exprString(item)
+		}
+		return strings.Join(parts, exprString(args[1])), nil
+	}},
+	"lines": {1, 1, func(args []interface{}) (interface{}, error) {
+		text := strings.TrimRight(exprString(args[0]), "\n")
+		if text == "" {
+			return []interface{}{}, nil
+		}
+		return exprStrings(strings.Split(text, "\n")), nil
+	}},
+	"default": {2, 2, func(args []interface{}) (interface{}, error) {
+		if args[0] == nil || strings.TrimSpace(exprString(args[0])) == "" {
+			return args[1], nil
+		}
+		return args[0], nil
+	}},
+	"quote": {1, 1, func(args []interface{}) (interface{}, error) {
+		// Single quotes keep everything literal in a POSIX shell, except
+		// single quotes themselves
+		return "'" + strings.ReplaceAll(exprString(args[0]), "'", `'\''`) + "'", nil
+	}},
+	"json": {1, 1, func(args []interface{}) (interface{}, error) {
+		data, err := json.Marshal(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}},
+}
+
+// exprStrings converts strings to a list value.
+func exprStrings(strs []string) []interface{} {
+	list := make([]interface{}, len(strs))
+	for i, s := range strs {
+		list[i] = s
+	}
+	return list
+}
+
+// exprFold reduces numeric arguments with fn.
+func exprFold(args []interface{}, fn func(a, b float64) float64) (interface{}, error) {
+	var result float64
+	for i, arg := range args {
+		n, ok := exprNumber(arg)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a number", exprString(arg))
+		}
+		if i == 0 {
+			result = n
+		} else {
+			result = fn(result, n)
+		}
+	}
+	return result, nil
+}
+
+// exprNumber converts a value to a number; strings are parsed after trimming.
+// Strings such as "NaN" and "Inf" stay strings, as they are words in text
+// more often than numbers, and would compare unlike any.
+func exprNumber(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return n, err == nil && !math.IsNaN(n) && !math.IsInf(n, 0)
+	}
+	return 0, false
+}
+
+// exprString converts a value to its string form. Whole numbers print
+// without a decimal point, lists and objects as JSON, and nil as "".
+func exprString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	case []interface{}, map[string]interface{}:
+		data, err := json.Marshal(val)
+		if err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// exprTruthy applies `when` truthiness: false, 0, and empty, "false", "0",
+// or "no" strings are false.
+func exprTruthy(v interface{}) bool {
+	switch val := v.(type) {
+	case bool:
+		return val
+	case float64:
+		return val != 0
+	case []interface{}:
+		return len(val) > 0
+	case map[string]interface{}:
+		return len(val) > 0
+	}
+	return isTruthy(exprString(v))
+}
+
+// exprEqual compares values numerically when both are numbers (or numeric
+// strings), otherwise as trimmed strings.
+func exprEqual(a, b interface{}) bool {
+	if x, ok := exprNumber(a); ok {
+		if y, ok := exprNumber(b); ok {
+			return x == y
+		}
+	}
+	return strings.TrimSpace(exprString(a)) == strings.TrimSpace(exprString(b))
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestExpr_Eval tests evaluating expressions against an environment.
+func TestExpr_Eval(t *testing.T) {
+	env := ExprEnv{
+		"outputs.test":   "3 passed, 1 FAIL",
+		"outputs.count":  "42\n",
+		"outputs.my-app": "ok",
+		"status.lint":    "skipped",
+		"cpu_count":      float64(8),
+		"outputs.nan":    "NaN",
+		"outputs.inf":    " Inf\n",
+	}
+
+	tests := []struct {
+		expr    string
+		want    interface{}
+		wantErr bool
+	}{
+		{"cpu_count / 2", float64(4), false},
+		{"1 + 2 * 3", float64(7), false},
+		{"(1 + 2) * 3", float64(9), false},
+		{"-cpu_count + 10", float64(2), false},
+		{"7 % 4", float64(3), false},
+		{"outputs.count > 40", true, false},
+		{"outputs.count == 42", true, false},
+		{"outputs.count + 1", float64(43), false},
+		{"'a' + 'b'", "ab", false},
+		{"outputs.test contains 'FAIL'", true, false},
+		{"outputs.test not contains 'FAIL'", false, false},
+		{"outputs.test matches '^[0-9]+ passed'", true, false},
+		{"status.lint == 'skipped' && outputs.count > 3", true, false},
+		{"status.lint == 'failed' or not (cpu_count < 4)", true, false},
+		{"!true || false", false, false},
+		{"outputs.my-app == \"ok\"", true, false},
+		{"max(1, cpu_count / 4, 3)", float64(3), false},
+		{"min(cpu_count, 2)", float64(2), false},
+		{"len(upper(trim(' ab ')))", float64(2), false},
+		{"int(7 / 2)", float64(3), false},
+		{"env.CORTEX_EXPR_TEST_UNSET", "", false},
+		{"outputs.missing", nil, true},
+		{"outputs.test > 3", nil, true},
+		{"1 / 0", nil, true},
+		{"outputs.nan == 'NaN'", true, false},
+		{"outputs.nan == outputs.nan", true, false},
+		{"outputs.inf == 'Inf'", true, false},
+		{"outputs.inf > 1", nil, true},
+		{"outputs.nan + 1", nil, true},
+		{"int(outputs.inf)", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseExpr() error = %v", err)
+			}
+			got, err := expr.Eval(env)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Eval() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseExpr_Errors tests that malformed expressions are rejected.
+func TestParseExpr_Errors(t *testing.T) {
+	tests := []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"'unterminated",
+		"a == == b",
+		"foo(1)",
+		"min()",
+		"1 2",
+		"a # b",
+		"é == 1",
+		"a == ü",
+	}
+
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			if _, err := ParseExpr(src); err == nil {
+				t.Errorf("ParseExpr(%q) expected error", src)
+			}
+		})
+	}
+}
+
+// TestExpr_Vars tests listing referenced variables.
+func TestExpr_Vars(t *testing.T) {
+	expr, err := ParseExpr("status.lint == 'ok' && len(outputs.build) > cpu_count || outputs.shard.* contains 'x'")
+	if err != nil {
+		t.Fatalf("ParseExpr() error = %v", err)
+	}
+
+	want := []string{"cpu_count", "outputs.build", "outputs.shard.*", "status.lint"}
+	if got := expr.Vars(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Vars() = %v, want %v", got, want)
+	}
+}
+
+// TestExprBlock tests detecting {{ expression }} values.
+func TestExprBlock(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   string
+		wantOK bool
+	}{
+		{"{{ cpu_count / 2 }}", "cpu_count / 2", true},
+		{"{{status.a == 'ok'}}", "status.a == 'ok'", true},
+		{"{{outputs.check}}", "", false},
+		{"{{outputs.check | tail 1}}", "", false},
+		{"{{ vars.env }}", "", false},
+		{"{{outputs.test}} contains 'FAIL'", "", false},
+		{"{{status.a}} == {{status.b}}", "", false},
+		{"4", "", false},
+		{"{{  }}", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := ExprBlock(tt.value)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("ExprBlock(%q) = %q, %v; want %q, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestCondition_Expr tests `when` conditions written as expressions.
+func TestCondition_Expr(t *testing.T) {
+	outputs := map[string]string{"count": "5", "log": "error: boom"}
+	statuses := map[string]string{"lint": "failed"}
+
+	tests := []struct {
+		when    string
+		want    bool
+		wantErr bool
+	}{
+		{"{{ outputs.count >= 5 && status.lint == 'failed' }}", true, false},
+		{"{{ outputs.log matches 'error:' and outputs.count < 3 }}", false, false},
+		{"{{ outputs.log > 1 }}", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.when, func(t *testing.T) {
+			cond, err := ParseCondition(tt.when)
+			if err != nil {
+				t.Fatalf("ParseCondition() error = %v", err)
+			}
+			if cond.Expr == nil {
+				t.Fatalf("ParseCondition() did not parse an expression")
+			}
+			got, err := cond.Check(outputs, statuses)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidate_ExprCondition tests plan-time checks of `when` expressions.
+func TestValidate_ExprCondition(t *testing.T) {
+	tests := []struct {
+		name    string
+		when    string
+		wantErr string
+	}{
+		{"valid", "{{ status.build == 'success' && cpu_count > 1 }}", ""},
+		{"syntax error", "{{ status.build == }}", "invalid 'when'"},
+		{"task not in needs", "{{ outputs.other contains 'x' }}", "\"other\" is not in 'needs'"},
+		{"unknown variable", "{{ vars.env == 'prod' }}", "unknown variable vars.env"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AgentflowConfig{
+				Agents: map[string]AgentConfig{"sh": {Tool: "shell"}},
+				Tasks: map[string]TaskConfig{
+					"build":  {Agent: "sh", Command: "make"},
+					"other":  {Agent: "sh", Command: "true"},
+					"deploy": {Agent: "sh", Command: "./deploy.sh", Needs: StringList{"build"}, When: tt.when},
+				},
+			}
+
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestParseConfig_SettingsExpr tests {{ expression }} values in settings.
+func TestParseConfig_SettingsExpr(t *testing.T) {
+	yaml := `
+agents:
+  sh:
+    tool: shell
+tasks:
+  a:
+    agent: sh
+    command: "true"
+settings:
+  max_parallel: "{{ max(cpu_count / 2, 1) }}"
+`
+	cfg, err := ParseConfig([]byte(yaml), t.TempDir())
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+
+	want := runtime.NumCPU() / 2
+	if want < 1 {
+		want = 1
+	}
+	if cfg.Settings.MaxParallel != want {
+		t.Errorf("MaxParallel = %d, want %d", cfg.Settings.MaxParallel, want)
+	}
+
+	if _, err := ParseConfig([]byte(strings.Replace(yaml, "max(cpu_count / 2, 1)", "cpu_count / ", 1)), t.TempDir()); err == nil {
+		t.Error("ParseConfig() expected error for invalid settings expression")
+	}
+}
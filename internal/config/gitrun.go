@@ -0,0 +1,89 @@
+package config
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// GitRunConfig makes a run's changes revertible: each run gets its own
+// branch, each write task's changes their own commit, and the branch can be
+// pushed when the run succeeds. Git commands run in the workflow's workdir.
+type GitRunConfig struct {
+	Branch  string `yaml:"branch"`  // Branch created for each run, e.g. "cortex/{{run.id}}" (optional)
+	Commit  bool   `yaml:"commit"`  // Commit after each successful write task
+	Message string `yaml:"message"` // Commit message (default: DefaultCommitMessage)
+	Push    bool   `yaml:"push"`    // Push the branch when the run succeeds
+	Remote  string `yaml:"remote"`  // Remote to push to (default: origin)
+}
+
+// DefaultCommitMessage is the message of commits after write tasks when
+// git.message is not set.
+const DefaultCommitMessage = "cortex: {{task.name}}"
+
+// commitVarRegex matches {{task.name}} patterns in commit messages.
+var commitVarRegex = regexp.MustCompile(`\{\{task\.([a-zA-Z0-9_]+)\}\}`)
+
+// CommitVars lists the task variables of commit messages.
+var CommitVars = []string{"task.name", "task.agent", "task.changes"}
+
+// CommitMessage returns the message of the commit after a write task, with
+// the built-in variables and {{task.name}}, {{task.agent}}, and
+// {{task.changes}} (a summary of the changed files) filled in.
+func (g *GitRunConfig) CommitMessage(builtins map[string]string, task, agent, changes string) string {
+	message := g.Message
+	if message == "" {
+		message = DefaultCommitMessage
+	}
+	message = strings.NewReplacer(
+		"{{task.name}}", task,
+		"{{task.agent}}", agent,
+		"{{task.changes}}", changes,
+	).Replace(message)
+	return strings.TrimSpace(ExpandBuiltins(message, builtins))
+}
+
+// validateGitRun checks the git settings of a workflow.
+func validateGitRun(filePath string, g *GitRunConfig) []*ConfigError {
+	if g == nil {
+		return nil
+	}
+	var errs []*ConfigError
+	if !g.Commit && (g.Message != "" || g.Push) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"git: 'message' and 'push' require 'commit: true'",
+			"Add 'commit: true' to commit after each write task"))
+	}
+	if g.Remote != "" && !g.Push {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"git: 'remote' requires 'push: true'",
+			"Add 'push: true', or remove 'remote'"))
+	}
+	if strings.ContainsAny(g.Branch, " ~^:?*[\\") {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"git: invalid branch name \""+g.Branch+"\"",
+			"Branch names can't contain spaces or any of ~^:?*[\\"))
+	}
+
+	for _, ref := range ExtractBuiltinRefs(g.Branch + "\n" + g.Message) {
+		if !IsBuiltinVar(ref) {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"git: references unknown built-in variable {{"+ref+"}}",
+				"Available: {{"+strings.Join(BuiltinVars, "}}, {{")+"}}"))
+		}
+	}
+	for _, match := range commitVarRegex.FindAllStringSubmatch(g.Branch, -1) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"git: branch references {{task."+match[1]+"}}",
+			"The branch is created before any task runs; use {{run.id}} or another built-in variable"))
+	}
+	for _, match := range commitVarRegex.FindAllStringSubmatch(g.Message, -1) {
+		name := "task." + match[1]
+		if !slices.Contains(CommitVars, name) {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"git: message references unknown variable {{"+name+"}}",
+				"Available: {{"+strings.Join(CommitVars, "}}, {{")+"}}"))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGitRunConfig_CommitMessage tests that commit messages fill in the
+// task and built-in variables, with a default message.
+func TestGitRunConfig_CommitMessage(t *testing.T) {
+	builtins := map[string]string{BuiltinRunID: "20240102-150405"}
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"", "cortex: fix"},
+		{"{{task.name}} ({{task.agent}}): {{task.changes}}", "fix (coder): 2 files changed (+3 -1)"},
+		{"Run {{run.id}}: {{task.name}}\n", "Run 20240102-150405: fix"},
+	}
+	for _, tt := range tests {
+		g := &GitRunConfig{Commit: true, Message: tt.message}
+		if got := g.CommitMessage(builtins, "fix", "coder", "2 files changed (+3 -1)"); got != tt.want {
+			t.Errorf("CommitMessage(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+// TestValidate_GitRun tests the checks of a workflow's git settings.
+func TestValidate_GitRun(t *testing.T) {
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{"ai": {Tool: "claude-code"}},
+		Tasks:  map[string]TaskConfig{"fix": {Agent: "ai", Prompt: "Fix it", Write: true}},
+		Git: &GitRunConfig{
+			Branch:  "cortex/{{run.id}}",
+			Commit:  true,
+			Message: "{{task.name}}: {{task.changes}}",
+			Push:    true,
+			Remote:  "upstream",
+		},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !UsesGitBuiltins(&AgentflowConfig{Git: &GitRunConfig{Branch: "cortex/{{git.short_commit}}"}}) {
+		t.Error("UsesGitBuiltins() = false for a branch using {{git.short_commit}}")
+	}
+
+	config.Git = &GitRunConfig{
+		Branch:  "agents {{task.name}}",
+		Message: "{{task.title}} {{run.uuid}}",
+		Remote:  "upstream",
+	}
+	err := Validate(config)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	for _, want := range []string{
+		`git: 'message' and 'push' require 'commit: true'`,
+		`git: 'remote' requires 'push: true'`,
+		`git: invalid branch name "agents {{task.name}}"`,
+		`git: references unknown built-in variable {{run.uuid}}`,
+		`git: branch references {{task.name}}`,
+		`git: message references unknown variable {{task.title}}`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
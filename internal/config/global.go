@@ -1,18 +1,31 @@
 package config
 
 import (
+	"fmt"
+	"math"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/adityaraj/agentflow/internal/paths"
 )
 
 // GlobalConfig represents the global ~/.cortex/config.yml configuration.
 type GlobalConfig struct {
-	Defaults DefaultsConfig  `yaml:"defaults"`
-	Settings SettingsConfig  `yaml:"settings"`
-	Webhooks []WebhookConfig `yaml:"webhooks"`
+	Defaults  DefaultsConfig        `yaml:"defaults"`
+	Settings  SettingsConfig        `yaml:"settings"`
+	Webhooks  []WebhookConfig       `yaml:"webhooks"`
+	Server    ServerConfig          `yaml:"server"`
+	Analytics AnalyticsConfig       `yaml:"analytics"`
+	Log       LogConfig             `yaml:"log"`
+	Tools     map[string]ToolConfig `yaml:"tools"` // Custom agent tools, by name
 }
 
 // DefaultsConfig contains default agent settings.
@@ -23,10 +36,178 @@ type DefaultsConfig struct {
 
 // SettingsConfig contains execution settings.
 type SettingsConfig struct {
-	Parallel    bool `yaml:"parallel"`     // Enable parallel execution (default: true)
-	MaxParallel int  `yaml:"max_parallel"` // Max concurrent tasks (default: CPU cores)
-	Verbose     bool `yaml:"verbose"`      // Verbose output
-	Stream      bool `yaml:"stream"`       // Stream agent logs
+	Parallel            bool            `yaml:"parallel"`              // Enable parallel execution (default: true)
+	MaxParallel         int             `yaml:"max_parallel"`          // Max concurrent tasks (default: CPU cores)
+	Verbose             bool            `yaml:"verbose"`               // Verbose output
+	Stream              bool            `yaml:"stream"`                // Stream agent logs
+	Incremental         bool            `yaml:"incremental"`           // Skip tasks whose declared outputs are up to date
+	MaxTokens           int             `yaml:"max_tokens"`            // Abort the run once total tokens exceed this (0 = no limit)
+	MaxCostUSD          float64         `yaml:"max_cost_usd"`          // Abort the run once reported cost exceeds this (0 = no limit)
+	Output              string          `yaml:"output"`                // How streamed output of parallel tasks is shown (default: interleaved)
+	Theme               ThemeConfig     `yaml:"theme"`                 // Status colors and glyphs
+	Color               string          `yaml:"color"`                 // When output is colored: auto, always, or never (default: auto)
+	FailFast            *bool           `yaml:"fail_fast"`             // Stop the run at the first failure (default: true)
+	Telemetry           TelemetryConfig `yaml:"telemetry"`             // OpenTelemetry export of runs
+	Retention           RetentionConfig `yaml:"retention"`             // Limits on the sessions kept per project
+	PromptHooks         StringList      `yaml:"prompt_hooks"`          // Commands transforming the prompt of every AI task (global ones run first)
+	KillTimeout         string          `yaml:"kill_timeout"`          // Time a cancelled task's processes get to exit before they are killed (default: 5s)
+	DefaultSystemPrompt *bool           `yaml:"default_system_prompt"` // Give claude-code tasks without a system prompt the built-in formatting rules (default: true)
+	MaxOutputBytes      string          `yaml:"max_output_bytes"`      // Output kept of each task's stdout and stderr, head and tail, e.g. 1MB (default: all)
+}
+
+// DefaultKillTimeout is how long the processes of a cancelled task get to
+// exit after the interrupt before they are killed.
+const DefaultKillTimeout = 5 * time.Second
+
+// KillTimeoutDuration returns the time the processes of a cancelled task
+// get to exit before they are killed.
+func (s SettingsConfig) KillTimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(strings.TrimSpace(s.KillTimeout)); err == nil && d > 0 {
+		return d
+	}
+	return DefaultKillTimeout
+}
+
+// MaxOutput returns the bytes kept of each task's stdout and stderr, or 0
+// to keep all of it.
+func (s SettingsConfig) MaxOutput() int64 {
+	if n, err := ParseSize(s.MaxOutputBytes); err == nil {
+		return n
+	}
+	return 0
+}
+
+// FailFastEnabled reports whether the run stops at the first failed task.
+// Without fail-fast, only the failed task's dependents are skipped.
+func (s SettingsConfig) FailFastEnabled() bool {
+	return s.FailFast == nil || *s.FailFast
+}
+
+// DefaultSystemPromptEnabled reports whether claude-code tasks that set no
+// system prompt get the built-in formatting rules.
+func (s SettingsConfig) DefaultSystemPromptEnabled() bool {
+	return s.DefaultSystemPrompt == nil || *s.DefaultSystemPrompt
+}
+
+// TelemetryConfig configures OTLP export of a span per run and task, and of
+// task success and failure counters.
+type TelemetryConfig struct {
+	Endpoint    string            `yaml:"endpoint"`     // OTLP/HTTP collector URL, e.g. http://localhost:4318 (empty = disabled)
+	Headers     map[string]string `yaml:"headers"`      // Sent with every export, e.g. an API key
+	ServiceName string            `yaml:"service_name"` // service.name resource attribute (default: cortex)
+}
+
+// Values for SettingsConfig.Output.
+const (
+	OutputInterleaved = "interleaved" // Write lines as they arrive
+	OutputPrefixed    = "prefixed"    // Prefix each line with the task name
+	OutputGrouped     = "grouped"     // Buffer each task's output and print it as one block when the task ends
+)
+
+// SupportedOutputModes lists all valid settings.output values.
+var SupportedOutputModes = []string{OutputInterleaved, OutputPrefixed, OutputGrouped}
+
+// Values for SettingsConfig.Color.
+const (
+	ColorAuto   = "auto"   // Color when stdout is a terminal and NO_COLOR is unset
+	ColorAlways = "always" // Color even when piped, e.g. for CI logs that render escape codes
+	ColorNever  = "never"  // Never color
+)
+
+// SupportedColorModes lists all valid settings.color values.
+var SupportedColorModes = []string{ColorAuto, ColorAlways, ColorNever}
+
+// ThemeConfig selects the palette and glyphs used for task and run statuses.
+type ThemeConfig struct {
+	Palette string            `yaml:"palette"` // default or accessible
+	Glyphs  map[string]string `yaml:"glyphs"`  // Glyph overrides by status (success, failed, skipped, running, warning)
+}
+
+// Values for ThemeConfig.Palette.
+const (
+	PaletteDefault    = "default"    // Green for success, red for failure
+	PaletteAccessible = "accessible" // Blue and magenta, distinguishable with common color blindness
+)
+
+// SupportedPalettes lists all valid settings.theme.palette values.
+var SupportedPalettes = []string{PaletteDefault, PaletteAccessible}
+
+// SupportedGlyphStatuses lists the statuses settings.theme.glyphs can set.
+var SupportedGlyphStatuses = []string{"success", "failed", "skipped", "running", "warning", "cancelled"}
+
+// settingsExprFields lists numeric settings whose value may be a
+// {{ expression }}, evaluated when the config is loaded. Integer settings
+// are rounded down and at least 1.
+var settingsExprFields = map[string]string{
+	"max_parallel": "!!int",
+	"max_tokens":   "!!int",
+	"max_cost_usd": "!!float",
+}
+
+// UnmarshalYAML evaluates {{ expression }} values of numeric settings, such
+// as max_parallel: "{{ cpu_count / 2 }}", before decoding.
+func (s *SettingsConfig) UnmarshalYAML(node *yaml.Node) error {
+	if err := evalSettingsExprs(node); err != nil {
+		return err
+	}
+
+	type plain SettingsConfig
+	return node.Decode((*plain)(s))
+}
+
+// evalSettingsExprs replaces {{ expression }} values of numeric settings in a
+// settings mapping with their result, following aliases and `<<` merge keys
+// so settings shared through an anchor are evaluated too.
+func evalSettingsExprs(node *yaml.Node) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.ShortTag() == "!!merge" {
+			// The merged value is a mapping, an alias, or a list of them
+			merged := []*yaml.Node{value}
+			if value.Kind == yaml.SequenceNode {
+				merged = value.Content
+			}
+			for _, m := range merged {
+				if err := evalSettingsExprs(m); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		tag, numeric := settingsExprFields[key.Value]
+		if value.Kind != yaml.ScalarNode || !numeric {
+			continue
+		}
+		inner, ok := ExprBlock(value.Value)
+		if !ok {
+			continue
+		}
+
+		expr, err := ParseExpr(inner)
+		if err != nil {
+			return fmt.Errorf("line %d: settings.%s: %w", value.Line, key.Value, err)
+		}
+		n, err := expr.EvalFloat(SettingsEnv())
+		if err != nil {
+			return fmt.Errorf("line %d: settings.%s: %w", value.Line, key.Value, err)
+		}
+		if tag == "!!int" {
+			value.Value = strconv.Itoa(max(int(math.Floor(n)), 1))
+		} else {
+			value.Value = strconv.FormatFloat(n, 'f', -1, 64)
+		}
+		value.Tag = tag
+		value.Style = 0
+	}
+	return nil
 }
 
 // WebhookConfig defines a webhook endpoint.
@@ -34,6 +215,125 @@ type WebhookConfig struct {
 	URL     string            `yaml:"url"`
 	Events  []string          `yaml:"events"` // Events to trigger on
 	Headers map[string]string `yaml:"headers"`
+	Retries *int              `yaml:"retries"` // Attempts after a failed one (default: 3)
+	Backoff string            `yaml:"backoff"` // Delay before the first retry, doubled after each (default: 1s)
+}
+
+// Webhook delivery defaults.
+const (
+	DefaultWebhookRetries = 3
+	DefaultWebhookBackoff = time.Second
+)
+
+// RetryCount returns how many times a failed delivery is retried.
+func (w *WebhookConfig) RetryCount() int {
+	if w.Retries == nil {
+		return DefaultWebhookRetries
+	}
+	return *w.Retries
+}
+
+// BackoffDelay returns the delay before the first retry.
+func (w *WebhookConfig) BackoffDelay() time.Duration {
+	if d, err := time.ParseDuration(w.Backoff); err == nil && d > 0 {
+		return d
+	}
+	return DefaultWebhookBackoff
+}
+
+// validateWebhooks checks the retry settings of webhooks.
+func validateWebhooks(hooks []WebhookConfig) error {
+	for i, hook := range hooks {
+		if hook.Retries != nil && *hook.Retries < 0 {
+			return fmt.Errorf("webhooks[%d]: retries must not be negative", i)
+		}
+		if hook.Backoff != "" && !isPositiveDuration(hook.Backoff) {
+			return fmt.Errorf("webhooks[%d]: invalid backoff %q (use a duration like '1s' or '500ms')", i, hook.Backoff)
+		}
+	}
+	return nil
+}
+
+// ServerConfig configures who may use a shared cortex server.
+type ServerConfig struct {
+	Users []ServerUser `yaml:"users"` // API token holders; with none, requests are not authenticated
+}
+
+// ServerUser is a holder of a server API token.
+type ServerUser struct {
+	Name     string `yaml:"name"`
+	Token    string `yaml:"token"`     // Token value
+	TokenEnv string `yaml:"token_env"` // Or the environment variable holding it
+	Role     string `yaml:"role"`      // viewer, runner, or admin (default: runner)
+}
+
+// Values for ServerUser.Role.
+const (
+	RoleViewer = "viewer" // May read runs and logs
+	RoleRunner = "runner" // May also trigger workflows without write-enabled tasks
+	RoleAdmin  = "admin"  // May trigger any workflow
+)
+
+// SupportedRoles lists all valid server user roles.
+var SupportedRoles = []string{RoleViewer, RoleRunner, RoleAdmin}
+
+// AnalyticsConfig controls the opt-in usage analytics summarized by
+// `cortex stats --self`. Records hold command and flag names, tools, workflow
+// features, and run outcomes; never project names, paths, prompts, or flag
+// values.
+type AnalyticsConfig struct {
+	Enabled bool            `yaml:"enabled"` // Append a record per command to a local file (default: false)
+	Remote  AnalyticsRemote `yaml:"remote"`  // Optional endpoint that also receives each record
+}
+
+// AnalyticsRemote is an endpoint, such as an organization's collector, that
+// receives analytics records. It is independent of the local file.
+type AnalyticsRemote struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// Active reports whether records are kept locally or sent anywhere.
+func (a AnalyticsConfig) Active() bool {
+	return a.Enabled || a.Remote.URL != ""
+}
+
+// LogConfig controls the operational log: JSON records of runs, tasks, and
+// warnings, kept apart from the terminal output. --log-level, --log-file,
+// and --log-format override it.
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, or error (default: info)
+	File   string `yaml:"file"`   // Log file, "-" for stderr (default: logs/cortex-<date>.log in the state directory)
+	Format string `yaml:"format"` // json or text (default: json)
+}
+
+// SupportedLogLevels lists all valid log levels.
+var SupportedLogLevels = []string{"debug", "info", "warn", "error"}
+
+// SupportedLogFormats lists all valid log formats.
+var SupportedLogFormats = []string{"json", "text"}
+
+// validateLog checks the log level and format.
+func validateLog(l LogConfig) error {
+	if l.Level != "" && !slices.Contains(SupportedLogLevels, l.Level) {
+		return fmt.Errorf("log: invalid level %q (use %s)", l.Level, strings.Join(SupportedLogLevels, ", "))
+	}
+	if l.Format != "" && !slices.Contains(SupportedLogFormats, l.Format) {
+		return fmt.Errorf("log: invalid format %q (use %s)", l.Format, strings.Join(SupportedLogFormats, ", "))
+	}
+	return nil
+}
+
+// validateAnalytics checks the remote analytics endpoint.
+func validateAnalytics(a AnalyticsConfig) error {
+	if a.Remote.URL == "" {
+		return nil
+	}
+	u, err := url.Parse(a.Remote.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("analytics.remote: invalid url %q (use an http or https URL)", a.Remote.URL)
+	}
+	return nil
 }
 
 // DefaultSettings returns the default settings.
@@ -46,16 +346,16 @@ func DefaultSettings() SettingsConfig {
 	}
 }
 
-// LoadGlobalConfig loads the global configuration from ~/.cortex/config.yml.
+// LoadGlobalConfig loads the global configuration from config.yml in the
+// config directory (~/.cortex, or $XDG_CONFIG_HOME/cortex).
 // Returns an empty config (with defaults) if the file doesn't exist.
 func LoadGlobalConfig() (*GlobalConfig, error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := paths.ConfigDir()
 	if err != nil {
 		return defaultGlobalConfig(), nil
 	}
 
-	configPath := filepath.Join(homeDir, ".cortex", "config.yml")
-	return LoadGlobalConfigFromPath(configPath)
+	return LoadGlobalConfigFromPath(filepath.Join(configDir, "config.yml"))
 }
 
 // LoadGlobalConfigFromPath loads global config from a specific path.
@@ -72,6 +372,39 @@ func LoadGlobalConfigFromPath(path string) (*GlobalConfig, error) {
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, err
 	}
+	unknown, err := findUnknownFields(data, SchemaGlobal)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		return nil, unknown[0].err()
+	}
+
+	if err := validateWebhooks(config.Webhooks); err != nil {
+		return nil, err
+	}
+	if err := validateAnalytics(config.Analytics); err != nil {
+		return nil, err
+	}
+	if err := validateLog(config.Log); err != nil {
+		return nil, err
+	}
+	if errs := validateRetention("", config.Settings.Retention); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if errs := validateKillTimeout("", config.Settings.KillTimeout); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if errs := validateColor("", config.Settings.Color); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if errs := validateMaxOutput("", config.Settings.MaxOutputBytes); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if err := validateTools(config.Tools); err != nil {
+		return nil, err
+	}
+	RegisterTools(config.Tools)
 
 	// Apply defaults for unset values
 	applyDefaults(&config)
@@ -136,6 +469,35 @@ func MergeConfigs(global *GlobalConfig, local *AgentflowConfig, cliSettings *Set
 		merged.Settings.Parallel = local.Settings.Parallel
 		merged.Settings.Verbose = local.Settings.Verbose || merged.Settings.Verbose
 		merged.Settings.Stream = local.Settings.Stream || merged.Settings.Stream
+		merged.Settings.Incremental = local.Settings.Incremental || merged.Settings.Incremental
+		if local.Settings.MaxTokens > 0 {
+			merged.Settings.MaxTokens = local.Settings.MaxTokens
+		}
+		if local.Settings.MaxCostUSD > 0 {
+			merged.Settings.MaxCostUSD = local.Settings.MaxCostUSD
+		}
+		if local.Settings.Output != "" {
+			merged.Settings.Output = local.Settings.Output
+		}
+		merged.Settings.Theme = mergeTheme(merged.Settings.Theme, local.Settings.Theme)
+		if local.Settings.Color != "" {
+			merged.Settings.Color = local.Settings.Color
+		}
+		if local.Settings.FailFast != nil {
+			merged.Settings.FailFast = local.Settings.FailFast
+		}
+		merged.Settings.Telemetry = mergeTelemetry(merged.Settings.Telemetry, local.Settings.Telemetry)
+		merged.Settings.Retention = mergeRetention(merged.Settings.Retention, local.Settings.Retention)
+		merged.Settings.PromptHooks = append(slices.Clone(merged.Settings.PromptHooks), local.Settings.PromptHooks...)
+		if local.Settings.KillTimeout != "" {
+			merged.Settings.KillTimeout = local.Settings.KillTimeout
+		}
+		if local.Settings.DefaultSystemPrompt != nil {
+			merged.Settings.DefaultSystemPrompt = local.Settings.DefaultSystemPrompt
+		}
+		if local.Settings.MaxOutputBytes != "" {
+			merged.Settings.MaxOutputBytes = local.Settings.MaxOutputBytes
+		}
 	}
 
 	// Override with CLI flags (highest priority)
@@ -146,6 +508,10 @@ func MergeConfigs(global *GlobalConfig, local *AgentflowConfig, cliSettings *Set
 		// CLI flags always win
 		merged.Settings.Verbose = cliSettings.Verbose || merged.Settings.Verbose
 		merged.Settings.Stream = cliSettings.Stream || merged.Settings.Stream
+		merged.Settings.Incremental = cliSettings.Incremental || merged.Settings.Incremental
+		if cliSettings.DefaultSystemPrompt != nil {
+			merged.Settings.DefaultSystemPrompt = cliSettings.DefaultSystemPrompt
+		}
 	}
 
 	// Apply default model/tool to agents that don't specify them
@@ -163,6 +529,45 @@ func MergeConfigs(global *GlobalConfig, local *AgentflowConfig, cliSettings *Set
 	return merged
 }
 
+// mergeTheme overlays the palette and glyphs set in local onto base.
+func mergeTheme(base, local ThemeConfig) ThemeConfig {
+	if local.Palette != "" {
+		base.Palette = local.Palette
+	}
+	if len(local.Glyphs) > 0 {
+		glyphs := make(map[string]string, len(base.Glyphs)+len(local.Glyphs))
+		for status, glyph := range base.Glyphs {
+			glyphs[status] = glyph
+		}
+		for status, glyph := range local.Glyphs {
+			glyphs[status] = glyph
+		}
+		base.Glyphs = glyphs
+	}
+	return base
+}
+
+// mergeTelemetry overlays the telemetry settings set in local onto base.
+func mergeTelemetry(base, local TelemetryConfig) TelemetryConfig {
+	if local.Endpoint != "" {
+		base.Endpoint = local.Endpoint
+	}
+	if local.ServiceName != "" {
+		base.ServiceName = local.ServiceName
+	}
+	if len(local.Headers) > 0 {
+		headers := make(map[string]string, len(base.Headers)+len(local.Headers))
+		for key, value := range base.Headers {
+			headers[key] = value
+		}
+		for key, value := range local.Headers {
+			headers[key] = value
+		}
+		base.Headers = headers
+	}
+	return base
+}
+
 // MatchesEvent checks if a webhook should be triggered for an event.
 func (w *WebhookConfig) MatchesEvent(eventType string) bool {
 	if len(w.Events) == 0 {
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMergeConfigs_Budget tests that Cortexfile budgets override global ones.
+func TestMergeConfigs_Budget(t *testing.T) {
+	global := &GlobalConfig{Settings: SettingsConfig{MaxTokens: 1000, MaxCostUSD: 2}}
+	local := &AgentflowConfig{Settings: &SettingsConfig{MaxCostUSD: 0.5}}
+
+	merged := MergeConfigs(global, local, nil)
+	if merged.Settings.MaxTokens != 1000 {
+		t.Errorf("MaxTokens = %d, want 1000", merged.Settings.MaxTokens)
+	}
+	if merged.Settings.MaxCostUSD != 0.5 {
+		t.Errorf("MaxCostUSD = %v, want 0.5", merged.Settings.MaxCostUSD)
+	}
+}
+
+// TestMergeConfigs_Output tests that a Cortexfile output mode overrides the global one.
+func TestMergeConfigs_Output(t *testing.T) {
+	global := &GlobalConfig{Settings: SettingsConfig{Output: OutputGrouped}}
+
+	merged := MergeConfigs(global, &AgentflowConfig{}, nil)
+	if merged.Settings.Output != OutputGrouped {
+		t.Errorf("Output = %q, want %q", merged.Settings.Output, OutputGrouped)
+	}
+
+	local := &AgentflowConfig{Settings: &SettingsConfig{Output: OutputPrefixed}}
+	merged = MergeConfigs(global, local, nil)
+	if merged.Settings.Output != OutputPrefixed {
+		t.Errorf("Output = %q, want %q", merged.Settings.Output, OutputPrefixed)
+	}
+}
+
+// TestMergeConfigs_Color tests that a Cortexfile color mode overrides the global one.
+func TestMergeConfigs_Color(t *testing.T) {
+	global := &GlobalConfig{Settings: SettingsConfig{Color: ColorNever}}
+
+	merged := MergeConfigs(global, &AgentflowConfig{Settings: &SettingsConfig{}}, nil)
+	if merged.Settings.Color != ColorNever {
+		t.Errorf("Color = %q, want %q", merged.Settings.Color, ColorNever)
+	}
+
+	local := &AgentflowConfig{Settings: &SettingsConfig{Color: ColorAlways}}
+	merged = MergeConfigs(global, local, nil)
+	if merged.Settings.Color != ColorAlways {
+		t.Errorf("Color = %q, want %q", merged.Settings.Color, ColorAlways)
+	}
+}
+
+// TestMergeConfigs_DefaultSystemPrompt tests that the Cortexfile and then
+// the CLI override the global default_system_prompt setting.
+func TestMergeConfigs_DefaultSystemPrompt(t *testing.T) {
+	off, on := false, true
+	global := &GlobalConfig{}
+
+	merged := MergeConfigs(global, &AgentflowConfig{}, nil)
+	if !merged.Settings.DefaultSystemPromptEnabled() {
+		t.Error("expected the default system prompt to be enabled by default")
+	}
+
+	global.Settings.DefaultSystemPrompt = &off
+	merged = MergeConfigs(global, &AgentflowConfig{Settings: &SettingsConfig{DefaultSystemPrompt: &on}}, nil)
+	if !merged.Settings.DefaultSystemPromptEnabled() {
+		t.Error("expected the Cortexfile to enable the default system prompt")
+	}
+
+	merged = MergeConfigs(global, &AgentflowConfig{Settings: &SettingsConfig{DefaultSystemPrompt: &on}}, &SettingsConfig{DefaultSystemPrompt: &off})
+	if merged.Settings.DefaultSystemPromptEnabled() {
+		t.Error("expected the CLI to disable the default system prompt")
+	}
+}
+
+// TestMergeConfigs_Theme tests that Cortexfile theme settings overlay global ones.
+func TestMergeConfigs_Theme(t *testing.T) {
+	global := &GlobalConfig{Settings: SettingsConfig{Theme: ThemeConfig{
+		Palette: PaletteAccessible,
+		Glyphs:  map[string]string{"success": "+", "failed": "x"},
+	}}}
+	local := &AgentflowConfig{Settings: &SettingsConfig{Theme: ThemeConfig{
+		Glyphs: map[string]string{"failed": "!"},
+	}}}
+
+	merged := MergeConfigs(global, local, nil)
+	theme := merged.Settings.Theme
+	if theme.Palette != PaletteAccessible {
+		t.Errorf("Palette = %q, want %q", theme.Palette, PaletteAccessible)
+	}
+	if theme.Glyphs["success"] != "+" || theme.Glyphs["failed"] != "!" {
+		t.Errorf("Glyphs = %v, want success \"+\" and failed \"!\"", theme.Glyphs)
+	}
+	if global.Settings.Theme.Glyphs["failed"] != "x" {
+		t.Errorf("global glyphs were modified: %v", global.Settings.Theme.Glyphs)
+	}
+}
+
+// TestMergeConfigs_FailFast tests that fail_fast defaults to true and a
+// Cortexfile setting overrides the global one.
+func TestMergeConfigs_FailFast(t *testing.T) {
+	merged := MergeConfigs(&GlobalConfig{}, &AgentflowConfig{}, nil)
+	if !merged.Settings.FailFastEnabled() {
+		t.Error("FailFastEnabled() = false, want true by default")
+	}
+
+	off, on := false, true
+	global := &GlobalConfig{Settings: SettingsConfig{FailFast: &off}}
+	merged = MergeConfigs(global, &AgentflowConfig{Settings: &SettingsConfig{}}, nil)
+	if merged.Settings.FailFastEnabled() {
+		t.Error("FailFastEnabled() = true, want global false kept")
+	}
+
+	merged = MergeConfigs(global, &AgentflowConfig{Settings: &SettingsConfig{FailFast: &on}}, nil)
+	if !merged.Settings.FailFastEnabled() {
+		t.Error("FailFastEnabled() = false, want local true to override")
+	}
+}
+
+// TestMergeConfigs_Telemetry tests that Cortexfile telemetry settings
+// overlay global ones.
+func TestMergeConfigs_Telemetry(t *testing.T) {
+	global := &GlobalConfig{Settings: SettingsConfig{Telemetry: TelemetryConfig{
+		Endpoint: "http://collector:4318",
+		Headers:  map[string]string{"Authorization": "Bearer a", "X-Team": "infra"},
+	}}}
+	local := &AgentflowConfig{Settings: &SettingsConfig{Telemetry: TelemetryConfig{
+		ServiceName: "nightly",
+		Headers:     map[string]string{"Authorization": "Bearer b"},
+	}}}
+
+	telemetry := MergeConfigs(global, local, nil).Settings.Telemetry
+	if telemetry.Endpoint != "http://collector:4318" || telemetry.ServiceName != "nightly" {
+		t.Errorf("Telemetry = %+v, want global endpoint and local service name", telemetry)
+	}
+	if telemetry.Headers["Authorization"] != "Bearer b" || telemetry.Headers["X-Team"] != "infra" {
+		t.Errorf("Headers = %v, want local Authorization and global X-Team", telemetry.Headers)
+	}
+	if global.Settings.Telemetry.Headers["Authorization"] != "Bearer a" {
+		t.Errorf("global headers were modified: %v", global.Settings.Telemetry.Headers)
+	}
+}
+
+// TestMergeConfigs_PromptHooks tests that Cortexfile prompt hooks run after
+// global ones.
+func TestMergeConfigs_PromptHooks(t *testing.T) {
+	global := &GlobalConfig{Settings: SettingsConfig{PromptHooks: StringList{"./compliance.sh"}}}
+	local := &AgentflowConfig{Settings: &SettingsConfig{PromptHooks: StringList{"./redact.sh"}}}
+
+	hooks := MergeConfigs(global, local, nil).Settings.PromptHooks
+	if len(hooks) != 2 || hooks[0] != "./compliance.sh" || hooks[1] != "./redact.sh" {
+		t.Errorf("PromptHooks = %v, want [./compliance.sh ./redact.sh]", hooks)
+	}
+	if len(global.Settings.PromptHooks) != 1 {
+		t.Errorf("global hooks were modified: %v", global.Settings.PromptHooks)
+	}
+}
+
+// TestWebhookConfig_Retries tests webhook retry defaults and validation.
+func TestWebhookConfig_Retries(t *testing.T) {
+	hook := WebhookConfig{}
+	if hook.RetryCount() != DefaultWebhookRetries || hook.BackoffDelay() != DefaultWebhookBackoff {
+		t.Errorf("defaults = %d, %v", hook.RetryCount(), hook.BackoffDelay())
+	}
+
+	zero := 0
+	hook = WebhookConfig{Retries: &zero, Backoff: "250ms"}
+	if hook.RetryCount() != 0 || hook.BackoffDelay() != 250*time.Millisecond {
+		t.Errorf("configured = %d, %v", hook.RetryCount(), hook.BackoffDelay())
+	}
+
+	negative := -1
+	if err := validateWebhooks([]WebhookConfig{{Retries: &negative}}); err == nil {
+		t.Error("expected an error for negative retries")
+	}
+	if err := validateWebhooks([]WebhookConfig{{Backoff: "soon"}}); err == nil {
+		t.Error("expected an error for an invalid backoff")
+	}
+}
+
+// TestAnalyticsConfig tests that analytics are off by default and the remote
+// endpoint is validated.
+func TestAnalyticsConfig(t *testing.T) {
+	if (AnalyticsConfig{}).Active() {
+		t.Error("Active() = true, want analytics off by default")
+	}
+	remote := AnalyticsConfig{Remote: AnalyticsRemote{URL: "https://usage.example.com/cortex"}}
+	if !remote.Active() {
+		t.Error("Active() = false, want true with a remote url")
+	}
+	if err := validateAnalytics(remote); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	for _, url := range []string{"usage.example.com", "ftp://usage.example.com"} {
+		if err := validateAnalytics(AnalyticsConfig{Remote: AnalyticsRemote{URL: url}}); err == nil {
+			t.Errorf("url %q: expected an error", url)
+		}
+	}
+}
+
+func TestValidateLog(t *testing.T) {
+	valid := []LogConfig{
+		{},
+		{Level: "debug", Format: "text", File: "-"},
+		{Level: "error", Format: "json", File: "/var/log/cortex.log"},
+	}
+	for _, l := range valid {
+		if err := validateLog(l); err != nil {
+			t.Errorf("%+v: expected no error, got: %v", l, err)
+		}
+	}
+
+	invalid := []LogConfig{{Level: "loud"}, {Format: "xml"}}
+	for _, l := range invalid {
+		if err := validateLog(l); err == nil {
+			t.Errorf("%+v: expected an error", l)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IncludeConfig pulls the agents and tasks of other YAML files into a
+// Cortexfile. Written as a plain string, it is just the path.
+type IncludeConfig struct {
+	Path      string `yaml:"path"`      // File or glob, relative to the including file
+	Namespace string `yaml:"namespace"` // Prefix of the included names, as <namespace>-<name> (optional)
+}
+
+// UnmarshalYAML accepts a path string or a mapping.
+func (i *IncludeConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&i.Path)
+	}
+	type plain IncludeConfig
+	return node.Decode((*plain)(i))
+}
+
+// includeKeys lists the top-level keys an included file may have.
+var includeKeys = []string{"agents", "tasks", "task_templates", "include"}
+
+// namespaceRegex matches valid namespaces, which become part of task names.
+var namespaceRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// taskRefRegex matches references to tasks in templates and conditions:
+// outputs.X, status.X, artifacts.X, and previous.X.
+var taskRefRegex = regexp.MustCompile(`\b(outputs|status|artifacts|previous)\.([a-zA-Z0-9_-]+)`)
+
+// loadIncludes merges the agents and tasks of the files config includes into
+// it. Definitions of config itself override included ones with the same
+// name; two included files defining the same name is an error. stack holds
+// the files being loaded, to detect include cycles.
+func loadIncludes(config *AgentflowConfig, baseDir string, stack []string) error {
+	agentSources := make(map[string]string)
+	taskSources := make(map[string]string)
+	for _, inc := range config.Include {
+		if inc.Path == "" {
+			return fmt.Errorf("include: path is required")
+		}
+		if inc.Namespace != "" && !namespaceRegex.MatchString(inc.Namespace) {
+			return fmt.Errorf("include %s: invalid namespace %q (use letters, digits, '_', and '-')", inc.Path, inc.Namespace)
+		}
+
+		paths, err := MatchFiles(inc.Path, baseDir)
+		if err != nil {
+			return fmt.Errorf("include %s: %w", inc.Path, err)
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("include %s: no such file", inc.Path)
+		}
+
+		for _, path := range paths {
+			included, err := loadInclude(path, stack)
+			if err != nil {
+				return err
+			}
+			config.Sources = append(config.Sources, included.Sources...)
+			config.files = append(config.files, included.files...)
+			for _, f := range included.unknownFields {
+				if f.file == "" {
+					f.file = path
+				}
+				config.unknownFields = append(config.unknownFields, f)
+			}
+			if inc.Namespace != "" {
+				applyNamespace(included, inc.Namespace)
+			}
+
+			for name, agent := range included.Agents {
+				if _, local := config.Agents[name]; local && agentSources[name] == "" {
+					continue
+				}
+				if src, ok := agentSources[name]; ok {
+					return fmt.Errorf("agent %q is defined by both %s and %s; include one with a namespace", name, src, path)
+				}
+				agentSources[name] = path
+				config.Agents[name] = agent
+			}
+			for name, task := range included.Tasks {
+				if _, local := config.Tasks[name]; local && taskSources[name] == "" {
+					continue
+				}
+				if src, ok := taskSources[name]; ok {
+					return fmt.Errorf("task %q is defined by both %s and %s; include one with a namespace", name, src, path)
+				}
+				taskSources[name] = path
+				config.Tasks[name] = task
+			}
+		}
+	}
+	return nil
+}
+
+// loadInclude parses an included file, with paths in it resolved relative
+// to its directory and its own includes merged.
+func loadInclude(path string, stack []string) (*AgentflowConfig, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(stack, abs) {
+		return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack, abs), " -> "))
+	}
+
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, fmt.Errorf("include: failed to read %s: %w", path, err)
+	}
+	var keys map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("include %s: failed to parse YAML: %w", path, err)
+	}
+	for key := range keys {
+		if !slices.Contains(includeKeys, key) {
+			return nil, fmt.Errorf("include %s: %q can't be set in an included file (only %s)", path, key, strings.Join(includeKeys, ", "))
+		}
+	}
+
+	included, err := parseConfig(data, filepath.Dir(abs), append(stack, abs))
+	if err != nil {
+		return nil, fmt.Errorf("include %s: %w", path, err)
+	}
+	return included, nil
+}
+
+// applyNamespace prefixes the agents and tasks of an included file with
+// namespace, along with the references between them.
+func applyNamespace(config *AgentflowConfig, namespace string) {
+	prefix := namespace + "-"
+	rename := func(name string, defined bool) string {
+		if defined {
+			return prefix + name
+		}
+		return name
+	}
+
+	agents := make(map[string]AgentConfig, len(config.Agents))
+	for name, agent := range config.Agents {
+		agents[prefix+name] = agent
+	}
+
+	expandConfigText(config, func(text string) string {
+		return taskRefRegex.ReplaceAllStringFunc(text, func(ref string) string {
+			groups := taskRefRegex.FindStringSubmatch(ref)
+			if _, ok := config.Tasks[groups[2]]; !ok {
+				return ref
+			}
+			return groups[1] + "." + prefix + groups[2]
+		})
+	})
+
+	tasks := make(map[string]TaskConfig, len(config.Tasks))
+	for name, task := range config.Tasks {
+		_, ok := config.Agents[task.Agent]
+		task.Agent = rename(task.Agent, ok)
+		if task.ReviewWith != "" {
+			_, ok := config.Agents[task.ReviewWith]
+			task.ReviewWith = rename(task.ReviewWith, ok)
+		}
+		if len(task.Needs) > 0 {
+			needs := make(StringList, len(task.Needs))
+			for i, dep := range task.Needs {
+				_, ok := config.Tasks[dep]
+				needs[i] = rename(dep, ok)
+			}
+			task.Needs = needs
+		}
+		tasks[prefix+name] = task
+	}
+
+	config.Agents = agents
+	config.Tasks = tasks
+}
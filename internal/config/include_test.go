@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files, by path relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// TestLoadConfig_Include tests merging included agents and tasks.
+func TestLoadConfig_Include(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"shared/review.yml": `
+agents:
+  reviewer:
+    tool: claude-code
+    model: sonnet
+tasks:
+  analyze:
+    agent: reviewer
+    prompt_file: prompts/analyze.md
+  report:
+    agent: reviewer
+    needs: [analyze]
+    prompt: "Report on {{outputs.analyze | head 10}}"
+    when: "{{status.analyze}} == success"
+`,
+		"shared/prompts/analyze.md": "Analyze the code",
+		"shared/shell.yml": `
+agents:
+  sh:
+    tool: shell
+`,
+		"Cortexfile.yml": `
+include:
+  - shared/shell.yml
+  - path: shared/review.yml
+    namespace: lint
+agents:
+  sh:
+    tool: shell
+    env:
+      LOCAL: "1"
+tasks:
+  build:
+    agent: sh
+    command: make
+  summary:
+    agent: lint-reviewer
+    needs: [lint-report]
+    prompt: "{{outputs.lint-report}}"
+`,
+	})
+
+	cfg, err := LoadConfig(filepath.Join(dir, "Cortexfile.yml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if _, ok := cfg.Agents["lint-reviewer"]; !ok {
+		t.Errorf("expected namespaced agent lint-reviewer, got %v", cfg.Agents)
+	}
+	if cfg.Agents["sh"].Env["LOCAL"] != "1" {
+		t.Error("expected the local agent sh to override the included one")
+	}
+
+	analyze, ok := cfg.Tasks["lint-analyze"]
+	if !ok {
+		t.Fatalf("expected namespaced task lint-analyze, got %v", cfg.Tasks)
+	}
+	if analyze.Agent != "lint-reviewer" || analyze.Prompt != "Analyze the code" {
+		t.Errorf("lint-analyze = agent %q, prompt %q", analyze.Agent, analyze.Prompt)
+	}
+
+	report := cfg.Tasks["lint-report"]
+	if len(report.Needs) != 1 || report.Needs[0] != "lint-analyze" {
+		t.Errorf("lint-report needs = %v, want [lint-analyze]", report.Needs)
+	}
+	if report.Prompt != "Report on {{outputs.lint-analyze | head 10}}" {
+		t.Errorf("lint-report prompt = %q", report.Prompt)
+	}
+	if report.When != "{{status.lint-analyze}} == success" {
+		t.Errorf("lint-report when = %q", report.When)
+	}
+
+	wantSources := []string{
+		filepath.Join(dir, "Cortexfile.yml"),
+		filepath.Join(dir, "shared/shell.yml"),
+		filepath.Join(dir, "shared/review.yml"),
+	}
+	if strings.Join(cfg.Sources, ",") != strings.Join(wantSources, ",") {
+		t.Errorf("sources = %v, want %v", cfg.Sources, wantSources)
+	}
+
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected merged config to be valid, got: %v", err)
+	}
+}
+
+// TestLoadConfig_IncludeErrors tests rejecting bad includes.
+func TestLoadConfig_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name: "missing file",
+			files: map[string]string{
+				"Cortexfile.yml": "include: [nope.yml]\n",
+			},
+			wantErr: "no such file",
+		},
+		{
+			name: "conflicting includes",
+			files: map[string]string{
+				"a.yml":          "agents:\n  sh:\n    tool: shell\n",
+				"b.yml":          "agents:\n  sh:\n    tool: shell\n",
+				"Cortexfile.yml": "include: [a.yml, b.yml]\n",
+			},
+			wantErr: "defined by both",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"a.yml":          "include: [b.yml]\n",
+				"b.yml":          "include: [a.yml]\n",
+				"Cortexfile.yml": "include: [a.yml]\n",
+			},
+			wantErr: "include cycle",
+		},
+		{
+			name: "unsupported key",
+			files: map[string]string{
+				"a.yml":          "settings:\n  verbose: true\n",
+				"Cortexfile.yml": "include: [a.yml]\n",
+			},
+			wantErr: "can't be set in an included file",
+		},
+		{
+			name: "invalid namespace",
+			files: map[string]string{
+				"a.yml":          "agents: {}\n",
+				"Cortexfile.yml": "include:\n  - path: a.yml\n    namespace: a.b\n",
+			},
+			wantErr: "invalid namespace",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			_, err := LoadConfig(filepath.Join(dir, "Cortexfile.yml"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
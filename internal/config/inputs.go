@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Input types supported in the inputs block.
+const (
+	InputTypeString = "string"
+	InputTypeNumber = "number"
+	InputTypeBool   = "bool"
+)
+
+// SupportedInputTypes lists the valid values for an input's type.
+var SupportedInputTypes = []string{InputTypeString, InputTypeNumber, InputTypeBool}
+
+// InputConfig declares a workflow variable expected from --var, --var-file,
+// or vars. Inputs are referenced like any variable: {{vars.name}}.
+type InputConfig struct {
+	Type        string     `yaml:"type"`        // string (default), number, or bool
+	Required    bool       `yaml:"required"`    // Fail if no non-empty value is provided
+	Default     string     `yaml:"default"`     // Value used when none is provided
+	Allowed     StringList `yaml:"allowed"`     // Permitted values (optional)
+	Description string     `yaml:"description"` // Shown in error messages
+}
+
+// CheckValue reports why value is not acceptable for the input, or nil.
+func (in InputConfig) CheckValue(value string) error {
+	switch in.Type {
+	case InputTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+	case InputTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%q is not a bool (use true or false)", value)
+		}
+	}
+
+	if len(in.Allowed) > 0 {
+		for _, allowed := range in.Allowed {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of: %s", value, strings.Join(in.Allowed, ", "))
+	}
+	return nil
+}
+
+// CheckInputs validates the resolved variables against the inputs block.
+// Call after ApplyVars. Returns a ConfigErrors listing every missing or
+// malformed input.
+func CheckInputs(config *AgentflowConfig, filePath string) error {
+	errs := &ConfigErrors{}
+
+	for _, name := range sortedInputNames(config.Inputs) {
+		input := config.Inputs[name]
+		value := config.Vars[name]
+
+		if value == "" {
+			if input.Required {
+				hint := "Pass --var " + name + "=<value> or set it in a --var-file"
+				if input.Description != "" {
+					hint = input.Description + ". " + hint
+				}
+				errs.Add(NewConfigErrorWithHint(filePath, 0, "input \""+name+"\" is required", hint))
+			}
+			continue
+		}
+
+		if err := input.CheckValue(value); err != nil {
+			errs.Add(NewConfigErrorWithHint(filePath, 0,
+				"input \""+name+"\": "+err.Error(),
+				inputHint(name, input)))
+		}
+	}
+
+	if errs.HasErrors() {
+		return errs
+	}
+	return nil
+}
+
+// validateInputDecls checks input declarations: known types and defaults
+// that satisfy their own type and allowed values.
+func validateInputDecls(filePath string, inputs map[string]InputConfig) []*ConfigError {
+	var errs []*ConfigError
+	for _, name := range sortedInputNames(inputs) {
+		input := inputs[name]
+		if input.Type != "" && !isSupportedInputType(input.Type) {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"input \""+name+"\": unsupported type \""+input.Type+"\"",
+				"Supported input types: "+strings.Join(SupportedInputTypes, ", ")))
+			continue
+		}
+		if input.Default != "" {
+			if err := input.CheckValue(input.Default); err != nil {
+				errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+					"input \""+name+"\": invalid default: "+err.Error(),
+					inputHint(name, input)))
+			}
+		}
+	}
+	return errs
+}
+
+// inputHint describes the values an input accepts.
+func inputHint(name string, input InputConfig) string {
+	if len(input.Allowed) > 0 {
+		return "Use --var " + name + "=<" + strings.Join(input.Allowed, "|") + ">"
+	}
+	typ := input.Type
+	if typ == "" {
+		typ = InputTypeString
+	}
+	return "Use --var " + name + "=<" + typ + ">"
+}
+
+func isSupportedInputType(t string) bool {
+	for _, supported := range SupportedInputTypes {
+		if t == supported {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedInputNames(inputs map[string]InputConfig) []string {
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
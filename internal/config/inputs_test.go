@@ -0,0 +1,111 @@
+package config
+
+import "testing"
+
+// TestCheckInputs tests validating resolved variables against input declarations.
+func TestCheckInputs(t *testing.T) {
+	inputs := map[string]InputConfig{
+		"env":      {Required: true, Allowed: StringList{"dev", "staging", "prod"}},
+		"replicas": {Type: InputTypeNumber, Default: "1"},
+		"dry_run":  {Type: InputTypeBool, Default: "false"},
+		"notes":    {},
+	}
+
+	tests := []struct {
+		name            string
+		overrides       map[string]string
+		wantErrContains []string
+	}{
+		{
+			name:      "valid with defaults",
+			overrides: map[string]string{"env": "prod"},
+		},
+		{
+			name:            "missing required input",
+			overrides:       nil,
+			wantErrContains: []string{`input "env" is required`},
+		},
+		{
+			name:            "value not allowed",
+			overrides:       map[string]string{"env": "qa"},
+			wantErrContains: []string{`input "env": "qa" is not one of: dev, staging, prod`},
+		},
+		{
+			name:            "malformed number and bool",
+			overrides:       map[string]string{"env": "dev", "replicas": "three", "dry_run": "maybe"},
+			wantErrContains: []string{`"three" is not a number`, `"maybe" is not a bool`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AgentflowConfig{Inputs: inputs}
+			ApplyVars(cfg, tt.overrides)
+
+			err := CheckInputs(cfg, "Cortexfile.yml")
+			if len(tt.wantErrContains) == 0 {
+				if err != nil {
+					t.Fatalf("CheckInputs() unexpected error: %v", err)
+				}
+				if cfg.Vars["replicas"] != "1" || cfg.Vars["notes"] != "" {
+					t.Errorf("defaults not applied: %v", cfg.Vars)
+				}
+				return
+			}
+
+			valErr, ok := err.(*ConfigErrors)
+			if !ok {
+				t.Fatalf("expected *ConfigErrors, got %T (%v)", err, err)
+			}
+			for _, want := range tt.wantErrContains {
+				if !errorsContain(valErr, want) {
+					t.Errorf("expected error containing %q, got: %v", want, valErr)
+				}
+			}
+		})
+	}
+}
+
+// TestValidate_InputDecls tests validation of input declarations.
+func TestValidate_InputDecls(t *testing.T) {
+	tests := []struct {
+		name            string
+		inputs          map[string]InputConfig
+		wantErrContains string
+	}{
+		{
+			name:            "unsupported type",
+			inputs:          map[string]InputConfig{"count": {Type: "int"}},
+			wantErrContains: `input "count": unsupported type "int"`,
+		},
+		{
+			name:            "default not allowed",
+			inputs:          map[string]InputConfig{"env": {Default: "qa", Allowed: StringList{"dev", "prod"}}},
+			wantErrContains: `input "env": invalid default`,
+		},
+		{
+			name:            "default wrong type",
+			inputs:          map[string]InputConfig{"replicas": {Type: InputTypeNumber, Default: "many"}},
+			wantErrContains: `input "replicas": invalid default`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AgentflowConfig{
+				Inputs: tt.inputs,
+				Agents: map[string]AgentConfig{"agent1": {Tool: "claude-code"}},
+				Tasks:  map[string]TaskConfig{"task1": {Agent: "agent1", Prompt: "test"}},
+			}
+
+			err := Validate(cfg)
+			valErr, ok := err.(*ConfigErrors)
+			if !ok {
+				t.Fatalf("expected *ConfigErrors, got %T", err)
+			}
+			if !errorsContain(valErr, tt.wantErrContains) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErrContains, valErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Item is a single row of fan-out data. Object rows map field names to values;
+// scalar rows store their value under the empty key.
+type Item map[string]string
+
+// FanOutInstanceName returns the name of the nth (1-based) instance of a
+// fan-out task.
+func FanOutInstanceName(task string, n int) string {
+	return fmt.Sprintf("%s-%d", task, n)
+}
+
+// itemVarRegex matches {{item}}, {{item.field}}, {{matrix}}, and
+// {{matrix.field}} patterns.
+var itemVarRegex = regexp.MustCompile(`\{\{(?:item|matrix)(?:\.([a-zA-Z0-9_-]+))?\}\}`)
+
+// MatrixItems builds fan-out items from matrix entries. Entries containing
+// glob characters expand to the matching files (relative to baseDir when the
+// pattern is relative); other entries are used as-is. Each item exposes its
+// value as {{matrix}} and {{matrix.value}}.
+func MatrixItems(entries []string, baseDir string) ([]Item, error) {
+	var items []Item
+	for _, entry := range entries {
+		if !strings.ContainsAny(entry, "*?[") {
+			items = append(items, Item{"": entry, "value": entry})
+			continue
+		}
+
+		pattern := entry
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(baseDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", entry, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("glob %q matched no files", entry)
+		}
+
+		for _, match := range matches {
+			if !filepath.IsAbs(entry) {
+				if rel, err := filepath.Rel(baseDir, match); err == nil {
+					match = rel
+				}
+			}
+			items = append(items, Item{"": match, "value": match})
+		}
+	}
+	return items, nil
+}
+
+// LoadItems reads fan-out data from a JSON or CSV file.
+// JSON files must contain an array of objects or scalars.
+// CSV files must have a header row naming the fields.
+func LoadItems(path string) ([]Item, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return parseJSONItems(data)
+	case ".csv":
+		return parseCSVItems(data)
+	default:
+		return nil, fmt.Errorf("unsupported data file type %q (use .json or .csv)", filepath.Ext(path))
+	}
+}
+
+// parseJSONItems parses a JSON array into items.
+func parseJSONItems(data []byte) ([]Item, error) {
+	var rows []json.RawMessage
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("expected a JSON array: %w", err)
+	}
+
+	items := make([]Item, 0, len(rows))
+	for _, row := range rows {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(row, &fields); err == nil {
+			item := make(Item, len(fields))
+			for key, value := range fields {
+				item[key] = jsonValueString(value)
+			}
+			items = append(items, item)
+			continue
+		}
+		items = append(items, Item{"": jsonValueString(row)})
+	}
+	return items, nil
+}
+
+// jsonValueString returns strings unquoted and other JSON values as raw JSON.
+func jsonValueString(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	return string(value)
+}
+
+// parseCSVItems parses CSV data with a header row into items.
+func parseCSVItems(data []byte) ([]Item, error) {
+	records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return []Item{}, nil
+	}
+
+	header := records[0]
+	items := make([]Item, 0, len(records)-1)
+	for _, record := range records[1:] {
+		item := make(Item, len(header))
+		for i, field := range header {
+			if i < len(record) {
+				item[field] = record[i]
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// ExpandItemVars replaces {{item}} and {{item.field}} placeholders (or their
+// {{matrix}} aliases) with item values. Unknown fields are left as-is.
+func ExpandItemVars(text string, item Item) string {
+	return itemVarRegex.ReplaceAllStringFunc(text, func(match string) string {
+		field := itemVarRegex.FindStringSubmatch(match)[1]
+		if value, ok := item[field]; ok {
+			return value
+		}
+		return match
+	})
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestLoadItems tests loading fan-out data from JSON and CSV files.
+func TestLoadItems(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "agentflow-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"objects.json": `[{"name": "api", "port": 8080}, {"name": "web", "port": 3000}]`,
+		"scalars.json": `["auth", "billing"]`,
+		"services.csv": "name,owner\napi,team-a\nweb,team-b\n",
+		"bad.json":     `{"name": "api"}`,
+		"data.txt":     "api\nweb\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		want    []Item
+		wantErr bool
+	}{
+		{
+			name: "json objects",
+			file: "objects.json",
+			want: []Item{{"name": "api", "port": "8080"}, {"name": "web", "port": "3000"}},
+		},
+		{
+			name: "json scalars",
+			file: "scalars.json",
+			want: []Item{{"": "auth"}, {"": "billing"}},
+		},
+		{
+			name: "csv with header",
+			file: "services.csv",
+			want: []Item{{"name": "api", "owner": "team-a"}, {"name": "web", "owner": "team-b"}},
+		},
+		{
+			name:    "json object instead of array",
+			file:    "bad.json",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported extension",
+			file:    "data.txt",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := LoadItems(filepath.Join(tmpDir, tt.file))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(items) != len(tt.want) {
+				t.Fatalf("expected %d items, got %d", len(tt.want), len(items))
+			}
+			for i, want := range tt.want {
+				for key, value := range want {
+					if items[i][key] != value {
+						t.Errorf("item %d field %q = %q, want %q", i, key, items[i][key], value)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestExpandItemVars tests {{item}} placeholder substitution.
+func TestExpandItemVars(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		item Item
+		want string
+	}{
+		{"scalar item", "Audit {{item}}", Item{"": "auth"}, "Audit auth"},
+		{"object fields", "{{item.name}} on {{item.port}}", Item{"name": "api", "port": "8080"}, "api on 8080"},
+		{"unknown field left as-is", "{{item.missing}}", Item{"name": "api"}, "{{item.missing}}"},
+		{"outputs untouched", "{{outputs.scan}} {{item}}", Item{"": "x"}, "{{outputs.scan}} x"},
+		{"matrix alias", "Review {{matrix.value}} ({{matrix}})", Item{"": "a.go", "value": "a.go"}, "Review a.go (a.go)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpandItemVars(tt.text, tt.item); got != tt.want {
+				t.Errorf("ExpandItemVars() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMatrixItems tests building fan-out items from matrix values and globs.
+func TestMatrixItems(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "agentflow-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, "src"), 0755); err != nil {
+		t.Fatalf("failed to create src dir: %v", err)
+	}
+	for _, name := range []string{"a.go", "b.go", "notes.md"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, "src", name), []byte("x"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		entries []string
+		want    []Item
+		wantErr bool
+	}{
+		{
+			name:    "literal values",
+			entries: []string{"linux", "darwin"},
+			want:    []Item{{"": "linux", "value": "linux"}, {"": "darwin", "value": "darwin"}},
+		},
+		{
+			name:    "relative glob",
+			entries: []string{"src/*.go"},
+			want: []Item{
+				{"": filepath.Join("src", "a.go"), "value": filepath.Join("src", "a.go")},
+				{"": filepath.Join("src", "b.go"), "value": filepath.Join("src", "b.go")},
+			},
+		},
+		{
+			name:    "glob without matches",
+			entries: []string{"src/*.rs"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatrixItems(tt.entries, tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MatrixItems() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatrixItems() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidate_FanOutInstanceCollision tests that a task may not have the
+// name of a fan-out instance.
+func TestValidate_FanOutInstanceCollision(t *testing.T) {
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{"ai": {Tool: "claude-code"}},
+		Tasks: map[string]TaskConfig{
+			"audit":   {Agent: "ai", Prompt: "Audit {{matrix}}", Matrix: StringList{"api", "web"}, Items: []Item{{"": "api"}, {"": "web"}}},
+			"audit-2": {Agent: "ai", Prompt: "Audit the rest"},
+		},
+	}
+	err := Validate(config)
+	if err == nil {
+		t.Fatal("expected an error for a task named like a fan-out instance")
+	}
+	if !strings.Contains(err.Error(), `fan-out instance "audit-2" has the name of another task`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	delete(config.Tasks, "audit-2")
+	if err := Validate(config); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
@@ -0,0 +1,478 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Output formats of a task.
+const (
+	OutputText = "text" // Output is used as-is (default)
+	OutputJSON = "json" // Output must be JSON, optionally matching output_schema
+)
+
+// OutputFormats lists all valid output_format values.
+var OutputFormats = []string{OutputText, OutputJSON}
+
+// schemaTypes lists the type names a JSON Schema can use.
+var schemaTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// unsupportedSchemaKeywords are JSON Schema keywords output_schema doesn't
+// implement. They are rejected so a schema never silently checks less than
+// it says.
+var unsupportedSchemaKeywords = []string{
+	"$ref", "$defs", "definitions", "allOf", "anyOf", "oneOf", "not", "if", "then", "else",
+	"patternProperties", "dependentRequired", "dependentSchemas", "const", "uniqueItems", "format",
+}
+
+// fencedJSONRegex matches a Markdown code block, which agents often wrap
+// JSON in.
+var fencedJSONRegex = regexp.MustCompile("(?s)```(?:json|JSON)?\\s*\\n(.*?)\\n\\s*```")
+
+// trailingCommaRegex matches a comma before a closing bracket.
+var trailingCommaRegex = regexp.MustCompile(`,(\s*[}\]])`)
+
+// ParseJSONOutput extracts the JSON value from the output of a task with
+// output_format: json and checks it against schema, if set. Output that
+// isn't JSON as a whole is repaired where possible: the JSON is taken from
+// a ```json block or from around the prose, and trailing commas are
+// dropped. Returns the JSON text.
+func ParseJSONOutput(output string, schema map[string]interface{}) (string, error) {
+	text, value, err := repairJSON(output)
+	if err != nil {
+		return "", err
+	}
+	if schema != nil {
+		if errs := validateSchema(value, schema, "$"); len(errs) > 0 {
+			if len(errs) > 5 {
+				errs = append(errs[:5], fmt.Sprintf("and %d more", len(errs)-5))
+			}
+			return "", fmt.Errorf("output doesn't match output_schema: %s", strings.Join(errs, "; "))
+		}
+	}
+	return text, nil
+}
+
+// JSONOutputPrompt appends to the prompt of an AI task with output_format:
+// json the instruction to answer with JSON only, matching schema if set.
+func JSONOutputPrompt(prompt string, schema map[string]interface{}) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(prompt, "\n"))
+	b.WriteString("\n\nRespond with a single JSON value and nothing else: no explanation and no code fences.")
+	if schema != nil {
+		if data, err := json.MarshalIndent(schema, "", "  "); err == nil {
+			b.WriteString(" It must match this JSON Schema:\n\n")
+			b.Write(data)
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// repairJSON returns the JSON text in output and its decoded value.
+func repairJSON(output string) (string, interface{}, error) {
+	candidates := []string{strings.TrimSpace(output)}
+	for _, match := range fencedJSONRegex.FindAllStringSubmatch(output, -1) {
+		candidates = append(candidates, strings.TrimSpace(match[1]))
+	}
+	if start := strings.IndexAny(output, "{["); start >= 0 {
+		if end := strings.LastIndexAny(output, "}]"); end > start {
+			candidates = append(candidates, output[start:end+1])
+		}
+	}
+
+	for _, fix := range []func(string) string{
+		func(s string) string { return s },
+		func(s string) string { return trailingCommaRegex.ReplaceAllString(s, "$1") },
+	} {
+		for _, candidate := range candidates {
+			text := fix(candidate)
+			if value, err := decodeJSON(text); err == nil {
+				return text, value, nil
+			}
+		}
+	}
+
+	if strings.TrimSpace(output) == "" {
+		return "", nil, fmt.Errorf("output is empty, expected JSON")
+	}
+	_, err := decodeJSON(candidates[len(candidates)-1])
+	return "", nil, fmt.Errorf("output is not valid JSON: %w", err)
+}
+
+// decodeJSON decodes a single JSON value, keeping numbers as json.Number.
+func decodeJSON(text string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// OutputField returns the field at path (e.g. ["issues", "0", "file"]) of
+// the JSON output of a task, for {{outputs.task.field}}. Strings are
+// returned as-is, other values as JSON. Missing fields are empty.
+func OutputField(output string, path []string) string {
+	value, err := decodeJSON(output)
+	if err != nil {
+		return ""
+	}
+	for _, key := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return ""
+			}
+			value = v[i]
+		default:
+			return ""
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return ""
+		}
+		return strings.TrimSuffix(buf.String(), "\n")
+	}
+}
+
+// validateOutputFormat checks a task's output_format and output_schema.
+func validateOutputFormat(filePath, name string, task TaskConfig) []*ConfigError {
+	var errs []*ConfigError
+	if task.OutputFormat != "" && !containsString(OutputFormats, task.OutputFormat) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": unsupported output_format \""+task.OutputFormat+"\"",
+			"Supported values: "+strings.Join(OutputFormats, ", ")))
+	}
+	if task.OutputSchema == nil {
+		return errs
+	}
+	if task.OutputFormat != OutputJSON {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": output_schema requires output_format: json",
+			"Add 'output_format: json' to the task"))
+	}
+	for _, problem := range CheckSchema(task.OutputSchema) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": invalid output_schema: "+problem,
+			"output_schema supports type, properties, required, additionalProperties, items, enum, pattern, and length and range limits"))
+	}
+	return errs
+}
+
+// validateOutputField checks a {{outputs.task.field}} reference: the task
+// must have JSON output, and if its schema lists properties, the field must
+// be one of them.
+func validateOutputField(filePath, taskName, ref, refTask, field string, tasks map[string]TaskConfig) *ConfigError {
+	refCfg := tasks[refTask]
+	if refCfg.OutputFormat != OutputJSON {
+		return NewConfigErrorWithHint(filePath, 0,
+			"task \""+taskName+"\": "+ref+" selects a field, but task \""+refTask+"\" doesn't have JSON output",
+			"Add 'output_format: json' to task \""+refTask+"\", or use {{outputs."+refTask+"}}")
+	}
+	top := strings.Split(field, ".")[0]
+	if refCfg.OutputSchema == nil || SchemaHasField(refCfg.OutputSchema, top) {
+		return nil
+	}
+	hint := "Fields of task \"" + refTask + "\": " + strings.Join(SchemaFields(refCfg.OutputSchema), ", ")
+	if suggestion := SuggestClosestMatch(top, SchemaFields(refCfg.OutputSchema)); suggestion != "" {
+		hint = "Did you mean \"" + suggestion + "\"?"
+	}
+	return NewConfigErrorWithHint(filePath, 0,
+		"task \""+taskName+"\": "+ref+" references field \""+top+"\", which the output_schema of \""+refTask+"\" doesn't define",
+		hint)
+}
+
+// validateSchema checks value against a JSON Schema, returning a message
+// for every mismatch, prefixed with the path of the value.
+func validateSchema(value interface{}, schema map[string]interface{}, path string) []string {
+	var errs []string
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types := schemaTypeList(schema["type"]); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool {
+		return hasSchemaType(value, t)
+	}) {
+		fail("expected %s, got %s", strings.Join(types, " or "), jsonTypeName(value))
+		return errs
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !slices.ContainsFunc(enum, func(e interface{}) bool {
+		return jsonEqual(value, e)
+	}) {
+		fail("must be one of %s", formatEnum(enum))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				fail("missing required field %q", name)
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			if sub, ok := properties[name].(map[string]interface{}); ok {
+				errs = append(errs, validateSchema(v[name], sub, path+"."+name)...)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					fail("unexpected field %q", name)
+				}
+			case map[string]interface{}:
+				errs = append(errs, validateSchema(v[name], extra, path+"."+name)...)
+			}
+		}
+	case []interface{}:
+		if n, ok := schemaNumber(schema["minItems"]); ok && float64(len(v)) < n {
+			fail("expected at least %v items, got %d", n, len(v))
+		}
+		if n, ok := schemaNumber(schema["maxItems"]); ok && float64(len(v)) > n {
+			fail("expected at most %v items, got %d", n, len(v))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				errs = append(errs, validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case string:
+		length := float64(len([]rune(v)))
+		if n, ok := schemaNumber(schema["minLength"]); ok && length < n {
+			fail("expected at least %v characters", n)
+		}
+		if n, ok := schemaNumber(schema["maxLength"]); ok && length > n {
+			fail("expected at most %v characters", n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				fail("doesn't match pattern %q", pattern)
+			}
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if min, ok := schemaNumber(schema["minimum"]); ok && n < min {
+			fail("must be at least %v, got %s", min, v)
+		}
+		if max, ok := schemaNumber(schema["maximum"]); ok && n > max {
+			fail("must be at most %v, got %s", max, v)
+		}
+	}
+	return errs
+}
+
+// CheckSchema reports the problems of an output_schema: unsupported
+// keywords, unknown types, and keywords with values of the wrong kind.
+func CheckSchema(schema map[string]interface{}) []string {
+	var problems []string
+	var check func(schema map[string]interface{}, path string)
+	check = func(schema map[string]interface{}, path string) {
+		for _, key := range slices.Sorted(maps.Keys(schema)) {
+			value := schema[key]
+			at := path + key
+			switch {
+			case slices.Contains(unsupportedSchemaKeywords, key):
+				problems = append(problems, fmt.Sprintf("%s: keyword %q is not supported", at, key))
+			case key == "type":
+				types := schemaTypeList(value)
+				if len(types) == 0 {
+					problems = append(problems, fmt.Sprintf("%s: must be a type name or a list of them", at))
+				}
+				for _, t := range types {
+					if !slices.Contains(schemaTypes, t) {
+						problems = append(problems, fmt.Sprintf("%s: unknown type %q (use %s)", at, t, strings.Join(schemaTypes, ", ")))
+					}
+				}
+			case key == "properties":
+				props, ok := value.(map[string]interface{})
+				if !ok {
+					problems = append(problems, fmt.Sprintf("%s: must be a mapping of field schemas", at))
+					continue
+				}
+				for _, name := range slices.Sorted(maps.Keys(props)) {
+					sub, ok := props[name].(map[string]interface{})
+					if !ok {
+						problems = append(problems, fmt.Sprintf("%s.%s: must be a schema", at, name))
+						continue
+					}
+					check(sub, at+"."+name+".")
+				}
+			case key == "items":
+				sub, ok := value.(map[string]interface{})
+				if !ok {
+					problems = append(problems, fmt.Sprintf("%s: must be a schema", at))
+					continue
+				}
+				check(sub, at+".")
+			case key == "additionalProperties":
+				switch sub := value.(type) {
+				case bool:
+				case map[string]interface{}:
+					check(sub, at+".")
+				default:
+					problems = append(problems, fmt.Sprintf("%s: must be a boolean or a schema", at))
+				}
+			case key == "required":
+				if list, ok := value.([]interface{}); !ok || len(schemaStrings(value)) != len(list) {
+					problems = append(problems, fmt.Sprintf("%s: must be a list of field names", at))
+				}
+			case key == "enum":
+				if _, ok := value.([]interface{}); !ok {
+					problems = append(problems, fmt.Sprintf("%s: must be a list", at))
+				}
+			case key == "pattern":
+				pattern, ok := value.(string)
+				if !ok {
+					problems = append(problems, fmt.Sprintf("%s: must be a regular expression", at))
+				} else if _, err := regexp.Compile(pattern); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: invalid regular expression: %s", at, err))
+				}
+			case slices.Contains([]string{"minimum", "maximum", "minLength", "maxLength", "minItems", "maxItems"}, key):
+				if _, ok := schemaNumber(value); !ok {
+					problems = append(problems, fmt.Sprintf("%s: must be a number", at))
+				}
+			}
+		}
+	}
+	check(schema, "")
+	return problems
+}
+
+// SchemaHasField reports whether field may appear at the top level of the
+// output a schema describes: schemas that list properties only allow those.
+func SchemaHasField(schema map[string]interface{}, field string) bool {
+	props, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		return true
+	}
+	_, ok = props[field]
+	return ok
+}
+
+// SchemaFields returns the top-level fields a schema lists.
+func SchemaFields(schema map[string]interface{}) []string {
+	props, _ := schema["properties"].(map[string]interface{})
+	return slices.Sorted(maps.Keys(props))
+}
+
+// schemaTypeList returns the type names of a "type" keyword.
+func schemaTypeList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		return schemaStrings(v)
+	}
+	return nil
+}
+
+// schemaStrings returns the strings in a list value.
+func schemaStrings(value interface{}) []string {
+	list, _ := value.([]interface{})
+	var strs []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// schemaNumber converts a numeric schema value, decoded from YAML, to a float.
+func schemaNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// hasSchemaType reports whether a decoded JSON value is of a schema type.
+func hasSchemaType(value interface{}, typ string) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return typ == "object"
+	case []interface{}:
+		return typ == "array"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case nil:
+		return typ == "null"
+	case json.Number:
+		if typ == "number" {
+			return true
+		}
+		if typ == "integer" {
+			n, err := v.Float64()
+			return err == nil && n == float64(int64(n))
+		}
+	}
+	return false
+}
+
+// jsonTypeName returns the schema type name of a decoded JSON value.
+func jsonTypeName(value interface{}) string {
+	for _, t := range []string{"object", "array", "string", "boolean", "null", "integer", "number"} {
+		if hasSchemaType(value, t) {
+			return t
+		}
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// jsonEqual reports whether a decoded JSON value equals an enum value decoded
+// from YAML. Numbers compare by value, other scalars by their text.
+func jsonEqual(value, enum interface{}) bool {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		e, isNum := schemaNumber(enum)
+		return err == nil && isNum && f == e
+	}
+	switch value.(type) {
+	case string, bool, nil:
+		return value == enum
+	}
+	return false
+}
+
+// formatEnum lists enum values for an error message.
+func formatEnum(enum []interface{}) string {
+	parts := make([]string, len(enum))
+	for i, e := range enum {
+		data, _ := json.Marshal(e)
+		parts[i] = string(data)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// testSchema is the output_schema the JSON output tests check against.
+const testSchema = `
+type: object
+required: [verdict, issues]
+additionalProperties: false
+properties:
+  verdict:
+    type: string
+    enum: [pass, fail]
+  score:
+    type: integer
+    minimum: 0
+    maximum: 10
+  issues:
+    type: array
+    items:
+      type: object
+      required: [file]
+      properties:
+        file: { type: string, minLength: 1 }
+        line: { type: integer }
+`
+
+// parseSchema decodes a YAML output_schema like the config parser does.
+func parseSchema(t *testing.T, src string) map[string]interface{} {
+	t.Helper()
+	var schema map[string]interface{}
+	if err := yaml.Unmarshal([]byte(src), &schema); err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	return schema
+}
+
+// TestParseJSONOutput tests repairing and validating JSON output.
+func TestParseJSONOutput(t *testing.T) {
+	schema := parseSchema(t, testSchema)
+
+	tests := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "plain",
+			output: `{"verdict": "pass", "issues": []}` + "\n",
+			want:   `{"verdict": "pass", "issues": []}`,
+		},
+		{
+			name:   "code fence",
+			output: "Here is the result:\n```json\n{\"verdict\": \"fail\", \"issues\": [{\"file\": \"a.go\", \"line\": 3}]}\n```\nLet me know!",
+			want:   `{"verdict": "fail", "issues": [{"file": "a.go", "line": 3}]}`,
+		},
+		{
+			name:   "prose around",
+			output: `Sure! {"verdict": "pass", "issues": []} Hope this helps.`,
+			want:   `{"verdict": "pass", "issues": []}`,
+		},
+		{
+			name:   "trailing comma",
+			output: `{"verdict": "pass", "issues": [],}`,
+			want:   `{"verdict": "pass", "issues": []}`,
+		},
+		{
+			name:    "empty",
+			output:  "  \n",
+			wantErr: "output is empty",
+		},
+		{
+			name:    "not json",
+			output:  "All good, no issues.",
+			wantErr: "not valid JSON",
+		},
+		{
+			name:    "missing field",
+			output:  `{"verdict": "pass"}`,
+			wantErr: `$: missing required field "issues"`,
+		},
+		{
+			name:    "enum",
+			output:  `{"verdict": "maybe", "issues": []}`,
+			wantErr: `$.verdict: must be one of "pass", "fail"`,
+		},
+		{
+			name:    "wrong type",
+			output:  `{"verdict": "pass", "issues": [{"file": "a.go", "line": "3"}]}`,
+			wantErr: "$.issues[0].line: expected integer, got string",
+		},
+		{
+			name:    "range",
+			output:  `{"verdict": "pass", "score": 11, "issues": []}`,
+			wantErr: "$.score: must be at most 10",
+		},
+		{
+			name:    "additional field",
+			output:  `{"verdict": "pass", "issues": [], "notes": "x"}`,
+			wantErr: `unexpected field "notes"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJSONOutput(tt.output, schema)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseJSONOutput() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseJSONOutput() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseJSONOutput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExpandPrompt_JSONFields tests {{outputs.task.field}} references.
+func TestExpandPrompt_JSONFields(t *testing.T) {
+	outputs := map[string]string{
+		"review": `{"verdict": "fail", "score": 4, "issues": [{"file": "a.go", "line": 3}], "ok": false}`,
+		"plain":  "not json",
+	}
+
+	tests := []struct {
+		prompt string
+		want   string
+	}{
+		{"{{outputs.review.verdict}}", "fail"},
+		{"{{outputs.review.score}}", "4"},
+		{"{{outputs.review.ok}}", "false"},
+		{"{{outputs.review.issues.0.file}}:{{outputs.review.issues.0.line}}", "a.go:3"},
+		{"{{outputs.review.issues}}", `[{"file":"a.go","line":3}]`},
+		{"{{outputs.review.issues.1.file}}", ""},
+		{"{{outputs.review.missing}}", ""},
+		{"{{outputs.plain.field}}", ""},
+		{"{{outputs.review.verdict | max_chars 2}}", "fa"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prompt, func(t *testing.T) {
+			if got := ExpandPrompt(tt.prompt, outputs); got != tt.want {
+				t.Errorf("ExpandPrompt(%q) = %q, want %q", tt.prompt, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidate_OutputFormat tests checking output_format, output_schema, and
+// references to fields of JSON output.
+func TestValidate_OutputFormat(t *testing.T) {
+	agents := map[string]AgentConfig{"ai": {Tool: "claude-code"}}
+	schema := parseSchema(t, testSchema)
+
+	tests := []struct {
+		name    string
+		tasks   map[string]TaskConfig
+		wantErr string
+	}{
+		{
+			name: "valid",
+			tasks: map[string]TaskConfig{
+				"review": {Agent: "ai", Prompt: "Review", OutputFormat: OutputJSON, OutputSchema: schema},
+				"fix":    {Agent: "ai", Needs: []string{"review"}, Prompt: "Fix {{outputs.review.issues}}", When: "{{outputs.review.verdict}} == fail"},
+			},
+		},
+		{
+			name: "unknown format",
+			tasks: map[string]TaskConfig{
+				"review": {Agent: "ai", Prompt: "Review", OutputFormat: "yaml"},
+			},
+			wantErr: `unsupported output_format "yaml"`,
+		},
+		{
+			name: "schema without json",
+			tasks: map[string]TaskConfig{
+				"review": {Agent: "ai", Prompt: "Review", OutputSchema: schema},
+			},
+			wantErr: "output_schema requires output_format: json",
+		},
+		{
+			name: "unsupported keyword",
+			tasks: map[string]TaskConfig{
+				"review": {Agent: "ai", Prompt: "Review", OutputFormat: OutputJSON, OutputSchema: parseSchema(t, "oneOf: [{type: string}]")},
+			},
+			wantErr: `keyword "oneOf" is not supported`,
+		},
+		{
+			name: "unknown type",
+			tasks: map[string]TaskConfig{
+				"review": {Agent: "ai", Prompt: "Review", OutputFormat: OutputJSON, OutputSchema: parseSchema(t, "properties: {n: {type: int}}")},
+			},
+			wantErr: `properties.n.type: unknown type "int"`,
+		},
+		{
+			name: "field of text output",
+			tasks: map[string]TaskConfig{
+				"review": {Agent: "ai", Prompt: "Review"},
+				"fix":    {Agent: "ai", Needs: []string{"review"}, Prompt: "Fix {{outputs.review.issues}}"},
+			},
+			wantErr: "doesn't have JSON output",
+		},
+		{
+			name: "field not in schema",
+			tasks: map[string]TaskConfig{
+				"review": {Agent: "ai", Prompt: "Review", OutputFormat: OutputJSON, OutputSchema: schema},
+				"fix":    {Agent: "ai", Needs: []string{"review"}, Prompt: "Fix {{outputs.review.isues}}"},
+			},
+			wantErr: `references field "isues"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&AgentflowConfig{Agents: agents, Tasks: tt.tasks})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			valErr, ok := err.(*ConfigErrors)
+			if !ok {
+				t.Fatalf("expected *ConfigErrors, got %T", err)
+			}
+			if !errorsContain(valErr, tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, valErr)
+			}
+		})
+	}
+}
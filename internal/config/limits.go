@@ -0,0 +1,95 @@
+package config
+
+import (
+	"runtime"
+	"strings"
+	"time"
+)
+
+// LimitsConfig caps the resources of a shell task, so a runaway command
+// can't exhaust the machine or bloat the run's results. CPU and memory are
+// enforced with rlimits on the task's processes, so only on Linux; the
+// wall-clock time and output limits apply everywhere. The memory limit caps
+// virtual address space (RLIMIT_AS), not resident memory: runtimes that
+// reserve far more than they use, such as Go, Node, and the JVM, fail to
+// start under limits well above what they need.
+type LimitsConfig struct {
+	CPU    string `yaml:"cpu"`    // CPU time of each process, e.g. 30s or 5m (rounded up to seconds)
+	Memory string `yaml:"memory"` // Virtual address space of each process, e.g. 512MB or 2GB
+	Time   string `yaml:"time"`   // Wall-clock time of the task, e.g. 10m; the task is stopped after it
+	Output string `yaml:"output"` // Output kept of each of stdout and stderr, e.g. 1MB; the rest is dropped
+}
+
+// TaskLimits are the parsed limits of a task. Zero fields mean no limit.
+type TaskLimits struct {
+	CPU    time.Duration
+	Memory int64
+	Time   time.Duration
+	Output int64
+}
+
+// Parse returns the limits in l; a nil l has none. Limits that don't parse
+// are left unset, as validation reports them.
+func (l *LimitsConfig) Parse() TaskLimits {
+	var limits TaskLimits
+	if l == nil {
+		return limits
+	}
+	if d, err := time.ParseDuration(strings.TrimSpace(l.CPU)); err == nil && d > 0 {
+		limits.CPU = d
+	}
+	if d, err := time.ParseDuration(strings.TrimSpace(l.Time)); err == nil && d > 0 {
+		limits.Time = d
+	}
+	if n, err := ParseSize(l.Memory); err == nil {
+		limits.Memory = n
+	}
+	if n, err := ParseSize(l.Output); err == nil {
+		limits.Output = n
+	}
+	return limits
+}
+
+// rlimitsSupported reports whether cpu and memory limits can be enforced
+// on this system.
+var rlimitsSupported = runtime.GOOS == "linux"
+
+// validateLimits checks the limits of a task run with tool, in a sandbox
+// if sandboxed.
+func validateLimits(filePath, name string, l *LimitsConfig, tool string, sandboxed bool) []*ConfigError {
+	if l == nil {
+		return nil
+	}
+	prefix := "task \"" + name + "\": "
+	var errs []*ConfigError
+	if tool != "" && tool != "shell" {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			prefix+"'limits' only apply to shell tasks",
+			"Remove 'limits', or run the task with a shell agent"))
+	}
+	if sandboxed && (l.CPU != "" || l.Memory != "") {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			prefix+"'limits' cpu and memory don't apply to sandboxed tasks",
+			"Set 'cpus' and 'memory' in the agent's sandbox instead"))
+	} else if !rlimitsSupported && (l.CPU != "" || l.Memory != "") {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			prefix+"'limits' cpu and memory are only enforced on Linux",
+			"Remove them, or bound the task with 'time' instead"))
+	}
+	for _, d := range []struct{ field, value string }{{"cpu", l.CPU}, {"time", l.Time}} {
+		if d.value != "" && !isPositiveDuration(d.value) {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				prefix+"invalid limits "+d.field+" \""+d.value+"\"",
+				"Use a duration such as '30s' or '10m'"))
+		}
+	}
+	for _, s := range []struct{ field, value string }{{"memory", l.Memory}, {"output", l.Output}} {
+		if s.value == "" {
+			continue
+		}
+		if _, err := ParseSize(s.value); err != nil {
+			errs = append(errs, NewConfigError(filePath, 0, prefix+"limits "+s.field+": "+err.Error()))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLimitsConfig_Parse tests that task limits parse into durations and
+// byte counts.
+func TestLimitsConfig_Parse(t *testing.T) {
+	l := &LimitsConfig{CPU: "90s", Memory: "512MB", Time: "10m", Output: "1KB"}
+	want := TaskLimits{CPU: 90 * time.Second, Memory: 512 << 20, Time: 10 * time.Minute, Output: 1024}
+	if got := l.Parse(); got != want {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+	var none *LimitsConfig
+	if got := none.Parse(); got != (TaskLimits{}) {
+		t.Errorf("Parse() of nil = %+v, want no limits", got)
+	}
+}
+
+// TestValidate_Limits tests the checks of task limits.
+func TestValidate_Limits(t *testing.T) {
+	supported := rlimitsSupported
+	rlimitsSupported = true
+	defer func() { rlimitsSupported = supported }()
+
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{"sh": {Tool: "shell"}},
+		Tasks: map[string]TaskConfig{"build": {Agent: "sh", Command: "make",
+			Limits: &LimitsConfig{CPU: "5m", Memory: "2GB", Time: "15m", Output: "1MB"}}},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	config.Agents["ai"] = AgentConfig{Tool: "claude-code"}
+	config.Agents["boxed"] = AgentConfig{Tool: "shell", Sandbox: &SandboxConfig{Image: "alpine"}}
+	config.Tasks["review"] = TaskConfig{Agent: "ai", Prompt: "Review", Limits: &LimitsConfig{Time: "1m"}}
+	config.Tasks["boxed"] = TaskConfig{Agent: "boxed", Command: "make", Limits: &LimitsConfig{Memory: "1GB", Time: "1m"}}
+	config.Tasks["bad"] = TaskConfig{Agent: "sh", Command: "make", Limits: &LimitsConfig{CPU: "5", Time: "-1m", Output: "lots"}}
+
+	err := Validate(config)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	for _, want := range []string{
+		`task "review": 'limits' only apply to shell tasks`,
+		`task "boxed": 'limits' cpu and memory don't apply to sandboxed tasks`,
+		`task "bad": invalid limits cpu "5"`,
+		`task "bad": invalid limits time "-1m"`,
+		`task "bad": limits output: invalid size "lots"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+
+	// Where rlimits aren't supported, cpu and memory limits are rejected
+	rlimitsSupported = false
+	err = Validate(&AgentflowConfig{
+		Agents: map[string]AgentConfig{"sh": {Tool: "shell"}},
+		Tasks:  map[string]TaskConfig{"build": {Agent: "sh", Command: "make", Limits: &LimitsConfig{Memory: "2GB"}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), `task "build": 'limits' cpu and memory are only enforced on Linux`) {
+		t.Errorf("expected an error for limits without rlimits, got: %v", err)
+	}
+}
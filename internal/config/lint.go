@@ -0,0 +1,199 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Lint rules: likely mistakes and inefficiencies in a valid config.
+const (
+	LintUnusedAgent     = "unused-agent"     // No task runs or reviews with the agent
+	LintUnusedOutput    = "unused-output"    // Tasks need an AI task but don't read its output
+	LintUnreviewedWrite = "unreviewed-write" // A task writes files without review_changes_with
+	LintLongPrompt      = "long-prompt"      // A prompt is over the length limit
+	LintMissingModel    = "missing-model"    // An AI agent leaves the model to the tool's default
+	LintSequential      = "sequential"       // Dependencies make every task wait for the previous one
+)
+
+// LintRules lists all lint rules.
+var LintRules = []string{LintUnusedAgent, LintUnusedOutput, LintUnreviewedWrite, LintLongPrompt, LintMissingModel, LintSequential}
+
+// DefaultMaxPromptLength is the prompt length, in characters, above which
+// lint warns.
+const DefaultMaxPromptLength = 8000
+
+// LintWarning is a likely mistake or inefficiency in a valid config.
+type LintWarning struct {
+	Rule    string // One of LintRules
+	File    string // File path
+	Line    int    // Line of the agent or task (0 if unknown)
+	Message string
+	Hint    string
+}
+
+// LintOptions adjusts the checks of Lint.
+type LintOptions struct {
+	DefaultModel    string // defaults.model of the global config, used by agents that set none
+	MaxPromptLength int    // Longest prompt, in characters, without a warning (0: DefaultMaxPromptLength)
+}
+
+// modelTools lists the agent tools that run a model chosen with 'model'.
+// api agents require one.
+var modelTools = []string{"claude-code", "opencode", "aider"}
+
+// outputRefRegex matches references that read a task's output: outputs.X,
+// artifacts.X, and results.X of fan-out tasks.
+var outputRefRegex = regexp.MustCompile(`\b(?:outputs|artifacts|results)\.([a-zA-Z0-9_-]+)`)
+
+// Lint checks a valid config for likely mistakes that validation allows:
+// unused agents and outputs, unreviewed writes, long prompts, missing
+// models, and dependencies that leave nothing to run in parallel. Only
+// agents and tasks defined in the file itself, not in included ones, are
+// reported.
+func Lint(config *AgentflowConfig, filePath string, opts LintOptions) []LintWarning {
+	if opts.MaxPromptLength <= 0 {
+		opts.MaxPromptLength = DefaultMaxPromptLength
+	}
+	var warnings []LintWarning
+	warn := func(rule, section, name, message, hint string) {
+		w := LintWarning{Rule: rule, File: filePath, Message: message, Hint: hint}
+		if pos, ok := config.positions[section+"."+name]; ok {
+			w.Line = pos.line
+		}
+		warnings = append(warnings, w)
+	}
+
+	agentNames := slices.Sorted(maps.Keys(config.Agents))
+	taskNames := slices.Sorted(maps.Keys(config.Tasks))
+
+	// Agents, and the models of those in use
+	usedAgents := make(map[string]bool)
+	for _, task := range config.Tasks {
+		usedAgents[task.Agent] = true
+		usedAgents[task.ReviewWith] = true
+	}
+	for _, name := range agentNames {
+		agent := config.Agents[name]
+		if !config.definedHere("agents", name) {
+			continue
+		}
+		if !usedAgents[name] {
+			warn(LintUnusedAgent, "agents", name,
+				fmt.Sprintf("agent %q is not used by any task", name),
+				"Remove it, or set 'agent: "+name+"' on the tasks it should run")
+			continue
+		}
+		if agent.Model == "" && opts.DefaultModel == "" && slices.Contains(modelTools, agent.Tool) {
+			warn(LintMissingModel, "agents", name,
+				fmt.Sprintf("agent %q sets no model, so %s picks one", name, agent.Tool),
+				"Set 'model:' on the agent, or defaults.model in ~/.cortex/config.yml, for runs that don't change with the tool's default")
+		}
+	}
+
+	// Tasks whose output is read, and the tasks that need each task
+	readOutputs := make(map[string]bool)
+	dependents := make(map[string][]string)
+	for _, name := range taskNames {
+		task := config.Tasks[name]
+		for _, match := range outputRefRegex.FindAllStringSubmatch(taskVarText(task)+"\n"+task.Until, -1) {
+			readOutputs[match[1]] = true
+		}
+		for _, dep := range task.Needs {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	for _, name := range taskNames {
+		task := config.Tasks[name]
+		if !config.definedHere("tasks", name) {
+			continue
+		}
+		agent := task.RunsWith(config.Agents[task.Agent])
+		isAI := task.BuiltinTool() == "" && agent.Tool != "" && agent.Tool != "shell"
+
+		if isAI && !task.Write && len(dependents[name]) > 0 && !readOutputs[name] {
+			warn(LintUnusedOutput, "tasks", name,
+				fmt.Sprintf("task %q: output is never referenced", name),
+				fmt.Sprintf("%s %s use {{outputs.%s}}; pass it in the prompt, or drop the task if only the order matters",
+					strings.Join(dependents[name], ", "), plural(len(dependents[name]), "needs it but doesn't", "need it but don't"), name))
+		}
+		if task.Write && task.ReviewWith == "" {
+			warn(LintUnreviewedWrite, "tasks", name,
+				fmt.Sprintf("task %q writes files without a review", name),
+				"Add 'review_changes_with: <agent>' (and 'rollback_on_reject: true') to check its changes before other tasks build on them")
+		}
+		if len(task.Prompt) > opts.MaxPromptLength {
+			warn(LintLongPrompt, "tasks", name,
+				fmt.Sprintf("task %q: prompt is %d characters (limit %d)", name, len(task.Prompt), opts.MaxPromptLength),
+				"Point the agent at files to read instead of inlining them, or split the task")
+		}
+	}
+
+	if chain := sequentialChain(config.Tasks); chain != nil {
+		warn(LintSequential, "", "",
+			fmt.Sprintf("all %d tasks run one after another: %s", len(chain), strings.Join(chain, " -> ")),
+			"Remove needs that aren't real dependencies so independent tasks can run in parallel")
+	}
+	return warnings
+}
+
+// definedHere reports whether a name of a section is defined in the config
+// file itself rather than in an included one. Configs that weren't parsed
+// from a file define everything.
+func (c *AgentflowConfig) definedHere(section, name string) bool {
+	if c.positions == nil {
+		return true
+	}
+	_, ok := c.positions[section+"."+name]
+	return ok
+}
+
+// sequentialChain returns the tasks in execution order if dependencies
+// leave at most one task ready at a time, or nil if some can run in
+// parallel. Workflows of fewer than three tasks are not reported.
+func sequentialChain(tasks map[string]TaskConfig) []string {
+	if len(tasks) < 3 {
+		return nil
+	}
+
+	levels := make(map[string]int)
+	var level func(name string, visiting map[string]bool) int
+	level = func(name string, visiting map[string]bool) int {
+		if l, ok := levels[name]; ok {
+			return l
+		}
+		if visiting[name] {
+			return 0 // Cycles are reported by validation
+		}
+		visiting[name] = true
+		l := 0
+		for _, dep := range tasks[name].Needs {
+			if _, ok := tasks[dep]; ok {
+				l = max(l, level(dep, visiting)+1)
+			}
+		}
+		levels[name] = l
+		return l
+	}
+
+	chain := make([]string, len(tasks))
+	for name := range tasks {
+		l := level(name, make(map[string]bool))
+		if l >= len(chain) || chain[l] != "" {
+			return nil
+		}
+		chain[l] = name
+	}
+	return chain
+}
+
+// plural returns singular for a count of one, and otherwise many.
+func plural(n int, singular, many string) string {
+	if n == 1 {
+		return singular
+	}
+	return many
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLint tests warning about likely mistakes in valid configs.
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		opts  LintOptions
+		wants []string // "rule: message", in order
+	}{
+		{
+			name: "clean",
+			yaml: `
+agents:
+  ai: { tool: claude-code, model: sonnet }
+  sh: { tool: shell }
+tasks:
+  analyze: { agent: ai, prompt: Analyze the code. }
+  lint: { agent: sh, command: make lint }
+  report: { agent: ai, needs: [analyze, lint], prompt: "Report on {{outputs.analyze}}" }
+`,
+		},
+		{
+			name: "warnings",
+			yaml: `
+agents:
+  ai: { tool: claude-code }
+  sh: { tool: shell }
+  spare: { tool: opencode, model: gpt-4o }
+tasks:
+  analyze: { agent: ai, prompt: Analyze the code and list every module. }
+  implement: { agent: ai, write: true, needs: analyze, prompt: Implement it. }
+  test: { agent: sh, needs: implement, command: make test }
+`,
+			opts: LintOptions{MaxPromptLength: 20},
+			wants: []string{
+				`missing-model: agent "ai" sets no model, so claude-code picks one`,
+				`unused-agent: agent "spare" is not used by any task`,
+				`unused-output: task "analyze": output is never referenced`,
+				`long-prompt: task "analyze": prompt is 39 characters (limit 20)`,
+				`unreviewed-write: task "implement" writes files without a review`,
+				`sequential: all 3 tasks run one after another: analyze -> implement -> test`,
+			},
+		},
+		{
+			name: "default model and reviewed writes",
+			yaml: `
+agents:
+  ai: { tool: claude-code }
+  reviewer: { tool: claude-code, model: opus }
+tasks:
+  fix: { agent: ai, write: true, review_changes_with: reviewer, prompt: Fix it. }
+`,
+			opts: LintOptions{DefaultModel: "sonnet"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseConfig([]byte(tt.yaml), "/project")
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+			if err := Validate(cfg); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			var got []string
+			for _, w := range Lint(cfg, "Cortexfile.yml", tt.opts) {
+				got = append(got, w.Rule+": "+w.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.wants, "\n") {
+				t.Errorf("Lint() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.wants, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxIterations caps the runs of a task with `until` that doesn't set
+// max_iterations.
+const DefaultMaxIterations = 5
+
+// Loop variables, available in the prompt, command, and script of a task
+// with `until`.
+const (
+	LoopIteration = "iteration" // Number of the current run, from 1
+	LoopOutput    = "output"    // Output of the previous run (empty in the first)
+)
+
+// LoopVars lists all loop variables.
+var LoopVars = []string{LoopIteration, LoopOutput}
+
+// loopVarRegex matches {{loop.name}} patterns, with the same "| modifier N"
+// suffixes as {{outputs.X}}.
+var loopVarRegex = regexp.MustCompile(`\{\{loop\.([a-zA-Z0-9_-]+)(\s*\|[^{}]*)?\}\}`)
+
+// Iterations returns the cap on the runs of a task with `until`.
+func (t TaskConfig) Iterations() int {
+	if t.MaxIterations > 0 {
+		return t.MaxIterations
+	}
+	return DefaultMaxIterations
+}
+
+// ExpandLoopVars replaces {{loop.iteration}} and {{loop.output}} with the
+// number of the current run of a loop task and the output of its previous
+// run. Text outside a loop (iteration 0) is returned as-is.
+func ExpandLoopVars(text string, iteration int, previous string) string {
+	if iteration == 0 {
+		return text
+	}
+	return loopVarRegex.ReplaceAllStringFunc(text, func(match string) string {
+		groups := loopVarRegex.FindStringSubmatch(match)
+		mods, err := parseOutputModifiers(groups[2])
+		if err != nil {
+			return match
+		}
+		var value string
+		switch groups[1] {
+		case LoopIteration:
+			value = strconv.Itoa(iteration)
+		case LoopOutput:
+			value = previous
+		default:
+			return match
+		}
+		for _, mod := range mods {
+			value = mod.apply(value)
+		}
+		return value
+	})
+}
+
+// UsesLoopOutput reports whether text references {{loop.output}}.
+func UsesLoopOutput(text string) bool {
+	for _, match := range loopVarRegex.FindAllStringSubmatch(text, -1) {
+		if match[1] == LoopOutput {
+			return true
+		}
+	}
+	return false
+}
+
+// LoopPrompt adds the output of the previous run of a loop task to its
+// prompt, for prompts that don't place it with {{loop.output}}.
+func LoopPrompt(prompt string, iteration int, previous string) string {
+	return fmt.Sprintf("%s\n\nThis is attempt %d. The previous attempt ended with:\n\n%s", strings.TrimRight(prompt, "\n"), iteration, previous)
+}
+
+// RenameTaskRefs renames the outputs.X, status.X, artifacts.X, and
+// previous.X references to task from in text to task to.
+func RenameTaskRefs(text, from, to string) string {
+	return taskRefRegex.ReplaceAllStringFunc(text, func(ref string) string {
+		groups := taskRefRegex.FindStringSubmatch(ref)
+		if groups[2] != from {
+			return ref
+		}
+		return groups[1] + "." + to
+	})
+}
+
+// validateLoop checks the `until` condition and max_iterations of a task,
+// and its {{loop.X}} references. The condition may read the output and
+// status of the task itself, which are those of its latest run.
+func validateLoop(filePath, name string, task TaskConfig, tasks map[string]TaskConfig) []*ConfigError {
+	var errs []*ConfigError
+	if task.MaxIterations < 0 {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			fmt.Sprintf("task %q: max_iterations must be positive, got %d", name, task.MaxIterations),
+			fmt.Sprintf("Omit it for the default of %d", DefaultMaxIterations)))
+	}
+	if task.Until == "" {
+		if task.MaxIterations != 0 {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": max_iterations requires 'until'",
+				"Add an 'until' condition that ends the loop"))
+		}
+	} else {
+		deps := append(append([]string(nil), task.Needs...), name)
+		errs = append(errs, validateCondition(filePath, name, "until", task.Until, deps)...)
+		errs = append(errs, validateTemplateVarsStructured(filePath, name, task.Until, deps, tasks)...)
+	}
+
+	text := task.Prompt + "\n" + task.Command
+	if task.Script != nil {
+		text += "\n" + task.Script.Code
+	}
+	for _, match := range loopVarRegex.FindAllStringSubmatch(text, -1) {
+		switch {
+		case task.Until == "":
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": "+match[0]+" is only set in tasks with 'until'",
+				"Add an 'until' condition to re-run the task"))
+		case !containsString(LoopVars, match[1]):
+			hint := "Available: {{loop." + strings.Join(LoopVars, "}}, {{loop.") + "}}"
+			if suggestion := SuggestClosestMatch(match[1], LoopVars); suggestion != "" {
+				hint = "Did you mean {{loop." + suggestion + "}}?"
+			}
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": unknown loop variable "+match[0], hint))
+		default:
+			if _, err := parseOutputModifiers(match[2]); err != nil {
+				errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+					"task \""+name+"\": invalid template "+match[0]+": "+err.Error(),
+					"Supported modifiers: "+strings.Join(OutputModifiers, ", ")))
+			}
+		}
+	}
+	return errs
+}
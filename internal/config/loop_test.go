@@ -0,0 +1,126 @@
+package config
+
+import "testing"
+
+// TestExpandLoopVars tests filling {{loop.iteration}} and {{loop.output}}.
+func TestExpandLoopVars(t *testing.T) {
+	tests := []struct {
+		text      string
+		iteration int
+		previous  string
+		want      string
+	}{
+		{"Attempt {{loop.iteration}}", 2, "", "Attempt 2"},
+		{"Last time: {{loop.output}}", 3, "FAIL a\nFAIL b\n", "Last time: FAIL a\nFAIL b\n"},
+		{"{{loop.output | tail 1}}", 2, "one\ntwo\n", "two"},
+		{"{{loop.output}}", 1, "", ""},
+		{"{{loop.unknown}}", 2, "x", "{{loop.unknown}}"},
+		{"Attempt {{loop.iteration}}", 0, "", "Attempt {{loop.iteration}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := ExpandLoopVars(tt.text, tt.iteration, tt.previous); got != tt.want {
+				t.Errorf("ExpandLoopVars() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRenameTaskRefs tests pointing references to a task at another name.
+func TestRenameTaskRefs(t *testing.T) {
+	got := RenameTaskRefs("{{outputs.fix}} contains PASS and {{status.fix}} and {{outputs.fixer}}", "fix", "fix-2")
+	want := "{{outputs.fix-2}} contains PASS and {{status.fix-2}} and {{outputs.fixer}}"
+	if got != want {
+		t.Errorf("RenameTaskRefs() = %q, want %q", got, want)
+	}
+}
+
+// TestValidate_Loop tests checking until, max_iterations, and loop
+// variables.
+func TestValidate_Loop(t *testing.T) {
+	agents := map[string]AgentConfig{"ai": {Tool: "claude-code"}, "sh": {Tool: "shell"}}
+
+	tests := []struct {
+		name    string
+		tasks   map[string]TaskConfig
+		wantErr string
+	}{
+		{
+			name: "valid",
+			tasks: map[string]TaskConfig{
+				"lint": {Agent: "sh", Command: "make lint"},
+				"fix": {Agent: "ai", Needs: []string{"lint"}, Prompt: "Fix the tests (attempt {{loop.iteration}}):\n{{loop.output | tail 50}}",
+					Until: "{{outputs.fix}} contains 'ALL TESTS PASS'", MaxIterations: 3},
+			},
+		},
+		{
+			name: "expression",
+			tasks: map[string]TaskConfig{
+				"test": {Agent: "sh", Command: "make test", Until: "{{ status.test == 'success' }}"},
+			},
+		},
+		{
+			name: "invalid condition",
+			tasks: map[string]TaskConfig{
+				"fix": {Agent: "ai", Prompt: "Fix", Until: "{{outputs.fix}} =="},
+			},
+			wantErr: `task "fix": invalid 'until'`,
+		},
+		{
+			name: "task not in needs",
+			tasks: map[string]TaskConfig{
+				"lint": {Agent: "sh", Command: "make lint"},
+				"fix":  {Agent: "ai", Prompt: "Fix", Until: "{{ status.lint == 'success' }}"},
+			},
+			wantErr: `'until' references status.lint but "lint" is not in 'needs'`,
+		},
+		{
+			name: "negative max_iterations",
+			tasks: map[string]TaskConfig{
+				"fix": {Agent: "ai", Prompt: "Fix", Until: "{{outputs.fix}} contains PASS", MaxIterations: -1},
+			},
+			wantErr: "max_iterations must be positive",
+		},
+		{
+			name: "max_iterations without until",
+			tasks: map[string]TaskConfig{
+				"fix": {Agent: "ai", Prompt: "Fix", MaxIterations: 3},
+			},
+			wantErr: "max_iterations requires 'until'",
+		},
+		{
+			name: "loop variable without until",
+			tasks: map[string]TaskConfig{
+				"fix": {Agent: "ai", Prompt: "Fix {{loop.output}}"},
+			},
+			wantErr: "{{loop.output}} is only set in tasks with 'until'",
+		},
+		{
+			name: "unknown loop variable",
+			tasks: map[string]TaskConfig{
+				"fix": {Agent: "ai", Prompt: "Fix {{loop.outptu}}", Until: "{{outputs.fix}} contains PASS"},
+			},
+			wantErr: "unknown loop variable {{loop.outptu}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&AgentflowConfig{Agents: agents, Tasks: tt.tasks})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			valErr, ok := err.(*ConfigErrors)
+			if !ok {
+				t.Fatalf("expected *ConfigErrors, got %T", err)
+			}
+			if !errorsContain(valErr, tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, valErr)
+			}
+		})
+	}
+}
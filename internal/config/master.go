@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 
 	"gopkg.in/yaml.v3"
 )
@@ -29,7 +32,8 @@ type MasterConfig struct {
 	// Workflows defines the Cortexfiles to run
 	Workflows []WorkflowEntry `yaml:"workflows"`
 
-	// Variables defines global variables available to all workflows
+	// Variables defines global variables available to all workflows as
+	// {{vars.name}}
 	Variables map[string]string `yaml:"variables"`
 }
 
@@ -50,7 +54,8 @@ type WorkflowEntry struct {
 	// Needs specifies dependencies on other workflows (by name)
 	Needs StringList `yaml:"needs"`
 
-	// Variables for this specific workflow (merged with global)
+	// Variables for this specific workflow (merged with global). Values may
+	// use {{workflows.<name>.outputs.<task>}} of workflows listed in Needs.
 	Variables map[string]string `yaml:"variables"`
 }
 
@@ -84,6 +89,13 @@ func LoadMasterConfig(path string) (*MasterConfig, error) {
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse master config: %w", err)
 	}
+	unknown, err := findUnknownFields(data, SchemaMaster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse master config: %w", err)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("invalid master config: %w", unknown[0].err())
+	}
 
 	// Apply defaults
 	if config.Mode == "" {
@@ -147,9 +159,82 @@ func ValidateMasterConfig(cfg *MasterConfig) error {
 		}
 	}
 
+	// Outputs can only be used from workflows that finish first
+	for name, value := range cfg.Variables {
+		if match := workflowOutputRegex.FindString(value); match != "" {
+			return fmt.Errorf("variable %q: %s can only be used in a workflow's variables", name, match)
+		}
+	}
+	for _, w := range cfg.Workflows {
+		for _, value := range w.Variables {
+			for _, match := range workflowOutputRegex.FindAllStringSubmatch(value, -1) {
+				if !slices.Contains(w.Needs, match[1]) {
+					return fmt.Errorf("workflow %q uses %s but doesn't need workflow %q", w.Name, match[0], match[1])
+				}
+				if _, err := parseOutputModifiers(match[3]); err != nil {
+					return fmt.Errorf("workflow %q: invalid template %s: %w", w.Name, match[0], err)
+				}
+			}
+		}
+	}
+
 	return nil
 }
 
+// workflowOutputRegex matches {{workflows.name.outputs.task}} patterns, with
+// the same "| modifier N" suffixes as {{outputs.X}}.
+var workflowOutputRegex = regexp.MustCompile(`\{\{workflows\.([a-zA-Z0-9_-]+)\.outputs\.([a-zA-Z0-9_-]+)(\s*\|[^{}]*)?\}\}`)
+
+// WorkflowVars returns the variables a workflow runs with: the master's
+// variables overridden by the workflow's own, with
+// {{workflows.<name>.outputs.<task>}} replaced by the task outputs of
+// finished workflows (keyed by workflow, then task). Referencing a task the
+// workflow doesn't have is an error.
+func WorkflowVars(cfg *MasterConfig, w WorkflowEntry, outputs map[string]map[string]string) (map[string]string, error) {
+	vars := make(map[string]string, len(cfg.Variables)+len(w.Variables))
+	for name, value := range cfg.Variables {
+		vars[name] = value
+	}
+	for name, value := range w.Variables {
+		vars[name] = value
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var err error
+		vars[name] = workflowOutputRegex.ReplaceAllStringFunc(vars[name], func(match string) string {
+			groups := workflowOutputRegex.FindStringSubmatch(match)
+			output, ok := outputs[groups[1]][groups[2]]
+			if !ok {
+				if err == nil {
+					err = fmt.Errorf("variable %q: workflow %q has no output of task %q", name, groups[1], groups[2])
+				}
+				return match
+			}
+			mods, modErr := parseOutputModifiers(groups[3])
+			if modErr != nil {
+				if err == nil {
+					err = fmt.Errorf("variable %q: invalid template %s: %w", name, match, modErr)
+				}
+				return match
+			}
+			for _, mod := range mods {
+				output = mod.apply(output)
+			}
+			return output
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return vars, nil
+}
+
 // ResolveWorkflowPaths expands glob patterns in workflow paths and returns resolved entries.
 func ResolveWorkflowPaths(cfg *MasterConfig, baseDir string) ([]WorkflowEntry, error) {
 	var resolved []WorkflowEntry
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestValidateMasterConfigWorkflowOutputs tests checking
+// {{workflows.X.outputs.Y}} references in workflow variables.
+func TestValidateMasterConfigWorkflowOutputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     MasterConfig
+		wantErr string
+	}{
+		{
+			name: "output of a needed workflow",
+			cfg: MasterConfig{Workflows: []WorkflowEntry{
+				{Name: "backend", Path: "backend/Cortexfile.yml"},
+				{Name: "frontend", Path: "frontend/Cortexfile.yml", Needs: StringList{"backend"},
+					Variables: map[string]string{"api": "{{workflows.backend.outputs.analyze | head 5}}"}},
+			}},
+		},
+		{
+			name: "workflow not needed",
+			cfg: MasterConfig{Workflows: []WorkflowEntry{
+				{Name: "backend", Path: "backend/Cortexfile.yml"},
+				{Name: "frontend", Path: "frontend/Cortexfile.yml",
+					Variables: map[string]string{"api": "{{workflows.backend.outputs.analyze}}"}},
+			}},
+			wantErr: `doesn't need workflow "backend"`,
+		},
+		{
+			name: "invalid modifier",
+			cfg: MasterConfig{Workflows: []WorkflowEntry{
+				{Name: "backend", Path: "backend/Cortexfile.yml"},
+				{Name: "frontend", Path: "frontend/Cortexfile.yml", Needs: StringList{"backend"},
+					Variables: map[string]string{"api": "{{workflows.backend.outputs.analyze | first 5}}"}},
+			}},
+			wantErr: "invalid template",
+		},
+		{
+			name: "master variables",
+			cfg: MasterConfig{
+				Variables: map[string]string{"api": "{{workflows.backend.outputs.analyze}}"},
+				Workflows: []WorkflowEntry{{Name: "backend", Path: "backend/Cortexfile.yml"}},
+			},
+			wantErr: "can only be used in a workflow's variables",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMasterConfig(&tt.cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateMasterConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateMasterConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestWorkflowVars tests merging master and workflow variables and filling
+// in the outputs of finished workflows.
+func TestWorkflowVars(t *testing.T) {
+	cfg := &MasterConfig{Variables: map[string]string{"env": "staging", "region": "eu"}}
+	outputs := map[string]map[string]string{
+		"backend": {"analyze": "line 1\nline 2\nline 3\n"},
+	}
+
+	w := WorkflowEntry{Name: "frontend", Variables: map[string]string{
+		"env":     "prod",
+		"summary": "Backend: {{workflows.backend.outputs.analyze | head 1}}",
+	}}
+	got, err := WorkflowVars(cfg, w, outputs)
+	if err != nil {
+		t.Fatalf("WorkflowVars() error = %v", err)
+	}
+	want := map[string]string{"env": "prod", "region": "eu", "summary": "Backend: line 1\n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WorkflowVars() = %v, want %v", got, want)
+	}
+
+	w.Variables = map[string]string{"summary": "{{workflows.backend.outputs.missing}}"}
+	if _, err := WorkflowVars(cfg, w, outputs); err == nil || !strings.Contains(err.Error(), `no output of task "missing"`) {
+		t.Errorf("WorkflowVars() error = %v, want missing task error", err)
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// validateMCPServers checks the MCP config files of a claude-code agent:
+// each exists and is a JSON document with an mcpServers object, as claude's
+// --mcp-config expects.
+func validateMCPServers(filePath, name string, agent AgentConfig) []*ConfigError {
+	if len(agent.MCPServers) == 0 {
+		return nil
+	}
+	prefix := "agent \"" + name + "\": "
+	if agent.Tool != "claude-code" {
+		return []*ConfigError{NewConfigErrorWithHint(filePath, 0,
+			prefix+"'mcp_servers' only apply to claude-code agents",
+			"Remove 'mcp_servers', or set 'tool: claude-code'")}
+	}
+
+	var errs []*ConfigError
+	for _, file := range agent.MCPServers {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				prefix+"mcp_servers file not found: "+file,
+				"Paths are relative to the Cortexfile"))
+			continue
+		}
+		var doc struct {
+			MCPServers map[string]json.RawMessage `json:"mcpServers"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil || doc.MCPServers == nil {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				prefix+"mcp_servers file "+file+" is not an MCP config",
+				`Use JSON of the form {"mcpServers": {"<name>": {"command": "...", "args": [...]}}}`))
+		}
+	}
+	return errs
+}
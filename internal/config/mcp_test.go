@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidate_MCPServers tests that the MCP config files of agents exist
+// and hold MCP servers.
+func TestValidate_MCPServers(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	good := write("db.json", `{"mcpServers": {"postgres": {"command": "pg-mcp", "args": ["--readonly"]}}}`)
+	notMCP := write("other.json", `{"servers": {}}`)
+
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{"db": {Tool: "claude-code", MCPServers: StringList{good}}},
+		Tasks:  map[string]TaskConfig{"query": {Agent: "db", Prompt: "Count users"}},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	config.Agents["bad"] = AgentConfig{Tool: "claude-code", MCPServers: StringList{filepath.Join(dir, "missing.json"), notMCP}}
+	config.Agents["oc"] = AgentConfig{Tool: "opencode", MCPServers: StringList{good}}
+	config.Tasks["switch"] = TaskConfig{Agent: "db", Prompt: "x", Tool: "aider"}
+
+	err := Validate(config)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	for _, want := range []string{
+		`agent "bad": mcp_servers file not found: ` + filepath.Join(dir, "missing.json"),
+		`agent "bad": mcp_servers file ` + notMCP + ` is not an MCP config`,
+		`agent "oc": 'mcp_servers' only apply to claude-code agents`,
+		`task "switch": tool "aider" can't use the mcp_servers of agent "db"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
+
+// TestResolveMCPServers tests that MCP config paths are resolved from the
+// Cortexfile directory.
+func TestResolveMCPServers(t *testing.T) {
+	dir := t.TempDir()
+	abs := filepath.Join(dir, "shared", "mcp.json")
+	config := &AgentflowConfig{Agents: map[string]AgentConfig{
+		"db": {Tool: "claude-code", MCPServers: StringList{"mcp/db.json", abs}},
+	}}
+	resolveMCPServers(config, dir)
+
+	got := config.Agents["db"].MCPServers
+	if got[0] != filepath.Join(dir, "mcp/db.json") || got[1] != abs {
+		t.Errorf("MCPServers = %v", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,17 +17,40 @@ func LoadConfig(path string) (*AgentflowConfig, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	return ParseConfig(data, filepath.Dir(path))
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(data, filepath.Dir(path), []string{abs})
 }
 
 // ParseConfig parses YAML config data and resolves prompt_file references.
 // baseDir is used to resolve relative prompt_file paths.
 func ParseConfig(data []byte, baseDir string) (*AgentflowConfig, error) {
+	return parseConfig(data, baseDir, nil)
+}
+
+// parseConfig parses YAML config data. stack holds the files being loaded,
+// the last one being parsed, to detect include cycles.
+func parseConfig(data []byte, baseDir string, stack []string) (*AgentflowConfig, error) {
 	var config AgentflowConfig
+	var filePath string
+	if len(stack) > 0 {
+		filePath = stack[len(stack)-1]
+	}
 
 	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, decodeErrors(filePath, data, err)
+	}
+
+	// Keep misspelled and unsupported keys for validation to report, and
+	// where agents and tasks are defined for the errors about them
+	unknown, err := findUnknownFields(data, SchemaCortexfile)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
+	config.unknownFields = unknown
+	config.positions = keyPositions(data)
 
 	// Initialize maps if nil (empty config)
 	if config.Agents == nil {
@@ -36,18 +60,73 @@ func ParseConfig(data []byte, baseDir string) (*AgentflowConfig, error) {
 		config.Tasks = make(map[string]TaskConfig)
 	}
 
+	// Build tasks from task templates before anything else reads them
+	if err := instantiateTemplates(&config, data); err != nil {
+		return nil, err
+	}
+
 	// Resolve prompt_file references
 	if err := resolvePromptFiles(&config, baseDir); err != nil {
 		return nil, err
 	}
 
+	// Resolve system_prompt_file references of agents and tasks
+	if err := resolveSystemPromptFiles(&config, baseDir); err != nil {
+		return nil, err
+	}
+
+	// Resolve inputs/outputs file declarations
+	resolveFileDeps(&config, baseDir)
+
+	// Resolve top-level, agent, and task working directories
+	resolveWorkdirs(&config, baseDir)
+
+	// Resolve certificate files of api agents and http requests
+	resolveTLSFiles(&config, baseDir)
+
+	// Resolve MCP config files of claude-code agents
+	resolveMCPServers(&config, baseDir)
+	resolveSandboxMounts(&config, baseDir)
+
+	// Record an explicit env_file, which secrets are read from
+	if config.EnvFile != "" {
+		envFile := config.EnvFile
+		if !filepath.IsAbs(envFile) {
+			envFile = filepath.Join(baseDir, envFile)
+		}
+		config.files = append(config.files, envFile)
+	}
+
+	// Load items_from data and matrix values for static fan-out
+	if err := resolveItemsFrom(&config, baseDir); err != nil {
+		return nil, err
+	}
+
+	// Merge included agents and tasks, their paths already resolved
+	if len(stack) > 0 {
+		config.Sources = []string{stack[len(stack)-1]}
+	}
+	if err := loadIncludes(&config, baseDir, stack); err != nil {
+		return nil, err
+	}
+
+	// Apply the defaults of stages, including to included tasks
+	applyStages(&config)
+
 	return &config, nil
 }
 
 // resolvePromptFiles loads content from prompt_file paths into the Prompt field.
+// A prompt_file glob loads every matching file, in name order, separated by a
+// blank line. A glob matching no files is left for validation to report.
 func resolvePromptFiles(config *AgentflowConfig, baseDir string) error {
 	for name, task := range config.Tasks {
-		if task.PromptFile != "" {
+		// A task with both prompt and prompt_file is reported by validation
+		if task.PromptFile == "" || task.Prompt != "" {
+			continue
+		}
+
+		if !containsGlob(task.PromptFile) {
 			// Resolve path relative to config file directory
 			promptPath := task.PromptFile
 			if !filepath.IsAbs(promptPath) {
@@ -61,8 +140,265 @@ func resolvePromptFiles(config *AgentflowConfig, baseDir string) error {
 
 			// Store the loaded content in Prompt field
 			task.Prompt = string(content)
+			task.PromptFiles = []string{promptPath}
+			config.Tasks[name] = task
+			config.files = append(config.files, promptPath)
+			continue
+		}
+
+		matches, err := MatchFiles(task.PromptFile, baseDir)
+		if err != nil {
+			return fmt.Errorf("task %q: invalid prompt_file pattern %q: %w", name, task.PromptFile, err)
+		}
+		parts := make([]string, 0, len(matches))
+		for _, match := range matches {
+			content, err := os.ReadFile(match)
+			if err != nil {
+				return fmt.Errorf("task %q: failed to read prompt_file %q: %w", name, match, err)
+			}
+			parts = append(parts, strings.TrimRight(string(content), "\n"))
+		}
+
+		task.Prompt = strings.Join(parts, "\n\n")
+		task.PromptFiles = matches
+		config.Tasks[name] = task
+		config.files = append(config.files, matches...)
+	}
+	return nil
+}
+
+// resolveSystemPromptFiles loads system_prompt_file paths, relative to the
+// config file directory, into the SystemPrompt fields.
+func resolveSystemPromptFiles(config *AgentflowConfig, baseDir string) error {
+	load := func(kind, name, prompt, file string) (string, error) {
+		if file == "" {
+			return prompt, nil
+		}
+		if prompt != "" {
+			return "", fmt.Errorf("%s %q: set either system_prompt or system_prompt_file, not both", kind, name)
+		}
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%s %q: failed to read system_prompt_file %q: %w", kind, name, file, err)
+		}
+		config.files = append(config.files, path)
+		return string(content), nil
+	}
+
+	for name, agent := range config.Agents {
+		prompt, err := load("agent", name, agent.SystemPrompt, agent.SystemPromptFile)
+		if err != nil {
+			return err
+		}
+		agent.SystemPrompt = prompt
+		config.Agents[name] = agent
+	}
+	for name, task := range config.Tasks {
+		prompt, err := load("task", name, task.SystemPrompt, task.SystemPromptFile)
+		if err != nil {
+			return err
+		}
+		task.SystemPrompt = prompt
+		config.Tasks[name] = task
+	}
+	return nil
+}
+
+// MatchFiles returns the files matching a path or glob pattern, resolved
+// relative to baseDir, in name order. A path without glob characters matches
+// itself if it exists.
+func MatchFiles(pattern, baseDir string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(baseDir, pattern)
+	}
+	if !containsGlob(pattern) {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	// Directories are not files to read
+	files := matches[:0]
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && !info.IsDir() {
+			files = append(files, match)
+		}
+	}
+	return files, nil
+}
+
+// resolveFileDeps makes inputs/outputs/artifacts paths absolute relative to baseDir.
+func resolveFileDeps(config *AgentflowConfig, baseDir string) {
+	resolve := func(paths StringList) StringList {
+		if len(paths) == 0 {
+			return paths
+		}
+		resolved := make(StringList, len(paths))
+		for i, p := range paths {
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(baseDir, p)
+			}
+			resolved[i] = p
+		}
+		return resolved
+	}
+
+	for name, task := range config.Tasks {
+		task.Inputs = resolve(task.Inputs)
+		task.Outputs = resolve(task.Outputs)
+		task.Artifacts = resolve(task.Artifacts)
+		config.Tasks[name] = task
+	}
+}
+
+// resolveWorkdirs makes workdir paths absolute relative to baseDir, so a
+// workflow behaves the same regardless of where cortex is run from.
+func resolveWorkdirs(config *AgentflowConfig, baseDir string) {
+	resolve := func(dir string) string {
+		if dir == "" {
+			return dir
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(baseDir, dir)
+		}
+		config.files = append(config.files, dir)
+		return dir
+	}
+
+	config.Workdir = resolve(config.Workdir)
+	for name, stage := range config.Stages {
+		stage.Workdir = resolve(stage.Workdir)
+		config.Stages[name] = stage
+	}
+	for name, agent := range config.Agents {
+		agent.Workdir = resolve(agent.Workdir)
+		config.Agents[name] = agent
+	}
+	for name, task := range config.Tasks {
+		task.Workdir = resolve(task.Workdir)
+		config.Tasks[name] = task
+	}
+}
+
+// resolveTLSFiles makes tls file paths absolute relative to baseDir.
+func resolveTLSFiles(config *AgentflowConfig, baseDir string) {
+	resolve := func(tls *TLSConfig) *TLSConfig {
+		if tls == nil {
+			return nil
+		}
+		resolved := *tls
+		for _, p := range []*string{&resolved.CAFile, &resolved.CertFile, &resolved.KeyFile} {
+			if *p == "" {
+				continue
+			}
+			if !filepath.IsAbs(*p) {
+				*p = filepath.Join(baseDir, *p)
+			}
+			config.files = append(config.files, *p)
+		}
+		return &resolved
+	}
+
+	for name, agent := range config.Agents {
+		agent.TLS = resolve(agent.TLS)
+		config.Agents[name] = agent
+	}
+	for _, task := range config.Tasks {
+		if task.HTTP != nil {
+			task.HTTP.TLS = resolve(task.HTTP.TLS)
+		}
+		if task.Poll != nil && task.Poll.HTTP != nil {
+			task.Poll.HTTP.TLS = resolve(task.Poll.HTTP.TLS)
+		}
+	}
+}
+
+// resolveMCPServers makes the MCP config files of agents relative to the
+// config file directory absolute.
+func resolveMCPServers(config *AgentflowConfig, baseDir string) {
+	for name, agent := range config.Agents {
+		if len(agent.MCPServers) == 0 {
+			continue
+		}
+		files := make(StringList, len(agent.MCPServers))
+		for i, file := range agent.MCPServers {
+			if file != "" && !filepath.IsAbs(file) {
+				file = filepath.Join(baseDir, file)
+			}
+			files[i] = file
+		}
+		agent.MCPServers = files
+		config.Agents[name] = agent
+	}
+}
+
+// resolveSandboxMounts makes the host paths of sandbox mounts absolute,
+// relative to the config file directory, expanding a leading ~/ to the home
+// directory.
+func resolveSandboxMounts(config *AgentflowConfig, baseDir string) {
+	for name, agent := range config.Agents {
+		if agent.Sandbox == nil || len(agent.Sandbox.Mounts) == 0 {
+			continue
+		}
+		sandbox := *agent.Sandbox
+		sandbox.Mounts = make(StringList, len(agent.Sandbox.Mounts))
+		for i, mount := range agent.Sandbox.Mounts {
+			host, rest, ok := strings.Cut(mount, ":")
+			if ok && host != "" {
+				if home, err := os.UserHomeDir(); err == nil && (host == "~" || strings.HasPrefix(host, "~/")) {
+					host = filepath.Join(home, host[1:])
+				} else if !filepath.IsAbs(host) {
+					host = filepath.Join(baseDir, host)
+				}
+				mount = host + ":" + rest
+			}
+			sandbox.Mounts[i] = mount
+		}
+		agent.Sandbox = &sandbox
+		config.Agents[name] = agent
+	}
+}
+
+// resolveItemsFrom loads fan-out rows from items_from paths or matrix entries
+// into the Items field.
+func resolveItemsFrom(config *AgentflowConfig, baseDir string) error {
+	for name, task := range config.Tasks {
+		if len(task.Matrix) > 0 && task.ItemsFrom == "" {
+			items, err := MatrixItems(task.Matrix, baseDir)
+			if err != nil {
+				return fmt.Errorf("task %q: failed to expand matrix: %w", name, err)
+			}
+			task.Items = items
 			config.Tasks[name] = task
+			continue
+		}
+		if task.ItemsFrom == "" {
+			continue
+		}
+
+		itemsPath := task.ItemsFrom
+		if !filepath.IsAbs(itemsPath) {
+			itemsPath = filepath.Join(baseDir, itemsPath)
 		}
+
+		items, err := LoadItems(itemsPath)
+		if err != nil {
+			return fmt.Errorf("task %q: failed to load items_from %q: %w", name, task.ItemsFrom, err)
+		}
+
+		task.Items = items
+		config.Tasks[name] = task
+		config.files = append(config.files, itemsPath)
 	}
 	return nil
 }
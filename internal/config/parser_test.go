@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -267,6 +268,16 @@ func TestResolvePromptFiles(t *testing.T) {
 		t.Fatalf("failed to write prompt file: %v", err)
 	}
 
+	// Create prompt parts matched by a glob
+	if err := os.Mkdir(filepath.Join(tmpDir, "parts"), 0755); err != nil {
+		t.Fatalf("failed to create parts dir: %v", err)
+	}
+	for name, content := range map[string]string{"b.md": "Second part\n", "a.md": "First part\n"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, "parts", name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write prompt part: %v", err)
+		}
+	}
+
 	tests := []struct {
 		name        string
 		task        TaskConfig
@@ -315,6 +326,26 @@ func TestResolvePromptFiles(t *testing.T) {
 			wantErr: true,
 			wantErrContains: "failed to read prompt_file",
 		},
+		{
+			name: "glob prompt file",
+			task: TaskConfig{
+				Agent:      "agent1",
+				PromptFile: "parts/*.md",
+			},
+			baseDir:    tmpDir,
+			wantPrompt: "First part\n\nSecond part",
+			wantErr:    false,
+		},
+		{
+			name: "glob prompt file matching nothing",
+			task: TaskConfig{
+				Agent:      "agent1",
+				PromptFile: "parts/*.txt",
+			},
+			baseDir:    tmpDir,
+			wantPrompt: "",
+			wantErr:    false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -351,6 +382,44 @@ func TestResolvePromptFiles(t *testing.T) {
 	}
 }
 
+// TestParseConfig_Workdirs tests that workdirs are resolved relative to the config file.
+func TestParseConfig_Workdirs(t *testing.T) {
+	yamlData := `
+workdir: project
+agents:
+  coder:
+    tool: claude-code
+    workdir: ../shared
+  builder:
+    tool: shell
+tasks:
+  build:
+    agent: builder
+    command: make
+    workdir: /abs/build
+  review:
+    agent: coder
+    prompt: Review
+`
+	config, err := ParseConfig([]byte(yamlData), "/base")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Workdir != "/base/project" {
+		t.Errorf("expected workdir %q, got %q", "/base/project", config.Workdir)
+	}
+	if got := config.Agents["coder"].Workdir; got != "/shared" {
+		t.Errorf("expected agent workdir %q, got %q", "/shared", got)
+	}
+	if got := config.Agents["builder"].Workdir; got != "" {
+		t.Errorf("expected empty agent workdir, got %q", got)
+	}
+	if got := config.Tasks["build"].Workdir; got != "/abs/build" {
+		t.Errorf("expected task workdir %q, got %q", "/abs/build", got)
+	}
+}
+
 // TestFindCortexfile tests finding Cortexfile in directory.
 func TestFindCortexfile(t *testing.T) {
 	// Create a temporary directory for test files
@@ -599,3 +668,242 @@ tasks:
 		t.Errorf("architect model: expected opus, got %s", cfg.Agents["architect"].Model)
 	}
 }
+
+// TestParseConfig_Anchors tests that anchors, aliases, and `<<` merge keys
+// work for agents, tasks, list fields, and settings.
+func TestParseConfig_Anchors(t *testing.T) {
+	yamlData := `
+x-deps: &deps [build, lint]
+x-env: &env
+  LOG_LEVEL: debug
+x-review: &review
+  agent: reviewer
+  needs: *deps
+  artifacts: [report.md]
+  env: *env
+x-limits: &limits
+  max_parallel: "{{ 1 + 2 }}"
+agents:
+  base: &base
+    tool: claude-code
+    model: sonnet
+  reviewer:
+    <<: *base
+    model: opus
+tasks:
+  build:
+    agent: base
+    prompt: Build
+  lint:
+    agent: base
+    prompt: Lint
+  review-api:
+    <<: *review
+    prompt: Review the API
+  review-docs:
+    <<: *review
+    prompt: Review the docs
+    needs: build
+    env:
+      <<: *env
+      DOCS: "1"
+settings:
+  <<: *limits
+  parallel: true
+`
+	config, err := ParseConfig([]byte(yamlData), "/base")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := config.Agents["reviewer"]; got.Tool != "claude-code" || got.Model != "opus" {
+		t.Errorf("expected merged agent with tool claude-code and model opus, got %+v", got)
+	}
+
+	api := config.Tasks["review-api"]
+	if api.Agent != "reviewer" || api.Prompt != "Review the API" {
+		t.Errorf("expected merged task fields, got agent %q prompt %q", api.Agent, api.Prompt)
+	}
+	if !reflect.DeepEqual(api.Needs, StringList{"build", "lint"}) {
+		t.Errorf("expected needs from alias, got %v", api.Needs)
+	}
+	if !reflect.DeepEqual(api.Artifacts, StringList{"/base/report.md"}) {
+		t.Errorf("expected merged artifacts, got %v", api.Artifacts)
+	}
+	if api.Env["LOG_LEVEL"] != "debug" {
+		t.Errorf("expected env from alias, got %v", api.Env)
+	}
+
+	docs := config.Tasks["review-docs"]
+	if !reflect.DeepEqual(docs.Needs, StringList{"build"}) {
+		t.Errorf("expected needs to override merged value, got %v", docs.Needs)
+	}
+	if docs.Env["LOG_LEVEL"] != "debug" || docs.Env["DOCS"] != "1" {
+		t.Errorf("expected merged env, got %v", docs.Env)
+	}
+
+	if config.Settings == nil || config.Settings.MaxParallel != 3 || !config.Settings.Parallel {
+		t.Errorf("expected merged settings with max_parallel 3, got %+v", config.Settings)
+	}
+
+	if err := Validate(config); err != nil {
+		t.Errorf("expected anchored config to validate, got: %v", err)
+	}
+}
+
+// TestResolveSystemPromptFiles tests that system_prompt_file is loaded into
+// the system prompt of agents and tasks.
+func TestResolveSystemPromptFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "reviewer.md"), []byte("You review code."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{"ai": {Tool: "claude-code", SystemPromptFile: "reviewer.md"}},
+		Tasks:  map[string]TaskConfig{"review": {Agent: "ai", Prompt: "Review", SystemPromptFile: filepath.Join(dir, "reviewer.md")}},
+	}
+	if err := resolveSystemPromptFiles(config, dir); err != nil {
+		t.Fatalf("resolveSystemPromptFiles() error = %v", err)
+	}
+	if got := config.Agents["ai"].SystemPrompt; got != "You review code." {
+		t.Errorf("agent SystemPrompt = %q", got)
+	}
+	if got := config.Tasks["review"].SystemPrompt; got != "You review code." {
+		t.Errorf("task SystemPrompt = %q", got)
+	}
+
+	config = &AgentflowConfig{
+		Tasks: map[string]TaskConfig{"both": {Agent: "ai", SystemPrompt: "x", SystemPromptFile: "reviewer.md"}},
+	}
+	err := resolveSystemPromptFiles(config, dir)
+	if err == nil || !strings.Contains(err.Error(), `task "both": set either system_prompt or system_prompt_file`) {
+		t.Errorf("expected conflict error, got: %v", err)
+	}
+
+	config = &AgentflowConfig{
+		Agents: map[string]AgentConfig{"missing": {Tool: "api", SystemPromptFile: "missing.md"}},
+	}
+	err = resolveSystemPromptFiles(config, dir)
+	if err == nil || !strings.Contains(err.Error(), `agent "missing": failed to read system_prompt_file "missing.md"`) {
+		t.Errorf("expected read error, got: %v", err)
+	}
+}
+
+// TestAgentflowConfig_Privileged tests which workflows run commands or
+// change state.
+func TestAgentflowConfig_Privileged(t *testing.T) {
+	ai := map[string]AgentConfig{"ai": {Tool: "claude-code"}}
+	tests := []struct {
+		name   string
+		config AgentflowConfig
+		want   bool
+	}{
+		{"read-only AI task", AgentflowConfig{Agents: ai, Tasks: map[string]TaskConfig{"a": {Agent: "ai", Prompt: "Review"}}}, false},
+		{"wait task", AgentflowConfig{Tasks: map[string]TaskConfig{"w": {Wait: &WaitConfig{Duration: "1s"}}}}, false},
+		{"write task", AgentflowConfig{Agents: ai, Tasks: map[string]TaskConfig{"a": {Agent: "ai", Write: true}}}, true},
+		{"allowed tools", AgentflowConfig{Agents: ai, Tasks: map[string]TaskConfig{"a": {Agent: "ai", Permissions: &PermissionsConfig{Allow: StringList{"Bash"}}}}}, true},
+		{"shell agent", AgentflowConfig{Agents: map[string]AgentConfig{"sh": {Tool: "shell"}}, Tasks: map[string]TaskConfig{"a": {Agent: "sh", Command: "ls"}}}, true},
+		{"shell tool override", AgentflowConfig{Agents: ai, Tasks: map[string]TaskConfig{"a": {Agent: "ai", Tool: "shell", Command: "ls"}}}, true},
+		{"agent prompt hooks", AgentflowConfig{Agents: map[string]AgentConfig{"ai": {Tool: "claude-code", PromptHooks: StringList{"./hook"}}}, Tasks: map[string]TaskConfig{"a": {Agent: "ai"}}}, true},
+		{"settings prompt hooks", AgentflowConfig{Agents: ai, Tasks: map[string]TaskConfig{"a": {Agent: "ai"}}, Settings: &SettingsConfig{PromptHooks: StringList{"./hook"}}}, true},
+		{"script task", AgentflowConfig{Tasks: map[string]TaskConfig{"s": {Script: &ScriptConfig{Lang: "python", Code: "print(1)"}}}}, true},
+		{"http task", AgentflowConfig{Tasks: map[string]TaskConfig{"h": {HTTP: &HTTPConfig{URL: "https://example.com"}}}}, true},
+		{"git settings", AgentflowConfig{Agents: ai, Tasks: map[string]TaskConfig{"a": {Agent: "ai"}}, Git: &GitRunConfig{}}, true},
+		{"api agent", AgentflowConfig{Agents: map[string]AgentConfig{"api": {Tool: "api", Model: "gpt-4o"}}, Tasks: map[string]TaskConfig{"a": {Agent: "api"}}}, false},
+		{"api agent with default endpoint", AgentflowConfig{Agents: map[string]AgentConfig{"api": {Tool: "api", BaseURL: DefaultAPIBaseURL, APIKeyEnv: DefaultAPIKeyEnv}}, Tasks: map[string]TaskConfig{"a": {Agent: "api"}}}, false},
+		{"base_url", AgentflowConfig{Agents: map[string]AgentConfig{"api": {Tool: "api", BaseURL: "https://example.com/v1"}}, Tasks: map[string]TaskConfig{"a": {Agent: "api"}}}, true},
+		{"api_key_env", AgentflowConfig{Agents: map[string]AgentConfig{"api": {Tool: "api", APIKeyEnv: "AWS_SECRET_ACCESS_KEY"}}, Tasks: map[string]TaskConfig{"a": {Agent: "api"}}}, true},
+		{"proxy", AgentflowConfig{Agents: map[string]AgentConfig{"api": {Tool: "api", Proxy: "http://example.com:8080"}}, Tasks: map[string]TaskConfig{"a": {Agent: "api"}}}, true},
+		{"agent env", AgentflowConfig{Agents: map[string]AgentConfig{"ai": {Tool: "claude-code", Env: map[string]string{"ANTHROPIC_BASE_URL": "https://example.com"}}}, Tasks: map[string]TaskConfig{"a": {Agent: "ai"}}}, true},
+		{"task env", AgentflowConfig{Agents: ai, Tasks: map[string]TaskConfig{"a": {Agent: "ai", Env: map[string]string{"HTTPS_PROXY": "http://example.com"}}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.Privileged(); got != tt.want {
+				t.Errorf("Privileged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAgentflowConfig_FilesOutside tests which file references leave the
+// Cortexfile's directory.
+func TestAgentflowConfig_FilesOutside(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "project")
+	for _, d := range []string{dir, filepath.Join(dir, "prompts")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for path, content := range map[string]string{
+		filepath.Join(dir, "prompts", "review.md"): "Review",
+		filepath.Join(dir, "items.json"):           `["a"]`,
+		filepath.Join(root, "secret.txt"):          "secret",
+		filepath.Join(root, "shared.yml"):          "tasks:\n  shared:\n    agent: ai\n    prompt: Hi\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(root, "secret.txt"), filepath.Join(dir, "link.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "files inside",
+			yaml: "workdir: .\ntasks:\n  a: {agent: ai, prompt_file: prompts/review.md}\n  b: {agent: ai, prompt: 'Hi {{item}}', items_from: items.json}\n",
+		},
+		{
+			name: "prompt_file with ..",
+			yaml: "tasks:\n  a: {agent: ai, prompt_file: ../secret.txt}\n",
+			want: []string{filepath.Join(root, "secret.txt")},
+		},
+		{
+			name: "absolute system_prompt_file",
+			yaml: "tasks:\n  a: {agent: ai, prompt: Hi, system_prompt_file: " + filepath.Join(root, "secret.txt") + "}\n",
+			want: []string{filepath.Join(root, "secret.txt")},
+		},
+		{
+			name: "symlink leaving the directory",
+			yaml: "tasks:\n  a: {agent: ai, prompt_file: link.md}\n",
+			want: []string{filepath.Join(dir, "link.md")},
+		},
+		{
+			name: "tls file",
+			yaml: "tasks:\n  a: {agent: ai, prompt: Hi}\n  h: {http: {url: 'https://example.com', tls: {ca_file: ../ca.pem}}}\n",
+			want: []string{filepath.Join(root, "ca.pem")},
+		},
+		{
+			name: "include",
+			yaml: "include: [../shared.yml]\ntasks:\n  a: {agent: ai, prompt: Hi}\n",
+			want: []string{filepath.Join(root, "shared.yml")},
+		},
+		{
+			name: "workdir",
+			yaml: "workdir: /\ntasks:\n  a: {agent: ai, prompt: Hi}\n",
+			want: []string{"/"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "Cortexfile.yml")
+			if err := os.WriteFile(path, []byte("agents:\n  ai: {tool: claude-code}\n"+tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if got := cfg.FilesOutside(dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilesOutside() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
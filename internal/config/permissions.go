@@ -0,0 +1,63 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// PermissionsConfig limits what the tools of a claude-code task may do, in
+// place of the blanket permission write: true gives. Rules use Claude Code's
+// permission syntax, such as "Edit(src/**)" or "Bash(go test:*)"; paths are
+// gitignore-style patterns relative to the task's working directory.
+type PermissionsConfig struct {
+	Allow    StringList `yaml:"allow"`      // Tools the task may use without asking, e.g. "Edit(src/**)"
+	Deny     StringList `yaml:"deny"`       // Tools the task may not use, e.g. "Bash(rm:*)"
+	DenyPath StringList `yaml:"deny_paths"` // Paths no tool may read or edit
+	ReadOnly StringList `yaml:"read_only"`  // Paths that may be read but not edited
+}
+
+// permissionRuleRegex matches a tool permission rule: a tool name with an
+// optional specifier in parentheses.
+var permissionRuleRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*(\(.+\))?$`)
+
+// DeniedTools returns the rules of tools the task may not use: deny,
+// followed by Read and Edit rules for deny_paths and Edit rules for
+// read_only paths.
+func (p *PermissionsConfig) DeniedTools() []string {
+	rules := append([]string(nil), p.Deny...)
+	for _, path := range p.DenyPath {
+		rules = append(rules, "Read("+path+")", "Edit("+path+")")
+	}
+	for _, path := range p.ReadOnly {
+		rules = append(rules, "Edit("+path+")")
+	}
+	return rules
+}
+
+// validatePermissions checks the permissions of a task run with tool.
+func validatePermissions(filePath, name string, p *PermissionsConfig, tool string) []*ConfigError {
+	if p == nil {
+		return nil
+	}
+	var errs []*ConfigError
+	if tool != "" && tool != "claude-code" {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": 'permissions' only apply to claude-code tasks",
+			"Remove 'permissions', or run the task with 'tool: claude-code'"))
+	}
+	for _, rule := range append(append([]string(nil), p.Allow...), p.Deny...) {
+		if !permissionRuleRegex.MatchString(strings.TrimSpace(rule)) {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": invalid permission rule \""+rule+"\"",
+				"Use a tool name with an optional specifier, e.g. 'Read', 'Edit(src/**)', or 'Bash(go test:*)'"))
+		}
+	}
+	for _, path := range append(append([]string(nil), p.DenyPath...), p.ReadOnly...) {
+		if strings.TrimSpace(path) == "" || strings.ContainsAny(path, "()") {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": invalid permission path \""+path+"\"",
+				"Use a path or glob such as 'secrets/**' or '.env'"))
+		}
+	}
+	return errs
+}
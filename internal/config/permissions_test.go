@@ -0,0 +1,63 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestPermissionsConfig_DeniedTools tests that denied paths and read-only
+// paths become Read and Edit rules after the denied tools.
+func TestPermissionsConfig_DeniedTools(t *testing.T) {
+	p := &PermissionsConfig{
+		Deny:     StringList{"Bash(rm:*)"},
+		DenyPath: StringList{".env"},
+		ReadOnly: StringList{"go.mod"},
+	}
+	want := []string{"Bash(rm:*)", "Read(.env)", "Edit(.env)", "Edit(go.mod)"}
+	if got := p.DeniedTools(); !slices.Equal(got, want) {
+		t.Errorf("DeniedTools() = %v, want %v", got, want)
+	}
+}
+
+// TestValidate_Permissions tests the checks of task permissions.
+func TestValidate_Permissions(t *testing.T) {
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{
+			"ai": {Tool: "claude-code"},
+			"oc": {Tool: "opencode"},
+		},
+		Tasks: map[string]TaskConfig{
+			"docs": {Agent: "ai", Prompt: "Fix the docs", Write: true, Permissions: &PermissionsConfig{
+				Allow:    StringList{"Read", "Edit(docs/**)", "Bash(go test:*)"},
+				DenyPath: StringList{"secrets/**"},
+				ReadOnly: StringList{"go.mod"},
+			}},
+		},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	config.Tasks["other"] = TaskConfig{Agent: "oc", Prompt: "x", Permissions: &PermissionsConfig{Allow: StringList{"Read"}}}
+	config.Tasks["bad"] = TaskConfig{Agent: "ai", Prompt: "x", Permissions: &PermissionsConfig{
+		Deny:     StringList{"Edit src/**"},
+		ReadOnly: StringList{"Edit(go.mod)"},
+	}}
+	config.Tasks["wait"] = TaskConfig{Wait: &WaitConfig{Duration: "1s"}, Permissions: &PermissionsConfig{}}
+
+	err := Validate(config)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	for _, want := range []string{
+		`task "other": 'permissions' only apply to claude-code tasks`,
+		`task "bad": invalid permission rule "Edit src/**"`,
+		`task "bad": invalid permission path "Edit(go.mod)"`,
+		`task "wait": wait tasks cannot set`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// Task phases, set by listing tasks under setup or teardown.
+const (
+	PhaseSetup    = "setup"    // Runs before every other task
+	PhaseTeardown = "teardown" // Runs after every other task, even when the run failed or was cancelled
+)
+
+// TaskPhase returns the phase of a task: PhaseSetup, PhaseTeardown, or "" for
+// a regular task.
+func (c *AgentflowConfig) TaskPhase(name string) string {
+	switch {
+	case containsString(c.Setup, name):
+		return PhaseSetup
+	case containsString(c.Teardown, name):
+		return PhaseTeardown
+	}
+	return ""
+}
+
+// validatePhases checks the setup and teardown lists: they name defined
+// tasks, no task is in both, and needs don't cross phases in a way that
+// contradicts their order. Setup tasks can only need setup tasks, teardown
+// tasks only teardown tasks, and regular tasks can't need teardown tasks.
+func validatePhases(filePath string, config *AgentflowConfig, availableTasks []string) []*ConfigError {
+	var errs []*ConfigError
+	for _, phase := range []string{PhaseSetup, PhaseTeardown} {
+		names := config.Setup
+		if phase == PhaseTeardown {
+			names = config.Teardown
+		}
+		for _, name := range names {
+			if _, exists := config.Tasks[name]; !exists {
+				hint := "Available tasks: " + strings.Join(availableTasks, ", ")
+				if suggestion := SuggestClosestMatch(name, availableTasks); suggestion != "" {
+					hint = "Did you mean \"" + suggestion + "\"? " + hint
+				}
+				errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+					phase+": undefined task \""+name+"\"",
+					hint))
+			}
+		}
+	}
+	for _, name := range config.Setup {
+		if containsString(config.Teardown, name) {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\" is listed in both setup and teardown",
+				"Define separate tasks for bring-up and cleanup"))
+		}
+	}
+
+	names := make([]string, 0, len(config.Tasks))
+	for name := range config.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		phase := config.TaskPhase(name)
+		for _, dep := range config.Tasks[name].Needs {
+			depPhase := config.TaskPhase(dep)
+			switch {
+			case phase == PhaseSetup && depPhase != PhaseSetup:
+				errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+					"setup task \""+name+"\" cannot need \""+dep+"\", which is not a setup task",
+					"Setup tasks run before every other task; add \""+dep+"\" to setup or drop the need"))
+			case phase == PhaseTeardown && depPhase != PhaseTeardown:
+				errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+					"teardown task \""+name+"\" cannot need \""+dep+"\", which is not a teardown task",
+					"Teardown tasks already run after every other task, even failed ones; only list other teardown tasks in needs"))
+			case phase != PhaseTeardown && depPhase == PhaseTeardown:
+				errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+					"task \""+name+"\" cannot need teardown task \""+dep+"\"",
+					"Teardown tasks run after every other task"))
+			}
+		}
+	}
+	return errs
+}
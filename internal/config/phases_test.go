@@ -0,0 +1,76 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidatePhases tests the setup and teardown lists and the needs they
+// allow between phases.
+func TestValidatePhases(t *testing.T) {
+	tasks := map[string]TaskConfig{
+		"start": {Agent: "sh", Command: "start"},
+		"seed":  {Agent: "sh", Command: "seed", Needs: StringList{"start"}},
+		"build": {Agent: "sh", Command: "build", Needs: StringList{"seed"}},
+		"stop":  {Agent: "sh", Command: "stop"},
+		"clean": {Agent: "sh", Command: "clean", Needs: StringList{"stop"}},
+	}
+	withNeeds := func(name string, needs ...string) map[string]TaskConfig {
+		copied := make(map[string]TaskConfig, len(tasks))
+		for n, task := range tasks {
+			copied[n] = task
+		}
+		task := copied[name]
+		task.Needs = needs
+		copied[name] = task
+		return copied
+	}
+
+	tests := []struct {
+		name     string
+		tasks    map[string]TaskConfig
+		setup    StringList
+		teardown StringList
+		wantErr  string
+	}{
+		{"valid", tasks, StringList{"start", "seed"}, StringList{"stop", "clean"}, ""},
+		{"regular task needs setup task", withNeeds("build", "start"), StringList{"start", "seed"}, StringList{"stop", "clean"}, ""},
+		{"undefined setup task", tasks, StringList{"strat"}, nil, `setup: undefined task "strat"`},
+		{"undefined teardown task", tasks, nil, StringList{"missing"}, `teardown: undefined task "missing"`},
+		{"task in both", tasks, StringList{"start", "seed", "stop"}, StringList{"stop", "clean"}, "listed in both setup and teardown"},
+		{"setup needs regular task", tasks, StringList{"seed"}, nil, `setup task "seed" cannot need "start"`},
+		{"teardown needs regular task", withNeeds("stop", "build"), StringList{"start", "seed"}, StringList{"stop", "clean"}, `teardown task "stop" cannot need "build"`},
+		{"regular task needs teardown task", withNeeds("build", "clean"), StringList{"start", "seed"}, StringList{"stop", "clean"}, `task "build" cannot need teardown task "clean"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AgentflowConfig{
+				Agents:   map[string]AgentConfig{"sh": {Tool: "shell"}},
+				Tasks:    tt.tasks,
+				Setup:    tt.setup,
+				Teardown: tt.teardown,
+			}
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestTaskPhase tests looking up a task's phase.
+func TestTaskPhase(t *testing.T) {
+	cfg := &AgentflowConfig{Setup: StringList{"start"}, Teardown: StringList{"stop"}}
+	for name, want := range map[string]string{"start": PhaseSetup, "stop": PhaseTeardown, "build": ""} {
+		if got := cfg.TaskPhase(name); got != want {
+			t.Errorf("TaskPhase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// position is where a key is defined in a config file.
+type position struct {
+	line   int
+	column int
+}
+
+// positionSections maps the subjects of error messages, as in `task "build":`,
+// to the top-level sections that define them.
+var positionSections = map[string]string{
+	"agent":    "agents",
+	"task":     "tasks",
+	"template": "task_templates",
+	"stage":    "stages",
+	"input":    "inputs",
+	"trigger":  "triggers",
+}
+
+// subjectRegex matches the subject an error message starts with.
+var subjectRegex = regexp.MustCompile(`^(agent|task|template|stage|input|trigger) "([^"]+)"`)
+
+// keyPositions returns the positions of the names defined in each section
+// of a Cortexfile, by "<section>.<name>".
+func keyPositions(data []byte) map[string]position {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+
+	positions := make(map[string]position)
+	root := doc.Content[0]
+	for i := 0; root.Kind == yaml.MappingNode && i+1 < len(root.Content); i += 2 {
+		section, value := root.Content[i].Value, root.Content[i+1]
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+		if value.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			key := value.Content[j]
+			positions[section+"."+key.Value] = position{key.Line, key.Column}
+		}
+	}
+	return positions
+}
+
+// locateErrors sets the position of errors in filePath about an agent, task,
+// or other named definition to where it is defined.
+func locateErrors(errs []*ConfigError, filePath string, positions map[string]position) {
+	for _, e := range errs {
+		if e.Line != 0 || e.File != filePath {
+			continue
+		}
+		match := subjectRegex.FindStringSubmatch(e.Message)
+		if match == nil {
+			continue
+		}
+		if pos, ok := positions[positionSections[match[1]]+"."+match[2]]; ok {
+			e.Line, e.Column = pos.line, pos.column
+		}
+	}
+}
+
+// yamlLineRegex matches the line yaml.v3 puts in front of its errors.
+var yamlLineRegex = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// typeErrorRegex matches a value of the wrong type, as reported by yaml.v3.
+var typeErrorRegex = regexp.MustCompile("^cannot unmarshal (!!\\w+)(?: `(.*)`)? into (.+)$")
+
+// decodeErrors converts an error decoding a config file into config errors
+// at the lines yaml.v3 reports. Values of the wrong type are reported with
+// their key and column.
+func decodeErrors(filePath string, data []byte, err error) error {
+	errs := &ConfigErrors{}
+	var doc yaml.Node
+	if syntaxErr := yaml.Unmarshal(data, &doc); syntaxErr != nil {
+		line, msg := splitYAMLLine(syntaxErr.Error())
+		errs.Add(ErrYAMLParse(filePath, line, msg))
+		return errs
+	}
+
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+	for _, msg := range messages {
+		line, msg := splitYAMLLine(msg)
+		match := typeErrorRegex.FindStringSubmatch(msg)
+		if match == nil {
+			// Such as an invalid {{ expression }} setting
+			errs.Add(NewConfigError(filePath, line, msg))
+			continue
+		}
+		path, column := findValue(&doc, "", line, match[1])
+		e := NewConfigErrorWithHint(filePath, line,
+			typeMismatch(path, match[1], match[2], match[3]), typeHint(match[3]))
+		e.Column = column
+		errs.Add(e)
+	}
+	return errs
+}
+
+// splitYAMLLine splits the line number off a yaml.v3 error message, or
+// returns 0 if it has none.
+func splitYAMLLine(msg string) (int, string) {
+	msg = strings.TrimPrefix(msg, "yaml: ")
+	match := yamlLineRegex.FindStringSubmatch(msg)
+	if match == nil {
+		return 0, msg
+	}
+	line, _ := strconv.Atoi(match[1])
+	return line, match[2]
+}
+
+// findValue returns the dotted key path and column of the value with a tag
+// on a line, or an empty path if there is none.
+func findValue(node *yaml.Node, path string, line int, tag string) (string, int) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) > 0 {
+			return findValue(node.Content[0], path, line, tag)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Line == line && value.ShortTag() == tag {
+				return joinSchemaPath(path, key.Value), value.Column
+			}
+			if found, column := findValue(value, joinSchemaPath(path, key.Value), line, tag); found != "" {
+				return found, column
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			itemPath := path + "[" + strconv.Itoa(i) + "]"
+			if item.Line == line && item.ShortTag() == tag {
+				return itemPath, item.Column
+			}
+			if found, column := findValue(item, itemPath, line, tag); found != "" {
+				return found, column
+			}
+		}
+	}
+	return "", 0
+}
+
+// yamlTypeNames describes YAML tags and Go types in type mismatch errors.
+var yamlTypeNames = map[string]string{
+	"!!str":   "a string",
+	"!!int":   "an integer",
+	"!!float": "a number",
+	"!!bool":  "a boolean",
+	"!!seq":   "a list",
+	"!!map":   "a mapping",
+	"string":  "a string",
+	"bool":    "a boolean",
+	"float64": "a number",
+
+	"config.StringList": "a string or a list",
+}
+
+// goTypeName describes the Go type a YAML value is decoded into.
+func goTypeName(goType string) string {
+	goType = strings.TrimPrefix(goType, "*")
+	switch {
+	case yamlTypeNames[goType] != "":
+		return yamlTypeNames[goType]
+	case strings.HasPrefix(goType, "int") || strings.HasPrefix(goType, "uint"):
+		return "an integer"
+	case strings.HasPrefix(goType, "[]"):
+		return "a list"
+	default:
+		// Maps and structs
+		return "a mapping"
+	}
+}
+
+// typeMismatch describes a value of the wrong type.
+func typeMismatch(path, tag, value, goType string) string {
+	got := yamlTypeNames[tag]
+	if got == "" {
+		got = strings.TrimPrefix(tag, "!!")
+	}
+	if value != "" {
+		got += " (" + strconv.Quote(value) + ")"
+	}
+	msg := "expected " + goTypeName(goType) + ", got " + got
+	if path == "" {
+		return msg
+	}
+	return path + ": " + msg
+}
+
+// typeHint suggests how to write a value of a Go type.
+func typeHint(goType string) string {
+	switch goTypeName(goType) {
+	case "an integer":
+		return "Use a whole number, without quotes"
+	case "a number":
+		return "Use a number, without quotes"
+	case "a boolean":
+		return "Use true or false, without quotes"
+	case "a string":
+		return "Use a single value; quote it if it contains ': ' or starts with '[' or '{'"
+	case "a list", "a string or a list":
+		return "Use a list, such as [a, b] or one '- item' per line"
+	default:
+		return "Use a mapping of 'key: value' lines, indented under the key"
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import "testing"
+
+// TestParseConfig_DecodeErrors tests reporting values of the wrong type and
+// YAML syntax errors at their position.
+func TestParseConfig_DecodeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		wantErrs []string
+	}{
+		{
+			name: "type mismatches",
+			yaml: `
+tasks:
+  lint:
+    command: make lint
+    priority: high
+  build:
+    command: [make, all]
+    needs: { lint: true }
+    write: 1.5
+`,
+			wantErrs: []string{
+				`line 5: tasks.lint.priority: expected an integer, got a string ("high")` + "\n  Hint: Use a whole number",
+				`line 7: tasks.build.command: expected a string, got a list`,
+				`line 8: tasks.build.needs: expected a string or a list, got a mapping`,
+				`line 9: tasks.build.write: expected a boolean, got a number ("1.5")`,
+			},
+		},
+		{
+			name:     "syntax error",
+			yaml:     "tasks:\n  lint: {\n  build: x\n",
+			wantErrs: []string{"YAML parse error"},
+		},
+		{
+			name:     "invalid setting expression",
+			yaml:     "settings:\n  max_parallel: \"{{ cpu_count / 0 }}\"\n",
+			wantErrs: []string{`line 2: settings.max_parallel: expression "cpu_count / 0": division by zero`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tt.yaml), "/project")
+			valErr, ok := err.(*ConfigErrors)
+			if !ok {
+				t.Fatalf("expected *ConfigErrors, got %v", err)
+			}
+			if len(valErr.Errors) != len(tt.wantErrs) {
+				t.Errorf("got %d errors, want %d: %v", len(valErr.Errors), len(tt.wantErrs), valErr)
+			}
+			for _, want := range tt.wantErrs {
+				if !errorsContain(valErr, want) {
+					t.Errorf("expected error containing %q, got: %v", want, valErr)
+				}
+			}
+		})
+	}
+}
+
+// TestValidate_ErrorPositions tests pointing errors about agents, tasks, and
+// stages at their definitions.
+func TestValidate_ErrorPositions(t *testing.T) {
+	yaml := `
+agents:
+  sh: { tool: shell }
+  ai: { tool: claud }
+stages:
+  checks: { agent: sh }
+tasks:
+  lint:
+    agent: sh
+    command: make lint
+  build:
+    agent: sh
+    command: make
+    needs: [lnt]
+`
+	cfg, err := ParseConfig([]byte(yaml), "/project")
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+	valErr, ok := ValidateWithFile(cfg, "Cortexfile.yml").(*ConfigErrors)
+	if !ok {
+		t.Fatalf("expected *ConfigErrors")
+	}
+
+	for _, want := range []string{
+		`Cortexfile.yml:4:3: agent "ai" uses unsupported tool "claud"`,
+		`Cortexfile.yml:6:3: stage "checks": has no tasks`,
+		`Cortexfile.yml:11:3: task "build" depends on undefined task "lnt"`,
+	} {
+		if !errorsContain(valErr, want) {
+			t.Errorf("expected error containing %q, got: %v", want, valErr)
+		}
+	}
+}
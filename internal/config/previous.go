@@ -0,0 +1,61 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// previousRegex matches {{previous.taskname}} patterns, with the same
+// "| modifier N" suffixes as {{outputs.X}}.
+var previousRegex = regexp.MustCompile(`\{\{previous\.([a-zA-Z0-9_-]+)(\s*\|[^{}]*)?\}\}`)
+
+// ApplyPrevious substitutes {{previous.task}} with the outputs of an earlier
+// run (cortex run --after), in the same places as {{vars.name}}. References
+// to tasks the earlier run does not have are left as-is for validation to
+// report.
+func ApplyPrevious(config *AgentflowConfig, outputs map[string]string) {
+	if len(outputs) == 0 {
+		return
+	}
+	expandConfigText(config, func(text string) string {
+		return ExpandPrevious(text, outputs)
+	})
+}
+
+// ExpandPrevious replaces {{previous.task}} placeholders with outputs of an
+// earlier run. Unknown tasks and invalid modifiers are left as-is.
+func ExpandPrevious(text string, outputs map[string]string) string {
+	return previousRegex.ReplaceAllStringFunc(text, func(match string) string {
+		groups := previousRegex.FindStringSubmatch(match)
+		output, ok := outputs[groups[1]]
+		if !ok {
+			return match
+		}
+		mods, err := parseOutputModifiers(groups[2])
+		if err != nil {
+			return match
+		}
+		for _, mod := range mods {
+			output = mod.apply(output)
+		}
+		return output
+	})
+}
+
+// validatePreviousRefs reports {{previous.X}} placeholders left in a task,
+// which ApplyPrevious could not fill.
+func validatePreviousRefs(filePath, taskName string, task TaskConfig) []*ConfigError {
+	var errs []*ConfigError
+	for _, match := range previousRegex.FindAllStringSubmatch(taskVarText(task), -1) {
+		if _, err := parseOutputModifiers(match[2]); err != nil {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+taskName+"\": invalid template "+match[0]+": "+err.Error(),
+				"Supported modifiers: "+strings.Join(OutputModifiers, ", ")))
+			continue
+		}
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+taskName+"\": "+match[0]+" has no earlier run output",
+			"Pass --after <run-id> of a run with a task named \""+match[1]+"\""))
+	}
+	return errs
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestApplyPrevious tests filling {{previous.task}} from an earlier run.
+func TestApplyPrevious(t *testing.T) {
+	cfg := &AgentflowConfig{
+		Agents: map[string]AgentConfig{"sh": {Tool: "shell"}},
+		Tasks: map[string]TaskConfig{
+			"a": {Agent: "sh", Command: "echo {{previous.plan | tail 1}}"},
+			"b": {Agent: "sh", Command: "echo {{previous.review}}"},
+		},
+	}
+
+	ApplyPrevious(cfg, map[string]string{"plan": "one\ntwo\n"})
+
+	if got := cfg.Tasks["a"].Command; got != "echo two" {
+		t.Errorf("Command = %q, want %q", got, "echo two")
+	}
+	err := ValidateWithFile(cfg, "Cortexfile.yml")
+	if err == nil || !strings.Contains(err.Error(), `task "b": {{previous.review}} has no earlier run output`) {
+		t.Errorf("expected an error for the unfilled placeholder, got: %v", err)
+	}
+	if strings.Contains(err.Error(), `task "a"`) {
+		t.Errorf("unexpected error for task a: %v", err)
+	}
+}
@@ -0,0 +1,572 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// A Template is text with tags that are evaluated when a task runs:
+//
+//	{{ expr }}                        the value of an expression
+//	{{if expr}} ... {{else if expr}} ... {{else}} ... {{end}}
+//	{{range name in expr}} ... {{end}}  the body once per element of a list
+//
+// Expressions use the syntax of `when` conditions (see Expr) and can read
+// outputs, status, vars, env, artifacts, results (the instance results of a
+// fan-out task), and the built-in run, project, and git variables. Plain
+// references such as {{outputs.task}} are left to ExpandPrompt and the other
+// expanders, and tags that aren't valid expressions over these variables
+// are kept as text, so prompts can contain other template languages. A tag
+// on a line of its own takes the line with it.
+type Template struct {
+	nodes []tmplNode
+}
+
+// TemplateRef is a variable an expression in a template reads.
+type TemplateRef struct {
+	Name string // Variable, like outputs.review or vars.env
+	Line int    // Line of the tag, from 1
+}
+
+// templateRoots lists the variables expressions in templates can read.
+var templateRoots = []string{"outputs", "status", "vars", "env", "artifacts", "results", "run", "project", "git", "cpu_count"}
+
+// tagRegex matches a {{ }} tag.
+var tagRegex = regexp.MustCompile(`(?s)\{\{(.*?)\}\}`)
+
+// rangeRegex matches the inside of a {{range name in expr}} tag.
+var rangeRegex = regexp.MustCompile(`(?s)^range\s+([a-zA-Z_][a-zA-Z0-9_]*)\s+in\s+(.+)$`)
+
+// bareRefRegex matches an expression that is a single variable.
+var bareRefRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z0-9_*-]+)*$`)
+
+// tmplToken is a run of text or a tag. Text next to a tag that stands on a
+// line of its own loses that line's whitespace and newline.
+type tmplToken struct {
+	text      string // Text, or the whole tag
+	inner     string // Trimmed inside of a tag
+	tag       bool
+	line      int
+	trimLeft  bool // Drop leading blanks through the first newline
+	trimRight bool // Drop trailing blanks after the last newline
+}
+
+// value returns the text of a text token, trimmed as marked.
+func (t *tmplToken) value() string {
+	text := t.text
+	if t.trimLeft {
+		if i := strings.IndexByte(text, '\n'); i >= 0 && strings.TrimSpace(text[:i]) == "" {
+			text = text[i+1:]
+		} else if strings.TrimSpace(text) == "" {
+			text = ""
+		}
+	}
+	if t.trimRight {
+		i := strings.LastIndexByte(text, '\n')
+		if strings.TrimSpace(text[i+1:]) == "" {
+			text = text[:i+1]
+		}
+	}
+	return text
+}
+
+type tmplNode interface{}
+
+// tmplText is literal text.
+type tmplText struct{ tok *tmplToken }
+
+// tmplExpr outputs the value of an expression.
+type tmplExpr struct {
+	expr *Expr
+	line int
+}
+
+// tmplIf renders the body of the first condition that holds, or the else
+// body if there is one past the conditions.
+type tmplIf struct {
+	conds  []*Expr
+	lines  []int // Line of each condition's tag
+	bodies [][]tmplNode
+}
+
+// tmplRange renders its body for each element of a list, bound to name.
+type tmplRange struct {
+	name string
+	list *Expr
+	body []tmplNode
+	line int
+}
+
+// ParseTemplate parses the tags of a template. It fails on blocks without
+// {{end}} and on misplaced {{else}} tags within blocks.
+func ParseTemplate(text string) (*Template, error) {
+	p := &tmplParser{tokens: tokenizeTemplate(text)}
+	nodes, _, err := p.parseList(false)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{nodes: nodes}, nil
+}
+
+// RenderTemplate parses and renders text. Text without tags is returned
+// as-is.
+func RenderTemplate(text string, env ExprEnv) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := ParseTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Render(env)
+}
+
+// Render evaluates the template's tags against env.
+func (t *Template) Render(env ExprEnv) (string, error) {
+	var b strings.Builder
+	if err := renderNodes(&b, t.nodes, env); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Refs returns the variables the template's expressions read, except loop
+// variables, in order of appearance.
+func (t *Template) Refs() []TemplateRef {
+	var refs []TemplateRef
+	var walk func(nodes []tmplNode, loopVars []string)
+	add := func(expr *Expr, line int, loopVars []string) {
+		for _, name := range expr.Vars() {
+			root, _, _ := strings.Cut(name, ".")
+			if !slices.Contains(loopVars, root) {
+				refs = append(refs, TemplateRef{Name: name, Line: line})
+			}
+		}
+	}
+	walk = func(nodes []tmplNode, loopVars []string) {
+		for _, node := range nodes {
+			switch n := node.(type) {
+			case *tmplExpr:
+				add(n.expr, n.line, loopVars)
+			case *tmplIf:
+				for i, cond := range n.conds {
+					add(cond, n.lines[i], loopVars)
+				}
+				for _, body := range n.bodies {
+					walk(body, loopVars)
+				}
+			case *tmplRange:
+				add(n.list, n.line, loopVars)
+				walk(n.body, append(slices.Clone(loopVars), n.name))
+			}
+		}
+	}
+	walk(t.nodes, nil)
+	return refs
+}
+
+// outputRefs returns the variables the template outputs, in order of
+// appearance: those of plain {{vars.name}} tags and read by output tags, but
+// not by conditions, loop lists, or loop variables. With skipQuoted, variables only read through quote()
+// are left out.
+func (t *Template) outputRefs(skipQuoted bool) []TemplateRef {
+	var refs []TemplateRef
+	var walk func(nodes []tmplNode, loopVars []string)
+	walk = func(nodes []tmplNode, loopVars []string) {
+		for _, node := range nodes {
+			switch n := node.(type) {
+			case *tmplText:
+				// Plain {{vars.name}} tags, which ApplyVars substitutes
+				if m := varRegex.FindStringSubmatch(n.tok.text); n.tok.tag && m != nil {
+					refs = append(refs, TemplateRef{Name: "vars." + m[1], Line: n.tok.line})
+				}
+			case *tmplExpr:
+				seen := make(map[string]bool)
+				exprVars(n.expr.root, skipQuoted, seen)
+				for _, name := range slices.Sorted(maps.Keys(seen)) {
+					root, _, _ := strings.Cut(name, ".")
+					if !slices.Contains(loopVars, root) {
+						refs = append(refs, TemplateRef{Name: name, Line: n.line})
+					}
+				}
+			case *tmplIf:
+				for _, body := range n.bodies {
+					walk(body, loopVars)
+				}
+			case *tmplRange:
+				walk(n.body, append(slices.Clone(loopVars), n.name))
+			}
+		}
+	}
+	walk(t.nodes, nil)
+	return refs
+}
+
+// tokenizeTemplate splits text into text and tag tokens.
+func tokenizeTemplate(text string) []*tmplToken {
+	var tokens []*tmplToken
+	line, last := 1, 0
+	for _, loc := range tagRegex.FindAllStringSubmatchIndex(text, -1) {
+		if loc[0] > last {
+			tokens = append(tokens, &tmplToken{text: text[last:loc[0]], line: line})
+			line += strings.Count(text[last:loc[0]], "\n")
+		}
+		tokens = append(tokens, &tmplToken{
+			text:  text[loc[0]:loc[1]],
+			inner: strings.TrimSpace(text[loc[2]:loc[3]]),
+			tag:   true,
+			line:  line,
+		})
+		line += strings.Count(text[loc[0]:loc[1]], "\n")
+		last = loc[1]
+	}
+	if last < len(text) {
+		tokens = append(tokens, &tmplToken{text: text[last:], line: line})
+	}
+	return tokens
+}
+
+type tmplParser struct {
+	tokens   []*tmplToken
+	pos      int
+	loopVars []string
+}
+
+// parseList parses nodes up to the end of the text or, in a block, up to an
+// {{else}}, {{else if}}, or {{end}} tag, which it returns.
+func (p *tmplParser) parseList(inBlock bool) ([]tmplNode, *tmplToken, error) {
+	var nodes []tmplNode
+	for p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		p.pos++
+		if !tok.tag {
+			nodes = append(nodes, &tmplText{tok: tok})
+			continue
+		}
+
+		switch {
+		case tok.inner == "end" || tok.inner == "else" || strings.HasPrefix(tok.inner, "else if "):
+			if inBlock {
+				p.trimLine(p.pos - 1)
+				return nodes, tok, nil
+			}
+			// Kept as text, like the tags of other template languages
+			nodes = append(nodes, &tmplText{tok: tok})
+
+		case strings.HasPrefix(tok.inner, "if "):
+			cond, ok := p.parseExpr(strings.TrimPrefix(tok.inner, "if "))
+			if !ok {
+				nodes = append(nodes, &tmplText{tok: tok})
+				continue
+			}
+			p.trimLine(p.pos - 1)
+			node, err := p.parseIf(cond, tok)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, node)
+
+		case rangeRegex.MatchString(tok.inner):
+			m := rangeRegex.FindStringSubmatch(tok.inner)
+			list, ok := p.parseExpr(m[2])
+			if !ok {
+				nodes = append(nodes, &tmplText{tok: tok})
+				continue
+			}
+			p.trimLine(p.pos - 1)
+			p.loopVars = append(p.loopVars, m[1])
+			body, closer, err := p.parseList(true)
+			p.loopVars = p.loopVars[:len(p.loopVars)-1]
+			if err != nil {
+				return nil, nil, err
+			}
+			if closer == nil {
+				return nil, nil, fmt.Errorf("line %d: {{%s}} has no {{end}}", tok.line, tok.inner)
+			}
+			if closer.inner != "end" {
+				return nil, nil, fmt.Errorf("line %d: {{%s}} in {{range}}", closer.line, closer.inner)
+			}
+			nodes = append(nodes, &tmplRange{name: m[1], list: list, body: body, line: tok.line})
+
+		default:
+			expr, ok := p.parseExpr(tok.inner)
+			if ok && bareRefRegex.MatchString(tok.inner) {
+				// Plain references are left to the other expanders
+				root, _, _ := strings.Cut(tok.inner, ".")
+				ok = root == "env" || root == "cpu_count" || slices.Contains(p.loopVars, root)
+			}
+			if !ok {
+				nodes = append(nodes, &tmplText{tok: tok})
+				continue
+			}
+			nodes = append(nodes, &tmplExpr{expr: expr, line: tok.line})
+		}
+	}
+	return nodes, nil, nil
+}
+
+// parseIf parses the branches of an {{if}} tag up to its {{end}}.
+func (p *tmplParser) parseIf(cond *Expr, open *tmplToken) (*tmplIf, error) {
+	node := &tmplIf{}
+	line := open.line
+	for {
+		body, closer, err := p.parseList(true)
+		if err != nil {
+			return nil, err
+		}
+		if closer == nil {
+			return nil, fmt.Errorf("line %d: {{%s}} has no {{end}}", open.line, open.inner)
+		}
+		if cond != nil {
+			node.conds = append(node.conds, cond)
+			node.lines = append(node.lines, line)
+		}
+		node.bodies = append(node.bodies, body)
+
+		switch {
+		case closer.inner == "end":
+			return node, nil
+		case cond == nil:
+			return nil, fmt.Errorf("line %d: {{%s}} after {{else}}", closer.line, closer.inner)
+		case closer.inner == "else":
+			cond = nil
+		default:
+			src := strings.TrimPrefix(closer.inner, "else if ")
+			line = closer.line
+			var ok bool
+			if cond, ok = p.parseExpr(src); !ok {
+				return nil, fmt.Errorf("line %d: invalid condition in {{%s}}", closer.line, closer.inner)
+			}
+		}
+	}
+}
+
+// parseExpr parses the expression of a tag. It fails on expressions that
+// don't parse or read variables templates don't have.
+func (p *tmplParser) parseExpr(src string) (*Expr, bool) {
+	expr, err := ParseExpr(src)
+	if err != nil {
+		return nil, false
+	}
+	for _, name := range expr.Vars() {
+		root, _, _ := strings.Cut(name, ".")
+		if !slices.Contains(templateRoots, root) && !slices.Contains(p.loopVars, root) {
+			return nil, false
+		}
+	}
+	return expr, true
+}
+
+// trimLine marks the whitespace around the tag at index i for removal if
+// the tag is alone on its line.
+func (p *tmplParser) trimLine(i int) {
+	var before, after *tmplToken
+	if i > 0 && !p.tokens[i-1].tag {
+		before = p.tokens[i-1]
+	}
+	if i+1 < len(p.tokens) && !p.tokens[i+1].tag {
+		after = p.tokens[i+1]
+	}
+
+	startsLine := i == 0
+	if before != nil {
+		j := strings.LastIndexByte(before.text, '\n')
+		startsLine = strings.TrimSpace(before.text[j+1:]) == "" && (j >= 0 || i == 1)
+	}
+	endsLine := i == len(p.tokens)-1
+	if after != nil {
+		j := strings.IndexByte(after.text, '\n')
+		endsLine = (j >= 0 && strings.TrimSpace(after.text[:j]) == "") || (j < 0 && i+1 == len(p.tokens)-1 && strings.TrimSpace(after.text) == "")
+	}
+	if !startsLine || !endsLine {
+		return
+	}
+	if before != nil {
+		before.trimRight = true
+	}
+	if after != nil {
+		after.trimLeft = true
+	}
+}
+
+// renderNodes writes the rendered nodes to b.
+func renderNodes(b *strings.Builder, nodes []tmplNode, env ExprEnv) error {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *tmplText:
+			if n.tok.tag {
+				b.WriteString(n.tok.text)
+			} else {
+				b.WriteString(n.tok.value())
+			}
+		case *tmplExpr:
+			v, err := n.expr.Eval(env)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n.line, err)
+			}
+			b.WriteString(exprString(v))
+		case *tmplIf:
+			for i, body := range n.bodies {
+				if i < len(n.conds) {
+					ok, err := n.conds[i].EvalBool(env)
+					if err != nil {
+						return fmt.Errorf("line %d: %w", n.lines[i], err)
+					}
+					if !ok {
+						continue
+					}
+				}
+				if err := renderNodes(b, body, env); err != nil {
+					return err
+				}
+				break
+			}
+		case *tmplRange:
+			v, err := n.list.Eval(env)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n.line, err)
+			}
+			items, ok := exprList(v)
+			if !ok {
+				return fmt.Errorf("line %d: range over %s: not a list", n.line, n.list)
+			}
+			saved, had := env[n.name]
+			for _, item := range items {
+				env[n.name] = item
+				if err := renderNodes(b, n.body, env); err != nil {
+					return err
+				}
+			}
+			if had {
+				env[n.name] = saved
+			} else {
+				delete(env, n.name)
+			}
+		}
+	}
+	return nil
+}
+
+// TemplateData holds the values the templates of a task read when it runs.
+type TemplateData struct {
+	Outputs   map[string]string   // Task outputs
+	Statuses  map[string]string   // Task statuses
+	Vars      map[string]string   // Workflow variables
+	Env       map[string]string   // Task environment, over the process environment
+	Artifacts map[string][]string // Collected artifact paths by task
+	Groups    map[string][]string // Fan-out task -> instance names
+	Builtins  map[string]string   // Values of run.id and the other built-in variables
+}
+
+// ExprEnv returns the variables of the data for expressions. results.<task>
+// lists the instances of a fan-out task, each an object with its name,
+// output, and status.
+func (d TemplateData) ExprEnv() ExprEnv {
+	env := ConditionEnv(d.Outputs, d.Statuses)
+	for name, value := range d.Env {
+		env["env."+name] = value
+	}
+	for name, value := range d.Vars {
+		env["vars."+name] = value
+	}
+	for name, value := range d.Builtins {
+		env[name] = value
+	}
+	for name, paths := range d.Artifacts {
+		env["artifacts."+name] = exprStrings(paths)
+	}
+	for name, instances := range d.Groups {
+		results := make([]interface{}, len(instances))
+		for i, inst := range instances {
+			results[i] = map[string]interface{}{
+				"name":   inst,
+				"output": d.Outputs[inst],
+				"status": d.Statuses[inst],
+			}
+		}
+		env["results."+name] = results
+	}
+	return env
+}
+
+// validateTemplates checks the template tags of a task's prompt, command,
+// and script: that blocks are closed, and that the variables expressions
+// read are defined, with the line of the tag in the error.
+func validateTemplates(filePath, taskName string, task TaskConfig, config *AgentflowConfig, availableTasks []string) []*ConfigError {
+	var errs []*ConfigError
+	fields := []struct{ name, text string }{
+		{"prompt", task.Prompt},
+		{"command", task.Command},
+	}
+	if task.Script != nil {
+		fields = append(fields, struct{ name, text string }{"script", task.Script.Code})
+	}
+
+	for _, field := range fields {
+		if !strings.Contains(field.text, "{{") {
+			continue
+		}
+		tmpl, err := ParseTemplate(field.text)
+		if err != nil {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+taskName+"\": "+field.name+" "+err.Error(),
+				"Close {{if}} and {{range}} blocks with {{end}}"))
+			continue
+		}
+		for _, ref := range tmpl.Refs() {
+			msg, hint := checkTemplateRef(ref.Name, task, config, availableTasks)
+			if msg != "" {
+				errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+					fmt.Sprintf("task %q: %s line %d: %s", taskName, field.name, ref.Line, msg), hint))
+			}
+		}
+	}
+	return errs
+}
+
+// checkTemplateRef checks a variable read by a template expression of a
+// task. Returns an error message and hint, or empty strings if it's defined.
+func checkTemplateRef(name string, task TaskConfig, config *AgentflowConfig, availableTasks []string) (string, string) {
+	root, rest, _ := strings.Cut(name, ".")
+	key, _, _ := strings.Cut(rest, ".")
+
+	switch root {
+	case "outputs", "status", "artifacts", "results":
+		if key == "" {
+			return "undefined reference " + name, "Use " + root + ".<task>"
+		}
+		dep, exists := config.Tasks[key]
+		if !exists {
+			hint := "Define the task or fix the reference"
+			if suggestion := SuggestClosestMatch(key, availableTasks); suggestion != "" {
+				hint = "Did you mean " + root + "." + suggestion + "?"
+			}
+			return "undefined task \"" + key + "\" in " + name, hint
+		}
+		if root == "results" && !dep.IsFanOut() {
+			return "results." + key + " needs a fan-out task", "Set items_from or matrix on \"" + key + "\", or use outputs." + key
+		}
+		if !containsString(task.Needs, key) {
+			return name + " references \"" + key + "\" which is not in 'needs'",
+				"Add '" + key + "' to the 'needs' list to ensure it runs first"
+		}
+	case "vars":
+		_, isVar := config.Vars[key]
+		_, isInput := config.Inputs[key]
+		if !isVar && !isInput {
+			return "undefined variable " + name, "Define it under 'vars:' or pass --var " + key + "=<value>"
+		}
+	case "run", "project", "git":
+		if !IsBuiltinVar(root + "." + key) {
+			hint := "Available: " + strings.Join(BuiltinVars, ", ")
+			if suggestion := SuggestClosestMatch(root+"."+key, BuiltinVars); suggestion != "" {
+				hint = "Did you mean " + suggestion + "?"
+			}
+			return "undefined built-in variable " + name, hint
+		}
+	}
+	return "", ""
+}
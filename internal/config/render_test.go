@@ -0,0 +1,241 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// testTemplateData is the run state the template tests render against.
+func testTemplateData() ExprEnv {
+	return TemplateData{
+		Outputs: map[string]string{
+			"review":      `{"verdict": "fail", "issues": [{"file": "a.go"}, {"file": "b.go"}]}`,
+			"lint":        "ok\n",
+			"test[unit]":  "unit passed",
+			"test[integ]": "integ failed",
+		},
+		Statuses: map[string]string{
+			"lint":        "success",
+			"test[unit]":  "success",
+			"test[integ]": "failed",
+		},
+		Vars:      map[string]string{"env": "prod", "tags": "a,b,c"},
+		Env:       map[string]string{"HOME": "/home/me"},
+		Artifacts: map[string][]string{"build": {"dist/app", "dist/app.sig"}},
+		Groups:    map[string][]string{"test": {"test[unit]", "test[integ]"}},
+		Builtins:  map[string]string{BuiltinGitBranch: "main"},
+	}.ExprEnv()
+}
+
+// TestRenderTemplate tests conditionals, loops, and functions in templates.
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "no tags",
+			text: "Review the code",
+			want: "Review the code",
+		},
+		{
+			name: "if",
+			text: "Deploy{{if vars.env == 'prod'}} carefully{{end}}.",
+			want: "Deploy carefully.",
+		},
+		{
+			name: "else if",
+			text: "{{if status.lint == 'failed'}}fix lint{{else if git.branch == 'main'}}release{{else}}wait{{end}}",
+			want: "release",
+		},
+		{
+			name: "else",
+			text: "{{if vars.env == 'dev'}}dev{{else}}{{upper(vars.env)}}{{end}}",
+			want: "PROD",
+		},
+		{
+			name: "range over JSON field",
+			text: "{{range issue in outputs.review.issues}}[{{issue.file}}]{{end}}",
+			want: "[a.go][b.go]",
+		},
+		{
+			name: "range over results",
+			text: "Failures:\n{{range r in results.test}}\n{{if r.status == 'failed'}}\n- {{r.name}}: {{r.output}}\n{{end}}\n{{end}}\nDone",
+			want: "Failures:\n- test[integ]: integ failed\nDone",
+		},
+		{
+			name: "range over artifacts",
+			text: "{{range p in artifacts.build}}{{p}} {{end}}",
+			want: "dist/app dist/app.sig ",
+		},
+		{
+			name: "string functions",
+			text: "{{join(split(vars.tags, ','), ' | ')}} {{replace(vars.env, 'prod', 'production')}} {{trim(outputs.lint)}} {{len(lines('a\nb'))}}",
+			want: "a | b | c production ok 2",
+		},
+		{
+			name: "quote",
+			text: "echo {{quote(vars.env)}} {{quote(\"it's $HOME\")}}",
+			want: `echo 'prod' 'it'\''s $HOME'`,
+		},
+		{
+			name: "default",
+			text: "{{default(outputs.review.summary, 'none')}}",
+			want: "none",
+		},
+		{
+			name: "env",
+			text: "{{env.HOME}}",
+			want: "/home/me",
+		},
+		{
+			name: "plain references left for other expanders",
+			text: "{{outputs.lint}} {{vars.env}} {{run.id}}",
+			want: "{{outputs.lint}} {{vars.env}} {{run.id}}",
+		},
+		{
+			name: "other template languages kept",
+			text: "{{ .Name }} {{#each items}}{{this}}{{/each}} {{end}} {{unknown.var}}",
+			want: "{{ .Name }} {{#each items}}{{this}}{{/each}} {{end}} {{unknown.var}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.text, testTemplateData())
+			if err != nil {
+				t.Fatalf("RenderTemplate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRenderTemplate_Errors tests parse and evaluation errors with lines.
+func TestRenderTemplate_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{
+			name:    "unclosed if",
+			text:    "a\n{{if vars.env == 'prod'}}\nb",
+			wantErr: "line 2: {{if vars.env == 'prod'}} has no {{end}}",
+		},
+		{
+			name:    "unclosed range",
+			text:    "{{range i in artifacts.build}}",
+			wantErr: "line 1: {{range i in artifacts.build}} has no {{end}}",
+		},
+		{
+			name:    "else after else",
+			text:    "{{if vars.env}}\na\n{{else}}\nb\n{{else}}\nc\n{{end}}",
+			wantErr: "line 5: {{else}} after {{else}}",
+		},
+		{
+			name:    "range over a number",
+			text:    "x\n{{range i in len(vars.env)}}{{i}}{{end}}",
+			wantErr: "line 2: range over len(vars.env): not a list",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenderTemplate(tt.text, testTemplateData())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("RenderTemplate() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidate_Templates tests reporting undefined references in template
+// expressions, with their lines.
+func TestValidate_Templates(t *testing.T) {
+	agents := map[string]AgentConfig{"ai": {Tool: "claude-code"}, "sh": {Tool: "shell"}}
+
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		tasks   map[string]TaskConfig
+		wantErr string
+	}{
+		{
+			name: "valid",
+			vars: map[string]string{"env": "prod"},
+			tasks: map[string]TaskConfig{
+				"test": {Agent: "sh", Command: "go test {{item}}", Matrix: StringList{"./a", "./b"}},
+				"fix": {Agent: "ai", Needs: []string{"test"}, Prompt: "{{if vars.env == 'prod'}}\nCareful.\n{{end}}\n" +
+					"{{range r in results.test}}{{r.name}}: {{r.status}}\n{{end}}{{upper(git.branch)}}"},
+			},
+		},
+		{
+			name: "unclosed block",
+			tasks: map[string]TaskConfig{
+				"fix": {Agent: "ai", Prompt: "Fix\n{{if env.CI}}\nquietly"},
+			},
+			wantErr: `task "fix": prompt line 2: {{if env.CI}} has no {{end}}`,
+		},
+		{
+			name: "undefined task",
+			tasks: map[string]TaskConfig{
+				"lint": {Agent: "sh", Command: "make lint"},
+				"fix":  {Agent: "ai", Needs: []string{"lint"}, Prompt: "Fix\n\n{{if status.lnt == 'failed'}}lint{{end}}"},
+			},
+			wantErr: `task "fix": prompt line 3: undefined task "lnt" in status.lnt`,
+		},
+		{
+			name: "not in needs",
+			tasks: map[string]TaskConfig{
+				"lint": {Agent: "sh", Command: "make lint"},
+				"fix":  {Agent: "ai", Prompt: "{{if status.lint == 'failed'}}lint{{end}}"},
+			},
+			wantErr: "which is not in 'needs'",
+		},
+		{
+			name: "results of a single task",
+			tasks: map[string]TaskConfig{
+				"lint": {Agent: "sh", Command: "make lint"},
+				"fix":  {Agent: "ai", Needs: []string{"lint"}, Prompt: "{{range r in results.lint}}{{r.output}}{{end}}"},
+			},
+			wantErr: "results.lint needs a fan-out task",
+		},
+		{
+			name: "undefined variable",
+			tasks: map[string]TaskConfig{
+				"deploy": {Agent: "sh", Command: "echo start\n{{if vars.target == 'prod'}}make prod{{end}}"},
+			},
+			wantErr: `task "deploy": command line 2: undefined variable vars.target`,
+		},
+		{
+			name: "undefined built-in",
+			tasks: map[string]TaskConfig{
+				"fix": {Agent: "ai", Prompt: "{{if env.CI}}\nci\n{{else if git.brnch == 'main'}}\nmain\n{{end}}"},
+			},
+			wantErr: "prompt line 3: undefined built-in variable git.brnch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&AgentflowConfig{Agents: agents, Vars: tt.vars, Tasks: tt.tasks})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			valErr, ok := err.(*ConfigErrors)
+			if !ok {
+				t.Fatalf("expected *ConfigErrors, got %T", err)
+			}
+			if !errorsContain(valErr, tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, valErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionConfig limits the saved sessions of a project. After each run,
+// the oldest sessions beyond any limit are deleted.
+type RetentionConfig struct {
+	MaxSessions int    `yaml:"max_sessions"` // Most recent sessions to keep (0 = no limit)
+	MaxAge      string `yaml:"max_age"`      // Delete sessions older than this, e.g. 30d, 2w, 12h (empty = no limit)
+	MaxSize     string `yaml:"max_size"`     // Total disk size of the sessions, e.g. 500MB, 2GB (empty = no limit)
+}
+
+// IsZero reports whether no limit is set.
+func (r RetentionConfig) IsZero() bool {
+	return r.MaxSessions <= 0 && r.MaxAge == "" && r.MaxSize == ""
+}
+
+// Limits parses MaxAge and MaxSize; unset limits are 0.
+func (r RetentionConfig) Limits() (maxAge time.Duration, maxBytes int64, err error) {
+	if r.MaxAge != "" {
+		if maxAge, err = ParseAge(r.MaxAge); err != nil {
+			return 0, 0, err
+		}
+	}
+	if r.MaxSize != "" {
+		if maxBytes, err = ParseSize(r.MaxSize); err != nil {
+			return 0, 0, err
+		}
+	}
+	return maxAge, maxBytes, nil
+}
+
+// mergeRetention overrides the limits of base that local sets.
+func mergeRetention(base, local RetentionConfig) RetentionConfig {
+	if local.MaxSessions > 0 {
+		base.MaxSessions = local.MaxSessions
+	}
+	if local.MaxAge != "" {
+		base.MaxAge = local.MaxAge
+	}
+	if local.MaxSize != "" {
+		base.MaxSize = local.MaxSize
+	}
+	return base
+}
+
+// validateRetention checks the retention limits.
+func validateRetention(filePath string, r RetentionConfig) []*ConfigError {
+	var errs []*ConfigError
+	if r.MaxSessions < 0 {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"settings: retention max_sessions must not be negative",
+			"Use 0 or leave it out to keep any number of sessions"))
+	}
+	if r.MaxAge != "" {
+		if _, err := ParseAge(r.MaxAge); err != nil {
+			errs = append(errs, NewConfigError(filePath, 0, "settings: retention max_age: "+err.Error()))
+		}
+	}
+	if r.MaxSize != "" {
+		if _, err := ParseSize(r.MaxSize); err != nil {
+			errs = append(errs, NewConfigError(filePath, 0, "settings: retention max_size: "+err.Error()))
+		}
+	}
+	return errs
+}
+
+// ParseAge parses an age given in days (30d), weeks (2w), or as a Go
+// duration (12h).
+func ParseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			if count, err := strconv.Atoi(n); err == nil && count > 0 {
+				return time.Duration(count) * unit, nil
+			}
+			return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w, or 12h)", s)
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w, or 12h)", s)
+	}
+	return d, nil
+}
+
+// sizeUnits are the suffixes ParseSize accepts, longest first so that "MB"
+// isn't read as "B". Decimal and binary units are both powers of 1024, as
+// disk usage is usually reported.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseSize parses a size in bytes with an optional unit: 500MB, 2GB, 1.5G,
+// or 1048576.
+func ParseSize(s string) (int64, error) {
+	number, unit := strings.TrimSpace(s), int64(1)
+	for _, u := range sizeUnits {
+		if n, ok := strings.CutSuffix(strings.ToUpper(number), strings.ToUpper(u.suffix)); ok {
+			number, unit = strings.TrimSpace(n), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n <= 0 || n*float64(unit) >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 500MB or 2GB)", s)
+	}
+	return int64(n * float64(unit)), nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseAge tests parsing ages in days, weeks, and Go durations.
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"0d", 0, true},
+		{"-1h", 0, true},
+		{"xd", 0, true},
+		{"soon", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseAge(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseAge(%q) = %v, %v, want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestParseSize tests parsing sizes with and without units.
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"1048576", 1 << 20, false},
+		{"512B", 512, false},
+		{"500MB", 500 << 20, false},
+		{"2GB", 2 << 30, false},
+		{"2gib", 2 << 30, false},
+		{"1.5G", 3 << 29, false},
+		{"10 KB", 10 << 10, false},
+		{"0", 0, true},
+		{"-1GB", 0, true},
+		{"7XB", 0, true},
+		{"GB", 0, true},
+		{"inf", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSize(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseSize(%q) = %d, %v, want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestMergeRetention tests that the Cortexfile overrides the limits it sets.
+func TestMergeRetention(t *testing.T) {
+	global := &GlobalConfig{Settings: SettingsConfig{Retention: RetentionConfig{MaxSessions: 50, MaxAge: "30d"}}}
+	local := &AgentflowConfig{Settings: &SettingsConfig{Retention: RetentionConfig{MaxAge: "7d", MaxSize: "1GB"}}}
+
+	got := MergeConfigs(global, local, nil).Settings.Retention
+	want := RetentionConfig{MaxSessions: 50, MaxAge: "7d", MaxSize: "1GB"}
+	if got != want {
+		t.Errorf("merged retention = %+v, want %+v", got, want)
+	}
+}
+
+// TestValidateRetention tests the retention checks of settings validation.
+func TestValidateRetention(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention RetentionConfig
+		wantErrs  int
+	}{
+		{"unset", RetentionConfig{}, 0},
+		{"valid", RetentionConfig{MaxSessions: 20, MaxAge: "30d", MaxSize: "2GB"}, 0},
+		{"negative count", RetentionConfig{MaxSessions: -1}, 1},
+		{"invalid age and size", RetentionConfig{MaxAge: "soon", MaxSize: "big"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := validateRetention("Cortexfile.yml", tt.retention); len(errs) != tt.wantErrs {
+				t.Errorf("validateRetention() = %v, want %d errors", errs, tt.wantErrs)
+			}
+		})
+	}
+}
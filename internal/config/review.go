@@ -0,0 +1,77 @@
+package config
+
+import "strings"
+
+// ReviewTaskSuffix is appended to the name of a task with
+// review_changes_with to name the task that reviews its changes.
+const ReviewTaskSuffix = "-review"
+
+// Verdicts a reviewer ends its review with.
+const (
+	VerdictApprove = "APPROVE"
+	VerdictReject  = "REJECT"
+)
+
+// ReviewTaskName returns the name of the task reviewing the changes of task.
+func ReviewTaskName(task string) string {
+	return task + ReviewTaskSuffix
+}
+
+// ReviewPrompt returns the prompt of the task reviewing the changes of
+// task. The executor appends the diff.
+func ReviewPrompt(task string) string {
+	return "Review the changes task \"" + task + "\" made to the repository, shown in the diff below. " +
+		"Check them for bugs, security issues, and changes unrelated to the task. " +
+		"Don't modify any files.\n\n" +
+		"End your review with a line that is exactly \"VERDICT: " + VerdictApprove + "\" if the changes can be kept, " +
+		"or \"VERDICT: " + VerdictReject + "\" if they must be undone."
+}
+
+// ParseVerdict returns the verdict of a review: the last "VERDICT: X" line
+// of its output, or "" if there is none.
+func ParseVerdict(output string) string {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.Trim(strings.TrimSpace(lines[i]), "*_`")
+		rest, ok := strings.CutPrefix(strings.ToUpper(line), "VERDICT:")
+		if !ok {
+			continue
+		}
+		switch verdict := strings.Trim(strings.TrimSpace(rest), "*_`."); verdict {
+		case VerdictApprove, VerdictReject:
+			return verdict
+		}
+	}
+	return ""
+}
+
+// validateReview checks review_changes_with: the reviewer is a defined AI
+// agent, and the review task's name is free.
+func validateReview(filePath, name string, task TaskConfig, config *AgentflowConfig, availableAgents []string) []*ConfigError {
+	var errs []*ConfigError
+	if task.ReviewWith == "" {
+		if task.RollbackOnReject {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": rollback_on_reject requires review_changes_with",
+				"Add 'review_changes_with: <agent_name>' to have the changes reviewed"))
+		}
+		return errs
+	}
+
+	agent, exists := config.Agents[task.ReviewWith]
+	switch {
+	case !exists:
+		errs = append(errs, ErrUndefinedAgent(filePath, 0, name, task.ReviewWith, availableAgents))
+	case agent.Tool == "shell":
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": review_changes_with agent \""+task.ReviewWith+"\" is a shell agent",
+			"Reviews are prompts; use an AI agent, or add a task that checks the changes with a command"))
+	}
+
+	if _, exists := config.Tasks[ReviewTaskName(name)]; exists {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": review task \""+ReviewTaskName(name)+"\" conflicts with a defined task",
+			"Rename the task \""+ReviewTaskName(name)+"\""))
+	}
+	return errs
+}
@@ -0,0 +1,97 @@
+package config
+
+import "testing"
+
+// TestParseVerdict tests reading the verdict at the end of a review.
+func TestParseVerdict(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"approve", "Looks good.\nVERDICT: APPROVE\n", VerdictApprove},
+		{"reject", "Breaks the build.\nVERDICT: REJECT", VerdictReject},
+		{"markdown", "Fine.\n\n**VERDICT: approve**\n", VerdictApprove},
+		{"last wins", "VERDICT: APPROVE\nOn second thought:\nVERDICT: REJECT\n", VerdictReject},
+		{"none", "Looks good to me.\n", ""},
+		{"unknown", "VERDICT: MAYBE\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseVerdict(tt.output); got != tt.want {
+				t.Errorf("ParseVerdict() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidate_Review tests checking review_changes_with.
+func TestValidate_Review(t *testing.T) {
+	agents := map[string]AgentConfig{
+		"coder":    {Tool: "claude-code"},
+		"reviewer": {Tool: "claude-code"},
+		"sh":       {Tool: "shell"},
+	}
+
+	tests := []struct {
+		name    string
+		tasks   map[string]TaskConfig
+		wantErr string
+	}{
+		{
+			name: "valid",
+			tasks: map[string]TaskConfig{
+				"fix": {Agent: "coder", Prompt: "Fix it", ReviewWith: "reviewer", RollbackOnReject: true},
+			},
+		},
+		{
+			name: "undefined reviewer",
+			tasks: map[string]TaskConfig{
+				"fix": {Agent: "coder", Prompt: "Fix it", ReviewWith: "reviewr"},
+			},
+			wantErr: "undefined agent",
+		},
+		{
+			name: "shell reviewer",
+			tasks: map[string]TaskConfig{
+				"fix": {Agent: "coder", Prompt: "Fix it", ReviewWith: "sh"},
+			},
+			wantErr: "is a shell agent",
+		},
+		{
+			name: "rollback without review",
+			tasks: map[string]TaskConfig{
+				"fix": {Agent: "coder", Prompt: "Fix it", RollbackOnReject: true},
+			},
+			wantErr: "rollback_on_reject requires review_changes_with",
+		},
+		{
+			name: "review task name taken",
+			tasks: map[string]TaskConfig{
+				"fix":        {Agent: "coder", Prompt: "Fix it", ReviewWith: "reviewer"},
+				"fix-review": {Agent: "reviewer", Prompt: "Review it"},
+			},
+			wantErr: "conflicts with a defined task",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&AgentflowConfig{Agents: agents, Tasks: tt.tasks})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			valErr, ok := err.(*ConfigErrors)
+			if !ok {
+				t.Fatalf("expected *ConfigErrors, got %T", err)
+			}
+			if !errorsContain(valErr, tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, valErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SandboxConfig runs an agent's tasks in a container instead of on the
+// host: the agent's CLI, or a shell agent's commands, run in a new container
+// of the image for each task, which sees only the task's working directory
+// and the mounts.
+type SandboxConfig struct {
+	Type    string     `yaml:"type"`    // Sandbox kind: docker (default)
+	Image   string     `yaml:"image"`   // Image with the agent's CLI installed
+	Mounts  StringList `yaml:"mounts"`  // Extra volumes as host:container[:ro], host paths relative to the Cortexfile
+	Network string     `yaml:"network"` // Network access: bridge (default), none, or host
+	CPUs    string     `yaml:"cpus"`    // CPU limit, e.g. "2" or "0.5" (default: none)
+	Memory  string     `yaml:"memory"`  // Memory limit, e.g. "512m" or "4g" (default: none)
+	User    string     `yaml:"user"`    // User the container runs as, e.g. "1000:1000" (default: the image's)
+}
+
+// Sandbox types and network modes.
+const (
+	SandboxDocker  = "docker"
+	NetworkBridge  = "bridge"
+	NetworkNone    = "none"
+	NetworkHost    = "host"
+	DefaultNetwork = NetworkBridge
+)
+
+// SupportedSandboxTypes lists all valid sandbox types.
+var SupportedSandboxTypes = []string{SandboxDocker}
+
+// SupportedSandboxNetworks lists all valid sandbox network modes.
+var SupportedSandboxNetworks = []string{NetworkBridge, NetworkNone, NetworkHost}
+
+// memoryRegex matches a memory limit: a number with an optional b, k, m,
+// or g unit.
+var memoryRegex = regexp.MustCompile(`^[0-9]+[bkmgBKMG]?$`)
+
+// validateSandbox checks the sandbox of an agent.
+func validateSandbox(filePath, name string, agent AgentConfig) []*ConfigError {
+	s := agent.Sandbox
+	if s == nil {
+		return nil
+	}
+	prefix := "agent \"" + name + "\": "
+	var errs []*ConfigError
+	if agent.Tool == "api" {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			prefix+"'sandbox' doesn't apply to api agents",
+			"api agents call their endpoint without running a process; remove 'sandbox'"))
+	}
+	if s.Type != "" && !containsString(SupportedSandboxTypes, s.Type) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			prefix+"unsupported sandbox type \""+s.Type+"\"",
+			"Supported sandbox types: "+strings.Join(SupportedSandboxTypes, ", ")))
+	}
+	if strings.TrimSpace(s.Image) == "" {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			prefix+"sandbox requires 'image'",
+			"Add 'image: ...' with an image that has the agent's CLI installed"))
+	}
+	for _, mount := range s.Mounts {
+		parts := strings.Split(mount, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || !strings.HasPrefix(parts[1], "/") ||
+			(len(parts) == 3 && parts[2] != "ro" && parts[2] != "rw") {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				prefix+"invalid sandbox mount \""+mount+"\"",
+				"Use host:container or host:container:ro, e.g. '~/.claude:/root/.claude:ro'"))
+		}
+	}
+	if s.Network != "" && !containsString(SupportedSandboxNetworks, s.Network) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			prefix+"unsupported sandbox network \""+s.Network+"\"",
+			"Supported networks: "+strings.Join(SupportedSandboxNetworks, ", ")))
+	}
+	if cpus, err := strconv.ParseFloat(s.CPUs, 64); s.CPUs != "" && (err != nil || cpus <= 0) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			prefix+"invalid sandbox cpus \""+s.CPUs+"\"",
+			"Use a positive number of CPUs, e.g. '2' or '0.5'"))
+	}
+	if s.Memory != "" && !memoryRegex.MatchString(s.Memory) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			prefix+"invalid sandbox memory \""+s.Memory+"\"",
+			"Use a size such as '512m' or '4g'"))
+	}
+	return errs
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidate_Sandbox tests the checks of agent sandboxes.
+func TestValidate_Sandbox(t *testing.T) {
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{"coder": {Tool: "claude-code", Sandbox: &SandboxConfig{
+			Image:   "ghcr.io/acme/claude-code:latest",
+			Mounts:  StringList{"/home/me/.claude:/root/.claude:ro", "/cache:/cache"},
+			Network: "none",
+			CPUs:    "0.5",
+			Memory:  "4g",
+		}}},
+		Tasks: map[string]TaskConfig{"fix": {Agent: "coder", Prompt: "Fix it", Write: true}},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	config.Agents["bad"] = AgentConfig{Tool: "shell", Sandbox: &SandboxConfig{
+		Type:    "podman",
+		Mounts:  StringList{"/data", "/data:data", "/data:/data:rx"},
+		Network: "internet",
+		CPUs:    "0",
+		Memory:  "4 GB",
+	}}
+	config.Agents["llm"] = AgentConfig{Tool: "api", Model: "gpt-4o", Sandbox: &SandboxConfig{Image: "alpine"}}
+	config.Tasks["switch"] = TaskConfig{Agent: "coder", Prompt: "x", Tool: "api", Model: "gpt-4o"}
+
+	err := Validate(config)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	for _, want := range []string{
+		`agent "bad": unsupported sandbox type "podman"`,
+		`agent "bad": sandbox requires 'image'`,
+		`agent "bad": invalid sandbox mount "/data"`,
+		`agent "bad": invalid sandbox mount "/data:data"`,
+		`agent "bad": invalid sandbox mount "/data:/data:rx"`,
+		`agent "bad": unsupported sandbox network "internet"`,
+		`agent "bad": invalid sandbox cpus "0"`,
+		`agent "bad": invalid sandbox memory "4 GB"`,
+		`agent "llm": 'sandbox' doesn't apply to api agents`,
+		`task "switch": api tasks can't run in the sandbox of agent "coder"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
+
+// TestResolveSandboxMounts tests that the host paths of sandbox mounts are
+// resolved from the Cortexfile directory and the home directory.
+func TestResolveSandboxMounts(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	dir := t.TempDir()
+	sandbox := &SandboxConfig{Image: "alpine", Mounts: StringList{"cache:/cache", "~/.claude:/root/.claude:ro", "/data:/data"}}
+	config := &AgentflowConfig{Agents: map[string]AgentConfig{"coder": {Tool: "claude-code", Sandbox: sandbox}}}
+	resolveSandboxMounts(config, dir)
+
+	got := config.Agents["coder"].Sandbox.Mounts
+	want := []string{filepath.Join(dir, "cache") + ":/cache", filepath.Join(home, ".claude") + ":/root/.claude:ro", "/data:/data"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Mounts[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
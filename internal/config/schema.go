@@ -0,0 +1,337 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config files a JSON Schema can be generated for.
+const (
+	SchemaCortexfile = "cortexfile" // Cortexfile.yml
+	SchemaMaster     = "master"     // MasterCortex.yml
+	SchemaGlobal     = "global"     // ~/.cortex/config.yml
+)
+
+// SchemaTypes lists all config files a schema can be generated for.
+var SchemaTypes = []string{SchemaCortexfile, SchemaMaster, SchemaGlobal}
+
+// schemaRoots maps each schema type to its root struct and title.
+var schemaRoots = map[string]struct {
+	typ   reflect.Type
+	title string
+}{
+	SchemaCortexfile: {reflect.TypeOf(AgentflowConfig{}), "Cortexfile"},
+	SchemaMaster:     {reflect.TypeOf(MasterConfig{}), "MasterCortex"},
+	SchemaGlobal:     {reflect.TypeOf(GlobalConfig{}), "Cortex global config"},
+}
+
+// extensionKeyPattern matches top-level keys that are ignored by every
+// config file, so they can hold shared YAML anchors.
+const extensionKeyPattern = "^x-"
+
+var extensionKeyRegex = regexp.MustCompile(extensionKeyPattern)
+
+// schemaEnums lists the values of string fields that only accept fixed
+// values, by "<struct>.<key>".
+var schemaEnums = map[string][]string{
+	"TaskConfig.show_output":   SupportedShowOutputModes,
+	"TaskConfig.output_format": OutputFormats,
+	"ScriptConfig.lang":        SupportedScriptLangs,
+	"GitConfig.op":             SupportedGitOps,
+	"NotifyConfig.level":       SupportedNotifyLevels,
+	"InputConfig.type":         SupportedInputTypes,
+	"TriggerConfig.type":       SupportedTriggerTypes,
+	"SettingsConfig.output":    SupportedOutputModes,
+	"SettingsConfig.color":     SupportedColorModes,
+	"ToolConfig.output":        SupportedToolOutputs,
+	"ThemeConfig.palette":      SupportedPalettes,
+	"LogConfig.level":          SupportedLogLevels,
+	"LogConfig.format":         SupportedLogFormats,
+	"ServerUser.role":          SupportedRoles,
+	"MasterConfig.mode":        {"sequential", "parallel"},
+}
+
+// GenerateSchema returns the JSON Schema (draft-07) of a config file, built
+// from the fields of its Go structs. Nested mappings reject unknown keys;
+// the top level also accepts x- prefixed ones.
+func GenerateSchema(schemaType string) (map[string]any, error) {
+	root, ok := schemaRoots[schemaType]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema type %q (use %s)", schemaType, strings.Join(SchemaTypes, ", "))
+	}
+
+	b := &schemaBuilder{defs: make(map[string]any)}
+	schema := b.structSchema(root.typ)
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = root.title
+	schema["patternProperties"] = map[string]any{extensionKeyPattern: map[string]any{}}
+	schema["definitions"] = b.defs
+	return schema, nil
+}
+
+// schemaBuilder collects the definitions of the structs a schema uses.
+type schemaBuilder struct {
+	defs map[string]any
+}
+
+// typeSchema returns the schema of a Go type. Structs become references to
+// their definition.
+func (b *schemaBuilder) typeSchema(t reflect.Type) map[string]any {
+	switch t {
+	case reflect.TypeOf(StringList{}):
+		// A single string or a list of them
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		}}
+	case reflect.TypeOf(IncludeConfig{}):
+		// A path, or a mapping with the path and namespace
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "string"},
+			b.ref(t),
+		}}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.typeSchema(t.Elem())
+	case reflect.Struct:
+		return b.ref(t)
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.typeSchema(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.typeSchema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		// interface{}: any value
+		return map[string]any{}
+	}
+}
+
+// ref adds the definition of a struct, if it is new, and returns a
+// reference to it.
+func (b *schemaBuilder) ref(t reflect.Type) map[string]any {
+	if _, ok := b.defs[t.Name()]; !ok {
+		b.defs[t.Name()] = nil // Reserved while the fields are built
+		b.defs[t.Name()] = b.structSchema(t)
+	}
+	return map[string]any{"$ref": "#/definitions/" + t.Name()}
+}
+
+// structSchema returns the object schema of a struct, with a property per
+// YAML key.
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	for key, field := range yamlFields(t) {
+		props[key] = b.fieldSchema(t, key, field.Type)
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+// fieldSchema returns the schema of the key of a struct, with the values a
+// string field accepts and the {{ expression }} strings numeric settings
+// accept, and the statuses theme glyphs can be set for.
+func (b *schemaBuilder) fieldSchema(owner reflect.Type, key string, t reflect.Type) map[string]any {
+	schema := b.typeSchema(t)
+	if values := schemaEnums[owner.Name()+"."+key]; len(values) > 0 {
+		schema["enum"] = values
+	}
+	if owner == reflect.TypeOf(SettingsConfig{}) {
+		if _, ok := settingsExprFields[key]; ok {
+			schema["type"] = []string{schema["type"].(string), "string"}
+		}
+	}
+	if (owner == reflect.TypeOf(AgentConfig{}) || owner == reflect.TypeOf(TaskConfig{})) && key == "tool" {
+		// Custom tools of the global config are valid too
+		schema["enum"] = SupportedToolNames()
+	}
+	if owner == reflect.TypeOf(ThemeConfig{}) && key == "glyphs" {
+		schema["propertyNames"] = map[string]any{"enum": SupportedGlyphStatuses}
+	}
+	return schema
+}
+
+// yamlFields returns the struct fields decoded from YAML, by key. Fields
+// tagged yaml:"-" are left out.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		switch key {
+		case "-":
+			continue
+		case "":
+			key = strings.ToLower(field.Name)
+		}
+		fields[key] = field
+	}
+	return fields
+}
+
+// unknownField is a mapping key that the schema of its mapping doesn't
+// define, most often a misspelled one.
+type unknownField struct {
+	file   string   // File the key is in (empty: the validated one)
+	path   string   // Dotted path of the mapping, e.g. tasks.build (empty: top level)
+	key    string   // The key
+	line   int      // Line of the key
+	column int      // Column of the key
+	known  []string // Keys the mapping accepts
+}
+
+// message describes the unknown key.
+func (f unknownField) message() string {
+	if f.path == "" {
+		return "unknown top-level field \"" + f.key + "\""
+	}
+	return "unknown field \"" + f.key + "\" in " + f.path
+}
+
+// hint suggests the closest known key, or lists them all.
+func (f unknownField) hint() string {
+	if suggestion := SuggestClosestMatch(f.key, f.known); suggestion != "" {
+		return "Did you mean \"" + suggestion + "\"?"
+	}
+	hint := "Known fields: " + strings.Join(f.known, ", ")
+	if f.path == "" {
+		hint += " (prefix other top-level keys with x-)"
+	}
+	return hint
+}
+
+// err returns the unknown key as an error, for config files loaded without
+// validation.
+func (f unknownField) err() error {
+	return fmt.Errorf("line %d: %s (%s)", f.line, f.message(), f.hint())
+}
+
+// findUnknownFields returns the keys of a YAML document that the schema of
+// the config file doesn't define, following aliases and `<<` merge keys.
+func findUnknownFields(data []byte, schemaType string) ([]unknownField, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	schema, err := GenerateSchema(schemaType)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &schemaChecker{defs: schema["definitions"].(map[string]any)}
+	c.check(doc.Content[0], schema, "")
+	return c.found, nil
+}
+
+// schemaChecker walks a YAML document along a generated schema.
+type schemaChecker struct {
+	defs  map[string]any
+	found []unknownField
+}
+
+// check records the unknown keys of node and its children.
+func (c *schemaChecker) check(node *yaml.Node, schema map[string]any, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	schema = c.resolve(schema, node)
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		props, _ := schema["properties"].(map[string]any)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.ShortTag() == "!!merge" {
+				// The merged value is a mapping, an alias, or a list of them
+				merged := []*yaml.Node{value}
+				if value.Kind == yaml.SequenceNode {
+					merged = value.Content
+				}
+				for _, m := range merged {
+					c.check(m, schema, path)
+				}
+				continue
+			}
+
+			if prop, ok := props[key.Value].(map[string]any); ok {
+				c.check(value, prop, joinSchemaPath(path, key.Value))
+				continue
+			}
+			if _, ok := schema["patternProperties"]; ok && extensionKeyRegex.MatchString(key.Value) {
+				continue
+			}
+			switch addl := schema["additionalProperties"].(type) {
+			case map[string]any:
+				c.check(value, addl, joinSchemaPath(path, key.Value))
+			case bool:
+				if !addl {
+					c.found = append(c.found, unknownField{
+						path:   path,
+						key:    key.Value,
+						line:   key.Line,
+						column: key.Column,
+						known:  slices.Sorted(maps.Keys(props)),
+					})
+				}
+			}
+		}
+	case yaml.SequenceNode:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range node.Content {
+				c.check(item, items, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+}
+
+// resolve follows a schema's reference, and picks the alternative of a
+// oneOf that matches the kind of node.
+func (c *schemaChecker) resolve(schema map[string]any, node *yaml.Node) map[string]any {
+	if ref, ok := schema["$ref"].(string); ok {
+		return c.resolve(c.defs[strings.TrimPrefix(ref, "#/definitions/")].(map[string]any), node)
+	}
+	alternatives, ok := schema["oneOf"].([]any)
+	if !ok {
+		return schema
+	}
+	want := map[yaml.Kind]string{yaml.MappingNode: "object", yaml.SequenceNode: "array"}[node.Kind]
+	for _, alt := range alternatives {
+		alt := c.resolve(alt.(map[string]any), node)
+		if alt["type"] == want {
+			return alt
+		}
+	}
+	return map[string]any{}
+}
+
+// joinSchemaPath appends a key to a dotted path.
+func joinSchemaPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGenerateSchema tests building the schema of each config file from
+// its structs.
+func TestGenerateSchema(t *testing.T) {
+	schema, err := GenerateSchema(SchemaCortexfile)
+	if err != nil {
+		t.Fatalf("GenerateSchema() error = %v", err)
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+	for _, want := range []string{
+		`"tasks":{"additionalProperties":{"$ref":"#/definitions/TaskConfig"},"type":"object"}`,
+		`"needs":{"oneOf":[{"type":"string"},{"items":{"type":"string"},"type":"array"}]}`,
+		`"tool":{"enum":["claude-code","opencode","aider","api","shell"],"type":"string"}`,
+		`"max_parallel":{"type":["integer","string"]}`,
+		`"patternProperties":{"^x-":{}}`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("schema doesn't contain %s", want)
+		}
+	}
+	// Fields not read from YAML are left out
+	props := schema["definitions"].(map[string]any)["TaskConfig"].(map[string]any)["properties"].(map[string]any)
+	for _, key := range []string{"PromptFiles", "promptfiles", "items", "review_of"} {
+		if _, ok := props[key]; ok {
+			t.Errorf("TaskConfig schema has %q", key)
+		}
+	}
+
+	for _, schemaType := range []string{SchemaMaster, SchemaGlobal} {
+		if _, err := GenerateSchema(schemaType); err != nil {
+			t.Errorf("GenerateSchema(%q) error = %v", schemaType, err)
+		}
+	}
+	if _, err := GenerateSchema("cortex"); err == nil {
+		t.Error("expected error for unknown schema type")
+	}
+}
+
+// TestFindUnknownFields_Templates tests that the files `cortex init`
+// writes only use known fields.
+func TestFindUnknownFields_Templates(t *testing.T) {
+	tests := []struct {
+		template   string
+		schemaType string
+	}{
+		{CortexfileTemplate, SchemaCortexfile},
+		{MinimalCortexfileTemplate, SchemaCortexfile},
+		{MasterCortexTemplate, SchemaMaster},
+		{GlobalConfigTemplate, SchemaGlobal},
+	}
+	for _, tt := range tests {
+		unknown, err := findUnknownFields([]byte(tt.template), tt.schemaType)
+		if err != nil {
+			t.Fatalf("findUnknownFields() error = %v", err)
+		}
+		for _, f := range unknown {
+			t.Errorf("%s template: line %d: %s", tt.schemaType, f.line, f.message())
+		}
+	}
+}
+
+// TestValidate_UnknownFields tests reporting misspelled keys, including
+// ones merged from anchors and ones in included files.
+func TestValidate_UnknownFields(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"shared.yml": `
+tasks:
+  report:
+    agent: sh
+    command: ./report.sh
+    continue_on_eror: true
+`,
+		"Cortexfile.yml": `
+x-defaults: &defaults
+  agent: sh
+  piority: 2
+include: [shared.yml]
+agents:
+  sh: { tool: shell, enf: { CI: "1" } }
+task:
+  extra: {}
+tasks:
+  lint:
+    <<: *defaults
+    command: make lint
+  deploy:
+    http: { url: "https://example.com", method: POST, hedaers: { A: b } }
+    nedds: [lint]
+settings:
+  max_paralel: 2
+  theme: { palette: accessible }
+`,
+	})
+	path := filepath.Join(dir, "Cortexfile.yml")
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	err = ValidateWithFile(cfg, path)
+	valErr, ok := err.(*ConfigErrors)
+	if !ok {
+		t.Fatalf("expected *ConfigErrors, got %v", err)
+	}
+
+	for _, want := range []string{
+		`Cortexfile.yml:4:3: unknown field "piority" in tasks.lint` + "\n  Hint: Did you mean \"priority\"?",
+		`Cortexfile.yml:7:22: unknown field "enf" in agents.sh`,
+		`Cortexfile.yml:8:1: unknown top-level field "task"` + "\n  Hint: Did you mean \"tasks\"?",
+		`unknown field "hedaers" in tasks.deploy.http`,
+		`Cortexfile.yml:16:5: unknown field "nedds" in tasks.deploy`,
+		`unknown field "max_paralel" in settings`,
+		`shared.yml:6:5: unknown field "continue_on_eror" in tasks.report`,
+	} {
+		if !errorsContain(valErr, want) {
+			t.Errorf("expected error containing %q, got: %v", want, valErr)
+		}
+	}
+	if errorsContain(valErr, "x-defaults") {
+		t.Errorf("x- keys should be ignored, got: %v", valErr)
+	}
+}
+
+// TestLoadConfigFiles_UnknownFields tests rejecting misspelled keys of the
+// master and global config files.
+func TestLoadConfigFiles_UnknownFields(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"MasterCortex.yml": "mode: parallel\nworkflows:\n  - path: a/Cortexfile.yml\n    neds: [b]\n",
+		"config.yml":       "webhooks:\n  - url: https://example.com/hook\n    retires: 2\n",
+	})
+
+	_, err := LoadMasterConfig(filepath.Join(dir, "MasterCortex.yml"))
+	if want := `line 4: unknown field "neds" in workflows[0] (Did you mean "needs"?)`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("LoadMasterConfig() error = %v, want error containing %q", err, want)
+	}
+	_, err = LoadGlobalConfigFromPath(filepath.Join(dir, "config.yml"))
+	if want := `line 3: unknown field "retires" in webhooks[0] (Did you mean "retries"?)`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("LoadGlobalConfigFromPath() error = %v, want error containing %q", err, want)
+	}
+}
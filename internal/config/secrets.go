@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultEnvFile is the dotenv file loaded from the Cortexfile directory when
+// env_file is not set.
+const DefaultEnvFile = ".env"
+
+// ResolveSecrets looks up every name in the secrets section, first in the
+// process environment and then in the env file. The env file is optional
+// unless env_file is set explicitly. Returns an error naming any secret that
+// could not be found.
+func ResolveSecrets(cfg *AgentflowConfig, baseDir string) (map[string]string, error) {
+	secrets := make(map[string]string, len(cfg.Secrets))
+	if len(cfg.Secrets) == 0 {
+		return secrets, nil
+	}
+
+	envFile, fileVars, err := loadEnvFileVars(cfg, baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, name := range cfg.Secrets {
+		if value, ok := os.LookupEnv(name); ok {
+			secrets[name] = value
+		} else if value, ok := fileVars[name]; ok {
+			secrets[name] = value
+		} else {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("secrets not found in environment or %s: %s", filepath.Base(envFile), strings.Join(missing, ", "))
+	}
+	return secrets, nil
+}
+
+// ResolveSigningKey looks up the variable named by signing_key_env like a
+// secret, but without injecting it into tasks, which could otherwise sign
+// forged results. Returns "" if signing_key_env is not set. Errors leave the
+// name out, in case the key itself was put there.
+func ResolveSigningKey(cfg *AgentflowConfig, baseDir string) (string, error) {
+	if cfg.SigningKeyEnv == "" {
+		return "", nil
+	}
+	if value, ok := os.LookupEnv(cfg.SigningKeyEnv); ok && value != "" {
+		return value, nil
+	}
+
+	envFile, fileVars, err := loadEnvFileVars(cfg, baseDir)
+	if err != nil {
+		return "", err
+	}
+	if value := fileVars[cfg.SigningKeyEnv]; value != "" {
+		return value, nil
+	}
+	return "", fmt.Errorf("the variable named by signing_key_env is not set in the environment or %s", filepath.Base(envFile))
+}
+
+// loadEnvFileVars reads the env file of a config, returning its path. A
+// missing default .env is not an error.
+func loadEnvFileVars(cfg *AgentflowConfig, baseDir string) (string, map[string]string, error) {
+	envFile := cfg.EnvFile
+	if envFile == "" {
+		envFile = DefaultEnvFile
+	}
+	if !filepath.IsAbs(envFile) {
+		envFile = filepath.Join(baseDir, envFile)
+	}
+
+	fileVars, err := LoadEnvFile(envFile)
+	if err != nil && (cfg.EnvFile != "" || !os.IsNotExist(err)) {
+		return envFile, nil, fmt.Errorf("failed to read env_file %q: %w", envFile, err)
+	}
+	return envFile, fileVars, nil
+}
+
+// LoadEnvFile parses a dotenv file of KEY=VALUE lines.
+// Blank lines, # comments, an optional "export " prefix, and matching
+// surrounding quotes are handled.
+func LoadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[key] = value
+	}
+	return vars, scanner.Err()
+}
+
+// SecretValues returns the values of resolved secrets, for redaction.
+func SecretValues(secrets map[string]string) []string {
+	values := make([]string, 0, len(secrets))
+	for _, v := range secrets {
+		values = append(values, v)
+	}
+	return values
+}
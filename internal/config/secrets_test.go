@@ -0,0 +1,184 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestLoadEnvFile tests parsing dotenv files.
+func TestLoadEnvFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "agentflow-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	content := `# comment
+GITHUB_TOKEN=ghp_abc123
+
+export DB_PASSWORD="p@ss word"
+SINGLE='quoted'
+EMPTY=
+not a var
+`
+	path := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write .env: %v", err)
+	}
+
+	got, err := LoadEnvFile(path)
+	if err != nil {
+		t.Fatalf("LoadEnvFile() error = %v", err)
+	}
+
+	want := map[string]string{
+		"GITHUB_TOKEN": "ghp_abc123",
+		"DB_PASSWORD":  "p@ss word",
+		"SINGLE":       "quoted",
+		"EMPTY":        "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadEnvFile() = %v, want %v", got, want)
+	}
+}
+
+// TestResolveSecrets tests resolving secrets from the environment and .env.
+func TestResolveSecrets(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "agentflow-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("FROM_FILE=file-value\nFROM_ENV=stale\n"), 0644); err != nil {
+		t.Fatalf("failed to write .env: %v", err)
+	}
+	t.Setenv("FROM_ENV", "env-value")
+
+	tests := []struct {
+		name    string
+		cfg     AgentflowConfig
+		baseDir string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "no secrets",
+			cfg:     AgentflowConfig{},
+			baseDir: tmpDir,
+			want:    map[string]string{},
+		},
+		{
+			name:    "environment takes precedence over .env",
+			cfg:     AgentflowConfig{Secrets: StringList{"FROM_ENV", "FROM_FILE"}},
+			baseDir: tmpDir,
+			want:    map[string]string{"FROM_ENV": "env-value", "FROM_FILE": "file-value"},
+		},
+		{
+			name:    "missing secret",
+			cfg:     AgentflowConfig{Secrets: StringList{"CORTEX_TEST_MISSING"}},
+			baseDir: tmpDir,
+			wantErr: true,
+		},
+		{
+			name:    "default .env is optional",
+			cfg:     AgentflowConfig{Secrets: StringList{"FROM_ENV"}},
+			baseDir: filepath.Join(tmpDir, "nested"),
+			want:    map[string]string{"FROM_ENV": "env-value"},
+		},
+		{
+			name:    "explicit env_file must exist",
+			cfg:     AgentflowConfig{Secrets: StringList{"FROM_ENV"}, EnvFile: "secrets.env"},
+			baseDir: tmpDir,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSecrets(&tt.cfg, tt.baseDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveSecrets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveSecrets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestResolveSigningKey tests looking up the signing key like a secret.
+func TestResolveSigningKey(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "agentflow-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("FILE_KEY=file-key\n"), 0644); err != nil {
+		t.Fatalf("failed to write .env: %v", err)
+	}
+	t.Setenv("ENV_KEY", "env-key")
+
+	tests := []struct {
+		name    string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{"not set", "", "", false},
+		{"from environment", "ENV_KEY", "env-key", false},
+		{"from .env", "FILE_KEY", "file-key", false},
+		{"missing", "CORTEX_TEST_MISSING", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSigningKey(&AgentflowConfig{SigningKeyEnv: tt.key}, tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveSigningKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// The name may be the key itself, put there by mistake
+			if err != nil && strings.Contains(err.Error(), tt.key) {
+				t.Errorf("ResolveSigningKey() error = %v, want it without %s", err, tt.key)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveSigningKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidate_SigningKeyEnv tests that signing_key_env must name a variable,
+// and that the error leaves out what it was set to.
+func TestValidate_SigningKeyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyEnv  string
+		wantErr bool
+	}{
+		{"not set", "", false},
+		{"variable", "CORTEX_SIGNING_KEY", false},
+		{"key", "c0rtex-s1gning/key+", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AgentflowConfig{
+				Agents:        map[string]AgentConfig{"sh": {Tool: "shell"}},
+				Tasks:         map[string]TaskConfig{"a": {Agent: "sh", Command: "true"}},
+				SigningKeyEnv: tt.keyEnv,
+			}
+			err := ValidateWithFile(cfg, "Cortexfile.yml")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateWithFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && strings.Contains(err.Error(), tt.keyEnv) {
+				t.Errorf("ValidateWithFile() error = %v, want it without %s", err, tt.keyEnv)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// StagePrefix marks a reference to every task of a stage in needs, as in
+// needs: [stage:analysis].
+const StagePrefix = "stage:"
+
+// StageConfig groups tasks that share an agent, environment, working
+// directory, and dependencies. Settings of a task win over those of its
+// stage: env entries are merged, and needs are added to the task's own.
+type StageConfig struct {
+	Tasks   StringList        `yaml:"tasks"`   // Tasks in the stage
+	Agent   string            `yaml:"agent"`   // Agent of tasks that set none (optional)
+	Env     map[string]string `yaml:"env"`     // Environment variables of every task (optional)
+	Workdir string            `yaml:"workdir"` // Working directory of tasks that set none (optional)
+	Needs   StringList        `yaml:"needs"`   // Dependencies of every task (optional)
+}
+
+// applyStages applies the defaults of each stage to its tasks and replaces
+// stage:X in needs with the tasks of stage X. References to unknown stages
+// and tasks in several stages are left for validation to report.
+func applyStages(config *AgentflowConfig) {
+	if len(config.Stages) == 0 {
+		return
+	}
+
+	for _, stage := range config.Stages {
+		for _, name := range stage.Tasks {
+			task, ok := config.Tasks[name]
+			if !ok || len(config.TaskStages(name)) > 1 {
+				continue
+			}
+			if task.Agent == "" && task.BuiltinTool() == "" {
+				task.Agent = stage.Agent
+			}
+			if task.Workdir == "" {
+				task.Workdir = stage.Workdir
+			}
+			if len(stage.Env) > 0 {
+				env := make(map[string]string, len(stage.Env)+len(task.Env))
+				for k, v := range stage.Env {
+					env[k] = v
+				}
+				for k, v := range task.Env {
+					env[k] = v
+				}
+				task.Env = env
+			}
+			task.Needs = append(append(StringList(nil), stage.Needs...), task.Needs...)
+			config.Tasks[name] = task
+		}
+	}
+
+	for name, task := range config.Tasks {
+		task.Needs = expandStageNeeds(config, name, task.Needs)
+		config.Tasks[name] = task
+	}
+}
+
+// expandStageNeeds replaces stage:X in the needs of task name with the tasks
+// of stage X other than the task itself, without duplicates.
+func expandStageNeeds(config *AgentflowConfig, name string, needs StringList) StringList {
+	var expanded StringList
+	add := func(dep string) {
+		if !containsString(expanded, dep) {
+			expanded = append(expanded, dep)
+		}
+	}
+	for _, dep := range needs {
+		stage, ok := config.Stages[strings.TrimPrefix(dep, StagePrefix)]
+		if !strings.HasPrefix(dep, StagePrefix) || !ok {
+			add(dep)
+			continue
+		}
+		for _, member := range stage.Tasks {
+			if member != name {
+				add(member)
+			}
+		}
+	}
+	return expanded
+}
+
+// TaskStages returns the stages that list a task, in name order.
+func (c *AgentflowConfig) TaskStages(name string) []string {
+	var stages []string
+	for _, stageName := range slices.Sorted(maps.Keys(c.Stages)) {
+		if containsString(c.Stages[stageName].Tasks, name) {
+			stages = append(stages, stageName)
+		}
+	}
+	return stages
+}
+
+// validateStages checks that stages list defined tasks and agents, that no
+// task is in two stages, and that stage:X references name defined stages.
+func validateStages(filePath string, config *AgentflowConfig, availableTasks []string) []*ConfigError {
+	var errs []*ConfigError
+	stageNames := slices.Sorted(maps.Keys(config.Stages))
+
+	for _, stageName := range stageNames {
+		stage := config.Stages[stageName]
+		prefix := "stage \"" + stageName + "\": "
+		if len(stage.Tasks) == 0 {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				prefix+"has no tasks",
+				"List the tasks of the stage under 'tasks'"))
+		}
+		for _, name := range stage.Tasks {
+			if _, exists := config.Tasks[name]; !exists {
+				hint := "Available tasks: " + strings.Join(availableTasks, ", ")
+				if suggestion := SuggestClosestMatch(name, availableTasks); suggestion != "" {
+					hint = "Did you mean \"" + suggestion + "\"? " + hint
+				}
+				errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+					prefix+"undefined task \""+name+"\"", hint))
+			} else if stages := config.TaskStages(name); len(stages) > 1 && stages[0] == stageName {
+				errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+					"task \""+name+"\" is in several stages: "+strings.Join(stages, ", "),
+					"A task can be in one stage; move the shared settings to the task"))
+			}
+		}
+		if _, exists := config.Agents[stage.Agent]; stage.Agent != "" && !exists {
+			hint := "Define the agent under 'agents:'"
+			if suggestion := SuggestClosestMatch(stage.Agent, slices.Collect(maps.Keys(config.Agents))); suggestion != "" {
+				hint = "Did you mean \"" + suggestion + "\"?"
+			}
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				prefix+"undefined agent \""+stage.Agent+"\"", hint))
+		}
+	}
+
+	for _, taskName := range slices.Sorted(maps.Keys(config.Tasks)) {
+		for _, dep := range config.Tasks[taskName].Needs {
+			stageName, ok := strings.CutPrefix(dep, StagePrefix)
+			if _, defined := config.Stages[stageName]; !ok || defined {
+				continue
+			}
+			hint := "Define it under 'stages:'"
+			if suggestion := SuggestClosestMatch(stageName, stageNames); suggestion != "" {
+				hint = "Did you mean \"" + StagePrefix + suggestion + "\"?"
+			}
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+taskName+"\": needs undefined stage \""+stageName+"\"", hint))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseConfig_Stages tests applying stage defaults and expanding
+// stage:X in needs.
+func TestParseConfig_Stages(t *testing.T) {
+	yaml := `
+agents:
+  architect: { tool: claude-code }
+  coder: { tool: claude-code }
+  sh: { tool: shell }
+stages:
+  analysis:
+    agent: architect
+    env: { LEVEL: deep, REGION: eu }
+    workdir: src
+    needs: [fetch]
+    tasks: [api, db]
+  build:
+    agent: coder
+    needs: [stage:analysis]
+    tasks: [implement, docs]
+tasks:
+  fetch:
+    agent: sh
+    command: ./fetch.sh
+  api:
+    prompt: Analyze the API.
+  db:
+    agent: coder
+    prompt: Analyze the schema.
+    env: { LEVEL: quick }
+    workdir: /srv/db
+    needs: [api]
+  implement:
+    prompt: Implement it.
+  docs:
+    prompt: Document it.
+    needs: [implement]
+  release:
+    agent: sh
+    command: ./release.sh
+    needs: [stage:build, fetch]
+`
+	cfg, err := ParseConfig([]byte(yaml), "/project")
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	api, db := cfg.Tasks["api"], cfg.Tasks["db"]
+	if api.Agent != "architect" || db.Agent != "coder" {
+		t.Errorf("agents = %q, %q, want architect, coder", api.Agent, db.Agent)
+	}
+	if want := filepath.Join("/project", "src"); api.Workdir != want || db.Workdir != "/srv/db" {
+		t.Errorf("workdirs = %q, %q, want %q, /srv/db", api.Workdir, db.Workdir, want)
+	}
+	if want := map[string]string{"LEVEL": "quick", "REGION": "eu"}; !reflect.DeepEqual(db.Env, want) {
+		t.Errorf("db env = %v, want %v", db.Env, want)
+	}
+
+	tests := []struct {
+		task string
+		want StringList
+	}{
+		{"api", StringList{"fetch"}},
+		{"db", StringList{"fetch", "api"}},
+		{"implement", StringList{"api", "db"}},
+		{"docs", StringList{"api", "db", "implement"}},
+		{"release", StringList{"implement", "docs", "fetch"}},
+	}
+	for _, tt := range tests {
+		if got := cfg.Tasks[tt.task].Needs; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s needs = %v, want %v", tt.task, got, tt.want)
+		}
+	}
+}
+
+// TestValidate_Stages tests reporting mistakes in stages.
+func TestValidate_Stages(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "undefined task",
+			yaml: `
+stages:
+  checks: { agent: sh, tasks: [lint, tset] }
+tasks:
+  lint: { command: make lint }
+  test: { command: make test }
+`,
+			wantErr: `stage "checks": undefined task "tset"`,
+		},
+		{
+			name: "undefined agent",
+			yaml: `
+stages:
+  checks: { agent: shell, tasks: [lint] }
+tasks:
+  lint: { command: make lint }
+`,
+			wantErr: `stage "checks": undefined agent "shell"`,
+		},
+		{
+			name: "task in two stages",
+			yaml: `
+stages:
+  checks: { agent: sh, tasks: [lint] }
+  quality: { agent: sh, tasks: [lint] }
+tasks:
+  lint: { command: make lint }
+`,
+			wantErr: `task "lint" is in several stages: checks, quality`,
+		},
+		{
+			name: "undefined stage",
+			yaml: `
+stages:
+  checks: { agent: sh, tasks: [lint] }
+tasks:
+  lint: { command: make lint }
+  deploy: { agent: sh, command: ./deploy.sh, needs: [stage:check] }
+`,
+			wantErr: `task "deploy": needs undefined stage "check"`,
+		},
+		{
+			name: "empty stage",
+			yaml: `
+stages:
+  checks: { agent: sh }
+tasks:
+  lint: { agent: sh, command: make lint }
+`,
+			wantErr: `stage "checks": has no tasks`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseConfig([]byte("agents:\n  sh: { tool: shell }\n"+tt.yaml), "/project")
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+			err = Validate(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
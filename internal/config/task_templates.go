@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// paramRegex matches {{params.name}} patterns.
+var paramRegex = regexp.MustCompile(`\{\{params\.([a-zA-Z0-9_-]+)\}\}`)
+
+// instantiateTemplates builds the tasks that name a template under
+// task_templates: the template's fields with {{params.name}} substituted,
+// overridden by the fields the task sets itself (env maps are merged). A
+// template's params are the defaults of its parameters. data is the YAML the
+// config was parsed from.
+func instantiateTemplates(config *AgentflowConfig, data []byte) error {
+	templated := false
+	for name, task := range config.Tasks {
+		if task.Template != "" {
+			templated = true
+		} else if len(task.Params) > 0 {
+			return fmt.Errorf("task %q: params require a template", name)
+		}
+	}
+	if !templated {
+		return nil
+	}
+
+	var nodes struct {
+		Tasks         map[string]yaml.Node `yaml:"tasks"`
+		TaskTemplates map[string]yaml.Node `yaml:"task_templates"`
+	}
+	if err := yaml.Unmarshal(data, &nodes); err != nil {
+		return fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(config.Tasks)) {
+		task := config.Tasks[name]
+		if task.Template == "" {
+			continue
+		}
+		tmpl, ok := config.TaskTemplates[task.Template]
+		if !ok {
+			available := slices.Sorted(maps.Keys(config.TaskTemplates))
+			msg := fmt.Sprintf("task %q: undefined template %q", name, task.Template)
+			if suggestion := SuggestClosestMatch(task.Template, available); suggestion != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+			}
+			return fmt.Errorf("%s", msg)
+		}
+		if tmpl.Template != "" {
+			return fmt.Errorf("template %q: templates can't use other templates", task.Template)
+		}
+
+		params, err := templateParams(name, task, tmpl, nodes.TaskTemplates[task.Template])
+		if err != nil {
+			return err
+		}
+		expand := func(text string) string {
+			return paramRegex.ReplaceAllStringFunc(text, func(match string) string {
+				return params[paramRegex.FindStringSubmatch(match)[1]]
+			})
+		}
+
+		var instance TaskConfig
+		tmplNode := nodes.TaskTemplates[task.Template]
+		if err := expandNode(&tmplNode, expand).Decode(&instance); err != nil {
+			return fmt.Errorf("task %q: template %q: %w", name, task.Template, err)
+		}
+		taskNode := nodes.Tasks[name]
+		if err := expandNode(&taskNode, expand).Decode(&instance); err != nil {
+			return fmt.Errorf("task %q: %w", name, err)
+		}
+		instance.Template = ""
+		instance.Params = nil
+		config.Tasks[name] = instance
+	}
+	return nil
+}
+
+// templateParams returns the parameters of a task instantiating tmpl: the
+// task's params over the template's defaults. Every parameter the template
+// references must have a value, and every param the task sets must be one
+// the template declares or references.
+func templateParams(name string, task, tmpl TaskConfig, tmplNode yaml.Node) (map[string]string, error) {
+	referenced := make(map[string]bool)
+	collectParams(&tmplNode, referenced)
+
+	params := maps.Clone(tmpl.Params)
+	if params == nil {
+		params = make(map[string]string)
+	}
+	for param, value := range task.Params {
+		if _, declared := tmpl.Params[param]; !declared && !referenced[param] {
+			return nil, fmt.Errorf("task %q: template %q has no param %q", name, task.Template, param)
+		}
+		params[param] = value
+	}
+
+	var missing []string
+	for param := range referenced {
+		if _, ok := params[param]; !ok {
+			missing = append(missing, param)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return nil, fmt.Errorf("task %q: template %q needs params: %s", name, task.Template, strings.Join(missing, ", "))
+	}
+	return params, nil
+}
+
+// collectParams adds the names of the {{params.name}} references in the
+// scalars under node to refs.
+func collectParams(node *yaml.Node, refs map[string]bool) {
+	if node.Kind == yaml.ScalarNode {
+		for _, match := range paramRegex.FindAllStringSubmatch(node.Value, -1) {
+			refs[match[1]] = true
+		}
+	}
+	for _, child := range node.Content {
+		collectParams(child, refs)
+	}
+}
+
+// expandNode returns a copy of node with expand applied to every scalar.
+// Aliases are replaced with copies of what they refer to.
+func expandNode(node *yaml.Node, expand func(string) string) *yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		return expandNode(node.Alias, expand)
+	}
+	copied := *node
+	copied.Anchor = ""
+	if node.Kind == yaml.ScalarNode {
+		copied.Value = expand(node.Value)
+	}
+	if len(node.Content) > 0 {
+		copied.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			copied.Content[i] = expandNode(child, expand)
+		}
+	}
+	return &copied
+}
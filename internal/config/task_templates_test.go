@@ -0,0 +1,137 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseConfig_TaskTemplates tests instantiating task templates.
+func TestParseConfig_TaskTemplates(t *testing.T) {
+	data := []byte(`
+agents:
+  reviewer:
+    tool: claude-code
+  sh:
+    tool: shell
+
+task_templates:
+  review-module:
+    agent: reviewer
+    needs: ["test-{{params.module}}"]
+    prompt: "Review {{params.module}} for {{params.focus}}"
+    env:
+      MODULE: "{{params.module}}"
+      LEVEL: strict
+    params:
+      focus: bugs
+  test-module:
+    agent: sh
+    command: "go test ./{{params.module}}/..."
+
+tasks:
+  test-api:
+    template: test-module
+    params: {module: api}
+  test-web:
+    template: test-module
+    params: {module: web}
+  review-api:
+    template: review-module
+    params: {module: api}
+  review-web:
+    template: review-module
+    params: {module: web, focus: security}
+    env:
+      LEVEL: lenient
+    priority: 2
+`)
+
+	cfg, err := ParseConfig(data, t.TempDir())
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+
+	if got := cfg.Tasks["test-web"].Command; got != "go test ./web/..." {
+		t.Errorf("test-web command = %q", got)
+	}
+
+	api := cfg.Tasks["review-api"]
+	if api.Prompt != "Review api for bugs" {
+		t.Errorf("review-api prompt = %q, want the default focus", api.Prompt)
+	}
+	if !reflect.DeepEqual([]string(api.Needs), []string{"test-api"}) {
+		t.Errorf("review-api needs = %v, want [test-api]", api.Needs)
+	}
+	if api.Template != "" || api.Params != nil {
+		t.Errorf("expected template and params to be cleared, got %q %v", api.Template, api.Params)
+	}
+
+	web := cfg.Tasks["review-web"]
+	if web.Prompt != "Review web for security" {
+		t.Errorf("review-web prompt = %q", web.Prompt)
+	}
+	if web.Env["MODULE"] != "web" || web.Env["LEVEL"] != "lenient" {
+		t.Errorf("review-web env = %v, want merged env with the task's LEVEL", web.Env)
+	}
+	if web.Priority != 2 {
+		t.Errorf("review-web priority = %d, want 2", web.Priority)
+	}
+
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected instantiated config to be valid, got: %v", err)
+	}
+}
+
+// TestParseConfig_TaskTemplateErrors tests rejecting bad instantiations.
+func TestParseConfig_TaskTemplateErrors(t *testing.T) {
+	templates := `
+agents:
+  sh:
+    tool: shell
+task_templates:
+  test-module:
+    agent: sh
+    command: "go test ./{{params.module}}/..."
+tasks:
+`
+	tests := []struct {
+		name    string
+		tasks   string
+		wantErr string
+	}{
+		{"undefined template", "  t:\n    template: test-modul\n    params: {module: api}\n", `did you mean "test-module"`},
+		{"missing param", "  t:\n    template: test-module\n", "needs params: module"},
+		{"unknown param", "  t:\n    template: test-module\n    params: {module: api, modle: x}\n", `has no param "modle"`},
+		{"params without template", "  t:\n    agent: sh\n    command: ls\n    params: {module: api}\n", "params require a template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(templates+tt.tasks), t.TempDir())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidate_ParamsOutsideTemplate tests that {{params.x}} in a plain task
+// is reported.
+func TestValidate_ParamsOutsideTemplate(t *testing.T) {
+	cfg := &AgentflowConfig{
+		Agents: map[string]AgentConfig{"sh": {Tool: "shell"}},
+		Tasks: map[string]TaskConfig{
+			"t": {Agent: "sh", Command: "echo {{params.module}}"},
+		},
+	}
+
+	err := Validate(cfg)
+	valErr, ok := err.(*ConfigErrors)
+	if !ok {
+		t.Fatalf("expected *ConfigErrors, got %T", err)
+	}
+	if !errorsContain(valErr, "outside a task template") {
+		t.Errorf("expected params error, got: %v", valErr)
+	}
+}
@@ -2,35 +2,169 @@ package config
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
+// artifactVarRegex matches {{artifacts.taskname}} patterns.
+var artifactVarRegex = regexp.MustCompile(`\{\{artifacts\.([a-zA-Z0-9_-]+)\}\}`)
+
+// Output modifiers that trim a task output before it is substituted.
+const (
+	ModHead     = "head"      // First N lines
+	ModTail     = "tail"      // Last N lines
+	ModMaxChars = "max_chars" // First N characters
+)
+
+// OutputModifiers lists all modifiers supported in {{outputs.X | mod N}}.
+var OutputModifiers = []string{ModHead, ModTail, ModMaxChars}
+
+// outputModifier is one "| name N" step of a template variable.
+type outputModifier struct {
+	name string
+	n    int
+}
+
 // ExpandPrompt replaces {{outputs.<task-name>}} placeholders in a prompt
-// with actual output values from completed tasks.
+// with actual output values from completed tasks. Modifiers after a pipe
+// trim the output first and are applied left to right.
 //
 // Example:
 //
 //	prompt: "Based on: {{outputs.analyze}}\nImplement changes."
 //	outputs: {"analyze": "Found 3 issues..."}
 //	result: "Based on: Found 3 issues...\nImplement changes."
+//
+//	prompt: "Last lines of the log: {{outputs.test | tail 20 | max_chars 2000}}"
+//
+// {{outputs.<task-name>.<field>}} selects a field of JSON output, e.g.
+// {{outputs.review.issues.0.file}}.
 func ExpandPrompt(prompt string, outputs map[string]string) string {
 	result := prompt
 
 	// Find and replace all {{outputs.X}} patterns
 	matches := templateVarRegex.FindAllStringSubmatch(prompt, -1)
 	for _, match := range matches {
-		placeholder := match[0] // Full match: {{outputs.taskname}}
+		placeholder := match[0] // Full match: {{outputs.taskname | mod N}}
 		taskName := match[1]    // Captured group: taskname
 
-		if output, exists := outputs[taskName]; exists {
-			result = strings.Replace(result, placeholder, output, -1)
+		output, exists := outputs[taskName]
+		if !exists {
+			// Leave placeholder as-is (validation should catch this)
+			continue
+		}
+		mods, err := parseOutputModifiers(match[3])
+		if err != nil {
+			continue
+		}
+		if field := match[2]; field != "" && field != ".*" {
+			output = OutputField(output, strings.Split(field[1:], "."))
+		}
+		for _, mod := range mods {
+			output = mod.apply(output)
 		}
-		// If output doesn't exist, leave placeholder as-is (validation should catch this)
+		result = strings.Replace(result, placeholder, output, -1)
 	}
 
 	return result
 }
 
+// ExpandArtifacts replaces {{artifacts.<task-name>}} placeholders with the
+// absolute paths of the files collected from that task, separated by spaces
+// so the list can be passed to shell commands.
+func ExpandArtifacts(text string, artifacts map[string][]string) string {
+	return artifactVarRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := artifactVarRegex.FindStringSubmatch(placeholder)[1]
+		if paths, ok := artifacts[name]; ok {
+			return strings.Join(paths, " ")
+		}
+		return placeholder
+	})
+}
+
+// CacheDirVar is replaced with the task's cache directory, which is kept
+// across runs.
+const CacheDirVar = "{{cache_dir}}"
+
+// ExpandCacheDir replaces {{cache_dir}} placeholders with a task's cache
+// directory.
+func ExpandCacheDir(text, dir string) string {
+	return strings.ReplaceAll(text, CacheDirVar, dir)
+}
+
+// ExtractArtifactVars returns all task names referenced in {{artifacts.X}} patterns.
+func ExtractArtifactVars(text string) []string {
+	var tasks []string
+	seen := make(map[string]bool)
+	for _, match := range artifactVarRegex.FindAllStringSubmatch(text, -1) {
+		if !seen[match[1]] {
+			tasks = append(tasks, match[1])
+			seen[match[1]] = true
+		}
+	}
+	return tasks
+}
+
+// parseOutputModifiers parses a "| head 50 | max_chars 4000" suffix.
+func parseOutputModifiers(suffix string) ([]outputModifier, error) {
+	if strings.TrimSpace(suffix) == "" {
+		return nil, nil
+	}
+
+	steps := strings.Split(suffix, "|")
+	if strings.TrimSpace(steps[0]) != "" {
+		return nil, fmt.Errorf("unexpected %q before modifier", strings.TrimSpace(steps[0]))
+	}
+
+	var mods []outputModifier
+	for _, step := range steps[1:] {
+		fields := strings.Fields(step)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty modifier")
+		}
+		name := fields[0]
+		if !containsString(OutputModifiers, name) {
+			return nil, fmt.Errorf("unknown modifier %q", name)
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("modifier %q takes one number", name)
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("modifier %q needs a positive number, got %q", name, fields[1])
+		}
+		mods = append(mods, outputModifier{name: name, n: n})
+	}
+	return mods, nil
+}
+
+// apply trims output according to the modifier. Trailing newlines do not
+// count as a line.
+func (m outputModifier) apply(output string) string {
+	switch m.name {
+	case ModHead:
+		lines := strings.SplitAfter(strings.TrimRight(output, "\n"), "\n")
+		if len(lines) <= m.n {
+			return output
+		}
+		return strings.Join(lines[:m.n], "")
+	case ModTail:
+		lines := strings.SplitAfter(strings.TrimRight(output, "\n"), "\n")
+		if len(lines) <= m.n {
+			return output
+		}
+		return strings.Join(lines[len(lines)-m.n:], "")
+	case ModMaxChars:
+		runes := []rune(output)
+		if len(runes) <= m.n {
+			return output
+		}
+		return string(runes[:m.n])
+	}
+	return output
+}
+
 // ExtractTemplateVars returns all task names referenced in {{outputs.X}} patterns.
 func ExtractTemplateVars(prompt string) []string {
 	matches := templateVarRegex.FindAllStringSubmatch(prompt, -1)
@@ -28,6 +28,14 @@ func TestExpandPrompt(t *testing.T) {
 			},
 			want: "Based on: result from task1",
 		},
+		{
+			name:   "fan-out wildcard alias",
+			prompt: "All: {{outputs.review.*}}",
+			outputs: map[string]string{
+				"review": "one\n\ntwo",
+			},
+			want: "All: one\n\ntwo",
+		},
 		{
 			name:   "multiple template variables",
 			prompt: "Combine {{outputs.task1}} and {{outputs.task2}}",
@@ -433,3 +441,81 @@ func TestExpandPrompt_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestExpandPrompt_Modifiers tests trimming outputs with | head, | tail, and | max_chars.
+func TestExpandPrompt_Modifiers(t *testing.T) {
+	outputs := map[string]string{
+		"log":  "one\ntwo\nthree\nfour\n",
+		"text": "héllo world",
+	}
+
+	tests := []struct {
+		name   string
+		prompt string
+		want   string
+	}{
+		{"head", "{{outputs.log | head 2}}", "one\ntwo\n"},
+		{"tail", "{{outputs.log | tail 2}}", "three\nfour"},
+		{"head longer than output", "{{outputs.log | head 10}}", "one\ntwo\nthree\nfour\n"},
+		{"max_chars counts characters", "{{outputs.text | max_chars 5}}", "héllo"},
+		{"chained", "{{outputs.log|tail 3|head 1}}", "two\n"},
+		{"fan-out alias", "{{outputs.log.* | head 1}}", "one\n"},
+		{"mixed with plain", "{{outputs.text}}: {{outputs.log | head 1}}", "héllo world: one\n"},
+		{"invalid modifier left as-is", "{{outputs.log | grep x}}", "{{outputs.log | grep x}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpandPrompt(tt.prompt, outputs); got != tt.want {
+				t.Errorf("ExpandPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseOutputModifiers_Errors tests that malformed modifiers are rejected.
+func TestParseOutputModifiers_Errors(t *testing.T) {
+	tests := []string{
+		"| head",
+		"| head 0",
+		"| tail -5",
+		"| head 5 10",
+		"| max_chars many",
+		"| summarize 5",
+		"| head 5 |",
+	}
+
+	for _, suffix := range tests {
+		t.Run(suffix, func(t *testing.T) {
+			if _, err := parseOutputModifiers(suffix); err == nil {
+				t.Errorf("parseOutputModifiers(%q) expected error", suffix)
+			}
+		})
+	}
+}
+
+// TestExpandArtifacts tests {{artifacts.X}} expansion.
+func TestExpandArtifacts(t *testing.T) {
+	artifacts := map[string][]string{
+		"build": {"/runs/1/artifacts/build/app.tar", "/runs/1/artifacts/build/build.log"},
+		"empty": {},
+	}
+
+	got := ExpandArtifacts("tar tf {{artifacts.build}}; ls {{artifacts.empty}}{{artifacts.other}}", artifacts)
+	want := "tar tf /runs/1/artifacts/build/app.tar /runs/1/artifacts/build/build.log; ls {{artifacts.other}}"
+	if got != want {
+		t.Errorf("ExpandArtifacts() = %q, want %q", got, want)
+	}
+
+	if vars := ExtractArtifactVars(got + " {{artifacts.other}} {{artifacts.a-b}}"); !reflect.DeepEqual(vars, []string{"other", "a-b"}) {
+		t.Errorf("ExtractArtifactVars() = %v", vars)
+	}
+}
+
+func TestExpandCacheDir(t *testing.T) {
+	got := ExpandCacheDir("npm ci --cache {{cache_dir}} && ls {{cache_dir}}/x {{outputs.a}}", "/data/p/cache/install")
+	want := "npm ci --cache /data/p/cache/install && ls /data/p/cache/install/x {{outputs.a}}"
+	if got != want {
+		t.Errorf("ExpandCacheDir() = %q, want %q", got, want)
+	}
+}
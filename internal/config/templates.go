@@ -7,9 +7,32 @@ const CortexfileTemplate = `# Cortexfile.yml - Cortex Workflow Configuration
 # ============================================================================
 # WORKING DIRECTORY (Optional)
 # ============================================================================
-# Set working directory for all agents. Can be absolute or relative path.
+# Set working directory for all agents. Can be absolute or relative path
+# (relative to this file). Agents and tasks can override it with their own
+# 'workdir:'.
 # workdir: /path/to/project
 
+# ============================================================================
+# VARIABLES (Optional)
+# ============================================================================
+# Defaults for {{vars.name}}; override with --var-file or --var name=value.
+# vars:
+#   env: dev
+#
+# Declare expected variables under 'inputs' to validate them before running:
+# inputs:
+#   env: { required: true, allowed: [dev, prod], description: Target env }
+#   replicas: { type: number, default: 2 }
+
+# ============================================================================
+# SECRETS (Optional)
+# ============================================================================
+# Env var names read from the environment or .env (next to this file) and
+# injected into every task. Their values are redacted from saved results and
+# webhook payloads.
+# secrets: [GITHUB_TOKEN]
+# env_file: .env
+
 # ============================================================================
 # AGENTS
 # ============================================================================
@@ -18,6 +41,9 @@ const CortexfileTemplate = `# Cortexfile.yml - Cortex Workflow Configuration
 # Supported tools:
 #   - claude-code : Claude AI via Claude Code CLI
 #   - opencode    : OpenCode AI CLI
+#   - aider       : Aider CLI for code-editing tasks
+#   - api         : OpenAI-compatible chat completions API (no CLI needed;
+#                   set model, optional base_url and api_key_env)
 #   - shell       : Execute shell commands directly
 #
 # Models (for AI agents):
@@ -62,6 +88,20 @@ agents:
 #   - command    : (shell agents) Shell command to execute
 #   - needs      : Dependencies - single task or array of tasks
 #   - write      : Allow file writes (default: false)
+#   - script     : Inline python/node script (no agent needed)
+#                  script: { lang: python, code: "print('hi')" }
+#   - items_from : JSON/CSV file; expands into one task per row ({{item}},
+#                  {{item.field}}) named task-1, task-2, ...
+#   - matrix     : Values or file globs to fan out over ({{matrix.value}});
+#                  dependents read all outputs via {{outputs.task.*}}
+#   - inputs     : Files/globs the task reads (for --incremental)
+#   - outputs    : Files the task produces; with --incremental the task is
+#                  skipped when outputs are newer than inputs and the prompt
+#                  is unchanged
+#   - when       : Run only if true, e.g. "{{outputs.test}} contains 'FAIL'"
+#                  or "{{status.lint}} == skipped"; otherwise skipped
+#   - env        : Environment variables (also on agents); ${VAR} expands
+#                  from secrets or the environment
 #
 # Template variables:
 #   Use {{outputs.task_name}} to reference output from a dependency task
@@ -159,6 +199,10 @@ settings:
 
   # Stream real-time output from agents (default: true)
   stream: true
+
+  # Abort the run once total tokens or reported cost exceed a limit
+  # max_tokens: 500000
+  # max_cost_usd: 5
 `
 
 // MasterCortexTemplate is the default template for a new MasterCortex.yml
@@ -240,7 +284,7 @@ workflows:
 // MinimalCortexfileTemplate is a minimal template for quick start
 const MinimalCortexfileTemplate = `# Cortexfile.yml - Minimal Template
 #
-# Supported tools: claude-code, opencode, shell
+# Supported tools: claude-code, opencode, aider, api, shell
 # Run with: cortex run
 
 agents:
@@ -303,6 +347,16 @@ settings:
   # Stream real-time output from agents
   stream: true
 
+  # Abort runs once total tokens or reported cost exceed a limit (0 = no limit)
+  max_tokens: 0
+  max_cost_usd: 0
+
+  # Export a span per run and task to an OpenTelemetry collector (OTLP/HTTP)
+  # telemetry:
+  #   endpoint: http://localhost:4318
+  #   headers:
+  #     Authorization: "Bearer token"
+
 # ============================================================================
 # WEBHOOKS (Optional)
 # ============================================================================
@@ -311,8 +365,14 @@ settings:
 # Supported events:
 #   - run_start    : When a workflow run starts
 #   - run_complete : When a workflow run completes
+#   - run_cancelled: When a workflow run is interrupted (instead of run_complete)
 #   - task_start   : When a task starts
-#   - task_complete: When a task completes
+#   - task_complete: When a task completes or is skipped
+#   - task_failed  : When a task fails
+#   - level_start  : When a dependency level starts (parallel mode)
+#   - master_run_start, master_run_complete, workflow_start,
+#     workflow_complete, workflow_failed, workflow_skipped
+#                  : MasterCortex runs, with aggregate progress
 #   - *            : All events
 
 # webhooks:
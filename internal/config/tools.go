@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ToolConfig declares a custom agent tool in the global config: a CLI run
+// with the task's prompt, whose output is read as text or JSON.
+type ToolConfig struct {
+	Executable        string     `yaml:"executable"`          // Name on PATH or path of the CLI
+	Args              StringList `yaml:"args"`                // Arguments; {{prompt}}, {{model}}, and {{workdir}} are replaced. Without {{prompt}}, the prompt goes to stdin
+	ModelArgs         StringList `yaml:"model_args"`          // Added after args when the agent sets a model
+	SystemPromptArgs  StringList `yaml:"system_prompt_args"`  // Added when the task has a system prompt; {{system_prompt}} is replaced
+	WriteArgs         StringList `yaml:"write_args"`          // Added after args for write-enabled tasks
+	Output            string     `yaml:"output"`              // text (default), ndjson, or json-field
+	Field             string     `yaml:"field"`               // Dotted path of the text in JSON output (ndjson, json-field)
+	InputTokensField  string     `yaml:"input_tokens_field"`  // Dotted path of the input token count in JSON output (optional)
+	OutputTokensField string     `yaml:"output_tokens_field"` // Dotted path of the output token count in JSON output (optional)
+}
+
+// Values for ToolConfig.Output.
+const (
+	ToolOutputText      = "text"       // Stdout is the result
+	ToolOutputNDJSON    = "ndjson"     // One JSON object per line; the result joins their field
+	ToolOutputJSONField = "json-field" // One JSON document; the result is its field
+)
+
+// SupportedToolOutputs lists all valid tools.<name>.output values.
+var SupportedToolOutputs = []string{ToolOutputText, ToolOutputNDJSON, ToolOutputJSONField}
+
+// reservedToolNames are taken by built-in tools and task kinds.
+var reservedToolNames = []string{"script", "http", "git", "wait", "poll", "notify", "pull_request"}
+
+// toolNameRegex matches valid custom tool names.
+var toolNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+var (
+	customToolsMu sync.RWMutex
+	// customTools holds the names of the tools declared in the global
+	// config last loaded, which agents may use like the built-in tools.
+	customTools map[string]bool
+)
+
+// RegisterTools makes the declared tools valid agent tools, replacing the
+// ones registered before. LoadGlobalConfig registers the tools it reads.
+func RegisterTools(tools map[string]ToolConfig) {
+	names := make(map[string]bool, len(tools))
+	for name := range tools {
+		names[name] = true
+	}
+	customToolsMu.Lock()
+	customTools = names
+	customToolsMu.Unlock()
+}
+
+// isCustomTool reports whether tool was declared under tools:.
+func isCustomTool(tool string) bool {
+	customToolsMu.RLock()
+	defer customToolsMu.RUnlock()
+	return customTools[tool]
+}
+
+// SupportedToolNames returns the built-in tools followed by the declared
+// custom tools, sorted.
+func SupportedToolNames() []string {
+	customToolsMu.RLock()
+	custom := make([]string, 0, len(customTools))
+	for name := range customTools {
+		custom = append(custom, name)
+	}
+	customToolsMu.RUnlock()
+	sort.Strings(custom)
+	return append(append([]string(nil), SupportedTools...), custom...)
+}
+
+// validateTools checks the tools section of the global config.
+func validateTools(tools map[string]ToolConfig) error {
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tool := tools[name]
+		prefix := fmt.Sprintf("tools: %q", name)
+		switch {
+		case !toolNameRegex.MatchString(name):
+			return fmt.Errorf("%s: names start with a letter and use letters, digits, '-', and '_'", prefix)
+		case containsString(SupportedTools, name) || containsString(reservedToolNames, name):
+			return fmt.Errorf("%s: name is taken by a built-in tool or task kind", prefix)
+		case strings.TrimSpace(tool.Executable) == "":
+			return fmt.Errorf("%s: executable is required", prefix)
+		case tool.Output != "" && !containsString(SupportedToolOutputs, tool.Output):
+			return fmt.Errorf("%s: unsupported output %q (supported: %s)", prefix, tool.Output, strings.Join(SupportedToolOutputs, ", "))
+		case tool.Field == "" && (tool.Output == ToolOutputNDJSON || tool.Output == ToolOutputJSONField):
+			return fmt.Errorf("%s: output %s requires 'field'", prefix, tool.Output)
+		}
+	}
+	return nil
+}
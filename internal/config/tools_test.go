@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidateTools tests the checks of custom tool declarations.
+func TestValidateTools(t *testing.T) {
+	tests := []struct {
+		name    string
+		tools   map[string]ToolConfig
+		wantErr string
+	}{
+		{"text tool", map[string]ToolConfig{"gemini": {Executable: "gemini", Args: StringList{"-p", "{{prompt}}"}}}, ""},
+		{"json-field tool", map[string]ToolConfig{"llm": {Executable: "llm", Output: ToolOutputJSONField, Field: "result"}}, ""},
+		{"missing executable", map[string]ToolConfig{"gemini": {}}, `tools: "gemini": executable is required`},
+		{"built-in name", map[string]ToolConfig{"aider": {Executable: "aider"}}, `tools: "aider": name is taken`},
+		{"task kind name", map[string]ToolConfig{"http": {Executable: "curl"}}, `tools: "http": name is taken`},
+		{"invalid name", map[string]ToolConfig{"my tool": {Executable: "x"}}, `tools: "my tool": names start with a letter`},
+		{"unknown output", map[string]ToolConfig{"llm": {Executable: "llm", Output: "xml"}}, `unsupported output "xml"`},
+		{"ndjson without field", map[string]ToolConfig{"llm": {Executable: "llm", Output: ToolOutputNDJSON}}, "output ndjson requires 'field'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTools(tt.tools)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateTools() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateTools() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestLoadGlobalConfig_Tools tests that tools declared in the global config
+// become valid agent tools.
+func TestLoadGlobalConfig_Tools(t *testing.T) {
+	t.Cleanup(func() { RegisterTools(nil) })
+
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{"g": {Tool: "gemini"}},
+		Tasks:  map[string]TaskConfig{"ask": {Agent: "g", Prompt: "hi"}},
+	}
+	err := Validate(config)
+	if err == nil || !strings.Contains(err.Error(), `unsupported tool "gemini"`) {
+		t.Fatalf("expected unsupported tool error before the tool is declared, got: %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yml")
+	data := `tools:
+  gemini:
+    executable: gemini
+    args: ["-p", "{{prompt}}"]
+    model_args: ["-m", "{{model}}"]
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	global, err := LoadGlobalConfigFromPath(path)
+	if err != nil {
+		t.Fatalf("LoadGlobalConfigFromPath() error = %v", err)
+	}
+	if got := global.Tools["gemini"].ModelArgs; len(got) != 2 || got[1] != "{{model}}" {
+		t.Errorf("ModelArgs = %v, want [-m {{model}}]", got)
+	}
+
+	if err := Validate(config); err != nil {
+		t.Errorf("expected declared tool to validate, got: %v", err)
+	}
+	if names := SupportedToolNames(); names[len(names)-1] != "gemini" {
+		t.Errorf("SupportedToolNames() = %v, want gemini last", names)
+	}
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Trigger types supported in the triggers block.
+const (
+	TriggerGitHub = "github" // GitHub webhook, signed with X-Hub-Signature-256
+	TriggerHTTP   = "http"   // Any JSON POST, authenticated with X-Cortex-Token
+)
+
+// SupportedTriggerTypes lists the valid values for a trigger's type.
+var SupportedTriggerTypes = []string{TriggerGitHub, TriggerHTTP}
+
+// TriggerConfig declares an incoming webhook that starts the workflow when
+// it is served by `cortex serve`. Fields of the JSON payload become workflow
+// variables, which are whatever the sender put there: validation requires
+// shell commands to quote them (see validateTriggerVarUse).
+type TriggerConfig struct {
+	Type      string            `yaml:"type"`       // github or http
+	SecretEnv string            `yaml:"secret_env"` // Env var holding the webhook secret (required when server.users is set)
+	Events    StringList        `yaml:"events"`     // github: events that start a run (default: push)
+	Branches  StringList        `yaml:"branches"`   // github: branch patterns of push events (default: all)
+	Vars      map[string]string `yaml:"vars"`       // Variable name -> payload field (e.g. head_commit.id)
+}
+
+// AcceptsEvent reports whether a GitHub event starts the workflow, and if not,
+// why. Push events are filtered by branch; other events have no branch.
+func (t TriggerConfig) AcceptsEvent(event string, payload any) (bool, string) {
+	events := t.Events
+	if len(events) == 0 {
+		events = StringList{"push"}
+	}
+	if !containsString(events, event) {
+		return false, "event \"" + event + "\" is not one of: " + strings.Join(events, ", ")
+	}
+
+	if len(t.Branches) == 0 || event != "push" {
+		return true, ""
+	}
+	ref, _ := PayloadValue(payload, "ref")
+	branch, ok := strings.CutPrefix(ref, "refs/heads/")
+	if !ok {
+		return false, "push of \"" + ref + "\" is not to a branch"
+	}
+	for _, pattern := range t.Branches {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true, ""
+		}
+	}
+	return false, "branch \"" + branch + "\" does not match: " + strings.Join(t.Branches, ", ")
+}
+
+// PayloadVars returns the workflow variables mapped from a payload. Fields
+// missing from the payload are left out, so the variable keeps its default.
+func (t TriggerConfig) PayloadVars(payload any) map[string]string {
+	vars := make(map[string]string, len(t.Vars))
+	for name, field := range t.Vars {
+		if value, ok := PayloadValue(payload, field); ok {
+			vars[name] = value
+		}
+	}
+	return vars
+}
+
+// PayloadValue looks up a dotted field path (e.g. "commits.0.id") in a
+// decoded JSON payload. Strings are returned as is, other scalars in their
+// JSON form, and objects and arrays as JSON.
+func PayloadValue(payload any, field string) (string, bool) {
+	value := payload
+	for _, key := range strings.Split(field, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			var ok bool
+			if value, ok = v[key]; !ok {
+				return "", false
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", false
+			}
+			value = v[i]
+		default:
+			return "", false
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case nil:
+		return "", true
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		return string(data), true
+	}
+}
+
+// validateTriggers checks trigger declarations: known types, GitHub-only
+// filters, and variable mappings to valid fields.
+func validateTriggers(filePath string, triggers map[string]TriggerConfig) []*ConfigError {
+	var errs []*ConfigError
+	for _, name := range sortedTriggerNames(triggers) {
+		trigger := triggers[name]
+		prefix := "trigger \"" + name + "\": "
+
+		switch {
+		case trigger.Type == "":
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				prefix+"type is required",
+				"Add 'type: github' or 'type: http'"))
+		case !containsString(SupportedTriggerTypes, trigger.Type):
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				prefix+"unsupported type \""+trigger.Type+"\"",
+				"Supported trigger types: "+strings.Join(SupportedTriggerTypes, ", ")))
+		case trigger.Type != TriggerGitHub && (len(trigger.Events) > 0 || len(trigger.Branches) > 0):
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				prefix+"'events' and 'branches' only apply to github triggers",
+				"Remove them or use 'type: github'"))
+		}
+
+		for _, pattern := range trigger.Branches {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+					prefix+"invalid branch pattern \""+pattern+"\"",
+					"Use a branch name or a glob like 'release/*'"))
+			}
+		}
+
+		for _, varName := range sortedKeys(trigger.Vars) {
+			field := trigger.Vars[varName]
+			if field == "" || strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") || strings.Contains(field, "..") {
+				errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+					prefix+"invalid payload field \""+field+"\" for variable \""+varName+"\"",
+					"Use a dotted path into the JSON payload, like 'head_commit.id' or 'commits.0.message'"))
+			}
+		}
+	}
+	return errs
+}
+
+// validateTriggerVarUse checks that the variables triggers set from their
+// payload reach shell commands only through quote(), and script code not at
+// all, since the sender of a webhook controls them and the run is an admin's.
+func validateTriggerVarUse(filePath string, config *AgentflowConfig) []*ConfigError {
+	payloadVars := make(map[string]bool)
+	for _, trigger := range config.Triggers {
+		for name := range trigger.Vars {
+			payloadVars["vars."+name] = true
+		}
+	}
+	if len(payloadVars) == 0 {
+		return nil
+	}
+
+	var errs []*ConfigError
+	for _, name := range slices.Sorted(maps.Keys(config.Tasks)) {
+		task := config.Tasks[name]
+		fields := []struct{ name, text string }{{"command", task.Command}}
+		if task.Poll != nil {
+			fields = append(fields, struct{ name, text string }{"poll command", task.Poll.Command})
+		}
+		if task.Script != nil {
+			fields = append(fields, struct{ name, text string }{"script", task.Script.Code})
+		}
+
+		for _, field := range fields {
+			if !strings.Contains(field.text, "{{") {
+				continue
+			}
+			tmpl, err := ParseTemplate(field.text)
+			if err != nil {
+				continue // Reported by validateTemplates
+			}
+			for _, ref := range tmpl.outputRefs(field.name != "script") {
+				if !payloadVars[ref.Name] {
+					continue
+				}
+				if field.name == "script" {
+					errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+						fmt.Sprintf("task %q: script line %d uses trigger variable %s", name, ref.Line, ref.Name),
+						"Its value comes from the webhook payload; pass it in 'env' and read the environment variable instead"))
+				} else {
+					errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+						fmt.Sprintf("task %q: %s line %d uses trigger variable %s without quote()", name, field.name, ref.Line, ref.Name),
+						"Its value comes from the webhook payload; use {{quote("+ref.Name+")}} so the shell reads it as one literal word"))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func sortedTriggerNames(triggers map[string]TriggerConfig) []string {
+	names := make([]string, 0, len(triggers))
+	for name := range triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestPayloadValue tests looking up dotted fields in a JSON payload.
+func TestPayloadValue(t *testing.T) {
+	var payload any
+	err := json.Unmarshal([]byte(`{
+		"ref": "refs/heads/main",
+		"forced": false,
+		"size": 3,
+		"pusher": {"name": "octocat"},
+		"commits": [{"id": "abc"}, {"id": "def"}],
+		"base": null
+	}`), &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		field  string
+		want   string
+		wantOK bool
+	}{
+		{"ref", "refs/heads/main", true},
+		{"pusher.name", "octocat", true},
+		{"commits.1.id", "def", true},
+		{"forced", "false", true},
+		{"size", "3", true},
+		{"base", "", true},
+		{"pusher", `{"name":"octocat"}`, true},
+		{"commits.2.id", "", false},
+		{"commits.x", "", false},
+		{"pusher.email", "", false},
+		{"ref.name", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, ok := PayloadValue(payload, tt.field)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("PayloadValue(%q) = %q, %v, want %q, %v", tt.field, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestTriggerAcceptsEvent tests filtering GitHub events and branches.
+func TestTriggerAcceptsEvent(t *testing.T) {
+	push := func(ref string) any {
+		return map[string]any{"ref": ref}
+	}
+
+	tests := []struct {
+		name       string
+		trigger    TriggerConfig
+		event      string
+		payload    any
+		want       bool
+		wantReason string
+	}{
+		{"push by default", TriggerConfig{}, "push", push("refs/heads/dev"), true, ""},
+		{"other events ignored by default", TriggerConfig{}, "issues", nil, false, `event "issues" is not one of: push`},
+		{"listed event", TriggerConfig{Events: StringList{"push", "release"}}, "release", nil, true, ""},
+		{"branch match", TriggerConfig{Branches: StringList{"main", "release/*"}}, "push", push("refs/heads/release/1.2"), true, ""},
+		{"branch mismatch", TriggerConfig{Branches: StringList{"main"}}, "push", push("refs/heads/dev"), false, `branch "dev" does not match: main`},
+		{"tag push", TriggerConfig{Branches: StringList{"main"}}, "push", push("refs/tags/v1"), false, `push of "refs/tags/v1" is not to a branch`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := tt.trigger.AcceptsEvent(tt.event, tt.payload)
+			if got != tt.want || reason != tt.wantReason {
+				t.Errorf("AcceptsEvent() = %v, %q, want %v, %q", got, reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+// TestTriggerPayloadVars tests mapping payload fields to variables.
+func TestTriggerPayloadVars(t *testing.T) {
+	trigger := TriggerConfig{Vars: map[string]string{
+		"sha":    "after",
+		"author": "head_commit.author.name",
+		"branch": "missing",
+	}}
+	payload := map[string]any{
+		"after":       "abc123",
+		"head_commit": map[string]any{"author": map[string]any{"name": "Octo"}},
+	}
+
+	want := map[string]string{"sha": "abc123", "author": "Octo"}
+	if got := trigger.PayloadVars(payload); !reflect.DeepEqual(got, want) {
+		t.Errorf("PayloadVars() = %v, want %v", got, want)
+	}
+}
+
+// TestValidate_Triggers tests validation of trigger declarations.
+func TestValidate_Triggers(t *testing.T) {
+	tests := []struct {
+		name            string
+		triggers        map[string]TriggerConfig
+		wantErrContains []string
+	}{
+		{
+			name: "valid",
+			triggers: map[string]TriggerConfig{
+				"push": {Type: TriggerGitHub, Branches: StringList{"main"}, Vars: map[string]string{"sha": "after"}},
+				"hook": {Type: TriggerHTTP, SecretEnv: "HOOK_SECRET"},
+			},
+		},
+		{
+			name:            "missing type",
+			triggers:        map[string]TriggerConfig{"t": {}},
+			wantErrContains: []string{`trigger "t": type is required`},
+		},
+		{
+			name:            "unsupported type",
+			triggers:        map[string]TriggerConfig{"t": {Type: "cron"}},
+			wantErrContains: []string{`trigger "t": unsupported type "cron"`},
+		},
+		{
+			name:            "github filters on http trigger",
+			triggers:        map[string]TriggerConfig{"t": {Type: TriggerHTTP, Events: StringList{"push"}}},
+			wantErrContains: []string{`'events' and 'branches' only apply to github triggers`},
+		},
+		{
+			name: "bad branch pattern and field",
+			triggers: map[string]TriggerConfig{"t": {
+				Type:     TriggerGitHub,
+				Branches: StringList{"release/["},
+				Vars:     map[string]string{"sha": "head_commit..id"},
+			}},
+			wantErrContains: []string{`invalid branch pattern "release/["`, `invalid payload field "head_commit..id" for variable "sha"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AgentflowConfig{
+				Agents:   map[string]AgentConfig{"sh": {Tool: "shell"}},
+				Tasks:    map[string]TaskConfig{"a": {Agent: "sh", Command: "true"}},
+				Triggers: tt.triggers,
+			}
+			err := ValidateWithFile(cfg, "Cortexfile.yml")
+			if len(tt.wantErrContains) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.wantErrContains {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err.Error(), want)
+				}
+			}
+		})
+	}
+}
+
+// TestValidate_TriggerVarUse tests that payload variables reach shell
+// commands only through quote(), and script code not at all.
+func TestValidate_TriggerVarUse(t *testing.T) {
+	tests := []struct {
+		name            string
+		task            TaskConfig
+		wantErrContains string
+	}{
+		{"quoted", TaskConfig{Agent: "sh", Command: "git checkout {{quote(vars.sha)}}"}, ""},
+		{"quoted with other text", TaskConfig{Agent: "sh", Command: "echo {{quote('sha: ' + vars.sha)}}"}, ""},
+		{"other variable", TaskConfig{Agent: "sh", Command: "deploy {{vars.env}}"}, ""},
+		{"in a condition", TaskConfig{Agent: "sh", Command: "{{if vars.sha == 'main'}}echo main{{end}}"}, ""},
+		{"in a prompt", TaskConfig{Agent: "ai", Prompt: "Review {{vars.sha}}"}, ""},
+		{"plain", TaskConfig{Agent: "sh", Command: "git checkout {{vars.sha}}"}, `task "a": command line 1 uses trigger variable vars.sha without quote()`},
+		{"double quotes", TaskConfig{Agent: "sh", Command: "echo \"{{vars.sha}}\""}, "without quote()"},
+		{"in another function", TaskConfig{Agent: "sh", Command: "echo {{upper(vars.sha)}}"}, "without quote()"},
+		{"next to a quoted one", TaskConfig{Agent: "sh", Command: "echo {{quote(vars.sha) + vars.sha}}"}, "without quote()"},
+		{"in an if body", TaskConfig{Agent: "sh", Command: "{{if true}}\necho {{vars.sha}}\n{{end}}"}, "command line 2"},
+		{"poll command", TaskConfig{Poll: &PollConfig{Command: "test -e {{vars.sha}}"}}, `poll command line 1 uses trigger variable vars.sha`},
+		{"script", TaskConfig{Script: &ScriptConfig{Lang: "python", Code: "print({{quote(vars.sha)}})"}}, `script line 1 uses trigger variable vars.sha`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AgentflowConfig{
+				Agents:   map[string]AgentConfig{"sh": {Tool: "shell"}, "ai": {Tool: "claude-code"}},
+				Tasks:    map[string]TaskConfig{"a": tt.task},
+				Vars:     map[string]string{"sha": "HEAD", "env": "staging"},
+				Triggers: map[string]TriggerConfig{"push": {Type: TriggerGitHub, Vars: map[string]string{"sha": "after"}}},
+			}
+			err := ValidateWithFile(cfg, "Cortexfile.yml")
+			if tt.wantErrContains == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrContains) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErrContains)
+			}
+		})
+	}
+}
@@ -1,7 +1,14 @@
 package config
 
 import (
+	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // ValidateWithFile checks the configuration for errors, including file path info.
@@ -9,14 +16,25 @@ import (
 func ValidateWithFile(config *AgentflowConfig, filePath string) error {
 	errs := &ConfigErrors{}
 
-	// Check for empty config
-	if len(config.Agents) == 0 {
+	// Check for empty config (workflows made only of built-in tasks need no agents)
+	if len(config.Agents) == 0 && needsAgents(config.Tasks) {
 		errs.Add(ErrNoAgents(filePath))
 	}
 	if len(config.Tasks) == 0 {
 		errs.Add(ErrNoTasks(filePath))
 	}
 
+	// Report keys that no field decodes, such as misspelled ones
+	for _, f := range config.unknownFields {
+		file := f.file
+		if file == "" {
+			file = filePath
+		}
+		e := NewConfigErrorWithHint(file, f.line, f.message(), f.hint())
+		e.Column = f.column
+		errs.Add(e)
+	}
+
 	// Collect available agent and task names for hints
 	availableAgents := make([]string, 0, len(config.Agents))
 	for name := range config.Agents {
@@ -32,65 +50,184 @@ func ValidateWithFile(config *AgentflowConfig, filePath string) error {
 		if agent.Tool == "" {
 			errs.Add(NewConfigErrorWithHint(filePath, 0,
 				"agent \""+name+"\": tool is required",
-				"Add 'tool: claude-code', 'tool: opencode', or 'tool: shell'"))
+				"Add 'tool: claude-code', 'tool: opencode', 'tool: aider', 'tool: api', or 'tool: shell'"))
 		} else if !IsSupportedTool(agent.Tool) {
 			errs.Add(ErrUnsupportedTool(filePath, 0, name, agent.Tool))
+		} else if agent.Tool == "api" && agent.Model == "" {
+			errs.Add(NewConfigErrorWithHint(filePath, 0,
+				"agent \""+name+"\": api agents require 'model'",
+				"Add 'model: <model_id>' (e.g. 'gpt-4o-mini') for the chat completions endpoint"))
 		}
-	}
 
-	// Validate tasks
-	for name, task := range config.Tasks {
-		// Check agent reference
-		if task.Agent == "" {
+		if agent.Tool != "shell" && agent.Shell != "" {
 			errs.Add(NewConfigErrorWithHint(filePath, 0,
-				"task \""+name+"\": agent is required",
-				"Add 'agent: <agent_name>' to specify which agent runs this task"))
-		} else if _, exists := config.Agents[task.Agent]; !exists {
-			errs.Add(ErrUndefinedAgent(filePath, 0, name, task.Agent, availableAgents))
+				"agent \""+name+"\": 'shell' only applies to shell agents",
+				"Remove 'shell', or set 'tool: shell' to run the agent's tasks as commands"))
 		}
 
-		// Get agent tool type to determine validation rules
-		var agentTool string
-		if agent, exists := config.Agents[task.Agent]; exists {
-			agentTool = agent.Tool
+		for _, e := range validateMCPServers(filePath, name, agent) {
+			errs.Add(e)
 		}
 
-		// Check prompt/command based on agent type
-		hasPrompt := task.Prompt != ""
-		hasPromptFile := task.PromptFile != ""
-		hasCommand := task.Command != ""
+		for _, e := range validateSandbox(filePath, name, agent) {
+			errs.Add(e)
+		}
 
-		if agentTool == "shell" {
-			// Shell agents require 'command' field
-			if !hasCommand {
-				errs.Add(NewConfigErrorWithHint(filePath, 0,
-					"task \""+name+"\": shell agent requires 'command' field",
-					"Add 'command: <shell_command>' to specify the command to run"))
+		if agent.SystemPrompt != "" && IsSupportedTool(agent.Tool) && !SupportsSystemPrompt(agent.Tool) {
+			errs.Add(errSystemPrompt(filePath, "agent \""+name+"\"", agent.Tool))
+		}
+
+		if agent.Tool != "api" && (agent.Proxy != "" || agent.TLS != nil || agent.Warmup) {
+			errs.Add(NewConfigErrorWithHint(filePath, 0,
+				"agent \""+name+"\": 'proxy', 'tls', and 'warmup' only apply to api agents",
+				"CLI tools read HTTPS_PROXY and their own settings; set them in the agent's 'env'"))
+		} else {
+			for _, e := range validateClientSettings(filePath, "agent \""+name+"\": ", agent.Proxy, agent.TLS) {
+				errs.Add(e)
 			}
-			if hasPrompt || hasPromptFile {
-				errs.Add(NewConfigErrorWithHint(filePath, 0,
-					"task \""+name+"\": shell agent should use 'command', not 'prompt' or 'prompt_file'",
-					"Replace 'prompt' or 'prompt_file' with 'command: <shell_command>'"))
+		}
+	}
+
+	if config.Settings != nil {
+		for _, e := range validateSettings(filePath, config.Settings) {
+			errs.Add(e)
+		}
+	}
+
+	// Validate input declarations
+	for _, e := range validateInputDecls(filePath, config.Inputs) {
+		errs.Add(e)
+	}
+
+	// Leave the value out, in case the key itself was put there
+	if config.SigningKeyEnv != "" && !envVarRegex.MatchString(config.SigningKeyEnv) {
+		errs.Add(NewConfigErrorWithHint(filePath, 0,
+			"signing_key_env is not the name of an environment variable",
+			"Set it to the name of the variable holding the key, e.g. 'CORTEX_SIGNING_KEY', rather than to the key"))
+	}
+
+	for _, e := range validateTriggers(filePath, config.Triggers) {
+		errs.Add(e)
+	}
+	for _, e := range validateTriggerVarUse(filePath, config) {
+		errs.Add(e)
+	}
+
+	for _, e := range validateGitRun(filePath, config.Git) {
+		errs.Add(e)
+	}
+
+	for _, e := range validatePhases(filePath, config, availableTasks) {
+		errs.Add(e)
+	}
+
+	for _, e := range validateStages(filePath, config, availableTasks) {
+		errs.Add(e)
+	}
+
+	// Validate tasks
+	for name, task := range config.Tasks {
+		if task.BuiltinTool() != "" {
+			for _, e := range validateBuiltinTask(filePath, name, task) {
+				errs.Add(e)
 			}
-		} else {
-			// AI agents require prompt or prompt_file
-			if !hasPrompt && !hasPromptFile {
-				errs.Add(ErrNoPrompt(filePath, 0, name))
+			for _, e := range validateLimits(filePath, name, task.Limits, task.BuiltinTool(), false) {
+				errs.Add(e)
 			}
-			if hasPrompt && hasPromptFile {
+		} else {
+			// Check agent reference
+			if task.Agent == "" {
 				errs.Add(NewConfigErrorWithHint(filePath, 0,
-					"task \""+name+"\": cannot have both 'prompt' and 'prompt_file'",
-					"Use either inline 'prompt:' or external 'prompt_file:', not both"))
+					"task \""+name+"\": agent is required",
+					"Add 'agent: <agent_name>' to specify which agent runs this task"))
+			} else if _, exists := config.Agents[task.Agent]; !exists {
+				errs.Add(ErrUndefinedAgent(filePath, 0, name, task.Agent, availableAgents))
 			}
-			if hasCommand {
+
+			// Get agent tool type to determine validation rules
+			var agentTool string
+			if agent, exists := config.Agents[task.Agent]; exists {
+				for _, e := range validateTaskTool(filePath, name, task, agent) {
+					errs.Add(e)
+				}
+				agentTool = task.RunsWith(agent).Tool
+			}
+			for _, e := range validatePermissions(filePath, name, task.Permissions, agentTool) {
+				errs.Add(e)
+			}
+			for _, e := range validateLimits(filePath, name, task.Limits, agentTool, config.Agents[task.Agent].Sandbox != nil) {
+				errs.Add(e)
+			}
+
+			// Check prompt/command based on agent type
+			hasPrompt := task.Prompt != "" && len(task.PromptFiles) == 0 // Not loaded from prompt_file
+			hasPromptFile := task.PromptFile != ""
+			hasCommand := task.Command != ""
+
+			if agentTool == "shell" {
+				// Shell agents require 'command' field
+				if !hasCommand {
+					errs.Add(NewConfigErrorWithHint(filePath, 0,
+						"task \""+name+"\": shell agent requires 'command' field",
+						"Add 'command: <shell_command>' to specify the command to run"))
+				}
+				if hasPrompt || hasPromptFile {
+					errs.Add(NewConfigErrorWithHint(filePath, 0,
+						"task \""+name+"\": shell agent should use 'command', not 'prompt' or 'prompt_file'",
+						"Replace 'prompt' or 'prompt_file' with 'command: <shell_command>'"))
+				}
+			} else {
+				// AI agents require prompt or prompt_file
+				if !hasPrompt && !hasPromptFile {
+					errs.Add(ErrNoPrompt(filePath, 0, name))
+				}
+				if hasPrompt && hasPromptFile {
+					errs.Add(NewConfigErrorWithHint(filePath, 0,
+						"task \""+name+"\": cannot have both 'prompt' and 'prompt_file'",
+						"Use either inline 'prompt:' or external 'prompt_file:', not both"))
+				}
+				if hasCommand {
+					errs.Add(NewConfigErrorWithHint(filePath, 0,
+						"task \""+name+"\": 'command' field is only for shell agents",
+						"Use 'prompt' or 'prompt_file' for AI agents, or change agent tool to 'shell'"))
+				}
+			}
+		}
+
+		if task.ItemsFrom != "" && len(task.Matrix) > 0 {
+			errs.Add(NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": cannot have both 'items_from' and 'matrix'",
+				"Use either 'items_from:' for a data file or 'matrix:' for values/globs, not both"))
+		}
+		// Instances would replace tasks with their names
+		for i := range task.Items {
+			instance := FanOutInstanceName(name, i+1)
+			if _, exists := config.Tasks[instance]; exists {
 				errs.Add(NewConfigErrorWithHint(filePath, 0,
-					"task \""+name+"\": 'command' field is only for shell agents",
-					"Use 'prompt' or 'prompt_file' for AI agents, or change agent tool to 'shell'"))
+					"task \""+name+"\": fan-out instance \""+instance+"\" has the name of another task",
+					"Rename task \""+instance+"\", or the fan-out task"))
 			}
 		}
 
+		for _, e := range validateReview(filePath, name, task, config, availableAgents) {
+			errs.Add(e)
+		}
+
+		if task.ShowOutput != "" && !containsString(SupportedShowOutputModes, task.ShowOutput) {
+			errs.Add(NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": unsupported show_output \""+task.ShowOutput+"\"",
+				"Supported values: "+strings.Join(SupportedShowOutputModes, ", ")))
+		}
+
+		for _, e := range validateOutputFormat(filePath, name, task) {
+			errs.Add(e)
+		}
+
 		// Check dependency references
 		for _, dep := range task.Needs {
+			if strings.HasPrefix(dep, StagePrefix) {
+				continue // Checked by validateStages
+			}
 			if _, exists := config.Tasks[dep]; !exists {
 				errs.Add(ErrUndefinedDependency(filePath, 0, name, dep, availableTasks))
 			}
@@ -100,10 +237,67 @@ func ValidateWithFile(config *AgentflowConfig, filePath string) error {
 		}
 
 		// Validate template variables reference valid dependencies
-		templateErrs := validateTemplateVarsStructured(filePath, name, task.Prompt, task.Needs, config.Tasks)
+		templateErrs := validateTemplateVarsStructured(filePath, name, taskTemplateText(task), task.Needs, config.Tasks)
 		for _, e := range templateErrs {
 			errs.Add(e)
 		}
+
+		for _, e := range validateTemplates(filePath, name, task, config, availableTasks) {
+			errs.Add(e)
+		}
+
+		for _, e := range validateArtifacts(filePath, name, task, config.Tasks) {
+			errs.Add(e)
+		}
+
+		for _, e := range validateFilePatterns(filePath, name, task, config.Tasks) {
+			errs.Add(e)
+		}
+
+		// Check variable references are defined
+		for _, ref := range ExtractVarRefs(taskVarText(task)) {
+			_, isVar := config.Vars[ref]
+			_, isInput := config.Inputs[ref]
+			if !isVar && !isInput {
+				errs.Add(NewConfigErrorWithHint(filePath, 0,
+					"task \""+name+"\": references undefined variable {{vars."+ref+"}}",
+					"Define it under 'vars:' or pass --var "+ref+"=<value>"))
+			}
+		}
+
+		// Check built-in variable references exist
+		for _, ref := range ExtractBuiltinRefs(taskVarText(task)) {
+			if IsBuiltinVar(ref) {
+				continue
+			}
+			hint := "Available: {{" + strings.Join(BuiltinVars, "}}, {{") + "}}"
+			if suggestion := SuggestClosestMatch(ref, BuiltinVars); suggestion != "" {
+				hint = "Did you mean {{" + suggestion + "}}?"
+			}
+			errs.Add(NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": references unknown built-in variable {{"+ref+"}}", hint))
+		}
+
+		// Params are substituted when a template is instantiated
+		if match := paramRegex.FindString(taskVarText(task)); match != "" {
+			errs.Add(NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": "+match+" outside a task template",
+				"Move the task under 'task_templates:' and instantiate it with 'template:' and 'params:'"))
+		}
+
+		for _, e := range validatePreviousRefs(filePath, name, task) {
+			errs.Add(e)
+		}
+
+		if task.When != "" {
+			for _, e := range validateCondition(filePath, name, "when", task.When, task.Needs) {
+				errs.Add(e)
+			}
+		}
+
+		for _, e := range validateLoop(filePath, name, task, config.Tasks) {
+			errs.Add(e)
+		}
 	}
 
 	// Check for circular dependencies
@@ -111,20 +305,562 @@ func ValidateWithFile(config *AgentflowConfig, filePath string) error {
 		errs.Add(ErrCircularDependency(filePath, cycle))
 	}
 
+	locateErrors(errs.Errors, filePath, config.positions)
+
 	if errs.HasErrors() {
 		return errs
 	}
 	return nil
 }
 
+// needsAgents reports whether any task (or an empty task list) requires agents.
+func needsAgents(tasks map[string]TaskConfig) bool {
+	if len(tasks) == 0 {
+		return true
+	}
+	for _, task := range tasks {
+		if task.BuiltinTool() == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTaskTool checks the tool, model, and system prompt a task sets
+// over its agent's. Shell tasks run commands and AI tasks prompts, so a task
+// can't switch between the two.
+func validateTaskTool(filePath, name string, task TaskConfig, agent AgentConfig) []*ConfigError {
+	var errs []*ConfigError
+	switch {
+	case task.Tool == "":
+	case !IsSupportedTool(task.Tool):
+		err := ErrUnsupportedTool(filePath, 0, "", task.Tool)
+		err.Message = "task \"" + name + "\" uses unsupported tool \"" + task.Tool + "\""
+		errs = append(errs, err)
+	case (task.Tool == "shell") != (agent.Tool == "shell"):
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": tool \""+task.Tool+"\" can't replace the "+agent.Tool+" tool of agent \""+task.Agent+"\"",
+			"Shell agents run commands and AI agents prompts; use an agent of the other kind instead"))
+	case task.Tool == "api" && task.RunsWith(agent).Model == "":
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": api tasks require 'model'",
+			"Add 'model: <model_id>' to the task or its agent"))
+	}
+
+	runs := task.RunsWith(agent)
+	if task.Model != "" && runs.Tool == "shell" {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": 'model' doesn't apply to shell tasks",
+			"Remove 'model'; shell tasks run their command as is"))
+	}
+	if task.Tool != "" && len(agent.MCPServers) > 0 && agent.Tool == "claude-code" && runs.Tool != "claude-code" {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": tool \""+task.Tool+"\" can't use the mcp_servers of agent \""+task.Agent+"\"",
+			"Only claude-code tasks get MCP servers; use an agent without 'mcp_servers'"))
+	}
+	if task.Tool == "api" && agent.Sandbox != nil {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": api tasks can't run in the sandbox of agent \""+task.Agent+"\"",
+			"api tasks run no process; use an agent without 'sandbox'"))
+	}
+	if (task.SystemPrompt != "" || task.Tool != "") && runs.SystemPrompt != "" && IsSupportedTool(runs.Tool) && !SupportsSystemPrompt(runs.Tool) {
+		errs = append(errs, errSystemPrompt(filePath, "task \""+name+"\"", runs.Tool))
+	}
+	return errs
+}
+
+// errSystemPrompt reports a system prompt set for a tool that takes none.
+func errSystemPrompt(filePath, subject, tool string) *ConfigError {
+	return NewConfigErrorWithHint(filePath, 0,
+		subject+": 'system_prompt' doesn't apply to "+tool+" tasks",
+		"Only claude-code, api, and custom tools with system_prompt_args take a system prompt; put the instructions in the prompt instead")
+}
+
+// validateBuiltinTask checks tasks of built-in types that run without an agent.
+func validateBuiltinTask(filePath, name string, task TaskConfig) []*ConfigError {
+	var errs []*ConfigError
+
+	if task.Agent != "" || task.Tool != "" || task.Model != "" || task.Prompt != "" || task.PromptFile != "" || task.SystemPrompt != "" || task.Permissions != nil || task.Command != "" {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": "+task.BuiltinTool()+" tasks cannot set 'agent', 'tool', 'model', 'prompt', 'prompt_file', 'system_prompt', 'permissions', or 'command'",
+			"Remove those fields; built-in task types run without an agent"))
+	}
+
+	if types := task.builtinTypes(); len(types) > 1 {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": cannot combine '"+strings.Join(types, "' and '")+"'",
+			"Split them into separate tasks connected with 'needs'"))
+	}
+
+	if task.Script != nil {
+		if !IsSupportedScriptLang(task.Script.Lang) {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": unsupported script lang \""+task.Script.Lang+"\"",
+				"Supported script languages: "+strings.Join(SupportedScriptLangs, ", ")))
+		}
+		if strings.TrimSpace(task.Script.Code) == "" {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": script requires 'code'",
+				"Add 'code: |' with the script source under 'script:'"))
+		}
+	}
+
+	if task.HTTP != nil {
+		errs = append(errs, validateHTTP(filePath, name, task.HTTP)...)
+	}
+
+	if task.Git != nil {
+		errs = append(errs, validateGit(filePath, name, task.Git)...)
+	}
+
+	if task.Wait != nil && !isPositiveDuration(task.Wait.Duration) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": wait requires a positive 'duration'",
+			"Use a duration like '30s' or '5m'"))
+	}
+
+	if task.Poll != nil {
+		errs = append(errs, validatePoll(filePath, name, task.Poll)...)
+	}
+
+	if task.Notify != nil {
+		if strings.TrimSpace(task.Notify.Message) == "" {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": notify requires 'message'",
+				"Add 'message: ...' under 'notify:'"))
+		}
+		if task.Notify.Level != "" && !containsString(SupportedNotifyLevels, task.Notify.Level) {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": unsupported notify level \""+task.Notify.Level+"\"",
+				"Supported levels: "+strings.Join(SupportedNotifyLevels, ", ")))
+		}
+	}
+
+	if task.PullRequest != nil {
+		errs = append(errs, validatePullRequest(filePath, name, task.PullRequest)...)
+	}
+
+	return errs
+}
+
+// validateHTTP checks the request of an http task.
+func validateHTTP(filePath, name string, h *HTTPConfig) []*ConfigError {
+	var errs []*ConfigError
+
+	if strings.TrimSpace(h.URL) == "" {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": http requires 'url'",
+			"Add 'url: https://...' under 'http:'"))
+	}
+	if h.Method != "" && !containsString(SupportedHTTPMethods, strings.ToUpper(h.Method)) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": unsupported http method \""+h.Method+"\"",
+			"Supported methods: "+strings.Join(SupportedHTTPMethods, ", ")))
+	}
+	if h.ExpectStatus != 0 && (h.ExpectStatus < 100 || h.ExpectStatus > 599) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": invalid expect_status "+strconv.Itoa(h.ExpectStatus),
+			"Use an HTTP status code between 100 and 599"))
+	}
+	if h.Timeout != "" && !isPositiveDuration(h.Timeout) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": invalid http timeout \""+h.Timeout+"\"",
+			"Use a positive duration like '30s' or '2m'"))
+	}
+	errs = append(errs, validateClientSettings(filePath, "task \""+name+"\": ", h.Proxy, h.TLS)...)
+
+	return errs
+}
+
+// validateClientSettings checks the proxy URL and TLS files of an HTTP
+// client. prefix names the agent or task in messages.
+func validateClientSettings(filePath, prefix, proxy string, tls *TLSConfig) []*ConfigError {
+	var errs []*ConfigError
+
+	// ${NAME} references are expanded at run time
+	if proxy != "" && !strings.Contains(proxy, "${") {
+		u, err := url.Parse(proxy)
+		if err != nil || u.Host == "" || !containsString(SupportedProxySchemes, u.Scheme) {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				prefix+"invalid proxy \""+proxy+"\"",
+				"Use a URL like 'http://proxy.internal:3128' (schemes: "+strings.Join(SupportedProxySchemes, ", ")+")"))
+		}
+	}
+
+	if tls == nil {
+		return errs
+	}
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			prefix+"tls needs both 'cert_file' and 'key_file'",
+			"Set both to use a client certificate, or neither"))
+	}
+	for _, file := range []struct{ key, path string }{{"ca_file", tls.CAFile}, {"cert_file", tls.CertFile}, {"key_file", tls.KeyFile}} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				prefix+"tls "+file.key+" not found: "+file.path,
+				"Paths are relative to the Cortexfile"))
+		}
+	}
+	return errs
+}
+
+// validatePoll checks that a poll task has exactly one check and valid timing.
+func validatePoll(filePath, name string, p *PollConfig) []*ConfigError {
+	var errs []*ConfigError
+
+	hasCommand := strings.TrimSpace(p.Command) != ""
+	switch {
+	case hasCommand && p.HTTP != nil:
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": poll cannot have both 'command' and 'http'",
+			"Choose one way to check readiness"))
+	case !hasCommand && p.HTTP == nil:
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": poll requires 'command' or 'http'",
+			"Add a command that exits 0 when ready, or an http check"))
+	case p.HTTP != nil:
+		errs = append(errs, validateHTTP(filePath, name, p.HTTP)...)
+	}
+
+	for _, field := range []struct{ key, value string }{{"interval", p.Interval}, {"timeout", p.Timeout}} {
+		if field.value != "" && !isPositiveDuration(field.value) {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": invalid poll "+field.key+" \""+field.value+"\"",
+				"Use a positive duration like '10s' or '15m'"))
+		}
+	}
+
+	return errs
+}
+
+// validateKillTimeout checks settings.kill_timeout.
+func validateKillTimeout(filePath, timeout string) []*ConfigError {
+	if timeout == "" || isPositiveDuration(timeout) {
+		return nil
+	}
+	return []*ConfigError{NewConfigErrorWithHint(filePath, 0,
+		"settings: invalid kill_timeout \""+timeout+"\"",
+		"Use a positive duration like '5s' or '1m'")}
+}
+
+// validateColor checks settings.color.
+func validateColor(filePath, color string) []*ConfigError {
+	if color == "" || containsString(SupportedColorModes, color) {
+		return nil
+	}
+	return []*ConfigError{NewConfigErrorWithHint(filePath, 0,
+		"settings: unsupported color \""+color+"\"",
+		"Supported values: "+strings.Join(SupportedColorModes, ", "))}
+}
+
+// validateMaxOutput checks settings.max_output_bytes.
+func validateMaxOutput(filePath, size string) []*ConfigError {
+	if size == "" {
+		return nil
+	}
+	if _, err := ParseSize(size); err != nil {
+		return []*ConfigError{NewConfigError(filePath, 0, "settings: max_output_bytes: "+err.Error())}
+	}
+	return nil
+}
+
+// isPositiveDuration reports whether s parses as a duration greater than zero.
+func isPositiveDuration(s string) bool {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	return err == nil && d > 0
+}
+
+// validateGit checks that a git task sets the fields its op requires and
+// no fields that belong to other ops. Branches filled from task outputs are
+// checked again when the task runs.
+func validateGit(filePath, name string, g *GitConfig) []*ConfigError {
+	if !containsString(SupportedGitOps, g.Op) {
+		return []*ConfigError{NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": unsupported git op \""+g.Op+"\"",
+			"Supported ops: "+strings.Join(SupportedGitOps, ", "))}
+	}
+
+	var errs []*ConfigError
+	require := func(field, value string) {
+		if strings.TrimSpace(value) == "" {
+			errs = append(errs, NewConfigError(filePath, 0,
+				"task \""+name+"\": git "+g.Op+" requires '"+field+"'"))
+		}
+	}
+	reject := func(field string, set bool) {
+		if set {
+			errs = append(errs, NewConfigError(filePath, 0,
+				"task \""+name+"\": git "+g.Op+" does not use '"+field+"'"))
+		}
+	}
+
+	switch g.Op {
+	case GitClone:
+		require("repo", g.Repo)
+		if g.Depth < 0 {
+			errs = append(errs, NewConfigError(filePath, 0,
+				"task \""+name+"\": git depth must be positive"))
+		}
+	case GitCheckout, GitCreateBranch:
+		require("branch", g.Branch)
+	case GitCommit:
+		require("message", g.Message)
+	}
+
+	// git would read these as options
+	for _, f := range []struct{ field, value string }{{"branch", g.Branch}, {"remote", g.Remote}} {
+		if strings.HasPrefix(strings.TrimSpace(f.value), "-") {
+			errs = append(errs, NewConfigError(filePath, 0,
+				"task \""+name+"\": git "+f.field+" must not start with '-'"))
+		}
+	}
+
+	reject("repo", g.Op != GitClone && g.Repo != "")
+	reject("depth", g.Op != GitClone && g.Depth != 0)
+	reject("message", g.Op != GitCommit && g.Message != "")
+	reject("paths", g.Op != GitCommit && len(g.Paths) > 0)
+	reject("remote", g.Op != GitPush && g.Remote != "")
+	reject("force", g.Op != GitPush && g.Force)
+
+	return errs
+}
+
+// repoNameRegex matches a GitHub repository as owner/name.
+var repoNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// envVarRegex matches an environment variable name.
+var envVarRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validatePullRequest checks the pull request of a pull_request task.
+// Fields filled from task outputs are checked when the task runs.
+func validatePullRequest(filePath, name string, p *PullRequestConfig) []*ConfigError {
+	var errs []*ConfigError
+	if strings.TrimSpace(p.Title) == "" {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": pull_request requires 'title'",
+			"Add 'title: ...' under 'pull_request:'"))
+	}
+	if p.Repo != "" && !strings.Contains(p.Repo, "{{") && !repoNameRegex.MatchString(p.Repo) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": invalid pull_request repo \""+p.Repo+"\"",
+			"Use owner/name, e.g. 'octocat/hello-world'"))
+	}
+	if p.TokenEnv != "" && !envVarRegex.MatchString(p.TokenEnv) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": invalid pull_request token_env \""+p.TokenEnv+"\"",
+			"Use the name of the environment variable holding the token, e.g. 'GITHUB_TOKEN'"))
+	}
+	if p.APIURL != "" {
+		if u, err := url.Parse(p.APIURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": invalid pull_request api_url \""+p.APIURL+"\"",
+				"Use the API endpoint of your GitHub Enterprise server, e.g. 'https://github.example.com/api/v3'"))
+		}
+	}
+	return errs
+}
+
+// validateArtifacts checks artifact glob patterns and that {{artifacts.X}}
+// references point at dependencies that declare artifacts.
+func validateArtifacts(filePath, name string, task TaskConfig, tasks map[string]TaskConfig) []*ConfigError {
+	var errs []*ConfigError
+
+	for _, pattern := range task.Artifacts {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": invalid artifacts pattern \""+pattern+"\"",
+				"Use a file path or a glob like 'dist/*.tar.gz'"))
+		}
+	}
+
+	for _, ref := range ExtractArtifactVars(taskVarText(task)) {
+		refTask, exists := tasks[ref]
+		switch {
+		case !exists:
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": template references undefined task \""+ref+"\"",
+				"Define the task or fix the template variable name"))
+		case !containsString(task.Needs, ref):
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": template references \""+ref+"\" which is not in 'needs'",
+				"Add '"+ref+"' to the 'needs' list to ensure it runs first"))
+		case len(refTask.Artifacts) == 0:
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": {{artifacts."+ref+"}} references a task without 'artifacts'",
+				"Add 'artifacts:' to task \""+ref+"\""))
+		}
+	}
+
+	return errs
+}
+
+// validateFilePatterns checks that prompt_file globs and inputs match at least
+// one file, so a typo fails validation rather than the run. An input that
+// another task declares as an output may not exist before the run and is not
+// checked. A prompt_file path without globs is checked when it is loaded.
+func validateFilePatterns(filePath, name string, task TaskConfig, tasks map[string]TaskConfig) []*ConfigError {
+	var errs []*ConfigError
+	baseDir := filepath.Dir(filePath)
+
+	check := func(field, pattern string) {
+		matches, err := MatchFiles(pattern, baseDir)
+		display := displayPath(pattern, baseDir)
+		switch {
+		case err != nil:
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": invalid "+field+" pattern \""+display+"\"",
+				"Use a file path or a glob like 'prompts/*.md'"))
+		case len(matches) == 0:
+			errs = append(errs, ErrNoFilesMatched(filePath, 0, name, field, display))
+		}
+	}
+
+	if containsGlob(task.PromptFile) {
+		check("prompt_file", task.PromptFile)
+	}
+	for _, pattern := range task.Inputs {
+		if !producedByTask(pattern, tasks) {
+			check("inputs", pattern)
+		}
+	}
+
+	return errs
+}
+
+// producedByTask reports whether a path or glob matches an output declared by
+// any task.
+func producedByTask(pattern string, tasks map[string]TaskConfig) bool {
+	for _, task := range tasks {
+		for _, output := range task.Outputs {
+			if output == pattern {
+				return true
+			}
+			if matched, _ := filepath.Match(pattern, output); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// displayPath shortens a path under baseDir to a relative one for messages.
+func displayPath(path, baseDir string) string {
+	if rel, err := filepath.Rel(baseDir, path); err == nil && filepath.IsAbs(path) && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// validateCondition checks that a task's `when` or `until` expression
+// parses and that any {{status.X}} it references is in deps.
+func validateCondition(filePath, name, field, when string, deps []string) []*ConfigError {
+	cond, err := ParseCondition(when)
+	if err != nil {
+		return []*ConfigError{NewConfigErrorWithHint(filePath, 0,
+			"task \""+name+"\": invalid '"+field+"': "+err.Error(),
+			"Use '<value> contains|not contains|==|!= <value>', a single value, or a {{ expression }}")}
+	}
+	if cond.Expr != nil {
+		return validateExprRefs(filePath, name, field, cond.Expr, deps)
+	}
+
+	var errs []*ConfigError
+	for _, ref := range ExtractStatusVars(when) {
+		if !containsString(deps, ref) {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": '"+field+"' references {{status."+ref+"}} but \""+ref+"\" is not in 'needs'",
+				"Add '"+ref+"' to the 'needs' list"))
+		}
+	}
+	return errs
+}
+
+// validateExprRefs checks that a `when` or `until` expression only
+// references known variables and that referenced tasks are in deps.
+func validateExprRefs(filePath, name, field string, expr *Expr, deps []string) []*ConfigError {
+	var errs []*ConfigError
+	for _, ref := range expr.Vars() {
+		root, task, _ := strings.Cut(ref, ".")
+		switch root {
+		case "outputs", "status":
+			task, _, _ = strings.Cut(task, ".")
+			if !containsString(deps, task) {
+				errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+					"task \""+name+"\": '"+field+"' references "+ref+" but \""+task+"\" is not in 'needs'",
+					"Add '"+task+"' to the 'needs' list"))
+			}
+		case "env", "cpu_count":
+		default:
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+name+"\": '"+field+"' references unknown variable "+ref,
+				"Expressions can use outputs.<task>, status.<task>, env.<NAME>, and cpu_count"))
+		}
+	}
+	return errs
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// taskVarText returns all task text that may contain {{vars.name}} references.
+func taskVarText(task TaskConfig) string {
+	text := taskTemplateText(task) + "\n" + task.Command
+	for _, v := range task.Env {
+		text += "\n" + v
+	}
+	return text
+}
+
+// taskTemplateText returns all task text that may contain template variables.
+func taskTemplateText(task TaskConfig) string {
+	text := task.Prompt + "\n" + task.When
+	if task.Script != nil {
+		text += "\n" + task.Script.Code
+	}
+	if task.HTTP != nil {
+		text += "\n" + task.HTTP.Text()
+	}
+	if task.Git != nil {
+		text += "\n" + task.Git.Text()
+	}
+	if task.Wait != nil {
+		text += "\n" + task.Wait.Duration
+	}
+	if task.Poll != nil {
+		text += "\n" + task.Poll.Text()
+	}
+	if task.Notify != nil {
+		text += "\n" + task.Notify.Message
+	}
+	if task.PullRequest != nil {
+		text += "\n" + task.PullRequest.Text()
+	}
+	return text
+}
+
 // Validate checks the configuration for errors (backward compatible).
 // Returns nil if valid, or a ConfigErrors with all issues found.
 func Validate(config *AgentflowConfig) error {
 	return ValidateWithFile(config, "Cortexfile.yml")
 }
 
-// templateVarRegex matches {{outputs.taskname}} patterns.
-var templateVarRegex = regexp.MustCompile(`\{\{outputs\.([a-zA-Z0-9_-]+)\}\}`)
+// templateVarRegex matches {{outputs.taskname}} patterns. The {{outputs.taskname.*}}
+// form is an explicit alias for the combined output of a fan-out task, and
+// {{outputs.taskname.field}} selects a field of JSON output; either suffix is
+// captured in the second group. Any "| modifier N" suffix is captured in the
+// third group.
+var templateVarRegex = regexp.MustCompile(`\{\{outputs\.([a-zA-Z0-9_-]+)(\.\*|(?:\.[a-zA-Z0-9_-]+)*)(\s*\|[^{}]*)?\}\}`)
 
 // validateTemplateVarsStructured checks that all {{outputs.X}} references are valid dependencies.
 func validateTemplateVarsStructured(filePath, taskName, prompt string, needs []string, tasks map[string]TaskConfig) []*ConfigError {
@@ -139,6 +875,12 @@ func validateTemplateVarsStructured(filePath, taskName, prompt string, needs []s
 	for _, match := range matches {
 		refTask := match[1]
 
+		if _, err := parseOutputModifiers(match[3]); err != nil {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"task \""+taskName+"\": invalid template "+match[0]+": "+err.Error(),
+				"Supported modifiers: "+strings.Join(OutputModifiers, ", ")+" (e.g. {{outputs."+refTask+" | tail 20}})"))
+		}
+
 		// Check if referenced task exists
 		if _, exists := tasks[refTask]; !exists {
 			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
@@ -147,6 +889,12 @@ func validateTemplateVarsStructured(filePath, taskName, prompt string, needs []s
 			continue
 		}
 
+		if field := match[2]; field != "" && field != ".*" {
+			if err := validateOutputField(filePath, taskName, match[0], refTask, field[1:], tasks); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
 		// Check if referenced task is in needs
 		if !needsSet[refTask] {
 			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
@@ -218,3 +966,46 @@ func detectCycles(tasks map[string]TaskConfig) error {
 
 // ValidationError is kept for backward compatibility.
 type ValidationError = ConfigErrors
+
+// validateSettings checks the settings that take one of a fixed set of values.
+func validateSettings(filePath string, settings *SettingsConfig) []*ConfigError {
+	var errs []*ConfigError
+	if settings.Output != "" && !containsString(SupportedOutputModes, settings.Output) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"settings: unsupported output \""+settings.Output+"\"",
+			"Supported values: "+strings.Join(SupportedOutputModes, ", ")))
+	}
+	if settings.Theme.Palette != "" && !containsString(SupportedPalettes, settings.Theme.Palette) {
+		errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+			"settings: unsupported theme palette \""+settings.Theme.Palette+"\"",
+			"Supported values: "+strings.Join(SupportedPalettes, ", ")))
+	}
+	if endpoint := settings.Telemetry.Endpoint; endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"settings: invalid telemetry endpoint \""+endpoint+"\"",
+				"Use the OTLP/HTTP URL of a collector, like 'http://localhost:4318'"))
+		}
+	}
+	errs = append(errs, validateRetention(filePath, settings.Retention)...)
+	errs = append(errs, validateKillTimeout(filePath, settings.KillTimeout)...)
+	errs = append(errs, validateColor(filePath, settings.Color)...)
+	errs = append(errs, validateMaxOutput(filePath, settings.MaxOutputBytes)...)
+	statuses := make([]string, 0, len(settings.Theme.Glyphs))
+	for status := range settings.Theme.Glyphs {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		if !containsString(SupportedGlyphStatuses, status) {
+			errs = append(errs, NewConfigErrorWithHint(filePath, 0,
+				"settings: unknown theme glyph status \""+status+"\"",
+				"Supported statuses: "+strings.Join(SupportedGlyphStatuses, ", ")))
+		} else if strings.TrimSpace(settings.Theme.Glyphs[status]) == "" {
+			errs = append(errs, NewConfigError(filePath, 0,
+				"settings: theme glyph for \""+status+"\" is empty"))
+		}
+	}
+	return errs
+}
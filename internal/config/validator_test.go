@@ -1,8 +1,11 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Helper to check if any error contains a substring
@@ -107,6 +110,16 @@ func TestValidate_AgentValidation(t *testing.T) {
 			},
 			wantErrContains: []string{`unsupported tool "invalid-tool"`},
 		},
+		{
+			name: "api agent without model",
+			agents: map[string]AgentConfig{
+				"agent1": {Tool: "api", BaseURL: "http://localhost:8080/v1"},
+			},
+			tasks: map[string]TaskConfig{
+				"task1": {Agent: "agent1", Prompt: "test"},
+			},
+			wantErrContains: []string{`api agents require 'model'`},
+		},
 		{
 			name: "valid supported tools",
 			agents: map[string]AgentConfig{
@@ -207,6 +220,184 @@ func TestValidate_TaskValidation(t *testing.T) {
 			},
 			wantErrContains: []string{`cannot depend on itself`},
 		},
+		{
+			name: "script with unsupported lang",
+			tasks: map[string]TaskConfig{
+				"task1": {Script: &ScriptConfig{Lang: "ruby", Code: "puts 1"}},
+			},
+			wantErrContains: []string{`unsupported script lang "ruby"`},
+		},
+		{
+			name: "script without code",
+			tasks: map[string]TaskConfig{
+				"task1": {Script: &ScriptConfig{Lang: "python"}},
+			},
+			wantErrContains: []string{`script requires 'code'`},
+		},
+		{
+			name: "script with agent",
+			tasks: map[string]TaskConfig{
+				"task1": {Agent: "agent1", Script: &ScriptConfig{Lang: "node", Code: "console.log(1)"}},
+			},
+			wantErrContains: []string{`script tasks cannot set 'agent'`},
+		},
+		{
+			name: "http without url",
+			tasks: map[string]TaskConfig{
+				"task1": {HTTP: &HTTPConfig{Method: "POST"}},
+			},
+			wantErrContains: []string{`http requires 'url'`},
+		},
+		{
+			name: "http with invalid method, status, and timeout",
+			tasks: map[string]TaskConfig{
+				"task1": {HTTP: &HTTPConfig{Method: "FETCH", URL: "https://example.com", ExpectStatus: 42, Timeout: "soon"}},
+			},
+			wantErrContains: []string{`unsupported http method "FETCH"`, `invalid expect_status 42`, `invalid http timeout "soon"`},
+		},
+		{
+			name: "script and http combined",
+			tasks: map[string]TaskConfig{
+				"task1": {Script: &ScriptConfig{Lang: "python", Code: "print(1)"}, HTTP: &HTTPConfig{URL: "https://example.com"}},
+			},
+			wantErrContains: []string{`cannot combine 'script' and 'http'`},
+		},
+		{
+			name: "wait without valid duration",
+			tasks: map[string]TaskConfig{
+				"task1": {Wait: &WaitConfig{}},
+				"task2": {Wait: &WaitConfig{Duration: "-5s"}},
+			},
+			wantErrContains: []string{`task "task1": wait requires a positive 'duration'`, `task "task2": wait requires a positive 'duration'`},
+		},
+		{
+			name: "poll without check",
+			tasks: map[string]TaskConfig{
+				"task1": {Poll: &PollConfig{Interval: "10s"}},
+			},
+			wantErrContains: []string{`poll requires 'command' or 'http'`},
+		},
+		{
+			name: "poll with both checks and bad timing",
+			tasks: map[string]TaskConfig{
+				"task1": {Poll: &PollConfig{Command: "true", HTTP: &HTTPConfig{URL: "https://example.com"}, Timeout: "forever"}},
+			},
+			wantErrContains: []string{`poll cannot have both 'command' and 'http'`, `invalid poll timeout "forever"`},
+		},
+		{
+			name: "poll with invalid http check",
+			tasks: map[string]TaskConfig{
+				"task1": {Poll: &PollConfig{HTTP: &HTTPConfig{Method: "GET"}}},
+			},
+			wantErrContains: []string{`http requires 'url'`},
+		},
+		{
+			name: "notify without message and with bad level",
+			tasks: map[string]TaskConfig{
+				"task1": {Notify: &NotifyConfig{Level: "urgent"}},
+			},
+			wantErrContains: []string{`notify requires 'message'`, `unsupported notify level "urgent"`},
+		},
+		{
+			name: "invalid show_output",
+			tasks: map[string]TaskConfig{
+				"task1": {Script: &ScriptConfig{Lang: "python", Code: "print(1)"}, ShowOutput: "verbose"},
+			},
+			wantErrContains: []string{`unsupported show_output "verbose"`},
+		},
+		{
+			name: "invalid artifacts pattern",
+			tasks: map[string]TaskConfig{
+				"task1": {Agent: "agent1", Prompt: "build", Artifacts: StringList{"dist/[a-"}},
+			},
+			wantErrContains: []string{`invalid artifacts pattern "dist/[a-"`},
+		},
+		{
+			name: "artifacts reference without artifacts or needs",
+			tasks: map[string]TaskConfig{
+				"task1": {Agent: "agent1", Prompt: "build"},
+				"task2": {Agent: "agent1", Prompt: "build", Artifacts: StringList{"out.txt"}},
+				"task3": {Agent: "agent1", Prompt: "review {{artifacts.task1}} and {{artifacts.task2}}", Needs: []string{"task1"}},
+			},
+			wantErrContains: []string{
+				`{{artifacts.task1}} references a task without 'artifacts'`,
+				`template references "task2" which is not in 'needs'`,
+			},
+		},
+		{
+			name: "invalid output modifier",
+			tasks: map[string]TaskConfig{
+				"task1": {Agent: "agent1", Prompt: "analyze"},
+				"task2": {Agent: "agent1", Prompt: "{{outputs.task1 | first 10}}", Needs: []string{"task1"}},
+			},
+			wantErrContains: []string{`invalid template {{outputs.task1 | first 10}}: unknown modifier "first"`},
+		},
+		{
+			name: "git with unsupported op",
+			tasks: map[string]TaskConfig{
+				"task1": {Git: &GitConfig{Op: "rebase"}},
+			},
+			wantErrContains: []string{`unsupported git op "rebase"`},
+		},
+		{
+			name: "git ops missing required fields",
+			tasks: map[string]TaskConfig{
+				"task1": {Git: &GitConfig{Op: "clone"}},
+				"task2": {Git: &GitConfig{Op: "create-branch"}},
+				"task3": {Git: &GitConfig{Op: "commit"}},
+			},
+			wantErrContains: []string{`git clone requires 'repo'`, `git create-branch requires 'branch'`, `git commit requires 'message'`},
+		},
+		{
+			name: "git field from another op",
+			tasks: map[string]TaskConfig{
+				"task1": {Git: &GitConfig{Op: "commit", Message: "wip", Force: true}},
+			},
+			wantErrContains: []string{`git commit does not use 'force'`},
+		},
+		{
+			name: "git names that look like options",
+			tasks: map[string]TaskConfig{
+				"task1": {Git: &GitConfig{Op: "push", Branch: "--force", Remote: "-u"}},
+			},
+			wantErrContains: []string{`git branch must not start with '-'`, `git remote must not start with '-'`},
+		},
+		{
+			name: "invalid pull request",
+			tasks: map[string]TaskConfig{
+				"task1": {PullRequest: &PullRequestConfig{Repo: "widgets", TokenEnv: "GH-TOKEN", APIURL: "github.example.com"}},
+				"task2": {PullRequest: &PullRequestConfig{Title: "Fix", Repo: "{{outputs.task1}}"}, Needs: []string{"task1"}},
+			},
+			wantErrContains: []string{
+				`task "task1": pull_request requires 'title'`,
+				`invalid pull_request repo "widgets"`,
+				`invalid pull_request token_env "GH-TOKEN"`,
+				`invalid pull_request api_url "github.example.com"`,
+			},
+		},
+		{
+			name: "when with missing operand",
+			tasks: map[string]TaskConfig{
+				"task1": {Agent: "agent1", Prompt: "test", When: "== 'x'"},
+			},
+			wantErrContains: []string{`task "task1": invalid 'when'`},
+		},
+		{
+			name: "when status references task not in needs",
+			tasks: map[string]TaskConfig{
+				"task1": {Agent: "agent1", Prompt: "test"},
+				"task2": {Agent: "agent1", Prompt: "test", When: "{{status.task1}} == skipped"},
+			},
+			wantErrContains: []string{`references {{status.task1}} but "task1" is not in 'needs'`},
+		},
+		{
+			name: "when output references task not in needs",
+			tasks: map[string]TaskConfig{
+				"task1": {Agent: "agent1", Prompt: "test"},
+				"task2": {Agent: "agent1", Prompt: "test", When: "{{outputs.task1}} contains 'FAIL'"},
+			},
+			wantErrContains: []string{`task1`},
+		},
 	}
 
 	for _, tt := range tests {
@@ -583,6 +774,9 @@ func TestIsSupportedTool(t *testing.T) {
 	}{
 		{"claude-code supported", "claude-code", true},
 		{"opencode supported", "opencode", true},
+		{"aider supported", "aider", true},
+		{"api supported", "api", true},
+		{"shell supported", "shell", true},
 		{"unsupported tool", "invalid-tool", false},
 		{"empty string", "", false},
 		{"case sensitive", "Claude-Code", false},
@@ -596,3 +790,425 @@ func TestIsSupportedTool(t *testing.T) {
 		})
 	}
 }
+
+// TestValidate_ShellAgent tests the command rules for shell agents.
+func TestValidate_ShellAgent(t *testing.T) {
+	agents := map[string]AgentConfig{"sh": {Tool: "shell"}}
+
+	valid := &AgentflowConfig{
+		Agents: agents,
+		Tasks: map[string]TaskConfig{
+			"build": {Agent: "sh", Command: "make build"},
+		},
+	}
+	if err := Validate(valid); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	invalid := &AgentflowConfig{
+		Agents: agents,
+		Tasks: map[string]TaskConfig{
+			"build": {Agent: "sh", Prompt: "make build"},
+		},
+	}
+	err := Validate(invalid)
+	if err == nil {
+		t.Fatal("expected validation error, got nil")
+	}
+	valErr, ok := err.(*ConfigErrors)
+	if !ok {
+		t.Fatalf("expected *ConfigErrors, got %T", err)
+	}
+	for _, want := range []string{"shell agent requires 'command' field", "shell agent should use 'command'"} {
+		if !errorsContain(valErr, want) {
+			t.Errorf("expected error containing %q, got: %v", want, valErr.Error())
+		}
+	}
+}
+
+// TestValidate_ScriptOnlyWorkflow tests that workflows with only built-in tasks need no agents.
+func TestValidate_ScriptOnlyWorkflow(t *testing.T) {
+	config := &AgentflowConfig{
+		Tasks: map[string]TaskConfig{
+			"fetch": {Script: &ScriptConfig{Lang: "python", Code: "print('data')"}},
+			"count": {
+				Script: &ScriptConfig{Lang: "node", Code: "console.log(`{{outputs.fetch}}`.length)"},
+				Needs:  []string{"fetch"},
+			},
+			"post": {
+				HTTP:  &HTTPConfig{Method: "post", URL: "https://example.com/notes", Body: `{"count": "{{outputs.count}}"}`},
+				Needs: []string{"count"},
+			},
+			"commit": {
+				Git:   &GitConfig{Op: "commit", Message: "Update notes ({{outputs.count}} items)", Paths: StringList{"notes"}},
+				Needs: []string{"count"},
+			},
+			"settle": {Wait: &WaitConfig{Duration: "30s"}, Needs: []string{"commit"}},
+			"ci": {
+				Poll:  &PollConfig{Command: "gh run view {{outputs.commit}} --exit-status", Interval: "30s", Timeout: "20m"},
+				Needs: []string{"commit", "settle"},
+			},
+			"announce": {
+				Notify: &NotifyConfig{Message: "CI passed for {{outputs.commit | max_chars 7}}", Level: "success"},
+				Needs:  []string{"ci", "commit"},
+			},
+		},
+	}
+
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+// TestValidate_SettingsOutput tests validation of settings.output.
+func TestValidate_SettingsOutput(t *testing.T) {
+	config := &AgentflowConfig{
+		Tasks:    map[string]TaskConfig{"build": {Script: &ScriptConfig{Lang: "python", Code: "print(1)"}}},
+		Settings: &SettingsConfig{Output: OutputPrefixed},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	config.Settings.Output = "columns"
+	err := Validate(config)
+	if err == nil || !strings.Contains(err.Error(), `settings: unsupported output "columns"`) {
+		t.Errorf("expected unsupported output error, got: %v", err)
+	}
+}
+
+// TestValidate_SettingsTheme tests validation of settings.theme.
+func TestValidate_SettingsTheme(t *testing.T) {
+	config := &AgentflowConfig{
+		Tasks: map[string]TaskConfig{"build": {Script: &ScriptConfig{Lang: "python", Code: "print(1)"}}},
+		Settings: &SettingsConfig{Theme: ThemeConfig{
+			Palette: PaletteAccessible,
+			Glyphs:  map[string]string{"success": "+", "failed": "x", "cancelled": "-"},
+		}},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	config.Settings.Theme = ThemeConfig{
+		Palette: "neon",
+		Glyphs:  map[string]string{"done": "+", "failed": " "},
+	}
+	err := Validate(config)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	for _, want := range []string{
+		`unsupported theme palette "neon"`,
+		`unknown theme glyph status "done"`,
+		`theme glyph for "failed" is empty`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
+
+// TestValidate_SettingsTelemetry tests validation of settings.telemetry.
+func TestValidate_SettingsTelemetry(t *testing.T) {
+	config := &AgentflowConfig{
+		Tasks:    map[string]TaskConfig{"build": {Script: &ScriptConfig{Lang: "python", Code: "print(1)"}}},
+		Settings: &SettingsConfig{Telemetry: TelemetryConfig{Endpoint: "https://otel.example.com:4318"}},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	for _, endpoint := range []string{"localhost:4318", "grpc://collector:4317", "http://"} {
+		config.Settings.Telemetry.Endpoint = endpoint
+		err := Validate(config)
+		if err == nil || !strings.Contains(err.Error(), "invalid telemetry endpoint") {
+			t.Errorf("endpoint %q: expected invalid endpoint error, got: %v", endpoint, err)
+		}
+	}
+}
+
+// TestValidate_SettingsKillTimeout tests validation of settings.kill_timeout.
+func TestValidate_SettingsKillTimeout(t *testing.T) {
+	config := &AgentflowConfig{
+		Tasks:    map[string]TaskConfig{"build": {Script: &ScriptConfig{Lang: "python", Code: "print(1)"}}},
+		Settings: &SettingsConfig{KillTimeout: "30s"},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got := config.Settings.KillTimeoutDuration(); got != 30*time.Second {
+		t.Errorf("KillTimeoutDuration() = %v, want 30s", got)
+	}
+
+	for _, timeout := range []string{"30", "-5s", "0s"} {
+		config.Settings.KillTimeout = timeout
+		err := Validate(config)
+		if err == nil || !strings.Contains(err.Error(), `settings: invalid kill_timeout "`+timeout+`"`) {
+			t.Errorf("kill_timeout %q: expected invalid kill_timeout error, got: %v", timeout, err)
+		}
+	}
+	if got := (SettingsConfig{}).KillTimeoutDuration(); got != DefaultKillTimeout {
+		t.Errorf("default KillTimeoutDuration() = %v, want %v", got, DefaultKillTimeout)
+	}
+}
+
+// TestValidate_SettingsMaxOutputBytes tests validation of
+// settings.max_output_bytes, which takes a size with or without a unit.
+func TestValidate_SettingsMaxOutputBytes(t *testing.T) {
+	config := &AgentflowConfig{
+		Tasks:    map[string]TaskConfig{"build": {Script: &ScriptConfig{Lang: "python", Code: "print(1)"}}},
+		Settings: &SettingsConfig{MaxOutputBytes: "1MB"},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got := config.Settings.MaxOutput(); got != 1<<20 {
+		t.Errorf("MaxOutput() = %d, want %d", got, 1<<20)
+	}
+	if got := (SettingsConfig{MaxOutputBytes: "65536"}).MaxOutput(); got != 65536 {
+		t.Errorf("MaxOutput() of 65536 = %d, want 65536", got)
+	}
+
+	config.Settings.MaxOutputBytes = "a lot"
+	err := Validate(config)
+	if err == nil || !strings.Contains(err.Error(), `settings: max_output_bytes: invalid size "a lot"`) {
+		t.Errorf("expected invalid max_output_bytes error, got: %v", err)
+	}
+	if got := (SettingsConfig{}).MaxOutput(); got != 0 {
+		t.Errorf("default MaxOutput() = %d, want 0", got)
+	}
+}
+
+// TestValidate_SettingsColor tests that settings.color only accepts auto, always, and never.
+func TestValidate_SettingsColor(t *testing.T) {
+	config := &AgentflowConfig{
+		Tasks:    map[string]TaskConfig{"build": {Script: &ScriptConfig{Lang: "python", Code: "print(1)"}}},
+		Settings: &SettingsConfig{},
+	}
+	for _, color := range SupportedColorModes {
+		config.Settings.Color = color
+		if err := Validate(config); err != nil {
+			t.Errorf("color %q: expected no error, got: %v", color, err)
+		}
+	}
+
+	config.Settings.Color = "yes"
+	err := Validate(config)
+	if err == nil || !strings.Contains(err.Error(), `settings: unsupported color "yes"`) {
+		t.Errorf("expected unsupported color error, got: %v", err)
+	}
+}
+
+// TestValidate_FilePatterns tests that prompt_file globs and inputs must match files.
+func TestValidate_FilePatterns(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte("prompt"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	filePath := filepath.Join(dir, "Cortexfile.yml")
+
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{"agent1": {Tool: "claude-code"}},
+		Tasks: map[string]TaskConfig{
+			"review": {
+				Agent:       "agent1",
+				Prompt:      "prompt",
+				PromptFile:  "*.md",
+				PromptFiles: []string{filepath.Join(dir, "a.md")},
+				Inputs:      []string{filepath.Join(dir, "*.md"), filepath.Join(dir, "build", "out.txt")},
+			},
+			"build": {
+				Agent:   "agent1",
+				Prompt:  "build",
+				Outputs: []string{filepath.Join(dir, "build", "out.txt")},
+			},
+		},
+	}
+	if err := ValidateWithFile(config, filePath); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	review := config.Tasks["review"]
+	review.Prompt = ""
+	review.PromptFile = "prompts/*.md"
+	review.PromptFiles = nil
+	review.Inputs = []string{filepath.Join(dir, "src", "*.go")}
+	config.Tasks["review"] = review
+
+	err := ValidateWithFile(config, filePath)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	for _, want := range []string{
+		`task "review": prompt_file "prompts/*.md" matched no files`,
+		`task "review": inputs "src/*.go" matched no files`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
+
+// TestValidate_ClientSettings tests proxy and TLS settings of api agents and
+// http tasks.
+func TestValidate_ClientSettings(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, []byte("cert"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{
+			"llm": {Tool: "api", Model: "gpt-4o-mini", Proxy: "http://proxy:3128", TLS: &TLSConfig{CAFile: caFile}, Warmup: true},
+		},
+		Tasks: map[string]TaskConfig{
+			"ask":   {Agent: "llm", Prompt: "hi"},
+			"fetch": {HTTP: &HTTPConfig{URL: "https://example.com", Proxy: "${CORP_PROXY}"}},
+		},
+	}
+	if err := ValidateWithFile(config, "Cortexfile.yml"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	config.Agents["cli"] = AgentConfig{Tool: "claude-code", Warmup: true}
+	config.Tasks["fetch"] = TaskConfig{HTTP: &HTTPConfig{
+		URL:   "https://example.com",
+		Proxy: "proxy:3128",
+		TLS:   &TLSConfig{CertFile: filepath.Join(dir, "client.pem")},
+	}}
+
+	err := ValidateWithFile(config, "Cortexfile.yml")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	for _, want := range []string{
+		`agent "cli": 'proxy', 'tls', and 'warmup' only apply to api agents`,
+		`task "fetch": invalid proxy "proxy:3128"`,
+		`task "fetch": tls needs both 'cert_file' and 'key_file'`,
+		`task "fetch": tls cert_file not found`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
+
+// TestValidate_AgentShell tests that only shell agents set a shell.
+func TestValidate_AgentShell(t *testing.T) {
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{
+			"ps": {Tool: "shell", Shell: "pwsh"},
+		},
+		Tasks: map[string]TaskConfig{
+			"clean": {Agent: "ps", Command: "Remove-Item build"},
+		},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	config.Agents["ai"] = AgentConfig{Tool: "claude-code", Shell: "bash"}
+	err := Validate(config)
+	if err == nil || !strings.Contains(err.Error(), `agent "ai": 'shell' only applies to shell agents`) {
+		t.Errorf("expected shell error, got: %v", err)
+	}
+}
+
+// TestValidate_TaskToolOverride tests the tool and model tasks set over
+// their agent's.
+func TestValidate_TaskToolOverride(t *testing.T) {
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{
+			"ai": {Tool: "claude-code", Model: "sonnet"},
+			"sh": {Tool: "shell"},
+		},
+		Tasks: map[string]TaskConfig{
+			"plan":  {Agent: "ai", Prompt: "Plan", Model: "opus"},
+			"draft": {Agent: "ai", Prompt: "Draft", Tool: "opencode", Model: "gpt-4o"},
+			"ask":   {Agent: "ai", Prompt: "Ask", Tool: "api", Model: "gpt-4o-mini"},
+			"build": {Agent: "sh", Command: "make"},
+		},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	config.Tasks["typo"] = TaskConfig{Agent: "ai", Prompt: "x", Tool: "opencod"}
+	config.Tasks["switch"] = TaskConfig{Agent: "ai", Command: "make", Tool: "shell"}
+	config.Tasks["bare"] = TaskConfig{Agent: "sh", Command: "make", Model: "opus"}
+	config.Tasks["fetch"] = TaskConfig{HTTP: &HTTPConfig{URL: "https://example.com"}, Model: "opus"}
+
+	err := Validate(config)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	for _, want := range []string{
+		`task "typo" uses unsupported tool "opencod"`,
+		`Did you mean "opencode"?`,
+		`task "switch": tool "shell" can't replace the claude-code tool of agent "ai"`,
+		`task "bare": 'model' doesn't apply to shell tasks`,
+		`task "fetch": http tasks cannot set 'agent', 'tool', 'model'`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
+
+// TestRunsWith tests that a task's tool and model replace its agent's.
+func TestRunsWith(t *testing.T) {
+	agent := AgentConfig{Tool: "claude-code", Model: "sonnet", Workdir: "src"}
+
+	got := TaskConfig{Model: "opus"}.RunsWith(agent)
+	if got.Tool != "claude-code" || got.Model != "opus" || got.Workdir != "src" {
+		t.Errorf("RunsWith() = %+v, want claude-code/opus in src", got)
+	}
+	got = TaskConfig{Tool: "aider"}.RunsWith(agent)
+	if got.Tool != "aider" || got.Model != "sonnet" {
+		t.Errorf("RunsWith() = %+v, want aider/sonnet", got)
+	}
+	if agent.Model != "sonnet" {
+		t.Errorf("RunsWith() changed the agent: %+v", agent)
+	}
+}
+
+// TestValidate_SystemPrompt tests that system prompts are only set for tools
+// that take one.
+func TestValidate_SystemPrompt(t *testing.T) {
+	config := &AgentflowConfig{
+		Agents: map[string]AgentConfig{
+			"ai":  {Tool: "claude-code", SystemPrompt: "Be brief."},
+			"oc":  {Tool: "opencode"},
+			"gpt": {Tool: "api", Model: "gpt-4o-mini"},
+		},
+		Tasks: map[string]TaskConfig{
+			"plan": {Agent: "ai", Prompt: "Plan"},
+			"ask":  {Agent: "gpt", Prompt: "Ask", SystemPrompt: "Answer in JSON."},
+			"code": {Agent: "oc", Prompt: "Code"},
+		},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	config.Agents["sh"] = AgentConfig{Tool: "shell", SystemPrompt: "x"}
+	config.Tasks["build"] = TaskConfig{Agent: "sh", Command: "make"}
+	config.Tasks["code"] = TaskConfig{Agent: "oc", Prompt: "Code", SystemPrompt: "Be brief."}
+	config.Tasks["switch"] = TaskConfig{Agent: "ai", Prompt: "Plan", Tool: "aider"}
+
+	err := Validate(config)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	for _, want := range []string{
+		`agent "sh": 'system_prompt' doesn't apply to shell tasks`,
+		`task "code": 'system_prompt' doesn't apply to opencode tasks`,
+		`task "switch": 'system_prompt' doesn't apply to aider tasks`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got: %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// varRegex matches {{vars.name}} patterns.
+var varRegex = regexp.MustCompile(`\{\{vars\.([a-zA-Z0-9_-]+)\}\}`)
+
+// ParseVarFlags parses --var flags of the form name=value.
+func ParseVarFlags(flags []string) (map[string]string, error) {
+	vars := make(map[string]string, len(flags))
+	for _, flag := range flags {
+		name, value, ok := strings.Cut(flag, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q: expected name=value", flag)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// LoadVarFile reads variables from a YAML (or JSON) file of name: value pairs.
+// Scalar values of any type are converted to strings.
+func LoadVarFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read var file %q: %w", path, err)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse var file %q: %w", path, err)
+	}
+
+	vars := make(map[string]string, len(raw))
+	for name, value := range raw {
+		switch v := value.(type) {
+		case nil:
+			vars[name] = ""
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("var file %q: %q must be a scalar value", path, name)
+		default:
+			vars[name] = fmt.Sprint(v)
+		}
+	}
+	return vars, nil
+}
+
+// ApplyVars merges overrides into the config's vars (later maps take
+// precedence), fills declared inputs that are still unset with their
+// defaults, and substitutes {{vars.name}} in prompts, commands, script
+// code, when conditions, and env values. Undefined variables are left as-is
+// for validation to report.
+func ApplyVars(config *AgentflowConfig, overrides ...map[string]string) {
+	for _, o := range overrides {
+		if len(o) == 0 {
+			continue
+		}
+		if config.Vars == nil {
+			config.Vars = make(map[string]string, len(o))
+		}
+		for name, value := range o {
+			config.Vars[name] = value
+		}
+	}
+	for name, input := range config.Inputs {
+		if config.Vars == nil {
+			config.Vars = make(map[string]string, len(config.Inputs))
+		}
+		if _, ok := config.Vars[name]; !ok {
+			config.Vars[name] = input.Default
+		}
+	}
+	if len(config.Vars) == 0 {
+		return
+	}
+
+	expandConfigText(config, func(text string) string {
+		return ExpandVars(text, config.Vars)
+	})
+}
+
+// expandConfigText applies expand to every task field that may hold
+// placeholders: prompts and system prompts, commands, script code, when
+// conditions, tool settings, artifacts, and agent and task env values.
+func expandConfigText(config *AgentflowConfig, expand func(string) string) {
+	for name, agent := range config.Agents {
+		agent.Env = expandEnv(agent.Env, expand)
+		agent.SystemPrompt = expand(agent.SystemPrompt)
+		config.Agents[name] = agent
+	}
+
+	for name, task := range config.Tasks {
+		task.Prompt = expand(task.Prompt)
+		task.SystemPrompt = expand(task.SystemPrompt)
+		task.Command = expand(task.Command)
+		task.When = expand(task.When)
+		task.Until = expand(task.Until)
+		task.Env = expandEnv(task.Env, expand)
+		if task.Script != nil {
+			script := *task.Script
+			script.Code = expand(script.Code)
+			task.Script = &script
+		}
+		if task.HTTP != nil {
+			task.HTTP = task.HTTP.Expand(expand)
+		}
+		if task.Git != nil {
+			task.Git = task.Git.Expand(expand)
+		}
+		if task.Wait != nil {
+			task.Wait = &WaitConfig{Duration: expand(task.Wait.Duration)}
+		}
+		if task.Poll != nil {
+			task.Poll = task.Poll.Expand(expand)
+		}
+		if task.Notify != nil {
+			task.Notify = &NotifyConfig{Message: expand(task.Notify.Message), Level: task.Notify.Level}
+		}
+		if task.PullRequest != nil {
+			task.PullRequest = task.PullRequest.Expand(expand)
+		}
+		task.Artifacts = expandList(task.Artifacts, expand)
+		config.Tasks[name] = task
+	}
+}
+
+// ExpandVars replaces {{vars.name}} placeholders with variable values.
+// Unknown variables are left as-is.
+func ExpandVars(text string, vars map[string]string) string {
+	return varRegex.ReplaceAllStringFunc(text, func(match string) string {
+		if value, ok := vars[varRegex.FindStringSubmatch(match)[1]]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// ExtractVarRefs returns all variable names referenced in {{vars.name}} patterns.
+func ExtractVarRefs(text string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range varRegex.FindAllStringSubmatch(text, -1) {
+		if !seen[match[1]] {
+			names = append(names, match[1])
+			seen[match[1]] = true
+		}
+	}
+	return names
+}
+
+// expandEnv returns a copy of env with expand applied to every value.
+func expandEnv(env map[string]string, expand func(string) string) map[string]string {
+	if len(env) == 0 {
+		return env
+	}
+	expanded := make(map[string]string, len(env))
+	for k, v := range env {
+		expanded[k] = expand(v)
+	}
+	return expanded
+}
+
+// expandList applies expand to every entry of a list.
+func expandList(list StringList, expand func(string) string) StringList {
+	if len(list) == 0 {
+		return list
+	}
+	expanded := make(StringList, len(list))
+	for i, v := range list {
+		expanded[i] = expand(v)
+	}
+	return expanded
+}
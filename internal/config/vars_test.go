@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestParseVarFlags tests parsing name=value flags.
+func TestParseVarFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"empty", nil, map[string]string{}, false},
+		{"simple", []string{"env=prod", "region=eu"}, map[string]string{"env": "prod", "region": "eu"}, false},
+		{"value with equals", []string{"query=a=b"}, map[string]string{"query": "a=b"}, false},
+		{"empty value", []string{"tag="}, map[string]string{"tag": ""}, false},
+		{"missing equals", []string{"env"}, nil, true},
+		{"missing name", []string{"=prod"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVarFlags(tt.flags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVarFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseVarFlags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadVarFile tests loading variables from YAML and JSON files.
+func TestLoadVarFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "agentflow-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"vars.yml":   "env: prod\nreplicas: 3\ndebug: false\nempty:\n",
+		"vars.json":  `{"env": "staging"}`,
+		"nested.yml": "db:\n  host: localhost\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "yaml scalars",
+			file: "vars.yml",
+			want: map[string]string{"env": "prod", "replicas": "3", "debug": "false", "empty": ""},
+		},
+		{
+			name: "json",
+			file: "vars.json",
+			want: map[string]string{"env": "staging"},
+		},
+		{
+			name:    "nested values rejected",
+			file:    "nested.yml",
+			wantErr: true,
+		},
+		{
+			name:    "missing file",
+			file:    "missing.yml",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadVarFile(filepath.Join(tmpDir, tt.file))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadVarFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadVarFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyVars tests variable precedence and substitution.
+func TestApplyVars(t *testing.T) {
+	cfg := &AgentflowConfig{
+		Vars: map[string]string{"env": "dev", "region": "us"},
+		Agents: map[string]AgentConfig{
+			"ops": {Tool: "shell", Env: map[string]string{"STAGE": "{{vars.env}}"}},
+		},
+		Tasks: map[string]TaskConfig{
+			"deploy": {
+				Agent:   "ops",
+				Command: "deploy --env {{vars.env}} --region {{vars.region}} {{vars.unknown}}",
+				When:    "{{vars.env}} != dev",
+			},
+			"notes": {Script: &ScriptConfig{Lang: "python", Code: "print('{{vars.env}}')"}},
+		},
+	}
+
+	ApplyVars(cfg, map[string]string{"env": "staging"}, map[string]string{"env": "prod"})
+
+	if got := cfg.Tasks["deploy"].Command; got != "deploy --env prod --region us {{vars.unknown}}" {
+		t.Errorf("Command = %q", got)
+	}
+	if got := cfg.Tasks["deploy"].When; got != "prod != dev" {
+		t.Errorf("When = %q", got)
+	}
+	if got := cfg.Tasks["notes"].Script.Code; got != "print('prod')" {
+		t.Errorf("Script.Code = %q", got)
+	}
+	if got := cfg.Agents["ops"].Env["STAGE"]; got != "prod" {
+		t.Errorf("agent Env = %q", got)
+	}
+}
+
+// TestValidate_UndefinedVar tests that unresolved variables are reported.
+func TestValidate_UndefinedVar(t *testing.T) {
+	cfg := &AgentflowConfig{
+		Agents: map[string]AgentConfig{"agent1": {Tool: "claude-code"}},
+		Tasks: map[string]TaskConfig{
+			"task1": {Agent: "agent1", Prompt: "Deploy to {{vars.env}}"},
+		},
+	}
+
+	err := Validate(cfg)
+	valErr, ok := err.(*ConfigErrors)
+	if !ok {
+		t.Fatalf("expected *ConfigErrors, got %T", err)
+	}
+	if !errorsContain(valErr, "undefined variable {{vars.env}}") {
+		t.Errorf("expected undefined variable error, got: %v", valErr)
+	}
+
+	ApplyVars(cfg, map[string]string{"env": "prod"})
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected no error after ApplyVars, got: %v", err)
+	}
+}
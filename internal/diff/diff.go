@@ -0,0 +1,158 @@
+// Package diff compares texts line by line.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of a diff line.
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Line is a line of an edit script.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// maxCells bounds the size of the comparison table, so that diffing large
+// files fails fast instead of using a lot of memory.
+const maxCells = 4_000_000
+
+// SplitLines splits text into lines, without their line endings.
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Lines returns a shortest edit script turning a into b. ok is false when
+// the texts are too large to compare.
+func Lines(a, b []string) (script []Line, ok bool) {
+	// Common prefix and suffix need no table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for _, line := range a[:prefix] {
+		script = append(script, Line{Equal, line})
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(ma)+1)*(len(mb)+1) > maxCells {
+		return nil, false
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of ma[i:]
+	// and mb[j:].
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			script = append(script, Line{Equal, ma[i]})
+			i++
+			j++
+		case i < len(ma) && (j == len(mb) || lcs[i+1][j] >= lcs[i][j+1]):
+			script = append(script, Line{Delete, ma[i]})
+			i++
+		default:
+			script = append(script, Line{Insert, mb[j]})
+			j++
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		script = append(script, Line{Equal, line})
+	}
+	return script, true
+}
+
+// Hunk is a group of changes with the unchanged lines around them.
+type Hunk struct {
+	AStart, ALen int // 1-based first line and line count in a
+	BStart, BLen int // 1-based first line and line count in b
+	Lines        []Line
+}
+
+// Header returns the hunk's "@@ -a,n +b,m @@" line.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.AStart, h.ALen), hunkRange(h.BStart, h.BLen))
+}
+
+func hunkRange(start, n int) string {
+	if n == 0 {
+		start-- // An empty range names the line before it
+	}
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// Hunks groups an edit script into hunks with up to context unchanged lines
+// around each change. Changes closer than twice the context share a hunk.
+func Hunks(script []Line, context int) []Hunk {
+	// aNext[k] and bNext[k] are the line numbers in a and b of script[k],
+	// or of the next line when script[k] is not on that side.
+	aNext := make([]int, len(script)+1)
+	bNext := make([]int, len(script)+1)
+	aNext[0], bNext[0] = 1, 1
+	for k, line := range script {
+		aNext[k+1], bNext[k+1] = aNext[k], bNext[k]
+		if line.Op != Insert {
+			aNext[k+1]++
+		}
+		if line.Op != Delete {
+			bNext[k+1]++
+		}
+	}
+
+	var hunks []Hunk
+	start, end := -1, -1 // Script range of the hunk being built
+	flush := func() {
+		h := Hunk{AStart: aNext[start], BStart: bNext[start], Lines: script[start:end]}
+		h.ALen = aNext[end] - aNext[start]
+		h.BLen = bNext[end] - bNext[start]
+		hunks = append(hunks, h)
+	}
+	for k, line := range script {
+		if line.Op == Equal {
+			continue
+		}
+		if start >= 0 && k-context > end {
+			flush()
+			start = -1
+		}
+		if start < 0 {
+			start = max(0, k-context)
+		}
+		end = min(len(script), k+context+1)
+	}
+	if start >= 0 {
+		flush()
+	}
+	return hunks
+}
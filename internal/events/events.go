@@ -0,0 +1,161 @@
+// Package events carries what happens during a run from the executor to the
+// subscribers that present, record, or forward it.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/state"
+)
+
+// Event is one of the event types below.
+type Event interface {
+	event()
+}
+
+// RunStarted is published once, before any task starts.
+type RunStarted struct {
+	RunID    string
+	Project  string
+	Plan     *planner.ExecutionPlan
+	Levels   []planner.ExecutionLevel // Dependency levels; nil in sequential mode
+	Parallel bool                     // Whether tasks run in parallel
+}
+
+// LevelStarted is published in parallel mode when the first task of a
+// dependency level starts.
+type LevelStarted struct {
+	Level int // 0-based
+	Total int
+	Tasks []string
+}
+
+// TaskStarted is published when a task begins running. Tasks that are
+// skipped, or fail before their agent starts, only publish TaskFinished.
+type TaskStarted struct {
+	Task  planner.ExecutionTask
+	Num   int // 1-based position in the run, for display
+	Total int
+	Level int // Dependency level in parallel mode; 0 otherwise
+}
+
+// Output streams of TaskOutputChunk.
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+)
+
+// TaskOutputChunk is output a task streamed while running. Data is only
+// valid during the call to Handle; subscribers that keep it must copy it.
+type TaskOutputChunk struct {
+	Task   string
+	Stream string // Stdout or Stderr
+	Data   []byte
+}
+
+// TaskFinished is published when a task has succeeded, failed, or been
+// skipped.
+type TaskFinished struct {
+	Task    planner.ExecutionTask
+	Result  *state.TaskResult
+	Err     error // Why the task failed; nil if it succeeded or was skipped
+	Ignored bool  // The failure does not fail the run (continue_on_error)
+	Num     int
+	Total   int
+}
+
+// BudgetExceeded is published when a task's usage pushes the run over its
+// budget, just before the remaining tasks are cancelled.
+type BudgetExceeded struct {
+	Task       string
+	Tokens     int
+	CostUSD    float64
+	MaxTokens  int
+	MaxCostUSD float64
+	Message    string
+}
+
+// RunFinished is published once, after the run result has its totals.
+type RunFinished struct {
+	Result *state.RunResult
+	Err    error
+}
+
+// Duration returns how long the run took.
+func (e RunFinished) Duration() time.Duration {
+	return e.Result.EndTime.Sub(e.Result.StartTime)
+}
+
+func (RunStarted) event()      {}
+func (LevelStarted) event()    {}
+func (TaskStarted) event()     {}
+func (TaskOutputChunk) event() {}
+func (TaskFinished) event()    {}
+func (BudgetExceeded) event()  {}
+func (RunFinished) event()     {}
+
+// Subscriber receives the events of a run. Tasks running in parallel
+// publish concurrently, so Handle must be safe for concurrent use.
+type Subscriber interface {
+	Handle(Event)
+}
+
+// SubscriberFunc adapts a function to a Subscriber.
+type SubscriberFunc func(Event)
+
+// Handle implements Subscriber.
+func (f SubscriberFunc) Handle(e Event) {
+	f(e)
+}
+
+// Bus delivers published events to its subscribers, synchronously and in
+// the order they subscribed, so a subscriber sees the work of those before
+// it (e.g. a task result is saved before it is announced).
+type Bus struct {
+	mu   sync.RWMutex
+	subs []Subscriber
+}
+
+// NewBus creates a bus with the given subscribers.
+func NewBus(subs ...Subscriber) *Bus {
+	return &Bus{subs: subs}
+}
+
+// Subscribe adds a subscriber.
+func (b *Bus) Subscribe(s Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, s)
+}
+
+// Publish sends an event to every subscriber.
+func (b *Bus) Publish(e Event) {
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		s.Handle(e)
+	}
+}
+
+// Writer returns a writer that publishes what is written to it as
+// TaskOutputChunk events of a task.
+func (b *Bus) Writer(task, stream string) *ChunkWriter {
+	return &ChunkWriter{bus: b, task: task, stream: stream}
+}
+
+// ChunkWriter publishes writes as TaskOutputChunk events.
+type ChunkWriter struct {
+	bus    *Bus
+	task   string
+	stream string
+}
+
+// Write implements io.Writer.
+func (w *ChunkWriter) Write(data []byte) (int, error) {
+	w.bus.Publish(TaskOutputChunk{Task: w.task, Stream: w.stream, Data: data})
+	return len(data), nil
+}
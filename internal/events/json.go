@@ -0,0 +1,78 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/adityaraj/agentflow/internal/state"
+)
+
+// FileEnv names the environment variable holding a file that `cortex run`
+// appends its run and task events to, as JSON lines. `cortex serve` sets it
+// to follow the tasks of the runs it starts.
+const FileEnv = "CORTEX_EVENTS_FILE"
+
+// Types of Record.
+const (
+	RecordRunStarted   = "run_started"
+	RecordTaskStarted  = "task_started"
+	RecordTaskFinished = "task_finished"
+	RecordRunFinished  = "run_finished"
+)
+
+// Record is the JSON form of an event written by JSONWriter. Task output is
+// not recorded.
+type Record struct {
+	Type    string  `json:"type"`
+	Task    string  `json:"task,omitempty"`
+	Agent   string  `json:"agent,omitempty"`
+	Tool    string  `json:"tool,omitempty"`
+	Model   string  `json:"model,omitempty"`
+	Status  string  `json:"status,omitempty"`  // Of a finished task or run
+	Seconds float64 `json:"seconds,omitempty"` // Duration of a finished task or run
+}
+
+// JSONWriter is a Subscriber that writes run and task events as JSON lines.
+type JSONWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONWriter creates a JSONWriter writing to w.
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{enc: json.NewEncoder(w)}
+}
+
+// Handle implements Subscriber.
+func (j *JSONWriter) Handle(e Event) {
+	var r Record
+	switch e := e.(type) {
+	case RunStarted:
+		r = Record{Type: RecordRunStarted}
+	case TaskStarted:
+		r = Record{Type: RecordTaskStarted, Task: e.Task.Name, Agent: e.Task.AgentName, Tool: e.Task.Tool, Model: e.Task.Model}
+	case TaskFinished:
+		r = Record{
+			Type:    RecordTaskFinished,
+			Task:    e.Task.Name,
+			Agent:   e.Task.AgentName,
+			Tool:    e.Task.Tool,
+			Model:   e.Task.Model,
+			Status:  e.Result.Status,
+			Seconds: e.Result.Elapsed().Seconds(),
+		}
+	case RunFinished:
+		status := state.StatusSuccess
+		if !e.Result.Success {
+			status = state.StatusFailed
+		}
+		r = Record{Type: RecordRunFinished, Status: status, Seconds: e.Duration().Seconds()}
+	default:
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_ = j.enc.Encode(r)
+}
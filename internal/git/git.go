@@ -0,0 +1,137 @@
+// Package git runs the git commands behind a workflow's git settings:
+// creating a branch for the run, committing after write tasks, and pushing
+// the branch once the run succeeds. Output, Command, and Run run the other
+// git commands of cortex the same way.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// DefaultRemote is pushed to when the git settings do not set a remote.
+const DefaultRemote = "origin"
+
+// Repo is the repository a run commits to.
+type Repo struct {
+	dir string     // Top-level directory of the repository
+	mu  sync.Mutex // Serializes the commits of parallel tasks
+}
+
+// Open returns the repository containing dir.
+func Open(ctx context.Context, dir string) (*Repo, error) {
+	top, err := Output(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("git settings need a git repository: %w", err)
+	}
+	return &Repo{dir: top}, nil
+}
+
+// Dir returns the top-level directory of the repository.
+func (r *Repo) Dir() string {
+	return r.dir
+}
+
+// CreateBranch creates a branch at the current commit and checks it out,
+// carrying over uncommitted changes. It fails if the branch exists.
+func (r *Repo) CreateBranch(ctx context.Context, name string) error {
+	_, err := Output(ctx, r.dir, "checkout", "-b", name)
+	return err
+}
+
+// CommitAll stages all changes, untracked files included, and commits them,
+// returning the new commit's SHA. It returns "" without committing if
+// nothing changed. Whatever else is editing the working tree meanwhile is
+// committed too, so the executor runs committing tasks alone.
+func (r *Repo) CommitAll(ctx context.Context, message string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := Output(ctx, r.dir, "add", "--all"); err != nil {
+		return "", err
+	}
+	// diff --quiet exits 1 when there are staged changes
+	_, err := Output(ctx, r.dir, "diff", "--cached", "--quiet")
+	if err == nil {
+		return "", nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return "", err
+	}
+	if _, err := Output(ctx, r.dir, "commit", "--quiet", "-m", message); err != nil {
+		return "", err
+	}
+	return Output(ctx, r.dir, "rev-parse", "HEAD")
+}
+
+// Push pushes the current branch to remote, setting it as the branch's
+// upstream, and returns "remote/branch".
+func (r *Repo) Push(ctx context.Context, remote string) (string, error) {
+	if remote == "" {
+		remote = DefaultRemote
+	}
+	branch, err := Output(ctx, r.dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if branch == "HEAD" {
+		return "", fmt.Errorf("cannot push: HEAD is detached")
+	}
+	if _, err := Output(ctx, r.dir, "push", "--quiet", "--set-upstream", remote, branch); err != nil {
+		return "", err
+	}
+	return remote + "/" + branch, nil
+}
+
+// gitError is a git command that failed, with what it printed to stderr.
+type gitError struct {
+	args   []string
+	stderr string
+	err    error
+}
+
+func (e *gitError) Error() string {
+	if e.stderr != "" {
+		return fmt.Sprintf("git %s: %s", e.args[0], e.stderr)
+	}
+	return fmt.Sprintf("git %s: %s", e.args[0], e.err)
+}
+
+func (e *gitError) Unwrap() error {
+	return e.err
+}
+
+// Output runs git in dir and returns its trimmed stdout.
+func Output(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := Run(Command(ctx, dir, args...))
+	return strings.TrimSpace(out), err
+}
+
+// Command returns a git command to run in dir, for callers that need more
+// than Output, such as extra environment variables or input. Credential
+// prompts are disabled, so an unattended run never blocks on one.
+func Command(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	return cmd
+}
+
+// Run runs a command made by Command and returns its stdout as is. The
+// error holds what git printed to stderr.
+func Run(cmd *exec.Cmd) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", &gitError{args: cmd.Args[1:], stderr: strings.TrimSpace(stderr.String()), err: err}
+	}
+	return stdout.String(), nil
+}
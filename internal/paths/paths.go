@@ -0,0 +1,209 @@
+// Package paths locates the files cortex keeps outside a project: the global
+// config, saved runs, state such as the webhook queue, and caches.
+//
+// By default everything lives in ~/.cortex. When XDG_CONFIG_HOME,
+// XDG_DATA_HOME, XDG_STATE_HOME, or XDG_CACHE_HOME is set, cortex follows the XDG base
+// directory layout instead, and moves what it finds in ~/.cortex there.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// XDG environment variables. Setting any of them selects the XDG layout.
+const (
+	EnvConfigHome = "XDG_CONFIG_HOME"
+	EnvDataHome   = "XDG_DATA_HOME"
+	EnvStateHome  = "XDG_STATE_HOME"
+	EnvCacheHome  = "XDG_CACHE_HOME"
+)
+
+// Layout holds the directories of one layout. In the legacy layout config,
+// data, and state are ~/.cortex, and the cache is ~/.cortex/cache.
+type Layout struct {
+	XDG    bool
+	Config string // config.yml
+	Data   string // sessions/: saved runs, artifacts, and the task hashes of incremental runs
+	State  string // webhooks/, analytics.jsonl, logs/, and daemon/: undelivered webhook events, opt-in usage records, the operational log, and the run queue of `cortex daemon`
+	Cache  string // Files that can be rebuilt, such as the per-task caches of incremental runs
+}
+
+// Current returns the layout in use.
+func Current() (Layout, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Layout{}, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return resolve(home, os.Getenv), nil
+}
+
+// resolve picks the layout for a home directory and environment.
+func resolve(home string, getenv func(string) string) Layout {
+	if getenv(EnvConfigHome) == "" && getenv(EnvDataHome) == "" && getenv(EnvStateHome) == "" && getenv(EnvCacheHome) == "" {
+		legacy := filepath.Join(home, ".cortex")
+		return Layout{Config: legacy, Data: legacy, State: legacy, Cache: filepath.Join(legacy, "cache")}
+	}
+	return xdgLayout(home, getenv)
+}
+
+// xdgLayout returns the XDG directories, using the spec's defaults for
+// variables that are not set.
+func xdgLayout(home string, getenv func(string) string) Layout {
+	dir := func(env, fallback string) string {
+		base := getenv(env)
+		if !filepath.IsAbs(base) {
+			// The spec says relative paths are invalid and must be ignored
+			base = filepath.Join(home, fallback)
+		}
+		return filepath.Join(base, "cortex")
+	}
+	return Layout{
+		XDG:    true,
+		Config: dir(EnvConfigHome, ".config"),
+		Data:   dir(EnvDataHome, filepath.Join(".local", "share")),
+		State:  dir(EnvStateHome, filepath.Join(".local", "state")),
+		Cache:  dir(EnvCacheHome, ".cache"),
+	}
+}
+
+// ConfigDir returns the directory holding config.yml.
+func ConfigDir() (string, error) {
+	l, err := Current()
+	return l.Config, err
+}
+
+// DataDir returns the directory holding sessions/.
+func DataDir() (string, error) {
+	l, err := Current()
+	return l.Data, err
+}
+
+// StateDir returns the directory holding webhooks/, analytics.jsonl, logs/,
+// and daemon/.
+func StateDir() (string, error) {
+	l, err := Current()
+	return l.State, err
+}
+
+// CacheDir returns the directory holding caches.
+func CacheDir() (string, error) {
+	l, err := Current()
+	return l.Cache, err
+}
+
+// LegacyDir returns ~/.cortex.
+func LegacyDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".cortex"), nil
+}
+
+// Move is an entry of ~/.cortex and where it belongs in a layout.
+type Move struct {
+	From string
+	To   string
+}
+
+// legacyMoves lists the entries of ~/.cortex and their place in l.
+func legacyMoves(legacy string, l Layout) []Move {
+	return []Move{
+		{From: filepath.Join(legacy, "config.yml"), To: filepath.Join(l.Config, "config.yml")},
+		{From: filepath.Join(legacy, "sessions"), To: filepath.Join(l.Data, "sessions")},
+		{From: filepath.Join(legacy, "webhooks"), To: filepath.Join(l.State, "webhooks")},
+		{From: filepath.Join(legacy, "analytics.jsonl"), To: filepath.Join(l.State, "analytics.jsonl")},
+		{From: filepath.Join(legacy, "logs"), To: filepath.Join(l.State, "logs")},
+		{From: filepath.Join(legacy, "daemon"), To: filepath.Join(l.State, "daemon")},
+		{From: filepath.Join(legacy, "cache"), To: l.Cache},
+	}
+}
+
+// Migrate moves the entries of ~/.cortex into the XDG directories when the
+// XDG layout is in use. Entries whose destination already exists are left
+// in place for `cortex doctor` to report. ~/.cortex is removed once empty.
+// Returns the moves made.
+func Migrate() ([]Move, error) {
+	l, err := Current()
+	if err != nil || !l.XDG {
+		return nil, err
+	}
+	legacy, err := LegacyDir()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		return nil, nil
+	}
+
+	var moved []Move
+	for _, m := range legacyMoves(legacy, l) {
+		if !exists(m.From) || exists(m.To) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(m.To), 0755); err != nil {
+			return moved, err
+		}
+		if err := os.Rename(m.From, m.To); err != nil {
+			return moved, fmt.Errorf("failed to move %s to %s: %w", m.From, m.To, err)
+		}
+		moved = append(moved, m)
+	}
+
+	// Fails while anything is left, which is what we want
+	_ = os.Remove(legacy)
+	return moved, nil
+}
+
+// Problem is an issue with the directory layout found by Check.
+type Problem struct {
+	Message string
+	Hint    string
+}
+
+// Check looks for files split between layouts: entries of ~/.cortex that
+// were not migrated because the XDG directories already had them, or XDG
+// directories that are ignored because no XDG variable is set.
+func Check() ([]Problem, error) {
+	l, err := Current()
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := LegacyDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	if l.XDG {
+		for _, m := range legacyMoves(legacy, l) {
+			if exists(m.From) && exists(m.To) {
+				problems = append(problems, Problem{
+					Message: fmt.Sprintf("%s exists in both %s and %s", filepath.Base(m.From), filepath.Dir(m.From), filepath.Dir(m.To)),
+					Hint:    fmt.Sprintf("Merge %s into %s and remove it; only the second is used", m.From, m.To),
+				})
+			}
+		}
+		return problems, nil
+	}
+
+	// In the legacy layout, XDG directories at their default locations
+	// were most likely created by a shell that sets the XDG variables
+	home := filepath.Dir(legacy)
+	for _, m := range legacyMoves(legacy, xdgLayout(home, func(string) string { return "" })) {
+		if exists(m.To) {
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("%s is not used because no XDG variable is set", m.To),
+				Hint:    fmt.Sprintf("Set %s, %s, %s, or %s in every shell that runs cortex, or move it to %s", EnvConfigHome, EnvDataHome, EnvStateHome, EnvCacheHome, m.From),
+			})
+		}
+	}
+	return problems, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,95 @@
+package planner
+
+import (
+	"github.com/adityaraj/agentflow/internal/config"
+)
+
+// ExpandFanOut replaces each task that declares items_from or matrix with one
+// task per item, named <task>-1, <task>-2, ... Item placeholders are substituted in
+// the prompt, command, when and until conditions, script code, http, git, poll, notify, and pull_request fields, and artifacts, and dependents are rewired to need
+// every instance. Returns the expanded tasks and a map of fan-out task name
+// to its instance names.
+func ExpandFanOut(tasks map[string]config.TaskConfig) (map[string]config.TaskConfig, map[string][]string) {
+	groups := make(map[string][]string)
+	expanded := make(map[string]config.TaskConfig, len(tasks))
+
+	for name, task := range tasks {
+		if !task.IsFanOut() {
+			expanded[name] = task
+			continue
+		}
+
+		instances := make([]string, 0, len(task.Items))
+		for i, item := range task.Items {
+			instanceName := config.FanOutInstanceName(name, i+1)
+			instance := expandTaskItem(task, item)
+			// The until condition of an instance reads its own output
+			instance.Until = config.RenameTaskRefs(instance.Until, name, instanceName)
+			expanded[instanceName] = instance
+			instances = append(instances, instanceName)
+		}
+		groups[name] = instances
+	}
+
+	if len(groups) == 0 {
+		return expanded, groups
+	}
+
+	// Rewire dependencies on fan-out tasks to their instances
+	for name, task := range expanded {
+		var needs config.StringList
+		for _, dep := range task.Needs {
+			if instances, ok := groups[dep]; ok {
+				needs = append(needs, instances...)
+			} else {
+				needs = append(needs, dep)
+			}
+		}
+		task.Needs = needs
+		expanded[name] = task
+	}
+
+	return expanded, groups
+}
+
+// expandTaskItem returns a copy of task with item placeholders substituted.
+func expandTaskItem(task config.TaskConfig, item config.Item) config.TaskConfig {
+	instance := task
+	instance.ItemsFrom = ""
+	instance.Matrix = nil
+	instance.Items = nil
+	instance.Prompt = config.ExpandItemVars(task.Prompt, item)
+	instance.Command = config.ExpandItemVars(task.Command, item)
+	instance.When = config.ExpandItemVars(task.When, item)
+	instance.Until = config.ExpandItemVars(task.Until, item)
+	if task.Script != nil {
+		script := *task.Script
+		script.Code = config.ExpandItemVars(script.Code, item)
+		instance.Script = &script
+	}
+	expandItem := func(text string) string {
+		return config.ExpandItemVars(text, item)
+	}
+	if task.HTTP != nil {
+		instance.HTTP = task.HTTP.Expand(expandItem)
+	}
+	if task.Git != nil {
+		instance.Git = task.Git.Expand(expandItem)
+	}
+	if task.Poll != nil {
+		instance.Poll = task.Poll.Expand(expandItem)
+	}
+	if task.Notify != nil {
+		instance.Notify = &config.NotifyConfig{Message: expandItem(task.Notify.Message), Level: task.Notify.Level}
+	}
+	if task.PullRequest != nil {
+		instance.PullRequest = task.PullRequest.Expand(expandItem)
+	}
+	if len(task.Artifacts) > 0 {
+		instance.Artifacts = make(config.StringList, len(task.Artifacts))
+		for i, pattern := range task.Artifacts {
+			instance.Artifacts[i] = expandItem(pattern)
+		}
+	}
+	return instance
+}
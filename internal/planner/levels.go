@@ -1,5 +1,7 @@
 package planner
 
+import "sort"
+
 // ExecutionLevel represents a group of tasks that can run in parallel.
 // All tasks in the same level have no dependencies on each other.
 type ExecutionLevel struct {
@@ -45,6 +47,7 @@ func BuildExecutionLevels(dag *DAG) []ExecutionLevel {
 			// This shouldn't happen with a valid DAG (no cycles)
 			break
 		}
+		sort.Strings(levelTasks)
 
 		// Add this level
 		levels = append(levels, ExecutionLevel{
@@ -99,3 +102,42 @@ func LevelForTask(levels []ExecutionLevel, taskName string) int {
 	}
 	return -1
 }
+
+// CriticalPath returns the longest chain of dependent tasks, first task
+// first. Each task counts as one step, so the chain is what bounds how soon
+// a parallel run can finish however many tasks run at once. Ties are broken
+// by task name. Returns nil if the DAG has a cycle.
+func CriticalPath(dag *DAG) []string {
+	order, err := TopologicalSort(dag)
+	if err != nil || len(order) == 0 {
+		return nil
+	}
+
+	// length is the longest chain ending at a task; prev is the dependency
+	// before it on that chain
+	length := make(map[string]int, len(order))
+	prev := make(map[string]string, len(order))
+	for _, name := range order {
+		length[name] = 1
+		for _, dep := range dag.Edges[name] {
+			n := length[dep] + 1
+			if n > length[name] || (n == length[name] && dep < prev[name]) {
+				length[name] = n
+				prev[name] = dep
+			}
+		}
+	}
+
+	end := ""
+	for _, name := range order {
+		if end == "" || length[name] > length[end] || (length[name] == length[end] && name < end) {
+			end = name
+		}
+	}
+
+	path := make([]string, length[end])
+	for i, name := len(path)-1, end; i >= 0; i, name = i-1, prev[name] {
+		path[i] = name
+	}
+	return path
+}
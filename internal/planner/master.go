@@ -0,0 +1,203 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/adityaraj/agentflow/internal/config"
+)
+
+// MasterWorkflow is a workflow of a MasterCortex, for rendering the graph of
+// the whole orchestration.
+type MasterWorkflow struct {
+	Name  string
+	Path  string
+	Needs []string
+	Plan  *ExecutionPlan // The workflow's own plan, to draw its tasks too (optional)
+}
+
+// MasterDAG builds the DAG of the workflows of a MasterCortex. Needs on
+// workflows that aren't among them (disabled ones) are left out; `cortex
+// master` never runs the workflows that have such needs.
+func MasterDAG(workflows []MasterWorkflow) *DAG {
+	known := make(map[string]bool, len(workflows))
+	for _, w := range workflows {
+		known[w.Name] = true
+	}
+
+	nodes := make(map[string]config.TaskConfig, len(workflows))
+	for _, w := range workflows {
+		var needs config.StringList
+		for _, dep := range w.Needs {
+			if known[dep] {
+				needs = append(needs, dep)
+			}
+		}
+		nodes[w.Name] = config.TaskConfig{Needs: needs}
+	}
+	return BuildDAG(nodes)
+}
+
+// RenderMasterGraph renders the workflows of a MasterCortex in the specified
+// format, with the tasks of workflows that have a plan.
+func RenderMasterGraph(name string, workflows []MasterWorkflow, format GraphFormat) string {
+	switch format {
+	case FormatDOT:
+		return RenderMasterDOT(name, workflows)
+	default:
+		return RenderMasterASCII(name, workflows)
+	}
+}
+
+// RenderMasterASCII renders the workflows level by level, each with its
+// tasks in compact form if it has a plan.
+func RenderMasterASCII(name string, workflows []MasterWorkflow) string {
+	if len(workflows) == 0 {
+		return "No workflows to display.\n"
+	}
+
+	dag := MasterDAG(workflows)
+	levels := BuildExecutionLevels(dag)
+	byName := make(map[string]MasterWorkflow, len(workflows))
+	width := 0
+	for _, w := range workflows {
+		byName[w.Name] = w
+		width = max(width, len(w.Name))
+	}
+
+	var sb strings.Builder
+	title := "Master Graph"
+	if name != "" {
+		title += ": " + name
+	}
+	sb.WriteString(fmt.Sprintf("\n◆ %s (%d workflows, %d levels)\n", title, len(workflows), len(levels)))
+	sb.WriteString("═══════════════════════════════════════════════════════\n\n")
+
+	for levelIdx, level := range levels {
+		parallelNote := ""
+		if len(level.Tasks) > 1 {
+			parallelNote = " (parallel)"
+		}
+		sb.WriteString(fmt.Sprintf("Level %d%s:\n", levelIdx, parallelNote))
+
+		for _, wfName := range level.Tasks {
+			w := byName[wfName]
+			deps := ""
+			if needs := dag.GetDependencies(wfName); len(needs) > 0 {
+				deps = "  ← " + strings.Join(needs, ", ")
+			}
+			sb.WriteString(fmt.Sprintf("  ● %-*s  %s%s\n", width, w.Name, w.Path, deps))
+			if w.Plan != nil {
+				sb.WriteString(fmt.Sprintf("    %*s└─ %s\n", width, "", RenderCompact(w.Plan.DAG)))
+			}
+		}
+
+		if levelIdx < len(levels)-1 {
+			sb.WriteString("        │\n")
+			sb.WriteString("        ▼\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("─────────────────────────────────────────────────────────\n")
+	sb.WriteString("Legend: ● workflow │ ← needs │ └─ tasks (a → b runs b after a, [a, b] in parallel)\n")
+
+	return sb.String()
+}
+
+// RenderMasterDOT renders the workflows in Graphviz DOT format. Workflows
+// with a plan become clusters of their tasks; an edge between two of them
+// joins the last tasks of one to the first tasks of the other.
+func RenderMasterDOT(name string, workflows []MasterWorkflow) string {
+	var sb strings.Builder
+
+	sb.WriteString("digraph MasterGraph {\n")
+	sb.WriteString("    rankdir=TB;\n")
+	sb.WriteString("    compound=true;\n")
+	if name != "" {
+		sb.WriteString(fmt.Sprintf("    label=%q;\n", name))
+	}
+	sb.WriteString("    node [shape=box, style=rounded, fontname=\"Arial\"];\n")
+	sb.WriteString("    edge [arrowhead=vee];\n\n")
+
+	byName := make(map[string]MasterWorkflow, len(workflows))
+	for _, w := range workflows {
+		byName[w.Name] = w
+		if w.Plan == nil || w.Plan.DAG.Size() == 0 {
+			sb.WriteString(fmt.Sprintf("    %q [label=%q];\n\n", w.Name, w.Name+"\n"+w.Path))
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("    subgraph %q {\n", clusterID(w)))
+		sb.WriteString(fmt.Sprintf("        label=%q;\n", w.Name+"\n"+w.Path))
+		sb.WriteString("        style=dashed;\n")
+		sb.WriteString("        color=gray;\n")
+		for _, t := range w.Plan.Tasks {
+			label := t.Name + "\n(" + t.Tool + ")"
+			if t.Model != "" {
+				label = t.Name + "\n(" + t.Tool + "/" + t.Model + ")"
+			}
+			sb.WriteString(fmt.Sprintf("        %q [label=%q];\n", taskNodeID(w.Name, t.Name), label))
+		}
+		for _, t := range w.Plan.Tasks {
+			for _, dep := range w.Plan.DAG.GetDependencies(t.Name) {
+				sb.WriteString(fmt.Sprintf("        %q -> %q;\n", taskNodeID(w.Name, dep), taskNodeID(w.Name, t.Name)))
+			}
+		}
+		sb.WriteString("    }\n\n")
+	}
+
+	// Add edges (workflow dependencies)
+	sb.WriteString("    // Workflow dependencies\n")
+	dag := MasterDAG(workflows)
+	for _, w := range workflows {
+		for _, dep := range dag.GetDependencies(w.Name) {
+			from, ltail := workflowEdgeEnd(byName[dep], false)
+			to, lhead := workflowEdgeEnd(w, true)
+			var attrs []string
+			if ltail != "" {
+				attrs = append(attrs, fmt.Sprintf("ltail=%q", ltail))
+			}
+			if lhead != "" {
+				attrs = append(attrs, fmt.Sprintf("lhead=%q", lhead))
+			}
+			edge := fmt.Sprintf("    %q -> %q", from, to)
+			if len(attrs) > 0 {
+				edge += " [" + strings.Join(attrs, ", ") + "]"
+			}
+			sb.WriteString(edge + ";\n")
+		}
+	}
+
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+// workflowEdgeEnd returns the node an edge to (head) or from a workflow
+// attaches to, and the cluster to clip it at if the workflow is drawn as one.
+func workflowEdgeEnd(w MasterWorkflow, head bool) (string, string) {
+	if w.Plan == nil || w.Plan.DAG.Size() == 0 {
+		return w.Name, ""
+	}
+
+	levels := BuildExecutionLevels(w.Plan.DAG)
+	level := levels[len(levels)-1]
+	if head {
+		level = levels[0]
+	}
+	tasks := append([]string(nil), level.Tasks...)
+	sort.Strings(tasks)
+	return taskNodeID(w.Name, tasks[0]), clusterID(w)
+}
+
+// clusterID returns the name of a workflow's cluster in RenderMasterDOT.
+func clusterID(w MasterWorkflow) string {
+	return "cluster_wf_" + w.Name
+}
+
+// taskNodeID returns the DOT node ID of a task of a workflow.
+func taskNodeID(workflow, task string) string {
+	return workflow + "/" + task
+}
@@ -0,0 +1,101 @@
+package planner
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/adityaraj/agentflow/internal/config"
+)
+
+// taskPhases maps each task of the expanded plan to its phase. A fan-out
+// task's phase applies to all its instances.
+func taskPhases(cfg *config.AgentflowConfig, tasks map[string]config.TaskConfig, groups map[string][]string) map[string]string {
+	phases := make(map[string]string)
+	for _, phase := range []string{config.PhaseSetup, config.PhaseTeardown} {
+		names := cfg.Setup
+		if phase == config.PhaseTeardown {
+			names = cfg.Teardown
+		}
+		for _, name := range names {
+			if instances, ok := groups[name]; ok {
+				for _, instance := range instances {
+					phases[instance] = phase
+				}
+			} else if _, ok := tasks[name]; ok {
+				phases[name] = phase
+			}
+		}
+	}
+	return phases
+}
+
+// needSetup makes every regular task that needs no other regular task need
+// all setup tasks, so no regular task starts before setup has finished, and
+// the failure of a setup task blocks them all.
+func needSetup(tasks map[string]config.TaskConfig, phases map[string]string) {
+	var setup []string
+	for _, name := range slices.Sorted(maps.Keys(tasks)) {
+		if phases[name] == config.PhaseSetup {
+			setup = append(setup, name)
+		}
+	}
+	if len(setup) == 0 {
+		return
+	}
+
+	for name, task := range tasks {
+		if phases[name] != "" || needsRegular(task, phases) {
+			continue
+		}
+		needs := append(config.StringList(nil), task.Needs...)
+		for _, s := range setup {
+			if !slices.Contains(needs, s) {
+				needs = append(needs, s)
+			}
+		}
+		task.Needs = needs
+		tasks[name] = task
+	}
+}
+
+// afterAll returns a copy of tasks in which every teardown task that needs no
+// other teardown task also needs the last tasks of the setup and regular
+// phases, ordering it after all of them. These edges only order the plan:
+// a teardown task's Dependencies keep its declared needs, so a failed
+// regular task doesn't keep it from running.
+func afterAll(tasks map[string]config.TaskConfig, phases map[string]string) map[string]config.TaskConfig {
+	// Tasks outside teardown that no other task outside teardown needs
+	needed := make(map[string]bool)
+	for name, task := range tasks {
+		if phases[name] != config.PhaseTeardown {
+			for _, dep := range task.Needs {
+				needed[dep] = true
+			}
+		}
+	}
+	var last []string
+	for _, name := range slices.Sorted(maps.Keys(tasks)) {
+		if phases[name] != config.PhaseTeardown && !needed[name] {
+			last = append(last, name)
+		}
+	}
+
+	ordered := make(map[string]config.TaskConfig, len(tasks))
+	for name, task := range tasks {
+		if phases[name] == config.PhaseTeardown && len(task.Needs) == 0 {
+			task.Needs = append(config.StringList(nil), last...)
+		}
+		ordered[name] = task
+	}
+	return ordered
+}
+
+// needsRegular reports whether a task needs a task outside setup and teardown.
+func needsRegular(task config.TaskConfig, phases map[string]string) bool {
+	for _, dep := range task.Needs {
+		if phases[dep] == "" {
+			return true
+		}
+	}
+	return false
+}
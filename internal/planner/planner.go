@@ -8,27 +8,71 @@ import (
 
 // ExecutionTask represents a task ready for execution with resolved agent info.
 type ExecutionTask struct {
-	Name         string   // Task name
-	AgentName    string   // Agent reference name
-	Tool         string   // CLI tool (claude-code, opencode)
-	Model        string   // Model identifier
-	Prompt       string   // Prompt text (resolved from prompt_file if needed)
-	Write        bool     // Allow file writes
-	Dependencies []string // Names of tasks this depends on
-	Workdir      string   // Working directory for agent execution
+	Name            string                    // Task name
+	AgentName       string                    // Agent reference name
+	Tool            string                    // CLI tool (claude-code, opencode)
+	Model           string                    // Model identifier
+	Prompt          string                    // Prompt text (resolved from prompt_file if needed)
+	SystemPrompt    string                    // System prompt of AI tasks (empty: the tool's default)
+	MCPServers      []string                  // MCP config files of claude-code tasks
+	Sandbox         *config.SandboxConfig     // Container the task runs in (nil: on the host)
+	Write           bool                      // Allow file writes
+	Permissions     *config.PermissionsConfig // Tools and paths claude-code tasks may use (nil: all or none, by Write)
+	Limits          config.TaskLimits         // Resource caps of shell tasks
+	Dependencies    []string                  // Names of tasks this depends on
+	Workdir         string                    // Working directory for agent execution
+	ScriptLang      string                    // Interpreter language for script tasks
+	Shell           string                    // Shell for shell agents (empty: the platform default)
+	BaseURL         string                    // API endpoint for api agents
+	APIKeyEnv       string                    // Env var holding the API key for api agents
+	Proxy           string                    // Proxy URL for api agents
+	TLS             *config.TLSConfig         // Certificate settings for api agents
+	Warmup          bool                      // Connect to the api agent's endpoint at run start
+	Inputs          []string                  // Declared input files (absolute, may be globs)
+	Outputs         []string                  // Declared output files (absolute)
+	Artifacts       []string                  // Files (absolute, may be globs) to collect after the task
+	Env             map[string]string         // Environment variables (agent env merged with task env)
+	When            string                    // Runtime condition; the task is skipped when false
+	Until           string                    // Condition ending a loop task; empty for other tasks
+	MaxIterations   int                       // Cap on the runs of a loop task
+	HTTP            *config.HTTPConfig        // Request for http tasks
+	Git             *config.GitConfig         // Operation for git tasks
+	Wait            string                    // Delay for wait tasks
+	Poll            *config.PollConfig        // Check for poll tasks
+	Notify          *config.NotifyConfig      // Message settings for notify tasks
+	PullRequest     *config.PullRequestConfig // Pull request for pull_request tasks
+	ShowOutput      string                    // Per-task output mode (full, summary, none)
+	ContinueOnError bool                      // A failure neither fails the run nor stops dependents
+	Priority        int                       // Start order among ready tasks, raised to that of the task's dependents
+	Phase           string                    // config.PhaseSetup, config.PhaseTeardown, or "" for a regular task
+	ReviewChanges   bool                      // Record the git changes the task makes for its review task
+	ReviewOf        string                    // Task whose changes this task reviews
+	Rollback        bool                      // Undo the reviewed task's changes if this review rejects them
+	PromptHooks     []string                  // Commands transforming the prompt, from the agent
+	OutputFormat    string                    // config.OutputText or config.OutputJSON
+	OutputSchema    map[string]interface{}    // JSON Schema the JSON output must match
 }
 
 // ExecutionPlan represents an ordered list of tasks to execute.
 type ExecutionPlan struct {
-	Tasks []ExecutionTask
-	DAG   *DAG // The dependency graph for parallel execution
+	Tasks  []ExecutionTask
+	DAG    *DAG                // The dependency graph for parallel execution
+	Groups map[string][]string // Fan-out task name -> expanded instance names
 }
 
 // BuildPlan creates an execution plan from the configuration.
 // Returns tasks in dependency order (dependencies before dependents).
 func BuildPlan(cfg *config.AgentflowConfig) (*ExecutionPlan, error) {
+	// Expand items_from fan-out into per-item tasks
+	taskConfigs, groups := ExpandFanOut(cfg.Tasks)
+
+	// Setup tasks come before every other task, teardown tasks after
+	phases := taskPhases(cfg, taskConfigs, groups)
+	ExpandReviews(taskConfigs, phases)
+	needSetup(taskConfigs, phases)
+
 	// Build DAG from tasks
-	dag := BuildDAG(cfg.Tasks)
+	dag := BuildDAG(afterAll(taskConfigs, phases))
 
 	// Get topologically sorted task names
 	order, err := TopologicalSort(dag)
@@ -38,9 +82,20 @@ func BuildPlan(cfg *config.AgentflowConfig) (*ExecutionPlan, error) {
 
 	// Build execution tasks with resolved agent info
 	tasks := make([]ExecutionTask, 0, len(order))
+	workdirs := make(map[string]string, len(order))
 	for _, name := range order {
-		taskCfg := cfg.Tasks[name]
-		agentCfg := cfg.Agents[taskCfg.Agent]
+		taskCfg := taskConfigs[name]
+
+		if builtin := taskCfg.BuiltinTool(); builtin != "" {
+			task := buildBuiltinTask(name, builtin, taskCfg, firstNonEmpty(taskCfg.Workdir, cfg.Workdir))
+			workdirs[name] = task.Workdir
+			task.Phase = phases[name]
+			task.ReviewChanges = taskCfg.ReviewWith != ""
+			tasks = append(tasks, task)
+			continue
+		}
+
+		agentCfg := taskCfg.RunsWith(cfg.Agents[taskCfg.Agent])
 
 		// For shell agents, use Command field; for AI agents, use Prompt
 		prompt := taskCfg.Prompt
@@ -48,19 +103,133 @@ func BuildPlan(cfg *config.AgentflowConfig) (*ExecutionPlan, error) {
 			prompt = taskCfg.Command
 		}
 
+		workdir := firstNonEmpty(taskCfg.Workdir, agentCfg.Workdir, cfg.Workdir)
+		if taskCfg.ReviewOf != "" {
+			// Review in the directory the reviewed task changed
+			workdir = workdirs[taskCfg.ReviewOf]
+		}
+		workdirs[name] = workdir
+
 		tasks = append(tasks, ExecutionTask{
-			Name:         name,
-			AgentName:    taskCfg.Agent,
-			Tool:         agentCfg.Tool,
-			Model:        agentCfg.Model,
-			Prompt:       prompt,
-			Write:        taskCfg.Write,
-			Dependencies: taskCfg.Needs,
-			Workdir:      cfg.Workdir,
+			Name:            name,
+			AgentName:       taskCfg.Agent,
+			Tool:            agentCfg.Tool,
+			Model:           agentCfg.Model,
+			Prompt:          prompt,
+			SystemPrompt:    agentCfg.SystemPrompt,
+			MCPServers:      agentCfg.MCPServers,
+			Sandbox:         agentCfg.Sandbox,
+			Write:           taskCfg.Write,
+			Permissions:     taskCfg.Permissions,
+			Limits:          taskCfg.Limits.Parse(),
+			Dependencies:    taskCfg.Needs,
+			Workdir:         workdir,
+			BaseURL:         agentCfg.BaseURL,
+			APIKeyEnv:       agentCfg.APIKeyEnv,
+			Proxy:           agentCfg.Proxy,
+			Shell:           agentCfg.Shell,
+			TLS:             agentCfg.TLS,
+			Warmup:          agentCfg.Warmup,
+			PromptHooks:     agentCfg.PromptHooks,
+			OutputFormat:    taskCfg.OutputFormat,
+			OutputSchema:    taskCfg.OutputSchema,
+			Inputs:          taskCfg.Inputs,
+			Outputs:         taskCfg.Outputs,
+			Artifacts:       taskCfg.Artifacts,
+			Env:             mergeEnv(agentCfg.Env, taskCfg.Env),
+			When:            taskCfg.When,
+			Until:           taskCfg.Until,
+			MaxIterations:   taskCfg.Iterations(),
+			ShowOutput:      taskCfg.ShowOutput,
+			ContinueOnError: taskCfg.ContinueOnError,
+			Priority:        taskCfg.Priority,
+			Phase:           phases[name],
+			ReviewChanges:   taskCfg.ReviewWith != "",
+			ReviewOf:        taskCfg.ReviewOf,
+			Rollback:        taskCfg.RollbackOnReject && taskCfg.ReviewOf != "",
 		})
 	}
+	inheritPriorities(tasks, dag)
+
+	return &ExecutionPlan{Tasks: tasks, DAG: dag, Groups: groups}, nil
+}
+
+// buildBuiltinTask creates an execution task for a built-in task type.
+// Built-in tasks have no agent, so the tool name doubles as the agent name.
+func buildBuiltinTask(name, tool string, taskCfg config.TaskConfig, workdir string) ExecutionTask {
+	task := ExecutionTask{
+		Name:            name,
+		AgentName:       tool,
+		Tool:            tool,
+		Write:           taskCfg.Write,
+		Dependencies:    taskCfg.Needs,
+		Workdir:         workdir,
+		Inputs:          taskCfg.Inputs,
+		Outputs:         taskCfg.Outputs,
+		Artifacts:       taskCfg.Artifacts,
+		Env:             mergeEnv(nil, taskCfg.Env),
+		When:            taskCfg.When,
+		Until:           taskCfg.Until,
+		MaxIterations:   taskCfg.Iterations(),
+		ShowOutput:      taskCfg.ShowOutput,
+		ContinueOnError: taskCfg.ContinueOnError,
+		Priority:        taskCfg.Priority,
+	}
+
+	if taskCfg.Script != nil {
+		task.Prompt = taskCfg.Script.Code
+		task.ScriptLang = taskCfg.Script.Lang
+	}
+	if taskCfg.HTTP != nil {
+		task.Prompt = taskCfg.HTTP.Body
+		task.HTTP = taskCfg.HTTP
+	}
+	if taskCfg.Git != nil {
+		task.Git = taskCfg.Git
+	}
+	if taskCfg.Wait != nil {
+		task.Wait = taskCfg.Wait.Duration
+	}
+	if taskCfg.Poll != nil {
+		task.Poll = taskCfg.Poll
+	}
+	if taskCfg.Notify != nil {
+		task.Prompt = taskCfg.Notify.Message
+		task.Notify = taskCfg.Notify
+	}
+	if taskCfg.PullRequest != nil {
+		task.Prompt = taskCfg.PullRequest.Body
+		task.PullRequest = taskCfg.PullRequest
+	}
+
+	return task
+}
 
-	return &ExecutionPlan{Tasks: tasks, DAG: dag}, nil
+// mergeEnv combines agent and task environment variables.
+// Task values take precedence. Returns nil if neither defines any.
+func mergeEnv(agentEnv, taskEnv map[string]string) map[string]string {
+	if len(agentEnv) == 0 && len(taskEnv) == 0 {
+		return nil
+	}
+	env := make(map[string]string, len(agentEnv)+len(taskEnv))
+	for k, v := range agentEnv {
+		env[k] = v
+	}
+	for k, v := range taskEnv {
+		env[k] = v
+	}
+	return env
+}
+
+// firstNonEmpty returns the first non-empty value, used to apply workdir
+// overrides from most to least specific.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
 }
 
 // String returns a human-readable representation of the execution plan.
@@ -0,0 +1,95 @@
+package planner
+
+import (
+	"slices"
+
+	"github.com/adityaraj/agentflow/internal/config"
+)
+
+// inheritPriorities raises each task's priority to the highest priority of
+// the tasks that depend on it, directly or transitively, so that a
+// high-priority task is not kept waiting behind low-priority upstream tasks.
+// Teardown tasks only pass their priority on to other teardown tasks, since
+// they wait for every other task anyway. tasks must be in dependency order.
+func inheritPriorities(tasks []ExecutionTask, dag *DAG) {
+	index := make(map[string]int, len(tasks))
+	for i, t := range tasks {
+		index[t.Name] = i
+	}
+
+	// Dependents come later in the plan, so walking it backwards settles
+	// each task's priority before its dependencies inherit it
+	for i := len(tasks) - 1; i >= 0; i-- {
+		for _, dependent := range dag.ReverseEdges[tasks[i].Name] {
+			j, ok := index[dependent]
+			if !ok || (tasks[j].Phase == config.PhaseTeardown && tasks[i].Phase != config.PhaseTeardown) {
+				continue
+			}
+			if tasks[j].Priority > tasks[i].Priority {
+				tasks[i].Priority = tasks[j].Priority
+			}
+		}
+	}
+}
+
+// ReadyQueue holds tasks whose dependencies have finished, in the order
+// they should start: highest priority first, then plan order.
+type ReadyQueue struct {
+	position map[string]int // Index in the plan
+	priority map[string]int
+	names    []string
+}
+
+// NewReadyQueue creates an empty queue for the tasks of plan.
+func NewReadyQueue(plan *ExecutionPlan) *ReadyQueue {
+	q := &ReadyQueue{
+		position: make(map[string]int, len(plan.Tasks)),
+		priority: make(map[string]int, len(plan.Tasks)),
+	}
+	for i, t := range plan.Tasks {
+		q.position[t.Name] = i
+		q.priority[t.Name] = t.Priority
+	}
+	return q
+}
+
+// Len returns the number of queued tasks.
+func (q *ReadyQueue) Len() int {
+	return len(q.names)
+}
+
+// Push adds a ready task.
+func (q *ReadyQueue) Push(name string) {
+	i := len(q.names)
+	for i > 0 && q.before(name, q.names[i-1]) {
+		i--
+	}
+	q.names = append(q.names, "")
+	copy(q.names[i+1:], q.names[i:])
+	q.names[i] = name
+}
+
+// Peek returns the task that should start next. The queue must not be empty.
+func (q *ReadyQueue) Peek() string {
+	return q.names[0]
+}
+
+// Pop removes and returns the task that should start next.
+func (q *ReadyQueue) Pop() string {
+	name := q.names[0]
+	q.names = q.names[1:]
+	return name
+}
+
+// Retain removes the queued tasks for which keep returns false.
+func (q *ReadyQueue) Retain(keep func(name string) bool) {
+	q.names = slices.DeleteFunc(q.names, func(name string) bool { return !keep(name) })
+}
+
+// before reports whether task a should start before task b.
+func (q *ReadyQueue) before(a, b string) bool {
+	if q.priority[a] != q.priority[b] {
+		return q.priority[a] > q.priority[b]
+	}
+	return q.position[a] < q.position[b]
+}
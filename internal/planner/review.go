@@ -0,0 +1,54 @@
+package planner
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/adityaraj/agentflow/internal/config"
+)
+
+// ExpandReviews adds a task reviewing the changes of each task with
+// review_changes_with, named by config.ReviewTaskName and in the same phase,
+// and rewires the task's dependents to need the review, so they only start
+// once the changes were accepted (or rolled back).
+func ExpandReviews(tasks map[string]config.TaskConfig, phases map[string]string) {
+	reviews := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(tasks)) {
+		task := tasks[name]
+		if task.ReviewWith == "" {
+			continue
+		}
+		review := config.ReviewTaskName(name)
+		tasks[review] = config.TaskConfig{
+			Agent:            task.ReviewWith,
+			Prompt:           config.ReviewPrompt(name),
+			Needs:            config.StringList{name},
+			Workdir:          task.Workdir,
+			Priority:         task.Priority,
+			RollbackOnReject: task.RollbackOnReject,
+			ReviewOf:         name,
+		}
+		if phase, ok := phases[name]; ok {
+			phases[review] = phase
+		}
+		reviews[name] = review
+	}
+	if len(reviews) == 0 {
+		return
+	}
+
+	for name, task := range tasks {
+		if task.ReviewOf != "" {
+			continue
+		}
+		var needs config.StringList
+		for _, dep := range task.Needs {
+			if review, ok := reviews[dep]; ok {
+				dep = review
+			}
+			needs = append(needs, dep)
+		}
+		task.Needs = needs
+		tasks[name] = task
+	}
+}
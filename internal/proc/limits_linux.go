@@ -0,0 +1,59 @@
+package proc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// limitShell sets the limits of a command before executing it.
+const limitShell = "/bin/sh"
+
+// LimitFailedStatus is the exit status of a command run with Limit whose
+// limits couldn't be set. The command itself did not run.
+const LimitFailedStatus = 126
+
+// Limit makes cmd, before it starts, run with its CPU seconds and address
+// space in bytes capped, and so the processes it starts. Zero means no
+// limit. The limits are set with ulimit by a shell that then executes the
+// command, so they apply before the command runs any code. A process past
+// its CPU time gets SIGXCPU, then SIGKILL a second later.
+func Limit(cmd *exec.Cmd, cpuSeconds uint64, memoryBytes uint64) error {
+	if cmd.Process != nil {
+		return fmt.Errorf("limits must be set before the command starts")
+	}
+	if cpuSeconds == 0 && memoryBytes == 0 {
+		return nil
+	}
+
+	script := ""
+	if cpuSeconds > 0 {
+		// The soft limit is lowered first, as it may not exceed the hard one
+		script += fmt.Sprintf("ulimit -S -t %d && ulimit -H -t %d && ", cpuSeconds, cpuSeconds+1)
+	}
+	if memoryBytes > 0 {
+		script += fmt.Sprintf("ulimit -v %d && ", (memoryBytes+1023)/1024)
+	}
+	script = fmt.Sprintf(`if ! { %s:; }; then echo "cortex: failed to apply limits" >&2; exit %d; fi; exec "$0" "$@"`,
+		script, LimitFailedStatus)
+
+	// The command is passed as arguments, so it needs no quoting
+	cmd.Args = append([]string{limitShell, "-c", script, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = limitShell
+	return nil
+}
+
+// CPULimitReached reports whether a process exited because it, or a
+// command of the shell it ran, used up its CPU time: it was killed by
+// SIGXCPU, or exited with the status shells report that with.
+func CPULimitReached(state *os.ProcessState) bool {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok {
+		return false
+	}
+	if status.Signaled() {
+		return status.Signal() == syscall.SIGXCPU
+	}
+	return status.ExitStatus() == 128+int(syscall.SIGXCPU)
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package proc
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// Limit caps the CPU seconds and address space of a command before it
+// starts. It is only supported on Linux.
+func Limit(cmd *exec.Cmd, cpuSeconds uint64, memoryBytes uint64) error {
+	if cpuSeconds == 0 && memoryBytes == 0 {
+		return nil
+	}
+	return errors.New("CPU and memory limits are only supported on Linux")
+}
+
+// CPULimitReached reports whether a process exited because it used up its
+// CPU time, which Limit never limits here.
+func CPULimitReached(state *os.ProcessState) bool {
+	return false
+}
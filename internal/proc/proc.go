@@ -0,0 +1,169 @@
+// Package proc starts and interrupts processes the same way on Unix and
+// Windows: shell commands run with /bin/sh or cmd.exe, and interrupts are
+// SIGINT or console Ctrl+Break events.
+package proc
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultKillTimeout is how long KillOnCancel waits for an interrupted
+// command to exit before killing it.
+const DefaultKillTimeout = 5 * time.Second
+
+// DefaultShell returns the shell commands run with when none is set:
+// %COMSPEC% (cmd.exe) on Windows and /bin/sh elsewhere.
+func DefaultShell() string {
+	if runtime.GOOS != "windows" {
+		return "/bin/sh"
+	}
+	if comspec := os.Getenv("COMSPEC"); comspec != "" {
+		return comspec
+	}
+	return "cmd.exe"
+}
+
+// ShellCommand returns the command running command with shell, or with
+// DefaultShell if shell is empty. cmd.exe gets the command after /S /C,
+// PowerShell (powershell or pwsh) after -Command, and other shells after -c.
+func ShellCommand(ctx context.Context, shell, command string) *exec.Cmd {
+	if shell == "" {
+		shell = DefaultShell()
+	}
+	switch shellName(shell) {
+	case "cmd":
+		cmd := exec.CommandContext(ctx, shell)
+		setCmdArgs(cmd, "/S", "/C", command)
+		return cmd
+	case "powershell", "pwsh":
+		return exec.CommandContext(ctx, shell, "-NoProfile", "-NonInteractive", "-Command", command)
+	default:
+		return exec.CommandContext(ctx, shell, "-c", command)
+	}
+}
+
+// shellName returns the lowercase name of a shell's executable, without
+// directory or .exe.
+func shellName(shell string) string {
+	// Windows paths use backslashes, also when checked on other systems
+	name := filepath.Base(strings.ReplaceAll(shell, `\`, "/"))
+	return strings.TrimSuffix(strings.ToLower(name), ".exe")
+}
+
+// Interruptible prepares a command, before it starts, to be stopped with
+// Interrupt. On Windows it gets a process group of its own, so interrupts
+// reach only it.
+func Interruptible(cmd *exec.Cmd) {
+	setInterruptible(cmd)
+}
+
+// Interrupt asks a process started with Interruptible to stop: SIGINT on
+// Unix and Ctrl+Break on Windows, both os.Interrupt to a Go program.
+func Interrupt(p *os.Process) error {
+	return interrupt(p)
+}
+
+// InterruptSelf interrupts the current process as if Ctrl+C were pressed,
+// for terminals in raw mode that read Ctrl+C as input. On Windows the
+// processes sharing the console are interrupted too.
+func InterruptSelf() error {
+	return interruptSelf()
+}
+
+// stopping holds the process groups of cancelled commands that are waiting
+// out their kill timeout, by process ID.
+var (
+	stopping   = make(map[int]*time.Timer)
+	stoppingMu sync.Mutex
+)
+
+// KillOnCancel makes cancelling the context of a command, before it starts,
+// stop the command and every process it started: they get an interrupt
+// (SIGINT, or Ctrl+Break on Windows) in a process group of their own, and
+// are killed if still running after timeout (DefaultKillTimeout if not
+// positive). Wait returns at most timeout after the cancellation, even if
+// the command's output is still held open. Such commands are run with Run
+// or Wait of this package, which call off the kill once the command is done.
+func KillOnCancel(cmd *exec.Cmd, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultKillTimeout
+	}
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		pid := cmd.Process.Pid
+		if err := interruptGroup(pid); err != nil {
+			return killGroup(pid)
+		}
+		stoppingMu.Lock()
+		defer stoppingMu.Unlock()
+		var timer *time.Timer
+		timer = time.AfterFunc(timeout, func() {
+			stoppingMu.Lock()
+			pending := stopping[pid] == timer
+			if pending {
+				delete(stopping, pid)
+			}
+			stoppingMu.Unlock()
+			if pending {
+				_ = killGroup(pid)
+			}
+		})
+		stopping[pid] = timer
+		return nil
+	}
+	cmd.WaitDelay = timeout
+}
+
+// Run starts a command of KillOnCancel and waits for it with Wait.
+func Run(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return Wait(cmd)
+}
+
+// Wait waits for a started command of KillOnCancel like cmd.Wait. If the
+// command was cancelled, its pending kill is called off, since the ID of
+// its process group may be reused once every process in it has exited:
+// the processes left are killed right away instead.
+func Wait(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+
+	pid := cmd.Process.Pid
+	stoppingMu.Lock()
+	timer, pending := stopping[pid]
+	if pending {
+		delete(stopping, pid)
+	}
+	stoppingMu.Unlock()
+	if pending && timer.Stop() {
+		// Background processes of the command may still be running
+		_ = killGroup(pid)
+	}
+	return err
+}
+
+// KillStopping kills the cancelled commands of KillOnCancel that haven't
+// reached their kill timeout yet, for exiting without waiting for them.
+func KillStopping() {
+	stoppingMu.Lock()
+	defer stoppingMu.Unlock()
+	for pid, timer := range stopping {
+		if timer.Stop() {
+			_ = killGroup(pid)
+		}
+		delete(stopping, pid)
+	}
+}
+
+// Alive reports whether a process with the ID is running.
+func Alive(pid int) bool {
+	return pid > 0 && alive(pid)
+}
@@ -0,0 +1,53 @@
+//go:build !windows
+
+package proc
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// ShutdownSignals are the signals asking cortex to stop.
+var ShutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+func setCmdArgs(cmd *exec.Cmd, args ...string) {
+	cmd.Args = append(cmd.Args, args...)
+}
+
+func setInterruptible(cmd *exec.Cmd) {}
+
+func interrupt(p *os.Process) error {
+	return p.Signal(os.Interrupt)
+}
+
+func interruptSelf() error {
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+	return p.Signal(os.Interrupt)
+}
+
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// interruptGroup and killGroup signal the process group led by pid.
+func interruptGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGINT)
+}
+
+func killGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+func alive(pid int) bool {
+	// Signal 0 checks that the process exists; EPERM means it belongs to
+	// another user
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
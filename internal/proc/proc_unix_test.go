@@ -0,0 +1,56 @@
+//go:build !windows
+
+package proc
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestKillOnCancel tests that cancelling a command stops the processes it
+// started in the background too, once Wait returns and without waiting out
+// the kill timeout.
+func TestKillOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Background commands of sh ignore SIGINT, so only the kill stops sleep
+	cmd := ShellCommand(ctx, "", "sleep 100 & echo $!; wait")
+	KillOnCancel(cmd, time.Minute)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read the pid of sleep: %v", err)
+	}
+	child, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		t.Fatalf("pid of sleep = %q, want a number", line)
+	}
+
+	start := time.Now()
+	cancel()
+	if err := Wait(cmd); err == nil {
+		t.Error("Wait() of a cancelled command succeeded, want an error")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Wait() took %v, want it to return without waiting out the kill timeout", elapsed)
+	}
+
+	// sleep is reaped by its new parent once killed
+	deadline := time.Now().Add(10 * time.Second)
+	for Alive(child) {
+		if time.Now().After(deadline) {
+			t.Fatalf("sleep (pid %d) still running after Wait() returned", child)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
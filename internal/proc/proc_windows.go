@@ -0,0 +1,78 @@
+//go:build windows
+
+package proc
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ShutdownSignals are the signals asking cortex to stop. Windows has no
+// SIGTERM; Ctrl+C and Ctrl+Break both arrive as os.Interrupt.
+var ShutdownSignals = []os.Signal{os.Interrupt}
+
+var procGenerateConsoleCtrlEvent = syscall.NewLazyDLL("kernel32.dll").NewProc("GenerateConsoleCtrlEvent")
+
+// setCmdArgs sets the command line verbatim: cmd.exe doesn't parse the
+// escaping Go applies to arguments, so /C "<command>" is passed as written.
+func setCmdArgs(cmd *exec.Cmd, args ...string) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	last := len(args) - 1
+	line := syscall.EscapeArg(cmd.Path) + " " + strings.Join(args[:last], " ") + ` "` + args[last] + `"`
+	cmd.SysProcAttr.CmdLine = line
+	cmd.Args = append(cmd.Args, args...)
+}
+
+func setInterruptible(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+func interrupt(p *os.Process) error {
+	return generateConsoleCtrlEvent(syscall.CTRL_BREAK_EVENT, uint32(p.Pid))
+}
+
+func interruptSelf() error {
+	// Process group 0 is every process attached to the console
+	return generateConsoleCtrlEvent(syscall.CTRL_C_EVENT, 0)
+}
+
+func generateConsoleCtrlEvent(event, processGroup uint32) error {
+	if ok, _, err := procGenerateConsoleCtrlEvent.Call(uintptr(event), uintptr(processGroup)); ok == 0 {
+		return err
+	}
+	return nil
+}
+
+func setProcessGroup(cmd *exec.Cmd) {
+	setInterruptible(cmd)
+}
+
+func interruptGroup(pid int) error {
+	return generateConsoleCtrlEvent(syscall.CTRL_BREAK_EVENT, uint32(pid))
+}
+
+// killGroup kills the process and its descendants. Windows has no process
+// groups to signal, so taskkill walks the process tree.
+func killGroup(pid int) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).Run()
+}
+
+func alive(pid int) bool {
+	const processQueryLimitedInformation = 0x1000
+	const stillActive = 259
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	return syscall.GetExitCodeProcess(h, &code) == nil && code == stillActive
+}
@@ -0,0 +1,30 @@
+// Package redact masks secret values in text before it is saved or sent,
+// such as task output, logs, and webhook payloads.
+package redact
+
+import (
+	"sort"
+	"strings"
+)
+
+// Secrets replaces every occurrence of the given secret values in text with
+// "***". Longer values are replaced first so overlapping secrets are fully
+// masked.
+func Secrets(text string, secrets []string) string {
+	if text == "" || len(secrets) == 0 {
+		return text
+	}
+
+	values := make([]string, 0, len(secrets))
+	for _, s := range secrets {
+		if s != "" {
+			values = append(values, s)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	for _, v := range values {
+		text = strings.ReplaceAll(text, v, "***")
+	}
+	return text
+}
@@ -0,0 +1,27 @@
+package redact
+
+import "testing"
+
+// TestSecrets tests masking secret values in text.
+func TestSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		secrets []string
+		want    string
+	}{
+		{"no secrets", "token abc", nil, "token abc"},
+		{"single", "token abc123 used", []string{"abc123"}, "token *** used"},
+		{"repeated", "abc abc", []string{"abc"}, "*** ***"},
+		{"overlapping prefers longer", "key abcdef", []string{"abc", "abcdef"}, "key ***"},
+		{"empty secret ignored", "text", []string{""}, "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Secrets(tt.text, tt.secrets); got != tt.want {
+				t.Errorf("Secrets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// Abort stops a run that is taking too long to stop, as on a second
+// interrupt: it cancels every task, teardown tasks included, and waits up to
+// grace for the run to finish and be saved. Failing that, it saves the run as
+// it stands, with the tasks still running marked cancelled, so the caller can
+// exit without leaving a half-written session.
+func (e *Executor) Abort(grace time.Duration) {
+	e.runMu.Lock()
+	abort, saved := e.abortRun, e.runSaved
+	e.runMu.Unlock()
+	if abort == nil {
+		return // Not started
+	}
+	abort()
+
+	select {
+	case <-saved:
+	case <-time.After(grace):
+		e.saveRun()
+	}
+}
+
+// saveRun saves the run in progress. Its running tasks are saved as
+// cancelled, with the output their logs got so far as their agents haven't
+// returned any.
+func (e *Executor) saveRun() {
+	e.runMu.Lock()
+	defer e.runMu.Unlock()
+	if e.run == nil {
+		return
+	}
+
+	run := &state.RunResult{
+		RunID:     e.run.RunID,
+		User:      e.run.User,
+		StartTime: e.run.StartTime,
+		EndTime:   time.Now(),
+		Tasks:     slices.Clone(e.run.Tasks),
+		Error:     "interrupted",
+		Cancelled: true,
+	}
+	for _, name := range slices.Sorted(maps.Keys(e.inflight)) {
+		taskResult := *e.inflight[name]
+		var streamed string
+		if taskResult.LogFile != "" {
+			if data, err := os.ReadFile(filepath.Join(e.store.RunDir(), taskResult.LogFile)); err == nil {
+				streamed = string(data)
+			}
+		}
+		taskResult.Cancel(streamed, "")
+		if err := e.store.SaveTaskResult(&taskResult); err != nil {
+			slog.Warn("failed to save task result", "task", name, "error", err)
+		}
+		run.Tasks = append(run.Tasks, taskResult)
+	}
+	run.CalculateTotalTokens()
+	e.store.RemoveStatus()
+
+	if err := e.store.SaveRunResult(run); err != nil {
+		ui.Warning("Failed to save run: %s", err)
+		slog.Warn("failed to save run result", "error", err)
+	}
+}
+
+// trackTask records that a task's agent is running.
+func (e *Executor) trackTask(taskResult *state.TaskResult) {
+	e.runMu.Lock()
+	defer e.runMu.Unlock()
+	e.inflight[taskResult.TaskName] = taskResult
+}
+
+// untrackTask records that a task's agent has returned.
+func (e *Executor) untrackTask(name string) {
+	e.runMu.Lock()
+	defer e.runMu.Unlock()
+	delete(e.inflight, name)
+}
+
+// addResult adds a finished task to the run.
+func (e *Executor) addResult(runResult *state.RunResult, taskResult *state.TaskResult) {
+	e.runMu.Lock()
+	defer e.runMu.Unlock()
+	runResult.Tasks = append(runResult.Tasks, *taskResult)
+}
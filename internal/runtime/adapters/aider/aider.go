@@ -0,0 +1,138 @@
+// Package aider implements the Agent interface for the aider CLI.
+package aider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/adityaraj/agentflow/internal/proc"
+	"github.com/adityaraj/agentflow/internal/runtime"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// Adapter implements the Agent interface for aider CLI.
+type Adapter struct {
+	// executable is the name or path of the aider CLI binary
+	executable string
+	// streamLogs enables real-time output streaming
+	streamLogs bool
+	// workdir specifies the working directory for execution
+	workdir string
+}
+
+// New creates a new aider adapter.
+// Uses "aider" as the default executable name.
+func New() *Adapter {
+	return &Adapter{
+		executable: "aider",
+		streamLogs: false,
+	}
+}
+
+// NewWithExecutable creates an aider adapter with a custom executable path.
+func NewWithExecutable(executable string) *Adapter {
+	return &Adapter{
+		executable: executable,
+		streamLogs: false,
+	}
+}
+
+// SetStreamLogs enables or disables real-time log streaming.
+func (a *Adapter) SetStreamLogs(enabled bool) {
+	a.streamLogs = enabled
+}
+
+// SetWorkdir sets the working directory for execution.
+func (a *Adapter) SetWorkdir(dir string) {
+	a.workdir = dir
+}
+
+// Run executes a task using the aider CLI.
+// Aider has no working directory flag, so the process is started in the
+// task or adapter workdir instead.
+func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	stream := task.Streaming(a.streamLogs)
+	args := a.buildArgs(task)
+
+	workdir := task.Workdir
+	if workdir == "" {
+		workdir = a.workdir
+	}
+	cmd := task.Command(ctx, workdir, a.executable, args...)
+	cmd.Env = task.Environ()
+	proc.KillOnCancel(cmd, task.KillTimeout)
+
+	stdout := runtime.NewCappedBuffer(task.MaxOutput)
+	stderr := runtime.NewCappedBuffer(task.MaxOutput)
+
+	out, errOut := task.Live(stream)
+	if stream {
+		ui.PrintStreamStart(out)
+	}
+	cmd.Stdout = io.MultiWriter(out, stdout)
+	cmd.Stderr = io.MultiWriter(errOut, stderr)
+
+	err := proc.Run(cmd)
+
+	if stream {
+		ui.PrintStreamEnd(task.Out())
+	}
+
+	// Diff output is kept verbatim (no markdown stripping) so it stays applicable
+	result := runtime.Result{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		ExitCode:  0,
+		Success:   true,
+		Truncated: stdout.Truncated() || stderr.Truncated(),
+	}
+
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+			result.Success = false
+		} else {
+			return result, fmt.Errorf("failed to execute aider: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// buildArgs constructs the command-line arguments for aider.
+func (a *Adapter) buildArgs(task runtime.Task) []string {
+	// Run a single message non-interactively with plain, unstreamed output
+	// and include applied diffs in stdout
+	args := []string{
+		"--message", task.Prompt,
+		"--no-pretty",
+		"--no-stream",
+		"--show-diffs",
+		"--no-check-update",
+	}
+
+	if task.Model != "" {
+		args = append(args, "--model", task.Model)
+	}
+
+	// Write-enabled tasks apply and commit edits without prompting;
+	// read-only tasks never touch the working tree
+	if task.Write {
+		args = append(args, "--yes-always", "--auto-commits")
+	} else {
+		args = append(args, "--no-auto-commits", "--dry-run")
+	}
+
+	return args
+}
+
+// Check verifies that the aider CLI is available.
+func (a *Adapter) Check() error {
+	cmd := exec.Command(a.executable, "--version")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("aider CLI not found or not executable: %w", err)
+	}
+	return nil
+}
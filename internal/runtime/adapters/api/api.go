@@ -0,0 +1,257 @@
+// Package api implements the Agent interface for OpenAI-compatible chat completions endpoints.
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/runtime"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// Defaults used when an agent does not configure base_url or api_key_env.
+const (
+	DefaultBaseURL   = config.DefaultAPIBaseURL
+	DefaultAPIKeyEnv = config.DefaultAPIKeyEnv
+)
+
+// Adapter implements the Agent interface by calling a chat completions API directly.
+type Adapter struct {
+	// clients pools connections per proxy and TLS settings
+	clients *runtime.HTTPClients
+	// streamLogs enables real-time output streaming (SSE)
+	streamLogs bool
+}
+
+// New creates a new API adapter.
+// No request timeout is set; cancellation comes from the task context.
+func New() *Adapter {
+	return &Adapter{
+		clients:    runtime.NewHTTPClients(),
+		streamLogs: false,
+	}
+}
+
+// SetStreamLogs enables or disables real-time log streaming.
+func (a *Adapter) SetStreamLogs(enabled bool) {
+	a.streamLogs = enabled
+}
+
+// chatMessage is a single message in a chat completions request or response.
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest is the chat completions request body.
+type chatRequest struct {
+	Model         string         `json:"model"`
+	Messages      []chatMessage  `json:"messages"`
+	Stream        bool           `json:"stream"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+// streamOptions asks the server to include usage in the final stream chunk.
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatUsage reports token usage for a completion.
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// chatResponse covers both full responses and SSE stream chunks.
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+		Delta   chatMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage"`
+}
+
+// Run sends the task prompt to the configured endpoint and returns the completion.
+// Non-2xx responses are reported as a failed result rather than an error,
+// mirroring a CLI exiting with a non-zero code.
+func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	stream := task.Streaming(a.streamLogs)
+	baseURL := task.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	keyEnv := task.APIKeyEnv
+	if keyEnv == "" {
+		keyEnv = DefaultAPIKeyEnv
+	}
+
+	var messages []chatMessage
+	if task.SystemPrompt != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: task.SystemPrompt})
+	}
+	body := chatRequest{
+		Model:    task.Model,
+		Messages: append(messages, chatMessage{Role: "user", Content: task.Prompt}),
+		Stream:   stream,
+	}
+	if stream {
+		body.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return runtime.Result{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := strings.TrimRight(baseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return runtime.Result{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Cortex/1.0")
+	if key := task.Getenv(keyEnv); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	client, err := a.clients.Get(task.Proxy, task.TLS)
+	if err != nil {
+		return runtime.Result{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return runtime.Result{}, fmt.Errorf("failed to call %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return runtime.Result{
+			Stderr:   fmt.Sprintf("%s returned status %d: %s", url, resp.StatusCode, strings.TrimSpace(string(data))),
+			ExitCode: 1,
+			Success:  false,
+		}, nil
+	}
+
+	var parsed parseResult
+	if stream {
+		ui.PrintStreamStart(task.Out())
+		stripper := ui.NewMarkdownStripWriter(task.Out())
+		parsed, err = parseSSE(resp.Body, stripper)
+		_ = stripper.Flush()
+		fmt.Fprintln(task.Out())
+		ui.PrintStreamEnd(task.Out())
+	} else {
+		parsed, err = parseJSON(resp.Body)
+	}
+	if err != nil {
+		return runtime.Result{}, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return runtime.Result{
+		Stdout:       ui.StripMarkdown(parsed.Output),
+		ExitCode:     0,
+		Success:      true,
+		InputTokens:  parsed.InputTokens,
+		OutputTokens: parsed.OutputTokens,
+	}, nil
+}
+
+// Warmup opens a connection to the task's endpoint ahead of its first
+// request, so the task doesn't wait for DNS, TCP, and TLS setup. The
+// connection is left in the pool for the task to reuse. Any response will
+// do, so the request carries no API key.
+func (a *Adapter) Warmup(ctx context.Context, task runtime.Task) error {
+	baseURL := task.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	client, err := a.clients.Get(task.Proxy, task.TLS)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, baseURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Cortex/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", baseURL, err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// parseResult holds the completion text and token usage.
+type parseResult struct {
+	Output       string
+	InputTokens  int
+	OutputTokens int
+}
+
+// parseJSON reads a non-streaming chat completions response.
+func parseJSON(r io.Reader) (parseResult, error) {
+	var resp chatResponse
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return parseResult{}, err
+	}
+
+	var result parseResult
+	if len(resp.Choices) > 0 {
+		result.Output = resp.Choices[0].Message.Content
+	}
+	if resp.Usage != nil {
+		result.InputTokens = resp.Usage.PromptTokens
+		result.OutputTokens = resp.Usage.CompletionTokens
+	}
+	return result, nil
+}
+
+// parseSSE reads a server-sent events stream, writing content deltas to w as
+// they arrive and returning the accumulated output.
+func parseSSE(r io.Reader, w io.Writer) (parseResult, error) {
+	scanner := bufio.NewScanner(r)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+
+	var result parseResult
+	var output strings.Builder
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			text := chunk.Choices[0].Delta.Content
+			_, _ = w.Write([]byte(text))
+			output.WriteString(text)
+		}
+		if chunk.Usage != nil {
+			result.InputTokens = chunk.Usage.PromptTokens
+			result.OutputTokens = chunk.Usage.CompletionTokens
+		}
+	}
+
+	result.Output = output.String()
+	return result, scanner.Err()
+}
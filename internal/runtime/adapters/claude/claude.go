@@ -3,7 +3,6 @@ package claude
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,8 +10,11 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
+	"github.com/adityaraj/agentflow/internal/proc"
 	"github.com/adityaraj/agentflow/internal/runtime"
+	"github.com/adityaraj/agentflow/internal/state"
 	"github.com/adityaraj/agentflow/internal/ui"
 )
 
@@ -32,6 +34,8 @@ type Adapter struct {
 	streamLogs bool
 	// systemPrompt overrides the default system prompt
 	systemPrompt string
+	// noDefaultSystemPrompt leaves tasks without a system prompt to claude's own
+	noDefaultSystemPrompt bool
 	// workdir specifies the working directory for Claude
 	workdir string
 }
@@ -71,76 +75,70 @@ func (a *Adapter) SetSystemPrompt(prompt string) {
 	a.systemPrompt = prompt
 }
 
+// SetDefaultSystemPrompt enables or disables the default formatting rules
+// for tasks that set no system prompt (enabled by default).
+func (a *Adapter) SetDefaultSystemPrompt(enabled bool) {
+	a.noDefaultSystemPrompt = !enabled
+}
+
 // SetWorkdir sets the working directory for Claude execution.
 func (a *Adapter) SetWorkdir(dir string) {
 	a.workdir = dir
 }
 
-// Run executes a task using the claude-code CLI.
+// Run executes a task using the claude-code CLI. Output is always read as
+// stream-json, so token usage and tool calls are recorded whether or not the
+// task's output is streamed.
 func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	stream := task.Streaming(a.streamLogs)
 	args := a.buildArgs(task)
-	cmd := exec.CommandContext(ctx, a.executable, args...)
-
-	// Streaming mode: use stream-json format and parse NDJSON in real-time
-	if a.streamLogs {
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			return runtime.Result{}, fmt.Errorf("failed to create stdout pipe: %w", err)
-		}
-
-		var stderr bytes.Buffer
-		cmd.Stderr = &stderr
-
-		if err := cmd.Start(); err != nil {
-			return runtime.Result{}, fmt.Errorf("failed to start claude: %w", err)
-		}
-
-		ui.PrintStreamStart()
-
-		// Parse NDJSON and stream text content in real-time
-		parsed := a.parseAndStreamNDJSON(stdout, os.Stdout)
-
-		ui.PrintStreamEnd()
-
-		err = cmd.Wait()
+	workdir := task.Workdir
+	if workdir == "" {
+		workdir = a.workdir
+	}
+	cmd := task.Command(ctx, workdir, a.executable, args...)
+	cmd.Env = task.Environ()
+	proc.KillOnCancel(cmd, task.KillTimeout)
 
-		result := runtime.Result{
-			Stdout:       ui.StripMarkdown(parsed.Output),
-			Stderr:       stderr.String(),
-			ExitCode:     0,
-			Success:      true,
-			InputTokens:  parsed.InputTokens,
-			OutputTokens: parsed.OutputTokens,
-			CacheRead:    parsed.CacheRead,
-			CacheWrite:   parsed.CacheWrite,
-		}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return runtime.Result{}, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
 
-		if err != nil {
-			if exitErr, ok := err.(*exec.ExitError); ok {
-				result.ExitCode = exitErr.ExitCode()
-				result.Success = false
-			} else {
-				return result, fmt.Errorf("claude execution failed: %w", err)
-			}
-		}
+	out, errOut := task.Live(stream)
+	stderr := runtime.NewCappedBuffer(task.MaxOutput)
+	cmd.Stderr = io.MultiWriter(errOut, stderr)
 
-		return result, nil
+	if err := cmd.Start(); err != nil {
+		return runtime.Result{}, fmt.Errorf("failed to start claude: %w", err)
 	}
 
-	// Non-streaming mode: use buffered text output
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	// Parse NDJSON, writing text content out in real time
+	if stream {
+		ui.PrintStreamStart(out)
+	}
+	parsed := a.parseAndStreamNDJSON(stdout, out)
+	if parsed.Output != "" && !strings.HasSuffix(parsed.Output, "\n") {
+		fmt.Fprintln(out) // End the text before what follows it
+	}
+	if stream {
+		ui.PrintStreamEnd(out)
+	}
 
-	cleanStdout := ui.StripMarkdown(stdout.String())
+	err = proc.Wait(cmd)
 
 	result := runtime.Result{
-		Stdout:   cleanStdout,
-		Stderr:   stderr.String(),
-		ExitCode: 0,
-		Success:  true,
+		Stdout:       ui.StripMarkdown(parsed.Output),
+		Stderr:       stderr.String(),
+		ExitCode:     0,
+		Success:      true,
+		InputTokens:  parsed.InputTokens,
+		OutputTokens: parsed.OutputTokens,
+		CacheRead:    parsed.CacheRead,
+		CacheWrite:   parsed.CacheWrite,
+		CostUSD:      parsed.CostUSD,
+		ToolCalls:    parsed.ToolCalls,
+		Truncated:    stderr.Truncated(),
 	}
 
 	if err != nil {
@@ -148,7 +146,7 @@ func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, e
 			result.ExitCode = exitErr.ExitCode()
 			result.Success = false
 		} else {
-			return result, fmt.Errorf("failed to execute claude: %w", err)
+			return result, fmt.Errorf("claude execution failed: %w", err)
 		}
 	}
 
@@ -161,21 +159,36 @@ func (a *Adapter) buildArgs(task runtime.Task) []string {
 		"-p", // SDK/headless mode
 	}
 
-	// Use stream-json for real-time streaming, text for buffered output
+	// MCP configs and permission rules come first: their flags take several
+	// values and would swallow the prompt at the end
+	for _, file := range task.MCPServers {
+		args = append(args, "--mcp-config", file)
+	}
+	if task.Permissions != nil {
+		for _, rule := range task.Permissions.Allow {
+			args = append(args, "--allowedTools", rule)
+		}
+		for _, rule := range task.Permissions.DeniedTools() {
+			args = append(args, "--disallowedTools", rule)
+		}
+	}
+
+	// Use stream-json for real-time text and tool events
 	// Note: stream-json requires --verbose flag
 	// --include-partial-messages enables real-time character-by-character streaming
-	if a.streamLogs {
-		args = append(args, "--output-format", "stream-json", "--verbose", "--include-partial-messages")
-	} else {
-		args = append(args, "--output-format", "text")
-	}
+	args = append(args, "--output-format", "stream-json", "--verbose", "--include-partial-messages")
 
-	// Add system prompt (use default if not overridden)
-	systemPrompt := a.systemPrompt
+	// Add system prompt: the task's, the adapter's, or the default
+	systemPrompt := task.SystemPrompt
 	if systemPrompt == "" {
+		systemPrompt = a.systemPrompt
+	}
+	if systemPrompt == "" && !a.noDefaultSystemPrompt {
 		systemPrompt = defaultSystemPrompt
 	}
-	args = append(args, "--system-prompt", systemPrompt)
+	if systemPrompt != "" {
+		args = append(args, "--system-prompt", systemPrompt)
+	}
 
 	// Add working directory if specified (from task or adapter)
 	workdir := task.Workdir
@@ -191,8 +204,12 @@ func (a *Adapter) buildArgs(task runtime.Task) []string {
 		args = append(args, "--model", task.Model)
 	}
 
-	// If writes are allowed, bypass permission checks
-	if task.Write {
+	// If writes are allowed, accept edits outside the denied rules, or
+	// bypass permission checks when the task sets no permissions
+	switch {
+	case task.Write && task.Permissions != nil:
+		args = append(args, "--permission-mode", "acceptEdits")
+	case task.Write:
 		args = append(args, "--dangerously-skip-permissions")
 	}
 
@@ -224,47 +241,97 @@ type streamMessage struct {
 			PartialJSON string `json:"partial_json"`
 		} `json:"delta"`
 	} `json:"event"`
-	// For assistant messages (final complete message)
+	// For assistant messages (final complete message) and user messages
+	// carrying tool results
 	Message *struct {
 		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
+			Type      string          `json:"type"`
+			Text      string          `json:"text"`
+			ID        string          `json:"id"`          // tool_use
+			Name      string          `json:"name"`        // tool_use
+			Input     json.RawMessage `json:"input"`       // tool_use
+			ToolUseID string          `json:"tool_use_id"` // tool_result
+			IsError   bool            `json:"is_error"`    // tool_result
 		} `json:"content"`
 		Usage *usageInfo `json:"usage"`
 	} `json:"message"`
 	// Usage info at result level
 	Usage *usageInfo `json:"usage"`
+	// Total cost of the session, reported on the result message
+	TotalCostUSD float64 `json:"total_cost_usd"`
 }
 
 // usageInfo represents token usage information from Claude
 type usageInfo struct {
-	InputTokens       int `json:"input_tokens"`
-	OutputTokens      int `json:"output_tokens"`
-	CacheReadTokens   int `json:"cache_read_input_tokens"`
+	InputTokens         int `json:"input_tokens"`
+	OutputTokens        int `json:"output_tokens"`
+	CacheReadTokens     int `json:"cache_read_input_tokens"`
 	CacheCreationTokens int `json:"cache_creation_input_tokens"`
 }
 
 // toolInput represents common tool input parameters
 type toolInput struct {
-	FilePath    string `json:"file_path"`
-	Path        string `json:"path"`
-	Pattern     string `json:"pattern"`
-	Command     string `json:"command"`
-	Description string `json:"description"`
-	Prompt      string `json:"prompt"`
-	Query       string `json:"query"`
-	URL         string `json:"url"`
-	OldString   string `json:"old_string"`
-	NewString   string `json:"new_string"`
+	FilePath     string `json:"file_path"`
+	NotebookPath string `json:"notebook_path"`
+	Path         string `json:"path"`
+	Pattern      string `json:"pattern"`
+	Command      string `json:"command"`
+	Description  string `json:"description"`
+	Prompt       string `json:"prompt"`
+	Query        string `json:"query"`
+	URL          string `json:"url"`
+	OldString    string `json:"old_string"`
+	NewString    string `json:"new_string"`
 }
 
-// parseResult holds the parsed output and token usage from streaming
+// parseResult holds the parsed output, token usage, and tool calls from
+// streaming
 type parseResult struct {
 	Output       string
 	InputTokens  int
 	OutputTokens int
 	CacheRead    int
 	CacheWrite   int
+	CostUSD      float64
+	ToolCalls    []state.ToolCall
+}
+
+// toolTimeline collects the tool calls of a stream in the order they start,
+// matching results to calls by tool use ID.
+type toolTimeline struct {
+	calls []state.ToolCall
+	byID  map[string]int
+}
+
+// start records a call of tool, unless the call with id is already known.
+func (t *toolTimeline) start(id, tool string) int {
+	if i, ok := t.byID[id]; ok && id != "" {
+		return i
+	}
+	t.calls = append(t.calls, state.ToolCall{Tool: tool, StartTime: time.Now()})
+	i := len(t.calls) - 1
+	if id != "" {
+		if t.byID == nil {
+			t.byID = make(map[string]int)
+		}
+		t.byID[id] = i
+	}
+	return i
+}
+
+// target sets what call i acted on from its input JSON, if not yet known.
+func (t *toolTimeline) target(i int, inputJSON string) {
+	if i >= 0 && i < len(t.calls) && t.calls[i].Target == "" {
+		t.calls[i].Target = toolTarget(inputJSON)
+	}
+}
+
+// finish records the result of the call with id.
+func (t *toolTimeline) finish(id string, isError bool) {
+	if i, ok := t.byID[id]; ok {
+		t.calls[i].EndTime = time.Now()
+		t.calls[i].Error = isError
+	}
 }
 
 // parseAndStreamNDJSON reads NDJSON from reader, streams text content to writer,
@@ -280,6 +347,8 @@ func (a *Adapter) parseAndStreamNDJSON(r io.Reader, w io.Writer) parseResult {
 	var currentTool string
 	var toolInputJSON strings.Builder
 	var toolDisplayed bool
+	var timeline toolTimeline
+	currentCall := -1
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -315,6 +384,7 @@ func (a *Adapter) parseAndStreamNDJSON(r io.Reader, w io.Writer) parseResult {
 			if msg.Event.Type == "content_block_start" && msg.Event.ContentBlock != nil {
 				if msg.Event.ContentBlock.Type == "tool_use" {
 					currentTool = msg.Event.ContentBlock.Name
+					currentCall = timeline.start(msg.Event.ContentBlock.ID, currentTool)
 					toolInputJSON.Reset()
 					toolDisplayed = false
 				}
@@ -356,11 +426,29 @@ func (a *Adapter) parseAndStreamNDJSON(r io.Reader, w io.Writer) parseResult {
 					toolMsg := fmt.Sprintf("\n%s  ⚡ %s%s %s%s%s\n", ui.Orange, currentTool, ui.Reset, ui.Dim, info, ui.Reset)
 					_, _ = w.Write([]byte(toolMsg))
 				}
+				timeline.target(currentCall, toolInputJSON.String())
 				currentTool = ""
+				currentCall = -1
 				toolDisplayed = false
 			}
 		}
 
+		// Complete tool calls of assistant messages, and their results
+		if msg.Message != nil {
+			for _, block := range msg.Message.Content {
+				switch block.Type {
+				case "tool_use":
+					timeline.target(timeline.start(block.ID, block.Name), string(block.Input))
+				case "tool_result":
+					timeline.finish(block.ToolUseID, block.IsError)
+				}
+			}
+		}
+
+		if msg.Type == "result" && msg.TotalCostUSD > 0 {
+			result.CostUSD = msg.TotalCostUSD
+		}
+
 		// Handle final result (fallback if no streaming events received)
 		if msg.Type == "result" && msg.Result != "" {
 			// Only use result if we haven't accumulated content from stream events
@@ -372,9 +460,25 @@ func (a *Adapter) parseAndStreamNDJSON(r io.Reader, w io.Writer) parseResult {
 	}
 
 	result.Output = fullOutput.String()
+	result.ToolCalls = timeline.calls
 	return result
 }
 
+// toolTarget returns what a tool call acted on, in full: its file, command,
+// URL, query, pattern, or path.
+func toolTarget(inputJSON string) string {
+	var input toolInput
+	if err := json.Unmarshal([]byte(inputJSON), &input); err != nil {
+		return ""
+	}
+	for _, target := range []string{input.FilePath, input.NotebookPath, input.Command, input.URL, input.Query, input.Pattern, input.Path, input.Description} {
+		if target != "" {
+			return target
+		}
+	}
+	return ""
+}
+
 // extractToolInfo extracts display info from tool input JSON
 func extractToolInfo(toolName, jsonStr string) string {
 	var input toolInput
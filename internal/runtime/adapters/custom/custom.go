@@ -0,0 +1,242 @@
+// Package custom implements the Agent interface for CLI tools declared
+// under tools: in the global config.
+package custom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/proc"
+	"github.com/adityaraj/agentflow/internal/runtime"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// Adapter runs a declared tool's CLI with the task's prompt and reads its
+// result from the output format the tool declares.
+type Adapter struct {
+	// name is the tool's name under tools:
+	name string
+	// tool is the declaration of the tool
+	tool config.ToolConfig
+	// streamLogs enables real-time output streaming
+	streamLogs bool
+	// workdir specifies the working directory for execution
+	workdir string
+}
+
+// New creates an adapter for the tool declared as name.
+func New(name string, tool config.ToolConfig) *Adapter {
+	return &Adapter{
+		name: name,
+		tool: tool,
+	}
+}
+
+// SetStreamLogs enables or disables real-time log streaming.
+func (a *Adapter) SetStreamLogs(enabled bool) {
+	a.streamLogs = enabled
+}
+
+// SetWorkdir sets the working directory for execution.
+func (a *Adapter) SetWorkdir(dir string) {
+	a.workdir = dir
+}
+
+// Run executes a task with the tool's CLI, started in the task or adapter
+// workdir. Streamed output shows the text the tool's output format yields:
+// all of stdout for text, the field of each line for ndjson, and nothing
+// until the end for json-field.
+func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	stream := task.Streaming(a.streamLogs)
+
+	workdir := task.Workdir
+	if workdir == "" {
+		workdir = a.workdir
+	}
+	args, promptInArgs := a.buildArgs(task, workdir)
+
+	cmd := task.Command(ctx, workdir, a.tool.Executable, args...)
+	cmd.Env = task.Environ()
+	proc.KillOnCancel(cmd, task.KillTimeout)
+	if !promptInArgs {
+		cmd.Stdin = strings.NewReader(task.Prompt)
+	}
+
+	// Stdout is capped by the executor, after its output format is parsed
+	var stdout bytes.Buffer
+	stderr := runtime.NewCappedBuffer(task.MaxOutput)
+
+	out, errOut := task.Live(stream)
+	if stream {
+		ui.PrintStreamStart(out)
+	}
+	cmd.Stdout = io.MultiWriter(a.liveOutput(out), &stdout)
+	cmd.Stderr = io.MultiWriter(errOut, stderr)
+
+	err := proc.Run(cmd)
+
+	if stream {
+		ui.PrintStreamEnd(task.Out())
+	}
+
+	result := runtime.Result{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		ExitCode:  0,
+		Success:   true,
+		Truncated: stderr.Truncated(),
+	}
+
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+			result.Success = false
+			return result, nil
+		}
+		return result, fmt.Errorf("failed to execute %s: %w", a.name, err)
+	}
+
+	if err := a.parseOutput(stdout.Bytes(), &result); err != nil {
+		result.Success = false
+		return result, err
+	}
+	return result, nil
+}
+
+// buildArgs fills in the tool's argument templates, adding model_args when
+// the agent sets a model, system_prompt_args when the task has a system
+// prompt, and write_args for write-enabled tasks. It also reports whether an
+// argument carries the prompt.
+func (a *Adapter) buildArgs(task runtime.Task, workdir string) (args []string, promptInArgs bool) {
+	r := strings.NewReplacer("{{prompt}}", task.Prompt, "{{model}}", task.Model, "{{system_prompt}}", task.SystemPrompt, "{{workdir}}", workdir)
+	add := func(templates []string) {
+		for _, t := range templates {
+			if strings.Contains(t, "{{prompt}}") {
+				promptInArgs = true
+			}
+			args = append(args, r.Replace(t))
+		}
+	}
+
+	add(a.tool.Args)
+	if task.Model != "" {
+		add(a.tool.ModelArgs)
+	}
+	if task.SystemPrompt != "" {
+		add(a.tool.SystemPromptArgs)
+	}
+	if task.Write {
+		add(a.tool.WriteArgs)
+	}
+	return args, promptInArgs
+}
+
+// liveOutput returns the writer that streamed stdout goes through.
+func (a *Adapter) liveOutput(w io.Writer) io.Writer {
+	switch a.tool.Output {
+	case config.ToolOutputNDJSON:
+		return &ndjsonWriter{w: w, field: a.tool.Field}
+	case config.ToolOutputJSONField:
+		return io.Discard
+	default:
+		return w
+	}
+}
+
+// parseOutput replaces the result's stdout with the text the tool's output
+// format yields, and reads the token counts if the tool declares them.
+func (a *Adapter) parseOutput(stdout []byte, result *runtime.Result) error {
+	switch a.tool.Output {
+	case config.ToolOutputJSONField:
+		var doc any
+		if err := json.Unmarshal(stdout, &doc); err != nil {
+			return fmt.Errorf("%s output is not JSON: %w", a.name, err)
+		}
+		text, ok := config.PayloadValue(doc, a.tool.Field)
+		if !ok {
+			return fmt.Errorf("%s output has no field %q", a.name, a.tool.Field)
+		}
+		result.Stdout = text
+		a.readTokens(doc, result)
+
+	case config.ToolOutputNDJSON:
+		var texts []string
+		for _, line := range bytes.Split(stdout, []byte("\n")) {
+			var doc any
+			if json.Unmarshal(line, &doc) != nil {
+				continue // Blank lines and log noise between events
+			}
+			if text, ok := config.PayloadValue(doc, a.tool.Field); ok && text != "" {
+				texts = append(texts, text)
+			}
+			a.readTokens(doc, result)
+		}
+		result.Stdout = strings.Join(texts, "\n")
+	}
+	return nil
+}
+
+// readTokens sets the token counts found in doc. In ndjson output the last
+// line that has them wins, as tools report totals at the end.
+func (a *Adapter) readTokens(doc any, result *runtime.Result) {
+	if n, ok := intField(doc, a.tool.InputTokensField); ok {
+		result.InputTokens = n
+	}
+	if n, ok := intField(doc, a.tool.OutputTokensField); ok {
+		result.OutputTokens = n
+	}
+}
+
+// intField reads a whole number at a dotted path of doc.
+func intField(doc any, field string) (int, bool) {
+	if field == "" {
+		return 0, false
+	}
+	value, ok := config.PayloadValue(doc, field)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	return n, err == nil
+}
+
+// ndjsonWriter writes the field of each complete JSON line written to it.
+type ndjsonWriter struct {
+	w     io.Writer
+	field string
+	buf   []byte
+}
+
+// Write implements io.Writer.
+func (n *ndjsonWriter) Write(p []byte) (int, error) {
+	n.buf = append(n.buf, p...)
+	for {
+		i := bytes.IndexByte(n.buf, '\n')
+		if i < 0 {
+			break
+		}
+		var doc any
+		if json.Unmarshal(n.buf[:i], &doc) == nil {
+			if text, ok := config.PayloadValue(doc, n.field); ok && text != "" {
+				fmt.Fprintln(n.w, text)
+			}
+		}
+		n.buf = n.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Check verifies that the tool's CLI is available.
+func (a *Adapter) Check() error {
+	if _, err := exec.LookPath(a.tool.Executable); err != nil {
+		return fmt.Errorf("%s CLI not found or not executable: %w", a.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+// Package git implements the Agent interface for built-in git tasks.
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/proc"
+	"github.com/adityaraj/agentflow/internal/runtime"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// DefaultRemote is pushed to when a push task does not set a remote.
+const DefaultRemote = "origin"
+
+// Adapter implements the Agent interface by running git subcommands.
+type Adapter struct {
+	// executable is the git binary (default: git)
+	executable string
+	// streamLogs enables real-time output streaming
+	streamLogs bool
+	// workdir specifies the working directory for git commands
+	workdir string
+}
+
+// New creates a new git adapter.
+func New() *Adapter {
+	return &Adapter{
+		executable: "git",
+		streamLogs: false,
+	}
+}
+
+// SetStreamLogs enables or disables real-time log streaming.
+func (a *Adapter) SetStreamLogs(enabled bool) {
+	a.streamLogs = enabled
+}
+
+// SetWorkdir sets the working directory for git commands.
+func (a *Adapter) SetWorkdir(dir string) {
+	a.workdir = dir
+}
+
+// Run performs the operation in task.Git. Git's own output goes to stderr;
+// stdout is a short value downstream tasks can use: the clone directory,
+// the branch name, the new commit SHA, or the pushed remote and branch.
+func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	stream := task.Streaming(a.streamLogs)
+	if task.Git == nil {
+		return runtime.Result{}, fmt.Errorf("no operation specified for git task")
+	}
+
+	// Fields filled from task outputs usually end in a newline
+	op := *task.Git
+	op.Repo = strings.TrimSpace(op.Repo)
+	op.Dir = strings.TrimSpace(op.Dir)
+	op.Branch = strings.TrimSpace(op.Branch)
+	op.Remote = strings.TrimSpace(op.Remote)
+
+	workdir := task.Workdir
+	if workdir == "" {
+		workdir = a.workdir
+	}
+	repoDir := workdir
+	if op.Dir != "" && op.Op != config.GitClone {
+		repoDir = resolve(workdir, op.Dir)
+	}
+
+	// Never block on a credential prompt in an unattended run
+	task.Env = append(slices.Clip(task.Env), "GIT_TERMINAL_PROMPT=0")
+
+	out, errOut := task.Live(stream)
+	r := &runner{adapter: a, task: task.Name, ctx: ctx, timeout: task.KillTimeout, env: task.Environ(), stream: stream, out: out, errOut: errOut}
+
+	if stream {
+		ui.PrintStreamStart(task.Out())
+	}
+
+	var output string
+	err := r.checkNames(repoDir, &op)
+	switch {
+	case err != nil:
+	case op.Op == config.GitClone:
+		output, err = r.clone(workdir, &op)
+	case op.Op == config.GitCheckout:
+		// "--" keeps a branch named like a file from being read as a path
+		output, err = op.Branch, r.run(repoDir, "checkout", op.Branch, "--")
+	case op.Op == config.GitCreateBranch:
+		// -b refuses to reset a branch that already exists
+		output, err = op.Branch, r.run(repoDir, "checkout", "-b", op.Branch, "--")
+	case op.Op == config.GitCommit:
+		output, err = r.commit(repoDir, &op)
+	case op.Op == config.GitPush:
+		output, err = r.push(repoDir, &op)
+	default:
+		err = fmt.Errorf("unsupported git op %q", op.Op)
+	}
+
+	if stream {
+		ui.PrintStreamEnd(task.Out())
+	}
+
+	result := runtime.Result{
+		Stdout:   output,
+		Stderr:   r.log.String(),
+		ExitCode: 0,
+		Success:  true,
+	}
+
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.Stdout = ""
+			result.ExitCode = exitErr.ExitCode()
+			result.Success = false
+			return result, nil
+		}
+		return result, err
+	}
+
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		result.Stdout += "\n"
+	}
+	return result, nil
+}
+
+// runner runs git subcommands for one task, collecting their combined output.
+type runner struct {
+	adapter *Adapter
+	task    string
+	ctx     context.Context
+	timeout time.Duration // Kill timeout of the task's processes
+	env     []string
+	stream  bool
+	out     io.Writer
+	errOut  io.Writer
+	log     bytes.Buffer
+}
+
+// checkNames rejects a branch or remote, which may come from a template,
+// that git could read as an option, and a branch that is not a valid branch
+// name.
+func (r *runner) checkNames(dir string, op *config.GitConfig) error {
+	if strings.HasPrefix(op.Remote, "-") {
+		return fmt.Errorf("invalid remote %q: must not start with '-'", op.Remote)
+	}
+	if op.Branch == "" {
+		return nil
+	}
+	if strings.HasPrefix(op.Branch, "-") {
+		return fmt.Errorf("invalid branch %q: must not start with '-'", op.Branch)
+	}
+	if op.Op == config.GitClone {
+		dir = "" // The clone target does not exist yet
+	}
+	if _, err := r.output(dir, "check-ref-format", "--branch", op.Branch); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("invalid branch name %q", op.Branch)
+		}
+		return err
+	}
+	return nil
+}
+
+// run executes a git subcommand in dir, logging its output.
+func (r *runner) run(dir string, args ...string) error {
+	_, err := r.exec(dir, false, args...)
+	return err
+}
+
+// output executes a git subcommand in dir and returns its trimmed stdout.
+func (r *runner) output(dir string, args ...string) (string, error) {
+	return r.exec(dir, true, args...)
+}
+
+func (r *runner) exec(dir string, capture bool, args ...string) (string, error) {
+	cmd := exec.CommandContext(r.ctx, r.adapter.executable, args...)
+	cmd.Dir = dir
+	cmd.Env = r.env
+	proc.KillOnCancel(cmd, r.timeout)
+
+	var stdout bytes.Buffer
+	logOut := io.MultiWriter(r.errOut, &r.log)
+	if r.stream {
+		fmt.Fprintf(r.out, "%s  $ git %s%s\n", ui.Dim, strings.Join(args, " "), ui.Reset)
+	} else {
+		fmt.Fprintf(r.out, "$ git %s\n", strings.Join(args, " "))
+	}
+	fmt.Fprintf(&r.log, "$ git %s\n", strings.Join(args, " "))
+	slog.Debug("running git", "task", r.task, "args", args, "dir", dir)
+
+	cmd.Stderr = logOut
+	if capture {
+		cmd.Stdout = &stdout
+	} else {
+		cmd.Stdout = logOut
+	}
+
+	if err := proc.Run(cmd); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to execute git: %w", err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// clone clones op.Repo and returns the directory it was cloned into.
+func (r *runner) clone(workdir string, op *config.GitConfig) (string, error) {
+	args := []string{"clone"}
+	if op.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(op.Depth))
+	}
+	if op.Branch != "" {
+		args = append(args, "--branch", op.Branch)
+	}
+
+	dir := op.Dir
+	if dir == "" {
+		dir = strings.TrimSuffix(filepath.Base(strings.TrimRight(op.Repo, "/")), ".git")
+	}
+	args = append(args, "--", op.Repo, dir)
+
+	if err := r.run(workdir, args...); err != nil {
+		return "", err
+	}
+	return resolve(workdir, dir), nil
+}
+
+// commit stages changes and commits them, returning the new commit SHA.
+// An empty stage is not an error: the task succeeds with empty output.
+func (r *runner) commit(dir string, op *config.GitConfig) (string, error) {
+	args := []string{"add", "-A"}
+	if len(op.Paths) > 0 {
+		args = append([]string{"add", "--"}, op.Paths...)
+	}
+	if err := r.run(dir, args...); err != nil {
+		return "", err
+	}
+
+	// diff --quiet exits 1 when there are staged changes
+	if err := r.run(dir, "diff", "--cached", "--quiet"); err == nil {
+		fmt.Fprintln(&r.log, "nothing to commit")
+		return "", nil
+	} else if _, ok := err.(*exec.ExitError); !ok {
+		return "", err
+	}
+
+	if err := r.run(dir, "commit", "-m", op.Message); err != nil {
+		return "", err
+	}
+	return r.output(dir, "rev-parse", "HEAD")
+}
+
+// push pushes op.Branch (or the current branch) and returns "remote/branch".
+func (r *runner) push(dir string, op *config.GitConfig) (string, error) {
+	remote := op.Remote
+	if remote == "" {
+		remote = DefaultRemote
+	}
+
+	branch := op.Branch
+	if branch == "" {
+		current, err := r.output(dir, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return "", err
+		}
+		if current == "HEAD" {
+			return "", fmt.Errorf("cannot push: HEAD is detached and no branch is set")
+		}
+		branch = current
+	}
+
+	args := []string{"push"}
+	if op.Force {
+		args = append(args, "--force-with-lease")
+	}
+	// A full ref cannot be mistaken for a tag or another refspec
+	args = append(args, remote, "refs/heads/"+branch)
+
+	if err := r.run(dir, args...); err != nil {
+		return "", err
+	}
+	return remote + "/" + branch, nil
+}
+
+// resolve joins a relative path onto the working directory.
+func resolve(workdir, path string) string {
+	if filepath.IsAbs(path) || workdir == "" {
+		return path
+	}
+	return filepath.Join(workdir, path)
+}
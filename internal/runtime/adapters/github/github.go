@@ -0,0 +1,265 @@
+// Package github implements the Agent interface for built-in pull_request
+// tasks, which open GitHub pull requests.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/git"
+	"github.com/adityaraj/agentflow/internal/runtime"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// DefaultAPIURL is the API endpoint of github.com.
+const DefaultAPIURL = "https://api.github.com"
+
+// requestTimeout bounds each request to the GitHub API.
+const requestTimeout = 30 * time.Second
+
+// remoteRegex matches the owner and name of a repository in a remote URL,
+// such as https://github.com/owner/name.git or git@github.com:owner/name.
+var remoteRegex = regexp.MustCompile(`[/:]([A-Za-z0-9_.-]+)/([A-Za-z0-9_.-]+?)(?:\.git)?/?$`)
+
+// Adapter implements the Agent interface by calling the GitHub REST API.
+type Adapter struct {
+	// clients pools connections to the API
+	clients *runtime.HTTPClients
+	// streamLogs enables printing the requests and the pull request
+	streamLogs bool
+}
+
+// New creates a new pull_request task adapter.
+func New() *Adapter {
+	return &Adapter{
+		clients:    runtime.NewHTTPClients(),
+		streamLogs: false,
+	}
+}
+
+// SetStreamLogs enables or disables real-time log streaming.
+func (a *Adapter) SetStreamLogs(enabled bool) {
+	a.streamLogs = enabled
+}
+
+// Run opens the pull request in task.PullRequest, with task.Prompt as its
+// body. Its URL becomes stdout. If the head branch already has an open pull
+// request, that one's URL is returned instead, so re-runs don't fail.
+func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	stream := task.Streaming(a.streamLogs)
+	spec := task.PullRequest
+	if spec == nil {
+		return runtime.Result{}, fmt.Errorf("no pull request specified for pull_request task")
+	}
+
+	tokenEnv := spec.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = config.DefaultGitHubTokenEnv
+	}
+	c := &client{
+		api:   strings.TrimRight(spec.APIURL, "/"),
+		token: task.Getenv(tokenEnv),
+	}
+	if c.api == "" {
+		c.api = DefaultAPIURL
+	}
+	if c.token == "" {
+		return runtime.Result{}, fmt.Errorf("no GitHub token: %s is not set", tokenEnv)
+	}
+	var err error
+	if c.http, err = a.clients.Get("", nil); err != nil {
+		return runtime.Result{}, err
+	}
+
+	// The repository and branch default to those of the task's workdir
+	workdir := task.Workdir
+	repo := strings.TrimSpace(spec.Repo)
+	if repo == "" {
+		if repo, err = originRepo(ctx, workdir); err != nil {
+			return runtime.Result{}, err
+		}
+	}
+	head := strings.TrimSpace(spec.Head)
+	if head == "" {
+		if head, err = currentBranch(ctx, workdir); err != nil {
+			return runtime.Result{}, err
+		}
+	}
+	base := strings.TrimSpace(spec.Base)
+	if base == "" {
+		var info struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/repos/"+repo, nil, &info); err != nil {
+			return runtime.Result{}, err
+		}
+		base = info.DefaultBranch
+	}
+
+	if stream {
+		ui.PrintStreamStart(task.Out())
+		fmt.Fprintf(task.Out(), "%s  → %s: %s into %s%s\n", ui.Dim, repo, head, base, ui.Reset)
+	}
+	slog.Debug("opening pull request", "task", task.Name, "repo", repo, "head", head, "base", base)
+
+	var pr struct {
+		HTMLURL string `json:"html_url"`
+	}
+	err = c.do(ctx, http.MethodPost, "/repos/"+repo+"/pulls", map[string]any{
+		"title": strings.TrimSpace(spec.Title),
+		"body":  strings.TrimSpace(task.Prompt),
+		"head":  head,
+		"base":  base,
+		"draft": spec.Draft,
+	}, &pr)
+	if apiErr, ok := err.(*apiError); ok && apiErr.status == http.StatusUnprocessableEntity && strings.Contains(apiErr.message, "already exists") {
+		pr.HTMLURL, err = c.openPullRequest(ctx, repo, head)
+	}
+	if stream {
+		if err == nil {
+			fmt.Fprintf(task.Out(), "%s  ← %s%s\n", ui.Dim, pr.HTMLURL, ui.Reset)
+		}
+		ui.PrintStreamEnd(task.Out())
+	}
+	if err != nil {
+		return runtime.Result{Stderr: err.Error() + "\n", ExitCode: 1}, nil
+	}
+
+	return runtime.Result{
+		Stdout:   pr.HTMLURL + "\n",
+		ExitCode: 0,
+		Success:  true,
+	}, nil
+}
+
+// client calls the GitHub REST API with a token.
+type client struct {
+	http  *http.Client
+	api   string
+	token string
+}
+
+// apiError is a response of the GitHub API with an error status.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("GitHub API: %d %s", e.status, e.message)
+}
+
+// do sends a request with a JSON body, if any, and decodes the JSON
+// response into out.
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.api+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, c.api+path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Validation errors carry the details in errors[].message
+		var e struct {
+			Message string `json:"message"`
+			Errors  []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		_ = json.Unmarshal(data, &e)
+		message := e.Message
+		for _, detail := range e.Errors {
+			if detail.Message != "" {
+				message += ": " + detail.Message
+			}
+		}
+		if message == "" {
+			message = http.StatusText(resp.StatusCode)
+		}
+		return &apiError{status: resp.StatusCode, message: message}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("unexpected GitHub API response: %w", err)
+	}
+	return nil
+}
+
+// openPullRequest returns the URL of the open pull request from head.
+func (c *client) openPullRequest(ctx context.Context, repo, head string) (string, error) {
+	owner, _, _ := strings.Cut(repo, "/")
+	if !strings.Contains(head, ":") {
+		head = owner + ":" + head
+	}
+	var prs []struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/repos/"+repo+"/pulls?state=open&head="+url.QueryEscape(head), nil, &prs); err != nil {
+		return "", err
+	}
+	if len(prs) == 0 {
+		return "", fmt.Errorf("a pull request from %s already exists but is not open", head)
+	}
+	return prs[0].HTMLURL, nil
+}
+
+// originRepo returns the owner/name of the repository the origin remote of
+// the repository at dir points to.
+func originRepo(ctx context.Context, dir string) (string, error) {
+	remote, err := git.Output(ctx, dir, "remote", "get-url", "origin")
+	if err != nil {
+		return "", fmt.Errorf("failed to find the repository, set 'repo': %w", err)
+	}
+	match := remoteRegex.FindStringSubmatch(remote)
+	if match == nil {
+		return "", fmt.Errorf("failed to find the repository in remote %q, set 'repo'", remote)
+	}
+	return match[1] + "/" + match[2], nil
+}
+
+// currentBranch returns the branch checked out in the repository at dir.
+func currentBranch(ctx context.Context, dir string) (string, error) {
+	branch, err := git.Output(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to find the head branch, set 'head': %w", err)
+	}
+	if branch == "HEAD" {
+		return "", fmt.Errorf("HEAD is detached, set 'head'")
+	}
+	return branch, nil
+}
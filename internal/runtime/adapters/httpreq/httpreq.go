@@ -0,0 +1,161 @@
+// Package httpreq implements the Agent interface for built-in http tasks.
+package httpreq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/runtime"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// DefaultTimeout is used when an http task does not set a timeout.
+const DefaultTimeout = 30 * time.Second
+
+// maxResponseBytes caps how much of a response body is kept as task output.
+const maxResponseBytes = 10 << 20
+
+// envRefRegex matches ${NAME} references in the URL, proxy, and header values.
+var envRefRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Adapter implements the Agent interface by sending a single HTTP request.
+type Adapter struct {
+	// clients pools connections per proxy and TLS settings
+	clients *runtime.HTTPClients
+	// streamLogs enables printing the request and response status
+	streamLogs bool
+}
+
+// New creates a new http task adapter.
+// Timeouts are applied per request from the task configuration.
+func New() *Adapter {
+	return &Adapter{
+		clients:    runtime.NewHTTPClients(),
+		streamLogs: false,
+	}
+}
+
+// SetStreamLogs enables or disables real-time log streaming.
+func (a *Adapter) SetStreamLogs(enabled bool) {
+	a.streamLogs = enabled
+}
+
+// Run sends the request in task.HTTP with task.Prompt as the body.
+// The response body becomes stdout; a status other than expect_status
+// (or any non-2xx status by default) fails the task.
+func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	stream := task.Streaming(a.streamLogs)
+	spec := task.HTTP
+	if spec == nil {
+		return runtime.Result{}, fmt.Errorf("no request specified for http task")
+	}
+
+	timeout := DefaultTimeout
+	if spec.Timeout != "" {
+		d, err := time.ParseDuration(spec.Timeout)
+		if err != nil {
+			return runtime.Result{}, fmt.Errorf("invalid http timeout %q: %w", spec.Timeout, err)
+		}
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	method := strings.ToUpper(spec.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if task.Prompt != "" {
+		body = strings.NewReader(task.Prompt)
+	}
+
+	client, err := a.clients.Get(expandEnvRefs(spec.Proxy, task), spec.TLS)
+	if err != nil {
+		return runtime.Result{}, err
+	}
+
+	target := expandEnvRefs(spec.URL, task)
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return runtime.Result{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range spec.Headers {
+		req.Header.Set(key, expandEnvRefs(value, task))
+	}
+	if task.Prompt != "" && req.Header.Get("Content-Type") == "" && json.Valid([]byte(task.Prompt)) {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	// Logs and errors show the URL as written, since ${NAME} references may
+	// expand to secrets
+	slog.Debug("sending request", "task", task.Name, "method", method, "url", spec.URL)
+	if stream {
+		ui.PrintStreamStart(task.Out())
+		fmt.Fprintf(task.Out(), "%s  → %s %s%s\n", ui.Dim, method, spec.URL, ui.Reset)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if stream {
+			ui.PrintStreamEnd(task.Out())
+		}
+		// The client's error repeats the expanded URL
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return runtime.Result{}, fmt.Errorf("%s %s: %w", method, spec.URL, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	slog.Debug("received response", "task", task.Name, "status", resp.StatusCode, "bytes", len(data))
+	if stream {
+		fmt.Fprintf(task.Out(), "%s  ← %s (%d bytes)%s\n", ui.Dim, resp.Status, len(data), ui.Reset)
+		ui.PrintStreamEnd(task.Out())
+	}
+	if err != nil {
+		return runtime.Result{}, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	result := runtime.Result{
+		Stdout:   string(data),
+		ExitCode: 0,
+		Success:  true,
+	}
+
+	ok := resp.StatusCode >= 200 && resp.StatusCode < 300
+	if spec.ExpectStatus != 0 {
+		ok = resp.StatusCode == spec.ExpectStatus
+	}
+	if !ok {
+		result.ExitCode = 1
+		result.Success = false
+		if spec.ExpectStatus != 0 {
+			result.Stderr = fmt.Sprintf("%s %s: got %s, expected %d", method, spec.URL, resp.Status, spec.ExpectStatus)
+		} else {
+			result.Stderr = fmt.Sprintf("%s %s: got %s", method, spec.URL, resp.Status)
+		}
+	}
+
+	return result, nil
+}
+
+// expandEnvRefs replaces ${NAME} with the value from the task environment,
+// so secrets can be used in URLs and headers without templating them.
+func expandEnvRefs(text string, task runtime.Task) string {
+	return envRefRegex.ReplaceAllStringFunc(text, func(ref string) string {
+		return task.Getenv(envRefRegex.FindStringSubmatch(ref)[1])
+	})
+}
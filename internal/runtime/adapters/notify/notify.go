@@ -0,0 +1,89 @@
+// Package notify implements the Agent interface for built-in notify tasks.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/adityaraj/agentflow/internal/runtime"
+	"github.com/adityaraj/agentflow/internal/ui"
+	"github.com/adityaraj/agentflow/internal/webhook"
+)
+
+// DefaultLevel is used when a notify task does not set a level.
+const DefaultLevel = "info"
+
+// Adapter implements the Agent interface by sending notify events to the
+// configured webhooks.
+type Adapter struct {
+	// webhooks delivers the events
+	webhooks *webhook.Manager
+	// runID and project identify the run in event payloads
+	runID   string
+	project string
+	// streamLogs enables printing the message
+	streamLogs bool
+}
+
+// New creates a new notify adapter that sends through webhooks.
+func New(webhooks *webhook.Manager, runID, project string) *Adapter {
+	return &Adapter{
+		webhooks:   webhooks,
+		runID:      runID,
+		project:    project,
+		streamLogs: false,
+	}
+}
+
+// SetStreamLogs enables or disables real-time log streaming.
+func (a *Adapter) SetStreamLogs(enabled bool) {
+	a.streamLogs = enabled
+}
+
+// Run sends the message in task.Prompt and waits for delivery. The message
+// becomes the task output. Notifications are best-effort: delivery errors
+// are recorded in stderr but do not fail the task.
+func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	stream := task.Streaming(a.streamLogs)
+	message := strings.TrimSpace(task.Prompt)
+	if message == "" {
+		return runtime.Result{}, fmt.Errorf("no message specified for notify task")
+	}
+
+	level := DefaultLevel
+	if task.Notify != nil && task.Notify.Level != "" {
+		level = task.Notify.Level
+	}
+
+	if stream {
+		ui.PrintStreamStart(task.Out())
+		fmt.Fprintf(task.Out(), "%s  [%s]%s %s\n", ui.Dim, level, ui.Reset, message)
+		ui.PrintStreamEnd(task.Out())
+	}
+
+	result := runtime.Result{
+		Stdout:   message + "\n",
+		ExitCode: 0,
+		Success:  true,
+	}
+
+	if a.webhooks == nil || !a.webhooks.HasWebhooks() {
+		result.Stderr = "no webhooks configured; message not sent\n"
+		return result, nil
+	}
+
+	event := webhook.NewNotifyEvent(a.runID, a.project, webhook.NotifyEvent{
+		Task:    task.Name,
+		Level:   level,
+		Message: message,
+	})
+	if err := a.webhooks.SendSync(event); err != nil {
+		result.Stderr = fmt.Sprintf("notification not delivered: %s\n", err)
+		ui.Warning("Notification from %s not delivered: %s", task.Name, err)
+		slog.Warn("notification not delivered", "task", task.Name, "error", err)
+	}
+
+	return result, nil
+}
@@ -2,13 +2,12 @@
 package opencode
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
-	"os"
 	"os/exec"
 
+	"github.com/adityaraj/agentflow/internal/proc"
 	"github.com/adityaraj/agentflow/internal/runtime"
 	"github.com/adityaraj/agentflow/internal/ui"
 )
@@ -52,53 +51,55 @@ func (a *Adapter) SetWorkdir(dir string) {
 
 // Run executes a task using the opencode CLI.
 func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	stream := task.Streaming(a.streamLogs)
 	args := a.buildArgs(task)
 
-	cmd := exec.CommandContext(ctx, a.executable, args...)
-
 	// Set working directory if specified
 	workdir := task.Workdir
 	if workdir == "" {
 		workdir = a.workdir
 	}
-	if workdir != "" {
-		cmd.Dir = workdir
-	}
+	cmd := task.Command(ctx, workdir, a.executable, args...)
+	cmd.Env = task.Environ()
+	proc.KillOnCancel(cmd, task.KillTimeout)
 
-	var stdout, stderr bytes.Buffer
+	stdout := runtime.NewCappedBuffer(task.MaxOutput)
+	stderr := runtime.NewCappedBuffer(task.MaxOutput)
 	var stripper *ui.MarkdownStripWriter
 
-	if a.streamLogs {
+	if stream {
 		// Print visual separator before streaming
-		ui.PrintStreamStart()
+		ui.PrintStreamStart(task.Out())
 		// Use MarkdownStripWriter to strip markdown in real-time as output streams
-		stripper = ui.NewMarkdownStripWriter(os.Stdout)
-		cmd.Stdout = io.MultiWriter(stripper, &stdout)
-		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+		stripper = ui.NewMarkdownStripWriter(task.Out())
+		cmd.Stdout = io.MultiWriter(stripper, stdout)
+		cmd.Stderr = io.MultiWriter(task.ErrOut(), stderr)
 	} else {
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
+		log, _ := task.Live(false)
+		cmd.Stdout = io.MultiWriter(log, stdout)
+		cmd.Stderr = io.MultiWriter(log, stderr)
 	}
 
-	err := cmd.Run()
+	err := proc.Run(cmd)
 
-	if a.streamLogs {
+	if stream {
 		// Flush any remaining buffered content
 		if stripper != nil {
 			stripper.Flush()
 		}
 		// Print visual separator after streaming
-		ui.PrintStreamEnd()
+		ui.PrintStreamEnd(task.Out())
 	}
 
 	// Strip markdown from stored output as well
 	cleanStdout := ui.StripMarkdown(stdout.String())
 
 	result := runtime.Result{
-		Stdout:   cleanStdout,
-		Stderr:   stderr.String(),
-		ExitCode: 0,
-		Success:  true,
+		Stdout:    cleanStdout,
+		Stderr:    stderr.String(),
+		ExitCode:  0,
+		Success:   true,
+		Truncated: stdout.Truncated() || stderr.Truncated(),
 	}
 
 	if err != nil {
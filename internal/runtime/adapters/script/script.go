@@ -0,0 +1,155 @@
+// Package script implements the Agent interface for inline Python and Node.js scripts.
+package script
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+
+	"github.com/adityaraj/agentflow/internal/proc"
+	"github.com/adityaraj/agentflow/internal/runtime"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// interpreter describes how to run a script language.
+type interpreter struct {
+	executable string // Interpreter binary
+	extension  string // Temp file extension
+}
+
+// defaultInterpreters maps script languages to their interpreters.
+var defaultInterpreters = map[string]interpreter{
+	"python": {executable: "python3", extension: ".py"},
+	"node":   {executable: "node", extension: ".js"},
+}
+
+// Adapter implements the Agent interface for inline scripts.
+type Adapter struct {
+	// interpreters maps script languages to interpreter settings
+	interpreters map[string]interpreter
+	// streamLogs enables real-time output streaming
+	streamLogs bool
+	// workdir specifies the working directory for scripts
+	workdir string
+}
+
+// New creates a new script adapter with default interpreters.
+func New() *Adapter {
+	interpreters := make(map[string]interpreter, len(defaultInterpreters))
+	for lang, interp := range defaultInterpreters {
+		interpreters[lang] = interp
+	}
+	return &Adapter{
+		interpreters: interpreters,
+		streamLogs:   false,
+	}
+}
+
+// SetStreamLogs enables or disables real-time log streaming.
+func (a *Adapter) SetStreamLogs(enabled bool) {
+	a.streamLogs = enabled
+}
+
+// SetWorkdir sets the working directory for script execution.
+func (a *Adapter) SetWorkdir(dir string) {
+	a.workdir = dir
+}
+
+// SetInterpreter overrides the interpreter binary for a language (e.g. "python" -> "python3.12").
+func (a *Adapter) SetInterpreter(lang, executable string) {
+	interp, ok := a.interpreters[lang]
+	if !ok {
+		return
+	}
+	interp.executable = executable
+	a.interpreters[lang] = interp
+}
+
+// Run writes the script in task.Prompt to a temp file and executes it.
+func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	stream := task.Streaming(a.streamLogs)
+	interp, ok := a.interpreters[task.ScriptLang]
+	if !ok {
+		return runtime.Result{}, fmt.Errorf("unsupported script lang %q", task.ScriptLang)
+	}
+	if task.Prompt == "" {
+		return runtime.Result{}, fmt.Errorf("no code specified for script task")
+	}
+
+	file, err := os.CreateTemp("", "cortex-script-*"+interp.extension)
+	if err != nil {
+		return runtime.Result{}, fmt.Errorf("failed to create script file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(task.Prompt); err != nil {
+		file.Close()
+		return runtime.Result{}, fmt.Errorf("failed to write script file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return runtime.Result{}, fmt.Errorf("failed to write script file: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, interp.executable, file.Name())
+	cmd.Env = task.Environ()
+	proc.KillOnCancel(cmd, task.KillTimeout)
+
+	workdir := task.Workdir
+	if workdir == "" {
+		workdir = a.workdir
+	}
+	if workdir != "" {
+		cmd.Dir = workdir
+	}
+
+	slog.Debug("running script", "task", task.Name, "interpreter", interp.executable, "lang", task.ScriptLang, "dir", cmd.Dir)
+
+	stdout := runtime.NewCappedBuffer(task.MaxOutput)
+	stderr := runtime.NewCappedBuffer(task.MaxOutput)
+
+	out, errOut := task.Live(stream)
+	if stream {
+		ui.PrintStreamStart(out)
+		fmt.Fprintf(out, "%s  $ %s <%s script>%s\n", ui.Dim, interp.executable, task.ScriptLang, ui.Reset)
+	}
+	cmd.Stdout = io.MultiWriter(out, stdout)
+	cmd.Stderr = io.MultiWriter(errOut, stderr)
+
+	err = proc.Run(cmd)
+
+	if stream {
+		ui.PrintStreamEnd(task.Out())
+	}
+
+	result := runtime.Result{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		ExitCode:  0,
+		Success:   true,
+		Truncated: stdout.Truncated() || stderr.Truncated(),
+	}
+
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+			result.Success = false
+		} else {
+			return result, fmt.Errorf("failed to execute %s: %w", interp.executable, err)
+		}
+	}
+
+	return result, nil
+}
+
+// Check verifies that all configured interpreters are available.
+func (a *Adapter) Check() error {
+	for lang, interp := range a.interpreters {
+		if _, err := exec.LookPath(interp.executable); err != nil {
+			return fmt.Errorf("%s interpreter %s not found: %w", lang, interp.executable, err)
+		}
+	}
+	return nil
+}
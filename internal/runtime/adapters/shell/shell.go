@@ -3,21 +3,26 @@ package shell
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"fmt"
 	"io"
-	"os"
+	"log/slog"
 	"os/exec"
-	"strings"
+	"time"
 
+	"github.com/adityaraj/agentflow/internal/proc"
 	"github.com/adityaraj/agentflow/internal/runtime"
 	"github.com/adityaraj/agentflow/internal/ui"
 )
 
+// sandboxShell runs the commands of sandboxed tasks whose agent sets no
+// shell.
+const sandboxShell = "/bin/sh"
+
 // Adapter implements the Agent interface for shell command execution.
 type Adapter struct {
-	// shell is the shell to use (default: /bin/sh)
+	// shell is the shell to use when the task sets none (default: cmd.exe
+	// on Windows, /bin/sh elsewhere)
 	shell string
 	// streamLogs enables real-time output streaming
 	streamLogs bool
@@ -28,7 +33,7 @@ type Adapter struct {
 // New creates a new Shell adapter with default settings.
 func New() *Adapter {
 	return &Adapter{
-		shell:      "/bin/sh",
+		shell:      proc.DefaultShell(),
 		streamLogs: false,
 	}
 }
@@ -54,34 +59,86 @@ func (a *Adapter) SetWorkdir(dir string) {
 // Run executes a shell command.
 // For shell agents, task.Prompt contains the command to execute.
 func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	stream := task.Streaming(a.streamLogs)
 	command := task.Prompt
 	if command == "" {
 		return runtime.Result{}, fmt.Errorf("no command specified for shell task")
 	}
 
-	// Build command with shell
-	cmd := exec.CommandContext(ctx, a.shell, "-c", command)
+	// Stop the command once its time limit is up
+	limits := task.Limits
+	runCtx := ctx
+	if limits.Time > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, limits.Time)
+		defer cancel()
+	}
 
-	// Set working directory
+	// Build command with the agent's shell, if it sets one
+	shell := task.Shell
+	if shell == "" {
+		shell = a.shell
+	}
 	workdir := task.Workdir
 	if workdir == "" {
 		workdir = a.workdir
 	}
-	if workdir != "" {
-		cmd.Dir = workdir
+	var cmd *exec.Cmd
+	if task.Sandbox != nil {
+		// The container's shell, whatever the host's
+		if task.Shell == "" {
+			shell = sandboxShell
+		}
+		cmd = task.Command(runCtx, workdir, shell, "-c", command)
+	} else {
+		cmd = proc.ShellCommand(runCtx, shell, command)
+		if workdir != "" {
+			cmd.Dir = workdir
+		}
+	}
+	cmd.Env = task.Environ()
+	proc.KillOnCancel(cmd, task.KillTimeout)
+
+	slog.Debug("running command", "task", task.Name, "shell", shell, "command", command, "dir", cmd.Dir)
+
+	// Streaming mode shows output in real-time, non-streaming mode captures it
+	var result runtime.Result
+	var err error
+	if stream {
+		result, err = a.runStreaming(cmd, command, task)
+	} else {
+		result, err = a.runBuffered(cmd, task)
+	}
+	if err != nil || result.Success {
+		return result, err
 	}
 
-	// Streaming mode: show output in real-time
-	if a.streamLogs {
-		return a.runStreaming(cmd, command)
+	// Say which limit stopped a failed command, if one did
+	switch {
+	case limits.Time > 0 && runCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil:
+		result.Stderr += fmt.Sprintf("[cortex: time limit of %s reached, command stopped]\n", limits.Time)
+	case limits.CPU > 0 && cmd.ProcessState != nil && proc.CPULimitReached(cmd.ProcessState):
+		result.Stderr += fmt.Sprintf("[cortex: cpu limit of %s reached]\n", limits.CPU)
 	}
+	return result, nil
+}
 
-	// Non-streaming mode: capture output
-	return a.runBuffered(cmd)
+// start starts cmd with the task's CPU and memory limits, which are in
+// place before the command runs and are inherited by the processes it
+// starts.
+func start(cmd *exec.Cmd, task runtime.Task) error {
+	limits := task.Limits
+	if limits.CPU > 0 || limits.Memory > 0 {
+		cpuSeconds := uint64((limits.CPU + time.Second - 1) / time.Second)
+		if err := proc.Limit(cmd, cpuSeconds, uint64(limits.Memory)); err != nil {
+			return fmt.Errorf("failed to apply limits: %w", err)
+		}
+	}
+	return cmd.Start()
 }
 
 // runStreaming executes the command with real-time output streaming.
-func (a *Adapter) runStreaming(cmd *exec.Cmd, command string) (runtime.Result, error) {
+func (a *Adapter) runStreaming(cmd *exec.Cmd, command string, task runtime.Task) (runtime.Result, error) {
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return runtime.Result{}, fmt.Errorf("failed to create stdout pipe: %w", err)
@@ -92,29 +149,30 @@ func (a *Adapter) runStreaming(cmd *exec.Cmd, command string) (runtime.Result, e
 		return runtime.Result{}, fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
-	if err := cmd.Start(); err != nil {
+	if err := start(cmd, task); err != nil {
 		return runtime.Result{}, fmt.Errorf("failed to start command: %w", err)
 	}
 
 	// Print command being executed
-	ui.PrintStreamStart()
+	ui.PrintStreamStart(task.Out())
 	displayCmd := command
 	if len(displayCmd) > 80 {
 		displayCmd = displayCmd[:80] + "..."
 	}
-	fmt.Printf("%s  $ %s%s\n", ui.Dim, displayCmd, ui.Reset)
+	fmt.Fprintf(task.Out(), "%s  $ %s%s\n", ui.Dim, displayCmd, ui.Reset)
 
 	// Stream stdout and stderr concurrently
-	var stdoutBuf, stderrBuf strings.Builder
+	stdoutBuf := runtime.NewCappedBuffer(task.MaxOutput)
+	stderrBuf := runtime.NewCappedBuffer(task.MaxOutput)
 	done := make(chan struct{}, 2)
 
 	go func() {
-		a.streamOutput(stdout, os.Stdout, &stdoutBuf)
+		a.streamOutput(stdout, task.Out(), stdoutBuf)
 		done <- struct{}{}
 	}()
 
 	go func() {
-		a.streamOutput(stderr, os.Stderr, &stderrBuf)
+		a.streamOutput(stderr, task.ErrOut(), stderrBuf)
 		done <- struct{}{}
 	}()
 
@@ -122,15 +180,16 @@ func (a *Adapter) runStreaming(cmd *exec.Cmd, command string) (runtime.Result, e
 	<-done
 	<-done
 
-	ui.PrintStreamEnd()
+	ui.PrintStreamEnd(task.Out())
 
-	err = cmd.Wait()
+	err = proc.Wait(cmd)
 
 	result := runtime.Result{
-		Stdout:   stdoutBuf.String(),
-		Stderr:   stderrBuf.String(),
-		ExitCode: 0,
-		Success:  true,
+		Stdout:    stdoutBuf.String(),
+		Stderr:    stderrBuf.String(),
+		ExitCode:  0,
+		Success:   true,
+		Truncated: stdoutBuf.Truncated() || stderrBuf.Truncated(),
 	}
 
 	if err != nil {
@@ -146,18 +205,24 @@ func (a *Adapter) runStreaming(cmd *exec.Cmd, command string) (runtime.Result, e
 }
 
 // runBuffered executes the command and captures all output.
-func (a *Adapter) runBuffered(cmd *exec.Cmd) (runtime.Result, error) {
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+func (a *Adapter) runBuffered(cmd *exec.Cmd, task runtime.Task) (runtime.Result, error) {
+	stdout := runtime.NewCappedBuffer(task.MaxOutput)
+	stderr := runtime.NewCappedBuffer(task.MaxOutput)
+	out, errOut := task.Live(false)
+	cmd.Stdout = io.MultiWriter(out, stdout)
+	cmd.Stderr = io.MultiWriter(errOut, stderr)
+
+	err := start(cmd, task)
+	if err == nil {
+		err = proc.Wait(cmd)
+	}
 
 	result := runtime.Result{
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
-		ExitCode: 0,
-		Success:  true,
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		ExitCode:  0,
+		Success:   true,
+		Truncated: stdout.Truncated() || stderr.Truncated(),
 	}
 
 	if err != nil {
@@ -173,7 +238,9 @@ func (a *Adapter) runBuffered(cmd *exec.Cmd) (runtime.Result, error) {
 }
 
 // streamOutput reads from reader and writes to both writer and buffer.
-func (a *Adapter) streamOutput(r io.Reader, w io.Writer, buf *strings.Builder) {
+// Once the buffer is full, the writer gets the truncation marker instead of
+// the remaining lines.
+func (a *Adapter) streamOutput(r io.Reader, w io.Writer, buf *runtime.CappedBuffer) {
 	scanner := bufio.NewScanner(r)
 	// Increase buffer size for long lines
 	scanBuf := make([]byte, 0, 64*1024)
@@ -181,15 +248,20 @@ func (a *Adapter) streamOutput(r io.Reader, w io.Writer, buf *strings.Builder) {
 
 	for scanner.Scan() {
 		line := scanner.Text()
-		buf.WriteString(line)
-		buf.WriteString("\n")
-		fmt.Fprintln(w, line)
+		truncated := buf.Truncated()
+		fmt.Fprintln(buf, line)
+		switch {
+		case !buf.Truncated():
+			fmt.Fprintln(w, line)
+		case !truncated:
+			fmt.Fprintf(w, "%s[cortex: output limit reached, the rest is not shown]%s\n", ui.Dim, ui.Reset)
+		}
 	}
 }
 
 // Check verifies that the shell is available.
 func (a *Adapter) Check() error {
-	cmd := exec.Command(a.shell, "-c", "echo ok")
+	cmd := proc.ShellCommand(context.Background(), a.shell, "echo ok")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("shell %s not available: %w", a.shell, err)
 	}
@@ -0,0 +1,171 @@
+// Package wait implements the Agent interface for built-in wait and poll tasks.
+package wait
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/runtime"
+	"github.com/adityaraj/agentflow/internal/runtime/adapters/httpreq"
+	"github.com/adityaraj/agentflow/internal/runtime/adapters/shell"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// Poll defaults used when a poll task does not set interval or timeout.
+const (
+	DefaultPollInterval = 10 * time.Second
+	DefaultPollTimeout  = 10 * time.Minute
+)
+
+// Adapter implements the Agent interface for wait and poll tasks.
+// Poll checks run through the shell and http adapters.
+type Adapter struct {
+	// shell runs command checks
+	shell *shell.Adapter
+	// http runs http checks
+	http *httpreq.Adapter
+	// streamLogs enables printing progress while waiting
+	streamLogs bool
+}
+
+// New creates a new wait adapter.
+func New() *Adapter {
+	return &Adapter{
+		shell:      shell.New(),
+		http:       httpreq.New(),
+		streamLogs: false,
+	}
+}
+
+// SetStreamLogs enables or disables real-time log streaming.
+// Individual poll attempts are never streamed.
+func (a *Adapter) SetStreamLogs(enabled bool) {
+	a.streamLogs = enabled
+}
+
+// Run sleeps for task.Wait, or repeats task.Poll until it succeeds.
+func (a *Adapter) Run(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	if task.Poll != nil {
+		return a.poll(ctx, task)
+	}
+	if task.Wait == "" {
+		return runtime.Result{}, fmt.Errorf("no duration specified for wait task")
+	}
+
+	d, err := time.ParseDuration(task.Wait)
+	if err != nil {
+		return runtime.Result{}, fmt.Errorf("invalid wait duration %q: %w", task.Wait, err)
+	}
+
+	slog.Debug("waiting", "task", task.Name, "duration", d.String())
+	if task.Streaming(a.streamLogs) {
+		ui.PrintStreamStart(task.Out())
+		fmt.Fprintf(task.Out(), "%s  Waiting %s%s\n", ui.Dim, d, ui.Reset)
+		ui.PrintStreamEnd(task.Out())
+	}
+
+	if err := sleep(ctx, d); err != nil {
+		return runtime.Result{}, err
+	}
+	return runtime.Result{ExitCode: 0, Success: true}, nil
+}
+
+// poll runs the check every interval until it succeeds or the timeout
+// expires. The output of the successful attempt becomes the task output.
+func (a *Adapter) poll(ctx context.Context, task runtime.Task) (runtime.Result, error) {
+	spec := task.Poll
+	interval, err := durationOr(spec.Interval, DefaultPollInterval)
+	if err != nil {
+		return runtime.Result{}, fmt.Errorf("invalid poll interval: %w", err)
+	}
+	timeout, err := durationOr(spec.Timeout, DefaultPollTimeout)
+	if err != nil {
+		return runtime.Result{}, fmt.Errorf("invalid poll timeout: %w", err)
+	}
+
+	check := task
+	check.Poll = nil
+	check.ShowOutput = ""
+	var checker runtime.Agent = a.shell
+	check.Prompt = spec.Command
+	if spec.HTTP != nil {
+		checker = a.http
+		check.Prompt = spec.HTTP.Body
+		check.HTTP = spec.HTTP
+	}
+
+	stream := task.Streaming(a.streamLogs)
+	if stream {
+		ui.PrintStreamStart(task.Out())
+		defer ui.PrintStreamEnd(task.Out())
+	}
+
+	deadline := time.Now().Add(timeout)
+	pollCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	var last runtime.Result
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		last, lastErr = checker.Run(pollCtx, check)
+		if lastErr == nil && last.Success {
+			slog.Debug("poll ready", "task", task.Name, "attempts", attempt)
+			if stream {
+				fmt.Fprintf(task.Out(), "%s  Ready after %d attempt(s)%s\n", ui.Dim, attempt, ui.Reset)
+			}
+			return last, nil
+		}
+
+		if ctx.Err() != nil {
+			return runtime.Result{}, ctx.Err()
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return timedOut(last, lastErr, timeout, attempt), nil
+		}
+
+		slog.Debug("poll not ready", "task", task.Name, "attempt", attempt, "retry_in", interval.String())
+		if stream {
+			fmt.Fprintf(task.Out(), "%s  Attempt %d not ready, retrying in %s%s\n", ui.Dim, attempt, interval, ui.Reset)
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return runtime.Result{}, err
+		}
+	}
+}
+
+// timedOut builds the failed result of a poll that never succeeded,
+// keeping the output of the last attempt.
+func timedOut(last runtime.Result, lastErr error, timeout time.Duration, attempts int) runtime.Result {
+	result := last
+	result.Success = false
+	if result.ExitCode == 0 {
+		result.ExitCode = 1
+	}
+	if lastErr != nil {
+		result.Stderr += lastErr.Error() + "\n"
+	}
+	result.Stderr += fmt.Sprintf("poll timed out after %s (%d attempts)\n", timeout, attempts)
+	return result
+}
+
+// durationOr parses s, returning def when s is empty.
+func durationOr(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// sleep waits for d or until ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
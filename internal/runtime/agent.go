@@ -3,29 +3,128 @@ package runtime
 
 import (
 	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
 )
 
 // Task represents a task to be executed by an agent.
 type Task struct {
-	Name    string // Task name
-	Agent   string // Agent name
-	Tool    string // CLI tool (claude-code, opencode)
-	Model   string // Model identifier
-	Prompt  string // Prompt text (already expanded with template variables)
-	Write   bool   // Allow file writes
-	Workdir string // Working directory for the agent (optional)
+	Name         string                    // Task name
+	Agent        string                    // Agent name
+	Tool         string                    // CLI tool (claude-code, opencode)
+	Model        string                    // Model identifier
+	Prompt       string                    // Prompt text (already expanded with template variables)
+	SystemPrompt string                    // System prompt (already expanded; empty: the tool's default)
+	MCPServers   []string                  // MCP config files for claude-code
+	Sandbox      *config.SandboxConfig     // Container the agent's process runs in (nil: on the host)
+	Write        bool                      // Allow file writes
+	Permissions  *config.PermissionsConfig // Tools and paths claude-code may use (nil: all or none, by Write)
+	Limits       config.TaskLimits         // Resource caps of shell tasks (zero fields: no limit)
+	MaxOutput    int64                     // Bytes of stdout and of stderr kept, head and tail (0: all)
+	Workdir      string                    // Working directory for the agent (optional)
+	ScriptLang   string                    // Interpreter language for script tasks
+	Shell        string                    // Shell for shell agents (empty: the platform default)
+	BaseURL      string                    // API endpoint for api agents
+	APIKeyEnv    string                    // Env var holding the API key for api agents
+	Proxy        string                    // Proxy URL for api agents
+	TLS          *config.TLSConfig         // Certificate settings for api agents
+	Env          []string                  // Extra environment variables (KEY=VALUE) including secrets
+	HTTP         *config.HTTPConfig        // Request for http tasks (already expanded)
+	Git          *config.GitConfig         // Operation for git tasks (already expanded)
+	Wait         string                    // Delay for wait tasks
+	Poll         *config.PollConfig        // Check for poll tasks (already expanded)
+	Notify       *config.NotifyConfig      // Settings for notify tasks; the message is in Prompt
+	PullRequest  *config.PullRequestConfig // Pull request for pull_request tasks (already expanded); the body is in Prompt
+	ShowOutput   string                    // Per-task output mode (full, summary, none); empty uses the adapter default
+	KillTimeout  time.Duration             // Time the task's processes get to exit after cancellation before they are killed (0: proc.DefaultKillTimeout)
+	Stdout       io.Writer                 // Destination for streamed output (set by the executor; nil means the terminal)
+	Stderr       io.Writer                 // Destination for streamed errors (set by the executor; nil means the terminal)
+	Log          io.Writer                 // Task log file, which Live writes to when output isn't streamed (set by the executor; nil: none)
+}
+
+// Environ returns the environment for the task's process: the current
+// process environment followed by the task's own variables.
+// Returns nil when the task adds nothing, so exec inherits the environment.
+func (t Task) Environ() []string {
+	if len(t.Env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), t.Env...)
+}
+
+// Getenv looks up a variable in the task environment, falling back to the
+// process environment.
+func (t Task) Getenv(key string) string {
+	for i := len(t.Env) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(t.Env[i], "="); ok && k == key {
+			return v
+		}
+	}
+	return os.Getenv(key)
+}
+
+// Out returns the writer for the task's streamed output.
+func (t Task) Out() io.Writer {
+	if t.Stdout != nil {
+		return t.Stdout
+	}
+	return ui.Stdout
+}
+
+// ErrOut returns the writer for the task's streamed errors.
+func (t Task) ErrOut() io.Writer {
+	if t.Stderr != nil {
+		return t.Stderr
+	}
+	return ui.Stderr
+}
+
+// Live returns the writers for the task's output as its process produces it:
+// the streamed output if stream is set, which the executor also writes to
+// the task log, and otherwise the task log alone. Either way, the log gets
+// all the output while the task runs.
+func (t Task) Live(stream bool) (stdout, stderr io.Writer) {
+	if stream {
+		return t.Out(), t.ErrOut()
+	}
+	if t.Log != nil {
+		return t.Log, t.Log
+	}
+	return io.Discard, io.Discard
+}
+
+// Streaming reports whether the task's output should be streamed live.
+// show_output: full always streams; summary and none never do. Otherwise the
+// adapter's own setting applies.
+func (t Task) Streaming(adapterDefault bool) bool {
+	switch t.ShowOutput {
+	case config.ShowOutputFull:
+		return true
+	case config.ShowOutputSummary, config.ShowOutputNone:
+		return false
+	}
+	return adapterDefault
 }
 
 // Result represents the result of executing a task.
 type Result struct {
-	Stdout       string // Standard output from the agent
-	Stderr       string // Standard error from the agent
-	ExitCode     int    // Exit code (0 = success)
-	Success      bool   // Whether the task succeeded
-	InputTokens  int    // Input tokens used (for AI agents)
-	OutputTokens int    // Output tokens used (for AI agents)
-	CacheRead    int    // Cache read tokens (for AI agents)
-	CacheWrite   int    // Cache write tokens (for AI agents)
+	Stdout       string           // Standard output from the agent
+	Stderr       string           // Standard error from the agent
+	ExitCode     int              // Exit code (0 = success)
+	Success      bool             // Whether the task succeeded
+	InputTokens  int              // Input tokens used (for AI agents)
+	OutputTokens int              // Output tokens used (for AI agents)
+	CacheRead    int              // Cache read tokens (for AI agents)
+	CacheWrite   int              // Cache write tokens (for AI agents)
+	CostUSD      float64          // Cost reported by the agent in USD (for AI agents)
+	ToolCalls    []state.ToolCall // Tools the agent used, in order (for AI agents that report them)
+	Truncated    bool             // Output beyond the task's MaxOutput was dropped
 }
 
 // Agent is the interface that all agent adapters must implement.
@@ -0,0 +1,79 @@
+package runtime
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Budget limits the cumulative usage of a run. Zero fields mean no limit.
+type Budget struct {
+	MaxTokens  int     // Total input + output tokens
+	MaxCostUSD float64 // Cost reported by agents, in USD
+}
+
+// IsSet reports whether any limit is configured.
+func (b Budget) IsSet() bool {
+	return b.MaxTokens > 0 || b.MaxCostUSD > 0
+}
+
+// BudgetExceededError is returned when a run stops because a completed task
+// pushed usage over the budget.
+type BudgetExceededError struct {
+	Budget  Budget
+	Task    string  // Task whose usage crossed the limit
+	Tokens  int     // Tokens used so far
+	CostUSD float64 // Cost so far
+}
+
+func (e *BudgetExceededError) Error() string {
+	if e.Budget.MaxTokens > 0 && e.Tokens > e.Budget.MaxTokens {
+		return fmt.Sprintf("budget exceeded after task %q: used %d tokens of max_tokens %d", e.Task, e.Tokens, e.Budget.MaxTokens)
+	}
+	return fmt.Sprintf("budget exceeded after task %q: cost $%.4f of max_cost_usd $%.2f", e.Task, e.CostUSD, e.Budget.MaxCostUSD)
+}
+
+// budgetTracker accumulates usage across concurrently running tasks.
+type budgetTracker struct {
+	mu       sync.Mutex
+	budget   Budget
+	tokens   int
+	costUSD  float64
+	exceeded *BudgetExceededError
+}
+
+// add records a task's usage and returns an error the first time the budget
+// is exceeded.
+func (b *budgetTracker) add(task string, tokens int, costUSD float64) *BudgetExceededError {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += tokens
+	b.costUSD += costUSD
+	if b.exceeded != nil || !b.budget.IsSet() {
+		return nil
+	}
+
+	overTokens := b.budget.MaxTokens > 0 && b.tokens > b.budget.MaxTokens
+	overCost := b.budget.MaxCostUSD > 0 && b.costUSD > b.budget.MaxCostUSD
+	if !overTokens && !overCost {
+		return nil
+	}
+
+	b.exceeded = &BudgetExceededError{
+		Budget:  b.budget,
+		Task:    task,
+		Tokens:  b.tokens,
+		CostUSD: b.costUSD,
+	}
+	return b.exceeded
+}
+
+// err returns the budget error if the budget has been exceeded.
+func (b *budgetTracker) err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.exceeded == nil {
+		return nil
+	}
+	return b.exceeded
+}
@@ -0,0 +1,146 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/state"
+)
+
+// maxTrackedFiles is the most files a working directory outside git may
+// hold for a write task's changes to be tracked; walking more before and
+// after every task would slow runs down.
+const maxTrackedFiles = 20000
+
+// errTooManyFiles stops the walk of a directory with more than
+// maxTrackedFiles files.
+var errTooManyFiles = errors.New("too many files to track changes")
+
+// fileStamp is what tells a file was changed outside git repositories.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+}
+
+// trackChanges records the working tree at dir before a write task runs:
+// the repository's tree inside git, the size and modification time of each
+// file outside it.
+func trackChanges(ctx context.Context, dir string) (*taskChanges, error) {
+	if c, err := snapshotChanges(ctx, dir); err == nil {
+		return c, nil
+	}
+	if dir == "" {
+		dir = "."
+	}
+	stamps, err := stampFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &taskChanges{dir: dir, stamps: stamps}, nil
+}
+
+// stampFiles returns the stamps of the regular files below dir by their
+// slash-separated relative paths. Version control directories are skipped.
+func stampFiles(dir string) (map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (d.Name() == ".git" || d.Name() == ".hg" || d.Name() == ".svn") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(stamps) == maxTrackedFiles {
+			return errTooManyFiles
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		stamps[filepath.ToSlash(rel)] = fileStamp{size: info.Size(), modTime: info.ModTime(), mode: info.Mode()}
+		return nil
+	})
+	return stamps, err
+}
+
+// finishFiles compares the files in the directory with their stamps from
+// before the task.
+func (c *taskChanges) finishFiles() error {
+	after, err := stampFiles(c.dir)
+	if err != nil {
+		return err
+	}
+	c.files = nil
+	for path, stamp := range after {
+		before, ok := c.stamps[path]
+		switch {
+		case !ok:
+			c.files = append(c.files, state.FileChange{Path: path, Status: state.ChangeAdded})
+		case before != stamp:
+			c.files = append(c.files, state.FileChange{Path: path, Status: state.ChangeModified})
+		}
+	}
+	for path := range c.stamps {
+		if _, ok := after[path]; !ok {
+			c.files = append(c.files, state.FileChange{Path: path, Status: state.ChangeDeleted})
+		}
+	}
+	sort.Slice(c.files, func(i, j int) bool { return c.files[i].Path < c.files[j].Path })
+	return nil
+}
+
+// changedFiles lists the files that differ between two trees of repo, with
+// the lines added and deleted in each. Binary files count no lines.
+func changedFiles(ctx context.Context, repo, before, after string) ([]state.FileChange, error) {
+	out, err := gitOutput(ctx, repo, nil, "diff", "--no-renames", "--name-status", "-z", before, after)
+	if err != nil {
+		return nil, err
+	}
+	var files []state.FileChange
+	index := make(map[string]int)
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status := state.ChangeModified
+		switch fields[i] {
+		case "A":
+			status = state.ChangeAdded
+		case "D":
+			status = state.ChangeDeleted
+		}
+		index[fields[i+1]] = len(files)
+		files = append(files, state.FileChange{Path: fields[i+1], Status: status})
+	}
+
+	// numstat gives "added<TAB>deleted<TAB>path", with "-" for binary files
+	out, err = gitOutput(ctx, repo, nil, "diff", "--no-renames", "--numstat", "-z", before, after)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range strings.Split(out, "\x00") {
+		parts := strings.SplitN(entry, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		i, ok := index[parts[2]]
+		if !ok {
+			continue
+		}
+		files[i].Added, _ = strconv.Atoi(parts[0])
+		files[i].Deleted, _ = strconv.Atoi(parts[1])
+	}
+	return files, nil
+}
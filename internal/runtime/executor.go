@@ -4,26 +4,64 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/events"
+	"github.com/adityaraj/agentflow/internal/git"
 	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/redact"
 	"github.com/adityaraj/agentflow/internal/state"
 	"github.com/adityaraj/agentflow/internal/ui"
+	"github.com/adityaraj/agentflow/internal/webhook"
 )
 
 // Executor runs tasks according to an execution plan.
 type Executor struct {
 	registry    *AgentRegistry
 	store       *state.Store
-	outputs     map[string]string // Task outputs for template expansion
-	outputsMu   sync.RWMutex      // Protects outputs map
-	verbose     bool
-	writer      io.Writer // Output writer for logs
-	parallel    bool      // Enable parallel execution
-	maxParallel int       // Max concurrent tasks (0 = unlimited)
+	outputs     map[string]string       // Task outputs for template expansion
+	statuses    map[string]string       // Task statuses for `when` conditions
+	artifacts   map[string][]string     // Collected artifact paths for {{artifacts.X}}
+	changes     map[string]*taskChanges // Git changes of tasks with review_changes_with, for their reviews
+	blocked     map[string]string       // Failed tasks, and tasks skipped because of them -> the failed task
+	outputsMu   sync.RWMutex            // Protects outputs, statuses, artifacts, changes, and blocked maps
+	treeMu      sync.RWMutex            // Held alone by tasks whose changes to the working tree are diffed, shared by the others
+	groups      map[string][]string     // Fan-out task name -> instance names
+	bus         *events.Bus             // Where the run's events are published
+	parallel    bool                    // Enable parallel execution
+	maxParallel int                     // Max concurrent tasks (0 = unlimited)
+	incremental bool                    // Skip tasks whose outputs are up to date
+	cache       *state.TaskCache        // Task hashes from previous runs (incremental mode)
+	secrets     map[string]string       // Resolved secrets injected into every task
+	promptHooks []PromptHook            // Transform the prompts of AI tasks, before the agents' own hooks
+	builtins    map[string]string       // Values of {{run.id}} and the other built-in variables
+	vars        map[string]string       // Workflow variables, for template expressions
+	budget      *budgetTracker          // Cumulative usage against the run budget
+	cancelRun   func()                  // Cancels remaining tasks (set during Execute)
+	project     string                  // Project name, for events
+	user        string                  // Who started the run
+	failFast    bool                    // Stop the run at the first failure
+	faults      []Fault                 // Simulated adapter failures and delays
+	killTimeout time.Duration           // Time the processes of cancelled tasks get to exit before they are killed
+	maxOutput   int64                   // Bytes kept of each task's stdout and stderr (0: all)
+	git         *config.GitRunConfig    // Branch per run and commit per write task (nil: off)
+	gitDir      string                  // Directory of the repository the git settings apply to
+	repo        *git.Repo               // Repository the run commits to (set during Execute)
+
+	// What Abort needs to stop and save a run
+	aborted  context.Context              // Context of teardown tasks, cancelled only by Abort (set during Execute)
+	abortRun func()                       // Cancels all tasks, teardown tasks included (set during Execute)
+	runSaved chan struct{}                // Closed once the finished run was saved (set during Execute)
+	run      *state.RunResult             // The run in progress
+	inflight map[string]*state.TaskResult // Tasks whose agent is running
+	runMu    sync.Mutex                   // Protects abortRun, runSaved, run.Tasks, and inflight
 }
 
 // ExecutorConfig holds configuration for creating an Executor.
@@ -34,6 +72,25 @@ type ExecutorConfig struct {
 	Verbose     bool
 	Parallel    bool
 	MaxParallel int
+	Incremental bool
+	Secrets     map[string]string
+	PromptHooks []PromptHook        // Run on the prompt of every AI task, before the agent's hooks
+	Builtins    map[string]string   // Values of {{run.id}} and the other built-in variables
+	Vars        map[string]string   // Workflow variables, for vars.<name> in template expressions
+	Budget      Budget              // Usage limits; the run is cancelled once exceeded
+	Webhooks    *webhook.Manager    // Optional, for run, task, level, budget_exceeded, and run_cancelled events
+	Subscribers []events.Subscriber // Extra subscribers to the run's events
+	Project     string
+	User        string               // Who started the run, recorded in the run result
+	Stream      bool                 // Adapters stream output by default (show_output can override per task)
+	Progress    bool                 // Show a spinner for non-streaming tasks and a progress bar in parallel mode
+	Output      string               // interleaved (default), prefixed, or grouped
+	FailFast    bool                 // Stop at the first failure; otherwise only skip the failed task's dependents
+	Faults      []Fault              // Simulated adapter failures and delays, for testing failure handling
+	KillTimeout time.Duration        // Time the processes of cancelled tasks get to exit before they are killed
+	MaxOutput   int64                // Bytes kept of each task's stdout and stderr (0: all)
+	Git         *config.GitRunConfig // Branch per run and commit per write task (optional)
+	Workdir     string               // Workflow's working directory, where the git settings apply
 }
 
 // NewExecutor creates a new Executor with the given registry and store.
@@ -42,29 +99,106 @@ func NewExecutor(registry *AgentRegistry, store *state.Store, writer io.Writer,
 		registry:    registry,
 		store:       store,
 		outputs:     make(map[string]string),
-		verbose:     verbose,
-		writer:      writer,
+		statuses:    make(map[string]string),
+		artifacts:   make(map[string][]string),
+		changes:     make(map[string]*taskChanges),
+		blocked:     make(map[string]string),
+		inflight:    make(map[string]*state.TaskResult),
+		bus:         events.NewBus(&storeSubscriber{store: store}, newLogSubscriber(store.RunID(), ""), newConsoleSubscriber(writer, verbose, false, false, "")),
 		parallel:    false,
 		maxParallel: 0,
+		budget:      &budgetTracker{},
+		failFast:    true,
 	}
 }
 
 // NewExecutorWithConfig creates a new Executor with full configuration.
+// The run's status file is updated, then results are saved to the store,
+// then logged, then sent to the webhooks, then printed, then passed to
+// cfg.Subscribers.
 func NewExecutorWithConfig(cfg ExecutorConfig) *Executor {
+	bus := events.NewBus(newStatusSubscriber(cfg.Store, cfg.Project, cfg.User), &storeSubscriber{store: cfg.Store}, newLogSubscriber(cfg.Store.RunID(), cfg.Project))
+	if cfg.Webhooks != nil {
+		bus.Subscribe(&webhookSubscriber{webhooks: cfg.Webhooks, runID: cfg.Store.RunID(), project: cfg.Project})
+	}
+	bus.Subscribe(newConsoleSubscriber(cfg.Writer, cfg.Verbose, cfg.Stream, cfg.Progress && ui.IsTerminal(), cfg.Output))
+	for _, sub := range cfg.Subscribers {
+		bus.Subscribe(sub)
+	}
+
 	return &Executor{
 		registry:    cfg.Registry,
 		store:       cfg.Store,
 		outputs:     make(map[string]string),
-		verbose:     cfg.Verbose,
-		writer:      cfg.Writer,
+		statuses:    make(map[string]string),
+		artifacts:   make(map[string][]string),
+		changes:     make(map[string]*taskChanges),
+		blocked:     make(map[string]string),
+		inflight:    make(map[string]*state.TaskResult),
+		bus:         bus,
 		parallel:    cfg.Parallel,
 		maxParallel: cfg.MaxParallel,
+		incremental: cfg.Incremental,
+		secrets:     cfg.Secrets,
+		promptHooks: cfg.PromptHooks,
+		builtins:    cfg.Builtins,
+		vars:        cfg.Vars,
+		budget:      &budgetTracker{budget: cfg.Budget},
+		project:     cfg.Project,
+		user:        cfg.User,
+		failFast:    cfg.FailFast,
+		faults:      cfg.Faults,
+		killTimeout: cfg.KillTimeout,
+		maxOutput:   cfg.MaxOutput,
+		git:         cfg.Git,
+		gitDir:      cfg.Workdir,
 	}
 }
 
 // Execute runs all tasks in the execution plan.
 // Uses parallel execution if enabled, otherwise sequential.
 func (e *Executor) Execute(ctx context.Context, plan *planner.ExecutionPlan) (*state.RunResult, error) {
+	e.initGroups(plan.Groups)
+
+	// Exceeding the budget cancels the tasks still running or queued;
+	// teardown tasks ignore that and interrupts, and only stop on Abort
+	aborted, abort := context.WithCancel(context.WithoutCancel(ctx))
+	defer abort()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(aborted, cancel)()
+	e.cancelRun = cancel
+	e.aborted = aborted
+
+	e.runMu.Lock()
+	e.abortRun = abort
+	e.runSaved = make(chan struct{})
+	e.runMu.Unlock()
+
+	if e.incremental && e.cache == nil {
+		cache, err := e.store.LoadTaskCache()
+		if err != nil {
+			ui.Warning("Incremental cache unavailable, running all tasks: %s", err)
+			slog.Warn("incremental cache unavailable", "error", err)
+		} else {
+			e.cache = cache
+			defer func() {
+				if err := cache.Save(); err != nil {
+					ui.Warning("Failed to save incremental cache: %s", err)
+					slog.Warn("failed to save incremental cache", "error", err)
+				}
+			}()
+		}
+	}
+
+	if e.git != nil {
+		if err := e.startGit(ctx); err != nil {
+			runResult := e.startRun(plan)
+			e.finishRun(ctx, runResult, err)
+			return runResult, err
+		}
+	}
+
 	if e.parallel {
 		return e.executeParallel(ctx, plan)
 	}
@@ -72,48 +206,56 @@ func (e *Executor) Execute(ctx context.Context, plan *planner.ExecutionPlan) (*s
 }
 
 // executeSequential runs all tasks in the execution plan sequentially.
-// Stops on the first failure and returns the error.
+// Stops on the first failure and returns the error, unless fail-fast is off.
 func (e *Executor) executeSequential(ctx context.Context, plan *planner.ExecutionPlan) (*state.RunResult, error) {
-	runResult := &state.RunResult{
-		RunID:     e.store.RunID(),
-		StartTime: time.Now(),
-		Tasks:     make([]state.TaskResult, 0, len(plan.Tasks)),
-		Success:   true,
-	}
+	runResult := e.startRun(plan)
 
 	totalTasks := len(plan.Tasks)
+	e.bus.Publish(events.RunStarted{RunID: runResult.RunID, Project: e.project, Plan: plan})
+
+	// Failures of tasks that did not stop the run
+	var failures []error
+	// The failure that stopped the run; only teardown tasks run after it
+	var stopErr error
+
 	for i, execTask := range plan.Tasks {
-		// Print task start with colors
-		ui.PrintTaskStart(i+1, totalTasks, execTask.Name, execTask.AgentName, execTask.Tool, execTask.Model)
-		ui.PrintTaskRunningWithProgress(i+1, totalTasks, true) // Show Ctrl+O hint with progress bar
+		teardown := execTask.Phase == config.PhaseTeardown
+		if stopErr != nil && !teardown {
+			continue
+		}
 
-		taskResult, err := e.executeTask(ctx, execTask)
+		taskCtx := e.taskContext(ctx, execTask)
+		taskResult, err := e.executeTask(taskCtx, execTask, i+1, totalTasks, 0)
+		err = e.finishTask(taskCtx, execTask, taskResult, err, i+1, totalTasks)
+		if err == nil {
+			err = e.chargeBudget(taskResult)
+		}
+		e.addResult(runResult, taskResult)
 		if err != nil {
-			runResult.Tasks = append(runResult.Tasks, *taskResult)
-			runResult.Success = false
-			runResult.EndTime = time.Now()
-			_ = e.store.SaveRunResult(runResult)
-			return runResult, err
+			if stopErr == nil && !teardown && e.stopRun(ctx) {
+				stopErr = err
+				continue
+			}
+			failures = append(failures, err)
 		}
-
-		runResult.Tasks = append(runResult.Tasks, *taskResult)
 	}
 
-	runResult.EndTime = time.Now()
-	_ = e.store.SaveRunResult(runResult)
-
-	return runResult, nil
+	err := failedRunError(failures)
+	if stopErr != nil {
+		err = stopErr
+	}
+	err = e.finishRun(ctx, runResult, err)
+	return runResult, err
 }
 
-// executeParallel runs tasks in parallel using execution levels.
-// Tasks in the same level run concurrently, levels run sequentially.
+// executeParallel runs tasks in parallel. A dependency-counting dispatcher
+// hands each task to a bounded pool of workers (max_parallel) as soon as all
+// of its needs have finished, so a slow task only holds up its own
+// dependents. A failure stops new tasks from starting, unless fail-fast is
+// off; tasks already running are allowed to finish, and teardown tasks still
+// run once they have.
 func (e *Executor) executeParallel(ctx context.Context, plan *planner.ExecutionPlan) (*state.RunResult, error) {
-	runResult := &state.RunResult{
-		RunID:     e.store.RunID(),
-		StartTime: time.Now(),
-		Tasks:     make([]state.TaskResult, 0, len(plan.Tasks)),
-		Success:   true,
-	}
+	runResult := e.startRun(plan)
 
 	// Build task lookup map
 	taskMap := make(map[string]planner.ExecutionTask)
@@ -121,118 +263,465 @@ func (e *Executor) executeParallel(ctx context.Context, plan *planner.ExecutionP
 		taskMap[t.Name] = t
 	}
 
-	// Build execution levels
+	// Levels only label progress now; tasks don't wait for their level
 	levels := planner.BuildExecutionLevels(plan.DAG)
 	totalTasks := len(plan.Tasks)
-	var completedTasks atomic.Int32
+	e.bus.Publish(events.RunStarted{RunID: runResult.RunID, Project: e.project, Plan: plan, Levels: levels, Parallel: true})
 
-	var resultsMu sync.Mutex
-
-	for _, level := range levels {
-		// Determine how many tasks to run concurrently
-		maxConcurrent := len(level.Tasks)
-		if e.maxParallel > 0 && maxConcurrent > e.maxParallel {
-			maxConcurrent = e.maxParallel
+	// Count unfinished dependencies; tasks with none are ready. Ready tasks
+	// start by priority, then in plan order, which is topological, so they
+	// start in a stable order.
+	remaining := make(map[string]int, totalTasks)
+	ready := planner.NewReadyQueue(plan)
+	queued := make(map[string]bool, totalTasks)
+	push := func(name string) {
+		queued[name] = true
+		ready.Push(name)
+	}
+	for _, t := range plan.Tasks {
+		remaining[t.Name] = plan.DAG.InDegree[t.Name]
+		if remaining[t.Name] == 0 {
+			push(t.Name)
 		}
+	}
 
-		// Semaphore for limiting concurrency
-		sem := make(chan struct{}, maxConcurrent)
-		var wg sync.WaitGroup
-
-		// Channel to collect errors
-		errChan := make(chan error, len(level.Tasks))
-
-		for _, taskName := range level.Tasks {
-			execTask := taskMap[taskName]
+	workers := totalTasks
+	if e.maxParallel > 0 && e.maxParallel < workers {
+		workers = e.maxParallel
+	}
 
-			wg.Add(1)
-			go func(task planner.ExecutionTask) {
-				defer wg.Done()
+	// Tasks are numbered in the order they start, by the loop below
+	type taskStart struct {
+		task planner.ExecutionTask
+		num  int
+	}
+	type taskDone struct {
+		name   string
+		result *state.TaskResult
+		err    error
+	}
+	work := make(chan taskStart)
+	done := make(chan taskDone)
 
-				// Acquire semaphore
-				sem <- struct{}{}
-				defer func() { <-sem }()
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range work {
+				task, taskNum := start.task, start.num
+				level := max(planner.LevelForTask(levels, task.Name), 0)
 
-				// Check if context is cancelled
-				if ctx.Err() != nil {
-					errChan <- ctx.Err()
-					return
+				taskCtx := e.taskContext(ctx, task)
+				taskResult, err := e.executeTask(taskCtx, task, taskNum, totalTasks, level)
+				err = e.finishTask(taskCtx, task, taskResult, err, taskNum, totalTasks)
+				if err == nil {
+					err = e.chargeBudget(taskResult)
 				}
 
-				// Get current task number for display (increment happens after execution)
-				taskNum := int(completedTasks.Load()) + 1
-				// Print task start
-				ui.PrintTaskStart(taskNum, totalTasks, task.Name, task.AgentName, task.Tool, task.Model)
-				ui.PrintTaskRunningWithProgress(taskNum, totalTasks, true) // Show Ctrl+O hint with progress
+				done <- taskDone{name: task.Name, result: taskResult, err: err}
+			}
+		}()
+	}
 
-				// Execute the task
-				taskResult, err := e.executeTask(ctx, task)
+	// Failures of finished tasks, in the order they finished
+	var failures []error
+	// Levels whose level_start event was sent; tasks start across levels,
+	// so a level starts with its first task
+	levelStarted := make(map[int]bool, len(levels))
+	stopping := false
+	running := 0
+	started := 0
+	finished := make(map[string]bool, totalTasks)
+	// Whether teardown tasks stopped waiting for tasks that won't run
+	released := false
+	for {
+		if ctx.Err() != nil {
+			stopping = true
+		}
+		if stopping {
+			// Only teardown tasks start once the run is stopping, and
+			// when nothing else is left running they no longer wait for
+			// the tasks that won't start, only for each other
+			ready.Retain(func(name string) bool {
+				return taskMap[name].Phase == config.PhaseTeardown
+			})
+			if running == 0 && !released {
+				released = true
+				for _, t := range plan.Tasks {
+					if t.Phase != config.PhaseTeardown || queued[t.Name] {
+						continue
+					}
+					remaining[t.Name] = 0
+					for _, dep := range t.Dependencies {
+						if !finished[dep] {
+							remaining[t.Name]++
+						}
+					}
+					if remaining[t.Name] == 0 {
+						push(t.Name)
+					}
+				}
+			}
+		}
 
-				// Increment completed count AFTER task execution
-				completedTasks.Add(1)
+		// Offer the next ready task to the pool; a nil channel never sends
+		var send chan taskStart
+		var next taskStart
+		if ready.Len() > 0 {
+			send, next = work, taskStart{task: taskMap[ready.Peek()], num: started + 1}
+		}
+		if send == nil && running == 0 {
+			break
+		}
 
-				resultsMu.Lock()
-				runResult.Tasks = append(runResult.Tasks, *taskResult)
-				resultsMu.Unlock()
+		select {
+		case send <- next:
+			ready.Pop()
+			running++
+			started++
+			if level := planner.LevelForTask(levels, next.task.Name); level >= 0 && !levelStarted[level] {
+				levelStarted[level] = true
+				e.bus.Publish(events.LevelStarted{Level: level, Total: len(levels), Tasks: levels[level].Tasks})
+			}
+		case d := <-done:
+			running--
+			finished[d.name] = true
+			e.addResult(runResult, d.result)
+			if d.err != nil {
+				failures = append(failures, d.err)
+				runResult.Success = false
+				if e.stopRun(ctx) {
+					stopping = true
+				}
+			}
 
-				if err != nil {
-					errChan <- err
+			// Dependents of a failed task still become ready: without
+			// fail-fast, executeTask skips them and their own dependents
+			for _, dependent := range plan.DAG.ReverseEdges[d.name] {
+				remaining[dependent]--
+				if remaining[dependent] == 0 {
+					push(dependent)
 				}
-			}(execTask)
+			}
 		}
+	}
+	close(work)
+	wg.Wait()
 
-		// Wait for all tasks in this level to complete
-		wg.Wait()
-		close(errChan)
+	// Report the budget rather than the cancellations it caused
+	if budgetErr := e.budget.err(); budgetErr != nil {
+		e.finishRun(ctx, runResult, budgetErr)
+		return runResult, budgetErr
+	}
+	if len(failures) == 0 && ctx.Err() != nil && len(runResult.Tasks) < totalTasks {
+		failures = append(failures, ctx.Err())
+	}
 
-		// Check for errors
-		var firstErr error
-		for err := range errChan {
-			if firstErr == nil {
-				firstErr = err
-			}
-			runResult.Success = false
-		}
+	err := failedRunError(failures)
+	if e.failFast && len(failures) > 0 {
+		err = failures[0]
+	}
+	err = e.finishRun(ctx, runResult, err)
+	return runResult, err
+}
+
+// finishTask publishes a finished task and returns the error that counts
+// against the run.
+func (e *Executor) finishTask(ctx context.Context, task planner.ExecutionTask, taskResult *state.TaskResult, err error, num, total int) error {
+	runErr := err
+	if err != nil {
+		runErr = e.taskFailed(ctx, task, err)
+	}
+	e.bus.Publish(events.TaskFinished{
+		Task:    task,
+		Result:  taskResult,
+		Err:     err,
+		Ignored: err != nil && runErr == nil,
+		Num:     num,
+		Total:   total,
+	})
+	return runErr
+}
+
+// taskFailed handles a task's error. A continue_on_error task's failure is
+// dropped; otherwise the task's dependents are blocked and the error is
+// returned. Cancellation is never dropped.
+func (e *Executor) taskFailed(ctx context.Context, task planner.ExecutionTask, err error) error {
+	if task.ContinueOnError && ctx.Err() == nil {
+		return nil
+	}
+
+	e.outputsMu.Lock()
+	e.blocked[task.Name] = task.Name
+	e.outputsMu.Unlock()
+	return err
+}
+
+// stopRun reports whether a failure ends the run: always with fail-fast,
+// and when the run was cancelled or its budget exceeded.
+func (e *Executor) stopRun(ctx context.Context) bool {
+	return e.failFast || ctx.Err() != nil || e.budget.err() != nil
+}
+
+// taskContext returns the context a task runs in. Teardown tasks run even
+// after the run was cancelled, so only Abort reaches them.
+func (e *Executor) taskContext(ctx context.Context, task planner.ExecutionTask) context.Context {
+	if task.Phase == config.PhaseTeardown {
+		return e.aborted
+	}
+	return ctx
+}
 
-		if firstErr != nil {
-			runResult.EndTime = time.Now()
-			_ = e.store.SaveRunResult(runResult)
-			return runResult, firstErr
+// failedRunError summarizes the failures of a run that kept going.
+func failedRunError(failures []error) error {
+	switch len(failures) {
+	case 0:
+		return nil
+	case 1:
+		return failures[0]
+	default:
+		return fmt.Errorf("%d tasks failed, first: %w", len(failures), failures[0])
+	}
+}
+
+// blockedBy returns the failed task that keeps a task with these
+// dependencies from running, or "" if it may run.
+func (e *Executor) blockedBy(deps []string) string {
+	e.outputsMu.RLock()
+	defer e.outputsMu.RUnlock()
+
+	for _, dep := range deps {
+		if failed, ok := e.blocked[dep]; ok {
+			return failed
 		}
 	}
+	return ""
+}
+
+// startRun creates the result of a run starting now.
+func (e *Executor) startRun(plan *planner.ExecutionPlan) *state.RunResult {
+	runResult := &state.RunResult{
+		RunID:     e.store.RunID(),
+		User:      e.user,
+		StartTime: time.Now(),
+		Tasks:     make([]state.TaskResult, 0, len(plan.Tasks)),
+		Success:   true,
+	}
 
+	e.runMu.Lock()
+	e.run = runResult
+	e.runMu.Unlock()
+	return runResult
+}
+
+// finishRun pushes the changes of a successful run if the git settings ask
+// for it, then records the end time and totals of the run and publishes it.
+// A run whose context was cancelled other than by its budget was
+// interrupted. It returns the run's error, which a failed push becomes.
+func (e *Executor) finishRun(ctx context.Context, runResult *state.RunResult, err error) error {
+	if err == nil {
+		err = e.pushGit(ctx)
+	}
 	runResult.EndTime = time.Now()
-	_ = e.store.SaveRunResult(runResult)
+	runResult.CalculateTotalTokens()
+	if err != nil {
+		runResult.Success = false
+		runResult.Error = err.Error()
+	}
+	runResult.Cancelled = ctx.Err() != nil && e.budget.err() == nil
+	e.bus.Publish(events.RunFinished{Result: runResult, Err: err})
+
+	e.runMu.Lock()
+	close(e.runSaved)
+	e.runMu.Unlock()
+	return err
+}
+
+// chargeBudget adds a finished task's usage to the run budget. The first time
+// the budget is exceeded it publishes BudgetExceeded and cancels the
+// remaining tasks.
+func (e *Executor) chargeBudget(taskResult *state.TaskResult) error {
+	exceeded := e.budget.add(taskResult.TaskName, taskResult.TokenUsage.TotalTokens, taskResult.CostUSD)
+	if exceeded == nil {
+		return nil
+	}
+
+	e.bus.Publish(events.BudgetExceeded{
+		Task:       exceeded.Task,
+		Tokens:     exceeded.Tokens,
+		CostUSD:    exceeded.CostUSD,
+		MaxTokens:  exceeded.Budget.MaxTokens,
+		MaxCostUSD: exceeded.Budget.MaxCostUSD,
+		Message:    exceeded.Error(),
+	})
+	if e.cancelRun != nil {
+		e.cancelRun()
+	}
+	return exceeded
+}
+
+// executeTask executes a single task and returns its result. num, total,
+// and level describe the task in its TaskStarted event.
+func (e *Executor) executeTask(ctx context.Context, execTask planner.ExecutionTask, num, total, level int) (*state.TaskResult, error) {
+	// Without fail-fast, dependents of a failed task are skipped
+	if failed := e.blockedBy(execTask.Dependencies); failed != "" {
+		taskResult := state.NewTaskResult(execTask.Name, execTask.AgentName, execTask.Tool, execTask.Model, "")
+		taskResult.Skip("dependency "+failed+" failed", "")
+		e.recordOutput(execTask.Name, "", state.StatusSkipped)
+
+		e.outputsMu.Lock()
+		e.blocked[execTask.Name] = failed
+		e.outputsMu.Unlock()
+		return taskResult, nil
+	}
 
-	return runResult, nil
+	// Evaluate the task's condition before anything else
+	if execTask.When != "" {
+		run, err := e.evaluateCondition(execTask.When)
+		if err != nil {
+			taskResult := state.NewTaskResult(execTask.Name, execTask.AgentName, execTask.Tool, execTask.Model, "")
+			taskResult.Complete("", err.Error(), 1, false)
+			e.recordOutput(execTask.Name, "", state.StatusFailed)
+			return taskResult, fmt.Errorf("task %q: invalid when condition: %w", execTask.Name, err)
+		}
+		if !run {
+			taskResult := state.NewTaskResult(execTask.Name, execTask.AgentName, execTask.Tool, execTask.Model, "")
+			taskResult.Skip("condition not met: "+execTask.When, "")
+			e.recordOutput(execTask.Name, "", state.StatusSkipped)
+			return taskResult, nil
+		}
+	}
+
+	// Loop tasks re-run until their condition holds
+	if execTask.Until != "" {
+		return e.executeLoop(ctx, execTask, num, total, level)
+	}
+	return e.runTask(ctx, execTask, loopState{}, num, total, level)
 }
 
-// executeTask executes a single task and returns its result.
-func (e *Executor) executeTask(ctx context.Context, execTask planner.ExecutionTask) (*state.TaskResult, error) {
+// runTask runs a task once, as one iteration of loop if it is a loop task.
+func (e *Executor) runTask(ctx context.Context, execTask planner.ExecutionTask, loop loopState, num, total, level int) (*state.TaskResult, error) {
 	// Get the agent adapter
 	agent := e.registry.Get(execTask.Tool)
 	if agent == nil {
 		taskResult := state.NewTaskResult(execTask.Name, execTask.AgentName, execTask.Tool, execTask.Model, "")
 		taskResult.Complete("", fmt.Sprintf("no adapter for tool %q", execTask.Tool), 1, false)
-		_ = e.store.SaveTaskResult(taskResult)
-		ui.PrintTaskStatus("Failed", false, "0s")
 		return taskResult, fmt.Errorf("no adapter registered for tool %q", execTask.Tool)
 	}
 
-	// Expand template variables in prompt
+	// Provision the task's cache directory, kept across runs
+	cacheDir, err := e.store.TaskCacheDir(execTask.Name)
+	if err != nil {
+		ui.Warning("Task %s: %s", execTask.Name, err)
+		slog.Warn("cache directory unavailable", "task", execTask.Name, "error", err)
+	} else {
+		// Leave no empty directories behind for tasks that don't use it
+		defer func() { _ = os.Remove(cacheDir) }()
+	}
+
+	// Expand template variables in prompt, rendering template tags first
 	e.outputsMu.RLock()
-	expandedPrompt := config.ExpandPrompt(execTask.Prompt, e.outputs)
+	var tmplEnv config.ExprEnv
+	var tmplErr error
+	expandOutputs := func(text string) string {
+		if strings.Contains(text, "{{") {
+			if tmplEnv == nil {
+				tmplEnv = e.templateEnv(execTask.Env, cacheDir)
+			}
+			rendered, err := config.RenderTemplate(text, tmplEnv)
+			if err != nil && tmplErr == nil {
+				tmplErr = err
+			}
+			if err == nil {
+				text = rendered
+			}
+		}
+		text = config.ExpandPrompt(config.ExpandBuiltins(text, e.builtins), e.outputs)
+		text = config.ExpandCacheDir(config.ExpandArtifacts(text, e.artifacts), cacheDir)
+		return config.ExpandLoopVars(text, loop.iteration, loop.previous)
+	}
+	expandedPrompt := expandOutputs(execTask.Prompt)
+	if loop.iteration > 1 && usesPrompt(execTask.Tool) && !config.UsesLoopOutput(execTask.Prompt) {
+		expandedPrompt = config.LoopPrompt(expandedPrompt, loop.iteration, loop.previous)
+	}
+	var httpReq *config.HTTPConfig
+	if execTask.HTTP != nil {
+		httpReq = execTask.HTTP.Expand(expandOutputs)
+	}
+	var gitOp *config.GitConfig
+	if execTask.Git != nil {
+		gitOp = execTask.Git.Expand(expandOutputs)
+	}
+	var pollCheck *config.PollConfig
+	if execTask.Poll != nil {
+		pollCheck = execTask.Poll.Expand(expandOutputs)
+	}
+	var pullRequest *config.PullRequestConfig
+	if execTask.PullRequest != nil {
+		pullRequest = execTask.PullRequest.Expand(expandOutputs)
+	}
+	var changes *taskChanges
+	if execTask.ReviewOf != "" {
+		changes = e.changes[execTask.ReviewOf]
+	}
 	e.outputsMu.RUnlock()
+	if tmplErr != nil {
+		taskResult := state.NewTaskResult(execTask.Name, execTask.AgentName, execTask.Tool, execTask.Model, "")
+		taskResult.Complete("", "template: "+tmplErr.Error(), 1, false)
+		e.recordOutput(execTask.Name, "", state.StatusFailed)
+		return taskResult, fmt.Errorf("task %q: template: %w", execTask.Name, tmplErr)
+	}
+
+	// Review tasks get the reviewed task's changes, if it made any
+	if execTask.ReviewOf != "" {
+		if changes == nil || changes.diff == "" {
+			taskResult := state.NewTaskResult(execTask.Name, execTask.AgentName, execTask.Tool, execTask.Model, "")
+			taskResult.Skip("no changes to review", "")
+			e.recordOutput(execTask.Name, "", state.StatusSkipped)
+			return taskResult, nil
+		}
+		expandedPrompt = reviewPrompt(expandedPrompt, changes)
+	}
+
+	// Ask AI agents for just JSON when the task's output must be JSON
+	if execTask.OutputFormat == config.OutputJSON && usesPrompt(execTask.Tool) {
+		expandedPrompt = config.JSONOutputPrompt(expandedPrompt, execTask.OutputSchema)
+	}
 
 	// Create task for execution
 	task := Task{
-		Name:    execTask.Name,
-		Agent:   execTask.AgentName,
-		Tool:    execTask.Tool,
-		Model:   execTask.Model,
-		Prompt:  expandedPrompt,
-		Write:   execTask.Write,
-		Workdir: execTask.Workdir,
+		Name:         execTask.Name,
+		Agent:        execTask.AgentName,
+		Tool:         execTask.Tool,
+		Model:        execTask.Model,
+		Prompt:       expandedPrompt,
+		SystemPrompt: expandOutputs(execTask.SystemPrompt),
+		Write:        execTask.Write,
+		Permissions:  execTask.Permissions,
+		Limits:       execTask.Limits,
+		MCPServers:   execTask.MCPServers,
+		Sandbox:      execTask.Sandbox,
+		Workdir:      execTask.Workdir,
+		ScriptLang:   execTask.ScriptLang,
+		Shell:        execTask.Shell,
+		BaseURL:      execTask.BaseURL,
+		APIKeyEnv:    execTask.APIKeyEnv,
+		Proxy:        execTask.Proxy,
+		TLS:          execTask.TLS,
+		Env:          e.taskEnv(execTask.Env, cacheDir),
+		HTTP:         httpReq,
+		Git:          gitOp,
+		Wait:         execTask.Wait,
+		Poll:         pollCheck,
+		Notify:       execTask.Notify,
+		PullRequest:  pullRequest,
+		ShowOutput:   execTask.ShowOutput,
+		KillTimeout:  e.killTimeout,
+		MaxOutput:    e.maxOutput,
+	}
+	if limit := execTask.Limits.Output; limit > 0 && (task.MaxOutput == 0 || limit < task.MaxOutput) {
+		task.MaxOutput = limit
 	}
 
 	// Create result tracker
@@ -244,17 +733,133 @@ func (e *Executor) executeTask(ctx context.Context, execTask planner.ExecutionTa
 		expandedPrompt,
 	)
 
-	// Execute the task
+	// In incremental mode, reuse the previous output if nothing changed
+	hash := taskHash(task)
+	if e.cache != nil {
+		if entry, ok := e.cache.Get(execTask.Name); ok && entry.Hash == hash {
+			if upToDate, _ := outputsUpToDate(execTask.Inputs, execTask.Outputs); upToDate {
+				taskResult.Skip("up to date", entry.Stdout)
+				e.recordOutput(execTask.Name, entry.Stdout, state.StatusSkipped)
+				return taskResult, nil
+			}
+		}
+	}
+
+	if fault, ok := e.faultFor(execTask.Name); ok {
+		agent = faultyAgent{agent: agent, fault: fault}
+	}
+
+	// Let prompt hooks transform the prompt of AI tasks
+	if usesPrompt(task.Tool) && (len(e.promptHooks) > 0 || len(execTask.PromptHooks) > 0) {
+		prompt, hooks, err := e.transformPrompt(ctx, task, execTask.PromptHooks)
+		if err != nil {
+			taskResult.Complete("", err.Error(), 1, false)
+			e.recordOutput(execTask.Name, "", state.StatusFailed)
+			return taskResult, fmt.Errorf("task %q: %w", execTask.Name, err)
+		}
+		if prompt != task.Prompt {
+			taskResult.OriginalPrompt = task.Prompt
+			task.Prompt, taskResult.Prompt = prompt, prompt
+		}
+		taskResult.PromptHooks = hooks
+	}
+
+	// Keep other tasks from changing the working tree while it's diffed,
+	// reverted, or committed, which would mix their changes in
+	if e.runsAlone(execTask) {
+		e.treeMu.Lock()
+		defer e.treeMu.Unlock()
+	} else {
+		e.treeMu.RLock()
+		defer e.treeMu.RUnlock()
+	}
+
+	// Snapshot the working tree to find what the task changes, for its
+	// review or to report the files a write task changed
+	var snapshot *taskChanges
+	if execTask.ReviewChanges {
+		if snapshot, err = snapshotChanges(ctx, execTask.Workdir); err != nil {
+			taskResult.Complete("", err.Error(), 1, false)
+			e.recordOutput(execTask.Name, "", state.StatusFailed)
+			return taskResult, fmt.Errorf("task %q: %w", execTask.Name, err)
+		}
+	} else if execTask.Write {
+		if snapshot, err = trackChanges(ctx, execTask.Workdir); err != nil {
+			slog.Debug("not tracking changes", "task", execTask.Name, "error", err)
+		}
+	}
+
+	// Execute the task, publishing its streamed output. Iterations of a loop
+	// task after the first continue its output.
+	if loop.iteration <= 1 {
+		e.bus.Publish(events.TaskStarted{Task: execTask, Num: num, Total: total, Level: level})
+	}
+	task.Stdout = e.bus.Writer(execTask.Name, events.Stdout)
+	task.Stderr = e.bus.Writer(execTask.Name, events.Stderr)
+	taskLog := e.openTaskLog(&task, taskResult, loop.iteration)
+	e.trackTask(taskResult)
 	result, err := agent.Run(ctx, task)
+	e.untrackTask(execTask.Name)
+	closeTaskLog(taskLog, result)
+	if ctx.Err() != nil && (err != nil || !result.Success) {
+		// Keep the output the task produced before it was stopped. Adapters
+		// stopped before collecting their output left it in the task log.
+		if result.Stdout == "" && result.Stderr == "" && taskLog != nil {
+			result.Stdout, _ = e.store.ReadTaskLog(taskLog)
+		}
+		capOutput(&result, task.MaxOutput)
+		taskResult.Truncated = result.Truncated
+		taskResult.Cancel(result.Stdout, result.Stderr)
+		e.recordOutput(execTask.Name, result.Stdout, state.StatusCancelled)
+		return taskResult, fmt.Errorf("task %q cancelled: %w", execTask.Name, ctx.Err())
+	}
 	if err != nil {
 		taskResult.Complete("", err.Error(), 1, false)
-		_ = e.store.SaveTaskResult(taskResult)
-		ui.PrintTaskStatus("Failed", false, taskResult.Duration)
-		if e.verbose {
-			fmt.Fprintf(e.writer, "  %sError:%s %s\n", ui.Dim, ui.Reset, err)
-		}
+		e.recordOutput(execTask.Name, "", state.StatusFailed)
 		return taskResult, fmt.Errorf("task %q failed: %w", execTask.Name, err)
 	}
+	capOutput(&result, task.MaxOutput)
+
+	// Check JSON output, keeping just the JSON for dependent tasks
+	var checkErr error
+	if result.Success && execTask.OutputFormat == config.OutputJSON {
+		var output string
+		if output, checkErr = config.ParseJSONOutput(result.Stdout, execTask.OutputSchema); checkErr == nil {
+			result.Stdout = output
+		}
+	}
+
+	// Record the changes for the task result and the review, or apply the
+	// review's verdict
+	if snapshot != nil {
+		changesErr := snapshot.finish(ctx)
+		if changesErr == nil {
+			e.recordChanges(execTask.Name, snapshot, taskResult)
+		}
+		if execTask.ReviewChanges && result.Success && checkErr == nil {
+			checkErr = changesErr
+			if checkErr == nil {
+				e.outputsMu.Lock()
+				e.changes[execTask.Name] = snapshot
+				e.outputsMu.Unlock()
+			}
+		} else if changesErr != nil {
+			slog.Warn("failed to record changes", "task", execTask.Name, "error", changesErr)
+		}
+	}
+	if result.Success && checkErr == nil && execTask.ReviewOf != "" {
+		checkErr = e.checkVerdict(ctx, execTask, result.Stdout)
+	}
+
+	// Commit the approved or unreviewed changes
+	if result.Success && checkErr == nil && e.commitsAfter(execTask) {
+		checkErr = e.commitTask(ctx, execTask, taskResult)
+	}
+	if checkErr != nil {
+		result.Success = false
+		result.ExitCode = 1
+		result.Stderr += checkErr.Error() + "\n"
+	}
 
 	// Complete the task result
 	taskResult.Complete(result.Stdout, result.Stderr, result.ExitCode, result.Success)
@@ -263,42 +868,218 @@ func (e *Executor) executeTask(ctx context.Context, execTask planner.ExecutionTa
 	if result.InputTokens > 0 || result.OutputTokens > 0 {
 		taskResult.SetTokenUsage(result.InputTokens, result.OutputTokens, result.CacheRead, result.CacheWrite)
 	}
+	taskResult.CostUSD = result.CostUSD
+	taskResult.SetToolCalls(result.ToolCalls)
+	taskResult.Truncated = result.Truncated
 
-	// Save task result
-	if err := e.store.SaveTaskResult(taskResult); err != nil {
-		ui.Warning("Failed to save result: %s", err)
+	if len(execTask.Artifacts) > 0 {
+		e.collectArtifacts(execTask.Name, execTask.Artifacts, taskResult)
 	}
 
 	// Store output for template expansion in dependent tasks
+	e.recordOutput(execTask.Name, result.Stdout, taskResult.Status)
+
+	if result.Success && e.cache != nil {
+		e.cache.Put(execTask.Name, state.TaskCacheEntry{
+			Hash:      hash,
+			Stdout:    redact.Secrets(result.Stdout, config.SecretValues(e.secrets)),
+			RunID:     e.store.RunID(),
+			UpdatedAt: time.Now(),
+		})
+	}
+
+	if checkErr != nil {
+		return taskResult, fmt.Errorf("task %q: %w", execTask.Name, checkErr)
+	}
+	if !result.Success {
+		return taskResult, fmt.Errorf("task %q failed with exit code %d", execTask.Name, result.ExitCode)
+	}
+	return taskResult, nil
+}
+
+// runsAlone reports whether a task runs while no other task does, as what
+// it changed in the working tree is found by comparing the tree before and
+// after it: write tasks, whose changes are tracked, reviewed, or committed,
+// and reviews, which revert the changes they reject. Otherwise the changes of
+// the others would be counted, undone, or committed with its own.
+func (e *Executor) runsAlone(task planner.ExecutionTask) bool {
+	return task.Write || task.ReviewChanges || task.ReviewOf != ""
+}
+
+// evaluateCondition parses a `when` expression and evaluates it against the
+// outputs and statuses of completed tasks.
+func (e *Executor) evaluateCondition(when string) (bool, error) {
+	cond, err := config.ParseCondition(when)
+	if err != nil {
+		return false, err
+	}
+
+	e.outputsMu.RLock()
+	defer e.outputsMu.RUnlock()
+	return cond.Check(e.outputs, e.statuses)
+}
+
+// recordOutput stores a task's output and status for dependent tasks.
+func (e *Executor) recordOutput(name, stdout, status string) {
 	e.outputsMu.Lock()
-	e.outputs[execTask.Name] = result.Stdout
-	e.outputsMu.Unlock()
+	defer e.outputsMu.Unlock()
 
-	if result.Success {
-		if result.InputTokens > 0 || result.OutputTokens > 0 {
-			ui.PrintTaskStatusWithTokens("Success", true, taskResult.Duration, result.InputTokens, result.OutputTokens)
-		} else {
-			ui.PrintTaskStatus("Success", true, taskResult.Duration)
-		}
+	e.outputs[name] = stdout
+	e.statuses[name] = status
+	e.updateGroupOutputs(name)
+}
+
+// recordChanges sets the files a task changed on its result and saves
+// their diff to the run directory.
+func (e *Executor) recordChanges(name string, c *taskChanges, taskResult *state.TaskResult) {
+	taskResult.Changes = c.files
+	if c.diff == "" {
+		return
+	}
+	file, err := e.store.SaveTaskDiff(name, c.diff)
+	if err != nil {
+		slog.Warn("failed to save diff", "task", name, "error", err)
+		return
+	}
+	taskResult.DiffFile = file
+}
+
+// collectArtifacts copies a task's artifacts into the run directory and
+// records them for the task result and {{artifacts.X}} references.
+func (e *Executor) collectArtifacts(name string, patterns []string, taskResult *state.TaskResult) {
+	saved, err := e.store.SaveArtifacts(name, patterns)
+	if err != nil {
+		ui.Warning("Failed to collect artifacts: %s", err)
+		slog.Warn("failed to collect artifacts", "task", name, "error", err)
+	} else if len(saved) == 0 {
+		ui.Warning("No files matched artifacts of task %q", name)
+		slog.Warn("no files matched artifacts", "task", name, "patterns", patterns)
 	} else {
-		if result.InputTokens > 0 || result.OutputTokens > 0 {
-			ui.PrintTaskStatusWithTokens("Failed", false, taskResult.Duration, result.InputTokens, result.OutputTokens)
-		} else {
-			ui.PrintTaskStatus("Failed", false, taskResult.Duration)
+		slog.Debug("artifacts collected", "task", name, "files", saved)
+	}
+	taskResult.Artifacts = saved
+
+	paths := make([]string, len(saved))
+	for i, rel := range saved {
+		paths[i] = filepath.Join(e.store.RunDir(), filepath.FromSlash(rel))
+	}
+
+	e.outputsMu.Lock()
+	defer e.outputsMu.Unlock()
+
+	e.artifacts[name] = paths
+	for group, instances := range e.groups {
+		var combined []string
+		member := false
+		for _, inst := range instances {
+			member = member || inst == name
+			combined = append(combined, e.artifacts[inst]...)
 		}
-		return taskResult, fmt.Errorf("task %q failed with exit code %d", execTask.Name, result.ExitCode)
+		if member {
+			e.artifacts[group] = combined
+		}
+	}
+}
+
+// taskEnv builds the KEY=VALUE environment for a task: its cache directory
+// (if any), all secrets, then the task's env entries with ${VAR} references
+// expanded from these or the process environment.
+func (e *Executor) taskEnv(env map[string]string, cacheDir string) []string {
+	if len(env) == 0 && len(e.secrets) == 0 && cacheDir == "" {
+		return nil
 	}
 
-	if e.verbose && result.Stdout != "" {
-		// Show first few lines of output in verbose mode
-		fmt.Fprintf(e.writer, "  %sOutput (truncated):%s\n", ui.Dim, ui.Reset)
-		lines := truncateLines(result.Stdout, 5)
-		for _, line := range lines {
-			fmt.Fprintf(e.writer, "    %s%s%s\n", ui.Dim, line, ui.Reset)
+	lookup := func(key string) string {
+		if key == state.CacheDirEnv && cacheDir != "" {
+			return cacheDir
 		}
+		if v, ok := e.secrets[key]; ok {
+			return v
+		}
+		return os.Getenv(key)
 	}
 
-	return taskResult, nil
+	vars := make([]string, 0, 1+len(e.secrets)+len(env))
+	if cacheDir != "" {
+		vars = append(vars, state.CacheDirEnv+"="+cacheDir)
+	}
+	for _, k := range sortedKeys(e.secrets) {
+		vars = append(vars, k+"="+e.secrets[k])
+	}
+	for _, k := range sortedKeys(env) {
+		vars = append(vars, k+"="+os.Expand(config.ExpandBuiltins(env[k], e.builtins), lookup))
+	}
+	return vars
+}
+
+// templateEnv returns the variables template expressions of a task read:
+// the results of the run so far, its vars and built-in variables, and the
+// task's environment. The caller holds outputsMu.
+func (e *Executor) templateEnv(env map[string]string, cacheDir string) config.ExprEnv {
+	taskEnv := make(map[string]string)
+	for _, kv := range e.taskEnv(env, cacheDir) {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			taskEnv[k] = v
+		}
+	}
+	return config.TemplateData{
+		Outputs:   e.outputs,
+		Statuses:  e.statuses,
+		Vars:      e.vars,
+		Env:       taskEnv,
+		Artifacts: e.artifacts,
+		Groups:    e.groups,
+		Builtins:  e.builtins,
+	}.ExprEnv()
+}
+
+// sortedKeys returns map keys in sorted order for a stable environment.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// initGroups registers fan-out groups so {{outputs.<task>}} resolves to the
+// combined output of all instances (empty until any instance completes).
+func (e *Executor) initGroups(groups map[string][]string) {
+	e.outputsMu.Lock()
+	defer e.outputsMu.Unlock()
+
+	e.groups = groups
+	for name := range groups {
+		if _, exists := e.outputs[name]; !exists {
+			e.outputs[name] = ""
+		}
+	}
+}
+
+// updateGroupOutputs refreshes the combined output of any fan-out group that
+// contains the given instance. Callers must hold outputsMu.
+func (e *Executor) updateGroupOutputs(instance string) {
+	for name, instances := range e.groups {
+		member := false
+		for _, inst := range instances {
+			if inst == instance {
+				member = true
+				break
+			}
+		}
+		if !member {
+			continue
+		}
+
+		var parts []string
+		for _, inst := range instances {
+			if output, ok := e.outputs[inst]; ok {
+				parts = append(parts, strings.TrimRight(output, "\n"))
+			}
+		}
+		e.outputs[name] = strings.Join(parts, "\n\n")
+	}
 }
 
 // truncateLines returns the first n lines of text.
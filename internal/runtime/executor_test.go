@@ -0,0 +1,302 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/state"
+)
+
+// fakeAgent runs tasks, recording the order in which they started and
+// finished and how many ran at once. Instead of sleeping, a task waits for
+// what its prompt names, so the tests don't depend on timing:
+//
+//	wait-start <task>   until the task has started
+//	wait-end <task>     until the task has finished
+//	wait-result <task>  until the scheduler has the task's result
+//	wait-running <n>    until n tasks have run at once
+//	fail                fail the task
+type fakeAgent struct {
+	mu      sync.Mutex
+	changed chan struct{} // Closed and replaced whenever a task starts or finishes
+	seq     int
+	start   map[string]int
+	end     map[string]int
+	running int
+	peak    int
+
+	recorded func(task string) bool // Reports whether the run has a task's result
+}
+
+func newFakeAgent() *fakeAgent {
+	return &fakeAgent{changed: make(chan struct{}), start: make(map[string]int), end: make(map[string]int)}
+}
+
+func (a *fakeAgent) Run(ctx context.Context, task Task) (Result, error) {
+	a.update(func() {
+		a.start[task.Name] = a.seq
+		a.running++
+		a.peak = max(a.peak, a.running)
+	})
+
+	var err error
+	words := strings.Fields(task.Prompt)
+	for i := 0; i+1 < len(words) && err == nil; i++ {
+		name := words[i+1]
+		switch words[i] {
+		case "wait-start":
+			err = a.waitFor(ctx, func() bool { _, ok := a.start[name]; return ok })
+		case "wait-end":
+			err = a.waitFor(ctx, func() bool { _, ok := a.end[name]; return ok })
+		case "wait-result":
+			err = a.waitFor(ctx, func() bool { return a.recorded(name) })
+		case "wait-running":
+			n, _ := strconv.Atoi(name)
+			err = a.waitFor(ctx, func() bool { return a.peak >= n })
+		}
+	}
+
+	a.update(func() {
+		a.end[task.Name] = a.seq
+		a.running--
+	})
+
+	if err != nil {
+		return Result{Stderr: err.Error(), ExitCode: 1}, nil
+	}
+	if slices.Contains(words, "fail") {
+		return Result{Stderr: "failed", ExitCode: 1}, nil
+	}
+	return Result{Stdout: task.Name + "\n", Success: true}, nil
+}
+
+// update changes the recorded state and wakes up the tasks waiting for it.
+func (a *fakeAgent) update(change func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.seq++
+	change()
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// waitFor waits until cond, called with the lock held, is true. It is
+// checked whenever a task starts or finishes, and every millisecond for what
+// the executor records.
+func (a *fakeAgent) waitFor(ctx context.Context, cond func() bool) error {
+	for {
+		a.mu.Lock()
+		ok, changed := cond(), a.changed
+		a.mu.Unlock()
+		if ok {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-time.After(time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (a *fakeAgent) ran(name string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.start[name]
+	return ok
+}
+
+// assertBefore checks that task first finished before task then started.
+func (a *fakeAgent) assertBefore(t *testing.T, first, then string) {
+	t.Helper()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	end, ok := a.end[first]
+	if !ok {
+		t.Fatalf("task %s did not run", first)
+	}
+	start, ok := a.start[then]
+	if !ok {
+		t.Fatalf("task %s did not run", then)
+	}
+	if start < end {
+		t.Errorf("task %s started before %s finished", then, first)
+	}
+}
+
+// runParallel runs the tasks of a Cortexfile with the parallel scheduler and
+// the fake agent.
+func runParallel(t *testing.T, yaml string, maxParallel int, failFast bool) (*fakeAgent, *state.RunResult, error) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	cfg, err := config.ParseConfig([]byte("agents:\n  fake:\n    tool: fake\n"+yaml), home)
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+	plan, err := planner.BuildPlan(cfg)
+	if err != nil {
+		t.Fatalf("BuildPlan() error = %v", err)
+	}
+	store, err := state.NewStoreWithPath(home, "project")
+	if err != nil {
+		t.Fatalf("NewStoreWithPath() error = %v", err)
+	}
+
+	agent := newFakeAgent()
+	registry := NewAgentRegistry()
+	registry.Register("fake", agent)
+	e := NewExecutorWithConfig(ExecutorConfig{
+		Registry:    registry,
+		Store:       store,
+		Writer:      io.Discard,
+		Parallel:    true,
+		MaxParallel: maxParallel,
+		FailFast:    failFast,
+		Project:     "project",
+	})
+	agent.recorded = func(task string) bool {
+		e.runMu.Lock()
+		defer e.runMu.Unlock()
+		return e.run != nil && slices.ContainsFunc(e.run.Tasks, func(r state.TaskResult) bool { return r.TaskName == task })
+	}
+
+	// A task waiting for one the scheduler never starts fails the test rather
+	// than hanging it
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := e.Execute(ctx, plan)
+	return agent, result, err
+}
+
+func taskStatuses(result *state.RunResult) map[string]string {
+	statuses := make(map[string]string, len(result.Tasks))
+	for _, task := range result.Tasks {
+		statuses[task.TaskName] = task.Status
+	}
+	return statuses
+}
+
+// TestExecuteParallel_Ordering tests that tasks start once their needs have
+// finished, without waiting for the rest of their level.
+func TestExecuteParallel_Ordering(t *testing.T) {
+	// c and slow only finish once after-b has started and d has finished,
+	// which they would never do if the scheduler waited for them
+	agent, result, err := runParallel(t, `
+tasks:
+  a: {agent: fake, prompt: "a"}
+  b: {agent: fake, prompt: "b", needs: [a]}
+  c: {agent: fake, prompt: "wait-start after-b", needs: [a]}
+  d: {agent: fake, prompt: "d", needs: [b, c]}
+  slow: {agent: fake, prompt: "wait-end d"}
+  after-b: {agent: fake, prompt: "after-b", needs: [b]}
+`, 0, true)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !result.Success || len(result.Tasks) != 6 {
+		t.Fatalf("Execute() success = %v with %d tasks, want success with 6", result.Success, len(result.Tasks))
+	}
+
+	agent.assertBefore(t, "a", "b")
+	agent.assertBefore(t, "a", "c")
+	agent.assertBefore(t, "b", "d")
+	agent.assertBefore(t, "c", "d")
+	agent.assertBefore(t, "b", "after-b")
+}
+
+// TestExecuteParallel_Failure tests how a failure reaches the tasks that
+// haven't started, with and without fail-fast. slow is running when broken
+// fails, and finishes once the scheduler knows.
+func TestExecuteParallel_Failure(t *testing.T) {
+	const yaml = `
+tasks:
+  broken: {agent: fake, prompt: "wait-start slow fail"}
+  dependent: {agent: fake, prompt: "dependent", needs: [broken]}
+  transitive: {agent: fake, prompt: "transitive", needs: [dependent]}
+  slow: {agent: fake, prompt: "wait-result broken"}
+  after-slow: {agent: fake, prompt: "after-slow", needs: [slow]}
+`
+
+	t.Run("fail fast", func(t *testing.T) {
+		agent, result, err := runParallel(t, yaml, 0, true)
+		if err == nil || !strings.Contains(err.Error(), "broken") {
+			t.Fatalf("Execute() error = %v, want the failure of broken", err)
+		}
+		if result.Success {
+			t.Error("Execute() succeeded, want failure")
+		}
+		for _, name := range []string{"dependent", "transitive", "after-slow"} {
+			if agent.ran(name) {
+				t.Errorf("task %s started after the failure", name)
+			}
+		}
+		// Tasks already running finish
+		if status := taskStatuses(result)["slow"]; status != state.StatusSuccess {
+			t.Errorf("slow status = %q, want %q", status, state.StatusSuccess)
+		}
+	})
+
+	t.Run("continue on error", func(t *testing.T) {
+		agent, result, err := runParallel(t, yaml, 0, false)
+		if err == nil {
+			t.Fatal("Execute() error = nil, want the failure of broken")
+		}
+		want := map[string]string{
+			"broken":     state.StatusFailed,
+			"dependent":  state.StatusSkipped,
+			"transitive": state.StatusSkipped,
+			"slow":       state.StatusSuccess,
+			"after-slow": state.StatusSuccess,
+		}
+		got := taskStatuses(result)
+		for name, status := range want {
+			if got[name] != status {
+				t.Errorf("%s status = %q, want %q", name, got[name], status)
+			}
+		}
+		if agent.ran("dependent") || agent.ran("transitive") {
+			t.Error("dependents of the failed task ran")
+		}
+	})
+}
+
+// TestExecuteParallel_MaxParallel tests that no more than max_parallel tasks
+// run at once, and that the pool is kept full: each task waits until as many
+// tasks as allowed have run at once.
+func TestExecuteParallel_MaxParallel(t *testing.T) {
+	for _, limit := range []int{1, 2, 4} {
+		t.Run(fmt.Sprintf("max %d", limit), func(t *testing.T) {
+			var yaml strings.Builder
+			yaml.WriteString("tasks:\n")
+			for i := range 6 {
+				fmt.Fprintf(&yaml, "  t%d: {agent: fake, prompt: \"wait-running %d\"}\n", i, limit)
+			}
+
+			agent, result, err := runParallel(t, yaml.String(), limit, true)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if len(result.Tasks) != 6 {
+				t.Fatalf("Execute() ran %d tasks, want 6", len(result.Tasks))
+			}
+			if agent.peak != limit {
+				t.Errorf("peak concurrency = %d, want %d", agent.peak, limit)
+			}
+		})
+	}
+}
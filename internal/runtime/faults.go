@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Fault simulates a misbehaving adapter, so a workflow's failure handling
+// (continue_on_error, fail_fast, teardown tasks, notifications, webhooks) can
+// be tried out without breaking anything for real.
+type Fault struct {
+	Task     string        // Task the fault applies to; empty for every task
+	Fail     bool          // Fail the task instead of running its agent
+	ExitCode int           // Exit code of the failure (0 = 1)
+	Latency  time.Duration // Delay before the agent runs
+}
+
+// String describes the fault.
+func (f Fault) String() string {
+	target := "every task"
+	if f.Task != "" {
+		target = "task " + f.Task
+	}
+	switch {
+	case f.Fail && f.Latency > 0:
+		return fmt.Sprintf("%s fails after %s", target, f.Latency)
+	case f.Fail:
+		return target + " fails"
+	default:
+		return fmt.Sprintf("%s is delayed by %s", target, f.Latency)
+	}
+}
+
+// faultFor combines the faults that apply to a task: it fails if any of
+// them fails it, with the exit code of the last, after the longest delay.
+func (e *Executor) faultFor(name string) (Fault, bool) {
+	var fault Fault
+	found := false
+	for _, f := range e.faults {
+		if f.Task != "" && f.Task != name {
+			continue
+		}
+		found = true
+		if f.Fail {
+			fault.Fail, fault.ExitCode = true, f.ExitCode
+		}
+		fault.Latency = max(fault.Latency, f.Latency)
+	}
+	fault.Task = name
+	return fault, found
+}
+
+// faultyAgent injects a fault in front of an agent.
+type faultyAgent struct {
+	agent Agent
+	fault Fault
+}
+
+// Run implements Agent.
+func (a faultyAgent) Run(ctx context.Context, task Task) (Result, error) {
+	if a.fault.Latency > 0 {
+		timer := time.NewTimer(a.fault.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return Result{ExitCode: -1}, nil
+		}
+	}
+
+	if a.fault.Fail {
+		exitCode := a.fault.ExitCode
+		if exitCode == 0 {
+			exitCode = 1
+		}
+		msg := "injected failure (--inject-failure)"
+		fmt.Fprintln(task.ErrOut(), msg)
+		return Result{Stderr: msg + "\n", ExitCode: exitCode}, nil
+	}
+	return a.agent.Run(ctx, task)
+}
@@ -0,0 +1,81 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/git"
+	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/state"
+)
+
+// startGit opens the repository of the workflow's git settings and creates
+// the run's branch, if they set one.
+func (e *Executor) startGit(ctx context.Context) error {
+	repo, err := git.Open(ctx, e.gitDir)
+	if err != nil {
+		return err
+	}
+	e.repo = repo
+	if e.git.Branch == "" {
+		return nil
+	}
+	branch := config.ExpandBuiltins(e.git.Branch, e.builtins)
+	if err := repo.CreateBranch(ctx, branch); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", branch, err)
+	}
+	slog.Info("created run branch", "branch", branch, "repo", repo.Dir())
+	return nil
+}
+
+// commitsAfter reports whether the changes of a task are committed once it
+// succeeds: those of write tasks without a review, and, after a review
+// approves them, those of the reviewed task.
+func (e *Executor) commitsAfter(task planner.ExecutionTask) bool {
+	if e.repo == nil || !e.git.Commit {
+		return false
+	}
+	return task.ReviewOf != "" || (task.Write && !task.ReviewChanges)
+}
+
+// commitTask commits the changes in the repository after a task and
+// records the commit on its result. The commit of a review is named after
+// the reviewed task.
+func (e *Executor) commitTask(ctx context.Context, task planner.ExecutionTask, taskResult *state.TaskResult) error {
+	name, agent, changes := task.Name, task.AgentName, taskResult.ChangeSummary()
+	if task.ReviewOf != "" {
+		name = task.ReviewOf
+		e.runMu.Lock()
+		for _, r := range e.run.Tasks {
+			if r.TaskName == task.ReviewOf {
+				agent, changes = r.Agent, r.ChangeSummary()
+			}
+		}
+		e.runMu.Unlock()
+	}
+
+	sha, err := e.repo.CommitAll(ctx, e.git.CommitMessage(e.builtins, name, agent, changes))
+	if err != nil {
+		return fmt.Errorf("failed to commit changes: %w", err)
+	}
+	if sha != "" {
+		taskResult.Commit = sha
+		slog.Info("committed task changes", "task", name, "commit", sha)
+	}
+	return nil
+}
+
+// pushGit pushes the run's branch, if the git settings ask for it.
+func (e *Executor) pushGit(ctx context.Context) error {
+	if e.repo == nil || !e.git.Push {
+		return nil
+	}
+	pushed, err := e.repo.Push(ctx, e.git.Remote)
+	if err != nil {
+		return fmt.Errorf("failed to push changes: %w", err)
+	}
+	slog.Info("pushed run branch", "branch", pushed)
+	return nil
+}
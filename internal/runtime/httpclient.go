@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/adityaraj/agentflow/internal/config"
+)
+
+// maxIdleConnsPerHost is how many idle connections each client keeps per
+// host, enough for parallel tasks calling the same endpoint to reuse them.
+const maxIdleConnsPerHost = 16
+
+// HTTPClients hands out HTTP clients for HTTP-based adapters. Tasks with the
+// same proxy and TLS settings share a client, and with it a pool of open
+// connections.
+type HTTPClients struct {
+	mu      sync.Mutex
+	clients map[httpClientKey]*http.Client
+}
+
+type httpClientKey struct {
+	proxy string
+	tls   config.TLSConfig
+}
+
+// NewHTTPClients creates an empty client cache.
+func NewHTTPClients() *HTTPClients {
+	return &HTTPClients{clients: make(map[httpClientKey]*http.Client)}
+}
+
+// Get returns the client for a proxy URL (empty uses the HTTPS_PROXY and
+// HTTP_PROXY environment variables) and TLS settings (nil for the defaults).
+// No timeout is set; requests are bounded by their context.
+func (c *HTTPClients) Get(proxy string, tlsCfg *config.TLSConfig) (*http.Client, error) {
+	key := httpClientKey{proxy: proxy}
+	if tlsCfg != nil {
+		key.tls = *tlsCfg
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[key]; ok {
+		return client, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if tlsCfg != nil {
+		clientTLS, err := buildTLSConfig(tlsCfg)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = clientTLS
+	}
+
+	client := &http.Client{Transport: transport}
+	c.clients[key] = client
+	return client, nil
+}
+
+func buildTLSConfig(cfg *config.TLSConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in ca_file %s", cfg.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
@@ -0,0 +1,83 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// taskHash identifies a task execution by tool, model, expanded prompts, and
+// the request or operation of built-in tasks.
+func taskHash(task Task) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s", task.Tool, task.Model, task.ScriptLang, task.Prompt)
+	if task.SystemPrompt != "" {
+		fmt.Fprintf(h, "\x00%s", task.SystemPrompt)
+	}
+	if task.HTTP != nil {
+		fmt.Fprintf(h, "\x00%s\x00%s\x00%s\x00%d", task.HTTP.Method, task.HTTP.URL, task.HTTP.Body, task.HTTP.ExpectStatus)
+		keys := make([]string, 0, len(task.HTTP.Headers))
+		for key := range task.HTTP.Headers {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(h, "\x00%s: %s", key, task.HTTP.Headers[key])
+		}
+	}
+	if task.Git != nil {
+		fmt.Fprintf(h, "\x00%s\x00%s", task.Git.Op, task.Git.Text())
+	}
+	if task.Poll != nil {
+		fmt.Fprintf(h, "\x00%s", task.Poll.Text())
+	}
+	if task.PullRequest != nil {
+		fmt.Fprintf(h, "\x00%s", task.PullRequest.Text())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// outputsUpToDate reports whether every declared output exists and is newer
+// than every declared input, make-style. Tasks without outputs are never up
+// to date. Returns a reason describing why the task must run otherwise.
+func outputsUpToDate(inputs, outputs []string) (bool, string) {
+	if len(outputs) == 0 {
+		return false, "no outputs declared"
+	}
+
+	var oldestOutput time.Time
+	for _, out := range outputs {
+		info, err := os.Stat(out)
+		if err != nil {
+			return false, fmt.Sprintf("output %s missing", filepath.Base(out))
+		}
+		if oldestOutput.IsZero() || info.ModTime().Before(oldestOutput) {
+			oldestOutput = info.ModTime()
+		}
+	}
+
+	for _, pattern := range inputs {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return false, fmt.Sprintf("invalid input pattern %s", pattern)
+		}
+		if len(matches) == 0 {
+			return false, fmt.Sprintf("input %s missing", filepath.Base(pattern))
+		}
+		for _, in := range matches {
+			info, err := os.Stat(in)
+			if err != nil {
+				return false, fmt.Sprintf("input %s unreadable", filepath.Base(in))
+			}
+			if !info.ModTime().Before(oldestOutput) {
+				return false, fmt.Sprintf("input %s changed", filepath.Base(in))
+			}
+		}
+	}
+
+	return true, ""
+}
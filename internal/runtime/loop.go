@@ -0,0 +1,79 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// loopState is the iteration of a loop task a run is, for {{loop.X}}.
+// The zero value is a task that doesn't loop.
+type loopState struct {
+	iteration int    // Number of the run, from 1
+	previous  string // Output of the previous run
+}
+
+// executeLoop runs a task with `until` until the condition holds after a
+// run, or max_iterations runs. A failed run doesn't end the loop, so the
+// condition can wait for the task to succeed; the task fails if the
+// condition never holds. The result is that of the last run, with the
+// usage of all runs.
+func (e *Executor) executeLoop(ctx context.Context, execTask planner.ExecutionTask, num, total, level int) (*state.TaskResult, error) {
+	var usage state.TokenUsage
+	var cost float64
+	var previous string
+	var start time.Time
+	for i := 1; ; i++ {
+		taskResult, err := e.runTask(ctx, execTask, loopState{iteration: i, previous: previous}, num, total, level)
+		if i == 1 {
+			start = taskResult.StartTime
+		}
+		taskResult.StartTime = start
+		usage.InputTokens += taskResult.TokenUsage.InputTokens
+		usage.OutputTokens += taskResult.TokenUsage.OutputTokens
+		usage.TotalTokens += taskResult.TokenUsage.TotalTokens
+		usage.CacheRead += taskResult.TokenUsage.CacheRead
+		usage.CacheWrite += taskResult.TokenUsage.CacheWrite
+		cost += taskResult.CostUSD
+		taskResult.TokenUsage, taskResult.CostUSD = usage, cost
+		taskResult.Iterations = i
+		if ctx.Err() != nil {
+			return taskResult, err
+		}
+		if !taskResult.EndTime.IsZero() {
+			taskResult.Duration = taskResult.EndTime.Sub(start).Round(time.Millisecond * 100).String()
+		}
+
+		done, condErr := e.evaluateCondition(execTask.Until)
+		if condErr != nil {
+			taskResult.Complete(taskResult.Stdout, condErr.Error(), 1, false)
+			e.recordOutput(execTask.Name, taskResult.Stdout, state.StatusFailed)
+			return taskResult, fmt.Errorf("task %q: invalid until condition: %w", execTask.Name, condErr)
+		}
+		if done {
+			slog.Debug("loop finished", "task", execTask.Name, "iterations", i)
+			return taskResult, err
+		}
+		if i == execTask.MaxIterations {
+			msg := fmt.Sprintf("until condition not met after %d iterations", i)
+			if taskResult.Success {
+				taskResult.Complete(taskResult.Stdout, taskResult.Stderr+msg+"\n", 1, false)
+				e.recordOutput(execTask.Name, taskResult.Stdout, state.StatusFailed)
+			}
+			return taskResult, fmt.Errorf("task %q: %s", execTask.Name, msg)
+		}
+
+		ui.Info("Task %s: until condition not met, running again (%d/%d)", execTask.Name, i+1, execTask.MaxIterations)
+		slog.Info("loop iteration", "task", execTask.Name, "iteration", i+1, "max_iterations", execTask.MaxIterations)
+		// The next run gets what went wrong in this one
+		previous = taskResult.Stdout
+		if !taskResult.Success {
+			previous += taskResult.Stderr
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// taskOutput creates the writers a task's streamed output is shown with,
+// according to the output mode (settings.output), and a function that
+// flushes them once the task has finished. Prefixes are padded to nameWidth.
+func taskOutput(mode, name string, nameWidth int) (stdout, stderr io.Writer, flush func()) {
+	switch mode {
+	case config.OutputPrefixed:
+		prefix := ui.Orange + name + strings.Repeat(" ", max(nameWidth-len(name), 0)) + " |" + ui.Reset + " "
+		out := &prefixWriter{w: ui.NewOutput(), prefix: prefix}
+		errOut := &prefixWriter{w: ui.NewErrOutput(), prefix: prefix}
+		return out, errOut, func() {
+			out.Close()
+			errOut.Close()
+		}
+	case config.OutputGrouped:
+		group := &groupWriter{}
+		return group, group, group.Flush
+	default:
+		out, errOut := ui.NewOutput(), ui.NewErrOutput()
+		return out, errOut, func() {
+			out.Close()
+			errOut.Close()
+		}
+	}
+}
+
+// prefixWriter writes each complete line with a prefix, like docker-compose.
+// Partial lines are held until their newline arrives or the writer is closed.
+type prefixWriter struct {
+	mu     sync.Mutex
+	w      *ui.Output
+	prefix string
+	buf    []byte
+}
+
+// Write implements io.Writer.
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.buf = append(p.buf, data...)
+	end := bytes.LastIndexByte(p.buf, '\n')
+	if end < 0 {
+		return len(data), nil
+	}
+
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(p.buf[:end+1], []byte("\n")) {
+		if len(line) > 0 {
+			out.WriteString(p.prefix)
+			out.Write(line)
+		}
+	}
+	p.buf = append(p.buf[:0], p.buf[end+1:]...)
+
+	if _, err := p.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Close writes any partial last line and releases the terminal.
+func (p *prefixWriter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.buf) > 0 {
+		line := p.prefix + string(p.buf) + "\n"
+		p.buf = nil
+		if _, err := p.w.Write([]byte(line)); err != nil {
+			return err
+		}
+	}
+	return p.w.Close()
+}
+
+// groupWriter buffers a task's stdout and stderr and prints them as one
+// block when flushed.
+type groupWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write implements io.Writer.
+func (g *groupWriter) Write(data []byte) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buf.Write(data)
+}
+
+// Flush prints the buffered output in a single write.
+func (g *groupWriter) Flush() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.buf.Len() == 0 {
+		return
+	}
+	if !bytes.HasSuffix(g.buf.Bytes(), []byte("\n")) {
+		g.buf.WriteByte('\n')
+	}
+	// Task output, so plain mode leaves its characters alone
+	out := ui.NewOutput()
+	_, _ = out.Write(g.buf.Bytes())
+	_ = out.Close()
+	g.buf.Reset()
+}
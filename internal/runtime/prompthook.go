@@ -0,0 +1,107 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/proc"
+)
+
+// PromptHook transforms the expanded prompt of an AI agent task before it is
+// passed to the adapter, e.g. to add boilerplate or strip personal data.
+// Hooks run in order, each getting the prompt the previous one returned.
+type PromptHook interface {
+	// Name identifies the hook in results and errors.
+	Name() string
+	// TransformPrompt returns the prompt to use for task. An error fails
+	// the task without running it.
+	TransformPrompt(ctx context.Context, task Task, prompt string) (string, error)
+}
+
+// promptHookFunc is a PromptHook implemented by a function.
+type promptHookFunc struct {
+	name string
+	fn   func(ctx context.Context, task Task, prompt string) (string, error)
+}
+
+// NewPromptHook returns a PromptHook named name that transforms prompts with fn.
+func NewPromptHook(name string, fn func(ctx context.Context, task Task, prompt string) (string, error)) PromptHook {
+	return promptHookFunc{name: name, fn: fn}
+}
+
+func (h promptHookFunc) Name() string { return h.name }
+
+func (h promptHookFunc) TransformPrompt(ctx context.Context, task Task, prompt string) (string, error) {
+	return h.fn(ctx, task, prompt)
+}
+
+// ExecPromptHook is a PromptHook that runs a shell command with the prompt
+// on stdin and takes its stdout as the new prompt. The command gets the
+// task's environment and CORTEX_TASK, CORTEX_AGENT, CORTEX_TOOL, and
+// CORTEX_MODEL.
+type ExecPromptHook struct {
+	Command string
+}
+
+// Name returns the hook's command.
+func (h ExecPromptHook) Name() string { return h.Command }
+
+// TransformPrompt runs the command. It fails if the command exits non-zero
+// or prints nothing.
+func (h ExecPromptHook) TransformPrompt(ctx context.Context, task Task, prompt string) (string, error) {
+	cmd := proc.ShellCommand(ctx, "", h.Command)
+	proc.KillOnCancel(cmd, task.KillTimeout)
+	cmd.Dir = task.Workdir
+	cmd.Env = append(append(os.Environ(), task.Env...),
+		"CORTEX_TASK="+task.Name,
+		"CORTEX_AGENT="+task.Agent,
+		"CORTEX_TOOL="+task.Tool,
+		"CORTEX_MODEL="+task.Model,
+	)
+	cmd.Stdin = strings.NewReader(prompt)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := proc.Run(cmd); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	if strings.TrimSpace(stdout.String()) == "" {
+		return "", fmt.Errorf("returned an empty prompt")
+	}
+	return stdout.String(), nil
+}
+
+// usesPrompt reports whether a tool passes its prompt to an AI agent, the
+// tasks prompt hooks apply to. Shell commands and built-in tasks keep theirs.
+func usesPrompt(tool string) bool {
+	return config.IsSupportedTool(tool) && tool != "shell"
+}
+
+// transformPrompt runs the executor's prompt hooks, then the task's own,
+// on the task's prompt. Returns the new prompt and the names of the hooks
+// that ran.
+func (e *Executor) transformPrompt(ctx context.Context, task Task, taskHooks []string) (string, []string, error) {
+	hooks := append([]PromptHook(nil), e.promptHooks...)
+	for _, command := range taskHooks {
+		hooks = append(hooks, ExecPromptHook{Command: command})
+	}
+
+	prompt := task.Prompt
+	names := make([]string, 0, len(hooks))
+	for _, hook := range hooks {
+		transformed, err := hook.TransformPrompt(ctx, task, prompt)
+		if err != nil {
+			return "", nil, fmt.Errorf("prompt hook %q: %w", hook.Name(), err)
+		}
+		prompt = transformed
+		names = append(names, hook.Name())
+	}
+	return prompt, names, nil
+}
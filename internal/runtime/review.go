@@ -0,0 +1,139 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/git"
+	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/state"
+)
+
+// taskChanges are the changes a task made to the working tree it ran in,
+// for its review task and its result. Outside git repositories only the
+// changed files are known.
+type taskChanges struct {
+	repo   string               // Top-level directory of the repository
+	before string               // Tree of the working tree before the task
+	after  string               // Tree of the working tree after the task
+	diff   string               // What changed, for the reviewer
+	files  []state.FileChange   // The changed files
+	dir    string               // Directory walked outside git repositories
+	stamps map[string]fileStamp // Files in dir before the task, outside git repositories
+}
+
+// snapshotChanges records the working tree of the repository at dir before
+// a task runs. Untracked files are included, ignored ones aren't.
+func snapshotChanges(ctx context.Context, dir string) (*taskChanges, error) {
+	repo, err := gitOutput(ctx, dir, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("review_changes_with needs a git repository: %w", err)
+	}
+	c := &taskChanges{repo: strings.TrimSpace(repo)}
+	if c.before, err = writeWorktree(ctx, c.repo); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// finish records the working tree after the task and the diff since the
+// snapshot.
+func (c *taskChanges) finish(ctx context.Context) error {
+	if c.repo == "" {
+		return c.finishFiles()
+	}
+	var err error
+	if c.after, err = writeWorktree(ctx, c.repo); err != nil {
+		return err
+	}
+	c.diff, err = gitOutput(ctx, c.repo, nil, "diff", "--no-color", "--stat", "--patch", c.before, c.after)
+	if err != nil {
+		return fmt.Errorf("failed to diff changes: %w", err)
+	}
+	if c.files, err = changedFiles(ctx, c.repo, c.before, c.after); err != nil {
+		return fmt.Errorf("failed to diff changes: %w", err)
+	}
+	return nil
+}
+
+// revert undoes the changes in the working tree. It fails if the changed
+// files were modified again since.
+func (c *taskChanges) revert(ctx context.Context) error {
+	patch, err := gitOutput(ctx, c.repo, nil, "diff", "--binary", c.before, c.after)
+	if err != nil {
+		return err
+	}
+	cmd := git.Command(ctx, c.repo, "apply", "--reverse", "--binary", "-")
+	cmd.Stdin = strings.NewReader(patch)
+	_, err = git.Run(cmd)
+	return err
+}
+
+// writeWorktree stores the working tree of repo as a git tree, through a
+// temporary index so the repository's own index is left alone, and returns
+// its ID.
+func writeWorktree(ctx context.Context, repo string) (string, error) {
+	dir, err := os.MkdirTemp("", "cortex-review-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(dir, "index")}
+	if _, err := gitOutput(ctx, repo, env, "add", "--all", "."); err != nil {
+		return "", fmt.Errorf("failed to snapshot working tree: %w", err)
+	}
+	tree, err := gitOutput(ctx, repo, env, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot working tree: %w", err)
+	}
+	return strings.TrimSpace(tree), nil
+}
+
+// gitOutput runs git in dir with extra environment variables and returns
+// its output as is, which diffs and patches need.
+func gitOutput(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := git.Command(ctx, dir, args...)
+	cmd.Env = append(cmd.Env, env...)
+	return git.Run(cmd)
+}
+
+// reviewPrompt appends the changes of the reviewed task to a review
+// task's prompt.
+func reviewPrompt(prompt string, c *taskChanges) string {
+	diff := c.diff
+	if !strings.HasSuffix(diff, "\n") {
+		diff += "\n"
+	}
+	return prompt + "\n\n```diff\n" + diff + "```\n"
+}
+
+// checkVerdict applies the verdict of a review task's output. A rejection,
+// or a review without a verdict, rolls the changes back if the task asks
+// for it, and returns the error failing the review.
+func (e *Executor) checkVerdict(ctx context.Context, execTask planner.ExecutionTask, output string) error {
+	verdict := config.ParseVerdict(output)
+	if verdict == config.VerdictApprove {
+		return nil
+	}
+
+	err := fmt.Errorf("reviewer rejected the changes of %q", execTask.ReviewOf)
+	if verdict == "" {
+		err = fmt.Errorf("review of %q gave no verdict", execTask.ReviewOf)
+	}
+	if !execTask.Rollback {
+		return err
+	}
+
+	e.outputsMu.RLock()
+	changes := e.changes[execTask.ReviewOf]
+	e.outputsMu.RUnlock()
+	if rbErr := changes.revert(ctx); rbErr != nil {
+		return fmt.Errorf("%w; rollback failed: %v", err, rbErr)
+	}
+	return fmt.Errorf("%w; changes rolled back", err)
+}
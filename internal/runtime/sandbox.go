@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	goruntime "runtime"
+	"strings"
+)
+
+// DockerExecutable runs the containers of docker sandboxes.
+const DockerExecutable = "docker"
+
+// errSandboxUnsupported is returned for sandboxed tasks on Windows, whose
+// paths can't be mounted at the same place in a Linux container.
+var errSandboxUnsupported = errors.New("sandboxed tasks are not supported on Windows")
+
+// Command returns the command running name with args in dir, or in the
+// current directory if dir is empty. Without a sandbox, it runs on the host.
+// With one, it runs in a new container of the sandbox's image, which has dir
+// mounted at the same path, so paths in args stay valid, along with the
+// sandbox's mounts and the task's environment variables. The container is
+// removed when the command exits.
+func (t Task) Command(ctx context.Context, dir, name string, args ...string) *exec.Cmd {
+	if t.Sandbox == nil {
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Dir = dir
+		return cmd
+	}
+
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	cmd := exec.CommandContext(ctx, DockerExecutable, t.dockerArgs(dir, name, args)...)
+	cmd.Dir = dir
+	if goruntime.GOOS == "windows" {
+		cmd.Err = errSandboxUnsupported
+	}
+	return cmd
+}
+
+// dockerArgs returns the arguments of the docker run command running name
+// with args in the task's sandbox.
+func (t Task) dockerArgs(dir, name string, args []string) []string {
+	s := t.Sandbox
+	dockerArgs := []string{
+		"run", "--rm", "--interactive", "--init",
+		"--label", "cortex.task=" + t.Name,
+		"--volume", dir + ":" + dir,
+		"--workdir", dir,
+	}
+	for _, mount := range s.Mounts {
+		dockerArgs = append(dockerArgs, "--volume", mount)
+	}
+	if s.Network != "" {
+		dockerArgs = append(dockerArgs, "--network", s.Network)
+	}
+	if s.CPUs != "" {
+		dockerArgs = append(dockerArgs, "--cpus", s.CPUs)
+	}
+	if s.Memory != "" {
+		dockerArgs = append(dockerArgs, "--memory", s.Memory)
+	}
+	if s.User != "" {
+		dockerArgs = append(dockerArgs, "--user", s.User)
+	}
+	// Pass variables by name, so docker reads their values, secrets among
+	// them, from its environment instead of its command line
+	seen := make(map[string]bool)
+	for _, kv := range t.Env {
+		key, _, _ := strings.Cut(kv, "=")
+		if key != "" && !seen[key] {
+			seen[key] = true
+			dockerArgs = append(dockerArgs, "--env", key)
+		}
+	}
+	dockerArgs = append(dockerArgs, s.Image, name)
+	return append(dockerArgs, args...)
+}
@@ -0,0 +1,414 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/events"
+	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+	"github.com/adityaraj/agentflow/internal/webhook"
+)
+
+// storeSubscriber saves task and run results.
+type storeSubscriber struct {
+	store *state.Store
+}
+
+// Handle implements events.Subscriber.
+func (s *storeSubscriber) Handle(e events.Event) {
+	switch e := e.(type) {
+	case events.TaskFinished:
+		if err := s.store.SaveTaskResult(e.Result); err != nil {
+			ui.Warning("Failed to save result: %s", err)
+			slog.Warn("failed to save task result", "task", e.Result.TaskName, "error", err)
+		}
+	case events.RunFinished:
+		_ = s.store.SaveRunResult(e.Result)
+	}
+}
+
+// statusSubscriber keeps the status file of the run up to date for
+// `cortex status`, and removes it when the run ends, before the run's
+// manifest is signed.
+type statusSubscriber struct {
+	store *state.Store
+
+	mu     sync.Mutex
+	status state.RunStatus
+}
+
+func newStatusSubscriber(store *state.Store, project, user string) *statusSubscriber {
+	return &statusSubscriber{store: store, status: state.RunStatus{
+		RunID:   store.RunID(),
+		Project: project,
+		User:    user,
+		PID:     os.Getpid(),
+	}}
+}
+
+// Handle implements events.Subscriber.
+func (s *statusSubscriber) Handle(e events.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch e := e.(type) {
+	case events.RunStarted:
+		s.status.StartTime = time.Now()
+		s.status.TaskCount = len(e.Plan.Tasks)
+		s.status.Levels = len(e.Levels)
+		s.status.Running = []state.RunningTask{}
+
+	case events.LevelStarted:
+		s.status.Level = e.Level + 1
+
+	case events.TaskStarted:
+		t := e.Task
+		s.status.Running = append(s.status.Running, state.RunningTask{Name: t.Name, Agent: t.AgentName, Tool: t.Tool, StartTime: time.Now()})
+
+	case events.TaskFinished:
+		s.status.Finished++
+		s.status.Running = slices.DeleteFunc(s.status.Running, func(t state.RunningTask) bool {
+			return t.Name == e.Task.Name
+		})
+
+	case events.RunFinished:
+		s.store.RemoveStatus()
+		return
+
+	default:
+		return
+	}
+
+	s.status.UpdatedAt = time.Now()
+	if err := s.store.SaveStatus(&s.status); err != nil {
+		slog.Debug("failed to save run status", "error", err)
+	}
+}
+
+// logSubscriber writes run, task, and budget events to the operational log.
+type logSubscriber struct {
+	log *slog.Logger
+}
+
+func newLogSubscriber(runID, project string) *logSubscriber {
+	return &logSubscriber{log: slog.Default().With("run_id", runID, "project", project)}
+}
+
+// Handle implements events.Subscriber.
+func (s *logSubscriber) Handle(e events.Event) {
+	switch e := e.(type) {
+	case events.RunStarted:
+		s.log.Info("run started", "tasks", len(e.Plan.Tasks), "parallel", e.Parallel)
+
+	case events.LevelStarted:
+		s.log.Debug("level started", "level", e.Level+1, "levels", e.Total, "tasks", e.Tasks)
+
+	case events.TaskStarted:
+		s.log.Info("task started", taskAttrs(e.Task)...)
+
+	case events.TaskFinished:
+		r := e.Result
+		attrs := append(taskAttrs(e.Task), "status", r.Status)
+		if r.Status != state.StatusSkipped {
+			attrs = append(attrs, "exit_code", r.ExitCode, "duration_ms", r.Elapsed().Milliseconds())
+		}
+		if r.TokenUsage.TotalTokens > 0 {
+			attrs = append(attrs, "input_tokens", r.TokenUsage.InputTokens, "output_tokens", r.TokenUsage.OutputTokens)
+		}
+		if r.CostUSD > 0 {
+			attrs = append(attrs, "cost_usd", r.CostUSD)
+		}
+		if r.SkipReason != "" {
+			attrs = append(attrs, "skip_reason", r.SkipReason)
+		}
+		level := slog.LevelInfo
+		if e.Err != nil {
+			attrs = append(attrs, "error", e.Err.Error())
+			level = slog.LevelError
+			if e.Ignored {
+				level = slog.LevelWarn
+			}
+		}
+		s.log.Log(context.Background(), level, "task finished", attrs...)
+
+	case events.BudgetExceeded:
+		s.log.Warn("budget exceeded", "task", e.Task, "tokens", e.Tokens, "cost_usd", e.CostUSD, "error", e.Message)
+
+	case events.RunFinished:
+		r := e.Result
+		attrs := []any{"success", r.Success, "tasks", len(r.Tasks), "duration_ms", e.Duration().Milliseconds(), "total_tokens", r.TokenUsage.TotalTokens}
+		if r.CostUSD > 0 {
+			attrs = append(attrs, "cost_usd", r.CostUSD)
+		}
+		if e.Err != nil {
+			s.log.Error("run finished", append(attrs, "error", e.Err.Error())...)
+			return
+		}
+		s.log.Info("run finished", attrs...)
+	}
+}
+
+// taskAttrs returns the log attributes identifying a task.
+func taskAttrs(t planner.ExecutionTask) []any {
+	attrs := []any{"task", t.Name, "agent", t.AgentName, "tool", t.Tool}
+	if t.Model != "" {
+		attrs = append(attrs, "model", t.Model)
+	}
+	return attrs
+}
+
+// webhookSubscriber sends run, level, task, and budget events to webhooks.
+// A run stopped by an interrupt ends with run_cancelled, not run_complete.
+type webhookSubscriber struct {
+	webhooks *webhook.Manager
+	runID    string
+	project  string
+}
+
+// Handle implements events.Subscriber.
+func (s *webhookSubscriber) Handle(e events.Event) {
+	switch e := e.(type) {
+	case events.RunStarted:
+		s.webhooks.Send(webhook.NewRunStartEvent(s.runID, s.project))
+
+	case events.LevelStarted:
+		s.webhooks.Send(webhook.NewLevelStartEvent(s.runID, s.project, webhook.LevelEvent{
+			Level: e.Level + 1,
+			Total: e.Total,
+			Tasks: e.Tasks,
+		}))
+
+	case events.TaskStarted:
+		t := e.Task
+		s.webhooks.Send(webhook.NewTaskStartEvent(s.runID, s.project, t.Name, t.AgentName, t.Tool, t.Model))
+
+	case events.TaskFinished:
+		// Skipped tasks send only task_complete, with status "skipped",
+		// since they never started
+		t, r := e.Task, e.Result
+		var event webhook.Event
+		if e.Err != nil || r.Status == state.StatusFailed {
+			msg := r.Stderr
+			if e.Err != nil {
+				msg = e.Err.Error()
+			}
+			event = webhook.NewTaskFailedEvent(s.runID, s.project, t.Name, t.AgentName, t.Tool, t.Model, r.Duration, msg)
+		} else {
+			event = webhook.NewTaskCompleteEvent(s.runID, s.project, t.Name, t.AgentName, t.Tool, t.Model, r.Duration, r.Success)
+		}
+		event.Task.Status = r.Status
+		s.webhooks.Send(event)
+
+	case events.BudgetExceeded:
+		s.webhooks.Send(webhook.NewBudgetExceededEvent(s.runID, s.project, webhook.BudgetEvent{
+			Task:       e.Task,
+			TokensUsed: e.Tokens,
+			CostUSD:    e.CostUSD,
+			MaxTokens:  e.MaxTokens,
+			MaxCostUSD: e.MaxCostUSD,
+		}))
+
+	case events.RunFinished:
+		r := e.Result
+		if !r.Cancelled {
+			s.webhooks.Send(webhook.NewRunCompleteEvent(s.runID, s.project, len(r.Tasks), e.Duration(), r.Success))
+			break
+		}
+		var cancelled []string
+		for _, t := range r.Tasks {
+			if t.Status == state.StatusCancelled {
+				cancelled = append(cancelled, t.TaskName)
+			}
+		}
+		s.webhooks.Send(webhook.NewRunCancelledEvent(s.runID, s.project, len(r.Tasks), e.Duration(), cancelled))
+	}
+}
+
+// consoleSubscriber prints a run to the terminal: a header and status line
+// per task, streamed output in the configured output mode, and the progress
+// spinner.
+type consoleSubscriber struct {
+	writer     io.Writer // Destination of error details and output summaries
+	verbose    bool
+	stream     bool   // Adapters stream output by default
+	progress   bool   // Show a spinner/progress bar while tasks run
+	outputMode string // How streamed task output is shown (settings.output)
+
+	mu        sync.Mutex
+	parallel  bool
+	nameWidth int                 // Longest task name, for aligning prefixes
+	finished  int                 // Tasks finished so far
+	tracker   *ui.ProgressTracker // Status line spinner, set by RunStarted
+	running   map[string]*consoleTask
+}
+
+// consoleTask is the output of a running task.
+type consoleTask struct {
+	stdout, stderr io.Writer
+	flush          func()
+	spinning       bool
+}
+
+func newConsoleSubscriber(writer io.Writer, verbose, stream, progress bool, outputMode string) *consoleSubscriber {
+	return &consoleSubscriber{
+		writer:     writer,
+		verbose:    verbose,
+		stream:     stream,
+		progress:   progress,
+		outputMode: outputMode,
+		running:    make(map[string]*consoleTask),
+	}
+}
+
+// Handle implements events.Subscriber.
+func (c *consoleSubscriber) Handle(e events.Event) {
+	switch e := e.(type) {
+	case events.RunStarted:
+		c.mu.Lock()
+		c.parallel = e.Parallel
+		for _, task := range e.Plan.Tasks {
+			c.nameWidth = max(c.nameWidth, len(task.Name))
+		}
+		if c.progress {
+			c.tracker = ui.NewProgressTracker(len(e.Plan.Tasks), len(e.Levels))
+		}
+		c.mu.Unlock()
+
+	case events.TaskStarted:
+		c.taskStarted(e)
+
+	case events.TaskOutputChunk:
+		c.mu.Lock()
+		t := c.running[e.Task]
+		c.mu.Unlock()
+		if t == nil {
+			return
+		}
+		if e.Stream == events.Stderr {
+			_, _ = t.stderr.Write(e.Data)
+		} else {
+			_, _ = t.stdout.Write(e.Data)
+		}
+
+	case events.TaskFinished:
+		c.taskFinished(e)
+
+	case events.BudgetExceeded:
+		ui.Error("%s", e.Message)
+
+	case events.RunFinished:
+		c.mu.Lock()
+		tracker := c.tracker
+		c.mu.Unlock()
+		if tracker != nil {
+			tracker.Stop()
+		}
+	}
+}
+
+func (c *consoleSubscriber) taskStarted(e events.TaskStarted) {
+	c.mu.Lock()
+	// Parallel tasks start before earlier ones finish, so the bar counts
+	// finished tasks rather than going by the task number
+	progress := e.Num
+	if c.parallel {
+		progress = c.finished + 1
+	}
+	c.mu.Unlock()
+	ui.PrintTaskStart(e.Num, e.Total, e.Task.Name, e.Task.AgentName, e.Task.Tool, e.Task.Model)
+	ui.PrintTaskRunningWithProgress(progress, e.Total, true) // Show Ctrl+O hint with progress bar
+
+	c.mu.Lock()
+	t := &consoleTask{}
+	t.stdout, t.stderr, t.flush = taskOutput(c.outputMode, e.Task.Name, c.nameWidth)
+	// Streamed output shows progress by itself, so in sequential runs only
+	// quiet tasks get a spinner
+	t.spinning = c.tracker != nil && (c.parallel || !(Task{ShowOutput: e.Task.ShowOutput}).Streaming(c.stream))
+	c.running[e.Task.Name] = t
+	tracker := c.tracker
+	c.mu.Unlock()
+
+	if t.spinning {
+		tracker.StartTask(e.Task.Name, e.Level)
+	}
+}
+
+func (c *consoleSubscriber) taskFinished(e events.TaskFinished) {
+	c.mu.Lock()
+	t, started := c.running[e.Task.Name]
+	delete(c.running, e.Task.Name)
+	tracker, parallel := c.tracker, c.parallel
+	progress := e.Num
+	if parallel {
+		progress = c.finished + 1
+	}
+	c.finished++
+	c.mu.Unlock()
+
+	r := e.Result
+	if started {
+		t.flush()
+	} else {
+		// Skipped, or failed before its agent ran
+		ui.PrintTaskStart(e.Num, e.Total, e.Task.Name, e.Task.AgentName, e.Task.Tool, e.Task.Model)
+		ui.PrintTaskRunningWithProgress(progress, e.Total, true)
+	}
+	// Parallel progress counts every task, sequential only those that spun
+	if tracker != nil && (parallel || (started && t.spinning)) {
+		tracker.CompleteTask(e.Task.Name)
+	}
+
+	if r.Status == state.StatusSkipped {
+		reason, _, _ := strings.Cut(r.SkipReason, ":")
+		if reason != "" {
+			reason = strings.ToUpper(reason[:1]) + reason[1:]
+		}
+		ui.PrintTaskSkipped(reason)
+		return
+	}
+	if r.Status == state.StatusCancelled {
+		ui.PrintTaskCancelled(ui.FormatDuration(r.Elapsed()))
+		return
+	}
+
+	status := "Success"
+	if !r.Success {
+		status = "Failed"
+	}
+	ui.PrintTaskStatusWithTokens(status, r.Success, ui.FormatDuration(r.Elapsed()), r.TokenUsage.InputTokens, r.TokenUsage.OutputTokens, r.ChangeSummary())
+	if e.Err != nil {
+		if c.verbose {
+			fmt.Fprintf(c.writer, "  %sError:%s %s\n", ui.Dim, ui.Reset, e.Err)
+		}
+		if e.Ignored {
+			ui.Warning("%s (continue_on_error)", e.Err)
+		}
+		return
+	}
+
+	// Show first few lines of output in verbose mode or when the task asks
+	// for a summary; show_output: none keeps the task quiet either way
+	showSummary := c.verbose
+	switch e.Task.ShowOutput {
+	case config.ShowOutputSummary:
+		showSummary = true
+	case config.ShowOutputNone:
+		showSummary = false
+	}
+	if showSummary && r.Stdout != "" {
+		fmt.Fprintf(c.writer, "  %sOutput (truncated):%s\n", ui.Dim, ui.Reset)
+		for _, line := range truncateLines(r.Stdout, 5) {
+			fmt.Fprintf(c.writer, "    %s%s%s\n", ui.Dim, line, ui.Reset)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package runtime
+
+import (
+	"io"
+	"log/slog"
+
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/ui"
+)
+
+// teeWriter writes a task's streamed output to the event bus and to its log
+// file. Separators adapters print around the output only go to the bus.
+type teeWriter struct {
+	out io.Writer
+	log *state.TaskLog
+}
+
+// Write implements io.Writer. A failing log never fails the task.
+func (w teeWriter) Write(p []byte) (int, error) {
+	w.log.Write(p)
+	return w.out.Write(p)
+}
+
+// Decor implements ui.DecorWriter.
+func (w teeWriter) Decor() io.Writer {
+	return w.out
+}
+
+var _ ui.DecorWriter = teeWriter{}
+
+// openTaskLog creates the log file of a task and tees its streamed output
+// to it, or gives it to the task as Log when the output isn't streamed,
+// recording the file in the task result. Later iterations of a loop task
+// add to the log of the first.
+func (e *Executor) openTaskLog(task *Task, taskResult *state.TaskResult, iteration int) *state.TaskLog {
+	open := e.store.CreateTaskLog
+	if iteration > 1 {
+		open = e.store.AppendTaskLog
+	}
+	taskLog, err := open(task.Name)
+	if err != nil {
+		slog.Warn("failed to create task log", "task", task.Name, "error", err)
+		return nil
+	}
+	task.Stdout = teeWriter{out: task.Stdout, log: taskLog}
+	task.Stderr = teeWriter{out: task.Stderr, log: taskLog}
+	task.Log = teeWriter{out: io.Discard, log: taskLog}
+	taskResult.LogFile = taskLog.Name()
+	return taskLog
+}
+
+// closeTaskLog closes a task's log file. Adapters without a process, such
+// as those of api and http tasks, write nothing to it unless they stream, so
+// the log gets their output from the result then.
+func closeTaskLog(taskLog *state.TaskLog, result Result) {
+	if taskLog == nil {
+		return
+	}
+	if taskLog.Size() == 0 {
+		io.WriteString(taskLog, result.Stdout)
+		io.WriteString(taskLog, result.Stderr)
+	}
+	if err := taskLog.Close(); err != nil {
+		slog.Warn("failed to write task log", "file", taskLog.Name(), "error", err)
+	}
+}
@@ -0,0 +1,110 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// CappedBuffer collects a process's output up to a limit, keeping the first
+// and last halves of it and dropping the middle, so both how the output
+// started and how it ended survive. Writes never fail, so the process isn't
+// blocked or killed by a full pipe.
+type CappedBuffer struct {
+	limit   int64 // Bytes kept (0: no limit)
+	head    bytes.Buffer
+	tail    []byte // Last bytes written once the head is full, at most twice the tail's share
+	dropped int64
+}
+
+// NewCappedBuffer returns a buffer keeping limit bytes of what is written to
+// it, or all of it if limit is 0.
+func NewCappedBuffer(limit int64) *CappedBuffer {
+	return &CappedBuffer{limit: limit}
+}
+
+// Write keeps data while it fits in the head and then keeps the latest of
+// it in the tail, counting what falls out of the tail as dropped.
+func (b *CappedBuffer) Write(data []byte) (int, error) {
+	n := len(data)
+	if b.limit <= 0 {
+		b.head.Write(data)
+		return n, nil
+	}
+	headLimit := b.limit / 2
+	if room := headLimit - int64(b.head.Len()); room > 0 {
+		take := min(room, int64(len(data)))
+		b.head.Write(data[:take])
+		data = data[take:]
+	}
+	b.tail = append(b.tail, data...)
+	// Trim the tail once it holds twice its share, so trimming is amortized
+	if tailLimit := b.limit - headLimit; int64(len(b.tail)) > 2*tailLimit {
+		cut := int64(len(b.tail)) - tailLimit
+		b.dropped += cut
+		b.tail = append(b.tail[:0], b.tail[cut:]...)
+	}
+	return n, nil
+}
+
+// Truncated reports whether output was dropped.
+func (b *CappedBuffer) Truncated() bool {
+	return b.dropped > 0 || int64(len(b.tail)) > b.limit-b.limit/2
+}
+
+// String returns the output kept, with a marker line in place of the
+// output dropped, if any was. The tail gives up room for the marker, so the
+// result stays within the limit unless the limit is shorter than the marker.
+func (b *CappedBuffer) String() string {
+	if b.limit <= 0 {
+		return b.head.String()
+	}
+	tail, dropped := b.tail, b.dropped
+	if tailLimit := b.limit - b.limit/2; int64(len(tail)) > tailLimit {
+		dropped += int64(len(tail)) - tailLimit
+		tail = tail[int64(len(tail))-tailLimit:]
+	}
+	if dropped == 0 {
+		return b.head.String() + string(tail)
+	}
+	head := b.head.String()
+	if len(head) > 0 && head[len(head)-1] != '\n' {
+		head += "\n"
+	}
+	for {
+		marker := TruncationMarker(b.limit, dropped) + "\n"
+		excess := int64(len(head)+len(marker)+len(tail)) - b.limit
+		if excess <= 0 || len(tail) == 0 {
+			return head + marker + string(tail)
+		}
+		cut := min(excess, int64(len(tail)))
+		dropped += cut
+		tail = tail[cut:]
+	}
+}
+
+// TruncationMarker returns the line marking where dropped bytes were cut
+// out of output limited to limit bytes.
+func TruncationMarker(limit, dropped int64) string {
+	return fmt.Sprintf("[cortex: output truncated to %d bytes, %d bytes dropped here]", limit, dropped)
+}
+
+// capOutput limits the output of adapters that don't cap it themselves,
+// such as those of api and http tasks, to limit bytes.
+func capOutput(result *Result, limit int64) {
+	var stdoutCut, stderrCut bool
+	result.Stdout, stdoutCut = TruncateOutput(result.Stdout, limit)
+	result.Stderr, stderrCut = TruncateOutput(result.Stderr, limit)
+	result.Truncated = result.Truncated || stdoutCut || stderrCut
+}
+
+// TruncateOutput limits collected output to limit bytes the way a
+// CappedBuffer does, reporting whether any was dropped. A limit of 0 keeps
+// all of it.
+func TruncateOutput(output string, limit int64) (string, bool) {
+	if limit <= 0 || int64(len(output)) <= limit {
+		return output, false
+	}
+	b := NewCappedBuffer(limit)
+	b.Write([]byte(output))
+	return b.String(), true
+}
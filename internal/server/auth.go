@@ -0,0 +1,209 @@
+// Package server provides access control for sharing a cortex host between
+// users: API tokens, the user each token belongs to, and what their role
+// allows.
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/adityaraj/agentflow/internal/config"
+)
+
+// User is an authenticated holder of an API token.
+type User struct {
+	Name string
+	Role string // config.RoleViewer, config.RoleRunner, or config.RoleAdmin
+}
+
+// CanView reports whether the user may read runs and logs.
+func (u User) CanView() bool {
+	return u.Role == config.RoleViewer || u.Role == config.RoleRunner || u.Role == config.RoleAdmin
+}
+
+// CanTrigger reports whether the user may start a workflow. Privileged
+// workflows, which run commands or change state (see
+// config.AgentflowConfig.Privileged), need the admin role.
+func (u User) CanTrigger(privileged bool) bool {
+	switch u.Role {
+	case config.RoleAdmin:
+		return true
+	case config.RoleRunner:
+		return !privileged
+	default:
+		return false
+	}
+}
+
+// Auth maps API tokens to users. Tokens are kept as SHA-256 hashes, so a
+// lookup does not compare secrets byte by byte.
+type Auth struct {
+	users map[[sha256.Size]byte]User
+	local bool // Set by AddLocalToken: only loopback Host headers are accepted
+}
+
+// NewAuth builds an Auth from the server users of the global config.
+// Returns an error for a user without a name or token, an unknown role, or
+// a token shared by two users.
+func NewAuth(cfg config.ServerConfig) (*Auth, error) {
+	a := &Auth{users: make(map[[sha256.Size]byte]User, len(cfg.Users))}
+
+	for i, u := range cfg.Users {
+		if u.Name == "" {
+			return nil, fmt.Errorf("server.users[%d]: name is required", i)
+		}
+
+		token := u.Token
+		if u.TokenEnv != "" {
+			token = os.Getenv(u.TokenEnv)
+			if token == "" {
+				return nil, fmt.Errorf("server user %q: environment variable %s is not set", u.Name, u.TokenEnv)
+			}
+		}
+		if token == "" {
+			return nil, fmt.Errorf("server user %q: 'token' or 'token_env' is required", u.Name)
+		}
+
+		role := u.Role
+		if role == "" {
+			role = config.RoleRunner
+		}
+		if !containsRole(role) {
+			return nil, fmt.Errorf("server user %q: unsupported role %q (supported: %s)", u.Name, role, strings.Join(config.SupportedRoles, ", "))
+		}
+
+		key := sha256.Sum256([]byte(token))
+		if other, exists := a.users[key]; exists {
+			return nil, fmt.Errorf("server users %q and %q have the same token", other.Name, u.Name)
+		}
+		a.users[key] = User{Name: u.Name, Role: role}
+	}
+
+	return a, nil
+}
+
+// AddLocalToken makes token authenticate an anonymous admin, for a server
+// without configured users. Such a server only answers requests addressed to
+// a loopback host, so a web page whose domain was rebound to 127.0.0.1
+// cannot reach it.
+func (a *Auth) AddLocalToken(token string) {
+	if a.users == nil {
+		a.users = make(map[[sha256.Size]byte]User)
+	}
+	a.users[sha256.Sum256([]byte(token))] = User{Role: config.RoleAdmin}
+	a.local = true
+}
+
+// HasUsers reports whether server users are configured, rather than no
+// token or only the one of AddLocalToken.
+func (a *Auth) HasUsers() bool {
+	return a.Enabled() && !a.local
+}
+
+// Enabled reports whether any tokens are configured. Without tokens,
+// requests are not authenticated.
+func (a *Auth) Enabled() bool {
+	return len(a.users) > 0
+}
+
+// Authenticate returns the user of the request's bearer token.
+func (a *Auth) Authenticate(r *http.Request) (User, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return User{}, false
+	}
+	user, ok := a.users[sha256.Sum256([]byte(token))]
+	return user, ok
+}
+
+// Middleware rejects cross-origin requests and requests without a valid
+// token, and makes the user available to handlers through UserFromContext.
+// When auth is not enabled, every request addressed to a loopback host runs
+// as an anonymous admin.
+func (a *Auth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.checkBrowser(w, r, a.local || !a.Enabled()) {
+			return
+		}
+
+		user := User{Role: config.RoleAdmin}
+		if a.Enabled() {
+			var ok bool
+			user, ok = a.Authenticate(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="cortex"`)
+				http.Error(w, "missing or invalid API token", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// userKey is the context key of the authenticated user.
+type userKey struct{}
+
+// UserFromContext returns the user set by Middleware.
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userKey{}).(User)
+	return user, ok
+}
+
+// checkBrowser rejects requests a web page may have sent on its own, writing
+// an error response: cross-origin requests, and with localOnly, requests
+// addressed to another host than localhost, as sent after DNS rebinding.
+func (a *Auth) checkBrowser(w http.ResponseWriter, r *http.Request, localOnly bool) bool {
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+		return false
+	}
+	if localOnly && !IsLoopback(hostname(r.Host)) {
+		http.Error(w, "requests must be addressed to localhost", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// sameOrigin reports whether the request comes from a page of the server
+// itself, or from outside a browser, which sends no Origin header.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+// IsLoopback reports whether host, a name or IP address without a port,
+// only refers to the local machine.
+func IsLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// hostname strips the port from a Host header.
+func hostname(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return strings.Trim(hostport, "[]")
+}
+
+func containsRole(role string) bool {
+	for _, r := range config.SupportedRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
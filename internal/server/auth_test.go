@@ -0,0 +1,33 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/adityaraj/agentflow/internal/config"
+)
+
+// TestUser_CanTrigger tests which roles may start plain and privileged
+// workflows.
+func TestUser_CanTrigger(t *testing.T) {
+	tests := []struct {
+		role       string
+		plain      bool
+		privileged bool
+	}{
+		{config.RoleViewer, false, false},
+		{config.RoleRunner, true, false},
+		{config.RoleAdmin, true, true},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			user := User{Name: "u", Role: tt.role}
+			if got := user.CanTrigger(false); got != tt.plain {
+				t.Errorf("CanTrigger(false) = %v, want %v", got, tt.plain)
+			}
+			if got := user.CanTrigger(true); got != tt.privileged {
+				t.Errorf("CanTrigger(true) = %v, want %v", got, tt.privileged)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strconv"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/planner"
+	"github.com/adityaraj/agentflow/internal/state"
+)
+
+// dashboardFiles is the web dashboard: a single page that reads the API with
+// the token the user enters.
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// maxSessions caps the sessions returned by GET /sessions.
+const maxSessions = 200
+
+// GraphNode is a task of a run's dependency graph.
+type GraphNode struct {
+	Name     string   `json:"name"`
+	Needs    []string `json:"needs,omitempty"`
+	Level    int      `json:"level"`              // Execution level, 0 for tasks without dependencies
+	Critical bool     `json:"critical,omitempty"` // On the critical path
+}
+
+// buildGraph returns the dependency graph of a workflow in execution order,
+// or nil if it cannot be planned.
+func buildGraph(cfg *config.AgentflowConfig) []GraphNode {
+	plan, err := planner.BuildPlan(cfg)
+	if err != nil {
+		return nil
+	}
+
+	levels := planner.BuildExecutionLevels(plan.DAG)
+	critical := make(map[string]bool)
+	for _, name := range planner.CriticalPath(plan.DAG) {
+		critical[name] = true
+	}
+
+	nodes := make([]GraphNode, 0, len(plan.Tasks))
+	for _, t := range plan.Tasks {
+		nodes = append(nodes, GraphNode{
+			Name:     t.Name,
+			Needs:    plan.DAG.Edges[t.Name],
+			Level:    planner.LevelForTask(levels, t.Name),
+			Critical: critical[t.Name],
+		})
+	}
+	return nodes
+}
+
+// dashboardHandler serves the dashboard page at / and its assets under
+// /ui/. The assets are public; the page asks for a token to call the API.
+func dashboardHandler() http.Handler {
+	sub, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err) // The embedded directory always exists
+	}
+	files := http.FileServerFS(sub)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFileFS(w, r, sub, "index.html")
+	})
+	mux.Handle("GET /ui/", http.StripPrefix("/ui/", files))
+	return mux
+}
+
+// handleListSessions lists past runs of every project, newest first.
+// Query parameters: project, limit (default and maximum 200), and failed.
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	if !s.canView(w, r) {
+		return
+	}
+
+	filter := state.SessionFilter{
+		Project:    r.URL.Query().Get("project"),
+		Limit:      maxSessions,
+		FailedOnly: r.URL.Query().Get("failed") == "true",
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		filter.Limit = min(n, maxSessions)
+	}
+
+	sessions, err := state.ListSessions(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if sessions == nil {
+		sessions = []state.SessionInfo{}
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+// handleGetSession returns a past run in the format of GET /runs/{id}, so
+// the dashboard shows both the same way. Runs still in progress have no end
+// time and report the tasks finished so far.
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	if !s.canView(w, r) {
+		return
+	}
+
+	project, id := r.PathValue("project"), r.PathValue("id")
+	if !state.IsPathElement(project) {
+		writeError(w, http.StatusBadRequest, "invalid project name")
+		return
+	}
+	if !state.IsPathElement(id) {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+	runDir, id, err := state.ResolveRunDir(project, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	info := RunInfo{ID: id, Status: StatusRunning, Project: project, RunDir: runDir}
+	if result, err := state.GetSession(project, id); err == nil {
+		info.User = result.User
+		info.StartTime = result.StartTime
+		info.EndTime = &result.EndTime
+		info.Status = StatusFailed
+		if result.Success {
+			info.Status = StatusSuccess
+		}
+	}
+
+	results, _ := state.ListTaskResults(runDir)
+	for _, t := range results {
+		if info.StartTime.IsZero() || t.StartTime.Before(info.StartTime) {
+			info.StartTime = t.StartTime
+		}
+		info.Tasks = append(info.Tasks, taskInfo(t))
+	}
+	writeJSON(w, http.StatusOK, info)
+}
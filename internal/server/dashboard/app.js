@@ -0,0 +1,343 @@
+// Cortex dashboard: lists the server's runs and past sessions, and shows a
+// run's dependency graph, task results, and live console output.
+"use strict";
+
+const tokenKey = "cortex-token";
+const refreshMs = 5000;
+
+let selected = null; // {kind: "run" | "session", id, project}
+let stream = null; // AbortController of the open event stream
+let pollTimer = null;
+
+// api fetches an API path with the saved token, showing the sign-in form
+// when the server rejects it.
+async function api(path, options = {}) {
+  const headers = {};
+  const token = localStorage.getItem(tokenKey);
+  if (token) headers.Authorization = "Bearer " + token;
+
+  const resp = await fetch(path, { ...options, headers });
+  if (resp.status === 401) {
+    showLogin(token ? "The token was rejected." : "");
+    throw new Error("unauthorized");
+  }
+  if (!resp.ok) {
+    const body = await resp.json().catch(() => ({}));
+    throw new Error(body.error || resp.statusText);
+  }
+  return resp;
+}
+
+async function getJSON(path) {
+  return (await api(path)).json();
+}
+
+function el(tag, attrs = {}, ...children) {
+  const node = document.createElement(tag);
+  for (const [key, value] of Object.entries(attrs)) {
+    if (key === "class") node.className = value;
+    else if (key.startsWith("on")) node.addEventListener(key.slice(2), value);
+    else node.setAttribute(key, value);
+  }
+  for (const child of children) {
+    if (child != null) node.append(child);
+  }
+  return node;
+}
+
+function svg(tag, attrs = {}) {
+  const node = document.createElementNS("http://www.w3.org/2000/svg", tag);
+  for (const [key, value] of Object.entries(attrs)) node.setAttribute(key, value);
+  return node;
+}
+
+const icons = { success: "✓", failed: "✗", skipped: "⊘", running: "●", pending: "○", queued: "◌" };
+
+function statusIcon(status) {
+  return el("span", { class: "status-" + status }, icons[status] || "○");
+}
+
+function formatTime(value) {
+  if (!value) return "";
+  return new Date(value).toLocaleString();
+}
+
+function formatDuration(ms) {
+  const s = Math.round(ms / 1000);
+  if (s < 60) return s + "s";
+  if (s < 3600) return Math.floor(s / 60) + "m " + (s % 60) + "s";
+  return Math.floor(s / 3600) + "h " + Math.floor((s % 3600) / 60) + "m";
+}
+
+// Sign-in
+
+function showLogin(message) {
+  document.getElementById("app").hidden = true;
+  document.getElementById("logout").hidden = true;
+  document.getElementById("login").hidden = false;
+  document.getElementById("login-error").textContent = message;
+}
+
+document.getElementById("login").addEventListener("submit", (e) => {
+  e.preventDefault();
+  localStorage.setItem(tokenKey, document.getElementById("token").value);
+  document.getElementById("login").hidden = true;
+  start();
+});
+
+document.getElementById("logout").addEventListener("click", () => {
+  localStorage.removeItem(tokenKey);
+  closeStream();
+  showLogin("");
+});
+
+// Lists
+
+async function refreshLists() {
+  const [runs, sessions] = await Promise.all([getJSON("/runs"), getJSON("/sessions?limit=50")]);
+  const serverRuns = new Set(runs.map((r) => r.id));
+
+  const runList = document.getElementById("runs");
+  runList.replaceChildren(
+    ...(runs.length ? runs.map((r) => listItem("run", r.id, r.project, r.status, r.start_time)) : [el("li", { class: "empty" }, "No runs yet")])
+  );
+
+  const sessionList = document.getElementById("sessions");
+  sessionList.replaceChildren(
+    ...(sessions.length
+      ? sessions.map((s) =>
+          // Runs of this server open with their graph and live output
+          serverRuns.has(s.run_id)
+            ? listItem("run", s.run_id, s.project, s.success ? "success" : "failed", s.start_time)
+            : listItem("session", s.run_id, s.project, s.success ? "success" : "failed", s.start_time)
+        )
+      : [el("li", { class: "empty" }, "No sessions")])
+  );
+}
+
+function listItem(kind, id, project, status, startTime) {
+  const item = el(
+    "li",
+    { title: id, onclick: () => select({ kind, id, project }) },
+    statusIcon(status),
+    el("span", { class: "name" }, project),
+    el("span", { class: "dim" }, formatTime(startTime))
+  );
+  if (selected && selected.id === id) item.classList.add("selected");
+  return item;
+}
+
+// Run detail
+
+function select(target) {
+  selected = target;
+  closeStream();
+  for (const item of document.querySelectorAll("nav li")) {
+    item.classList.toggle("selected", item.title === target.id);
+  }
+  if (target.kind === "run") showRun(target.id);
+  else showSession(target.project, target.id);
+}
+
+function closeStream() {
+  if (stream) stream.abort();
+  stream = null;
+  clearTimeout(pollTimer);
+}
+
+async function showRun(id) {
+  const detail = document.getElementById("detail");
+  const log = el("pre", { class: "log" });
+  const info = await getJSON("/runs/" + encodeURIComponent(id));
+  if (!selected || selected.id !== id) return; // Another run was selected meanwhile
+  const summary = el("div");
+  detail.replaceChildren(summary, el("h3", {}, "Output"), log);
+  renderSummary(summary, info);
+
+  // Task results only arrive with status events, so poll while running
+  const poll = async () => {
+    if (!selected || selected.id !== id) return;
+    const latest = await getJSON("/runs/" + encodeURIComponent(id)).catch(() => null);
+    if (latest) renderSummary(summary, latest);
+    if (!latest || active(latest.status)) pollTimer = setTimeout(poll, 2000);
+  };
+  if (active(info.status)) pollTimer = setTimeout(poll, 2000);
+
+  streamEvents(id, (event, data) => {
+    if (event === "log") {
+      const atBottom = log.scrollTop + log.clientHeight >= log.scrollHeight - 4;
+      log.append(data + "\n");
+      if (atBottom) log.scrollTop = log.scrollHeight;
+    } else if (event === "status") {
+      renderSummary(summary, JSON.parse(data));
+    }
+  });
+}
+
+async function showSession(project, id) {
+  const detail = document.getElementById("detail");
+  const info = await getJSON("/sessions/" + encodeURIComponent(project) + "/" + encodeURIComponent(id));
+  if (!selected || selected.id !== id) return;
+  const summary = el("div");
+  detail.replaceChildren(summary);
+  renderSummary(summary, info);
+}
+
+// streamEvents reads the run's server-sent events. EventSource cannot send
+// the token header, so the stream is read with fetch.
+async function streamEvents(id, onEvent) {
+  const controller = new AbortController();
+  stream = controller;
+
+  let resp;
+  try {
+    resp = await api("/runs/" + encodeURIComponent(id) + "/events", { signal: controller.signal });
+  } catch {
+    return;
+  }
+
+  const reader = resp.body.pipeThrough(new TextDecoderStream()).getReader();
+  let buffer = "";
+  try {
+    for (;;) {
+      const { value, done } = await reader.read();
+      if (done) break;
+      buffer += value;
+
+      let end;
+      while ((end = buffer.indexOf("\n\n")) >= 0) {
+        const block = buffer.slice(0, end);
+        buffer = buffer.slice(end + 2);
+
+        let event = "message";
+        const data = [];
+        for (const line of block.split("\n")) {
+          if (line.startsWith("event: ")) event = line.slice(7);
+          else if (line.startsWith("data: ")) data.push(line.slice(6));
+        }
+        onEvent(event, data.join("\n"));
+      }
+    }
+  } catch {
+    // Aborted when another run is selected
+  }
+}
+
+function renderSummary(container, info) {
+  const results = new Map((info.tasks || []).map((t) => [t.name, t]));
+  const end = info.end_time ? new Date(info.end_time) : new Date();
+  const meta = [info.id, formatTime(info.start_time)];
+  if (info.start_time) meta.push(formatDuration(end - new Date(info.start_time)));
+  if (info.user) meta.push("by " + info.user);
+  if (info.config_file) meta.push(info.config_file);
+
+  const children = [
+    el("h2", {}, statusIcon(info.status), " " + info.project + " ", el("span", { class: "status-" + info.status }, info.status)),
+    el("p", { class: "meta" }, meta.join(" · ")),
+  ];
+
+  if (info.graph && info.graph.length) {
+    children.push(el("h3", {}, "Graph"), renderGraph(info.graph, results, info.status));
+  }
+
+  children.push(el("h3", {}, "Tasks"));
+  const names = info.graph && info.graph.length ? info.graph.map((n) => n.name) : [...results.keys()];
+  if (names.length) {
+    const rows = names.map((name) => {
+      const t = results.get(name);
+      const status = t ? t.status || "success" : taskPending(info.status);
+      return el(
+        "tr",
+        {},
+        el("td", {}, statusIcon(status), " " + name),
+        el("td", { class: "status-" + status }, status),
+        el("td", {}, t ? t.duration : ""),
+        el("td", {}, t && t.status === "failed" ? "exit " + t.exit_code : "")
+      );
+    });
+    children.push(el("table", {}, el("tr", {}, el("th", {}, "Task"), el("th", {}, "Status"), el("th", {}, "Duration"), el("th")), ...rows));
+  } else {
+    children.push(el("p", { class: "empty" }, "No task results yet."));
+  }
+
+  container.replaceChildren(...children);
+}
+
+// active reports whether a run has yet to finish.
+function active(status) {
+  return status === "running" || status === "queued";
+}
+
+// taskPending is the status shown for a task without a result.
+function taskPending(runStatus) {
+  return active(runStatus) ? "pending" : "skipped";
+}
+
+// renderGraph draws the tasks in columns by execution level, with an edge
+// from each dependency to its dependent. Critical path tasks are outlined.
+function renderGraph(nodes, results, runStatus) {
+  const boxW = 150, boxH = 30, gapX = 60, gapY = 14, pad = 10;
+
+  const columns = [];
+  for (const node of nodes) {
+    (columns[node.level] ||= []).push(node);
+  }
+  const pos = new Map();
+  columns.forEach((column, level) => {
+    column.forEach((node, row) => {
+      pos.set(node.name, { x: pad + level * (boxW + gapX), y: pad + row * (boxH + gapY) });
+    });
+  });
+
+  const rows = Math.max(...columns.map((c) => (c ? c.length : 0)));
+  const width = pad * 2 + columns.length * boxW + (columns.length - 1) * gapX;
+  const height = pad * 2 + rows * boxH + (rows - 1) * gapY;
+  const root = svg("svg", { width, height, viewBox: `0 0 ${width} ${height}` });
+
+  for (const node of nodes) {
+    const to = pos.get(node.name);
+    for (const dep of node.needs || []) {
+      const from = pos.get(dep);
+      if (!from) continue;
+      const x1 = from.x + boxW, y1 = from.y + boxH / 2, x2 = to.x, y2 = to.y + boxH / 2;
+      const mid = (x1 + x2) / 2;
+      root.append(svg("path", { d: `M${x1},${y1} C${mid},${y1} ${mid},${y2} ${x2},${y2}` }));
+    }
+  }
+
+  for (const node of nodes) {
+    const { x, y } = pos.get(node.name);
+    const result = results.get(node.name);
+    const status = result ? result.status || "success" : taskPending(runStatus);
+    const group = svg("g", { class: "node-" + status + (node.critical ? " critical" : "") });
+    const title = svg("title");
+    title.textContent = node.name + " (" + status + ")" + (node.critical ? ", critical path" : "");
+    const label = svg("text", { x: x + boxW / 2, y: y + boxH / 2 });
+    label.textContent = (icons[status] || "") + " " + (node.name.length > 18 ? node.name.slice(0, 17) + "…" : node.name);
+    group.append(title, svg("rect", { x, y, width: boxW, height: boxH, rx: 4 }), label);
+    root.append(group);
+  }
+
+  return el("div", { class: "graph" }, root);
+}
+
+// Startup
+
+let refreshing = false;
+
+async function start() {
+  try {
+    await refreshLists();
+  } catch (err) {
+    if (err.message === "unauthorized") return; // The sign-in form is showing
+  }
+  document.getElementById("app").hidden = false;
+  document.getElementById("logout").hidden = !localStorage.getItem(tokenKey);
+
+  if (!refreshing) {
+    refreshing = true;
+    setInterval(() => refreshLists().catch(() => {}), refreshMs);
+  }
+}
+
+start();
@@ -0,0 +1,322 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/adityaraj/agentflow/internal/events"
+	"github.com/adityaraj/agentflow/internal/state"
+	"github.com/adityaraj/agentflow/internal/webhook"
+)
+
+// taskDurationBuckets are the upper bounds, in seconds, of the task duration
+// histogram: from quick shell tasks to long agent sessions.
+var taskDurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+// taskLabels identify a task series.
+type taskLabels struct {
+	agent, tool string
+}
+
+// taskStatusLabels identify a finished task series.
+type taskStatusLabels struct {
+	taskLabels
+	status string
+}
+
+// histogram counts observations into cumulative buckets.
+type histogram struct {
+	counts []uint64 // Per bucket of taskDurationBuckets, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(taskDurationBuckets))
+	}
+	for i, le := range taskDurationBuckets {
+		if v <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// metrics holds the Prometheus metrics of the server's runs since it
+// started.
+type metrics struct {
+	mu            sync.Mutex
+	runsActive    int
+	runsFinished  map[string]uint64 // By status
+	tasksActive   map[taskLabels]int
+	tasksFinished map[taskStatusLabels]uint64
+	taskDurations map[taskLabels]*histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		runsFinished:  make(map[string]uint64),
+		tasksActive:   make(map[taskLabels]int),
+		tasksFinished: make(map[taskStatusLabels]uint64),
+		taskDurations: make(map[taskLabels]*histogram),
+	}
+}
+
+func (m *metrics) runStarted() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runsActive++
+}
+
+func (m *metrics) runFinished(status string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runsActive--
+	m.runsFinished[status]++
+}
+
+func (m *metrics) taskStarted(l taskLabels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tasksActive[l]++
+}
+
+// taskEnded marks a started task as no longer running, without counting it
+// as finished, for tasks of runs that exited before finishing them.
+func (m *metrics) taskEnded(l taskLabels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tasksActive[l]--
+}
+
+// taskFinished counts a finished task. Skipped tasks have no duration.
+func (m *metrics) taskFinished(l taskLabels, started bool, status string, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if started {
+		m.tasksActive[l]--
+	}
+	m.tasksFinished[taskStatusLabels{l, status}]++
+	if status == state.StatusSkipped {
+		return
+	}
+	h := m.taskDurations[l]
+	if h == nil {
+		h = &histogram{}
+		m.taskDurations[l] = h
+	}
+	h.observe(seconds)
+}
+
+// write writes the metrics in the Prometheus text format. queued is the
+// number of runs waiting for a free slot, and webhookQueue the number of
+// undelivered webhook events, or -1 if unknown.
+func (m *metrics) write(w io.Writer, queued, webhookQueue int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP cortex_runs_active Runs in progress.")
+	fmt.Fprintln(w, "# TYPE cortex_runs_active gauge")
+	fmt.Fprintf(w, "cortex_runs_active %d\n", m.runsActive)
+
+	fmt.Fprintln(w, "# HELP cortex_runs_queued Runs waiting for a free slot.")
+	fmt.Fprintln(w, "# TYPE cortex_runs_queued gauge")
+	fmt.Fprintf(w, "cortex_runs_queued %d\n", queued)
+
+	fmt.Fprintln(w, "# HELP cortex_runs_total Finished runs by status.")
+	fmt.Fprintln(w, "# TYPE cortex_runs_total counter")
+	for _, status := range []string{StatusSuccess, StatusFailed} {
+		fmt.Fprintf(w, "cortex_runs_total{status=%s} %d\n", quoteLabel(status), m.runsFinished[status])
+	}
+
+	fmt.Fprintln(w, "# HELP cortex_tasks_active Tasks in progress by agent and tool.")
+	fmt.Fprintln(w, "# TYPE cortex_tasks_active gauge")
+	for _, l := range sortedTaskLabels(m.tasksActive) {
+		fmt.Fprintf(w, "cortex_tasks_active{%s} %d\n", l.labels(), m.tasksActive[l])
+	}
+
+	fmt.Fprintln(w, "# HELP cortex_tasks_total Finished tasks by agent, tool, and status.")
+	fmt.Fprintln(w, "# TYPE cortex_tasks_total counter")
+	finished := make([]taskStatusLabels, 0, len(m.tasksFinished))
+	for l := range m.tasksFinished {
+		finished = append(finished, l)
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		if finished[i].taskLabels != finished[j].taskLabels {
+			return finished[i].less(finished[j].taskLabels)
+		}
+		return finished[i].status < finished[j].status
+	})
+	for _, l := range finished {
+		fmt.Fprintf(w, "cortex_tasks_total{%s,status=%s} %d\n", l.labels(), quoteLabel(l.status), m.tasksFinished[l])
+	}
+
+	fmt.Fprintln(w, "# HELP cortex_task_duration_seconds Duration of tasks that ran, by agent and tool.")
+	fmt.Fprintln(w, "# TYPE cortex_task_duration_seconds histogram")
+	for _, l := range sortedTaskLabels(m.taskDurations) {
+		h := m.taskDurations[l]
+		var cumulative uint64
+		for i, le := range taskDurationBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "cortex_task_duration_seconds_bucket{%s,le=\"%s\"} %d\n", l.labels(), formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(w, "cortex_task_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", l.labels(), h.count)
+		fmt.Fprintf(w, "cortex_task_duration_seconds_sum{%s} %s\n", l.labels(), formatFloat(h.sum))
+		fmt.Fprintf(w, "cortex_task_duration_seconds_count{%s} %d\n", l.labels(), h.count)
+	}
+
+	if webhookQueue >= 0 {
+		fmt.Fprintln(w, "# HELP cortex_webhook_queue_depth Webhook events waiting to be delivered again.")
+		fmt.Fprintln(w, "# TYPE cortex_webhook_queue_depth gauge")
+		fmt.Fprintf(w, "cortex_webhook_queue_depth %d\n", webhookQueue)
+	}
+}
+
+func (l taskLabels) labels() string {
+	return "agent=" + quoteLabel(l.agent) + ",tool=" + quoteLabel(l.tool)
+}
+
+func (l taskLabels) less(o taskLabels) bool {
+	if l.agent != o.agent {
+		return l.agent < o.agent
+	}
+	return l.tool < o.tool
+}
+
+func sortedTaskLabels[V any](m map[taskLabels]V) []taskLabels {
+	keys := make([]taskLabels, 0, len(m))
+	for l := range m {
+		keys = append(keys, l)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].less(keys[j]) })
+	return keys
+}
+
+// quoteLabel quotes a label value, escaping as the text format requires.
+func quoteLabel(v string) string {
+	v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+	return `"` + v + `"`
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// eventLog follows the events file a run's `cortex run` process appends to
+// (see events.FileEnv), applying its task events to the metrics.
+type eventLog struct {
+	mu      sync.Mutex
+	path    string
+	offset  int64
+	partial []byte                // Incomplete last line
+	started map[string]taskLabels // Tasks that started and have not finished
+}
+
+func newEventLog(path string) *eventLog {
+	return &eventLog{path: path, started: make(map[string]taskLabels)}
+}
+
+// follow applies the events written since the last call.
+func (l *eventLog) follow(m *metrics) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.read(m)
+}
+
+// close applies the remaining events, ends the tasks the run left
+// unfinished, and removes the file.
+func (l *eventLog) close(m *metrics) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.read(m)
+	for _, labels := range l.started {
+		m.taskEnded(labels)
+	}
+	l.started = nil
+	_ = os.Remove(l.path)
+}
+
+// read applies complete lines after the offset. Callers hold l.mu.
+func (l *eventLog) read(m *metrics) {
+	if l.started == nil {
+		return // Closed
+	}
+	f, err := os.Open(l.path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if _, err := f.Seek(l.offset, io.SeekStart); err != nil {
+		return
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return
+	}
+	l.offset += int64(len(data))
+
+	data = append(l.partial, data...)
+	end := bytes.LastIndexByte(data, '\n')
+	l.partial = append([]byte(nil), data[end+1:]...)
+	if end < 0 {
+		return
+	}
+
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		var r events.Record
+		if err := json.Unmarshal(line, &r); err != nil {
+			continue
+		}
+		labels := taskLabels{agent: r.Agent, tool: r.Tool}
+		switch r.Type {
+		case events.RecordTaskStarted:
+			l.started[r.Task] = labels
+			m.taskStarted(labels)
+		case events.RecordTaskFinished:
+			_, started := l.started[r.Task]
+			delete(l.started, r.Task)
+			m.taskFinished(labels, started, r.Status, r.Seconds)
+		}
+	}
+}
+
+// handleMetrics serves the metrics in the Prometheus text format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !s.canView(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	queued := len(s.queue)
+	logs := make([]*eventLog, 0, len(s.runs))
+	for _, rn := range s.runs {
+		if rn.events != nil {
+			logs = append(logs, rn.events)
+		}
+	}
+	s.mu.Unlock()
+	for _, l := range logs {
+		l.follow(s.metrics)
+	}
+
+	depth := -1
+	if queue, err := webhook.DefaultQueue(); err == nil {
+		if deliveries, err := queue.List(); err == nil {
+			depth = len(deliveries)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metrics.write(w, queued, depth)
+}
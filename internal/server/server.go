@@ -0,0 +1,727 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/events"
+	"github.com/adityaraj/agentflow/internal/proc"
+	"github.com/adityaraj/agentflow/internal/state"
+)
+
+// Run statuses reported by the API.
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// maxRequestSize caps the body of a run request, inline Cortexfile included.
+const maxRequestSize = 1 << 20
+
+// interruptGrace is how long a run may take to stop after being interrupted
+// on shutdown before it is killed.
+const interruptGrace = 30 * time.Second
+
+// ansiPattern matches terminal escape sequences, which the console output of
+// a run may contain even with --no-color.
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+// Config configures a Server.
+type Config struct {
+	Executable string // Path of the cortex binary runs are started with
+	Auth       *Auth  // Token authentication; nil accepts every local request
+	WorkDir    string // Directory relative paths and inline workflows resolve from
+	MaxRuns    int    // Runs at once, more wait in a queue; 0 is unlimited
+	QueueFile  string // Where the queue is kept across restarts; empty keeps it in memory
+}
+
+// Server runs workflows on request, each as a `cortex run` child process,
+// and keeps their output for the status, logs, and events endpoints.
+type Server struct {
+	cfg  Config
+	ctx  context.Context // Cancelled on shutdown, interrupting runs
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	runs map[string]*run
+
+	active int          // Runs whose process is running
+	queue  []*queuedRun // Runs waiting for a free slot, in order
+
+	metrics  *metrics            // Served at /metrics
+	triggers map[string]*trigger // Webhooks registered with AddWorkflow
+}
+
+// RunRequest is the body of POST /runs. Exactly one of File and YAML is set.
+type RunRequest struct {
+	File string            `json:"file"` // Path of a Cortexfile on the server
+	YAML string            `json:"yaml"` // Or an inline Cortexfile
+	Dir  string            `json:"dir"`  // Directory an inline workflow runs in (default: the server's)
+	Vars map[string]string `json:"vars"` // Workflow variables, like --var
+}
+
+// queuedRun is a run waiting for a free slot, as kept in the queue file.
+type queuedRun struct {
+	Request RunRequest `json:"request"`
+	Info    RunInfo    `json:"info"`
+	Role    string     `json:"role"` // Role of the user who started it, checked again when it starts
+}
+
+// RunInfo describes a run started by the server.
+type RunInfo struct {
+	ID         string      `json:"id"`
+	Status     string      `json:"status"`
+	User       string      `json:"user,omitempty"`
+	ConfigFile string      `json:"config_file,omitempty"` // Empty for inline workflows
+	Project    string      `json:"project"`
+	RunDir     string      `json:"run_dir,omitempty"`
+	StartTime  time.Time   `json:"start_time"` // When it was queued, until it starts
+	EndTime    *time.Time  `json:"end_time,omitempty"`
+	ExitCode   int         `json:"exit_code"`
+	Tasks      []TaskInfo  `json:"tasks,omitempty"`
+	Graph      []GraphNode `json:"graph,omitempty"` // Dependency graph of the workflow
+}
+
+// TaskInfo is the result of a finished task of a run.
+type TaskInfo struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+	Duration string `json:"duration"`
+}
+
+// run is a running or finished workflow and its console output.
+type run struct {
+	mu      sync.Mutex
+	info    RunInfo
+	lines   []string
+	changed chan struct{} // Closed and replaced whenever lines or status change
+	events  *eventLog     // Task events of the run, for metrics
+}
+
+// New creates a Server. Runs are interrupted when ctx is cancelled.
+func New(ctx context.Context, cfg Config) *Server {
+	if cfg.Auth == nil {
+		cfg.Auth = &Auth{}
+	}
+	return &Server{
+		cfg:  cfg,
+		ctx:  ctx,
+		runs: make(map[string]*run),
+
+		metrics:  newMetrics(),
+		triggers: make(map[string]*trigger),
+	}
+}
+
+// Handler returns the HTTP handler of the API and the dashboard. Everything
+// but /healthz, the dashboard's static files, and webhooks (which carry
+// their own secret) requires a token when auth is enabled.
+func (s *Server) Handler() http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("POST /runs", s.handleCreateRun)
+	api.HandleFunc("GET /runs", s.handleListRuns)
+	api.HandleFunc("GET /runs/{id}", s.handleGetRun)
+	api.HandleFunc("GET /runs/{id}/logs", s.handleLogs)
+	api.HandleFunc("GET /runs/{id}/events", s.handleEvents)
+	api.HandleFunc("GET /sessions", s.handleListSessions)
+	api.HandleFunc("GET /sessions/{project}/{id}", s.handleGetSession)
+	api.HandleFunc("GET /triggers", s.handleListTriggers)
+	api.HandleFunc("GET /metrics", s.handleMetrics)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("POST /triggers/{name}", s.handleTrigger)
+	dashboard := dashboardHandler()
+	mux.Handle("GET /{$}", dashboard)
+	mux.Handle("GET /ui/", dashboard)
+	mux.Handle("/", s.cfg.Auth.Middleware(api))
+	return mux
+}
+
+// Wait blocks until every run has finished.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Server) handleCreateRun(w http.ResponseWriter, r *http.Request) {
+	user, _ := UserFromContext(r.Context())
+
+	// Browsers send forms and text/plain bodies cross-origin without asking
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "request body must be application/json")
+		return
+	}
+
+	var req RunRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if msg := authorize(user, req, nil); msg != "" {
+		writeError(w, http.StatusForbidden, msg)
+		return
+	}
+
+	configPath, dir, cleanup, err := s.prepareConfig(req, user)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err == nil {
+		err = config.ValidateWithFile(cfg, configPath)
+	}
+	if err != nil {
+		cleanup()
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if msg := authorize(user, req, cfg); msg != "" {
+		cleanup()
+		writeError(w, http.StatusForbidden, msg)
+		return
+	}
+
+	rn, err := s.submit(req, configPath, dir, user, buildGraph(cfg), cleanup)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, rn.snapshot())
+}
+
+// authorize returns why user may not start the workflow of req, loaded as
+// cfg, or "" if they may. With a nil cfg, only the request is checked, before
+// the workflow is loaded. Inline workflows need the admin role, as they can
+// read any file on the server into prompts and send its environment to any
+// endpoint, and so do workflows reading files outside their directory.
+func authorize(user User, req RunRequest, cfg *config.AgentflowConfig) string {
+	switch {
+	case !user.CanTrigger(false):
+		return "your role may not trigger workflows"
+	case req.YAML != "" && !user.CanTrigger(true):
+		return "only admins may trigger inline workflows"
+	case cfg == nil || user.CanTrigger(true):
+		return ""
+	case cfg.Privileged():
+		return "only admins may trigger workflows that run commands, write files, change state, or use another endpoint"
+	case len(cfg.Sources) > 0:
+		if outside := cfg.FilesOutside(filepath.Dir(cfg.Sources[0])); len(outside) > 0 {
+			return "only admins may trigger workflows that read files outside the Cortexfile's directory: " + strings.Join(outside, ", ")
+		}
+	}
+	return ""
+}
+
+// prepareConfig returns the Cortexfile and working directory of a request
+// of user. An inline Cortexfile is written to a temporary directory, which
+// cleanup removes.
+func (s *Server) prepareConfig(req RunRequest, user User) (configPath, dir string, cleanup func(), err error) {
+	cleanup = func() {}
+
+	switch {
+	case (req.File == "") == (req.YAML == ""):
+		return "", "", cleanup, errors.New("set exactly one of 'file' and 'yaml'")
+
+	case req.File != "":
+		configPath, err = s.resolve(req.File, user)
+		if err != nil {
+			return "", "", cleanup, err
+		}
+		if info, err := os.Stat(configPath); err != nil || info.IsDir() {
+			return "", "", cleanup, fmt.Errorf("no Cortexfile at %s", configPath)
+		}
+		return configPath, filepath.Dir(configPath), cleanup, nil
+	}
+
+	dir = s.cfg.WorkDir
+	if req.Dir != "" {
+		if dir, err = s.resolve(req.Dir, user); err != nil {
+			return "", "", cleanup, err
+		}
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", "", cleanup, fmt.Errorf("no directory at %s", dir)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "cortex-serve-*")
+	if err != nil {
+		return "", "", cleanup, err
+	}
+	cleanup = func() { os.RemoveAll(tmpDir) }
+
+	configPath = filepath.Join(tmpDir, "Cortexfile.yml")
+	if err := os.WriteFile(configPath, []byte(req.YAML), 0600); err != nil {
+		cleanup()
+		return "", "", func() {}, err
+	}
+	return configPath, dir, cleanup, nil
+}
+
+// resolve makes a path from a request of user absolute against the server's
+// working directory. Only admins, who may run inline workflows anyway, may
+// name a path outside it; for other users, it is an error, symbolic links
+// followed.
+func (s *Server) resolve(path string, user User) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.cfg.WorkDir, path)
+	}
+	path = filepath.Clean(path)
+	if user.CanTrigger(true) {
+		return path, nil
+	}
+
+	root, target := s.cfg.WorkDir, path
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		root = real
+	}
+	if real, err := filepath.EvalSymlinks(target); err == nil {
+		target = real
+	}
+	if rel, err := filepath.Rel(root, target); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s is outside the server's working directory", path)
+	}
+	return path, nil
+}
+
+// submit starts a validated run, or queues it when MaxRuns runs are already
+// running. A queued run keeps its ID, and its Cortexfile is read again when
+// its turn comes.
+func (s *Server) submit(req RunRequest, configPath, dir string, user User, graph []GraphNode, cleanup func()) (*run, error) {
+	id := state.NewRunID()
+
+	displayPath := configPath
+	if req.YAML != "" {
+		displayPath = "" // A temporary file, not worth reporting
+	}
+	rn := &run{
+		info: RunInfo{
+			ID:         id,
+			Status:     StatusQueued,
+			User:       user.Name,
+			ConfigFile: displayPath,
+			Project:    filepath.Base(dir),
+			StartTime:  time.Now(),
+			Graph:      graph,
+		},
+		changed: make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cfg.MaxRuns > 0 && (s.active >= s.cfg.MaxRuns || len(s.queue) > 0) {
+		cleanup()
+		s.queue = append(s.queue, &queuedRun{Request: req, Info: rn.info, Role: user.Role})
+		if err := s.saveQueue(); err != nil {
+			s.queue = s.queue[:len(s.queue)-1]
+			return nil, fmt.Errorf("failed to queue run: %w", err)
+		}
+		s.runs[id] = rn
+		return rn, nil
+	}
+
+	if err := s.start(rn, req, configPath, dir, cleanup); err != nil {
+		return nil, err
+	}
+	s.runs[id] = rn
+	return rn, nil
+}
+
+// start runs `cortex run` for the workflow in the background, collecting
+// its console output. Callers hold s.mu.
+func (s *Server) start(rn *run, req RunRequest, configPath, dir string, cleanup func()) error {
+	id := rn.info.ID
+	args := []string{"run", "-f", configPath, "--no-color", "--compact", "--interactive=false"}
+	names := make([]string, 0, len(req.Vars))
+	for name := range req.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "--var", name+"="+req.Vars[name])
+	}
+
+	cmd := exec.CommandContext(s.ctx, s.cfg.Executable, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), state.RunIDEnv+"="+id)
+	if rn.info.User != "" {
+		cmd.Env = append(cmd.Env, "CORTEX_USER="+rn.info.User)
+	}
+	// The run appends its task events to a file the metrics read from
+	eventsFile, err := os.CreateTemp("", "cortex-events-*.jsonl")
+	if err != nil {
+		cleanup()
+		return err
+	}
+	eventsFile.Close()
+	cmd.Env = append(cmd.Env, events.FileEnv+"="+eventsFile.Name())
+	proc.Interruptible(cmd)
+	cmd.Cancel = func() error { return proc.Interrupt(cmd.Process) }
+	cmd.WaitDelay = interruptGrace
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cleanup()
+		os.Remove(eventsFile.Name())
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+
+	if err := cmd.Start(); err != nil {
+		cleanup()
+		os.Remove(eventsFile.Name())
+		return fmt.Errorf("failed to start run: %w", err)
+	}
+	s.metrics.runStarted()
+	s.active++
+
+	rn.mu.Lock()
+	rn.info.Status = StatusRunning
+	rn.info.StartTime = time.Now()
+	rn.events = newEventLog(eventsFile.Name())
+	rn.notify()
+	rn.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cleanup()
+
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			rn.addLine(ansiPattern.ReplaceAllString(strings.TrimRight(scanner.Text(), "\r"), ""))
+		}
+
+		err := cmd.Wait()
+		rn.events.close(s.metrics)
+		rn.finish(cmd.ProcessState.ExitCode(), err == nil)
+		if err == nil {
+			s.metrics.runFinished(StatusSuccess)
+		} else {
+			s.metrics.runFinished(StatusFailed)
+		}
+
+		s.mu.Lock()
+		s.active--
+		s.dispatch()
+		s.mu.Unlock()
+	}()
+
+	return nil
+}
+
+// dispatch starts queued runs while there are free slots. Once the server
+// is shutting down, runs stay queued for the next start. Callers hold s.mu.
+func (s *Server) dispatch() {
+	if len(s.queue) == 0 {
+		return
+	}
+	for len(s.queue) > 0 && (s.cfg.MaxRuns == 0 || s.active < s.cfg.MaxRuns) && s.ctx.Err() == nil {
+		q := s.queue[0]
+		s.queue = s.queue[1:]
+		rn := s.runs[q.Info.ID]
+
+		configPath, dir, cleanup, err := s.prepareConfig(q.Request, q.user())
+		if err == nil {
+			err = q.authorize(configPath)
+			if err != nil {
+				cleanup()
+			}
+		}
+		if err == nil {
+			err = s.start(rn, q.Request, configPath, dir, cleanup)
+		}
+		if err != nil {
+			// The Cortexfile may have gone or changed while the run waited
+			rn.addLine("cortex: " + err.Error())
+			rn.finish(-1, false)
+		}
+	}
+	_ = s.saveQueue()
+}
+
+// authorize checks again that the user who queued the run may start it,
+// as its Cortexfile may have changed while it waited.
+func (q *queuedRun) authorize(configPath string) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err == nil {
+		err = config.ValidateWithFile(cfg, configPath)
+	}
+	if err != nil {
+		return err
+	}
+	if msg := authorize(q.user(), q.Request, cfg); msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// user returns the user who queued the run.
+func (q *queuedRun) user() User {
+	return User{Name: q.Info.User, Role: q.Role}
+}
+
+// LoadQueue restores the runs queued when the server last stopped, from
+// Config.QueueFile, and starts as many as there are free slots for. It
+// returns the number of runs restored.
+func (s *Server) LoadQueue() (int, error) {
+	if s.cfg.QueueFile == "" {
+		return 0, nil
+	}
+	data, err := os.ReadFile(s.cfg.QueueFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var queue []*queuedRun
+	if err := json.Unmarshal(data, &queue); err != nil {
+		return 0, fmt.Errorf("invalid queue file %s: %w", s.cfg.QueueFile, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, q := range queue {
+		q.Info.Status = StatusQueued
+		s.runs[q.Info.ID] = &run{info: q.Info, changed: make(chan struct{})}
+		s.queue = append(s.queue, q)
+	}
+	s.dispatch()
+	return len(queue), nil
+}
+
+// saveQueue writes the queue to Config.QueueFile. Inline Cortexfiles are
+// kept in it, so it is only readable by the owner. Callers hold s.mu.
+func (s *Server) saveQueue() error {
+	if s.cfg.QueueFile == "" {
+		return nil
+	}
+	queue := s.queue
+	if queue == nil {
+		queue = []*queuedRun{}
+	}
+	data, err := json.MarshalIndent(queue, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.cfg.QueueFile), 0700); err != nil {
+		return err
+	}
+	tmp := s.cfg.QueueFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.cfg.QueueFile)
+}
+
+func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	if !s.canView(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	infos := make([]RunInfo, 0, len(s.runs))
+	for _, rn := range s.runs {
+		info := rn.snapshot()
+		info.Tasks = nil
+		info.Graph = nil
+		infos = append(infos, info)
+	}
+	s.mu.Unlock()
+
+	// Newest first
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].StartTime.After(infos[j].StartTime)
+	})
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	if rn := s.lookup(w, r); rn != nil {
+		writeJSON(w, http.StatusOK, rn.snapshot())
+	}
+}
+
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	rn := s.lookup(w, r)
+	if rn == nil {
+		return
+	}
+
+	rn.mu.Lock()
+	lines := rn.lines
+	rn.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// handleEvents streams a run as server-sent events: a "status" event with
+// the run info, a "log" event per line of console output (starting from the
+// first), and a final "status" event once the run finishes.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	rn := s.lookup(w, r)
+	if rn == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	rc := http.NewResponseController(w)
+
+	writeEvent(w, "status", rn.snapshot())
+	sent := 0
+	for {
+		rn.mu.Lock()
+		lines := rn.lines[sent:]
+		done := rn.finished()
+		changed := rn.changed
+		rn.mu.Unlock()
+
+		for _, line := range lines {
+			fmt.Fprintf(w, "event: log\ndata: %s\n\n", line)
+		}
+		sent += len(lines)
+
+		if done {
+			writeEvent(w, "status", rn.snapshot())
+			rc.Flush()
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// lookup returns the run named in the request path, writing an error
+// response if there is none or the user may not view it.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) *run {
+	if !s.canView(w, r) {
+		return nil
+	}
+
+	s.mu.Lock()
+	rn := s.runs[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if rn == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("run %q not found", r.PathValue("id")))
+	}
+	return rn
+}
+
+func (s *Server) canView(w http.ResponseWriter, r *http.Request) bool {
+	if user, _ := UserFromContext(r.Context()); !user.CanView() {
+		writeError(w, http.StatusForbidden, "your role may not view runs")
+		return false
+	}
+	return true
+}
+
+func (rn *run) addLine(line string) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	rn.lines = append(rn.lines, line)
+	rn.notify()
+}
+
+// finished reports whether the run has ended. Callers hold rn.mu.
+func (rn *run) finished() bool {
+	return rn.info.Status == StatusSuccess || rn.info.Status == StatusFailed
+}
+
+func (rn *run) finish(exitCode int, success bool) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	now := time.Now()
+	rn.info.EndTime = &now
+	rn.info.ExitCode = exitCode
+	rn.info.Status = StatusFailed
+	if success {
+		rn.info.Status = StatusSuccess
+	}
+	rn.notify()
+}
+
+// notify wakes the event streams waiting on the run. Callers hold rn.mu.
+func (rn *run) notify() {
+	close(rn.changed)
+	rn.changed = make(chan struct{})
+}
+
+// snapshot returns the run info with the results of the tasks finished so
+// far, read from the run directory.
+func (rn *run) snapshot() RunInfo {
+	rn.mu.Lock()
+	info := rn.info
+	rn.mu.Unlock()
+
+	runDir, _, err := state.ResolveRunDir(info.Project, info.ID)
+	if err != nil {
+		return info
+	}
+	info.RunDir = runDir
+
+	results, _ := state.ListTaskResults(runDir)
+	for _, t := range results {
+		info.Tasks = append(info.Tasks, taskInfo(t))
+	}
+	return info
+}
+
+func taskInfo(t state.TaskResult) TaskInfo {
+	return TaskInfo{
+		Name:     t.TaskName,
+		Status:   t.Status,
+		ExitCode: t.ExitCode,
+		Duration: t.Duration,
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, v any) {
+	data, _ := json.Marshal(v)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
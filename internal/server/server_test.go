@@ -0,0 +1,417 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/adityaraj/agentflow/internal/config"
+)
+
+// writeCortexfile writes a Cortexfile to dir and returns its path.
+func writeCortexfile(t *testing.T, dir, yaml string) string {
+	t.Helper()
+	path := filepath.Join(dir, "Cortexfile.yml")
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestAuthorize tests which workflows each role may start.
+func TestAuthorize(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "project")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const agents = "agents:\n  ai: {tool: claude-code}\n  api: {tool: api, base_url: 'https://example.com/v1', api_key_env: AWS_SECRET_ACCESS_KEY}\n"
+	runner := User{Name: "runner", Role: config.RoleRunner}
+	admin := User{Name: "admin", Role: config.RoleAdmin}
+	viewer := User{Name: "viewer", Role: config.RoleViewer}
+
+	tests := []struct {
+		name string
+		user User
+		req  RunRequest
+		yaml string
+		want string // Part of the reason, or "" if allowed
+	}{
+		{"runner, read-only workflow", runner, RunRequest{File: "Cortexfile.yml"}, "tasks:\n  a: {agent: ai, prompt: Hi}\n", ""},
+		{"viewer", viewer, RunRequest{File: "Cortexfile.yml"}, "tasks:\n  a: {agent: ai, prompt: Hi}\n", "may not trigger"},
+		{"runner, inline workflow", runner, RunRequest{YAML: "tasks: {}"}, "tasks:\n  a: {agent: ai, prompt: Hi}\n", "inline"},
+		{"runner, shell task", runner, RunRequest{File: "Cortexfile.yml"}, "tasks:\n  a: {agent: ai, tool: shell, command: ls}\n", "run commands"},
+		{"runner, other endpoint", runner, RunRequest{File: "Cortexfile.yml"}, "tasks:\n  a: {agent: api, prompt: Hi}\n", "another endpoint"},
+		{"runner, file outside", runner, RunRequest{File: "Cortexfile.yml"}, "tasks:\n  a: {agent: ai, prompt_file: ../secret.txt}\n", "outside the Cortexfile's directory"},
+		{"admin, file outside", admin, RunRequest{File: "Cortexfile.yml"}, "tasks:\n  a: {agent: ai, prompt_file: ../secret.txt}\n", ""},
+		{"admin, other endpoint", admin, RunRequest{File: "Cortexfile.yml"}, "tasks:\n  a: {agent: api, prompt: Hi}\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.LoadConfig(writeCortexfile(t, dir, agents+tt.yaml))
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			got := authorize(tt.user, tt.req, cfg)
+			if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+				t.Errorf("authorize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestServer returns a server whose runs execute a script in place of
+// cortex, which prints its arguments and exits once the file in
+// CORTEX_TEST_RELEASE exists, along with its working directory.
+func newTestServer(t *testing.T, auth *Auth, maxRuns int) (*Server, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("runs a shell script in place of cortex")
+	}
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work")
+	if err := os.Mkdir(work, 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "cortex")
+	content := "#!/bin/sh\necho \"$@\"\nwhile [ ! -e \"$CORTEX_TEST_RELEASE\" ]; do sleep 0.01; done\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	release := filepath.Join(dir, "release")
+	t.Setenv("CORTEX_TEST_RELEASE", release)
+	if err := os.WriteFile(release, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(context.Background(), Config{
+		Executable: script,
+		Auth:       auth,
+		WorkDir:    work,
+		MaxRuns:    maxRuns,
+		QueueFile:  filepath.Join(dir, "queue.json"),
+	})
+	t.Cleanup(s.Wait)
+	return s, work
+}
+
+// hold makes the runs of newTestServer wait until the returned function is
+// called.
+func hold(t *testing.T) (release func()) {
+	t.Helper()
+	path := os.Getenv("CORTEX_TEST_RELEASE")
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// request sends a request to handler, with a JSON body unless body is "",
+// and returns the response.
+func request(handler http.Handler, method, target, token, body string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Host = "localhost:8080"
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// runStatus returns the status of a run of s.
+func runStatus(s *Server, id string) string {
+	s.mu.Lock()
+	rn := s.runs[id]
+	s.mu.Unlock()
+	if rn == nil {
+		return ""
+	}
+	return rn.snapshot().Status
+}
+
+// TestServer_Auth tests tokens, roles, and the checks of browser requests.
+func TestServer_Auth(t *testing.T) {
+	auth, err := NewAuth(config.ServerConfig{Users: []config.ServerUser{
+		{Name: "alice", Token: "admin-token", Role: config.RoleAdmin},
+		{Name: "ci", Token: "runner-token", Role: config.RoleRunner},
+		{Name: "bob", Token: "viewer-token", Role: config.RoleViewer},
+	}})
+	if err != nil {
+		t.Fatalf("NewAuth() error = %v", err)
+	}
+	s, work := newTestServer(t, auth, 0)
+	writeCortexfile(t, work, "agents:\n  ai: {tool: claude-code}\ntasks:\n  a: {agent: ai, prompt: Hi}\n")
+	outside := filepath.Dir(work)
+	writeCortexfile(t, outside, "agents:\n  ai: {tool: claude-code}\ntasks:\n  a: {agent: ai, prompt: Hi}\n")
+	handler := s.Handler()
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		token  string
+		body   string
+		header map[string]string
+		want   int
+	}{
+		{"no token", "GET", "/runs", "", "", nil, http.StatusUnauthorized},
+		{"unknown token", "GET", "/runs", "other", "", nil, http.StatusUnauthorized},
+		{"healthz without token", "GET", "/healthz", "", "", nil, http.StatusOK},
+		{"viewer lists runs", "GET", "/runs", "viewer-token", "", nil, http.StatusOK},
+		{"viewer starts a run", "POST", "/runs", "viewer-token", `{"file": "Cortexfile.yml"}`, nil, http.StatusForbidden},
+		{"runner starts a run", "POST", "/runs", "runner-token", `{"file": "Cortexfile.yml"}`, nil, http.StatusAccepted},
+		{"runner starts an inline run", "POST", "/runs", "runner-token", `{"yaml": "tasks: {}"}`, nil, http.StatusForbidden},
+		{"runner names a file with ..", "POST", "/runs", "runner-token", `{"file": "../Cortexfile.yml"}`, nil, http.StatusBadRequest},
+		{"runner names an absolute file", "POST", "/runs", "runner-token", `{"file": "` + filepath.Join(outside, "Cortexfile.yml") + `"}`, nil, http.StatusBadRequest},
+		{"admin names an absolute file", "POST", "/runs", "admin-token", `{"file": "` + filepath.Join(outside, "Cortexfile.yml") + `"}`, nil, http.StatusAccepted},
+		{"form body", "POST", "/runs", "runner-token", "", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, http.StatusUnsupportedMediaType},
+		{"cross-origin", "GET", "/runs", "viewer-token", "", map[string]string{"Origin": "https://example.com"}, http.StatusForbidden},
+		{"same origin", "GET", "/runs", "viewer-token", "", map[string]string{"Origin": "http://localhost:8080"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(handler, tt.method, tt.target, tt.token, tt.body, tt.header)
+			if w.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d (%s)", tt.method, tt.target, w.Code, tt.want, strings.TrimSpace(w.Body.String()))
+			}
+		})
+	}
+}
+
+// TestServer_LocalToken tests that a server without users only answers
+// requests with its token addressed to localhost.
+func TestServer_LocalToken(t *testing.T) {
+	auth := &Auth{}
+	auth.AddLocalToken("local-token")
+	s, _ := newTestServer(t, auth, 0)
+	handler := s.Handler()
+
+	if w := request(handler, "GET", "/runs", "", "", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("without token = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := request(handler, "GET", "/runs", "local-token", "", nil); w.Code != http.StatusOK {
+		t.Errorf("with token = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	r := httptest.NewRequest("GET", "/runs", nil)
+	r.Host = "rebound.example.com:8080"
+	r.Header.Set("Authorization", "Bearer local-token")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Host %s = %d, want %d", r.Host, w.Code, http.StatusForbidden)
+	}
+}
+
+// TestServer_GetSession tests that session lookups stay in the sessions
+// directory, however the run ID is encoded.
+func TestServer_GetSession(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME"} {
+		t.Setenv(env, "")
+	}
+	for _, project := range []string{"project", "other"} {
+		runDir := filepath.Join(home, ".cortex", "sessions", project, "run-1")
+		if err := os.MkdirAll(runDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(runDir, "run.json"), []byte(`{"run_id": "1", "success": true}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, _ := newTestServer(t, nil, 0)
+	handler := s.Handler()
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/sessions/project/1", http.StatusOK},
+		{"/sessions/project/latest", http.StatusOK},
+		{"/sessions/project/2", http.StatusNotFound},
+		{"/sessions/project/x%2F..%2F..%2Fother%2Frun-1", http.StatusBadRequest},
+		{"/sessions/project/x%5C..%5C..%5Cother%5Crun-1", http.StatusBadRequest},
+		{"/sessions/..%2Fsessions%2Fother/1", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := request(handler, "GET", tt.target, "", "", nil); w.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d: %s", tt.target, w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+// TestIsLoopback tests which hosts only refer to the local machine.
+func TestIsLoopback(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"localhost", true},
+		{"LOCALHOST", true},
+		{"127.0.0.1", true},
+		{"127.1.2.3", true},
+		{"::1", true},
+		{"0.0.0.0", false},
+		{"192.168.1.10", false},
+		{"localhost.example.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsLoopback(tt.host); got != tt.want {
+			t.Errorf("IsLoopback(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+// TestServer_Queue tests that runs beyond MaxRuns wait, and start in order
+// as slots free up.
+func TestServer_Queue(t *testing.T) {
+	s, work := newTestServer(t, nil, 1)
+	writeCortexfile(t, work, "agents:\n  ai: {tool: claude-code}\ntasks:\n  a: {agent: ai, prompt: Hi}\n")
+	handler := s.Handler()
+	release := hold(t)
+
+	var ids []string
+	for range 3 {
+		w := request(handler, "POST", "/runs", "", `{"file": "Cortexfile.yml"}`, nil)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("POST /runs = %d (%s)", w.Code, w.Body.String())
+		}
+		var info RunInfo
+		if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, info.ID)
+	}
+
+	want := []string{StatusRunning, StatusQueued, StatusQueued}
+	for i, id := range ids {
+		if got := runStatus(s, id); got != want[i] {
+			t.Errorf("run %d status = %q, want %q", i, got, want[i])
+		}
+	}
+
+	release()
+	s.Wait()
+	for i, id := range ids {
+		if got := runStatus(s, id); got != StatusSuccess {
+			t.Errorf("run %d status = %q, want %q", i, got, StatusSuccess)
+		}
+	}
+}
+
+// TestServer_LoadQueue tests that runs queued when the server last stopped
+// start, including without a limit on runs.
+func TestServer_LoadQueue(t *testing.T) {
+	for _, maxRuns := range []int{0, 1} {
+		t.Run(fmt.Sprintf("max runs %d", maxRuns), func(t *testing.T) {
+			s, work := newTestServer(t, nil, maxRuns)
+			writeCortexfile(t, work, "agents:\n  ai: {tool: claude-code}\ntasks:\n  a: {agent: ai, prompt: Hi}\n")
+			queue := []*queuedRun{
+				{Request: RunRequest{File: "Cortexfile.yml"}, Info: RunInfo{ID: "run-1", Status: StatusQueued}, Role: config.RoleRunner},
+				{Request: RunRequest{File: "Cortexfile.yml"}, Info: RunInfo{ID: "run-2", Status: StatusQueued}, Role: config.RoleRunner},
+			}
+			data, err := json.Marshal(queue)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(s.cfg.QueueFile, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			n, err := s.LoadQueue()
+			if err != nil || n != 2 {
+				t.Fatalf("LoadQueue() = %d, %v, want 2 runs", n, err)
+			}
+			s.Wait()
+			for _, id := range []string{"run-1", "run-2"} {
+				if got := runStatus(s, id); got != StatusSuccess {
+					t.Errorf("%s status = %q, want %q", id, got, StatusSuccess)
+				}
+			}
+		})
+	}
+}
+
+// TestServer_Trigger tests the secrets of GitHub and http triggers.
+func TestServer_Trigger(t *testing.T) {
+	auth, err := NewAuth(config.ServerConfig{Users: []config.ServerUser{{Name: "alice", Token: "admin-token", Role: config.RoleAdmin}}})
+	if err != nil {
+		t.Fatalf("NewAuth() error = %v", err)
+	}
+	s, work := newTestServer(t, auth, 0)
+	t.Setenv("TEST_HOOK_SECRET", "hook-secret")
+	path := writeCortexfile(t, work, `agents:
+  ai: {tool: claude-code}
+tasks:
+  a: {agent: ai, prompt: Hi}
+triggers:
+  gh: {type: github, secret_env: TEST_HOOK_SECRET}
+  plain: {type: http, secret_env: TEST_HOOK_SECRET}
+`)
+	if _, err := s.AddWorkflow(path); err != nil {
+		t.Fatalf("AddWorkflow() error = %v", err)
+	}
+	handler := s.Handler()
+
+	const payload = `{"ref": "refs/heads/main", "after": "abc"}`
+	mac := hmac.New(sha256.New, []byte("hook-secret"))
+	mac.Write([]byte(payload))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name   string
+		target string
+		header map[string]string
+		want   int
+	}{
+		{"github without signature", "/triggers/gh", map[string]string{"X-GitHub-Event": "push"}, http.StatusUnauthorized},
+		{"github with wrong signature", "/triggers/gh", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=00"}, http.StatusUnauthorized},
+		{"github ping", "/triggers/gh", map[string]string{"X-GitHub-Event": "ping", "X-Hub-Signature-256": signature}, http.StatusOK},
+		{"github push", "/triggers/gh", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": signature}, http.StatusAccepted},
+		{"http without token", "/triggers/plain", nil, http.StatusUnauthorized},
+		{"http with API token", "/triggers/plain", map[string]string{"Authorization": "Bearer admin-token"}, http.StatusUnauthorized},
+		{"http with token", "/triggers/plain", map[string]string{"X-Cortex-Token": "hook-secret"}, http.StatusAccepted},
+		{"unknown trigger", "/triggers/other", nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(handler, "POST", tt.target, "", payload, tt.header)
+			if w.Code != tt.want {
+				t.Errorf("POST %s = %d, want %d (%s)", tt.target, w.Code, tt.want, strings.TrimSpace(w.Body.String()))
+			}
+		})
+	}
+
+	// Without a secret, triggers are refused once users are configured
+	path = writeCortexfile(t, t.TempDir(), "agents:\n  ai: {tool: claude-code}\ntasks:\n  a: {agent: ai, prompt: Hi}\ntriggers:\n  open: {type: http}\n")
+	if _, err := s.AddWorkflow(path); err == nil || !strings.Contains(err.Error(), "secret_env") {
+		t.Errorf("AddWorkflow() error = %v, want secret_env required", err)
+	}
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/adityaraj/agentflow/internal/config"
+)
+
+// trigger is a webhook of a served workflow.
+type trigger struct {
+	name   string
+	file   string // Cortexfile the trigger is declared in
+	cfg    config.TriggerConfig
+	secret string // Empty when the trigger has no secret_env
+}
+
+// TriggerInfo describes a registered trigger.
+type TriggerInfo struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	File   string `json:"file"`
+	Path   string `json:"path"`   // URL path the webhook is sent to
+	Secret bool   `json:"secret"` // Whether requests must carry the secret
+}
+
+// AddWorkflow registers the triggers of a Cortexfile, so its webhooks start
+// runs at POST /triggers/<name>. The file is read again for every run, but
+// changes to its triggers need a restart. Returns an error if the file is
+// invalid, a secret is not set, a trigger has no secret_env while server
+// users are configured, or a trigger name is already taken.
+func (s *Server) AddWorkflow(path string) ([]TriggerInfo, error) {
+	path, err := s.resolve(path, User{Role: config.RoleAdmin})
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.ValidateWithFile(cfg, path); err != nil {
+		return nil, err
+	}
+	if len(cfg.Triggers) == 0 {
+		return nil, fmt.Errorf("%s declares no triggers", path)
+	}
+
+	names := make([]string, 0, len(cfg.Triggers))
+	for name := range cfg.Triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := make([]*trigger, 0, len(names))
+	for _, name := range names {
+		if other, exists := s.triggers[name]; exists {
+			return nil, fmt.Errorf("trigger %q of %s is already declared in %s", name, path, other.file)
+		}
+		t := &trigger{name: name, file: path, cfg: cfg.Triggers[name]}
+		if env := t.cfg.SecretEnv; env != "" {
+			if t.secret = os.Getenv(env); t.secret == "" {
+				return nil, fmt.Errorf("trigger %q: environment variable %s is not set", name, env)
+			}
+		} else if s.cfg.Auth.HasUsers() {
+			// Its runs are an admin's, so it can't be open to anyone
+			return nil, fmt.Errorf("trigger %q: 'secret_env' is required when server.users is configured", name)
+		}
+		added = append(added, t)
+	}
+
+	infos := make([]TriggerInfo, 0, len(added))
+	for _, t := range added {
+		s.triggers[t.name] = t
+		infos = append(infos, t.info())
+	}
+	return infos, nil
+}
+
+func (t *trigger) info() TriggerInfo {
+	return TriggerInfo{
+		Name:   t.name,
+		Type:   t.cfg.Type,
+		File:   t.file,
+		Path:   "/triggers/" + t.name,
+		Secret: t.secret != "",
+	}
+}
+
+func (s *Server) handleListTriggers(w http.ResponseWriter, r *http.Request) {
+	if !s.canView(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	infos := make([]TriggerInfo, 0, len(s.triggers))
+	for _, t := range s.triggers {
+		infos = append(infos, t.info())
+	}
+	s.mu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// handleTrigger starts a run for a webhook. It does not use API tokens:
+// GitHub webhooks are checked against their signature, and http triggers
+// against the X-Cortex-Token header. A trigger without a secret, only
+// allowed without server users, takes local requests that no web page sent.
+// Runs are attributed to "trigger:<name>".
+func (s *Server) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	t := s.triggers[r.PathValue("name")]
+	s.mu.Unlock()
+	if t == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("trigger %q not found", r.PathValue("name")))
+		return
+	}
+	if t.secret == "" && !s.cfg.Auth.checkBrowser(w, r, true) {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if !t.authorized(r, body) {
+		writeError(w, http.StatusUnauthorized, "missing or invalid webhook secret")
+		return
+	}
+
+	var payload any
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			writeError(w, http.StatusBadRequest, "payload is not JSON: "+err.Error())
+			return
+		}
+	}
+
+	if t.cfg.Type == config.TriggerGitHub {
+		event := r.Header.Get("X-GitHub-Event")
+		if event == "ping" {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "pong"})
+			return
+		}
+		if ok, reason := t.cfg.AcceptsEvent(event, payload); !ok {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ignored", "reason": reason})
+			return
+		}
+	}
+
+	cfg, err := config.LoadConfig(t.file)
+	if err == nil {
+		err = config.ValidateWithFile(cfg, t.file)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	req := RunRequest{File: t.file, Vars: t.cfg.PayloadVars(payload)}
+	user := User{Name: "trigger:" + t.name, Role: config.RoleAdmin}
+	rn, err := s.submit(req, t.file, filepath.Dir(t.file), user, buildGraph(cfg), func() {})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, rn.snapshot())
+}
+
+// authorized checks a webhook request against the trigger's secret.
+func (t *trigger) authorized(r *http.Request, body []byte) bool {
+	if t.secret == "" {
+		return true
+	}
+
+	if t.cfg.Type == config.TriggerGitHub {
+		sig, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+		if !ok {
+			return false
+		}
+		got, err := hex.DecodeString(sig)
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(t.secret))
+		mac.Write(body)
+		return hmac.Equal(got, mac.Sum(nil))
+	}
+
+	token := r.Header.Get("X-Cortex-Token")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(t.secret)) == 1
+}
@@ -0,0 +1,102 @@
+package state
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArtifactsDir is the run subdirectory that collected artifacts are copied into.
+const ArtifactsDir = "artifacts"
+
+// SaveArtifacts copies the files matching patterns into
+// artifacts/<task>/ under the run directory and returns their paths relative
+// to the run directory, with forward slashes. Matched directories are copied recursively. Each
+// file keeps its path below the non-glob prefix of its pattern, so
+// "reports/*/junit.xml" collects "<dir>/junit.xml" for every match.
+func (s *Store) SaveArtifacts(taskName string, patterns []string) ([]string, error) {
+	destRoot := filepath.Join(s.runDir, ArtifactsDir, TaskFileName(taskName))
+	seen := make(map[string]bool)
+	var saved []string
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return saved, fmt.Errorf("invalid artifacts pattern %q: %w", pattern, err)
+		}
+		base := globBase(pattern)
+
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.Type().IsRegular() {
+					return nil
+				}
+
+				rel, err := filepath.Rel(base, path)
+				if err != nil || strings.HasPrefix(rel, "..") {
+					rel = filepath.Base(path)
+				}
+				dest := filepath.Join(destRoot, rel)
+				if seen[dest] {
+					return nil
+				}
+				seen[dest] = true
+
+				if err := copyFile(path, dest); err != nil {
+					return err
+				}
+				runRel, _ := filepath.Rel(s.runDir, dest)
+				saved = append(saved, filepath.ToSlash(runRel))
+				return nil
+			})
+			if err != nil {
+				return saved, fmt.Errorf("failed to collect artifact %s: %w", match, err)
+			}
+		}
+	}
+
+	sort.Strings(saved)
+	return saved, nil
+}
+
+// globBase returns the directory part of pattern before the first element
+// containing glob metacharacters. For a plain path it is the parent directory.
+func globBase(pattern string) string {
+	parts := strings.Split(filepath.ToSlash(pattern), "/")
+	for i, part := range parts {
+		if strings.ContainsAny(part, "*?[") {
+			return filepath.FromSlash(strings.Join(parts[:i], "/") + "/")
+		}
+	}
+	return filepath.Dir(pattern)
+}
+
+// copyFile copies src to dest, creating parent directories.
+func copyFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,157 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TaskCacheEntry records the last successful execution of a task.
+type TaskCacheEntry struct {
+	Hash      string    `json:"hash"`   // Hash of tool, model, and expanded prompt
+	Stdout    string    `json:"stdout"` // Output reused when the task is skipped
+	RunID     string    `json:"run_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TaskCache persists per-task execution hashes for incremental runs.
+// It is stored per project alongside the run directories, one file per task,
+// so runs of the project at the same time only write the tasks they ran.
+type TaskCache struct {
+	dir     string
+	mu      sync.Mutex
+	entries map[string]TaskCacheEntry
+	changed map[string]bool // Tasks Put since the cache was loaded
+}
+
+// TaskCacheEntriesDir is the subdirectory of a project's sessions directory
+// holding the task cache, and legacyTaskCacheFile the file holding the
+// entries of all tasks before, still read for tasks without their own.
+const (
+	TaskCacheEntriesDir = "task-cache"
+	legacyTaskCacheFile = "task-cache.json"
+)
+
+// taskCacheFile is the content of a task's file in the task cache. The name
+// is kept, as the file name of a task may be shared by others.
+type taskCacheFile struct {
+	Task string `json:"task"`
+	TaskCacheEntry
+}
+
+// LoadTaskCache loads the project's task cache, returning an empty cache if none exists.
+func (s *Store) LoadTaskCache() (*TaskCache, error) {
+	projectDir := filepath.Dir(s.runDir)
+	cache := &TaskCache{
+		dir:     filepath.Join(projectDir, TaskCacheEntriesDir),
+		entries: make(map[string]TaskCacheEntry),
+		changed: make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(filepath.Join(projectDir, legacyTaskCacheFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read task cache: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &cache.entries); err != nil {
+			return nil, fmt.Errorf("failed to parse task cache: %w", err)
+		}
+	}
+
+	files, err := os.ReadDir(cache.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read task cache: %w", err)
+	}
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(cache.dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read task cache: %w", err)
+		}
+		var entry taskCacheFile
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse task cache %s: %w", file.Name(), err)
+		}
+		if entry.Task != "" {
+			cache.entries[entry.Task] = entry.TaskCacheEntry
+		}
+	}
+	return cache, nil
+}
+
+// Get returns the cache entry for a task.
+func (c *TaskCache) Get(taskName string) (TaskCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[taskName]
+	return entry, ok
+}
+
+// Put records a successful task execution.
+func (c *TaskCache) Put(taskName string, entry TaskCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[taskName] = entry
+	c.changed[taskName] = true
+}
+
+// Save writes the entries Put since the cache was loaded to disk, leaving
+// the other tasks' as they are, which another run may have changed since.
+func (c *TaskCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.changed) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create task cache directory: %w", err)
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.changed)) {
+		data, err := json.MarshalIndent(taskCacheFile{Task: name, TaskCacheEntry: c.entries[name]}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal task cache: %w", err)
+		}
+		if err := writeFileAtomic(filepath.Join(c.dir, TaskFileName(name)+".json"), data); err != nil {
+			return fmt.Errorf("failed to write task cache: %w", err)
+		}
+		delete(c.changed, name)
+	}
+	return nil
+}
+
+// CacheDirsDir is the subdirectory of a project's sessions directory that
+// held the task cache directories before they moved to the cache directory.
+const CacheDirsDir = "cache"
+
+// CacheDirEnv is the environment variable holding a task's cache directory.
+const CacheDirEnv = "CORTEX_CACHE_DIR"
+
+// TaskCacheDir creates, if needed, and returns a task's cache directory: a
+// directory of the project kept across runs, where the task can keep
+// downloads, installed dependencies, and other work for later runs to reuse.
+// A cache directory left in the sessions directory by an older version is
+// moved there first.
+func (s *Store) TaskCacheDir(taskName string) (string, error) {
+	dir := filepath.Join(s.cacheDir, TaskFileName(taskName))
+	old := filepath.Join(filepath.Dir(s.runDir), CacheDirsDir, TaskFileName(taskName))
+	if _, err := os.Stat(old); err == nil {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			if err := os.MkdirAll(s.cacheDir, 0755); err == nil && os.Rename(old, dir) == nil {
+				_ = os.Remove(filepath.Dir(old)) // Fails while other tasks' are left
+			}
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTaskCache_ConcurrentRuns tests that runs of a project saving their
+// task caches at the same time keep each other's entries.
+func TestTaskCache_ConcurrentRuns(t *testing.T) {
+	base := t.TempDir()
+	first, err := NewStoreWithPath(base, "project")
+	if err != nil {
+		t.Fatalf("NewStoreWithPath() error = %v", err)
+	}
+	second, err := NewStoreWithPath(base, "project")
+	if err != nil {
+		t.Fatalf("NewStoreWithPath() error = %v", err)
+	}
+
+	firstCache, err := first.LoadTaskCache()
+	if err != nil {
+		t.Fatalf("LoadTaskCache() error = %v", err)
+	}
+	secondCache, err := second.LoadTaskCache()
+	if err != nil {
+		t.Fatalf("LoadTaskCache() error = %v", err)
+	}
+	firstCache.Put("build", TaskCacheEntry{Hash: "b1", RunID: first.RunID()})
+	firstCache.Put("a/b", TaskCacheEntry{Hash: "slash"})
+	secondCache.Put("test", TaskCacheEntry{Hash: "t1", RunID: second.RunID()})
+	secondCache.Put("a_b", TaskCacheEntry{Hash: "underscore"})
+	if err := firstCache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := secondCache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	cache, err := first.LoadTaskCache()
+	if err != nil {
+		t.Fatalf("LoadTaskCache() error = %v", err)
+	}
+	for task, hash := range map[string]string{"build": "b1", "test": "t1", "a_b": "underscore"} {
+		if entry, ok := cache.Get(task); !ok || entry.Hash != hash {
+			t.Errorf("Get(%q) = %+v, %v, want hash %q", task, entry, ok, hash)
+		}
+	}
+	// a/b and a_b share a file, which holds the task saved last
+	if entry, ok := cache.Get("a/b"); ok {
+		t.Errorf("Get(%q) = %+v, want no entry", "a/b", entry)
+	}
+}
+
+// TestTaskCache_Legacy tests that the entries of the single file of older
+// versions are read, and replaced by the task's own file once saved.
+func TestTaskCache_Legacy(t *testing.T) {
+	base := t.TempDir()
+	store, err := NewStoreWithPath(base, "project")
+	if err != nil {
+		t.Fatalf("NewStoreWithPath() error = %v", err)
+	}
+	legacy := `{"build": {"hash": "old"}, "test": {"hash": "old"}}`
+	if err := os.WriteFile(filepath.Join(filepath.Dir(store.RunDir()), legacyTaskCacheFile), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := store.LoadTaskCache()
+	if err != nil {
+		t.Fatalf("LoadTaskCache() error = %v", err)
+	}
+	cache.Put("build", TaskCacheEntry{Hash: "new"})
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	cache, err = store.LoadTaskCache()
+	if err != nil {
+		t.Fatalf("LoadTaskCache() error = %v", err)
+	}
+	for task, hash := range map[string]string{"build": "new", "test": "old"} {
+		if entry, ok := cache.Get(task); !ok || entry.Hash != hash {
+			t.Errorf("Get(%q) = %+v, %v, want hash %q", task, entry, ok, hash)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package state
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/adityaraj/agentflow/internal/redact"
+)
+
+// File change status values recorded in FileChange.Status.
+const (
+	ChangeAdded    = "added"
+	ChangeModified = "modified"
+	ChangeDeleted  = "deleted"
+)
+
+// FileChange is a file a write-enabled task created, modified, or deleted.
+type FileChange struct {
+	Path    string `json:"path"`              // Relative to the repository, or to the working directory outside git
+	Status  string `json:"status"`            // added, modified, or deleted
+	Added   int    `json:"added,omitempty"`   // Lines added (git repositories only)
+	Deleted int    `json:"deleted,omitempty"` // Lines deleted (git repositories only)
+}
+
+// SaveTaskDiff saves the diff of the files a task changed to <task>.diff
+// in the run directory and returns its name. Secret values are redacted.
+func (s *Store) SaveTaskDiff(taskName, diff string) (string, error) {
+	name := TaskFileName(taskName) + ".diff"
+	if err := writeFileAtomic(filepath.Join(s.runDir, name), []byte(redact.Secrets(diff, s.secrets))); err != nil {
+		return "", fmt.Errorf("failed to write diff: %w", err)
+	}
+	return name, nil
+}
+
+// ChangeSummary describes the files the task changed, such as
+// "3 files changed (+12 -4)", or returns "" if it changed none.
+func (r *TaskResult) ChangeSummary() string {
+	if len(r.Changes) == 0 {
+		return ""
+	}
+	added, deleted := 0, 0
+	for _, c := range r.Changes {
+		added += c.Added
+		deleted += c.Deleted
+	}
+	summary := fmt.Sprintf("%d files changed", len(r.Changes))
+	if len(r.Changes) == 1 {
+		summary = "1 file changed"
+	}
+	if added > 0 || deleted > 0 {
+		summary += fmt.Sprintf(" (+%d -%d)", added, deleted)
+	}
+	return summary
+}
@@ -0,0 +1,112 @@
+package state
+
+import "time"
+
+// TaskEstimate is the average of a task's successful past runs.
+type TaskEstimate struct {
+	Runs     int           // Number of results averaged
+	Duration time.Duration // Average duration
+	CostUSD  float64       // Average reported cost
+}
+
+// History holds averages of past task results of a project, by task name
+// and model. Failed and skipped results are left out, since their duration
+// says little about a full run.
+type History struct {
+	Runs   int // Number of runs read
+	tasks  map[historyKey]*historyTotal
+	models map[string]*historyTotal
+}
+
+type historyKey struct {
+	task  string
+	model string
+}
+
+type historyTotal struct {
+	runs     int
+	duration time.Duration
+	costUSD  float64
+}
+
+func (t *historyTotal) add(r *TaskResult) {
+	t.runs++
+	t.duration += r.Elapsed()
+	t.costUSD += r.CostUSD
+}
+
+func (t *historyTotal) estimate() TaskEstimate {
+	return TaskEstimate{
+		Runs:     t.runs,
+		Duration: t.duration / time.Duration(t.runs),
+		CostUSD:  t.costUSD / float64(t.runs),
+	}
+}
+
+// LoadHistory reads the task results of a project's most recent runs from
+// ~/.cortex/sessions. limit caps the number of runs read (0 = all).
+func LoadHistory(project string, limit int) (*History, error) {
+	baseDir, err := getCortexDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadHistoryFromPath(baseDir, project, limit)
+}
+
+// LoadHistoryFromPath reads run history from a custom base path.
+func LoadHistoryFromPath(baseDir, project string, limit int) (*History, error) {
+	h := &History{
+		tasks:  make(map[historyKey]*historyTotal),
+		models: make(map[string]*historyTotal),
+	}
+
+	sessions, err := ListSessionsFromPath(baseDir, SessionFilter{Project: project, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range sessions {
+		results, err := ListTaskResults(s.RunDir)
+		if err != nil {
+			continue // Skip runs we can't read
+		}
+		h.Runs++
+
+		for i := range results {
+			r := &results[i]
+			if !r.Success || r.Status == StatusSkipped || r.EndTime.IsZero() {
+				continue
+			}
+
+			key := historyKey{task: r.TaskName, model: r.Model}
+			if h.tasks[key] == nil {
+				h.tasks[key] = &historyTotal{}
+			}
+			h.tasks[key].add(r)
+
+			if r.Model != "" {
+				if h.models[r.Model] == nil {
+					h.models[r.Model] = &historyTotal{}
+				}
+				h.models[r.Model].add(r)
+			}
+		}
+	}
+
+	return h, nil
+}
+
+// Estimate returns the average of past runs of a task with the given model.
+// A task that never ran with the model is estimated from the average of all
+// tasks that used it, which is reported by exact being false. ok is false
+// when there is no history to go on.
+func (h *History) Estimate(task, model string) (estimate TaskEstimate, exact, ok bool) {
+	if t := h.tasks[historyKey{task: task, model: model}]; t != nil {
+		return t.estimate(), true, true
+	}
+	if t := h.models[model]; model != "" && t != nil {
+		return t.estimate(), false, true
+	}
+	return TaskEstimate{}, false, false
+}
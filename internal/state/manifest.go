@@ -0,0 +1,220 @@
+package state
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ManifestFile is the name of a run's signed manifest in its run directory.
+const ManifestFile = "run.manifest.json"
+
+// ManifestAlgorithm is the signature algorithm of manifests.
+const ManifestAlgorithm = "hmac-sha256"
+
+// ErrNotSigned is returned by VerifyRun for a run without a manifest.
+var ErrNotSigned = errors.New("run is not signed")
+
+// Manifest lists a digest of every file of a run, signed with the project's
+// signing key, so archived runs can be shown not to have been changed since.
+type Manifest struct {
+	RunID     string            `json:"run_id"`
+	Project   string            `json:"project"`
+	SignedAt  time.Time         `json:"signed_at"`
+	Algorithm string            `json:"algorithm"`
+	KeyID     string            `json:"key_id"`    // Identifies the key without revealing it
+	Files     map[string]string `json:"files"`     // SHA-256 of each file, by path relative to the run directory
+	Signature string            `json:"signature"` // HMAC of the manifest without its signature
+}
+
+// Verification is the result of checking a run against its manifest.
+type Verification struct {
+	Manifest       *Manifest `json:"manifest"`
+	SignatureValid bool      `json:"signature_valid"`
+	KeyMatches     bool      `json:"key_matches"` // The key has the ID the run was signed with
+	Modified       []string  `json:"modified,omitempty"`
+	Missing        []string  `json:"missing,omitempty"`
+	Added          []string  `json:"added,omitempty"` // Files not listed in the manifest
+}
+
+// OK reports whether the signature is valid and every file is unchanged.
+func (v *Verification) OK() bool {
+	return v.SignatureValid && len(v.Modified) == 0 && len(v.Missing) == 0 && len(v.Added) == 0
+}
+
+// SetSigningKey sets the key runs are signed with when their result is
+// saved. An empty key disables signing.
+func (s *Store) SetSigningKey(key string) {
+	s.signingKey = key
+}
+
+// signRun writes the manifest of the run directory.
+func (s *Store) signRun() error {
+	files, err := hashRunFiles(s.runDir)
+	if err != nil {
+		return err
+	}
+
+	m := &Manifest{
+		RunID:     s.runID,
+		Project:   filepath.Base(s.projectDir),
+		SignedAt:  time.Now().UTC().Truncate(time.Second),
+		Algorithm: ManifestAlgorithm,
+		KeyID:     keyID(s.signingKey),
+		Files:     files,
+	}
+	m.Signature, err = m.sign(s.signingKey)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(s.runDir, ManifestFile), data)
+}
+
+// VerifyRun checks the files of a run directory against its manifest and
+// the manifest's signature against key. Returns ErrNotSigned if the run has
+// no manifest.
+func VerifyRun(runDir, key string) (*Verification, error) {
+	data, err := os.ReadFile(filepath.Join(runDir, ManifestFile))
+	if os.IsNotExist(err) {
+		return nil, ErrNotSigned
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if m.Algorithm != ManifestAlgorithm {
+		return nil, fmt.Errorf("unsupported manifest algorithm %q", m.Algorithm)
+	}
+
+	v := &Verification{Manifest: &m, KeyMatches: m.KeyID == keyID(key)}
+	want, err := m.sign(key)
+	if err != nil {
+		return nil, err
+	}
+	v.SignatureValid = hmac.Equal([]byte(want), []byte(m.Signature))
+
+	files, err := hashRunFiles(runDir)
+	if err != nil {
+		return nil, err
+	}
+	for name, sum := range m.Files {
+		got, ok := files[name]
+		switch {
+		case !ok:
+			v.Missing = append(v.Missing, name)
+		case got != sum:
+			v.Modified = append(v.Modified, name)
+		}
+	}
+	for name := range files {
+		if _, ok := m.Files[name]; !ok {
+			v.Added = append(v.Added, name)
+		}
+	}
+	slices.Sort(v.Modified)
+	slices.Sort(v.Missing)
+	slices.Sort(v.Added)
+	return v, nil
+}
+
+// sign returns the hex HMAC-SHA256 of the manifest without its signature.
+func (m Manifest) sign(key string) (string, error) {
+	m.Signature = ""
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// keyID returns a short fingerprint of a signing key.
+func keyID(key string) string {
+	sum := sha256.Sum256([]byte("cortex-signing-key:" + key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// hashRunFiles returns the SHA-256 of every file in a run directory other
+// than its manifest and temporary files, by slash-separated relative path.
+func hashRunFiles(runDir string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(runDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || isAtomicTemp(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(runDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ManifestFile {
+			return nil
+		}
+
+		sum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		files[rel] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash run files: %w", err)
+	}
+	return files, nil
+}
+
+// isAtomicTemp reports whether a file name is of the temporary files of
+// writeFileAtomic, which are left behind when a run is killed mid-write.
+// Other dot-files are hashed like the rest.
+func isAtomicTemp(name string) bool {
+	base, ok := strings.CutSuffix(name, ".tmp")
+	if !ok || !strings.HasPrefix(base, ".") {
+		return false
+	}
+	i := strings.LastIndex(base, "-")
+	if i < 2 {
+		return false
+	}
+	_, err := strconv.ParseUint(base[i+1:], 10, 64)
+	return err == nil
+}
+
+// hashFile returns the hex SHA-256 of a file.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,189 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// signedRun saves a run signed with key, with a task result, an artifact, a
+// dot-file, and a temporary file left by an interrupted write.
+func signedRun(t *testing.T, key string) *Store {
+	t.Helper()
+	store, err := NewStoreWithPath(t.TempDir(), "project")
+	if err != nil {
+		t.Fatalf("NewStoreWithPath() error = %v", err)
+	}
+	store.SetSigningKey(key)
+
+	files := map[string]string{
+		"build.json":                  `{"task_name": "build"}`,
+		"artifacts/build/report.txt":  "all good",
+		"artifacts/build/.env":        "TOKEN=1",
+		".run.json-1234567890.tmp":    "partial",
+		"artifacts/.report-12345.tmp": "partial",
+	}
+	for name, content := range files {
+		path := filepath.Join(store.RunDir(), filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.SaveRunResult(&RunResult{RunID: store.RunID(), Success: true}); err != nil {
+		t.Fatalf("SaveRunResult() error = %v", err)
+	}
+	return store
+}
+
+// TestVerifyRun_Signed tests that a run verifies with its key, and that every
+// file but temporary ones is in the manifest.
+func TestVerifyRun_Signed(t *testing.T) {
+	store := signedRun(t, "secret")
+
+	v, err := VerifyRun(store.RunDir(), "secret")
+	if err != nil {
+		t.Fatalf("VerifyRun() error = %v", err)
+	}
+	if !v.OK() || !v.KeyMatches {
+		t.Errorf("VerifyRun() = %+v, want a valid signature with the matching key", v)
+	}
+
+	want := []string{"artifacts/build/.env", "artifacts/build/report.txt", "build.json", "run.json"}
+	var got []string
+	for name := range v.Manifest.Files {
+		got = append(got, name)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("manifest files = %v, want %v", got, want)
+	}
+}
+
+// TestVerifyRun_Tampered tests that changes to a signed run are reported.
+func TestVerifyRun_Tampered(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		tamper   func(runDir string) error
+		modified []string
+		missing  []string
+		added    []string
+		badSig   bool
+	}{
+		{
+			name: "other key",
+			key:  "other",
+			tamper: func(string) error {
+				return nil
+			},
+			badSig: true,
+		},
+		{
+			name: "modified file",
+			tamper: func(runDir string) error {
+				return os.WriteFile(filepath.Join(runDir, "artifacts", "build", "report.txt"), []byte("all bad"), 0644)
+			},
+			modified: []string{"artifacts/build/report.txt"},
+		},
+		{
+			name: "modified dot-file",
+			tamper: func(runDir string) error {
+				return os.WriteFile(filepath.Join(runDir, "artifacts", "build", ".env"), []byte("TOKEN=2"), 0644)
+			},
+			modified: []string{"artifacts/build/.env"},
+		},
+		{
+			name: "removed file",
+			tamper: func(runDir string) error {
+				return os.Remove(filepath.Join(runDir, "build.json"))
+			},
+			missing: []string{"build.json"},
+		},
+		{
+			name: "added dot-file",
+			tamper: func(runDir string) error {
+				return os.WriteFile(filepath.Join(runDir, ".hidden"), []byte("x"), 0644)
+			},
+			added: []string{".hidden"},
+		},
+		{
+			name: "added file named like a temporary one",
+			tamper: func(runDir string) error {
+				return os.WriteFile(filepath.Join(runDir, ".notes.tmp"), []byte("x"), 0644)
+			},
+			added: []string{".notes.tmp"},
+		},
+		{
+			name: "modified file and manifest",
+			tamper: func(runDir string) error {
+				path := filepath.Join(runDir, "build.json")
+				if err := os.WriteFile(path, []byte(`{"task_name": "forged"}`), 0644); err != nil {
+					return err
+				}
+				sum, err := hashFile(path)
+				if err != nil {
+					return err
+				}
+				manifestPath := filepath.Join(runDir, ManifestFile)
+				data, err := os.ReadFile(manifestPath)
+				if err != nil {
+					return err
+				}
+				var m Manifest
+				if err := json.Unmarshal(data, &m); err != nil {
+					return err
+				}
+				m.Files["build.json"] = sum
+				data, err = json.Marshal(m)
+				if err != nil {
+					return err
+				}
+				return os.WriteFile(manifestPath, data, 0644)
+			},
+			badSig: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := signedRun(t, "secret")
+			if err := tt.tamper(store.RunDir()); err != nil {
+				t.Fatal(err)
+			}
+			key := tt.key
+			if key == "" {
+				key = "secret"
+			}
+
+			v, err := VerifyRun(store.RunDir(), key)
+			if err != nil {
+				t.Fatalf("VerifyRun() error = %v", err)
+			}
+			if v.SignatureValid == tt.badSig {
+				t.Errorf("SignatureValid = %v, want %v", v.SignatureValid, !tt.badSig)
+			}
+			if !slices.Equal(v.Modified, tt.modified) || !slices.Equal(v.Missing, tt.missing) || !slices.Equal(v.Added, tt.added) {
+				t.Errorf("VerifyRun() modified %v, missing %v, added %v; want %v, %v, %v",
+					v.Modified, v.Missing, v.Added, tt.modified, tt.missing, tt.added)
+			}
+			if want := tt.badSig || tt.modified != nil || tt.missing != nil || tt.added != nil; v.OK() == want {
+				t.Errorf("OK() = %v, want %v", v.OK(), !want)
+			}
+		})
+	}
+}
+
+// TestVerifyRun_Unsigned tests that a run saved without a key is reported as
+// not signed.
+func TestVerifyRun_Unsigned(t *testing.T) {
+	store := signedRun(t, "")
+	if _, err := VerifyRun(store.RunDir(), "secret"); !errors.Is(err, ErrNotSigned) {
+		t.Errorf("VerifyRun() error = %v, want ErrNotSigned", err)
+	}
+}
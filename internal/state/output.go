@@ -0,0 +1,91 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// LargeOutputSize is the size above which a task's stdout or stderr is
+// saved to its own file, <task>.stdout or <task>.stderr, instead of inside
+// the task result, which keeps only a preview. Listing runs then doesn't
+// parse output it doesn't show.
+const LargeOutputSize = 64 << 10
+
+// outputPreviewSize is the most output a preview keeps, in bytes.
+const outputPreviewSize = 4 << 10
+
+// preview cuts text down to at most outputPreviewSize bytes, at the end of
+// a line if there is one.
+func preview(text string) string {
+	if len(text) <= outputPreviewSize {
+		return text
+	}
+	cut := outputPreviewSize
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if i := strings.LastIndexByte(text[:cut], '\n'); i > 0 {
+		cut = i + 1
+	}
+	return text[:cut]
+}
+
+// saveLargeOutput moves stdout and stderr larger than LargeOutputSize out
+// of a (redacted) task result to their own files, leaving previews.
+func (s *Store) saveLargeOutput(result *TaskResult) error {
+	for _, out := range []struct {
+		text *string
+		file *string
+		ext  string
+	}{
+		{&result.Stdout, &result.StdoutFile, ".stdout"},
+		{&result.Stderr, &result.StderrFile, ".stderr"},
+	} {
+		*out.file = ""
+		if len(*out.text) <= LargeOutputSize {
+			continue
+		}
+		name := TaskFileName(result.TaskName) + out.ext
+		if err := writeFileAtomic(filepath.Join(s.runDir, name), []byte(*out.text)); err != nil {
+			return err
+		}
+		*out.text, *out.file = preview(*out.text), name
+	}
+	return nil
+}
+
+// LoadOutput replaces the previews of output saved to separate files with
+// the full output, read from runDir.
+func (r *TaskResult) LoadOutput(runDir string) error {
+	for _, out := range []struct {
+		text *string
+		file *string
+	}{
+		{&r.Stdout, &r.StdoutFile},
+		{&r.Stderr, &r.StderrFile},
+	} {
+		if *out.file == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(runDir, *out.file))
+		if err != nil {
+			return fmt.Errorf("failed to read output of task %q: %w", r.TaskName, err)
+		}
+		*out.text, *out.file = string(data), ""
+	}
+	return nil
+}
+
+// LoadOutputs loads the full output of task results listed by
+// ListTaskResults.
+func LoadOutputs(runDir string, results []TaskResult) error {
+	for i := range results {
+		if err := results[i].LoadOutput(runDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
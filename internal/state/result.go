@@ -14,37 +14,67 @@ type TokenUsage struct {
 	CacheWrite   int `json:"cache_write_tokens,omitempty"`
 }
 
+// Task status values recorded in TaskResult.Status.
+const (
+	StatusSuccess   = "success"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+	StatusCancelled = "cancelled"
+)
+
 // TaskResult represents the result of executing a single task.
 type TaskResult struct {
-	TaskName   string     `json:"task_name"`
-	Agent      string     `json:"agent"`
-	Tool       string     `json:"tool"`
-	Model      string     `json:"model,omitempty"`
-	Prompt     string     `json:"prompt"`
-	Stdout     string     `json:"stdout"`
-	Stderr     string     `json:"stderr,omitempty"`
-	Success    bool       `json:"success"`
-	ExitCode   int        `json:"exit_code"`
-	StartTime  time.Time  `json:"start_time"`
-	EndTime    time.Time  `json:"end_time"`
-	Duration   string     `json:"duration"` // Human-readable duration
-	TokenUsage TokenUsage `json:"token_usage,omitempty"`
+	TaskName       string         `json:"task_name"`
+	Agent          string         `json:"agent"`
+	Tool           string         `json:"tool"`
+	Model          string         `json:"model,omitempty"`
+	Prompt         string         `json:"prompt"`
+	OriginalPrompt string         `json:"original_prompt,omitempty"` // Prompt before prompt hooks changed it
+	PromptHooks    []string       `json:"prompt_hooks,omitempty"`    // Prompt hooks run on the prompt, in order
+	Stdout         string         `json:"stdout"`
+	Stderr         string         `json:"stderr,omitempty"`
+	StdoutFile     string         `json:"stdout_file,omitempty"` // Full stdout if too large to keep inline, relative to the run directory
+	StderrFile     string         `json:"stderr_file,omitempty"` // Full stderr if too large to keep inline
+	Success        bool           `json:"success"`
+	ExitCode       int            `json:"exit_code"`
+	Status         string         `json:"status,omitempty"`      // success, failed, skipped, or cancelled
+	SkipReason     string         `json:"skip_reason,omitempty"` // Why a skipped task did not run
+	StartTime      time.Time      `json:"start_time"`
+	EndTime        time.Time      `json:"end_time"`
+	Duration       string         `json:"duration"` // Human-readable duration
+	TokenUsage     TokenUsage     `json:"token_usage,omitempty"`
+	CostUSD        float64        `json:"cost_usd,omitempty"`    // Cost reported by the agent
+	Artifacts      []string       `json:"artifacts,omitempty"`   // Collected files, relative to the run directory
+	LogFile        string         `json:"log_file,omitempty"`    // Streamed output, relative to the run directory
+	Iterations     int            `json:"iterations,omitempty"`  // Runs of a loop task (until)
+	ToolCalls      []ToolCall     `json:"tool_calls,omitempty"`  // Tools the agent used, in order
+	ToolCounts     map[string]int `json:"tool_counts,omitempty"` // Number of calls per tool
+	Changes        []FileChange   `json:"changes,omitempty"`     // Files a write-enabled task changed
+	DiffFile       string         `json:"diff_file,omitempty"`   // Diff of the changes, relative to the run directory
+	Commit         string         `json:"commit,omitempty"`      // Commit of the task's changes, with git settings
+	Truncated      bool           `json:"truncated,omitempty"`   // Output beyond the task's limit was dropped
 }
 
 // RunResult represents the complete result of an agentflow run.
 type RunResult struct {
 	RunID      string       `json:"run_id"`
+	User       string       `json:"user,omitempty"` // Who started the run
 	StartTime  time.Time    `json:"start_time"`
 	EndTime    time.Time    `json:"end_time"`
 	Success    bool         `json:"success"`
 	Tasks      []TaskResult `json:"tasks"`
 	TokenUsage TokenUsage   `json:"token_usage,omitempty"` // Aggregate token usage
+	CostUSD    float64      `json:"cost_usd,omitempty"`    // Aggregate reported cost
+	Error      string       `json:"error,omitempty"`       // Why the run stopped early
+	Cancelled  bool         `json:"cancelled,omitempty"`   // Stopped by an interrupt
 }
 
-// CalculateTotalTokens calculates aggregate token usage from all tasks.
+// CalculateTotalTokens calculates aggregate token usage and cost from all tasks.
 func (r *RunResult) CalculateTotalTokens() {
 	r.TokenUsage = TokenUsage{}
+	r.CostUSD = 0
 	for _, task := range r.Tasks {
+		r.CostUSD += task.CostUSD
 		r.TokenUsage.InputTokens += task.TokenUsage.InputTokens
 		r.TokenUsage.OutputTokens += task.TokenUsage.OutputTokens
 		r.TokenUsage.TotalTokens += task.TokenUsage.TotalTokens
@@ -53,6 +83,17 @@ func (r *RunResult) CalculateTotalTokens() {
 	}
 }
 
+// SkippedCount returns the number of tasks that were skipped.
+func (r *RunResult) SkippedCount() int {
+	count := 0
+	for _, task := range r.Tasks {
+		if task.Status == StatusSkipped {
+			count++
+		}
+	}
+	return count
+}
+
 // NewTaskResult creates a new TaskResult with timing started.
 func NewTaskResult(taskName, agent, tool, model, prompt string) *TaskResult {
 	return &TaskResult{
@@ -71,10 +112,45 @@ func (r *TaskResult) Complete(stdout, stderr string, exitCode int, success bool)
 	r.Stderr = stderr
 	r.ExitCode = exitCode
 	r.Success = success
+	r.Status = StatusFailed
+	if success {
+		r.Status = StatusSuccess
+	}
 	r.EndTime = time.Now()
 	r.Duration = r.EndTime.Sub(r.StartTime).Round(time.Millisecond * 100).String()
 }
 
+// Skip marks the task as skipped. Skipped tasks count as successful so
+// dependents still run; stdout carries any reused output.
+func (r *TaskResult) Skip(reason, stdout string) {
+	r.Stdout = stdout
+	r.Success = true
+	r.Status = StatusSkipped
+	r.SkipReason = reason
+	r.EndTime = time.Now()
+	r.Duration = r.EndTime.Sub(r.StartTime).Round(time.Millisecond * 100).String()
+}
+
+// Cancel marks the task as stopped by an interrupt before it finished, with
+// the output it produced until then.
+func (r *TaskResult) Cancel(stdout, stderr string) {
+	r.Stdout = stdout
+	r.Stderr = stderr
+	r.ExitCode = -1
+	r.Success = false
+	r.Status = StatusCancelled
+	r.EndTime = time.Now()
+	r.Duration = r.EndTime.Sub(r.StartTime).Round(time.Millisecond * 100).String()
+}
+
+// Elapsed returns how long the task ran, or 0 if it has not finished.
+func (r *TaskResult) Elapsed() time.Duration {
+	if r.EndTime.IsZero() {
+		return 0
+	}
+	return r.EndTime.Sub(r.StartTime)
+}
+
 // SetTokenUsage sets the token usage for the task.
 func (r *TaskResult) SetTokenUsage(input, output, cacheRead, cacheWrite int) {
 	r.TokenUsage = TokenUsage{
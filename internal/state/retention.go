@@ -0,0 +1,140 @@
+package state
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RetentionPolicy limits the sessions kept for a project. Zero values mean
+// no limit.
+type RetentionPolicy struct {
+	MaxSessions int           // Most recent sessions to keep
+	MaxAge      time.Duration // Delete sessions that started longer ago
+	MaxBytes    int64         // Total size of the project's sessions
+}
+
+// IsZero reports whether the policy sets no limit.
+func (p RetentionPolicy) IsZero() bool {
+	return p.MaxSessions <= 0 && p.MaxAge <= 0 && p.MaxBytes <= 0
+}
+
+// Reasons a session is deleted by a retention policy.
+const (
+	PruneCount = "max_sessions"
+	PruneAge   = "max_age"
+	PruneSize  = "max_size"
+)
+
+// PrunedSession is a session a retention policy deletes.
+type PrunedSession struct {
+	SessionInfo
+	Size   int64  // Bytes on disk
+	Reason string // PruneCount, PruneAge, or PruneSize
+}
+
+// ApplyRetention deletes the sessions of a project that the policy doesn't
+// keep, oldest first, and returns them. The run keep (usually the one that
+// just finished) and runs still in progress are never deleted. With dryRun
+// nothing is deleted.
+func ApplyRetention(project string, policy RetentionPolicy, keep string, dryRun bool) ([]PrunedSession, error) {
+	baseDir, err := getCortexDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return ApplyRetentionFromPath(baseDir, project, policy, keep, dryRun)
+}
+
+// ApplyRetentionFromPath applies a retention policy under a custom base path.
+// Sessions that fail to delete are left out of the result, and the first
+// such error is returned along with the sessions that were deleted.
+func ApplyRetentionFromPath(baseDir, project string, policy RetentionPolicy, keep string, dryRun bool) ([]PrunedSession, error) {
+	pruned, err := retentionCandidates(baseDir, project, policy, keep)
+	if err != nil || dryRun {
+		return pruned, err
+	}
+
+	var firstErr error
+	deleted := pruned[:0]
+	for _, p := range pruned {
+		if err := os.RemoveAll(p.RunDir); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		deleted = append(deleted, p)
+	}
+	return deleted, firstErr
+}
+
+// retentionCandidates returns the sessions of a project that a retention
+// policy doesn't keep, oldest first. Sessions are kept newest first until
+// one exceeds a limit (the count, the age, or the total size); it and every
+// older session are deleted, except keep and the runs still in progress.
+func retentionCandidates(baseDir, project string, policy RetentionPolicy, keep string) ([]PrunedSession, error) {
+	if policy.IsZero() {
+		return nil, nil
+	}
+	sessions, err := ListSessionsFromPath(baseDir, SessionFilter{Project: project})
+	if err != nil {
+		return nil, err
+	}
+	running, err := RunningDirsFromPath(baseDir, project)
+	if err != nil {
+		return nil, err
+	}
+
+	// Runs that never finished have no start time in their result
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].Started().After(sessions[j].Started())
+	})
+
+	now := time.Now()
+	var pruned []PrunedSession
+	var kept int
+	var keptBytes int64
+	reason := "" // Set by the first session over a limit
+	for _, s := range sessions {
+		size := DirSize(s.RunDir)
+		if reason == "" {
+			switch {
+			case policy.MaxSessions > 0 && kept >= policy.MaxSessions:
+				reason = PruneCount
+			case policy.MaxAge > 0 && now.Sub(s.Started()) > policy.MaxAge:
+				reason = PruneAge
+			case policy.MaxBytes > 0 && keptBytes+size > policy.MaxBytes:
+				reason = PruneSize
+			}
+		}
+		if reason == "" || s.RunID == keep || running[s.RunDir] {
+			kept++
+			keptBytes += size
+			continue
+		}
+		pruned = append(pruned, PrunedSession{SessionInfo: s, Size: size, Reason: reason})
+	}
+
+	// Oldest first
+	for i, j := 0, len(pruned)-1; i < j; i, j = i+1, j-1 {
+		pruned[i], pruned[j] = pruned[j], pruned[i]
+	}
+	return pruned, nil
+}
+
+// DirSize returns the total size of the files under dir.
+func DirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,157 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// retentionSessions saves the sessions a to e of a project, started 5 to 1
+// hours ago, each holding 10000 bytes of output.
+func retentionSessions(t *testing.T) string {
+	t.Helper()
+	base := t.TempDir()
+	now := time.Now()
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		runDir := filepath.Join(base, "sessions", "project", "run-"+id)
+		if err := os.MkdirAll(runDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(RunResult{RunID: id, StartTime: now.Add(-time.Duration(5-i) * time.Hour)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(runDir, "run.json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(runDir, "build.log"), []byte(strings.Repeat("x", 10000)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return base
+}
+
+// TestApplyRetention tests which sessions a retention policy deletes, and
+// why.
+func TestApplyRetention(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetentionPolicy
+		keep    string
+		running string // Session in progress
+		exited  string // Session whose process exited without removing its status
+		dryRun  bool
+		want    []string // Deleted sessions, oldest first
+		reason  string
+	}{
+		{
+			name: "no limit",
+		},
+		{
+			name:   "count",
+			policy: RetentionPolicy{MaxSessions: 2},
+			want:   []string{"a", "b", "c"},
+			reason: PruneCount,
+		},
+		{
+			name:   "age",
+			policy: RetentionPolicy{MaxAge: 150 * time.Minute},
+			want:   []string{"a", "b", "c"},
+			reason: PruneAge,
+		},
+		{
+			name:   "size",
+			policy: RetentionPolicy{MaxBytes: 25000},
+			want:   []string{"a", "b", "c"},
+			reason: PruneSize,
+		},
+		{
+			name:   "first limit exceeded",
+			policy: RetentionPolicy{MaxSessions: 4, MaxAge: 210 * time.Minute, MaxBytes: 35000},
+			want:   []string{"a", "b"},
+			reason: PruneAge,
+		},
+		{
+			name:   "within the limits",
+			policy: RetentionPolicy{MaxSessions: 5, MaxAge: 6 * time.Hour, MaxBytes: 100000},
+		},
+		{
+			name:   "keep",
+			policy: RetentionPolicy{MaxSessions: 2},
+			keep:   "b",
+			want:   []string{"a", "c"},
+			reason: PruneCount,
+		},
+		{
+			name:    "in progress",
+			policy:  RetentionPolicy{MaxSessions: 2},
+			running: "c",
+			want:    []string{"a", "b"},
+			reason:  PruneCount,
+		},
+		{
+			name:   "exited",
+			policy: RetentionPolicy{MaxSessions: 2},
+			exited: "c",
+			want:   []string{"a", "b", "c"},
+			reason: PruneCount,
+		},
+		{
+			name:   "dry run",
+			policy: RetentionPolicy{MaxSessions: 2},
+			dryRun: true,
+			want:   []string{"a", "b", "c"},
+			reason: PruneCount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := retentionSessions(t)
+			runDir := func(id string) string {
+				return filepath.Join(base, "sessions", "project", "run-"+id)
+			}
+			for id, pid := range map[string]int{tt.running: os.Getpid(), tt.exited: 0} {
+				if id == "" {
+					continue
+				}
+				data, err := json.Marshal(RunStatus{RunID: id, Project: "project", PID: pid})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(runDir(id), StatusFile), data, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			pruned, err := ApplyRetentionFromPath(base, "project", tt.policy, tt.keep, tt.dryRun)
+			if err != nil {
+				t.Fatalf("ApplyRetentionFromPath() error = %v", err)
+			}
+			var got []string
+			for _, p := range pruned {
+				got = append(got, p.RunID)
+				if p.Reason != tt.reason {
+					t.Errorf("session %s reason = %q, want %q", p.RunID, p.Reason, tt.reason)
+				}
+				if p.Size < 10000 {
+					t.Errorf("session %s size = %d, want at least 10000", p.RunID, p.Size)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("ApplyRetentionFromPath() = %v, want %v", got, tt.want)
+			}
+
+			for _, id := range []string{"a", "b", "c", "d", "e"} {
+				_, err := os.Stat(runDir(id))
+				if deleted := !tt.dryRun && slices.Contains(tt.want, id); os.IsNotExist(err) != deleted {
+					t.Errorf("session %s exists = %v, want %v", id, err == nil, !deleted)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package state
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Fields of a task result that Search looks in.
+const (
+	FieldPrompt = "prompt"
+	FieldStdout = "stdout"
+	FieldStderr = "stderr"
+)
+
+// snippetRadius is how much of a matching line Search keeps on each side
+// of the match, in bytes.
+const snippetRadius = 60
+
+// SearchFilter contains the options of a search of saved task results.
+type SearchFilter struct {
+	Project       string // Search only this project (empty = all projects)
+	Limit         int    // Maximum number of matches to return (0 = no limit)
+	CaseSensitive bool   // Match case exactly
+}
+
+// SearchMatch is a line of a saved task result that contains the query.
+type SearchMatch struct {
+	Project string `json:"project"`
+	RunID   string `json:"run_id"`
+	Task    string `json:"task"`
+	Field   string `json:"field"`   // FieldPrompt, FieldStdout, or FieldStderr
+	Line    int    `json:"line"`    // 1-based line number within the field
+	Snippet string `json:"snippet"` // The line, cut down around the match
+	Offset  int    `json:"offset"`  // Byte offset of the match in Snippet
+}
+
+// Search finds the query in the prompts and output of saved task results,
+// newest run first.
+func Search(query string, filter SearchFilter) ([]SearchMatch, error) {
+	baseDir, err := getCortexDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return SearchFromPath(baseDir, query, filter)
+}
+
+// SearchFromPath searches saved task results under a custom base path.
+func SearchFromPath(baseDir, query string, filter SearchFilter) ([]SearchMatch, error) {
+	sessions, err := ListSessionsFromPath(baseDir, SessionFilter{Project: filter.Project})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []SearchMatch
+	for _, s := range sessions {
+		tasks, err := ListTaskResults(s.RunDir)
+		if err != nil || LoadOutputs(s.RunDir, tasks) != nil {
+			continue // Skip runs we can't read
+		}
+		for _, t := range tasks {
+			for _, field := range []struct{ name, text string }{
+				{FieldPrompt, t.Prompt},
+				{FieldStdout, t.Stdout},
+				{FieldStderr, t.Stderr},
+			} {
+				for i, line := range strings.Split(field.text, "\n") {
+					at := indexOf(line, query, filter.CaseSensitive)
+					if at < 0 {
+						continue
+					}
+					snippet, offset := snippetAround(line, at, len(query))
+					matches = append(matches, SearchMatch{
+						Project: s.Project,
+						RunID:   s.RunID,
+						Task:    t.TaskName,
+						Field:   field.name,
+						Line:    i + 1,
+						Snippet: snippet,
+						Offset:  offset,
+					})
+					if filter.Limit > 0 && len(matches) >= filter.Limit {
+						return matches, nil
+					}
+				}
+			}
+		}
+	}
+	return matches, nil
+}
+
+// indexOf returns the byte offset of query in line, or -1.
+func indexOf(line, query string, caseSensitive bool) int {
+	if caseSensitive {
+		return strings.Index(line, query)
+	}
+	// ToLower keeps the length of ASCII text only; fall back to a rune scan
+	// for the rest
+	lower, lowerQuery := strings.ToLower(line), strings.ToLower(query)
+	if len(lower) == len(line) {
+		return strings.Index(lower, lowerQuery)
+	}
+	for i := range line {
+		if strings.HasPrefix(strings.ToLower(line[i:]), lowerQuery) {
+			return i
+		}
+	}
+	return -1
+}
+
+// snippetAround cuts line down to the match at offset at and length n with
+// up to snippetRadius bytes around it, trimmed to whole characters and
+// marked with "…" where cut. It returns the snippet and the match's offset
+// in it.
+func snippetAround(line string, at, n int) (string, int) {
+	line = strings.TrimRight(line, "\r")
+	start, end := max(at-snippetRadius, 0), min(at+n+snippetRadius, len(line))
+	for start > 0 && !utf8.RuneStart(line[start]) {
+		start--
+	}
+	for end < len(line) && !utf8.RuneStart(line[end]) {
+		end++
+	}
+
+	snippet, offset := line[start:end], at-start
+	if start == 0 {
+		indent := len(snippet[:offset]) - len(strings.TrimLeft(snippet[:offset], " \t"))
+		snippet, offset = snippet[indent:], offset-indent
+	}
+	if start > 0 {
+		snippet, offset = "…"+snippet, offset+len("…")
+	}
+	if end < len(line) {
+		snippet += "…"
+	}
+	return snippet, offset
+}
@@ -2,26 +2,47 @@ package state
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/adityaraj/agentflow/internal/paths"
 )
 
 // SessionInfo contains summary information about a session.
 type SessionInfo struct {
 	RunID       string        `json:"run_id"`
 	Project     string        `json:"project"`
+	User        string        `json:"user,omitempty"` // Who started the run
 	StartTime   time.Time     `json:"start_time"`
 	EndTime     time.Time     `json:"end_time"`
 	Success     bool          `json:"success"`
+	Cancelled   bool          `json:"cancelled,omitempty"` // Stopped by an interrupt
 	TaskCount   int           `json:"task_count"`
 	Duration    time.Duration `json:"duration"`
 	RunDir      string        `json:"run_dir"`
 	TotalTokens int           `json:"total_tokens,omitempty"` // Total tokens used in session
 }
 
+// Started returns when the session's run started: its recorded start time,
+// or for runs that never finished, the time in its run ID or, failing that,
+// when its directory was last modified.
+func (s SessionInfo) Started() time.Time {
+	if !s.StartTime.IsZero() {
+		return s.StartTime
+	}
+	if t, ok := RunIDTime(s.RunID); ok {
+		return t
+	}
+	if info, err := os.Stat(s.RunDir); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
 // SessionFilter contains filter options for listing sessions.
 type SessionFilter struct {
 	Project    string // Filter by project name (empty = all projects)
@@ -94,9 +115,9 @@ func ListSessionsFromPath(baseDir string, filter SessionFilter) ([]SessionInfo,
 		sessions = filtered
 	}
 
-	// Sort by start time (newest first)
+	// Sort by start time (newest first); unfinished runs by their ID's
 	sort.Slice(sessions, func(i, j int) bool {
-		return sessions[i].StartTime.After(sessions[j].StartTime)
+		return sessions[i].Started().After(sessions[j].Started())
 	})
 
 	// Apply limit
@@ -173,9 +194,11 @@ func loadSessionInfo(runDir, runID, project string) (SessionInfo, error) {
 	return SessionInfo{
 		RunID:       runResult.RunID,
 		Project:     project,
+		User:        runResult.User,
 		StartTime:   runResult.StartTime,
 		EndTime:     runResult.EndTime,
 		Success:     runResult.Success,
+		Cancelled:   runResult.Cancelled,
 		TaskCount:   len(runResult.Tasks),
 		Duration:    runResult.EndTime.Sub(runResult.StartTime),
 		RunDir:      runDir,
@@ -195,6 +218,9 @@ func GetSession(project, runID string) (*RunResult, error) {
 
 // GetSessionFromPath loads session from a custom base path.
 func GetSessionFromPath(baseDir, project, runID string) (*RunResult, error) {
+	if !IsPathElement(project) || !IsPathElement(runID) {
+		return nil, fmt.Errorf("invalid run %q of project %q", runID, project)
+	}
 	runDir := filepath.Join(baseDir, "sessions", project, "run-"+runID)
 	runFile := filepath.Join(runDir, "run.json")
 
@@ -208,9 +234,205 @@ func GetSessionFromPath(baseDir, project, runID string) (*RunResult, error) {
 		return nil, err
 	}
 
+	// run.json keeps previews of task output; load the rest from each task's
+	// result file
+	for i, task := range result.Tasks {
+		if saved, err := LoadTaskResultFromDir(runDir, task.TaskName); err == nil {
+			result.Tasks[i].Stdout, result.Tasks[i].Stderr = saved.Stdout, saved.Stderr
+			result.Tasks[i].StdoutFile, result.Tasks[i].StderrFile = "", ""
+		}
+	}
+
 	return &result, nil
 }
 
+// ResolveRunDir returns the directory and ID of a run. runID "latest"
+// resolves to the project's most recent run.
+func ResolveRunDir(project, runID string) (string, string, error) {
+	baseDir, err := getCortexDir()
+	if err != nil {
+		return "", "", err
+	}
+
+	return ResolveRunDirFromPath(baseDir, project, runID)
+}
+
+// ResolveRunDirFromPath resolves a run directory from a custom base path.
+func ResolveRunDirFromPath(baseDir, project, runID string) (string, string, error) {
+	if !IsPathElement(project) || !IsPathElement(runID) {
+		return "", "", fmt.Errorf("invalid run %q of project %q", runID, project)
+	}
+	projectDir := filepath.Join(baseDir, "sessions", project)
+
+	if runID == "latest" {
+		entries, err := os.ReadDir(projectDir)
+		if err != nil {
+			return "", "", fmt.Errorf("no sessions found for project %q", project)
+		}
+		// Run IDs start with timestamps, so the greatest timestamp is the
+		// newest; of runs started in the same second, the one whose
+		// directory changed last wins
+		latest, latestStamp := "", ""
+		var latestMod time.Time
+		for _, entry := range entries {
+			if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "run-") {
+				continue
+			}
+			id := strings.TrimPrefix(entry.Name(), "run-")
+			stamp := id[:min(len(id), len(runIDTimeLayout))]
+			var mod time.Time
+			if info, err := entry.Info(); err == nil {
+				mod = info.ModTime()
+			}
+			if stamp > latestStamp || (stamp == latestStamp && mod.After(latestMod)) {
+				latest, latestStamp, latestMod = entry.Name(), stamp, mod
+			}
+		}
+		if latest == "" {
+			return "", "", fmt.Errorf("no sessions found for project %q", project)
+		}
+		runID = strings.TrimPrefix(latest, "run-")
+	}
+
+	runDir := filepath.Join(projectDir, "run-"+strings.TrimPrefix(runID, "run-"))
+	if info, err := os.Stat(runDir); err != nil || !info.IsDir() {
+		return "", "", fmt.Errorf("run %q not found for project %q", runID, project)
+	}
+	return runDir, strings.TrimPrefix(runID, "run-"), nil
+}
+
+// RunDirPath returns the directory a run of project is saved in, whether or
+// not it exists.
+func RunDirPath(project, runID string) (string, error) {
+	baseDir, err := getCortexDir()
+	if err != nil {
+		return "", err
+	}
+	if !IsPathElement(project) || !IsPathElement(runID) {
+		return "", fmt.Errorf("invalid run %q of project %q", runID, project)
+	}
+	return filepath.Join(baseDir, "sessions", project, "run-"+strings.TrimPrefix(runID, "run-")), nil
+}
+
+// IsPathElement reports whether a project name or run ID is a single path
+// element, which can't lead out of the sessions directory.
+func IsPathElement(name string) bool {
+	return name != "." && filepath.IsLocal(name) && !strings.ContainsAny(name, `/\`)
+}
+
+// FindRunDir resolves a run of project like ResolveRunDir, falling back to
+// the other projects when project has no run with that ID. Returns the run
+// directory, the project it belongs to, and the run ID.
+func FindRunDir(project, runID string) (string, string, string, error) {
+	baseDir, err := getCortexDir()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return FindRunDirFromPath(baseDir, project, runID)
+}
+
+// FindRunDirFromPath finds a run directory from a custom base path.
+func FindRunDirFromPath(baseDir, project, runID string) (string, string, string, error) {
+	runDir, id, err := ResolveRunDirFromPath(baseDir, project, runID)
+	if err == nil || runID == "latest" {
+		return runDir, project, id, err
+	}
+
+	projects, listErr := ListProjectsFromPath(baseDir)
+	if listErr != nil {
+		return "", "", "", err
+	}
+	for _, other := range projects {
+		if other == project {
+			continue
+		}
+		if runDir, id, otherErr := ResolveRunDirFromPath(baseDir, other, runID); otherErr == nil {
+			return runDir, other, id, nil
+		}
+	}
+	return "", "", "", fmt.Errorf("run %q not found", runID)
+}
+
+// RunOutputs returns the saved stdout of each task of a run, keyed by task
+// name, for use as {{previous.task}} in a later run.
+func RunOutputs(runDir string) (map[string]string, error) {
+	results, err := ListTaskResults(runDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := LoadOutputs(runDir, results); err != nil {
+		return nil, err
+	}
+	outputs := make(map[string]string, len(results))
+	for _, r := range results {
+		outputs[r.TaskName] = r.Stdout
+	}
+	return outputs, nil
+}
+
+// IsRunComplete reports whether a run has finished (its run.json exists).
+func IsRunComplete(runDir string) bool {
+	_, err := os.Stat(filepath.Join(runDir, "run.json"))
+	return err == nil
+}
+
+// LoadTaskResultFromDir loads a single task result from a run directory,
+// with its full output.
+func LoadTaskResultFromDir(runDir, taskName string) (*TaskResult, error) {
+	result, err := readTaskResult(runDir, taskName)
+	if err != nil {
+		return nil, err
+	}
+	if err := result.LoadOutput(runDir); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readTaskResult loads a task result as saved, with previews of large output.
+func readTaskResult(runDir, taskName string) (*TaskResult, error) {
+	data, err := os.ReadFile(filepath.Join(runDir, TaskFileName(taskName)+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var result TaskResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse result for task %q: %w", taskName, err)
+	}
+	return &result, nil
+}
+
+// ListTaskResults loads all task results saved in a run directory, ordered
+// by completion time. Results still being written are skipped. Large output
+// is left as a preview; LoadOutputs loads it.
+func ListTaskResults(runDir string) ([]TaskResult, error) {
+	entries, err := os.ReadDir(runDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []TaskResult
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == "run.json" || name == ManifestFile || name == StatusFile || filepath.Ext(name) != ".json" {
+			continue
+		}
+
+		result, err := readTaskResult(runDir, strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		results = append(results, *result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].EndTime.Before(results[j].EndTime)
+	})
+	return results, nil
+}
+
 // ProjectSummary contains summary info about a project's sessions.
 type ProjectSummary struct {
 	Name         string    // Project name
@@ -318,25 +540,7 @@ func ListProjectsFromPath(baseDir string) ([]string, error) {
 	return projects, nil
 }
 
-// getCortexDir returns the ~/.cortex directory path.
+// getCortexDir returns the directory holding sessions/.
 func getCortexDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-	return filepath.Join(homeDir, ".cortex"), nil
-}
-
-// FormatDuration formats a duration in human-readable form.
-func FormatDuration(d time.Duration) string {
-	if d < time.Second {
-		return d.Round(time.Millisecond).String()
-	}
-	if d < time.Minute {
-		return d.Round(time.Second * 1).String()
-	}
-	if d < time.Hour {
-		return d.Round(time.Second * 1).String()
-	}
-	return d.Round(time.Minute).String()
+	return paths.DataDir()
 }
@@ -0,0 +1,62 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/adityaraj/agentflow/internal/redact"
+)
+
+// ConfigDir is the directory of a run's config snapshot in its run directory.
+const ConfigDir = "config"
+
+// VarsFile is the name of the vars a run used in its config snapshot, in
+// --var-file format.
+const VarsFile = "vars.yml"
+
+// SaveConfigSnapshot copies the files a run's config was read from into the
+// run directory, so the run can be reproduced elsewhere. Files keep their
+// path relative to baseDir, the Cortexfile's directory; files outside it are
+// saved under external/. The final vars are saved as vars.yml, with secret
+// values redacted.
+func (s *Store) SaveConfigSnapshot(baseDir string, files []string, vars map[string]string) error {
+	destRoot := filepath.Join(s.runDir, ConfigDir)
+	seen := make(map[string]bool)
+	for _, file := range files {
+		rel, err := filepath.Rel(baseDir, file)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			rel = filepath.Join("external", filepath.Base(file))
+		}
+		if seen[rel] {
+			continue
+		}
+		seen[rel] = true
+
+		if err := copyFile(file, filepath.Join(destRoot, rel)); err != nil {
+			return fmt.Errorf("failed to save config file %s: %w", file, err)
+		}
+	}
+
+	if len(vars) == 0 {
+		return nil
+	}
+	redacted := make(map[string]string, len(vars))
+	for name, value := range vars {
+		redacted[name] = redact.Secrets(value, s.secrets)
+	}
+	data, err := yaml.Marshal(redacted)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vars: %w", err)
+	}
+	if err := os.MkdirAll(destRoot, 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(destRoot, VarsFile), data); err != nil {
+		return fmt.Errorf("failed to write vars: %w", err)
+	}
+	return nil
+}
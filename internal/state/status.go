@@ -0,0 +1,127 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/proc"
+)
+
+// StatusFile is the file in the directory of a run in progress that tells
+// `cortex status` what it is doing. It is removed when the run ends; like
+// temporary files, it is left out of the run's manifest.
+const StatusFile = ".status.json"
+
+// RunStatus is the progress of a run in progress.
+type RunStatus struct {
+	RunID     string        `json:"run_id"`
+	Project   string        `json:"project"`
+	User      string        `json:"user,omitempty"`
+	PID       int           `json:"pid"` // Process running the workflow
+	StartTime time.Time     `json:"start_time"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	TaskCount int           `json:"task_count"`       // Tasks in the plan
+	Finished  int           `json:"finished"`         // Tasks that succeeded, failed, or were skipped
+	Level     int           `json:"level,omitempty"`  // Dependency level started last, 1-based (parallel mode)
+	Levels    int           `json:"levels,omitempty"` // Dependency levels (parallel mode)
+	Running   []RunningTask `json:"running"`
+	RunDir    string        `json:"-"`
+}
+
+// RunningTask is a task of a run in progress whose agent is running.
+type RunningTask struct {
+	Name      string    `json:"name"`
+	Agent     string    `json:"agent"`
+	Tool      string    `json:"tool"`
+	StartTime time.Time `json:"start_time"`
+}
+
+// SaveStatus writes the status file of the run.
+func (s *Store) SaveStatus(status *RunStatus) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.runDir, StatusFile), data)
+}
+
+// RemoveStatus removes the status file of the run, once it has ended.
+func (s *Store) RemoveStatus() {
+	_ = os.Remove(filepath.Join(s.runDir, StatusFile))
+}
+
+// ListRunning returns the runs in progress, of one project or of all
+// (project empty), oldest first. Status files left by runs whose process
+// is gone, as after a crash, are skipped.
+func ListRunning(project string) ([]RunStatus, error) {
+	baseDir, err := getCortexDir()
+	if err != nil {
+		return nil, err
+	}
+	return ListRunningFromPath(baseDir, project)
+}
+
+// ListRunningFromPath lists runs in progress under a custom base path.
+func ListRunningFromPath(baseDir, project string) ([]RunStatus, error) {
+	pattern := filepath.Join(baseDir, "sessions", "*", "run-*", StatusFile)
+	if project != "" {
+		pattern = filepath.Join(baseDir, "sessions", project, "run-*", StatusFile)
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var running []RunStatus
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue // The run just ended
+		}
+		var status RunStatus
+		if err := json.Unmarshal(data, &status); err != nil || !proc.Alive(status.PID) {
+			continue
+		}
+		status.RunDir = filepath.Dir(file)
+		if status.Project == "" {
+			status.Project = filepath.Base(filepath.Dir(status.RunDir))
+		}
+		if status.RunID == "" {
+			status.RunID = strings.TrimPrefix(filepath.Base(status.RunDir), "run-")
+		}
+		running = append(running, status)
+	}
+
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].StartTime.Before(running[j].StartTime)
+	})
+	return running, nil
+}
+
+// RunningDirs returns the directories of the runs in progress, of one
+// project or of all (project empty).
+func RunningDirs(project string) (map[string]bool, error) {
+	baseDir, err := getCortexDir()
+	if err != nil {
+		return nil, err
+	}
+	return RunningDirsFromPath(baseDir, project)
+}
+
+// RunningDirsFromPath returns the directories of runs in progress under a
+// custom base path.
+func RunningDirsFromPath(baseDir, project string) (map[string]bool, error) {
+	running, err := ListRunningFromPath(baseDir, project)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[string]bool, len(running))
+	for _, r := range running {
+		dirs[r.RunDir] = true
+	}
+	return dirs, nil
+}
@@ -1,39 +1,123 @@
 package state
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"github.com/adityaraj/agentflow/internal/paths"
+	"github.com/adityaraj/agentflow/internal/redact"
 )
 
 // Store handles persistence of run results to disk.
 type Store struct {
-	baseDir    string // Base directory (~/.agentflow)
-	runID      string // Current run ID (timestamp-based)
-	runDir     string // Full path to current run directory
-	projectDir string // Project directory where agentflow was run
+	baseDir    string   // Base directory (~/.agentflow)
+	runID      string   // Current run ID (timestamp with a random suffix)
+	runDir     string   // Full path to current run directory
+	projectDir string   // Project directory where agentflow was run
+	cacheDir   string   // Directory of the project's task cache directories
+	secrets    []string // Secret values redacted from saved results
+	signingKey string   // Key the run's manifest is signed with (empty = unsigned)
+}
+
+// RunIDEnv names the environment variable that sets the ID of the next run,
+// so a process starting `cortex run` (like `cortex serve`) knows where the
+// run's results will be saved. The run clears it before starting tasks, so
+// their processes don't inherit it.
+const RunIDEnv = "CORTEX_RUN_ID"
+
+// runIDTimeLayout is the layout of the start time run IDs begin with.
+const runIDTimeLayout = "20060102-150405"
+
+// runDirAttempts bounds the run IDs tried when creating a run directory, in
+// case a run started at the same time took one.
+const runDirAttempts = 5
+
+// NewRunID returns a new run ID: the current time to the second followed by
+// a random suffix, such as 20240102-150405-3fa9c1, so runs started in the
+// same second get different IDs while IDs still sort by start time.
+func NewRunID() string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix) // Never fails
+	return time.Now().Format(runIDTimeLayout) + "-" + hex.EncodeToString(suffix)
+}
+
+// RunIDTime returns the start time a run ID begins with, for IDs with and
+// without the random suffix.
+func RunIDTime(runID string) (time.Time, bool) {
+	if len(runID) < len(runIDTimeLayout) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(runIDTimeLayout, runID[:len(runIDTimeLayout)], time.Local)
+	return t, err == nil
+}
+
+// createRunDir creates the directory of a new run in sessionsDir and
+// returns the run's ID and directory. The ID is $CORTEX_RUN_ID if set, and
+// a new one otherwise, tried again if a run started at the same time got
+// the same ID. A run directory is never reused, so simultaneous runs can't
+// mix their results.
+func createRunDir(sessionsDir string) (runID, runDir string, err error) {
+	if err := os.MkdirAll(sessionsDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create run directory: %w", err)
+	}
+	fixed := os.Getenv(RunIDEnv)
+	for attempt := 1; ; attempt++ {
+		runID = fixed
+		if runID == "" {
+			runID = NewRunID()
+		}
+		runDir = filepath.Join(sessionsDir, "run-"+runID)
+		err := os.Mkdir(runDir, 0755)
+		switch {
+		case err == nil:
+			return runID, runDir, nil
+		case !errors.Is(err, fs.ErrExist):
+			return "", "", fmt.Errorf("failed to create run directory: %w", err)
+		case fixed != "":
+			return "", "", fmt.Errorf("run %s already exists in %s (set by %s)", runID, sessionsDir, RunIDEnv)
+		case attempt == runDirAttempts:
+			return "", "", fmt.Errorf("failed to create run directory: %w", err)
+		}
+	}
+}
+
+// TaskFileName returns the name a task's files in a run directory start
+// with: the task name, with the characters Windows doesn't allow in file
+// names replaced by '_'.
+func TaskFileName(taskName string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, taskName)
 }
 
-// NewStore creates a new Store using ~/.cortex as the base directory.
-// Creates ~/.cortex/sessions/<project-name>/ structure if it doesn't exist.
+// NewStore creates a new Store using the data directory (~/.cortex, or
+// $XDG_DATA_HOME/cortex) as the base directory, and keeping task caches in
+// the cache directory (~/.cortex/cache, or $XDG_CACHE_HOME/cortex).
+// Creates <base>/sessions/<project-name>/ structure if it doesn't exist.
 func NewStore(projectDir string) (*Store, error) {
-	homeDir, err := os.UserHomeDir()
+	layout, err := paths.Current()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
-
-	baseDir := filepath.Join(homeDir, ".cortex")
-	runID := time.Now().Format("20060102-150405")
+	baseDir := layout.Data
 
 	// Create project-specific session directory
 	projectName := filepath.Base(projectDir)
 	sessionsDir := filepath.Join(baseDir, "sessions", projectName)
-	runDir := filepath.Join(sessionsDir, "run-"+runID)
-
-	if err := os.MkdirAll(runDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create run directory: %w", err)
+	runID, runDir, err := createRunDir(sessionsDir)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Store{
@@ -41,18 +125,17 @@ func NewStore(projectDir string) (*Store, error) {
 		runID:      runID,
 		runDir:     runDir,
 		projectDir: projectDir,
+		cacheDir:   filepath.Join(layout.Cache, projectName),
 	}, nil
 }
 
 // NewStoreWithPath creates a Store with a custom base path (for testing).
 func NewStoreWithPath(basePath, projectDir string) (*Store, error) {
-	runID := time.Now().Format("20060102-150405")
 	projectName := filepath.Base(projectDir)
 	sessionsDir := filepath.Join(basePath, "sessions", projectName)
-	runDir := filepath.Join(sessionsDir, "run-"+runID)
-
-	if err := os.MkdirAll(runDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create run directory: %w", err)
+	runID, runDir, err := createRunDir(sessionsDir)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Store{
@@ -60,41 +143,109 @@ func NewStoreWithPath(basePath, projectDir string) (*Store, error) {
 		runID:      runID,
 		runDir:     runDir,
 		projectDir: projectDir,
+		cacheDir:   filepath.Join(basePath, "cache", projectName),
 	}, nil
 }
 
+// SetSecrets sets the secret values to redact from saved results.
+func (s *Store) SetSecrets(values []string) {
+	s.secrets = values
+}
+
+// redact returns a copy of the task result with secret values masked.
+func (s *Store) redact(result TaskResult) TaskResult {
+	if len(s.secrets) == 0 {
+		return result
+	}
+	result.Prompt = redact.Secrets(result.Prompt, s.secrets)
+	result.OriginalPrompt = redact.Secrets(result.OriginalPrompt, s.secrets)
+	result.Stdout = redact.Secrets(result.Stdout, s.secrets)
+	result.Stderr = redact.Secrets(result.Stderr, s.secrets)
+	return result
+}
+
 // SaveTaskResult saves a task result to disk as JSON.
+// Secret values are redacted from the saved copy.
 func (s *Store) SaveTaskResult(result *TaskResult) error {
-	filename := filepath.Join(s.runDir, result.TaskName+".json")
+	filename := filepath.Join(s.runDir, TaskFileName(result.TaskName)+".json")
 
-	data, err := json.MarshalIndent(result, "", "  ")
+	redacted := s.redact(*result)
+	if err := s.saveLargeOutput(&redacted); err != nil {
+		return fmt.Errorf("failed to write task output: %w", err)
+	}
+
+	data, err := json.MarshalIndent(redacted, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal result: %w", err)
 	}
 
-	if err := os.WriteFile(filename, data, 0644); err != nil {
+	if err := writeFileAtomic(filename, data); err != nil {
 		return fmt.Errorf("failed to write result file: %w", err)
 	}
 
 	return nil
 }
 
-// SaveRunResult saves the complete run result to disk.
+// SaveRunResult saves the complete run result to disk. Only previews of task
+// output are kept, to keep run.json small; each task's result file and log
+// have the rest. With a signing key set, the run's manifest is signed too.
 func (s *Store) SaveRunResult(result *RunResult) error {
 	filename := filepath.Join(s.runDir, "run.json")
 
-	data, err := json.MarshalIndent(result, "", "  ")
+	redacted := *result
+	redacted.Tasks = make([]TaskResult, len(result.Tasks))
+	for i, task := range result.Tasks {
+		task = s.redact(task)
+		if len(task.Stdout) > LargeOutputSize {
+			task.StdoutFile = TaskFileName(task.TaskName) + ".stdout"
+		}
+		if len(task.Stderr) > LargeOutputSize {
+			task.StderrFile = TaskFileName(task.TaskName) + ".stderr"
+		}
+		task.Stdout, task.Stderr = preview(task.Stdout), preview(task.Stderr)
+		redacted.Tasks[i] = task
+	}
+
+	data, err := json.MarshalIndent(redacted, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal run result: %w", err)
 	}
 
-	if err := os.WriteFile(filename, data, 0644); err != nil {
+	if err := writeFileAtomic(filename, data); err != nil {
 		return fmt.Errorf("failed to write run result: %w", err)
 	}
 
+	if s.signingKey != "" {
+		if err := s.signRun(); err != nil {
+			return fmt.Errorf("failed to sign run: %w", err)
+		}
+	}
 	return nil
 }
 
+// writeFileAtomic writes a temporary file, then renames it, so an interrupted
+// run never leaves a partial result behind.
+func writeFileAtomic(filename string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
 // RunDir returns the path to the current run directory.
 func (s *Store) RunDir() string {
 	return s.runDir
@@ -107,7 +258,7 @@ func (s *Store) RunID() string {
 
 // LoadTaskResult loads a task result from disk.
 func (s *Store) LoadTaskResult(taskName string) (*TaskResult, error) {
-	filename := filepath.Join(s.runDir, taskName+".json")
+	filename := filepath.Join(s.runDir, TaskFileName(taskName)+".json")
 
 	data, err := os.ReadFile(filename)
 	if err != nil {
@@ -119,5 +270,8 @@ func (s *Store) LoadTaskResult(taskName string) (*TaskResult, error) {
 		return nil, fmt.Errorf("failed to unmarshal result: %w", err)
 	}
 
+	if err := result.LoadOutput(s.runDir); err != nil {
+		return nil, err
+	}
 	return &result, nil
 }
@@ -0,0 +1,125 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/adityaraj/agentflow/internal/redact"
+)
+
+// TaskLog is a task's log file, <task>.log in the run directory. Output is
+// written to it line by line as the task produces it, so a run that crashes
+// or is killed keeps what its tasks printed. Secret values are redacted and
+// terminal color codes removed.
+type TaskLog struct {
+	mu      sync.Mutex
+	file    *os.File
+	name    string   // File name, relative to the run directory
+	secrets []string // Secret values to redact
+	line    []byte   // Partial line not written yet
+	esc     int      // Escape sequence state: 0 = none, 1 = after ESC, 2 = in CSI
+	size    int64    // Bytes written so far
+}
+
+// CreateTaskLog creates (or truncates) the log file of a task.
+func (s *Store) CreateTaskLog(taskName string) (*TaskLog, error) {
+	return s.openTaskLog(taskName, os.O_TRUNC)
+}
+
+// AppendTaskLog opens the log file of a task to add the output of another
+// run of it, like the next iteration of a loop task.
+func (s *Store) AppendTaskLog(taskName string) (*TaskLog, error) {
+	return s.openTaskLog(taskName, os.O_APPEND)
+}
+
+func (s *Store) openTaskLog(taskName string, flag int) (*TaskLog, error) {
+	name := TaskFileName(taskName) + ".log"
+	file, err := os.OpenFile(filepath.Join(s.runDir, name), os.O_CREATE|os.O_WRONLY|flag, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task log: %w", err)
+	}
+	return &TaskLog{file: file, name: name, secrets: s.secrets}, nil
+}
+
+// Name returns the log file's name, relative to the run directory.
+func (l *TaskLog) Name() string {
+	return l.name
+}
+
+// Size returns the number of bytes written to the log, including any
+// partial line not flushed yet.
+func (l *TaskLog) Size() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size + int64(len(l.line))
+}
+
+// Write implements io.Writer. It is safe to call from several goroutines,
+// as stdout and stderr are written concurrently.
+func (l *TaskLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, b := range p {
+		switch {
+		case l.esc == 1:
+			// ESC [ starts a CSI sequence; any other ESC sequence is two bytes
+			l.esc = 0
+			if b == '[' {
+				l.esc = 2
+			}
+		case l.esc == 2:
+			if b >= 0x40 && b <= 0x7e {
+				l.esc = 0
+			}
+		case b == 0x1b:
+			l.esc = 1
+		default:
+			l.line = append(l.line, b)
+		}
+	}
+
+	if i := bytes.LastIndexByte(l.line, '\n'); i >= 0 {
+		if err := l.flush(l.line[:i+1]); err != nil {
+			return 0, err
+		}
+		l.line = append(l.line[:0], l.line[i+1:]...)
+	}
+	return len(p), nil
+}
+
+// flush writes complete lines to the file, redacted.
+func (l *TaskLog) flush(data []byte) error {
+	text := redact.Secrets(string(data), l.secrets)
+	n, err := l.file.WriteString(text)
+	l.size += int64(n)
+	return err
+}
+
+// Close writes the last partial line and closes the file.
+func (l *TaskLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.line) > 0 {
+		if err := l.flush(l.line); err != nil {
+			l.file.Close()
+			return err
+		}
+		l.line = nil
+	}
+	return l.file.Close()
+}
+
+// ReadTaskLog returns what a closed task log got: the end of its file, after
+// the output of earlier runs of a loop task.
+func (s *Store) ReadTaskLog(l *TaskLog) (string, error) {
+	data, err := os.ReadFile(filepath.Join(s.runDir, l.name))
+	if err != nil {
+		return "", err
+	}
+	return string(data[max(int64(len(data))-l.size, 0):]), nil
+}
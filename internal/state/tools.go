@@ -0,0 +1,48 @@
+package state
+
+import (
+	"slices"
+	"time"
+)
+
+// ToolCall is one use of a tool by an AI agent, such as a file read or a
+// shell command, as reported in the agent's event stream.
+type ToolCall struct {
+	Tool      string    `json:"tool"`              // Tool name, e.g. "Edit" or "Bash"
+	Target    string    `json:"target,omitempty"`  // File, pattern, command, or URL the call acted on
+	StartTime time.Time `json:"start_time"`        // When the agent started the call
+	EndTime   time.Time `json:"end_time,omitzero"` // When the tool's result came back (zero if it never did)
+	Error     bool      `json:"error,omitempty"`   // The tool reported an error
+}
+
+// editTools lists the tools that change files.
+var editTools = []string{"Edit", "MultiEdit", "Write", "NotebookEdit"}
+
+// SetToolCalls records the tool calls of the task, in the order they were
+// made, and counts them per tool.
+func (r *TaskResult) SetToolCalls(calls []ToolCall) {
+	r.ToolCalls = calls
+	r.ToolCounts = nil
+	if len(calls) == 0 {
+		return
+	}
+	r.ToolCounts = make(map[string]int)
+	for _, call := range calls {
+		r.ToolCounts[call.Tool]++
+	}
+}
+
+// EditedFiles returns the files the task's tool calls changed, each once, in
+// the order they were first changed. Failed calls are left out.
+func (r *TaskResult) EditedFiles() []string {
+	var files []string
+	for _, call := range r.ToolCalls {
+		if call.Error || call.Target == "" || !slices.Contains(editTools, call.Tool) {
+			continue
+		}
+		if !slices.Contains(files, call.Target) {
+			files = append(files, call.Target)
+		}
+	}
+	return files
+}
@@ -0,0 +1,267 @@
+// Package telemetry exports runs to an OpenTelemetry collector over
+// OTLP/HTTP: a span per run with a child span per task, and counters of
+// task successes and failures.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/events"
+	"github.com/adityaraj/agentflow/internal/state"
+)
+
+// DefaultServiceName is the service.name of exported data unless
+// settings.telemetry.service_name is set.
+const DefaultServiceName = "cortex"
+
+// scopeName identifies cortex as the instrumentation scope.
+const scopeName = "github.com/adityaraj/agentflow"
+
+// exportTimeout bounds each export request.
+const exportTimeout = 10 * time.Second
+
+// Exporter is an events.Subscriber that exports a run once it finishes.
+type Exporter struct {
+	cfg     config.TelemetryConfig
+	project string
+	client  *http.Client
+
+	pending sync.WaitGroup
+	mu      sync.Mutex
+	err     error // First export failure
+}
+
+// New creates an exporter for a project's runs. Returns nil when no
+// endpoint is configured.
+func New(cfg config.TelemetryConfig, project string) *Exporter {
+	if cfg.Endpoint == "" {
+		return nil
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = DefaultServiceName
+	}
+	return &Exporter{
+		cfg:     cfg,
+		project: project,
+		client:  &http.Client{Timeout: exportTimeout},
+	}
+}
+
+// Handle implements events.Subscriber. The export runs in the background
+// so the run summary is not held up by a slow collector; call Wait before
+// exiting.
+func (x *Exporter) Handle(e events.Event) {
+	finished, ok := e.(events.RunFinished)
+	if !ok {
+		return
+	}
+	x.pending.Add(1)
+	go func() {
+		defer x.pending.Done()
+		if err := x.export(finished.Result); err != nil {
+			x.mu.Lock()
+			if x.err == nil {
+				x.err = err
+			}
+			x.mu.Unlock()
+		}
+	}()
+}
+
+// Wait blocks until pending exports complete and returns the first error.
+func (x *Exporter) Wait() error {
+	x.pending.Wait()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.err
+}
+
+// export sends the spans and counters of a run.
+func (x *Exporter) export(run *state.RunResult) error {
+	res := resource{Attributes: []attribute{
+		stringAttr("service.name", x.cfg.ServiceName),
+		stringAttr("cortex.project", x.project),
+	}}
+
+	traces := tracesRequest{ResourceSpans: []resourceSpans{{
+		Resource:   res,
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: scopeName}, Spans: runSpans(run)}},
+	}}}
+	metrics := metricsRequest{ResourceMetrics: []resourceMetrics{{
+		Resource:     res,
+		ScopeMetrics: []scopeMetrics{{Scope: scope{Name: scopeName}, Metrics: taskCounters(run)}},
+	}}}
+
+	if err := x.post("/v1/traces", traces); err != nil {
+		return err
+	}
+	return x.post("/v1/metrics", metrics)
+}
+
+// post sends an export request to a signal's path under the endpoint.
+func (x *Exporter) post(path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode telemetry: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	url := strings.TrimSuffix(x.cfg.Endpoint, "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create telemetry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range x.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := x.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export telemetry to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to export telemetry to %s: status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// runSpans returns the run's span followed by a child span per task.
+func runSpans(run *state.RunResult) []span {
+	traceID := randomID(16)
+	runSpanID := randomID(8)
+
+	runStatus := spanStatus{Code: statusOK}
+	if !run.Success {
+		runStatus = spanStatus{Code: statusError, Message: run.Error}
+	}
+	spans := []span{{
+		TraceID:           traceID,
+		SpanID:            runSpanID,
+		Name:              "cortex run",
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: unixNano(run.StartTime),
+		EndTimeUnixNano:   unixNano(run.EndTime),
+		Attributes: []attribute{
+			stringAttr("cortex.run_id", run.RunID),
+			intAttr("cortex.tasks", int64(len(run.Tasks))),
+			intAttr("cortex.tokens", int64(run.TokenUsage.TotalTokens)),
+			doubleAttr("cortex.cost_usd", run.CostUSD),
+			intAttr("cortex.duration_ms", run.EndTime.Sub(run.StartTime).Milliseconds()),
+		},
+		Status: runStatus,
+	}}
+
+	for _, task := range run.Tasks {
+		attrs := []attribute{
+			stringAttr("cortex.task", task.TaskName),
+			stringAttr("cortex.agent", task.Agent),
+			stringAttr("cortex.tool", task.Tool),
+			stringAttr("cortex.status", task.Status),
+			intAttr("cortex.exit_code", int64(task.ExitCode)),
+			intAttr("cortex.duration_ms", task.Elapsed().Milliseconds()),
+			intAttr("cortex.tokens.input", int64(task.TokenUsage.InputTokens)),
+			intAttr("cortex.tokens.output", int64(task.TokenUsage.OutputTokens)),
+		}
+		if task.Model != "" {
+			attrs = append(attrs, stringAttr("cortex.model", task.Model))
+		}
+
+		status := spanStatus{Code: statusOK}
+		switch task.Status {
+		case state.StatusSkipped:
+			status = spanStatus{Code: statusUnset}
+			attrs = append(attrs, stringAttr("cortex.skip_reason", task.SkipReason))
+		case state.StatusFailed:
+			status = spanStatus{Code: statusError, Message: fmt.Sprintf("exit code %d", task.ExitCode)}
+		}
+
+		spans = append(spans, span{
+			TraceID:           traceID,
+			SpanID:            randomID(8),
+			ParentSpanID:      runSpanID,
+			Name:              task.TaskName,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: unixNano(task.StartTime),
+			EndTimeUnixNano:   unixNano(task.EndTime),
+			Attributes:        attrs,
+			Status:            status,
+		})
+	}
+	return spans
+}
+
+// counterKey groups task counts by tool and model.
+type counterKey struct {
+	tool, model string
+}
+
+// taskCounters returns the run's task successes and failures by tool and
+// model. Skipped tasks are not counted.
+func taskCounters(run *state.RunResult) []metric {
+	succeeded := make(map[counterKey]int64)
+	failed := make(map[counterKey]int64)
+	for _, task := range run.Tasks {
+		key := counterKey{task.Tool, task.Model}
+		switch task.Status {
+		case state.StatusSuccess:
+			succeeded[key]++
+		case state.StatusFailed:
+			failed[key]++
+		}
+	}
+
+	counter := func(name, description string, counts map[counterKey]int64) metric {
+		keys := make([]counterKey, 0, len(counts))
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].tool != keys[j].tool {
+				return keys[i].tool < keys[j].tool
+			}
+			return keys[i].model < keys[j].model
+		})
+
+		points := make([]dataPoint, 0, len(keys))
+		for _, key := range keys {
+			points = append(points, dataPoint{
+				Attributes:        []attribute{stringAttr("cortex.tool", key.tool), stringAttr("cortex.model", key.model)},
+				StartTimeUnixNano: unixNano(run.StartTime),
+				TimeUnixNano:      unixNano(run.EndTime),
+				AsInt:             fmt.Sprint(counts[key]),
+			})
+		}
+		return metric{
+			Name:        name,
+			Description: description,
+			Unit:        "{task}",
+			Sum:         sum{DataPoints: points, AggregationTemporality: temporalityDelta, IsMonotonic: true},
+		}
+	}
+
+	return []metric{
+		counter("cortex.tasks.succeeded", "Tasks that succeeded", succeeded),
+		counter("cortex.tasks.failed", "Tasks that failed", failed),
+	}
+}
+
+// randomID returns n random bytes as hex, for trace and span IDs.
+func randomID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -2,11 +2,21 @@ package ui
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
+// bannerWidth is the width of the boxed banner; narrower terminals get a
+// one-line banner instead.
+const bannerWidth = 62
+
+// dividerWidth is the widest a divider is drawn.
+const dividerWidth = 45
+
 // PrintBanner prints the welcome banner with ASCII art
 func PrintBanner(version string) {
 	// Get username
@@ -23,40 +33,50 @@ func PrintBanner(version string) {
 		displayPath = "~" + cwd[len(homeDir):]
 	}
 
-	fmt.Println()
+	fmt.Fprintln(Stdout)
+
+	width := Width()
+	if width < bannerWidth || plain {
+		fmt.Fprintf(Stdout, "%s◆ Cortex%s %sAI Agent Orchestrator%s\n", Orange+Bold, Reset, Dim, Reset)
+	} else {
+		printBannerBox()
+	}
 
+	// Welcome message
+	fmt.Fprintf(Stdout, "\n  %s%s%s\n", Bold+White, Truncate("Welcome, "+username+"!", width-2), Reset)
+
+	// Info line
+	info := "v" + version + "  "
+	fmt.Fprintf(Stdout, "  %s%s%s%s%s\n\n",
+		Dim, info, Reset+Dim, TruncatePath(displayPath, width-2-len(info)), Reset,
+	)
+}
+
+// printBannerBox prints the boxed ASCII art banner, bannerWidth columns wide.
+func printBannerBox() {
 	// Print banner with clean design (Claude Orange theme)
 	border := Orange + "  ╭────────────────────────────────────────────────────────╮" + Reset
 	borderB := Orange + "  ╰────────────────────────────────────────────────────────╯" + Reset
 	side := Orange + "  │" + Reset
 	sideEnd := Orange + "│" + Reset
 
-	fmt.Println(border)
-	fmt.Println(side + "                                                          " + sideEnd)
-	fmt.Printf("%s   %s ██████╗ ██████╗ ██████╗ ████████╗███████╗██╗  ██╗%s      %s\n", side, Orange+Bold, Reset, sideEnd)
-	fmt.Printf("%s   %s██╔════╝██╔═══██╗██╔══██╗╚══██╔══╝██╔════╝╚██╗██╔╝%s      %s\n", side, Orange+Bold, Reset, sideEnd)
-	fmt.Printf("%s   %s██║     ██║   ██║██████╔╝   ██║   █████╗   ╚███╔╝%s       %s\n", side, Orange+Bold, Reset, sideEnd)
-	fmt.Printf("%s   %s██║     ██║   ██║██╔══██╗   ██║   ██╔══╝   ██╔██╗%s       %s\n", side, Orange+Bold, Reset, sideEnd)
-	fmt.Printf("%s   %s╚██████╗╚██████╔╝██║  ██║   ██║   ███████╗██╔╝ ██╗%s      %s\n", side, Orange+Bold, Reset, sideEnd)
-	fmt.Printf("%s   %s ╚═════╝ ╚═════╝ ╚═╝  ╚═╝   ╚═╝   ╚══════╝╚═╝  ╚═╝%s      %s\n", side, Orange+Bold, Reset, sideEnd)
-	fmt.Println(side + "                                                          " + sideEnd)
-	fmt.Printf("%s            %sAI Agent Orchestrator%s                      %s\n", side, Dim, Reset, sideEnd)
-	fmt.Println(side + "                                                          " + sideEnd)
-	fmt.Println(borderB)
-
-	// Welcome message
-	fmt.Printf("\n  %sWelcome, %s!%s\n", Bold+White, username, Reset)
-
-	// Info line
-	fmt.Printf("  %sv%s%s  %s%s%s\n\n",
-		Dim, version, Reset,
-		Dim, displayPath, Reset,
-	)
+	fmt.Fprintln(Stdout, border)
+	fmt.Fprintln(Stdout, side+"                                                          "+sideEnd)
+	fmt.Fprintf(Stdout, "%s   %s ██████╗ ██████╗ ██████╗ ████████╗███████╗██╗  ██╗%s      %s\n", side, Orange+Bold, Reset, sideEnd)
+	fmt.Fprintf(Stdout, "%s   %s██╔════╝██╔═══██╗██╔══██╗╚══██╔══╝██╔════╝╚██╗██╔╝%s      %s\n", side, Orange+Bold, Reset, sideEnd)
+	fmt.Fprintf(Stdout, "%s   %s██║     ██║   ██║██████╔╝   ██║   █████╗   ╚███╔╝%s       %s\n", side, Orange+Bold, Reset, sideEnd)
+	fmt.Fprintf(Stdout, "%s   %s██║     ██║   ██║██╔══██╗   ██║   ██╔══╝   ██╔██╗%s       %s\n", side, Orange+Bold, Reset, sideEnd)
+	fmt.Fprintf(Stdout, "%s   %s╚██████╗╚██████╔╝██║  ██║   ██║   ███████╗██╔╝ ██╗%s      %s\n", side, Orange+Bold, Reset, sideEnd)
+	fmt.Fprintf(Stdout, "%s   %s ╚═════╝ ╚═════╝ ╚═╝  ╚═╝   ╚═╝   ╚══════╝╚═╝  ╚═╝%s      %s\n", side, Orange+Bold, Reset, sideEnd)
+	fmt.Fprintln(Stdout, side+"                                                          "+sideEnd)
+	fmt.Fprintf(Stdout, "%s            %sAI Agent Orchestrator%s                      %s\n", side, Dim, Reset, sideEnd)
+	fmt.Fprintln(Stdout, side+"                                                          "+sideEnd)
+	fmt.Fprintln(Stdout, borderB)
 }
 
 // PrintCompactBanner prints a minimal banner
 func PrintCompactBanner(version string) {
-	fmt.Printf("\n%s◆ Cortex%s v%s\n\n", Orange+Bold, Reset, version)
+	fmt.Fprintf(Stdout, "\n%s◆ Cortex%s v%s\n\n", Orange+Bold, Reset, version)
 }
 
 // PrintSessionInfo prints session information
@@ -68,58 +88,118 @@ func PrintSessionInfo(sessionID, outputDir string) {
 		displayPath = "~" + outputDir[len(homeDir):]
 	}
 
-	fmt.Printf("\n  %s○%s Session: %s\n", Orange, Reset, sessionID)
-	fmt.Printf("    %s→%s Output: %s\n", Orange, Reset, displayPath)
-	fmt.Println()
+	fmt.Fprintf(Stdout, "\n  %s○%s Session: %s\n", Orange, Reset, sessionID)
+	fmt.Fprintf(Stdout, "    %s→%s Output: %s\n", Orange, Reset, TruncatePath(displayPath, Width()-14))
+	fmt.Fprintln(Stdout)
 }
 
-// PrintDivider prints a horizontal divider
+// PrintDivider prints a horizontal divider, shortened to fit the terminal
 func PrintDivider() {
-	fmt.Printf("\n%s─────────────────────────────────────────────%s\n", Dim, Reset)
+	fmt.Fprintf(Stdout, "\n%s%s%s\n", Dim, strings.Repeat("─", min(dividerWidth, Width())), Reset)
 }
 
-// PrintExecutionPlan prints the execution plan with colors
-func PrintExecutionPlan(tasks []TaskInfo) {
-	fmt.Printf("\n  %s%s◆ Execution Plan%s\n", Bold, Orange, Reset)
-	fmt.Printf("  %s─────────────────%s\n\n", Dim, Reset)
+// PrintExecutionPlan prints the execution plan with colors. With parallel
+// set, cards are grouped under their execution level and the tasks on the
+// critical path are marked.
+func PrintExecutionPlan(tasks []TaskInfo, parallel bool) {
+	fmt.Fprintf(Stdout, "\n  %s%s◆ Execution Plan%s\n", Bold, Orange, Reset)
+	fmt.Fprintf(Stdout, "  %s─────────────────%s\n\n", Dim, Reset)
 
-	for i, task := range tasks {
-		// Task card with box drawing
-		fmt.Printf("  %s┌─%s %s%d%s %s│%s %s%s%s\n",
-			Orange, Reset,
-			Dim, i+1, Reset,
-			Orange, Reset,
-			Bold+Orange, task.Name, Reset,
-		)
-
-		// Dependencies if any
-		if len(task.Dependencies) > 0 {
-			fmt.Printf("  %s│%s  %s↳ needs: %v%s\n",
-				Orange, Reset,
-				Dim, task.Dependencies, Reset,
-			)
+	if !parallel {
+		for i, task := range tasks {
+			printTaskCard(i+1, task, false)
 		}
+		return
+	}
 
-		// Agent info
-		fmt.Printf("  %s│%s  %s◇%s %s%s%s\n",
-			Orange, Reset,
-			Dim, Reset,
-			Orange, task.Agent, Reset,
-		)
+	// Group by level, keeping plan order within a level
+	var levels [][]TaskInfo
+	for _, task := range tasks {
+		level := max(task.Level, 0)
+		for len(levels) <= level {
+			levels = append(levels, nil)
+		}
+		levels[level] = append(levels[level], task)
+	}
 
-		// Tool and model
-		toolInfo := task.Tool
-		if task.Model != "" {
-			toolInfo += " · " + task.Model
+	num := 0
+	var critical []string
+	for i, level := range levels {
+		if len(level) == 0 {
+			continue
 		}
-		fmt.Printf("  %s│%s  %s◇%s %s%s%s\n",
+		names := make([]string, len(level))
+		for j, task := range level {
+			names[j] = task.Name
+		}
+		header := fmt.Sprintf("Level %d", i+1)
+		if len(level) > 1 {
+			header += fmt.Sprintf(" (parallel ×%d)", len(level))
+		}
+		fmt.Fprintf(Stdout, "  %s%s%s%s\n", Bold, Cyan, Truncate(header+": "+strings.Join(names, ", "), Width()-2), Reset)
+
+		for _, task := range level {
+			num++
+			printTaskCard(num, task, true)
+			if task.Critical {
+				critical = append(critical, task.Name)
+			}
+		}
+	}
+
+	if len(critical) > 1 {
+		fmt.Fprintf(Stdout, "  %s★%s %sCritical path:%s %s\n\n", Yellow, Reset, Dim, Reset,
+			Truncate(strings.Join(critical, " → "), Width()-18))
+	}
+}
+
+// printTaskCard prints one task of the execution plan as a numbered card.
+func printTaskCard(num int, task TaskInfo, markCritical bool) {
+	// Card lines are cut to fit the terminal; width is what follows "  │  "
+	width := Width() - 5
+
+	label := fmt.Sprint(num)
+	marker := ""
+	nameWidth := width - len(label) - 1
+	if markCritical && task.Critical {
+		marker = " " + Yellow + "★" + Reset
+		nameWidth -= 2
+	}
+	fmt.Fprintf(Stdout, "  %s┌─%s %s%s%s %s│%s %s%s%s%s\n",
+		Orange, Reset,
+		Dim, label, Reset,
+		Orange, Reset,
+		Bold+Orange, Truncate(task.Name, nameWidth), Reset,
+		marker,
+	)
+
+	// Dependencies if any
+	if len(task.Dependencies) > 0 {
+		fmt.Fprintf(Stdout, "  %s│%s  %s%s%s\n",
 			Orange, Reset,
-			Dim, Reset,
-			Dim, toolInfo, Reset,
+			Dim, Truncate(fmt.Sprintf("↳ needs: %v", task.Dependencies), width), Reset,
 		)
+	}
 
-		fmt.Printf("  %s└───────────────────%s\n\n", Orange, Reset)
+	// Agent info
+	fmt.Fprintf(Stdout, "  %s│%s  %s◇%s %s%s%s\n",
+		Orange, Reset,
+		Dim, Reset,
+		Orange, Truncate(task.Agent, width-2), Reset,
+	)
+
+	// Tool and model
+	toolInfo := task.Tool
+	if task.Model != "" {
+		toolInfo += " · " + task.Model
 	}
+	fmt.Fprintf(Stdout, "  %s│%s  %s◇%s %s%s%s\n",
+		Orange, Reset,
+		Dim, Reset,
+		Dim, Truncate(toolInfo, width-2), Reset,
+	)
+
+	fmt.Fprintf(Stdout, "  %s└%s%s\n\n", Orange, strings.Repeat("─", min(18, width+2)), Reset)
 }
 
 // TaskInfo holds task display information
@@ -129,6 +209,8 @@ type TaskInfo struct {
 	Tool         string
 	Model        string
 	Dependencies []string
+	Level        int  // Execution level, 0-based
+	Critical     bool // On the critical path
 }
 
 // PrintTaskStart prints task start message
@@ -137,43 +219,59 @@ func PrintTaskStart(index, total int, name, agent, tool, model string) {
 	if model != "" {
 		modelStr = " · " + model
 	}
-	fmt.Printf("\n%s┌─%s %s[%d/%d]%s %s%s%s\n",
+	counter := fmt.Sprintf("[%d/%d]", index, total)
+	width := Width()
+	// One write, so parallel tasks cannot split the header
+	fmt.Fprintf(Stdout, "\n%s┌─%s %s%s%s %s%s%s\n%s│%s  %s%s%s %s%s%s\n",
 		Orange, Reset,
-		Dim, index, total, Reset,
-		Bold+Orange, name, Reset,
-	)
-	fmt.Printf("%s│%s  %s%s%s %s· %s%s%s\n",
+		Dim, counter, Reset,
+		Bold+Orange, Truncate(name, width-4-len(counter)), Reset,
 		Orange, Reset,
 		Orange, agent, Reset,
-		Dim, tool, modelStr, Reset,
+		Dim, Truncate("· "+tool+modelStr, width-4-len(agent)), Reset,
 	)
 }
 
 // PrintTaskStatus prints task status
 func PrintTaskStatus(status string, success bool, duration string) {
-	var statusStr string
+	statusStr := fmt.Sprintf("%s %s(%s)%s", statusLabel(status, success), Dim, duration, Reset)
+	fmt.Fprintf(Stdout, "%s└─%s %s\n", Orange, Reset, statusStr)
+}
+
+// PrintTaskSkipped prints a skipped task status
+func PrintTaskSkipped(reason string) {
+	fmt.Fprintf(Stdout, "%s└─%s %s%s Skipped%s %s(%s)%s\n",
+		Orange, Reset, StatusColor(StatusSkipped), StatusGlyph(StatusSkipped), Reset, Dim, reason, Reset)
+}
+
+// PrintTaskCancelled prints the status of a task stopped by an interrupt
+func PrintTaskCancelled(duration string) {
+	fmt.Fprintf(Stdout, "%s└─%s %s%s Cancelled%s %s(%s)%s\n",
+		Orange, Reset, StatusColor(StatusCancelled), StatusGlyph(StatusCancelled), Reset, Dim, duration, Reset)
+}
+
+// statusLabel returns the glyph and text for a finished task's status.
+func statusLabel(status string, success bool) string {
+	s := StatusFailed
 	if success {
-		statusStr = fmt.Sprintf("%s✓ %s%s %s(%s)%s", Green, status, Reset, Dim, duration, Reset)
-	} else {
-		statusStr = fmt.Sprintf("%s✗ %s%s %s(%s)%s", Red, status, Reset, Dim, duration, Reset)
+		s = StatusSuccess
 	}
-	fmt.Printf("%s└─%s %s\n", Orange, Reset, statusStr)
+	return StatusColor(s) + StatusGlyph(s) + " " + status + Reset
 }
 
-// PrintTaskStatusWithTokens prints task completion with token usage
-func PrintTaskStatusWithTokens(status string, success bool, duration string, inputTokens, outputTokens int) {
-	var statusStr string
+// PrintTaskStatusWithTokens prints task completion with token usage and a
+// summary of the files the task changed, if any
+func PrintTaskStatusWithTokens(status string, success bool, duration string, inputTokens, outputTokens int, changes string) {
 	tokenInfo := ""
 	if inputTokens > 0 || outputTokens > 0 {
 		tokenInfo = fmt.Sprintf(" %s│ %s%d%s in / %s%d%s out%s",
 			Dim, Cyan, inputTokens, Reset+Dim, Cyan, outputTokens, Reset+Dim, Reset)
 	}
-	if success {
-		statusStr = fmt.Sprintf("%s✓ %s%s %s(%s)%s%s", Green, status, Reset, Dim, duration, Reset, tokenInfo)
-	} else {
-		statusStr = fmt.Sprintf("%s✗ %s%s %s(%s)%s%s", Red, status, Reset, Dim, duration, Reset, tokenInfo)
+	if changes != "" {
+		tokenInfo += fmt.Sprintf(" %s│ %s%s", Dim, changes, Reset)
 	}
-	fmt.Printf("%s└─%s %s\n", Orange, Reset, statusStr)
+	statusStr := fmt.Sprintf("%s %s(%s)%s%s", statusLabel(status, success), Dim, duration, Reset, tokenInfo)
+	fmt.Fprintf(Stdout, "%s└─%s %s\n", Orange, Reset, statusStr)
 }
 
 // FormatTokenCount formats a token count with commas for readability
@@ -186,37 +284,47 @@ func FormatTokenCount(n int) string {
 
 // PrintTaskRunning prints running status
 func PrintTaskRunning() {
-	fmt.Printf("%s│%s  %s● Running...%s\n", Orange, Reset, Orange, Reset)
+	fmt.Fprintf(Stdout, "%s│%s  %s%s Running...%s\n", Orange, Reset, Orange, StatusGlyph(StatusRunning), Reset)
 }
 
 // PrintTaskRunningWithHint prints running status with toggle hint
 func PrintTaskRunningWithHint(showHint bool) {
 	if showHint {
-		fmt.Printf("%s│%s  %s● Running...%s  %s[Ctrl+O to expand]%s\n", Orange, Reset, Orange, Reset, Dim, Reset)
+		fmt.Fprintf(Stdout, "%s│%s  %s%s Running...%s  %s[Ctrl+O to expand]%s\n", Orange, Reset, Orange, StatusGlyph(StatusRunning), Reset, Dim, Reset)
 	} else {
-		fmt.Printf("%s│%s  %s● Running...%s\n", Orange, Reset, Orange, Reset)
+		fmt.Fprintf(Stdout, "%s│%s  %s%s Running...%s\n", Orange, Reset, Orange, StatusGlyph(StatusRunning), Reset)
 	}
 }
 
-// PrintTaskRunningWithProgress prints running status with progress bar
+// PrintTaskRunningWithProgress prints running status with progress bar.
+// The hint, then the bar, are left out when the terminal is too narrow.
 func PrintTaskRunningWithProgress(taskNum, totalTasks int, showHint bool) {
 	bar := RenderProgressBar(taskNum-1, totalTasks) // taskNum-1 because current task is running
-	if showHint {
-		fmt.Printf("%s│%s  %s● Running...%s %s %s[Ctrl+O to expand]%s\n",
-			Orange, Reset, Orange, Reset, bar, Dim, Reset)
+	width := Width()
+	if width < 34 {
+		bar = ""
+	}
+	if showHint && width >= 53 && !plain {
+		fmt.Fprintf(Stdout, "%s│%s  %s%s Running...%s %s %s[Ctrl+O to expand]%s\n",
+			Orange, Reset, Orange, StatusGlyph(StatusRunning), Reset, bar, Dim, Reset)
 	} else {
-		fmt.Printf("%s│%s  %s● Running...%s %s\n", Orange, Reset, Orange, Reset, bar)
+		fmt.Fprintf(Stdout, "%s│%s  %s%s Running...%s %s\n", Orange, Reset, Orange, StatusGlyph(StatusRunning), Reset, bar)
 	}
 }
 
-// PrintSummary prints the final summary
-func PrintSummary(success bool, outputDir string) {
+// PrintSummary prints the final summary, noting any skipped tasks
+func PrintSummary(success bool, skipped int, duration time.Duration, outputDir string) {
 	PrintDivider()
 
 	if success {
-		fmt.Printf("\n  %s✓ All tasks completed successfully%s\n", Green+Bold, Reset)
+		fmt.Fprintf(Stdout, "\n  %s%s All tasks completed successfully%s %s(%s)%s\n",
+			StatusColor(StatusSuccess)+Bold, StatusGlyph(StatusSuccess), Reset, Dim, FormatDuration(duration), Reset)
 	} else {
-		fmt.Printf("\n  %s✗ Workflow completed with failures%s\n", Red+Bold, Reset)
+		fmt.Fprintf(Stdout, "\n  %s%s Workflow completed with failures%s %s(%s)%s\n",
+			StatusColor(StatusFailed)+Bold, StatusGlyph(StatusFailed), Reset, Dim, FormatDuration(duration), Reset)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(Stdout, "  %s%s %d task(s) skipped%s\n", StatusColor(StatusSkipped), StatusGlyph(StatusSkipped), skipped, Reset)
 	}
 
 	// Shorten output path
@@ -225,7 +333,7 @@ func PrintSummary(success bool, outputDir string) {
 	if homeDir != "" && len(outputDir) > len(homeDir) && outputDir[:len(homeDir)] == homeDir {
 		displayPath = "~" + outputDir[len(homeDir):]
 	}
-	fmt.Printf("  %sResults: %s%s\n\n", Dim, displayPath, Reset)
+	fmt.Fprintf(Stdout, "  %sResults: %s%s\n\n", Dim, TruncatePath(displayPath, Width()-11), Reset)
 }
 
 // GetCortexHome returns the cortex home directory (~/.cortex)
@@ -237,23 +345,40 @@ func GetCortexHome() (string, error) {
 	return filepath.Join(homeDir, ".cortex"), nil
 }
 
-// PrintStreamStart prints a visual separator before streaming output
-func PrintStreamStart() {
-	fmt.Printf("%s│%s\n", Orange, Reset)
-	fmt.Printf("%s│%s  %sAgent output:%s\n", Orange, Reset, Dim, Reset)
-	fmt.Printf("%s│%s  %s─────────────%s\n", Orange, Reset, Dim, Reset)
+// DecorWriter is implemented by output writers that also keep a copy of
+// what is written to them, like a task's log file. Separators are written
+// to Decor instead, so they stay out of the copy.
+type DecorWriter interface {
+	Decor() io.Writer
+}
+
+// decor returns where separators around streamed output go.
+func decor(w io.Writer) io.Writer {
+	if d, ok := w.(DecorWriter); ok {
+		return d.Decor()
+	}
+	return w
+}
+
+// PrintStreamStart prints a visual separator to w before streaming output
+func PrintStreamStart(w io.Writer) {
+	fmt.Fprint(decor(w), boxes(fmt.Sprintf("%s│%s\n%s│%s  %sAgent output:%s\n%s│%s  %s─────────────%s\n",
+		Orange, Reset,
+		Orange, Reset, Dim, Reset,
+		Orange, Reset, Dim, Reset,
+	)))
 }
 
-// PrintStreamEnd prints a visual separator after streaming output
-func PrintStreamEnd() {
-	fmt.Printf("%s│%s  %s─────────────%s\n", Orange, Reset, Dim, Reset)
+// PrintStreamEnd prints a visual separator to w after streaming output
+func PrintStreamEnd(w io.Writer) {
+	fmt.Fprint(decor(w), boxes(fmt.Sprintf("%s│%s  %s─────────────%s\n", Orange, Reset, Dim, Reset)))
 }
 
 // PrintTaskProgress prints task progress with spinner
 func PrintTaskProgress(taskNum, totalTasks int, taskName string, elapsed string) {
 	spinner := SpinnerFrames[0] // Use first frame for static display
 	bar := RenderProgressBar(taskNum, totalTasks)
-	fmt.Printf("\r%s│%s  %s%s%s %s%s%s %s %s(%s)%s",
+	fmt.Fprintf(Stdout, "\r%s│%s  %s%s%s %s%s%s %s %s(%s)%s",
 		Orange, Reset,
 		Orange, spinner, Reset,
 		Bold, taskName, Reset,
@@ -265,7 +390,7 @@ func PrintTaskProgress(taskNum, totalTasks int, taskName string, elapsed string)
 // PrintOverallProgress prints overall workflow progress
 func PrintOverallProgress(completed, total int, elapsed string) {
 	bar := RenderProgressBar(completed, total)
-	fmt.Printf("\n  %sProgress:%s %s %d/%d %s(%s)%s\n",
+	fmt.Fprintf(Stdout, "\n  %sProgress:%s %s %d/%d %s(%s)%s\n",
 		Dim, Reset,
 		bar,
 		completed, total,
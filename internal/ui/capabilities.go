@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// plain replaces box-drawing characters with ASCII and turns off animated
+// output. It is on when stdout is not a terminal, as when piped to a file or
+// in CI, or with --plain.
+var plain = !IsTerminal() || os.Getenv("TERM") == "dumb"
+
+// ColorsSupported reports whether stdout can show colors: it is a terminal
+// other than TERM=dumb, NO_COLOR is unset, and on Windows the console is
+// known to handle escape codes.
+func ColorsSupported() bool {
+	if !IsTerminal() || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	if runtime.GOOS == "windows" && os.Getenv("TERM") == "" && os.Getenv("WT_SESSION") == "" {
+		return false
+	}
+	return true
+}
+
+// SetPlain turns plain output on or off.
+func SetPlain(enabled bool) {
+	plain = enabled
+}
+
+// IsPlain reports whether output is plain: no spinners or status line, and
+// ASCII in place of box drawing.
+func IsPlain() bool {
+	return plain
+}
+
+// escapePattern matches terminal escape sequences: CSI sequences such as
+// colors and cursor moves, and OSC sequences such as hyperlinks and titles.
+var escapePattern = regexp.MustCompile("\x1b\\[[0-9;?]*[ -/]*[@-~]|\x1b\\][^\x07\x1b]*(\x07|\x1b\\\\)")
+
+// StripEscapes removes terminal escape sequences from s.
+func StripEscapes(s string) string {
+	return escapePattern.ReplaceAllString(s, "")
+}
+
+// asciiBoxes maps the box-drawing and block characters of the UI to ASCII.
+var asciiBoxes = strings.NewReplacer(
+	"─", "-", "━", "-", "═", "=", "┄", "-", "┈", "-",
+	"│", "|", "┃", "|", "║", "|", "┆", "|", "┊", "|",
+	"┌", "+", "┐", "+", "└", "+", "┘", "+", "├", "+", "┤", "+", "┬", "+", "┴", "+", "┼", "+",
+	"╭", "+", "╮", "+", "╰", "+", "╯", "+",
+	"╔", "+", "╗", "+", "╚", "+", "╝", "+", "╠", "+", "╣", "+", "╦", "+", "╩", "+", "╬", "+",
+	"█", "#", "▓", "#", "▒", "#", "░", ".",
+)
+
+// boxes replaces box drawing with ASCII in plain mode, for decorations
+// written to task output writers, which filter leaves alone.
+func boxes(s string) string {
+	if plain {
+		return asciiBoxes.Replace(s)
+	}
+	return s
+}
+
+// filter prepares UI output for stdout: escape codes are dropped when
+// colors are off, and in plain mode box drawing becomes ASCII. Task output
+// written through NewOutput keeps its characters, losing only escape codes.
+func (o *Output) filter(data []byte) []byte {
+	if colorsEnabled && !(plain && o.ui) {
+		return data
+	}
+	s := string(data)
+	if !colorsEnabled {
+		s = StripEscapes(s)
+	}
+	if plain && o.ui {
+		s = asciiBoxes.Replace(s)
+	}
+	return []byte(s)
+}
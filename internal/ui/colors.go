@@ -3,9 +3,7 @@ package ui
 
 import (
 	"fmt"
-	"os"
 	"regexp"
-	"runtime"
 	"strings"
 )
 
@@ -51,20 +49,9 @@ const (
 	BgWhite   = "\033[47m"
 )
 
-var colorsEnabled = true
-
-func init() {
-	// Disable colors on Windows unless TERM is set
-	if runtime.GOOS == "windows" {
-		if os.Getenv("TERM") == "" && os.Getenv("WT_SESSION") == "" {
-			colorsEnabled = false
-		}
-	}
-	// Respect NO_COLOR env var
-	if os.Getenv("NO_COLOR") != "" {
-		colorsEnabled = false
-	}
-}
+// colorsEnabled is whether output is colored. When it is off, escape codes
+// written to Stdout and Stderr are dropped too.
+var colorsEnabled = ColorsSupported()
 
 // SetColorsEnabled enables or disables color output.
 func SetColorsEnabled(enabled bool) {
@@ -97,43 +84,43 @@ func OrangeText(text string) string        { return Colorize(Orange, text) }
 
 // Success prints a success message
 func Success(format string, args ...interface{}) {
-	fmt.Printf(GreenText("✓ ")+format+"\n", args...)
+	fmt.Fprintf(Stdout, Colorize(StatusColor(StatusSuccess), StatusGlyph(StatusSuccess)+" ")+format+"\n", args...)
 }
 
 // Error prints an error message
 func Error(format string, args ...interface{}) {
-	fmt.Printf(RedText("✗ ")+format+"\n", args...)
+	fmt.Fprintf(Stdout, Colorize(StatusColor(StatusFailed), StatusGlyph(StatusFailed)+" ")+format+"\n", args...)
 }
 
 // Warning prints a warning message
 func Warning(format string, args ...interface{}) {
-	fmt.Printf(YellowText("⚠ ")+format+"\n", args...)
+	fmt.Fprintf(Stdout, Colorize(StatusColor(StatusWarning), StatusGlyph(StatusWarning)+" ")+format+"\n", args...)
 }
 
 // Info prints an info message
 func Info(format string, args ...interface{}) {
-	fmt.Printf(OrangeText("ℹ ")+format+"\n", args...)
+	fmt.Fprintf(Stdout, OrangeText("ℹ ")+format+"\n", args...)
 }
 
 // Step prints a setup step with a dot indicator
 func Step(format string, args ...interface{}) {
-	fmt.Printf("  %s•%s %s"+format+"%s\n", Orange, Reset, Dim, Reset)
+	fmt.Fprintf(Stdout, "  %s•%s %s"+format+"%s\n", Orange, Reset, Dim, Reset)
 }
 
 // StepDone prints a completed step
 func StepDone(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	fmt.Printf("  %s✓%s %s\n", Green, Reset, msg)
+	fmt.Fprintf(Stdout, "  %s %s\n", StatusIcon(StatusSuccess), msg)
 }
 
 // PrintSetupStart prints the setup section header
 func PrintSetupStart() {
-	fmt.Printf("\n  %s○%s Setup\n", Orange, Reset)
+	fmt.Fprintf(Stdout, "\n  %s○%s Setup\n", Orange, Reset)
 }
 
 // PrintSetupStep prints a setup step with green tick
 func PrintSetupStep(text string) {
-	fmt.Printf("    %s✓%s %s\n", Green, Reset, text)
+	fmt.Fprintf(Stdout, "    %s %s\n", StatusIcon(StatusSuccess), text)
 }
 
 // PrintSetupEnd prints the setup section footer
@@ -144,21 +131,19 @@ func PrintSetupEnd() {
 // PrintConfigInfo prints configuration summary
 func PrintConfigInfo(levels, maxParallel int, parallel bool) {
 	if parallel {
-		fmt.Printf("\n  %s⚡%s Parallel: %d levels, %d concurrent\n", Orange, Reset, levels, maxParallel)
+		fmt.Fprintf(Stdout, "\n  %s⚡%s Parallel: %d levels, %d concurrent\n", Orange, Reset, levels, maxParallel)
 	} else {
-		fmt.Printf("\n  %s→%s Sequential execution\n", Orange, Reset)
+		fmt.Fprintf(Stdout, "\n  %s→%s Sequential execution\n", Orange, Reset)
 	}
 }
 
 // Task prints a task status
 func Task(name, status string, success bool) {
-	var statusColor string
+	s := StatusFailed
 	if success {
-		statusColor = GreenText(status)
-	} else {
-		statusColor = RedText(status)
+		s = StatusSuccess
 	}
-	fmt.Printf("  %s %s\n", BoldText(name), statusColor)
+	fmt.Fprintf(Stdout, "  %s %s\n", BoldText(name), Colorize(StatusColor(s), StatusGlyph(s)+" "+status))
 }
 
 // Regex patterns for markdown stripping
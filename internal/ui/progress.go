@@ -11,11 +11,13 @@ import (
 // SpinnerFrames contains the animation frames for the spinner
 var SpinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 
-// Spinner provides an animated spinner for terminal output
+// Spinner provides an animated spinner for terminal output.
+// It is drawn on the renderer's status line, so it never mixes with
+// task output.
 type Spinner struct {
 	frames   []string
 	current  int
-	message  string
+	message  func() string
 	interval time.Duration
 	stop     chan struct{}
 	done     chan struct{}
@@ -35,6 +37,12 @@ func NewSpinner() *Spinner {
 
 // Start begins the spinner animation with the given message
 func (s *Spinner) Start(message string) {
+	s.StartFunc(func() string { return message })
+}
+
+// StartFunc begins the spinner animation, calling message on every frame
+// so the text can change (for example to show elapsed time).
+func (s *Spinner) StartFunc(message func() string) {
 	s.mu.Lock()
 	if s.running {
 		s.mu.Unlock()
@@ -55,7 +63,7 @@ func (s *Spinner) Start(message string) {
 			select {
 			case <-s.stop:
 				// Clear the spinner line
-				fmt.Print("\r\033[K")
+				SetStatus("")
 				return
 			case <-ticker.C:
 				s.mu.Lock()
@@ -64,7 +72,7 @@ func (s *Spinner) Start(message string) {
 				s.current++
 				s.mu.Unlock()
 
-				fmt.Printf("\r%s%s%s %s", Orange, frame, Reset, msg)
+				SetStatus(fmt.Sprintf("%s%s%s %s", Orange, frame, Reset, msg()))
 			}
 		}
 	}()
@@ -73,7 +81,7 @@ func (s *Spinner) Start(message string) {
 // Update changes the spinner message
 func (s *Spinner) Update(message string) {
 	s.mu.Lock()
-	s.message = message
+	s.message = func() string { return message }
 	s.mu.Unlock()
 }
 
@@ -85,10 +93,12 @@ func (s *Spinner) Stop() {
 		return
 	}
 	s.running = false
+	// A Start racing with this Stop makes new channels
+	stop, done := s.stop, s.done
 	s.mu.Unlock()
 
-	close(s.stop)
-	<-s.done
+	close(stop)
+	<-done
 }
 
 // ProgressBar represents a progress bar
@@ -147,24 +157,31 @@ func (p *ProgressBar) RenderCompact() string {
 	return fmt.Sprintf("%d/%d (%s)", current, p.total, elapsed)
 }
 
-// ProgressTracker combines spinner and progress tracking
+// ProgressTracker shows a spinner on the status line while tasks run.
+// In sequential runs (no levels) it shows the running task and its elapsed
+// time; in parallel runs it shows a progress bar, how many tasks have
+// finished and are running, the current level, and the tasks in flight.
+// It is safe to call from the goroutines of parallel tasks.
 type ProgressTracker struct {
 	totalTasks     int
-	completedTasks atomic.Int32
+	completedTasks int
 	currentLevel   int
 	totalLevels    int
-	currentTask    string
+	running        []string             // Names of running tasks, in start order
+	started        map[string]time.Time // Start time of each running task
 	startTime      time.Time
 	spinner        *Spinner
 	enabled        bool
 	mu             sync.Mutex
 }
 
-// NewProgressTracker creates a new progress tracker
+// NewProgressTracker creates a new progress tracker.
+// Pass 0 for totalLevels in sequential runs.
 func NewProgressTracker(totalTasks, totalLevels int) *ProgressTracker {
 	return &ProgressTracker{
 		totalTasks:  totalTasks,
 		totalLevels: totalLevels,
+		started:     make(map[string]time.Time),
 		startTime:   time.Now(),
 		spinner:     NewSpinner(),
 		enabled:     true,
@@ -178,24 +195,41 @@ func (p *ProgressTracker) SetEnabled(enabled bool) {
 	p.mu.Unlock()
 }
 
-// StartTask marks a task as started
+// StartTask marks a task as started and shows the spinner
 func (p *ProgressTracker) StartTask(taskName string, level int) {
 	p.mu.Lock()
-	p.currentTask = taskName
-	p.currentLevel = level
+	// Tasks start across levels, so show the furthest one reached
+	p.currentLevel = max(p.currentLevel, level)
+	if _, ok := p.started[taskName]; !ok {
+		p.running = append(p.running, taskName)
+	}
+	p.started[taskName] = time.Now()
 	enabled := p.enabled
 	p.mu.Unlock()
 
 	if enabled {
-		msg := p.formatProgress()
-		p.spinner.Start(msg)
+		p.spinner.StartFunc(p.formatProgress)
 	}
 }
 
-// CompleteTask marks a task as completed
-func (p *ProgressTracker) CompleteTask() {
-	p.completedTasks.Add(1)
-	p.spinner.Stop()
+// CompleteTask marks a task as completed. In sequential runs the spinner
+// stops until the next task starts; in parallel runs it stays up until Stop.
+func (p *ProgressTracker) CompleteTask(taskName string) {
+	p.mu.Lock()
+	p.completedTasks++
+	delete(p.started, taskName)
+	for i, name := range p.running {
+		if name == taskName {
+			p.running = append(p.running[:i], p.running[i+1:]...)
+			break
+		}
+	}
+	idle := p.totalLevels == 0 && len(p.running) == 0
+	p.mu.Unlock()
+
+	if idle {
+		p.spinner.Stop()
+	}
 }
 
 // Stop stops the progress tracker
@@ -203,28 +237,45 @@ func (p *ProgressTracker) Stop() {
 	p.spinner.Stop()
 }
 
-// formatProgress formats the progress message
+// formatProgress formats the progress message. Task names are cut so the
+// status line, spinner included, fits on one terminal line.
 func (p *ProgressTracker) formatProgress() string {
-	completed := int(p.completedTasks.Load())
-	elapsed := time.Since(p.startTime).Round(time.Second)
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	// Progress bar
-	ratio := float64(completed) / float64(p.totalTasks)
-	if ratio > 1 {
-		ratio = 1
+	// Room left after the spinner frame and its space
+	width := Width() - 3
+
+	if p.totalLevels == 0 {
+		if len(p.running) == 0 {
+			return ""
+		}
+		name := p.running[0]
+		elapsed := fmt.Sprintf("(%s)", FormatDuration(time.Since(p.started[name]).Truncate(time.Second)))
+		name = Truncate(name, width-len(elapsed)-1)
+		return fmt.Sprintf("%s%s%s %s%s%s", Bold, name, Reset, Dim, elapsed, Reset)
 	}
-	barWidth := 16
-	filled := int(ratio * float64(barWidth))
-	empty := barWidth - filled
-	bar := strings.Repeat("█", filled) + strings.Repeat("░", empty)
 
-	return fmt.Sprintf("%s%s%s [%s] %d/%d (Level %d/%d) %s",
-		Bold, p.currentTask, Reset,
-		bar,
-		completed, p.totalTasks,
-		p.currentLevel+1, p.totalLevels,
-		elapsed,
-	)
+	// Keep the status to one line
+	running := strings.Join(p.running, ", ")
+	if len(p.running) > 3 {
+		running = fmt.Sprintf("%s +%d", strings.Join(p.running[:3], ", "), len(p.running)-3)
+	}
+
+	completed := p.completedTasks
+	counts := fmt.Sprintf("%d/%d done, %d running", completed, p.totalTasks, len(p.running))
+	level := fmt.Sprintf("(Level %d/%d)", p.currentLevel+1, p.totalLevels)
+	elapsed := fmt.Sprintf("(%s)", FormatDuration(time.Since(p.startTime).Truncate(time.Second)))
+
+	// On narrow terminals the bar (18 columns) and level go before the
+	// task names do
+	prefix := fmt.Sprintf("%s %s %s%s%s", RenderProgressBar(completed, p.totalTasks), counts, Dim, level, Reset)
+	prefixWidth := 18 + 1 + len(counts) + 1 + len(level)
+	if width-prefixWidth-len(elapsed)-2 < 10 {
+		prefix, prefixWidth = counts, len(counts)
+	}
+	running = Truncate(running, width-prefixWidth-len(elapsed)-2)
+	return fmt.Sprintf("%s %s%s%s %s%s%s", prefix, Bold, running, Reset, Dim, elapsed, Reset)
 }
 
 // RenderProgress renders a static progress line
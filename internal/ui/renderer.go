@@ -0,0 +1,209 @@
+package ui
+
+import (
+	"bytes"
+	"os"
+	"sync"
+)
+
+// Stdout and Stderr are the shared terminal writers. All UI output goes
+// through them (or through writers from NewOutput) so that a single render
+// goroutine performs every terminal write.
+var (
+	Stdout = &Output{ui: true}
+	Stderr = &Output{ui: true, stderr: true}
+)
+
+// Output is a terminal writer whose writes are serialized by the renderer.
+// While one Output has a partial line on screen, writes from other Outputs
+// are held back until that line ends, so concurrent tasks never split each
+// other's lines. Give each streaming task its own Output and Close it when
+// the stream ends.
+type Output struct {
+	stderr bool
+	ui     bool // Stdout or Stderr, whose box drawing plain mode replaces
+}
+
+// NewOutput creates a writer to stdout with its own line state.
+func NewOutput() *Output {
+	return &Output{}
+}
+
+// NewErrOutput creates a writer to stderr with its own line state.
+func NewErrOutput() *Output {
+	return &Output{stderr: true}
+}
+
+// Write hands p to the render goroutine and waits until it has been
+// written or queued behind another writer's open line.
+func (o *Output) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	done := make(chan error, 1)
+	render(renderMsg{out: o, data: append([]byte(nil), p...), done: done})
+	if err := <-done; err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close ends the writer's open line, if any, releasing the terminal to
+// other writers. The Output can still be written to afterwards.
+func (o *Output) Close() error {
+	done := make(chan error, 1)
+	render(renderMsg{out: o, close: true, done: done})
+	return <-done
+}
+
+// SetStatus shows text on a transient status line below all other output,
+// replacing the previous status. The renderer clears the line before other
+// writes and redraws it after them. An empty text removes the line. Does
+// nothing when stdout is not a terminal or output is plain.
+func SetStatus(text string) {
+	if !IsTerminal() || plain {
+		return
+	}
+	done := make(chan error, 1)
+	render(renderMsg{status: &text, done: done})
+	<-done
+}
+
+// renderMsg is a write, close, or status request sent to the render goroutine.
+type renderMsg struct {
+	out    *Output
+	data   []byte
+	close  bool
+	status *string
+	done   chan error
+}
+
+var (
+	renderOnce sync.Once
+	renderCh   chan renderMsg
+)
+
+// render sends msg to the render goroutine, starting it on first use.
+func render(msg renderMsg) {
+	renderOnce.Do(func() {
+		renderCh = make(chan renderMsg, 64)
+		r := &renderer{pending: make(map[*Output][]byte)}
+		go r.run()
+	})
+	renderCh <- msg
+}
+
+// renderer owns the terminal. Only its goroutine writes to os.Stdout and
+// os.Stderr on behalf of Outputs.
+type renderer struct {
+	// open is the Output whose partial line is on screen, if any
+	open *Output
+	// pending holds output from other writers while a line is open
+	pending map[*Output][]byte
+	// order lists writers with pending output, oldest first
+	order []*Output
+	// status is the transient status line, drawn while no line is open
+	status      string
+	statusShown bool
+}
+
+func (r *renderer) run() {
+	for msg := range renderCh {
+		var err error
+		switch {
+		case msg.status != nil:
+			r.setStatus(*msg.status)
+		case msg.close:
+			err = r.close(msg.out)
+		case r.open != nil && r.open != msg.out:
+			r.hold(msg.out, msg.data)
+		default:
+			err = r.emit(msg.out, msg.data)
+		}
+		if drainErr := r.drain(); err == nil {
+			err = drainErr
+		}
+		msg.done <- err
+	}
+}
+
+// emit writes data to the terminal and records whether it left a line open.
+func (r *renderer) emit(o *Output, data []byte) error {
+	r.clearStatus()
+	f := os.Stdout
+	if o.stderr {
+		f = os.Stderr
+	}
+	_, err := f.Write(o.filter(data))
+	if data[len(data)-1] == '\n' {
+		r.open = nil
+		r.drawStatus()
+	} else {
+		r.open = o
+	}
+	return err
+}
+
+// setStatus replaces the status line, drawing it now unless a line is open.
+func (r *renderer) setStatus(text string) {
+	r.status = text
+	if r.open != nil {
+		return
+	}
+	r.clearStatus()
+	r.drawStatus()
+}
+
+// drawStatus prints the status line without a newline, so the next write
+// can erase it.
+func (r *renderer) drawStatus() {
+	if r.status == "" {
+		return
+	}
+	os.Stdout.WriteString(r.status)
+	r.statusShown = true
+}
+
+// clearStatus erases the status line if it is on screen.
+func (r *renderer) clearStatus() {
+	if !r.statusShown {
+		return
+	}
+	os.Stdout.WriteString("\r\033[K")
+	r.statusShown = false
+}
+
+// hold queues data until the open line ends.
+func (r *renderer) hold(o *Output, data []byte) {
+	if _, ok := r.pending[o]; !ok {
+		r.order = append(r.order, o)
+	}
+	r.pending[o] = append(r.pending[o], data...)
+}
+
+// close ends o's open line, and makes sure output it still has queued ends
+// with a newline so it cannot hold the terminal once written.
+func (r *renderer) close(o *Output) error {
+	if buf, ok := r.pending[o]; ok && !bytes.HasSuffix(buf, []byte("\n")) {
+		r.pending[o] = append(buf, '\n')
+	}
+	if r.open != o {
+		return nil
+	}
+	return r.emit(o, []byte("\n"))
+}
+
+// drain writes queued output in arrival order until a writer leaves a line
+// open again.
+func (r *renderer) drain() error {
+	for r.open == nil && len(r.order) > 0 {
+		o := r.order[0]
+		r.order = r.order[1:]
+		data := r.pending[o]
+		delete(r.pending, o)
+		if err := r.emit(o, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,8 +3,11 @@ package ui
 import (
 	"os"
 	"sync"
+	"unicode/utf8"
 
 	"golang.org/x/term"
+
+	"github.com/adityaraj/agentflow/internal/proc"
 )
 
 // OutputMode defines how output is displayed
@@ -17,6 +20,51 @@ const (
 	OutputExpanded
 )
 
+// IsTerminal reports whether stdout is a terminal. Animated output such as
+// spinners and status lines is only drawn when it is.
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// DefaultWidth is the layout width used when stdout is not a terminal.
+const DefaultWidth = 80
+
+// Width returns the width of the terminal in columns, or DefaultWidth when
+// stdout is not a terminal.
+func Width() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return DefaultWidth
+	}
+	return width
+}
+
+// Truncate shortens s to at most width columns, marking the cut with "…".
+func Truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// TruncatePath shortens a path to at most width columns by dropping its
+// beginning, so the file name stays visible.
+func TruncatePath(path string, width int) string {
+	n := utf8.RuneCountInString(path)
+	if n <= width {
+		return path
+	}
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(path)
+	return "…" + string(runes[n-width+1:])
+}
+
 // TerminalController manages interactive terminal features
 type TerminalController struct {
 	mu         sync.RWMutex
@@ -138,11 +186,7 @@ func (t *TerminalController) listenKeys() {
 
 			// Ctrl+C is ASCII 3 - propagate interrupt
 			if buf[0] == 3 {
-				// Send SIGINT to self
-				p, _ := os.FindProcess(os.Getpid())
-				if p != nil {
-					_ = p.Signal(os.Interrupt)
-				}
+				_ = proc.InterruptSelf()
 			}
 		}
 	}
@@ -180,12 +224,12 @@ func (b *BufferedWriter) Write(p []byte) (n int, err error) {
 
 	// Write based on mode
 	if b.controller.IsExpanded() {
-		return os.Stdout.Write(p)
+		return Stdout.Write(p)
 	}
 
 	// In collapsed mode, only write if under limit
 	if b.lineCount <= b.controller.maxSummary {
-		return os.Stdout.Write(p)
+		return Stdout.Write(p)
 	}
 
 	return len(p), nil
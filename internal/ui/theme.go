@@ -0,0 +1,95 @@
+package ui
+
+// Status is an outcome shown with the current theme's color and glyph.
+type Status string
+
+const (
+	StatusSuccess   Status = "success"
+	StatusFailed    Status = "failed"
+	StatusSkipped   Status = "skipped"
+	StatusRunning   Status = "running"
+	StatusWarning   Status = "warning"
+	StatusCancelled Status = "cancelled"
+)
+
+// Theme holds the color and glyph for each status. Every status has its own
+// glyph, so statuses can be told apart without relying on color.
+type Theme struct {
+	Colors map[Status]string
+	Glyphs map[Status]string
+}
+
+// defaultGlyphs are the glyphs of both built-in palettes.
+var defaultGlyphs = map[Status]string{
+	StatusSuccess:   "✓",
+	StatusFailed:    "✗",
+	StatusSkipped:   "○",
+	StatusRunning:   "●",
+	StatusWarning:   "⚠",
+	StatusCancelled: "⊘",
+}
+
+// DefaultTheme uses green for success and red for failure.
+var DefaultTheme = Theme{
+	Colors: map[Status]string{
+		StatusSuccess:   Green,
+		StatusFailed:    Red,
+		StatusSkipped:   Yellow,
+		StatusRunning:   Cyan,
+		StatusWarning:   Yellow,
+		StatusCancelled: Yellow,
+	},
+	Glyphs: defaultGlyphs,
+}
+
+// AccessibleTheme avoids pairing red with green, using blue for success and
+// magenta for failure, which stay distinct with the common forms of color
+// blindness.
+var AccessibleTheme = Theme{
+	Colors: map[Status]string{
+		StatusSuccess:   BrightBlue,
+		StatusFailed:    BrightMagenta,
+		StatusSkipped:   Dim,
+		StatusRunning:   BrightCyan,
+		StatusWarning:   BrightYellow,
+		StatusCancelled: BrightYellow,
+	},
+	Glyphs: defaultGlyphs,
+}
+
+// currentTheme is the theme used for statuses; set it with SetTheme before
+// any output is drawn.
+var currentTheme = DefaultTheme
+
+// SetTheme sets the theme used for statuses.
+func SetTheme(theme Theme) {
+	currentTheme = theme
+}
+
+// WithGlyphs returns a copy of the theme with the given glyphs replaced.
+func (t Theme) WithGlyphs(glyphs map[Status]string) Theme {
+	merged := make(map[Status]string, len(t.Glyphs))
+	for status, glyph := range t.Glyphs {
+		merged[status] = glyph
+	}
+	for status, glyph := range glyphs {
+		merged[status] = glyph
+	}
+	t.Glyphs = merged
+	return t
+}
+
+// StatusColor returns the color code for status.
+func StatusColor(status Status) string {
+	return currentTheme.Colors[status]
+}
+
+// StatusGlyph returns the glyph for status.
+func StatusGlyph(status Status) string {
+	return currentTheme.Glyphs[status]
+}
+
+// StatusIcon returns the glyph for status in its color.
+func StatusIcon(status Status) string {
+	return StatusColor(status) + StatusGlyph(status) + Reset
+}
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimestampLayout is the layout for absolute times shown to the user.
+const TimestampLayout = "2006-01-02 15:04:05"
+
+// FormatDuration formats a duration for display: "850ms", "4.2s", "12s",
+// "2m 5s", or "1h 3m".
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < 10*time.Second:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Round(time.Second).Seconds()))
+	case d < time.Hour:
+		d = d.Round(time.Second)
+		return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		d = d.Round(time.Minute)
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
+
+// FormatTime formats t in the local timezone, or "unknown" if it is zero.
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Local().Format(TimestampLayout)
+}
+
+// FormatAge describes how long before now t was: "just now", "5m ago",
+// "3h ago", "yesterday", "4d ago", or a date for older times.
+func FormatAge(t, now time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	age := now.Sub(t)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	case age < 48*time.Hour:
+		return "yesterday"
+	case age < 7*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	}
+
+	local := t.Local()
+	if local.Year() == now.Local().Year() {
+		return local.Format("Jan 2")
+	}
+	return local.Format("Jan 2, 2006")
+}
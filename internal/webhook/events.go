@@ -12,16 +12,44 @@ const (
 	EventTaskStart    = "task_start"
 	EventTaskComplete = "task_complete"
 	EventTaskFailed   = "task_failed"
+
+	// EventRunCancelled is sent in place of run_complete when a run is
+	// stopped by an interrupt.
+	EventRunCancelled = "run_cancelled"
+
+	// EventLevelStart is sent in parallel mode when the first task of a
+	// dependency level starts.
+	EventLevelStart = "level_start"
+
+	// EventBudgetExceeded is sent when a run is aborted by settings.max_tokens
+	// or settings.max_cost_usd.
+	EventBudgetExceeded = "budget_exceeded"
+
+	// EventNotify is sent by notify tasks with a message from the workflow.
+	EventNotify = "notify"
+
+	// MasterCortex events
+	EventMasterRunStart    = "master_run_start"
+	EventMasterRunComplete = "master_run_complete"
+	EventWorkflowStart     = "workflow_start"
+	EventWorkflowComplete  = "workflow_complete"
+	EventWorkflowFailed    = "workflow_failed"
+	EventWorkflowSkipped   = "workflow_skipped"
 )
 
 // Event represents a webhook event payload.
 type Event struct {
-	Type      string     `json:"event"`
-	Timestamp time.Time  `json:"timestamp"`
-	RunID     string     `json:"run_id"`
-	Project   string     `json:"project"`
-	Task      *TaskEvent `json:"task,omitempty"`
-	Run       *RunEvent  `json:"run,omitempty"`
+	Type      string         `json:"event"`
+	Timestamp time.Time      `json:"timestamp"`
+	RunID     string         `json:"run_id"`
+	Project   string         `json:"project"`
+	Task      *TaskEvent     `json:"task,omitempty"`
+	Run       *RunEvent      `json:"run,omitempty"`
+	Workflow  *WorkflowEvent `json:"workflow,omitempty"`
+	Progress  *ProgressEvent `json:"progress,omitempty"`
+	Budget    *BudgetEvent   `json:"budget,omitempty"`
+	Notify    *NotifyEvent   `json:"notify,omitempty"`
+	Level     *LevelEvent    `json:"level,omitempty"`
 }
 
 // TaskEvent contains task-specific event data.
@@ -32,14 +60,57 @@ type TaskEvent struct {
 	Model    string `json:"model,omitempty"`
 	Duration string `json:"duration,omitempty"`
 	Success  bool   `json:"success"`
+	Status   string `json:"status,omitempty"` // success, failed, or skipped
 	Error    string `json:"error,omitempty"`
 }
 
+// LevelEvent describes a dependency level of a parallel run.
+type LevelEvent struct {
+	Level int      `json:"level"` // 1-based
+	Total int      `json:"total"` // Number of levels in the run
+	Tasks []string `json:"tasks"`
+}
+
 // RunEvent contains run-specific event data.
 type RunEvent struct {
-	TaskCount int    `json:"task_count"`
-	Duration  string `json:"duration"`
+	TaskCount      int      `json:"task_count"`
+	Duration       string   `json:"duration"`
+	Success        bool     `json:"success"`
+	CancelledTasks []string `json:"cancelled_tasks,omitempty"` // Tasks stopped while running (run_cancelled)
+}
+
+// WorkflowEvent contains data for a child workflow of a master run.
+type WorkflowEvent struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	TaskCount int    `json:"task_count,omitempty"`
+	Duration  string `json:"duration,omitempty"`
 	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BudgetEvent describes run usage when the budget was exceeded.
+type BudgetEvent struct {
+	Task       string  `json:"task"` // Task whose usage crossed the limit
+	TokensUsed int     `json:"tokens_used"`
+	CostUSD    float64 `json:"cost_usd"`
+	MaxTokens  int     `json:"max_tokens,omitempty"`
+	MaxCostUSD float64 `json:"max_cost_usd,omitempty"`
+}
+
+// NotifyEvent is a message sent by a notify task.
+type NotifyEvent struct {
+	Task    string `json:"task"`
+	Level   string `json:"level"` // info, success, warning, or error
+	Message string `json:"message"`
+}
+
+// ProgressEvent contains aggregate workflow progress for a master run.
+type ProgressEvent struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
 }
 
 // NewRunStartEvent creates a run_start event.
@@ -67,6 +138,15 @@ func NewRunCompleteEvent(runID, project string, taskCount int, duration time.Dur
 	}
 }
 
+// NewRunCancelledEvent creates a run_cancelled event for a run stopped while
+// the cancelled tasks were running.
+func NewRunCancelledEvent(runID, project string, taskCount int, duration time.Duration, cancelled []string) Event {
+	event := NewRunCompleteEvent(runID, project, taskCount, duration, false)
+	event.Type = EventRunCancelled
+	event.Run.CancelledTasks = cancelled
+	return event
+}
+
 // NewTaskStartEvent creates a task_start event.
 func NewTaskStartEvent(runID, project, taskName, agent, tool, model string) Event {
 	return Event{
@@ -119,3 +199,125 @@ func NewTaskFailedEvent(runID, project, taskName, agent, tool, model, duration,
 		},
 	}
 }
+
+// NewLevelStartEvent creates a level_start event.
+func NewLevelStartEvent(runID, project string, level LevelEvent) Event {
+	return Event{
+		Type:      EventLevelStart,
+		Timestamp: time.Now(),
+		RunID:     runID,
+		Project:   project,
+		Level:     &level,
+	}
+}
+
+// NewBudgetExceededEvent creates a budget_exceeded event.
+func NewBudgetExceededEvent(runID, project string, budget BudgetEvent) Event {
+	return Event{
+		Type:      EventBudgetExceeded,
+		Timestamp: time.Now(),
+		RunID:     runID,
+		Project:   project,
+		Budget:    &budget,
+	}
+}
+
+// NewNotifyEvent creates a notify event.
+func NewNotifyEvent(runID, project string, notify NotifyEvent) Event {
+	return Event{
+		Type:      EventNotify,
+		Timestamp: time.Now(),
+		RunID:     runID,
+		Project:   project,
+		Notify:    &notify,
+	}
+}
+
+// NewMasterRunStartEvent creates a master_run_start event.
+func NewMasterRunStartEvent(runID, master string, progress ProgressEvent) Event {
+	return Event{
+		Type:      EventMasterRunStart,
+		Timestamp: time.Now(),
+		RunID:     runID,
+		Project:   master,
+		Progress:  &progress,
+	}
+}
+
+// NewMasterRunCompleteEvent creates a master_run_complete event.
+// taskCount is the total number of tasks run across all workflows.
+func NewMasterRunCompleteEvent(runID, master string, taskCount int, duration time.Duration, success bool, progress ProgressEvent) Event {
+	return Event{
+		Type:      EventMasterRunComplete,
+		Timestamp: time.Now(),
+		RunID:     runID,
+		Project:   master,
+		Run: &RunEvent{
+			TaskCount: taskCount,
+			Duration:  duration.Round(time.Millisecond * 100).String(),
+			Success:   success,
+		},
+		Progress: &progress,
+	}
+}
+
+// NewWorkflowStartEvent creates a workflow_start event.
+func NewWorkflowStartEvent(runID, master, name, path string, progress ProgressEvent) Event {
+	return Event{
+		Type:      EventWorkflowStart,
+		Timestamp: time.Now(),
+		RunID:     runID,
+		Project:   master,
+		Workflow:  &WorkflowEvent{Name: name, Path: path},
+		Progress:  &progress,
+	}
+}
+
+// NewWorkflowCompleteEvent creates a workflow_complete event.
+func NewWorkflowCompleteEvent(runID, master, name, path string, taskCount int, duration time.Duration, progress ProgressEvent) Event {
+	return Event{
+		Type:      EventWorkflowComplete,
+		Timestamp: time.Now(),
+		RunID:     runID,
+		Project:   master,
+		Workflow: &WorkflowEvent{
+			Name:      name,
+			Path:      path,
+			TaskCount: taskCount,
+			Duration:  duration.Round(time.Millisecond * 100).String(),
+			Success:   true,
+		},
+		Progress: &progress,
+	}
+}
+
+// NewWorkflowFailedEvent creates a workflow_failed event.
+func NewWorkflowFailedEvent(runID, master, name, path string, taskCount int, duration time.Duration, errMsg string, progress ProgressEvent) Event {
+	return Event{
+		Type:      EventWorkflowFailed,
+		Timestamp: time.Now(),
+		RunID:     runID,
+		Project:   master,
+		Workflow: &WorkflowEvent{
+			Name:      name,
+			Path:      path,
+			TaskCount: taskCount,
+			Duration:  duration.Round(time.Millisecond * 100).String(),
+			Success:   false,
+			Error:     errMsg,
+		},
+		Progress: &progress,
+	}
+}
+
+// NewWorkflowSkippedEvent creates a workflow_skipped event.
+func NewWorkflowSkippedEvent(runID, master, name, path, reason string, progress ProgressEvent) Event {
+	return Event{
+		Type:      EventWorkflowSkipped,
+		Timestamp: time.Now(),
+		RunID:     runID,
+		Project:   master,
+		Workflow:  &WorkflowEvent{Name: name, Path: path, Error: reason},
+		Progress:  &progress,
+	}
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adityaraj/agentflow/internal/paths"
+	"github.com/adityaraj/agentflow/internal/proc"
+)
+
+// Delivery is a webhook request that has not succeeded yet. It holds
+// everything needed to send it again, so it can be retried after the
+// process that created it has exited.
+type Delivery struct {
+	ID        string            `json:"id"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Event     string            `json:"event"`   // Event type, for listing
+	Payload   json.RawMessage   `json:"payload"` // Redacted event JSON
+	Attempts  int               `json:"attempts"`
+	CreatedAt time.Time         `json:"created_at"`
+	LastError string            `json:"last_error,omitempty"`
+	Sender    int               `json:"sender,omitempty"` // ID of the process still attempting it, 0 once it gave up
+}
+
+// Retrying reports whether the process that created the delivery is still
+// attempting it, so Drain must leave it alone.
+func (d *Delivery) Retrying() bool {
+	return d.Sender != 0 && d.Sender != os.Getpid() && proc.Alive(d.Sender)
+}
+
+// Queue stores pending deliveries as one JSON file each. A delivery is
+// written before its first attempt and removed once it succeeds.
+type Queue struct {
+	dir string
+}
+
+// DefaultQueue returns the queue in webhooks/queue of the state directory
+// (~/.cortex, or $XDG_STATE_HOME/cortex).
+func DefaultQueue() (*Queue, error) {
+	stateDir, err := paths.StateDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewQueue(filepath.Join(stateDir, "webhooks", "queue")), nil
+}
+
+// NewQueue returns a queue stored in dir.
+func NewQueue(dir string) *Queue {
+	return &Queue{dir: dir}
+}
+
+// newDelivery creates a delivery of an event payload to a webhook.
+func newDelivery(url string, headers map[string]string, event string, payload []byte) *Delivery {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	now := time.Now()
+	return &Delivery{
+		// Timestamp first, so file names sort in creation order
+		ID:        now.Format("20060102-150405.000000") + "-" + hex.EncodeToString(suffix),
+		URL:       url,
+		Headers:   headers,
+		Event:     event,
+		Payload:   payload,
+		CreatedAt: now,
+	}
+}
+
+// Save writes a delivery, replacing any earlier version. Files are only
+// readable by the user, since headers may hold tokens.
+func (q *Queue) Save(d *Delivery) error {
+	if err := os.MkdirAll(q.dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write then rename, so a crash never leaves a partial file
+	tmp := q.path(d.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path(d.ID))
+}
+
+// Remove deletes a delivered delivery.
+func (q *Queue) Remove(id string) error {
+	err := os.Remove(q.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// List returns the pending deliveries, oldest first. Unreadable files are
+// skipped.
+func (q *Queue) List() ([]*Delivery, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var deliveries []*Delivery
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(q.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var d Delivery
+		if err := json.Unmarshal(data, &d); err != nil || d.ID == "" {
+			continue
+		}
+		deliveries = append(deliveries, &d)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	return deliveries, nil
+}
+
+// Lock takes the queue's drain lock, so two drains don't send the same
+// deliveries. A lock left by a process that has exited is taken over.
+// Returns a function that releases it.
+func (q *Queue) Lock() (unlock func(), err error) {
+	if err := os.MkdirAll(q.dir, 0700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(q.dir, "drain.lock")
+
+	// Write the pid first and link the file into place, so the lock never
+	// exists without the pid that tells whether it is held
+	tmp, err := os.CreateTemp(q.dir, "drain.lock-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = fmt.Fprintf(tmp, "%d\n", os.Getpid())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		err := os.Link(tmp.Name(), path)
+		if err == nil {
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue // Released meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+		pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+		if proc.Alive(pid) {
+			return nil, fmt.Errorf("the queue is being drained by process %d", pid)
+		}
+
+		// Move the stale lock away, which only one process can do. If
+		// another took it over meanwhile, what was moved is its lock:
+		// put it back.
+		stale := tmp.Name() + ".stale"
+		if err := os.Rename(path, stale); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		moved, err := os.ReadFile(stale)
+		if err == nil && !bytes.Equal(moved, data) {
+			_ = os.Link(stale, path)
+			os.Remove(stale)
+			return nil, fmt.Errorf("the queue is being drained by process %s", strings.TrimSpace(string(moved)))
+		}
+		os.Remove(stale)
+	}
+}
+
+func (q *Queue) path(id string) string {
+	return filepath.Join(q.dir, id+".json")
+}
@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// otherProcess starts a process that runs until the test ends, and returns
+// its pid.
+func otherProcess(t *testing.T) int {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sleep")
+	}
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	return cmd.Process.Pid
+}
+
+// exitedProcess returns the pid of a process that has exited.
+func exitedProcess(t *testing.T) int {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses true")
+	}
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+// TestQueue tests saving, listing, and removing deliveries.
+func TestQueue(t *testing.T) {
+	q := NewQueue(filepath.Join(t.TempDir(), "queue"))
+
+	if deliveries, err := q.List(); err != nil || len(deliveries) != 0 {
+		t.Fatalf("List() of a missing queue = %v, %v, want none", deliveries, err)
+	}
+
+	first := newDelivery("http://example.com/a", map[string]string{"Authorization": "Bearer x"}, "run_start", []byte(`{}`))
+	second := newDelivery("http://example.com/b", nil, "run_complete", []byte(`{}`))
+	for _, d := range []*Delivery{second, first} {
+		if err := q.Save(d); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	// Files other than deliveries are skipped
+	for name, content := range map[string]string{"broken.json": "{", "drain.lock": "1\n", "x.json.tmp": "{}"} {
+		if err := os.WriteFile(filepath.Join(q.dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deliveries, err := q.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[0].ID != first.ID || deliveries[1].ID != second.ID {
+		t.Fatalf("List() = %v, want %s then %s", deliveries, first.ID, second.ID)
+	}
+	if deliveries[0].Headers["Authorization"] != "Bearer x" {
+		t.Errorf("List() headers = %v, want the saved ones", deliveries[0].Headers)
+	}
+	if info, err := os.Stat(q.path(first.ID)); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0600) {
+		t.Errorf("delivery file = %v, %v, want mode 0600", info, err)
+	}
+
+	first.Attempts = 2
+	if err := q.Save(first); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := q.Remove(second.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := q.Remove(second.ID); err != nil {
+		t.Errorf("Remove() of a removed delivery error = %v, want nil", err)
+	}
+	deliveries, err = q.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].ID != first.ID || deliveries[0].Attempts != 2 {
+		t.Errorf("List() = %v, want %s with 2 attempts", deliveries, first.ID)
+	}
+}
+
+// TestQueue_Lock tests that one drain at a time holds the lock, and that a
+// lock left by an exited process is taken over.
+func TestQueue_Lock(t *testing.T) {
+	q := NewQueue(t.TempDir())
+	lockPath := filepath.Join(q.dir, "drain.lock")
+
+	unlock, err := q.Lock()
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if _, err := q.Lock(); err == nil {
+		t.Error("Lock() while locked succeeded, want an error")
+	}
+	unlock()
+	if unlock, err = q.Lock(); err != nil {
+		t.Fatalf("Lock() after unlock error = %v", err)
+	}
+	unlock()
+
+	for name, content := range map[string]string{
+		"exited process": strconv.Itoa(exitedProcess(t)) + "\n",
+		"no pid":         "", // Left by a version that created the lock before writing it
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := os.WriteFile(lockPath, []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			unlock, err := q.Lock()
+			if err != nil {
+				t.Fatalf("Lock() error = %v", err)
+			}
+			unlock()
+		})
+	}
+
+	t.Run("running process", func(t *testing.T) {
+		if err := os.WriteFile(lockPath, []byte(strconv.Itoa(otherProcess(t))+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(lockPath)
+		if _, err := q.Lock(); err == nil {
+			t.Error("Lock() held by a running process succeeded, want an error")
+		}
+	})
+
+	// Of attempts at the same time, only one gets the lock
+	t.Run("at once", func(t *testing.T) {
+		for range 20 {
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			var unlocks []func()
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if unlock, err := q.Lock(); err == nil {
+						mu.Lock()
+						unlocks = append(unlocks, unlock)
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+			if len(unlocks) != 1 {
+				t.Fatalf("%d of 8 Lock() calls succeeded, want 1", len(unlocks))
+			}
+			unlocks[0]()
+		}
+	})
+
+	// Neither the lock nor its temporary files are left behind
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("queue directory holds %d files after unlocking, want none", len(entries))
+	}
+}
+
+// TestDelivery_Retrying tests which deliveries another process still
+// attempts.
+func TestDelivery_Retrying(t *testing.T) {
+	tests := []struct {
+		name   string
+		sender int
+		want   bool
+	}{
+		{"given up", 0, false},
+		{"this process", os.Getpid(), false},
+		{"running process", otherProcess(t), true},
+		{"exited process", exitedProcess(t), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Delivery{Sender: tt.sender}
+			if got := d.Retrying(); got != tt.want {
+				t.Errorf("Retrying() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDrain tests that Drain sends pending deliveries once, removing those
+// that succeed and leaving those another process still attempts.
+func TestDrain(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.URL.Path]++
+		mu.Unlock()
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	q := NewQueue(t.TempDir())
+	headers := map[string]string{"X-Token": "secret"}
+	ok := newDelivery(server.URL+"/ok", headers, "run_start", []byte(`{"event":"run_start"}`))
+	failing := newDelivery(server.URL+"/fail", headers, "run_complete", []byte(`{}`))
+	failing.Sender = exitedProcess(t)
+	retrying := newDelivery(server.URL+"/retrying", headers, "task_start", []byte(`{}`))
+	retrying.Sender = otherProcess(t)
+	for _, d := range []*Delivery{ok, failing, retrying} {
+		if err := q.Save(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	unlock, err := q.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Drain(q); err == nil {
+		t.Error("Drain() of a locked queue succeeded, want an error")
+	}
+	unlock()
+
+	result, err := Drain(q)
+	if err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if result.Delivered != 1 || len(result.Failed) != 1 || result.Retrying != 1 {
+		t.Fatalf("Drain() = %d delivered, %d failed, %d retrying; want 1 each", result.Delivered, len(result.Failed), result.Retrying)
+	}
+	if received["/ok"] != 1 || received["/fail"] != 1 || received["/retrying"] != 0 {
+		t.Errorf("requests = %v, want one to /ok and /fail, none to /retrying", received)
+	}
+
+	deliveries, err := q.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 2 || deliveries[0].ID != failing.ID || deliveries[1].ID != retrying.ID {
+		t.Fatalf("List() after Drain() = %v, want %s and %s", deliveries, failing.ID, retrying.ID)
+	}
+	if d := deliveries[0]; d.Attempts != 1 || d.LastError != "webhook returned status 500" || d.Sender != 0 {
+		t.Errorf("failed delivery = %+v, want 1 attempt, the error, and no sender", d)
+	}
+	if d := deliveries[1]; d.Attempts != 0 || d.Sender != retrying.Sender {
+		t.Errorf("retrying delivery = %+v, want it unchanged", d)
+	}
+}
@@ -5,11 +5,26 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/adityaraj/agentflow/internal/config"
+	"github.com/adityaraj/agentflow/internal/redact"
+)
+
+// maxBackoff caps the delay between retries.
+const maxBackoff = time.Minute
+
+// Limits of asynchronous delivery: Send hands deliveries to a fixed pool of
+// workers through a bounded queue, so a slow endpoint during a burst of
+// task events can't pile up goroutines.
+const (
+	Workers   = 4
+	QueueSize = 256
 )
 
 // Manager handles sending webhook notifications.
@@ -17,34 +32,127 @@ type Manager struct {
 	hooks   []config.WebhookConfig
 	client  *http.Client
 	pending sync.WaitGroup
+	secrets []string // Secret values redacted from payloads
+	queue   *Queue   // Where failed deliveries wait for a retry; nil keeps none
+
+	jobs    chan job     // Deliveries waiting for a worker
+	start   sync.Once    // Starts the workers on the first Send
+	dropped atomic.Int64 // Deliveries Send found no room for
+}
+
+// job is an event to deliver to a webhook.
+type job struct {
+	hook  config.WebhookConfig
+	event Event
+}
+
+// Stats describes the asynchronous deliveries of a Manager.
+type Stats struct {
+	Queued  int   // Deliveries waiting for a worker
+	Dropped int64 // Deliveries left for `cortex webhooks drain` because the queue was full
 }
 
-// NewManager creates a new webhook manager.
+// NewManager creates a new webhook manager. Deliveries are kept in the
+// default queue until they succeed, so `cortex webhooks drain` can send
+// those that ran out of retries.
 func NewManager(hooks []config.WebhookConfig) *Manager {
+	queue, _ := DefaultQueue()
 	return &Manager{
-		hooks: hooks,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		hooks:  hooks,
+		client: newClient(),
+		queue:  queue,
+		jobs:   make(chan job, QueueSize),
 	}
 }
 
+func newClient() *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+	}
+}
+
+// SetSecrets sets the secret values to redact from webhook payloads.
+func (m *Manager) SetSecrets(values []string) {
+	m.secrets = values
+}
+
+// redactPayload masks secret values in a marshaled payload, including their
+// JSON-escaped forms.
+func (m *Manager) redactPayload(payload []byte) []byte {
+	if len(m.secrets) == 0 {
+		return payload
+	}
+	values := make([]string, 0, len(m.secrets)*2)
+	for _, s := range m.secrets {
+		values = append(values, s)
+		if escaped, err := json.Marshal(s); err == nil {
+			values = append(values, string(escaped[1:len(escaped)-1]))
+		}
+	}
+	return []byte(redact.Secrets(string(payload), values))
+}
+
 // Send dispatches an event to all matching webhooks.
-// Events are sent asynchronously and don't block execution.
+// Events are sent asynchronously and don't block execution. When the queue
+// of the workers is full, the delivery is dropped: it is only saved to the
+// on-disk queue, for `cortex webhooks drain`.
 func (m *Manager) Send(event Event) {
 	if len(m.hooks) == 0 {
 		return
 	}
+	m.start.Do(m.startWorkers)
 
 	for _, hook := range m.hooks {
-		if hook.MatchesEvent(event.Type) {
-			m.pending.Add(1)
-			go m.post(hook, event)
+		if !hook.MatchesEvent(event.Type) {
+			continue
+		}
+		m.pending.Add(1)
+		select {
+		case m.jobs <- job{hook: hook, event: event}:
+		default:
+			m.pending.Done()
+			m.drop(hook, event)
 		}
 	}
 }
 
-// SendSync dispatches an event and waits for all requests to complete.
+// startWorkers starts the workers that deliver the events queued by Send.
+func (m *Manager) startWorkers() {
+	for range Workers {
+		go func() {
+			for j := range m.jobs {
+				m.post(j.hook, j.event)
+			}
+		}()
+	}
+}
+
+// drop saves a delivery Send had no room for to the on-disk queue, without
+// attempting it.
+func (m *Manager) drop(hook config.WebhookConfig, event Event) {
+	// Warn once; a burst can drop many
+	level := slog.LevelDebug
+	if m.dropped.Add(1) == 1 {
+		level = slog.LevelWarn
+	}
+	slog.Log(context.Background(), level, "webhook delivery queue full, event dropped", "event", event.Type, "url", hook.URL, "queued", len(m.jobs))
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	d := newDelivery(hook.URL, hook.Headers, event.Type, m.redactPayload(payload))
+	d.LastError = "dropped: delivery queue full"
+	m.save(d)
+}
+
+// Stats returns the current state of asynchronous delivery.
+func (m *Manager) Stats() Stats {
+	return Stats{Queued: len(m.jobs), Dropped: m.dropped.Load()}
+}
+
+// SendSync dispatches an event and waits for all requests to complete,
+// retries included.
 func (m *Manager) SendSync(event Event) error {
 	if len(m.hooks) == 0 {
 		return nil
@@ -80,23 +188,73 @@ func (m *Manager) Wait() {
 	m.pending.Wait()
 }
 
-// post sends an event to a webhook asynchronously.
+// post sends an event to a webhook for Send.
 func (m *Manager) post(hook config.WebhookConfig, event Event) {
 	defer m.pending.Done()
 	_ = m.postSync(hook, event) // Ignore errors for async posts
 }
 
-// postSync sends an event to a webhook and returns any error.
+// postSync sends an event to a webhook, retrying with exponential backoff.
+// The delivery is queued on disk first and removed once it succeeds, so it
+// outlives the process if every attempt fails or the process exits while
+// retrying. Until then it is marked with the process ID, so Drain does not
+// send it a second time.
 func (m *Manager) postSync(hook config.WebhookConfig, event Event) error {
 	payload, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
+	d := newDelivery(hook.URL, hook.Headers, event.Type, m.redactPayload(payload))
+	d.Sender = os.Getpid()
+	m.save(d)
+
+	delay := hook.BackoffDelay()
+	for retry := 0; ; retry++ {
+		last := retry >= hook.RetryCount()
+		err = m.attempt(d, last)
+		if err == nil || last {
+			return err
+		}
+		time.Sleep(delay)
+		delay = min(delay*2, maxBackoff)
+	}
+}
+
+// attempt sends a delivery once, recording the outcome in the queue. After
+// the last attempt, the delivery is left to Drain.
+func (m *Manager) attempt(d *Delivery, last bool) error {
+	err := deliver(m.client, d)
+	d.Attempts++
+	if err == nil {
+		m.remove(d)
+		return nil
+	}
+	d.LastError = err.Error()
+	if last {
+		d.Sender = 0
+	}
+	m.save(d)
+	return err
+}
+
+func (m *Manager) save(d *Delivery) {
+	if m.queue != nil {
+		_ = m.queue.Save(d) // Delivery still works without the queue
+	}
+}
 
+func (m *Manager) remove(d *Delivery) {
+	if m.queue != nil {
+		_ = m.queue.Remove(d.ID)
+	}
+}
+
+// deliver posts a delivery's payload to its webhook.
+func deliver(client *http.Client, d *Delivery) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", hook.URL, bytes.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, "POST", d.URL, bytes.NewReader(d.Payload))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -105,11 +263,11 @@ func (m *Manager) postSync(hook config.WebhookConfig, event Event) error {
 	req.Header.Set("User-Agent", "Cortex/1.0")
 
 	// Add custom headers
-	for key, value := range hook.Headers {
+	for key, value := range d.Headers {
 		req.Header.Set(key, value)
 	}
 
-	resp, err := m.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send webhook: %w", err)
 	}
@@ -122,6 +280,51 @@ func (m *Manager) postSync(hook config.WebhookConfig, event Event) error {
 	return nil
 }
 
+// DrainResult is the outcome of Drain.
+type DrainResult struct {
+	Delivered int         // Deliveries sent and removed from the queue
+	Failed    []*Delivery // Deliveries still pending
+	Retrying  int         // Deliveries left alone, as another process still attempts them
+}
+
+// Drain sends every queued delivery once, removing those that succeed.
+// Deliveries another process is still retrying are skipped, and the queue
+// is locked, so no delivery is sent twice at once.
+func Drain(queue *Queue) (DrainResult, error) {
+	var result DrainResult
+	unlock, err := queue.Lock()
+	if err != nil {
+		return result, err
+	}
+	defer unlock()
+
+	deliveries, err := queue.List()
+	if err != nil {
+		return result, err
+	}
+
+	client := newClient()
+	for _, d := range deliveries {
+		if d.Retrying() {
+			result.Retrying++
+			continue
+		}
+		d.Sender = 0
+		if err := deliver(client, d); err != nil {
+			d.Attempts++
+			d.LastError = err.Error()
+			_ = queue.Save(d)
+			result.Failed = append(result.Failed, d)
+			continue
+		}
+		if err := queue.Remove(d.ID); err != nil {
+			return result, err
+		}
+		result.Delivered++
+	}
+	return result, nil
+}
+
 // HasWebhooks returns true if there are any webhooks configured.
 func (m *Manager) HasWebhooks() bool {
 	return len(m.hooks) > 0